@@ -9,15 +9,22 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 
+	"github.com/webpage-analyser-server/internal/audit"
 	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/digest"
 	"github.com/webpage-analyser-server/internal/handlers"
+	"github.com/webpage-analyser-server/internal/jobs"
 	"github.com/webpage-analyser-server/internal/metrics"
 	"github.com/webpage-analyser-server/internal/middleware"
 	"github.com/webpage-analyser-server/internal/router"
 	"github.com/webpage-analyser-server/internal/services"
+	"github.com/webpage-analyser-server/internal/storage"
 )
 
 
@@ -26,9 +33,18 @@ type App struct {
 	logger      *zap.Logger
 	metrics     *metrics.Metrics
 	cache       *services.Cache
+	store       storage.Store
 	analyzer    *services.Analyzer
+	auditor     *audit.Auditor
+	digests     *digest.Buffer
+	digester    *digest.Digester
+	stopDigests context.CancelFunc
+	jobs        *jobs.Runner
 	handler     *handlers.AnalyzeHandler
 	rateLimiter *middleware.RateLimiter
+	limitStore  *middleware.RedisLimiterStore // Set with the redis rate limit store
+	egressMeter *services.RedisEgressMeter    // Set with rate_limit.egress and the redis rate limit store
+	snapshots   *services.RedisSnapshotStore  // Set with snapshots.enabled
 	router      *router.Router
 	server      *http.Server
 }
@@ -48,7 +64,7 @@ func New(configPath string, env string) (*App, error) {
 	}
 
 	
-	m := metrics.New()
+	m := metrics.New(metrics.Options{NativeHistograms: cfg.Metrics.NativeHistograms})
 
 	
 	var cache *services.Cache
@@ -64,16 +80,99 @@ func New(configPath string, env string) (*App, error) {
 	}
 
 	
+	var store storage.Store
+	if cfg.Storage.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.StorageConnectTimeout)
+		defer cancel()
+		store, err = storage.Open(ctx, cfg.Storage, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize storage: %w", err)
+		}
+		logger.Info("Storage enabled", zap.String("driver", cfg.Storage.Driver))
+	} else {
+		store = storage.NewNoOpStore()
+		logger.Info("Storage disabled - analyses are not persisted")
+	}
+
+	
 	analyzer := services.NewAnalyzer(cfg, logger, m, cache)
+	analyzer.SetStore(store)
+
+	// Analyses of monitored URLs are also buffered for the daily digests
+	var digests *digest.Buffer
+	var digester *digest.Digester
+	if cfg.Digest.Enabled {
+		digests = digest.NewBuffer(newRedisClient(cfg), cfg.Digest.RedisPrefix)
+		digester, err = digest.NewDigester(cfg.Digest, digests, digest.SystemClock{}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize digests: %w", err)
+		}
+		analyzer.SetStore(services.AnalysisStores{store, digester})
+		logger.Info("Digests enabled", zap.String("schedule", cfg.Digest.Schedule), zap.Int("destinations", len(cfg.Digest.Destinations)))
+	}
+
+	// Pages analyzed with options.store_snapshot are kept on the cache's Redis server
+	var snapshots *services.RedisSnapshotStore
+	if cfg.Snapshots.Enabled {
+		snapshots = services.NewRedisSnapshotStore(newRedisClient(cfg), cfg.Snapshots.RedisPrefix)
+		analyzer.SetSnapshotStore(snapshots)
+		logger.Info("Snapshots enabled", zap.Int64("max_bytes", cfg.Snapshots.MaxBytes), zap.Duration("ttl", cfg.Snapshots.TTL))
+	}
+
+	// Background jobs of the asynchronous features share one runner
+	jobStore, err := newJobStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize job store: %w", err)
+	}
+	jobRunner := jobs.NewRunner(jobStore, cfg.Jobs, logger)
+
+	
+	var auditor *audit.Auditor
+	if cfg.Audit.Enabled {
+		sink, err := newAuditSink(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize audit sink: %w", err)
+		}
+		auditor = audit.NewAuditor(sink, cfg.Audit.BufferSize, logger, m)
+		logger.Info("Audit enabled", zap.String("sink", cfg.Audit.Sink))
+	}
+
+	
+	handler := handlers.NewAnalyzeHandler(cfg, logger, analyzer)
+	handler.SetAuditor(auditor)
+	analyses := handlers.NewAnalysesHandler(logger, store)
+	validate := handlers.NewValidateHandler(logger, analyzer)
+	duplicates := handlers.NewDuplicatesHandler(logger, analyzer)
+	exporter := handlers.NewExportHandler(cfg, logger, store, cache)
+	cacheStats := handlers.NewCacheStatsHandler(logger, cache)
+	snapshotHandler := handlers.NewSnapshotHandler(logger, analyzer)
 
 	
-	handler := handlers.NewAnalyzeHandler(logger, analyzer)
+	rateLimiter, limiterStore, err := newRateLimiter(cfg, logger, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize rate limiter: %w", err)
+	}
+	rateLimits := handlers.NewRateLimitHandler(logger, rateLimiter, cfg.RateLimit)
+
+	// Fetched bytes are counted in the store client budgets are kept in
+	var egressMeter *services.RedisEgressMeter
+	if cfg.RateLimit.Enabled && cfg.RateLimit.Egress.Enabled {
+		if limiterStore != nil {
+			egressMeter = services.NewRedisEgressMeter(newRedisClient(cfg), cfg.RateLimit.RedisPrefix)
+			analyzer.SetEgressMeter(egressMeter)
+		} else {
+			analyzer.SetEgressMeter(services.NewMemoryEgressMeter())
+		}
+		rateLimits.SetEgress(analyzer)
+		logger.Info("Egress quota enabled", zap.Int64("daily_bytes", cfg.RateLimit.Egress.DailyBytes))
+	}
 
 	
-	rateLimiter := middleware.NewRateLimiter()
+	capabilities := handlers.NewCapabilitiesHandler(services.Capabilities(cfg))
+	readiness := handlers.NewReadinessHandler(services.NewOutboundProbe(cfg.Readiness.Outbound, logger, m))
 
 	
-	r := router.New(cfg, logger, m, handler, rateLimiter)
+	r := router.New(cfg, logger, m, handler, analyses, validate, duplicates, exporter, cacheStats, rateLimits, capabilities, readiness, snapshotHandler, rateLimiter)
 
 	
 	srv := &http.Server{
@@ -88,9 +187,17 @@ func New(configPath string, env string) (*App, error) {
 		logger:      logger,
 		metrics:     m,
 		cache:       cache,
+		store:       store,
 		analyzer:    analyzer,
+		auditor:     auditor,
+		digests:     digests,
+		digester:    digester,
+		jobs:        jobRunner,
 		handler:     handler,
 		rateLimiter: rateLimiter,
+		limitStore:  limiterStore,
+		egressMeter: egressMeter,
+		snapshots:   snapshots,
 		router:      r,
 		server:      srv,
 	}, nil
@@ -109,6 +216,17 @@ func (a *App) Start() error {
 		}
 	}()
 
+	if err := a.jobs.Start(context.Background()); err != nil {
+		return fmt.Errorf("failed to start jobs: %w", err)
+	}
+
+	// Digests are sent on schedule until the app stops
+	if a.digester != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		a.stopDigests = cancel
+		go a.digester.Run(ctx)
+	}
+
 	return nil
 }
 
@@ -125,12 +243,66 @@ func (a *App) Stop() error {
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 
+	// Client budgets are saved once no request can change them any more; best effort
+	if a.limitStore != nil {
+		a.rateLimiter.Close()
+		if err := a.limitStore.Close(); err != nil {
+			return fmt.Errorf("rate limit store shutdown failed: %w", err)
+		}
+	} else if path := a.config.RateLimit.SnapshotPath; path != "" {
+		if err := a.rateLimiter.SaveSnapshot(path); err != nil {
+			a.logger.Warn("Failed to save rate limit snapshot", zap.String("path", path), zap.Error(err))
+		}
+	}
+
+	// Running jobs get the drain timeout to finish; queued jobs wait in the store for the next start
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), a.config.Jobs.DrainTimeout)
+	defer cancelDrain()
+	if err := a.jobs.Close(drainCtx); err != nil {
+		return fmt.Errorf("jobs shutdown failed: %w", err)
+	}
+
+	
+	if err := a.analyzer.Close(); err != nil {
+		return fmt.Errorf("analyzer shutdown failed: %w", err)
+	}
+	if a.egressMeter != nil {
+		if err := a.egressMeter.Close(); err != nil {
+			return fmt.Errorf("egress meter shutdown failed: %w", err)
+		}
+	}
+	if a.snapshots != nil {
+		if err := a.snapshots.Close(); err != nil {
+			return fmt.Errorf("snapshot store shutdown failed: %w", err)
+		}
+	}
+
+	
+	if err := a.auditor.Close(); err != nil {
+		return fmt.Errorf("audit shutdown failed: %w", err)
+	}
+
+	// Buffered digest events stay in Redis for the next run
+	if a.stopDigests != nil {
+		a.stopDigests()
+	}
+	if a.digests != nil {
+		if err := a.digests.Close(); err != nil {
+			return fmt.Errorf("digest shutdown failed: %w", err)
+		}
+	}
+
 	
 	if err := a.cache.Close(); err != nil {
 		return fmt.Errorf("cache shutdown failed: %w", err)
 	}
 
 	
+	if err := a.store.Close(); err != nil {
+		return fmt.Errorf("storage shutdown failed: %w", err)
+	}
+
+	
 	if err := a.logger.Sync(); err != nil {
 		return fmt.Errorf("logger sync failed: %w", err)
 	}
@@ -146,22 +318,116 @@ func (a *App) WaitForSignal() {
 }
 
 func initLogger(cfg *config.Config) (*zap.Logger, error) {
-	var config zap.Config
-
-	
 	level := zap.NewAtomicLevel()
 	if err := level.UnmarshalText([]byte(cfg.Logging.Level)); err != nil {
 		return nil, fmt.Errorf("invalid log level: %w", err)
 	}
 
-	if cfg.Logging.Format == "json" {
-		config = zap.NewProductionConfig()
-	} else {
-		config = zap.NewDevelopmentConfig()
+	outputs := cfg.Logging.Outputs
+	if len(outputs) == 0 {
+		outputs = []config.LogOutputConfig{{Path: constants.LogOutputStdout}}
+	}
+
+	// Build one core per sink so each can use its own encoding
+	cores := make([]zapcore.Core, 0, len(outputs))
+	for _, output := range outputs {
+		format := output.Format
+		if format == "" {
+			format = cfg.Logging.Format
+		}
+		cores = append(cores, zapcore.NewCore(newLogEncoder(format), newLogWriter(output.Path, cfg.Logging.Rotation), level))
+	}
+
+	options := []zap.Option{zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)}
+	if cfg.Logging.Format != constants.LogFormatJSON {
+		options = append(options, zap.Development())
 	}
 
-	config.Level = level
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	return zap.New(zapcore.NewTee(cores...), options...), nil
+}
+
+// newAuditSink returns the configured audit sink; the redis sink uses the cache's Redis server
+func newAuditSink(cfg *config.Config) (audit.Sink, error) {
+	switch cfg.Audit.Sink {
+	case "", constants.AuditSinkFile:
+		return audit.NewFileSink(cfg.Audit.Path, cfg.Logging.Rotation), nil
+	case constants.AuditSinkRedis:
+		return audit.NewRedisSink(newRedisClient(cfg), cfg.Audit.RedisStream, cfg.Audit.RedisMaxLen), nil
+	default:
+		return nil, fmt.Errorf("unsupported audit sink %q", cfg.Audit.Sink)
+	}
+}
+
+// newJobStore returns the configured job store; the redis store uses the cache's Redis server
+func newJobStore(cfg *config.Config) (jobs.Store, error) {
+	switch cfg.Jobs.Store {
+	case "", constants.JobStoreMemory:
+		return jobs.NewMemoryStore(cfg.Jobs.Retention), nil
+	case constants.JobStoreRedis:
+		return jobs.NewRedisStore(newRedisClient(cfg), cfg.Jobs.RedisPrefix, cfg.Jobs.Retention), nil
+	default:
+		return nil, fmt.Errorf("unsupported job store %q", cfg.Jobs.Store)
+	}
+}
+
+// newRateLimiter returns the client rate limiter. The redis store keeps budgets on the cache's
+// Redis server; with the memory store, budgets saved to the snapshot path at the last
+// shutdown are restored, best effort.
+func newRateLimiter(cfg *config.Config, logger *zap.Logger, m *metrics.Metrics) (*middleware.RateLimiter, *middleware.RedisLimiterStore, error) {
+	rateLimiter := middleware.NewRateLimiter(m)
+	switch cfg.RateLimit.Store {
+	case "", constants.RateLimitStoreMemory:
+		if path := cfg.RateLimit.SnapshotPath; path != "" {
+			if err := rateLimiter.RestoreSnapshot(path); err != nil {
+				logger.Warn("Failed to restore rate limit snapshot", zap.String("path", path), zap.Error(err))
+			}
+		}
+		return rateLimiter, nil, nil
+	case constants.RateLimitStoreRedis:
+		store := middleware.NewRedisLimiterStore(newRedisClient(cfg), cfg.RateLimit.RedisPrefix)
+		rateLimiter.SetStore(store, logger)
+		return rateLimiter, store, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported rate limit store %q", cfg.RateLimit.Store)
+	}
+}
+
+// newRedisClient connects to the cache's Redis server
+func newRedisClient(cfg *config.Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Cache.Redis.Host, cfg.Cache.Redis.Port),
+		DB:       cfg.Cache.Redis.DB,
+		Password: cfg.Cache.Redis.Password,
+	})
+}
+
+// newLogEncoder returns a JSON or console encoder for the given format
+func newLogEncoder(format string) zapcore.Encoder {
+	if format == constants.LogFormatJSON {
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewJSONEncoder(encoderConfig)
+	}
 
-	return config.Build()
-} 
\ No newline at end of file
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	return zapcore.NewConsoleEncoder(encoderConfig)
+}
+
+// newLogWriter returns the write syncer for a sink; file paths rotate via lumberjack
+func newLogWriter(path string, rotation config.LogRotationConfig) zapcore.WriteSyncer {
+	switch path {
+	case "", constants.LogOutputStdout:
+		return zapcore.Lock(os.Stdout)
+	case constants.LogOutputStderr:
+		return zapcore.Lock(os.Stderr)
+	}
+
+	return zapcore.AddSync(&lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    rotation.MaxSizeMB,
+		MaxBackups: rotation.MaxBackups,
+		MaxAge:     rotation.MaxAgeDays,
+		Compress:   rotation.Compress,
+	})
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+)
+
+func TestInitLogger_WritesToFile(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:   "info",
+			Format:  "console",
+			Outputs: []config.LogOutputConfig{{Path: logPath, Format: "json"}},
+		},
+	}
+
+	logger, err := initLogger(cfg)
+	require.NoError(t, err)
+	logger.Info("hello from test")
+	logger.Debug("filtered by level")
+	_ = logger.Sync()
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	// The file sink uses its own JSON encoding regardless of the top-level format
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "hello from test", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+}
+
+func TestInitLogger_RotatesWhenSizeCapReached(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:    "info",
+			Format:   "json",
+			Outputs:  []config.LogOutputConfig{{Path: logPath}},
+			Rotation: config.LogRotationConfig{MaxSizeMB: 1, MaxBackups: 3},
+		},
+	}
+
+	logger, err := initLogger(cfg)
+	require.NoError(t, err)
+
+	// Write a little over 1MB to force at least one rotation
+	payload := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		logger.Info(payload)
+	}
+	_ = logger.Sync()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(entries), 2, "expected the active log file plus at least one rotated backup")
+
+	info, err := os.Stat(logPath)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(1024*1024))
+}
+
+func TestInitLogger_InvalidLevel(t *testing.T) {
+	cfg := &config.Config{Logging: config.LoggingConfig{Level: "verbose"}}
+
+	_, err := initLogger(cfg)
+	assert.Error(t, err)
+}
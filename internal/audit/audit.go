@@ -0,0 +1,202 @@
+// Package audit records who analyzed what, when and with which outcome. Events are
+// buffered in memory and written to a sink in the background, so a slow sink never
+// delays a response; events that do not fit in the buffer are dropped and counted.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+)
+
+// Event is the audit record of one analysis request
+type Event struct {
+	Timestamp  time.Time
+	RequestID  string
+	ClientIP   string
+	APIKeyID   string // Fingerprint of the caller's API key, never the key itself
+	Operation  string
+	TargetURL  string // Without credentials
+	Outcome    string
+	Status     int
+	ErrorCode  string
+	DurationMs int64
+}
+
+// MarshalLogObject encodes the event as the fields of one JSON line
+func (e Event) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range e.fields() {
+		switch value := field.value.(type) {
+		case string:
+			enc.AddString(field.name, value)
+		case int64:
+			enc.AddInt64(field.name, value)
+		}
+	}
+	return nil
+}
+
+type eventField struct {
+	name  string
+	value any
+}
+
+// fields lists the event's fields in a fixed order, leaving out empty optional ones
+func (e Event) fields() []eventField {
+	fields := []eventField{
+		{"timestamp", e.Timestamp.UTC().Format(time.RFC3339Nano)},
+		{"request_id", e.RequestID},
+		{"client_ip", e.ClientIP},
+	}
+	if e.APIKeyID != "" {
+		fields = append(fields, eventField{"api_key_id", e.APIKeyID})
+	}
+	fields = append(fields,
+		eventField{"operation", e.Operation},
+		eventField{"target_url", e.TargetURL},
+		eventField{"outcome", e.Outcome},
+		eventField{"status", int64(e.Status)},
+	)
+	if e.ErrorCode != "" {
+		fields = append(fields, eventField{"error_code", e.ErrorCode})
+	}
+	return append(fields, eventField{"duration_ms", e.DurationMs})
+}
+
+// KeyID returns the fingerprint recorded in place of an API key; empty keys have none
+func KeyID(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])[:constants.AuditAPIKeyIDLength]
+}
+
+// Outcome classifies a response status
+func Outcome(status int) string {
+	switch {
+	case status == constants.StatusPartialContent:
+		return constants.AuditOutcomePartial
+	case status >= constants.StatusInternalServerError:
+		return constants.AuditOutcomeFailed
+	case status >= constants.StatusBadRequest:
+		return constants.AuditOutcomeRejected
+	default:
+		return constants.AuditOutcomeSuccess
+	}
+}
+
+// Sink stores audit events
+type Sink interface {
+	Write(ctx context.Context, event Event) error
+	Close() error
+}
+
+// Auditor buffers events and writes them to a sink from a background goroutine
+type Auditor struct {
+	sink    Sink
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+	events  chan Event
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAuditor starts an auditor holding up to bufferSize events waiting for the sink
+func NewAuditor(sink Sink, bufferSize int, logger *zap.Logger, m *metrics.Metrics) *Auditor {
+	if bufferSize <= 0 {
+		bufferSize = constants.DefaultAuditBufferSize
+	}
+
+	a := &Auditor{
+		sink:    sink,
+		logger:  logger,
+		metrics: m,
+		events:  make(chan Event, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Record queues an event without blocking; the event is dropped when the buffer is full.
+// Recording on a nil or closed auditor is a no-op.
+func (a *Auditor) Record(event Event) {
+	if a == nil {
+		return
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return
+	}
+
+	select {
+	case a.events <- event:
+	default:
+		a.drop()
+		a.logger.Warn("Audit buffer full, dropping event", zap.String("request_id", event.RequestID))
+	}
+}
+
+// Dropped returns the number of events lost to a full buffer or a failing sink
+func (a *Auditor) Dropped() int64 {
+	if a == nil {
+		return 0
+	}
+	return a.dropped.Load()
+}
+
+// Close writes the buffered events, then closes the sink
+func (a *Auditor) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.events)
+	a.mu.Unlock()
+
+	<-a.done
+	return a.sink.Close()
+}
+
+func (a *Auditor) run() {
+	defer close(a.done)
+	for event := range a.events {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.AuditWriteTimeout)
+		if err := a.sink.Write(ctx, event); err != nil {
+			a.drop()
+			a.logger.Warn("Failed to write audit event",
+				zap.String("request_id", event.RequestID),
+				zap.Error(err),
+			)
+		}
+		cancel()
+	}
+}
+
+func (a *Auditor) drop() {
+	a.dropped.Add(1)
+	if a.metrics != nil {
+		a.metrics.AuditEventsDropped.Inc()
+	}
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+)
+
+// recordingSink keeps written events; writes block while release is open and fail with err
+type recordingSink struct {
+	mu      sync.Mutex
+	events  []Event
+	release chan struct{}
+	err     error
+	closed  bool
+}
+
+func (s *recordingSink) Write(ctx context.Context, event Event) error {
+	if s.release != nil {
+		<-s.release
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func newTestEvent(requestID string) Event {
+	return Event{
+		Timestamp:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		RequestID:  requestID,
+		ClientIP:   "192.0.2.1",
+		Operation:  constants.AuditOperationAnalyze,
+		TargetURL:  "https://example.com/",
+		Outcome:    constants.AuditOutcomeSuccess,
+		Status:     200,
+		DurationMs: 42,
+	}
+}
+
+func TestAuditor_WritesEventsInOrder(t *testing.T) {
+	sink := &recordingSink{}
+	auditor := NewAuditor(sink, 10, zaptest.NewLogger(t), metrics.NewWithRegistry(prometheus.NewRegistry()))
+
+	auditor.Record(newTestEvent("a"))
+	auditor.Record(newTestEvent("b"))
+	require.NoError(t, auditor.Close())
+
+	assert.Equal(t, []Event{newTestEvent("a"), newTestEvent("b")}, sink.events)
+	assert.True(t, sink.closed)
+	assert.Zero(t, auditor.Dropped())
+}
+
+func TestAuditor_DropsEventsWhenBufferFull(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	auditor := NewAuditor(sink, 1, zaptest.NewLogger(t), m)
+
+	// The writer holds the first event while the buffer fills up with the second
+	auditor.Record(newTestEvent("a"))
+	require.Eventually(t, func() bool { return len(auditor.events) == 0 }, time.Second, time.Millisecond)
+	auditor.Record(newTestEvent("b"))
+	auditor.Record(newTestEvent("c"))
+	auditor.Record(newTestEvent("d"))
+
+	close(sink.release)
+	require.NoError(t, auditor.Close())
+
+	assert.Equal(t, []Event{newTestEvent("a"), newTestEvent("b")}, sink.events)
+	assert.Equal(t, int64(2), auditor.Dropped())
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.AuditEventsDropped))
+}
+
+func TestAuditor_CountsFailedWritesAsDropped(t *testing.T) {
+	sink := &recordingSink{err: errors.New("disk full")}
+	auditor := NewAuditor(sink, 10, zaptest.NewLogger(t), nil)
+
+	auditor.Record(newTestEvent("a"))
+	require.NoError(t, auditor.Close())
+
+	assert.Empty(t, sink.events)
+	assert.Equal(t, int64(1), auditor.Dropped())
+}
+
+func TestAuditor_NilAndClosed(t *testing.T) {
+	var auditor *Auditor
+	auditor.Record(newTestEvent("a"))
+	assert.NoError(t, auditor.Close())
+	assert.Zero(t, auditor.Dropped())
+
+	sink := &recordingSink{}
+	auditor = NewAuditor(sink, 10, zaptest.NewLogger(t), nil)
+	require.NoError(t, auditor.Close())
+	auditor.Record(newTestEvent("late"))
+	assert.NoError(t, auditor.Close())
+	assert.Empty(t, sink.events)
+}
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, constants.AuditOutcomeSuccess, Outcome(200))
+	assert.Equal(t, constants.AuditOutcomeSuccess, Outcome(304))
+	assert.Equal(t, constants.AuditOutcomePartial, Outcome(206))
+	assert.Equal(t, constants.AuditOutcomeRejected, Outcome(429))
+	assert.Equal(t, constants.AuditOutcomeFailed, Outcome(502))
+}
+
+func TestKeyID(t *testing.T) {
+	assert.Empty(t, KeyID(""))
+	id := KeyID("secret-key")
+	assert.Len(t, id, constants.AuditAPIKeyIDLength)
+	assert.Equal(t, id, KeyID("secret-key"))
+	assert.NotEqual(t, id, KeyID("other-key"))
+	assert.NotContains(t, id, "secret")
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+)
+
+func TestFileSink_WritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink := NewFileSink(path, config.LogRotationConfig{})
+
+	failed := newTestEvent("b")
+	failed.APIKeyID = KeyID("secret-key")
+	failed.Outcome = "failed"
+	failed.Status = 502
+	failed.ErrorCode = "FETCH_ERROR"
+
+	require.NoError(t, sink.Write(context.Background(), newTestEvent("a")))
+	require.NoError(t, sink.Write(context.Background(), failed))
+	require.NoError(t, sink.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var first map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":   "2024-05-01T12:00:00Z",
+		"request_id":  "a",
+		"client_ip":   "192.0.2.1",
+		"operation":   "analyze",
+		"target_url":  "https://example.com/",
+		"outcome":     "success",
+		"status":      float64(200),
+		"duration_ms": float64(42),
+	}, first, "the line holds the event only, without empty optional fields")
+
+	var second map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, KeyID("secret-key"), second["api_key_id"])
+	assert.Equal(t, "FETCH_ERROR", second["error_code"])
+	assert.Equal(t, float64(502), second["status"])
+}
@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisSink adds events to a Redis stream, trimmed to about maxLen entries
+type RedisSink struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisSink writes events to stream; the sink owns the client and closes it
+func NewRedisSink(client *redis.Client, stream string, maxLen int64) *RedisSink {
+	return &RedisSink{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (s *RedisSink) Write(ctx context.Context, event Event) error {
+	fields := event.fields()
+	values := make([]any, 0, 2*len(fields))
+	for _, field := range fields {
+		values = append(values, field.name, field.value)
+	}
+
+	err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add audit event to stream %s: %w", s.stream, err)
+	}
+	return nil
+}
+
+func (s *RedisSink) Close() error {
+	return s.client.Close()
+}
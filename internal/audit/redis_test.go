@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisSink_AddsStreamEntries(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	sink := NewRedisSink(client, "audit", 1000)
+
+	event := newTestEvent("a")
+	event.ErrorCode = "FETCH_ERROR"
+	require.NoError(t, sink.Write(context.Background(), event))
+	require.NoError(t, sink.Write(context.Background(), newTestEvent("b")))
+
+	entries, err := client.XRange(context.Background(), "audit", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":   "2024-05-01T12:00:00Z",
+		"request_id":  "a",
+		"client_ip":   "192.0.2.1",
+		"operation":   "analyze",
+		"target_url":  "https://example.com/",
+		"outcome":     "success",
+		"status":      "200",
+		"error_code":  "FETCH_ERROR",
+		"duration_ms": "42",
+	}, entries[0].Values)
+	assert.Equal(t, "b", entries[1].Values["request_id"])
+
+	require.NoError(t, sink.Close())
+}
+
+func TestRedisSink_WriteFailsWhenServerDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	sink := NewRedisSink(client, "audit", 1000)
+	server.Close()
+
+	err := sink.Write(context.Background(), newTestEvent("a"))
+	assert.ErrorContains(t, err, "stream audit")
+	require.NoError(t, sink.Close())
+}
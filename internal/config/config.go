@@ -11,64 +11,359 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server    ServerConfig
-	Cache     CacheConfig
-	Analyzer  AnalyzerConfig
-	Logging   LoggingConfig
-	Metrics   MetricsConfig
-	RateLimit RateLimitConfig
-	CORS      CORSConfig
+	Server    ServerConfig    `mapstructure:"server"`
+	Cache     CacheConfig     `mapstructure:"cache"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	Analyzer  AnalyzerConfig  `mapstructure:"analyzer"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	CORS      CORSConfig      `mapstructure:"cors"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Digest    DigestConfig    `mapstructure:"digest"`
+	Jobs      JobsConfig      `mapstructure:"jobs"`
+	Readiness ReadinessConfig `mapstructure:"readiness"`
+	Snapshots SnapshotsConfig `mapstructure:"snapshots"`
 }
 
 type ServerConfig struct {
-	Port    int
-	Timeout time.Duration
-	Mode    string
+	Port    int           `mapstructure:"port"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	Mode    string        `mapstructure:"mode"`
+	// WebDir serves the frontend from this directory instead of the copy embedded in the
+	// binary, for frontend development; templates are reloaded on every request in debug mode
+	WebDir string `mapstructure:"web_dir"`
 }
 
 type CacheConfig struct {
-	Enabled bool
-	TTL     time.Duration
-	Redis   RedisConfig
+	Enabled bool          `mapstructure:"enabled"`
+	TTL     time.Duration `mapstructure:"ttl"`
+	TTLJitter float64     `mapstructure:"ttl_jitter"` // Fraction of the TTL each entry's expiry varies by, randomly; 0 disables
+	Redis   RedisConfig   `mapstructure:"redis"`
+	DedicatedDB bool      `mapstructure:"dedicated_db"` // The Redis DB holds only cache entries, so stats count them with DBSIZE
+	TemporaryFailures TemporaryFailuresConfig `mapstructure:"temporary_failures"`
+	Duplicates DuplicatesConfig `mapstructure:"duplicates"`
+}
+
+// DuplicatesConfig indexes the titles and meta descriptions of cached analyses, so pages of
+// a site sharing them can be found
+type DuplicatesConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	MaxURLs int  `mapstructure:"max_urls"` // URLs indexed per title or meta description of a site; the latest cached are kept
+}
+
+// TemporaryFailuresConfig shortens the cache lifetime of results whose broken links are mostly
+// temporary failures, such as an external host that was briefly down
+type TemporaryFailuresConfig struct {
+	Threshold float64       `mapstructure:"threshold"` // Share of the failed links, 0 to 1, above which the TTL is shortened
+	TTL       time.Duration `mapstructure:"ttl"`       // Shortened TTL; 0 caches such results for the full TTL
+}
+
+// StorageConfig configures the optional durable store of analyses
+type StorageConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Driver  string `mapstructure:"driver"` // postgres or sqlite
+	DSN     string `mapstructure:"dsn"`
+}
+
+// AdminConfig protects the /admin endpoints
+type AdminConfig struct {
+	Token string `mapstructure:"token"` // Required as a bearer token; the endpoints are not served when empty
 }
 
 type RedisConfig struct {
-	Host     string
-	Port     int
-	DB       int
-	Password string
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	DB       int    `mapstructure:"db"`
+	Password string `mapstructure:"password"`
 }
 
 type AnalyzerConfig struct {
-	MaxLinks     int
-	LinkTimeout  time.Duration
-	MaxWorkers   int
-	MaxRedirects int
+	MaxLinks     int           `mapstructure:"max_links"`
+	LinkTimeout  time.Duration `mapstructure:"link_timeout"`
+	MaxWorkers   int           `mapstructure:"max_workers"`
+	SectionConcurrency int     `mapstructure:"section_concurrency"` // Sections of one analysis run at once; 0 means GOMAXPROCS, 1 runs them one by one
+	LinkPoolSize int           `mapstructure:"link_pool_size"` // Link check workers shared by all analyses
+	MaxRedirects int           `mapstructure:"max_redirects"`
+	LinkMaxRedirects int       `mapstructure:"link_max_redirects"` // Hops followed per link check; 0 judges links by their first response
+	MaxExternalHosts int       `mapstructure:"max_external_hosts"` // Distinct external hosts link checks may contact; 0 means unlimited
+	MaxBodyBytes int64         `mapstructure:"max_body_bytes"`
+	MinBodyBytes int64         `mapstructure:"min_body_bytes"` // Smaller fetched pages fail with EMPTY_DOCUMENT; 0 only rejects pages without content
+	AnalysisTimeout time.Duration `mapstructure:"analysis_timeout"`
+	Timeouts     TimeoutsConfig    `mapstructure:"timeouts"`
+	JSRendering  JSRenderingConfig `mapstructure:"js_rendering"`
+	SEO          SEOConfig         `mapstructure:"seo"`
+	BotProtection BotProtectionConfig `mapstructure:"bot_protection"`
+	LinkCircuit  LinkCircuitConfig `mapstructure:"link_circuit"`
+	LinkSampling LinkSamplingConfig `mapstructure:"link_sampling"`
+	Budget       BudgetConfig      `mapstructure:"budget"`
+	ExternalDomains ExternalDomainsConfig `mapstructure:"external_domains"`
+	InternalScope    string   `mapstructure:"internal_scope"`    // exact_host, registered_domain or suffixes: which links count as internal
+	InternalSuffixes []string `mapstructure:"internal_suffixes"` // Domains of the site, with their subdomains, in the suffixes scope
+	AnchorText       AnchorTextConfig `mapstructure:"anchor_text"`
+	MaxDOMElements int `mapstructure:"max_dom_elements"` // 0 disables the limits below
+	MaxDOMAnchors  int `mapstructure:"max_dom_anchors"`  // Anchors classified once the element limit is exceeded
+	MaxDOMForms    int `mapstructure:"max_dom_forms"`    // Forms scanned once the element limit is exceeded
+	ScopeUnmatched string `mapstructure:"scope_unmatched"` // document or empty: what options.scope_selector falls back to when it matches nothing
+	Auth           AuthConfig `mapstructure:"auth"`
+	Mobile         MobileConfig `mapstructure:"mobile"`
+	Soft404        Soft404Config `mapstructure:"soft_404"`
+	TargetPolicy   TargetPolicyConfig `mapstructure:"target_policy"`
+	// Proxies are the egress proxies options.egress selects by name, such as {us: http://proxy-us:3128};
+	// analyses without it connect directly, or through the proxy of the environment
+	Proxies map[string]string `mapstructure:"proxies"`
+	// LinkCheckOverrides changes how links to matching hosts are checked. It is a list rather
+	// than a map because configuration keys cannot contain the dots of a host name.
+	LinkCheckOverrides []LinkCheckOverride `mapstructure:"link_check_overrides"`
+}
+
+// TimeoutsConfig bounds each stage of the requests of an analysis, so unreachable hosts fail
+// fast while slow but live ones still get to send their body
+type TimeoutsConfig struct {
+	Page  FetchTimeouts `mapstructure:"page"`  // The page fetch, its redirects, the mobile fetch and origin probes
+	Links FetchTimeouts `mapstructure:"links"` // Link checks
+}
+
+// FetchTimeouts are the timeouts of the stages of a request; 0 keeps the default of a stage
+type FetchTimeouts struct {
+	Connect        time.Duration `mapstructure:"connect"`         // Establishing the TCP connection
+	TLSHandshake   time.Duration `mapstructure:"tls_handshake"`   // Completing the TLS handshake
+	ResponseHeader time.Duration `mapstructure:"response_header"` // Receiving the response headers once the request is sent
+	Overall        time.Duration `mapstructure:"overall"`         // Ceiling of the whole request, body and redirects included; 0 keeps the link timeout
+}
+
+// LinkCheckOverride changes how links to the hosts matching Pattern are checked
+type LinkCheckOverride struct {
+	Pattern string        `mapstructure:"pattern"` // An exact host, or *.example.com for its subdomains
+	Method  string        `mapstructure:"method"`  // Request method, such as GET for hosts rejecting HEAD; empty keeps HEAD
+	Timeout time.Duration `mapstructure:"timeout"` // Check timeout; 0 keeps the link timeout
+	Skip    bool          `mapstructure:"skip"`    // Do not check the links, reporting them under links.skipped.config
+}
+
+// TargetPolicyConfig restricts the hosts analyses send requests to: the target, its redirects and links
+type TargetPolicyConfig struct {
+	Mode                string   `mapstructure:"mode"`                  // enforce or dry_run
+	BlockedHosts        []string `mapstructure:"blocked_hosts"`         // Hosts, and their subdomains, never requested
+	AllowedHosts        []string `mapstructure:"allowed_hosts"`         // When set, only these hosts and their subdomains are requested
+	AllowedPorts        []int    `mapstructure:"allowed_ports"`         // When set, only these ports are requested
+	AllowPrivateResolve bool     `mapstructure:"allow_private_resolve"` // options.resolve may pin hosts to private, loopback and link-local addresses
+}
+
+// Soft404Config tunes the detection of "not found" pages served with status 200
+type Soft404Config struct {
+	MaxTextLength int           `mapstructure:"max_text_length"` // Pages with more visible text are never suspected by phrase
+	Phrases       []string      `mapstructure:"phrases"`         // Matched case-insensitively in the title, headings and text
+	ErrorTitleTTL time.Duration `mapstructure:"error_title_ttl"` // How long a host's error page title is remembered
+}
+
+// MobileConfig configures the mobile fetch of options.compare_mobile
+type MobileConfig struct {
+	UserAgent string `mapstructure:"user_agent"`
+}
+
+// AuthConfig restricts the per-request credentials of options.auth
+type AuthConfig struct {
+	PublicOnlyHosts []string `mapstructure:"public_only_hosts"` // Hosts, and their subdomains, analyzed without credentials only
+}
+
+// AnchorTextConfig configures the anchor text quality metrics and the link-generic-text accessibility rule
+type AnchorTextConfig struct {
+	GenericPhrases []string `mapstructure:"generic_phrases"` // Link texts that say nothing of the target, in any language
+}
+
+// ExternalDomainsConfig controls the per-domain breakdown of external links
+type ExternalDomainsConfig struct {
+	Mode string `mapstructure:"mode"`  // host or registered_domain
+	TopN int    `mapstructure:"top_n"` // Only the domains with the most links are reported
+}
+
+// BudgetConfig bounds the outbound requests made on behalf of analyses
+type BudgetConfig struct {
+	MaxOutboundRequestsPerAnalysis int     `mapstructure:"max_outbound_requests_per_analysis"` // 0 means unlimited
+	GlobalRequestsPerSecond        float64 `mapstructure:"global_requests_per_second"`         // Shared across analyses; 0 disables
+	GlobalBurst                    int     `mapstructure:"global_burst"`                       // Defaults to the per-second rate
+}
+
+// LinkCircuitConfig controls the per-host circuit breaker for external link checks
+type LinkCircuitConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold"` // Consecutive failures that open a host's circuit
+	SharedTTL        time.Duration `mapstructure:"shared_ttl"`        // Keep circuits open across analyses for this long; 0 disables
+}
+
+// LinkSamplingConfig controls which links are checked on pages with more unique links than
+// max_links: a random sample instead of the first links in document order
+type LinkSamplingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// BotProtectionConfig controls how challenge pages served instead of content are handled
+type BotProtectionConfig struct {
+	Action string `mapstructure:"action"` // "fail" or "tag"
+}
+
+// SEOConfig tunes the SEO score
+type SEOConfig struct {
+	Weights map[string]int `mapstructure:"weights"` // Score penalty per rule; missing rules use the defaults
+	IgnoreTrailingSlash bool `mapstructure:"ignore_trailing_slash"` // Canonical consistency treats /docs/ and /docs as the same page
+	CanonicalSimilarityThreshold float64 `mapstructure:"canonical_similarity_threshold"` // Score from which options.compare_canonical reports the same content
+}
+
+// JSRenderingConfig configures optional page rendering through a headless browser
+type JSRenderingConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Endpoint      string        `mapstructure:"endpoint"` // Chrome DevTools endpoint, e.g. ws://chrome:9222
+	Timeout       time.Duration `mapstructure:"timeout"`
+	WaitSelector  string        `mapstructure:"wait_selector"` // Wait for this selector instead of network idle
+	MaxConcurrent int           `mapstructure:"max_concurrent"`
 }
 
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level    string            `mapstructure:"level"`
+	Format   string            `mapstructure:"format"`
+	Outputs  []LogOutputConfig `mapstructure:"outputs"`
+	Rotation LogRotationConfig `mapstructure:"rotation"`
+	Access   AccessLogConfig   `mapstructure:"access"`
+}
+
+// LogOutputConfig describes a single log sink: stdout, stderr, or a file path
+type LogOutputConfig struct {
+	Path   string `mapstructure:"path"`
+	Format string `mapstructure:"format"` // Defaults to the top-level logging format
+}
+
+// LogRotationConfig controls rotation of file log sinks
+type LogRotationConfig struct {
+	MaxSizeMB  int  `mapstructure:"max_size_mb"`
+	MaxBackups int  `mapstructure:"max_backups"`
+	MaxAgeDays int  `mapstructure:"max_age_days"`
+	Compress   bool `mapstructure:"compress"`
+}
+
+// AccessLogConfig controls the per-request access log
+type AccessLogConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SampleRate logs 1-in-N successful requests; errors are always logged
+	SampleRate        int      `mapstructure:"sample_rate"`
+	RedactQueryParams []string `mapstructure:"redact_query_params"`
 }
 
 type MetricsConfig struct {
-	Enabled    bool
-	Prometheus PrometheusConfig
+	Enabled          bool             `mapstructure:"enabled"`
+	Prometheus       PrometheusConfig `mapstructure:"prometheus"`
+	NativeHistograms bool             `mapstructure:"native_histograms"` // Also expose latency histograms as native histograms (Prometheus 2.40+)
+}
+
+// TracingConfig configures trace context propagation
+type TracingConfig struct {
+	Enabled bool `mapstructure:"enabled"` // Continue incoming W3C traces and attach their trace IDs to latency exemplars
+}
+
+// AuditConfig configures the audit trail of analysis requests
+type AuditConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Sink        string `mapstructure:"sink"`          // file or redis
+	Path        string `mapstructure:"path"`          // JSONL file of the file sink, rotated like file log outputs
+	RedisStream string `mapstructure:"redis_stream"`  // Stream of the redis sink, on the cache's Redis server
+	RedisMaxLen int64  `mapstructure:"redis_max_len"` // Approximate number of events the stream keeps
+	BufferSize  int    `mapstructure:"buffer_size"`   // Events held in memory; further events are dropped and counted
+}
+
+// DigestConfig batches the analyses of monitored URLs into one summary per destination and day
+type DigestConfig struct {
+	Enabled      bool                `mapstructure:"enabled"`
+	Schedule     string              `mapstructure:"schedule"`     // Daily send time, HH:MM in UTC
+	BaseURL      string              `mapstructure:"base_url"`     // Public URL of this server, for links to the stored results
+	RedisPrefix  string              `mapstructure:"redis_prefix"` // Buffered events live under this prefix on the cache's Redis server
+	Destinations []DigestDestination `mapstructure:"destinations"`
+	SMTP         SMTPConfig          `mapstructure:"smtp"`
+}
+
+// JobsConfig tunes the runner of background jobs shared by asynchronous features
+type JobsConfig struct {
+	Concurrency  int           `mapstructure:"concurrency"`   // Jobs run at once
+	Timeout      time.Duration `mapstructure:"timeout"`       // Deadline of each job
+	MaxQueued    int           `mapstructure:"max_queued"`    // Jobs waiting to run; further jobs are rejected; 0 means unlimited
+	Retention    time.Duration `mapstructure:"retention"`     // How long finished jobs can still be read
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"` // Time running jobs get to finish on shutdown
+	Store        string        `mapstructure:"store"`         // memory, or redis to resume jobs after a restart
+	RedisPrefix  string        `mapstructure:"redis_prefix"`  // Jobs live under this prefix on the cache's Redis server
+}
+
+// DigestDestination receives the digest of the analyses of its URLs
+type DigestDestination struct {
+	Name    string   `mapstructure:"name"`
+	URLs    []string `mapstructure:"urls"`    // Monitored URLs, as analyzed
+	Webhook string   `mapstructure:"webhook"` // The digest is posted here as JSON
+	Email   []string `mapstructure:"email"`   // The digest is mailed to these recipients through smtp
+}
+
+// SMTPConfig is the mail server digests are sent through
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
 }
 
 type PrometheusConfig struct {
-	Buckets []float64
+	Buckets []float64 `mapstructure:"buckets"`
 }
 
 type RateLimitConfig struct {
-	Enabled           bool
-	RequestsPerMinute float64
+	Enabled           bool           `mapstructure:"enabled"`
+	RequestsPerMinute float64        `mapstructure:"requests_per_minute"`
+	PerTargetPerMinute float64       `mapstructure:"per_target_per_minute"` // Pages fetched per target host, across clients; 0 disables
+	Costs             RateLimitCosts `mapstructure:"costs"`
+	Store             string         `mapstructure:"store"`         // memory, or redis to keep client budgets on the cache's Redis server across restarts
+	RedisPrefix       string         `mapstructure:"redis_prefix"`  // Key prefix of the redis store
+	SnapshotPath      string         `mapstructure:"snapshot_path"` // With the memory store, budgets are saved here on shutdown and restored on start; empty disables
+	Egress            EgressConfig   `mapstructure:"egress"`
+}
+
+// EgressConfig counts the bytes fetched for each client per UTC day, page bodies and link
+// check responses alike, in the rate limit store
+type EgressConfig struct {
+	Enabled    bool  `mapstructure:"enabled"`
+	DailyBytes int64 `mapstructure:"daily_bytes"` // New analyses of clients past it fail with QUOTA_EXCEEDED; 0 only counts
+}
+
+// RateLimitCosts are the tokens a request to each route consumes
+type RateLimitCosts struct {
+	Analyze     int `mapstructure:"analyze"`
+	AnalyzeHTML int `mapstructure:"analyze_html"`
+	Analyses    int `mapstructure:"analyses"`
+	Validate    int `mapstructure:"validate"`
+}
+
+// ReadinessConfig configures the checks of the readiness endpoint
+type ReadinessConfig struct {
+	Outbound OutboundProbeConfig `mapstructure:"outbound"`
+}
+
+// OutboundProbeConfig configures the probe of outbound connectivity: a HEAD request to a canary URL
+type OutboundProbeConfig struct {
+	CanaryURL string        `mapstructure:"canary_url"` // Empty disables the probe
+	Timeout   time.Duration `mapstructure:"timeout"`
+	CacheTTL  time.Duration `mapstructure:"cache_ttl"` // How long a probe result answers readiness polls
+}
+
+// SnapshotsConfig keeps the HTML of pages analyzed with options.store_snapshot, so a disputed
+// analysis can be checked against what was fetched
+type SnapshotsConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	MaxBytes    int64         `mapstructure:"max_bytes"`    // Larger pages are not stored, counted before compression
+	TTL         time.Duration `mapstructure:"ttl"`          // How long snapshots are kept, usually shorter than cache.ttl
+	RedisPrefix string        `mapstructure:"redis_prefix"` // Snapshots live under this prefix on the cache's Redis server
 }
 
 type CORSConfig struct {
-	AllowedOrigins []string
-	AllowedMethods []string
-	AllowedHeaders []string
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
 }
 
 
@@ -103,24 +398,92 @@ func setDefaults() {
 	viper.SetDefault("server.port", constants.DefaultServerPort)
 	viper.SetDefault("server.timeout", constants.DefaultServerTimeout)
 	viper.SetDefault("server.mode", constants.DefaultServerMode)
+	viper.SetDefault("server.web_dir", "")
 
 	// Cache defaults
 	viper.SetDefault("cache.enabled", true)
 	viper.SetDefault("cache.ttl", constants.DefaultCacheTTL)
+	viper.SetDefault("cache.ttl_jitter", constants.DefaultCacheTTLJitter)
+	viper.SetDefault("cache.duplicates.enabled", false)
+	viper.SetDefault("cache.duplicates.max_urls", constants.DefaultDuplicateIndexMaxURLs)
+	viper.SetDefault("cache.temporary_failures.threshold", constants.DefaultTemporaryFailureThreshold)
+	viper.SetDefault("cache.temporary_failures.ttl", constants.DefaultTemporaryFailureTTL)
 	viper.SetDefault("cache.redis.host", constants.DefaultRedisHost)
 	viper.SetDefault("cache.redis.port", constants.DefaultRedisPort)
 	viper.SetDefault("cache.redis.db", constants.DefaultRedisDB)
 	viper.SetDefault("cache.redis.password", "")
+	viper.SetDefault("cache.dedicated_db", false)
+
+	// Storage defaults
+	viper.SetDefault("storage.enabled", false)
+	viper.SetDefault("storage.driver", constants.DefaultStorageDriver)
+	viper.SetDefault("storage.dsn", constants.DefaultStorageDSN)
 
 	// Analyzer defaults
 	viper.SetDefault("analyzer.max_links", constants.DefaultMaxLinks)
 	viper.SetDefault("analyzer.link_timeout", constants.DefaultLinkTimeout)
 	viper.SetDefault("analyzer.max_workers", constants.DefaultMaxWorkers)
+	viper.SetDefault("analyzer.link_pool_size", constants.DefaultLinkPoolSize)
+	viper.SetDefault("analyzer.section_concurrency", 0)
 	viper.SetDefault("analyzer.max_redirects", constants.DefaultMaxRedirects)
+	viper.SetDefault("analyzer.link_max_redirects", constants.DefaultLinkMaxRedirects)
+	viper.SetDefault("analyzer.max_external_hosts", constants.DefaultMaxExternalHosts)
+	viper.SetDefault("analyzer.max_body_bytes", constants.DefaultMaxBodyBytes)
+	viper.SetDefault("analyzer.min_body_bytes", constants.DefaultMinBodyBytes)
+	viper.SetDefault("analyzer.analysis_timeout", constants.DefaultAnalysisTimeout)
+	viper.SetDefault("analyzer.timeouts.page.connect", constants.DefaultPageConnectTimeout)
+	viper.SetDefault("analyzer.timeouts.page.tls_handshake", constants.DefaultPageTLSHandshakeTimeout)
+	viper.SetDefault("analyzer.timeouts.page.response_header", constants.DefaultPageResponseHeaderTimeout)
+	viper.SetDefault("analyzer.timeouts.links.connect", constants.DefaultLinkConnectTimeout)
+	viper.SetDefault("analyzer.timeouts.links.tls_handshake", constants.DefaultLinkTLSHandshakeTimeout)
+	viper.SetDefault("analyzer.timeouts.links.response_header", constants.DefaultLinkResponseHeaderTimeout)
+	viper.SetDefault("analyzer.js_rendering.enabled", false)
+	viper.SetDefault("analyzer.js_rendering.timeout", constants.DefaultRenderTimeout)
+	viper.SetDefault("analyzer.js_rendering.max_concurrent", constants.DefaultRenderMaxConcurrent)
+	viper.SetDefault("analyzer.seo.weights", constants.DefaultSEOWeights)
+	viper.SetDefault("analyzer.seo.ignore_trailing_slash", true)
+	viper.SetDefault("analyzer.seo.canonical_similarity_threshold", constants.DefaultCanonicalSimilarityThreshold)
+	viper.SetDefault("analyzer.bot_protection.action", constants.DefaultBotProtectionAction)
+	viper.SetDefault("analyzer.mobile.user_agent", constants.DefaultMobileUserAgent)
+	viper.SetDefault("analyzer.target_policy.mode", constants.DefaultTargetPolicyMode)
+	viper.SetDefault("analyzer.target_policy.allowed_ports", constants.DefaultTargetPolicyAllowedPorts)
+	viper.SetDefault("analyzer.target_policy.allow_private_resolve", false)
+	viper.SetDefault("analyzer.soft_404.max_text_length", constants.DefaultSoft404MaxTextLength)
+	viper.SetDefault("analyzer.soft_404.phrases", constants.DefaultSoft404Phrases)
+	viper.SetDefault("analyzer.soft_404.error_title_ttl", constants.DefaultSoft404ErrorTitleTTL)
+	viper.SetDefault("analyzer.link_circuit.enabled", true)
+	viper.SetDefault("analyzer.link_circuit.failure_threshold", constants.DefaultLinkCircuitFailureThreshold)
+	viper.SetDefault("analyzer.link_circuit.shared_ttl", 0)
+	viper.SetDefault("analyzer.link_sampling.enabled", false)
+	viper.SetDefault("analyzer.budget.max_outbound_requests_per_analysis", constants.DefaultMaxOutboundRequestsPerAnalysis)
+	viper.SetDefault("analyzer.budget.global_requests_per_second", constants.DefaultGlobalRequestsPerSecond)
+	viper.SetDefault("analyzer.budget.global_burst", 0)
+	viper.SetDefault("analyzer.external_domains.mode", constants.DefaultExternalDomainMode)
+	viper.SetDefault("analyzer.external_domains.top_n", constants.DefaultExternalDomainsTopN)
+	viper.SetDefault("analyzer.internal_scope", constants.DefaultInternalScope)
+	viper.SetDefault("analyzer.internal_suffixes", []string{})
+	viper.SetDefault("analyzer.anchor_text.generic_phrases", constants.DefaultGenericLinkTexts)
+	viper.SetDefault("analyzer.max_dom_elements", constants.DefaultMaxDOMElements)
+	viper.SetDefault("analyzer.max_dom_anchors", constants.DefaultMaxDOMAnchors)
+	viper.SetDefault("analyzer.max_dom_forms", constants.DefaultMaxDOMForms)
+	viper.SetDefault("analyzer.scope_unmatched", constants.ScopeUnmatchedDocument)
 
 	// Rate limit defaults
 	viper.SetDefault("rate_limit.enabled", constants.DefaultRateLimitEnabled)
 	viper.SetDefault("rate_limit.requests_per_minute", constants.DefaultRequestsPerMinute)
+	viper.SetDefault("rate_limit.per_target_per_minute", constants.DefaultPerTargetPerMinute)
+	viper.SetDefault("rate_limit.costs.analyze", constants.DefaultRateLimitCost)
+	viper.SetDefault("rate_limit.costs.analyze_html", constants.DefaultRateLimitCost)
+	viper.SetDefault("rate_limit.costs.analyses", constants.DefaultRateLimitCost)
+	viper.SetDefault("rate_limit.costs.validate", constants.DefaultRateLimitCost)
+	viper.SetDefault("rate_limit.store", constants.DefaultRateLimitStore)
+	viper.SetDefault("rate_limit.redis_prefix", constants.DefaultRateLimitRedisPrefix)
+	viper.SetDefault("rate_limit.snapshot_path", "")
+	viper.SetDefault("rate_limit.egress.enabled", false)
+	viper.SetDefault("rate_limit.egress.daily_bytes", 0)
+
+	// Admin defaults
+	viper.SetDefault("admin.token", "")
 
 	// CORS defaults
 	viper.SetDefault("cors.allowed_origins", []string{"*"})
@@ -130,8 +493,53 @@ func setDefaults() {
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "console")
+	viper.SetDefault("logging.outputs", []map[string]string{{"path": constants.LogOutputStdout}})
+	viper.SetDefault("logging.rotation.max_size_mb", constants.DefaultLogMaxSizeMB)
+	viper.SetDefault("logging.rotation.max_backups", constants.DefaultLogMaxBackups)
+	viper.SetDefault("logging.rotation.max_age_days", constants.DefaultLogMaxAgeDays)
+	viper.SetDefault("logging.access.enabled", true)
+	viper.SetDefault("logging.access.sample_rate", constants.DefaultAccessLogSampleRate)
+	viper.SetDefault("logging.access.redact_query_params", constants.DefaultRedactedQueryParams)
 
 	// Metrics defaults
 	viper.SetDefault("metrics.enabled", true)
 	viper.SetDefault("metrics.prometheus.buckets", []float64{0.1, 0.5, 1, 2, 5, 10})
+	viper.SetDefault("metrics.native_histograms", false)
+
+	// Tracing defaults
+	viper.SetDefault("tracing.enabled", false)
+
+	// Audit defaults
+	viper.SetDefault("audit.enabled", false)
+	viper.SetDefault("audit.sink", constants.DefaultAuditSink)
+	viper.SetDefault("audit.path", constants.DefaultAuditPath)
+	viper.SetDefault("audit.redis_stream", constants.DefaultAuditRedisStream)
+	viper.SetDefault("audit.redis_max_len", constants.DefaultAuditRedisMaxLen)
+	viper.SetDefault("audit.buffer_size", constants.DefaultAuditBufferSize)
+
+	// Digest defaults
+	viper.SetDefault("digest.enabled", false)
+	viper.SetDefault("digest.schedule", constants.DefaultDigestSchedule)
+	viper.SetDefault("digest.redis_prefix", constants.DefaultDigestRedisPrefix)
+	viper.SetDefault("digest.smtp.port", constants.DefaultSMTPPort)
+
+	// Job defaults
+	viper.SetDefault("jobs.concurrency", constants.DefaultJobConcurrency)
+	viper.SetDefault("jobs.timeout", constants.DefaultJobTimeout)
+	viper.SetDefault("jobs.max_queued", constants.DefaultJobMaxQueued)
+	viper.SetDefault("jobs.retention", constants.DefaultJobRetention)
+	viper.SetDefault("jobs.drain_timeout", constants.DefaultJobDrainTimeout)
+	viper.SetDefault("jobs.store", constants.DefaultJobStore)
+	viper.SetDefault("jobs.redis_prefix", constants.DefaultJobRedisPrefix)
+
+	// Readiness defaults
+	viper.SetDefault("readiness.outbound.canary_url", "")
+	viper.SetDefault("readiness.outbound.timeout", constants.DefaultOutboundProbeTimeout)
+	viper.SetDefault("readiness.outbound.cache_ttl", constants.DefaultOutboundProbeCacheTTL)
+
+	// Snapshot defaults
+	viper.SetDefault("snapshots.enabled", false)
+	viper.SetDefault("snapshots.max_bytes", constants.DefaultSnapshotMaxBytes)
+	viper.SetDefault("snapshots.ttl", constants.DefaultSnapshotTTL)
+	viper.SetDefault("snapshots.redis_prefix", constants.DefaultSnapshotRedisPrefix)
 } 
\ No newline at end of file
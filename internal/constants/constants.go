@@ -12,6 +12,7 @@ const (
 const (
 	DefaultServerPort    = 8080
 	DefaultServerMode    = "debug"
+	ServerModeDebug      = "debug"
 	DefaultServerTimeout = 30 * time.Second
 )
 
@@ -22,6 +23,69 @@ const (
 	DefaultRedisDB        = 0
 	DefaultRedisHost      = "redis"
 	CacheConnectionTimeout = 5 * time.Second
+	CacheOpGet             = "get"
+	CacheOpSet             = "set"
+	CacheOpDelete          = "delete"
+	CacheOpScan            = "scan"
+	CacheOpGetMulti        = "get_multi"
+	CacheOpSetMulti        = "set_multi"
+	CacheKeyPrefix         = "webpage:"
+	DefaultTemporaryFailureThreshold = 0.5 // Share of temporary link failures above which the cache TTL is shortened
+	DefaultTemporaryFailureTTL       = 5 * time.Minute
+	DefaultCacheTTLJitter            = 0.1 // Entries expire within ±10% of their TTL, so entries cached together expire apart
+	CacheScanBatchSize     = 100
+	CacheStatsMaxScannedKeys = 100000 // Keys counted by the cache stats before the count is reported as capped
+	CacheBackendRedis      = "redis"
+	CacheBackendNoOp       = "noop"
+	CacheEntriesMethodScan   = "scan"
+	CacheEntriesMethodDBSize = "dbsize"
+	DuplicateIndexKeyPrefix  = "webpage-duplicates:" // Outside the webpage:* keyspace of the cached entries
+	DuplicateIndexPageKey    = "page:"
+	DuplicateKindTitle       = "title"
+	DuplicateKindDescription = "meta_description"
+	DefaultDuplicateIndexMaxURLs = 100 // URLs indexed per title or meta description of a site
+)
+
+// Storage constants
+const (
+	StorageDriverPostgres   = "postgres"
+	StorageDriverSQLite     = "sqlite"
+	DefaultStorageDriver    = StorageDriverSQLite
+	DefaultStorageDSN       = "file:analyses.db"
+	StorageConnectTimeout   = 5 * time.Second
+	StorageWriteTimeout     = 5 * time.Second
+	StorageReadBatchSize    = 500 // Rows an export reads per query, so it never holds a connection for long
+	DefaultAnalysesPageSize = 20
+	MaxAnalysesPageSize     = 100
+)
+
+// Export constants
+const (
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatCSV     = "csv"
+	ContentTypeJSON     = "application/json"
+	ContentTypeNDJSON   = "application/x-ndjson"
+	ContentTypeCSV      = "text/csv; charset=utf-8"
+	ContentTypePDF      = "application/pdf"
+	ExportFlushInterval = 100              // Rows written between flushes to the client
+	ExportWriteTimeout  = 30 * time.Second // Write deadline per flushed chunk, replacing the server timeout
+)
+
+// Kinds of content an analysis reports on, and the limits of the analysis of XML documents
+const (
+	ContentKindHTML    = "html"
+	ContentKindSitemap = "sitemap"
+	ContentKindFeed    = "feed"
+	FeedFormatRSS      = "rss"
+	FeedFormatAtom     = "atom"
+	SitemapMaxEntries  = 50000 // The most entries the sitemap protocol allows in one file
+	FeedMaxItems       = 10000
+)
+
+// Response formats of analyses listed by GET /api/v1/capabilities
+const (
+	ResponseFormatJSON = "json"
+	ResponseFormatPDF  = "pdf" // GET /api/v1/analyze/report.pdf
 )
 
 // Analyzer constants
@@ -29,30 +93,593 @@ const (
 	DefaultMaxLinks     = 100
 	DefaultLinkTimeout  = 10 * time.Second
 	DefaultInternalLinkTimeout = 3 * time.Second // Shorter timeout for internal links
+	DefaultPageConnectTimeout        = 5 * time.Second  // Stage timeouts of the page fetch
+	DefaultPageTLSHandshakeTimeout   = 5 * time.Second
+	DefaultPageResponseHeaderTimeout = 10 * time.Second
+	DefaultLinkConnectTimeout        = 2 * time.Second // Stage timeouts of link checks, failing unreachable hosts fast
+	DefaultLinkTLSHandshakeTimeout   = 3 * time.Second
+	DefaultLinkResponseHeaderTimeout = 5 * time.Second
 	DefaultMaxWorkers   = 20
+	DefaultLinkPoolSize = 200 // Link check workers shared by all analyses
 	DefaultMaxRedirects = 0
+	DefaultLinkMaxRedirects = 5 // Redirect hops followed per link check
+	DefaultMaxExternalHosts = 0 // Distinct external hosts link checks may contact per analysis; 0 means unlimited
 	DefaultLoginFormThreshold = 10 // Minimum score required to consider a form as login form
+	DefaultMaxBodyBytes = 10 << 20 // Maximum size of fetched or submitted HTML (10 MiB)
+	DefaultMinBodyBytes = 64 // Fetched pages below this size are reported as empty documents
+	MaxBodyOverheadBytes = 64 << 10 // Allowance for JSON/multipart framing around submitted HTML
+	DefaultAnalysisTimeout = 25 * time.Second // Per-analysis deadline, below the default server write timeout
+	LinkSkipReasonDeadline = "deadline"
+	LinkSkipReasonCircuitOpen = "circuit_open" // Reported inaccessible without checking
+	DefaultLinkCircuitFailureThreshold = 3 // Consecutive failures that open a host's circuit
+	MaxRetryAfter = 24 * time.Hour // Upper bound for Retry-After values passed through from targets
+	LinkSkipReasonBudget = "budget" // The per-analysis outbound request budget was exhausted
+	LinkSkipReasonPolicy = "policy" // The target policy blocks the link's host
+	LinkSkipReasonConfig = "config" // An analyzer.link_check_overrides entry skips the link's host
+	LinkSkipReasonHostCap = "host_cap" // The link's external host is beyond analyzer.max_external_hosts
+	DefaultMaxDOMElements = 50000 // Larger documents get bounded per-element analyses
+	DefaultMaxDOMAnchors  = 5000  // Anchors classified in documents over the element limit
+	DefaultMaxDOMForms    = 100   // Forms scanned for login fields in documents over the element limit
+)
+
+// Analysis sections, in the order they run
+const (
+	SectionHTMLVersion   = "html_version"
+	SectionTitle         = "title"
+	SectionHeadings      = "headings"
+	SectionLoginForm     = "login_form"
+	SectionForms         = "forms"
+	SectionConsentBanner = "consent_banner"
+	SectionInterstitial  = "interstitial"
+	SectionPaywall       = "paywall"
+	SectionDocumentIssues = "document_issues"
+	SectionAccessibility = "accessibility"
+	SectionCSPReadiness  = "csp_readiness"
+	SectionResources     = "resources"
+	SectionResourceHints = "resource_hints"
+	SectionLegacyIE      = "legacy_ie"
+	SectionLinks         = "links"
+	SectionSEO           = "seo"
+)
+
+// JavaScript rendering constants
+const (
+	DefaultRenderTimeout         = 15 * time.Second
+	DefaultRenderMaxConcurrent   = 4
+	RenderConnectTimeout         = 5 * time.Second
+	RenderNetworkIdleWait        = 3 * time.Second // Upper bound on waiting for network idle after load
+	RenderNetworkIdleEvent       = "networkIdle"
+	RenderOutcomeSuccess         = "success"
+	RenderOutcomeTimeout         = "timeout"
+	RenderOutcomeUnavailable     = "unavailable"
+	RenderOutcomeError           = "error"
+	CacheVariantRenderJS         = "render_js"
+	CacheVariantSkipLinkCheck    = "skip_link_check"
+	CacheVariantOriginChecks     = "origin_checks"
+	CacheVariantAuth             = "auth" // Followed by a hash of the credentials
+	CacheVariantCompareMobile    = "compare_mobile"
+	CacheVariantAcceptLanguage   = "lang" // Followed by the normalized Accept-Language value
+	CacheVariantHeadingText      = "heading_text"
+	CacheVariantLinkDetails      = "link_details"
+	CacheVariantFetchManifest    = "fetch_manifest"
+	CacheVariantRawLinks         = "raw_links"
+	CacheVariantSamplingSeed     = "sampling_seed" // Followed by the seed
+	CacheVariantResolve          = "resolve"       // Followed by the pinned IP address
+	CacheVariantCompareCanonical = "compare_canonical"
+	CacheVariantEgress           = "egress" // Followed by the proxy pool name
+	CacheVariantScopeSelector    = "scope"  // Followed by the escaped selector
+)
+
+// Canonical similarity constants
+const (
+	CanonicalShingleWords               = 3   // Words per shingle of the text compared with SimHash
+	DefaultCanonicalSimilarityThreshold = 0.8 // Scores from this on report the canonical as serving the same content
+)
+
+// SupportedProxySchemes are the schemes of the egress proxies of analyzer.proxies
+var SupportedProxySchemes = []string{"http", "https", "socks5"}
+
+// Mobile comparison constants
+const (
+	DefaultMobileUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
 )
 
+// Target policy constants
+const (
+	TargetPolicyModeEnforce        = "enforce"
+	TargetPolicyModeDryRun         = "dry_run" // Would-be blocks are logged, counted and reported, never blocked
+	DefaultTargetPolicyMode        = TargetPolicyModeEnforce
+	TargetPolicyRuleBlocked        = "blocked_hosts" // Reported as blocked_hosts:<host>
+	TargetPolicyRuleNotAllowed     = "allowed_hosts" // The host is not in a non-empty allow list
+	TargetPolicyRulePortNotAllowed = "allowed_ports" // The port is not in a non-empty allow list
+)
+
+// DefaultTargetPolicyAllowedPorts are the ports analyses connect to unless configured otherwise
+var DefaultTargetPolicyAllowedPorts = []int{80, 443, 8080, 8443}
+
+// Soft 404 constants
+const (
+	DefaultSoft404MaxTextLength = 1000 // Visible characters of a page short enough to be an error page
+	DefaultSoft404ErrorTitleTTL = time.Hour
+	Soft404MaxErrorTitles       = 10000 // Hosts whose error page title is remembered at once
+	Soft404ReasonErrorPhrase    = "error_phrase"
+	Soft404ReasonErrorTitle     = "error_title"
+)
+
+// DefaultSoft404Phrases mark a short page as a "not found" page, matched case-insensitively
+var DefaultSoft404Phrases = []string{
+	"404",
+	"not found",
+	"page doesn't exist",
+	"page does not exist",
+	"no longer available",
+	"página no encontrada",
+	"página não encontrada",
+	"page introuvable",
+	"seite nicht gefunden",
+	"pagina non trovata",
+	"pagina niet gevonden",
+	"nie znaleziono strony",
+	"страница не найдена",
+	"ページが見つかりません",
+	"页面不存在",
+}
+
+// Outbound request budget constants
+const (
+	DefaultMaxOutboundRequestsPerAnalysis = 0 // Unlimited
+	DefaultGlobalRequestsPerSecond        = 0 // No global limit
+	OutboundOutcomeSent                   = "sent"
+	OutboundOutcomeBudgetExhausted        = "budget_exhausted"
+	FetchSourcePage                       = "page" // Page fetches, the mobile variant and web app manifest included
+	FetchSourceLink                       = "link" // Link check responses
+	LinkCheckDrainBytes                   = 4096   // Link check response bytes read so the connection can be reused
+)
+
+// Accessibility rule identifiers
+const (
+	A11yRuleImageMissingAlt   = "image-missing-alt"
+	A11yRuleInputMissingLabel = "input-missing-label"
+	A11yRuleLinkEmptyText     = "link-empty-text"
+	A11yRuleLinkGenericText   = "link-generic-text"
+	A11yRuleMissingLang       = "document-missing-lang"
+	A11yRuleButtonMissingName = "button-missing-name"
+	A11yRuleDuplicateID       = "duplicate-id"
+)
+
+// DefaultGenericLinkTexts lists link texts that say nothing about the link target, matched
+// case-insensitively against the whole text without surrounding punctuation
+var DefaultGenericLinkTexts = []string{
+	"click here", "here", "click", "more", "read more", "learn more", "link", "this link", "go",
+	"hier klicken", "hier", "mehr", "weiterlesen", "mehr erfahren",
+	"cliquez ici", "ici", "plus", "lire la suite", "en savoir plus",
+	"haga clic aquí", "clic aquí", "aquí", "más", "leer más",
+	"clique aqui", "aqui", "leia mais", "saiba mais",
+	"clicca qui", "qui", "leggi di più",
+	"klik hier", "lees meer",
+}
+
+// Form audit constants
+const (
+	FormsMaxReported = 50 // Forms listed under forms.items; the flags cover every form scanned
+)
+
+// Link sampling constants
+const (
+	LinkSamplingZ95         = 1.96 // Standard normal quantile of a 95% confidence interval
+	LinkSamplingMinReliable = 30   // Fewer links checked make the estimate unreliable
+)
+
+// Resource hint constants
+const (
+	ResourceHintPreload      = "preload"
+	ResourceHintPrefetch     = "prefetch"
+	ResourceHintPreconnect   = "preconnect"
+	ResourceHintDNSPrefetch  = "dns-prefetch"
+	ResourceHintsMaxReported = 50 // Entries listed per finding; the counts cover every hint
+)
+
+// Interstitial heuristic constants
+const (
+	InterstitialRuleFullViewportOverlay = "full_viewport_overlay" // Inline style fixes an element over the whole viewport
+	InterstitialRuleOverlayMarkup       = "overlay_markup"        // Visible element whose class or ID names a modal, overlay, popup or interstitial
+	InterstitialRuleScrollLockStyle     = "scroll_lock_style"     // Inline overflow:hidden on html or body
+	InterstitialRuleScrollLockClass     = "scroll_lock_class"     // Class on html or body that modal libraries set to lock scrolling
+	InterstitialConfidenceNone          = "none"
+	InterstitialConfidenceLow           = "low"
+	InterstitialConfidenceMedium        = "medium"
+	InterstitialConfidenceHigh          = "high"
+	InterstitialSuspectedScore          = 2  // Rule weights from which an interstitial is suspected, at medium confidence
+	InterstitialHighScore               = 3  // Rule weights from which confidence is high
+	InterstitialMaxEvidence             = 10 // Matched elements reported
+)
+
+// What links, headings and images are taken from when options.scope_selector matches no element
+const (
+	ScopeUnmatchedDocument = "document" // The whole document, as without a selector
+	ScopeUnmatchedEmpty    = "empty"    // Nothing, so those sections report no links, headings or images
+)
+
+// Paywall heuristic constants
+const (
+	PaywallSignalProviderScript = "provider_script"         // A script or element of a paywall provider such as Piano
+	PaywallSignalNotFree        = "not_accessible_for_free" // JSON-LD declares isAccessibleForFree false
+	PaywallSignalClassMarker    = "class_marker"            // Element whose class marks gated or metered content
+	PaywallSignalLoginForm      = "login_form"              // A login form on the article page
+	PaywallSignalLowWordCount   = "low_word_count"          // Fewer visible article words than PaywallMaxVisibleWords
+	PaywallProviderPiano        = "piano"
+	PaywallProviderTinypass     = "tinypass"
+	PaywallProviderLaterPay     = "laterpay"
+	PaywallMaxVisibleWords      = 200 // Visible article words below which the body looks truncated
+)
+
+// PaywallClassMarkers are the class names, lowercased, of the containers paywalls gate or meter
+// content with, such as the cssSelector of a publisher's paywalled-content markup
+var PaywallClassMarkers = []string{"paywall", "meteredcontent", "metered-content", "subscriber-only", "subscribers-only", "premium-content"}
+
+// Legacy IE constants
+const (
+	LegacyIEMaxConditions = 20 // Distinct conditional comment conditions reported
+)
+
+// LegacyIEPolyfills are the IE-only polyfills recognized by script file name, without
+// the .js or .min.js suffix
+var LegacyIEPolyfills = []string{
+	"html5shiv", "html5shiv-printshiv", "html5shim", "respond", "selectivizr",
+	"excanvas", "css3-mediaqueries", "es5-shim", "ie7", "ie8", "ie9", "pie",
+}
+
+// DocumentIssuesMaxDuplicateIDs bounds the duplicate id values reported, most repeated first
+const DocumentIssuesMaxDuplicateIDs = 50
+
+// LinkMaxBrokenFragments bounds the missing fragment ids reported, most linked first
+const LinkMaxBrokenFragments = 50
+
+// Pagination detection
+var (
+	// PaginationQueryParams name the page number in the query of numbered page links, as in ?page=2
+	// and ?paged=2; a bare ?p= is left out, as it usually names a post or product
+	PaginationQueryParams = []string{"page", "pg", "paged", "pagenum", "page_number"}
+	// PaginationPathSegments precede the page number in the path of numbered page links, as in /page/2
+	PaginationPathSegments = []string{"page"}
+	// InfiniteScrollAttributes mark the containers of infinite-scroll libraries
+	InfiniteScrollAttributes = []string{"data-infinite-scroll", "data-infinite-scroll-container", "data-infinite"}
+	// InfiniteScrollClassMarkers are found in the class names of infinite-scroll containers and
+	// of the sentinel elements an IntersectionObserver watches to load the next page
+	InfiniteScrollClassMarkers = []string{"infinite-scroll", "infinite_scroll", "infinitescroll", "scroll-sentinel", "load-more-sentinel", "pagination-sentinel"}
+)
+
+// Suspicious link reasons and the samples reported
+const (
+	SuspiciousLinkPunycodeHost  = "punycode_host"
+	SuspiciousLinkMixedScript   = "mixed_script"
+	SuspiciousLinkTextMismatch  = "text_mismatch"
+	SuspiciousLinkMaxSamples    = 50
+	SuspiciousLinkMaxTextLength = 200 // Characters of anchor text kept in a sample
+)
+
+// Anchor text issues, in the order offenders are reported, and the offenders listed
+const (
+	AnchorTextEmpty           = "empty"             // No text, image alt or ARIA label
+	AnchorTextImageMissingAlt = "image_missing_alt" // Only images, none with alt text
+	AnchorTextGeneric         = "generic"           // One of analyzer.anchor_text.generic_phrases
+	AnchorTextBareURL         = "bare_url"          // The text is a URL or domain
+	AnchorTextMaxOffenders    = 50
+	AnchorTextMaxTextLength   = 200 // Characters of anchor text kept in an offender
+)
+
+// LinkMaxRedirectsReported bounds the redirected links listed per analysis
+const LinkMaxRedirectsReported = 100
+
+// LinkMaxOffOriginRedirectSamples bounds the internal links listed for redirecting off the site
+const LinkMaxOffOriginRedirectSamples = 20
+
+// External domain breakdown constants
+const (
+	ExternalDomainModeHost       = "host"              // Count links by their raw host
+	ExternalDomainModeRegistered = "registered_domain" // Group subdomains under the domain registered below the public suffix
+	DefaultExternalDomainMode    = ExternalDomainModeRegistered
+	DefaultExternalDomainsTopN   = 20
+)
+
+// Internal link scope constants
+const (
+	InternalScopeExactHost        = "exact_host"        // Only links to the page's host and port are internal
+	InternalScopeRegisteredDomain = "registered_domain" // Links to any subdomain of the page's registered domain are internal
+	InternalScopeSuffixes         = "suffixes"          // Links between hosts under analyzer.internal_suffixes are internal
+	DefaultInternalScope          = InternalScopeRegisteredDomain
+)
+
+// Bot protection detection constants
+const (
+	BotProtectionActionFail    = "fail" // Reject the analysis with a BOT_PROTECTION error
+	BotProtectionActionTag     = "tag"  // Return the result flagged as a challenge page without caching it
+	DefaultBotProtectionAction = BotProtectionActionFail
+	BotChallengeMaxLinks       = 3 // Captcha pages with at most this many links are treated as challenges
+	BotProviderCloudflare      = "cloudflare"
+	BotProviderAkamai          = "akamai"
+	BotProviderDataDome        = "datadome"
+	BotProviderPerimeterX      = "perimeterx"
+	BotProviderRecaptcha       = "recaptcha"
+	BotProviderHCaptcha        = "hcaptcha"
+)
+
+// Content encodings the page fetch negotiates and decodes itself
+const (
+	EncodingGzip            = "gzip"
+	EncodingDeflate         = "deflate"
+	AcceptEncodingSupported = "gzip, deflate"
+	AltSvcHTTP3Prefix       = "h3" // Matches h3 and draft versions such as h3-29
+	FetchTruncatedBySizeLimit = "size_limit" // analyzer.max_body_bytes
+	FetchTruncatedByTimeout   = "timeout"    // analyzer.timeouts.page.overall or the analysis deadline
+)
+
+// PWA constants
+const (
+	MaxManifestBytes       = 64 << 10 // Larger web app manifests are not parsed
+	ManifestDisplayBrowser = "browser" // Display mode of manifests declaring none
+)
+
+// InstallableDisplayModes are the manifest display modes of apps browsers offer to install
+var InstallableDisplayModes = []string{"fullscreen", "standalone", "minimal-ui"}
+
+// Origin check constants
+const (
+	WWWPrefix = "www."
+)
+
+// URL validation constants
+const (
+	ValidationTimeout        = 2 * time.Second // Deadline of all checks of a validation
+	ValidationCheckLength    = "length"
+	ValidationCheckScheme    = "scheme"
+	ValidationCheckSyntax    = "syntax"
+	ValidationCheckHost      = "host"
+	ValidationCheckPolicy    = "policy"
+	ValidationCheckDNS       = "dns"
+	ValidationCheckRobots    = "robots"
+	ValidationCheckReachable = "reachability"
+	RobotsTxtPath            = "/robots.txt"
+	MaxRobotsTxtBytes        = 500 << 10 // Rules past this size are ignored, as crawlers do
+)
+
+// Cookie audit constants
+const (
+	CookieIssueMissingSecure        = "missing_secure"                // Set by an https page without Secure
+	CookieIssueSameSiteNoneInsecure = "same_site_none_without_secure" // Rejected by browsers
+	CookieIssueLongExpiry           = "long_expiry"
+	CookieMaxLifetime               = 400 * 24 * time.Hour // Browsers cap longer lifetimes at 400 days
+)
+
+// Consent banner detection constants
+const (
+	ConsentProviderOneTrust    = "onetrust"
+	ConsentProviderCookiebot   = "cookiebot"
+	ConsentProviderQuantcast   = "quantcast_choice"
+	ConsentProviderDidomi      = "didomi"
+	ConsentProviderTrustArc    = "trustarc"
+	ConsentProviderIABTCF      = "iab_tcf" // A TCF-compliant CMP that is not otherwise recognized
+	ConsentProviderGeneric     = "generic"
+	ConsentBannerMaxTextLength = 1500 // Larger elements are page content rather than a banner
+)
+
+// SEO rule identifiers, also used as keys of analyzer.seo.weights
+const (
+	SEORuleMissingTitle           = "missing_title"
+	SEORuleTitleTooLong           = "title_too_long"
+	SEORuleMissingMetaDescription = "missing_meta_description"
+	SEORuleMetaDescriptionLength  = "meta_description_length"
+	SEORuleMissingH1              = "missing_h1"
+	SEORuleMultipleH1             = "multiple_h1"
+	SEORuleImagesMissingAlt       = "images_missing_alt"
+	SEORuleMissingCanonical       = "missing_canonical"
+	SEORuleMultipleCanonical      = "multiple_canonical"
+	SEORuleNoindex                = "noindex"
+	SEORuleBrokenInternalLinks    = "broken_internal_links"
+)
+
+// SEO scoring constants
+const (
+	SEOMaxScore                 = 100
+	SEOMaxTitleLength           = 60
+	SEOMinMetaDescriptionLength = 50
+	SEOMaxMetaDescriptionLength = 160
+	SEOSeverityCritical         = "critical"
+	SEOSeverityWarning          = "warning"
+)
+
+// DefaultSEOWeights is the score penalty of each SEO rule. missing_canonical and
+// multiple_canonical never apply together, so the weights a page can lose at once add up
+// to SEOMaxScore.
+var DefaultSEOWeights = map[string]int{
+	SEORuleMissingTitle:           15,
+	SEORuleTitleTooLong:           5,
+	SEORuleMissingMetaDescription: 10,
+	SEORuleMetaDescriptionLength:  5,
+	SEORuleMissingH1:              10,
+	SEORuleMultipleH1:             5,
+	SEORuleImagesMissingAlt:       10,
+	SEORuleMissingCanonical:       10,
+	SEORuleMultipleCanonical:      5,
+	SEORuleNoindex:                20,
+	SEORuleBrokenInternalLinks:    10,
+}
+
 // RateLimit constants
 const (
 	DefaultRateLimitEnabled        = true
 	DefaultRequestsPerMinute       = 60.0
 	DefaultRateLimitBurstFactor    = 0.1 // 10% of rate
 	DefaultRateLimitCleanupTimeout = 1 * time.Hour
+	DefaultRateLimitCost           = 1   // Tokens per request on routes without their own cost
+	DefaultPerTargetPerMinute      = 0.0 // Analyses fetched per target host and minute; 0 disables
+	RateLimitStoreMemory           = "memory"
+	RateLimitStoreRedis            = "redis"
+	DefaultRateLimitStore          = RateLimitStoreMemory
+	DefaultRateLimitRedisPrefix    = "webpage-analyser:ratelimit:"
+	RateLimitStoreTimeout          = 100 * time.Millisecond // Per Redis operation; a slow store never holds up requests for long
+	RateLimitSnapshotMaxKeys       = 100000                 // Most depleted budgets saved by the shutdown snapshot
+	RateLimitDecisionAllowed       = "allowed"
+	RateLimitDecisionLimited       = "limited"
+	RateLimitKeyTypeIP             = "ip" // Budgets are kept per client IP address
+	EgressKeyPrefix                = "egress:"   // Under rate_limit.redis_prefix, followed by the UTC day and the client key
+	EgressDayLayout                = "2006-01-02"
+	EgressKeyTTL                   = 48 * time.Hour // Daily counters outlive their day, whatever the time zone of the reader
+)
+
+// Logging constants
+const (
+	DefaultAccessLogSampleRate = 1 // Log every successful request
+	RedactedValue              = "[redacted]"
+	LogFormatJSON              = "json"
+	LogFormatConsole           = "console"
+	LogOutputStdout            = "stdout"
+	LogOutputStderr            = "stderr"
+	DefaultLogMaxSizeMB        = 100
+	DefaultLogMaxBackups       = 5
+	DefaultLogMaxAgeDays       = 30
+)
+
+// DefaultRedactedQueryParams lists query parameters whose values are never written to the access log
+var DefaultRedactedQueryParams = []string{"token", "access_token", "api_key", "apikey", "key", "password", "secret", "signature", "sig"}
+
+// Context keys
+const (
+	ContextKeyRequestID = "request_id"
+	ContextKeyErrorCode = "error_code" // ErrorResponse.ErrorCode of a failed request
+)
+
+// Audit constants
+const (
+	AuditSinkFile             = "file"
+	AuditSinkRedis            = "redis"
+	DefaultAuditSink          = AuditSinkFile
+	DefaultAuditPath          = "audit.jsonl"
+	DefaultAuditRedisStream   = "webpage-analyser:audit"
+	DefaultAuditRedisMaxLen   = 1000000 // Approximate stream length kept by XADD MAXLEN ~
+	DefaultAuditBufferSize    = 1000    // Events held in memory while the sink catches up
+	AuditWriteTimeout         = 5 * time.Second
+	AuditOperationAnalyze     = "analyze"
+	AuditOperationAnalyzeHTML = "analyze_html"
+	AuditOperationReport      = "report"
+	AuditOutcomeSuccess       = "success"
+	AuditOutcomePartial       = "partial"
+	AuditOutcomeRejected      = "rejected" // 4xx responses
+	AuditOutcomeFailed        = "failed"   // 5xx responses
+	AuditAPIKeyIDLength       = 16         // Hex characters of the API key fingerprint
+)
+
+// Digest constants
+const (
+	DefaultDigestSchedule    = "08:00" // Daily, in UTC
+	DefaultDigestRedisPrefix = "webpage-analyser:digest:"
+	DefaultSMTPPort          = 587
+	DigestSendTimeout        = 30 * time.Second
+	DigestSubjectPrefix      = "Webpage analysis digest"
+)
+
+// Job constants
+const (
+	JobStatusQueued        = "queued"
+	JobStatusRunning       = "running"
+	JobStatusSucceeded     = "succeeded"
+	JobStatusFailed        = "failed"
+	JobStatusCanceled      = "canceled"
+	JobStoreMemory         = "memory"
+	JobStoreRedis          = "redis"
+	DefaultJobStore        = JobStoreMemory
+	DefaultJobConcurrency  = 4
+	DefaultJobTimeout      = 5 * time.Minute
+	DefaultJobMaxQueued    = 1000
+	DefaultJobRetention    = 24 * time.Hour // Finished jobs are listed this long
+	DefaultJobDrainTimeout = 30 * time.Second
+	DefaultJobRedisPrefix  = "webpage-analyser:jobs:"
+	JobIDByteLength        = 16
+	JobStoreTimeout        = 5 * time.Second // Store writes of a job, which outlive its context
+	JobLeaseGrace          = time.Minute     // Added to jobs.timeout for the lease of a running job
+	JobListBatchSize       = 500             // Jobs the Redis store reads per round trip when listing
+)
+
+// Readiness constants
+const (
+	ReadinessStatusReady         = "ready"
+	ReadinessStatusNotReady      = "not_ready"
+	DefaultOutboundProbeTimeout  = 3 * time.Second
+	DefaultOutboundProbeCacheTTL = 30 * time.Second // Readiness polls within it reuse the last probe
+)
+
+// Snapshot constants
+const (
+	DefaultSnapshotMaxBytes    = 2 << 20          // Larger pages are not stored, counted before compression
+	DefaultSnapshotTTL         = 30 * time.Minute // Shorter than the cache TTL; snapshots are for disputing recent analyses
+	DefaultSnapshotRedisPrefix = "webpage-analyser:snapshots:"
+	SnapshotStoreTimeout       = 5 * time.Second // Snapshot writes, which outlive the analysis deadline
+	SnapshotFieldBody          = "body"          // Gzip-compressed
+	SnapshotFieldContentType   = "content_type"
+	SnapshotFieldFetchedAt     = "fetched_at"
+	SnapshotContentTypeHTML    = "text/html; charset=utf-8" // Rendered pages, which have no response header
+	SnapshotSandboxPolicy      = "sandbox"                  // Snapshots are served as inert documents, never with this origin's privileges
 )
 
 // HTTP Status codes
 const (
-	StatusOK                  = 200
-	StatusBadRequest         = 400
-	StatusTooManyRequests    = 429
-	StatusInternalServerError = 500
+	StatusOK                    = 200
+	StatusPartialContent        = 206
+	StatusMultipleChoices       = 300
+	StatusNotModified           = 304
+	StatusBadRequest            = 400
+	StatusUnauthorized          = 401
+	StatusForbidden             = 403
+	StatusNotFound              = 404
+	StatusMethodNotAllowed      = 405
+	StatusGone                  = 410
+	StatusRequestEntityTooLarge = 413
+	StatusUnprocessableEntity   = 422
+	StatusTooManyRequests       = 429
+	StatusInternalServerError   = 500
+	StatusNotImplemented        = 501
+	StatusBadGateway            = 502
+	StatusServiceUnavailable    = 503
+	StatusGatewayTimeout        = 504
+)
+
+// Warning codes of non-fatal problems reported under warnings; see models.Warning
+const (
+	WarningCodeCacheReadFailed      = "CACHE_READ_FAILED"         // The cache lookup failed and the page was analyzed again
+	WarningCodeCacheWriteFailed     = "CACHE_WRITE_FAILED"        // The result could not be cached
+	WarningCodeStorageWriteFailed   = "STORAGE_WRITE_FAILED"      // The result could not be stored
+	WarningCodeLinksNotChecked      = "LINKS_NOT_CHECKED"         // More links than analyzer.max_links
+	WarningCodeBudgetExhausted      = "OUTBOUND_BUDGET_EXHAUSTED" // Links skipped once the outbound request budget ran out
+	WarningCodeAnalysisTruncated    = "ANALYSIS_TRUNCATED"        // Sections analyzed over part of an oversized document
+	WarningCodeJSRenderingFailed    = "JS_RENDERING_FAILED"       // The page was analyzed without JavaScript rendering
+	WarningCodeBodyTruncated        = "BODY_TRUNCATED"            // The page body was cut short; the prefix received was analyzed
+	WarningCodeTextSanitized        = "TEXT_SANITIZED"            // Invalid UTF-8 and control characters were replaced or stripped from extracted text
+	WarningCodeXMLMalformed         = "XML_MALFORMED"             // A sitemap or feed is not well-formed; the entries before the error were counted
+	WarningCodeCanonicalFetchFailed = "CANONICAL_FETCH_FAILED"    // The canonical URL could not be fetched, so its content was not compared
+	WarningCodeScopeNotFound        = "SCOPE_NOT_FOUND"           // options.scope_selector matched no element
+	WarningCodeSnapshotNotStored    = "SNAPSHOT_NOT_STORED"       // options.store_snapshot was asked for but the page was not stored
+)
+
+// Link failure categories; see models.LinkFailures
+const (
+	LinkFailureNotFound     = "not_found"     // 404, permanent
+	LinkFailureGone         = "gone"          // 410, permanent
+	LinkFailureClientError  = "client_error"  // Any other 4xx, permanent
+	LinkFailureRedirectLoop = "redirect_loop" // Permanent
+	LinkFailureServerError  = "server_error"  // 5xx, temporary
+	LinkFailureTimeout      = "timeout"       // Temporary
+	LinkFailureConnection   = "connection_error" // DNS, refused or reset connections, temporary
+	LinkFailureCircuitOpen  = "circuit_open"  // Not checked after repeated failures of the host, temporary
 )
 
 // Validation constants
 const (
 	MaxURLLength = 2048
+	MaxAcceptLanguageLength = 256
+	MaxHeadingTextLength = 200 // Characters of each heading kept by options.include_heading_text
+	MaxHeadingTextsPerLevel = 50 // Headings per level kept by options.include_heading_text
+	MaxRawLinksLimit = 1000 // Links per page of options.include_raw_links, also the default
+	MaxScopeSelectorLength = 256
 )
 
 // Metrics constants
@@ -65,6 +692,39 @@ const (
 	MetricCacheMissesHelp        = "Total number of cache misses"
 	MetricLinkCheckDurationName  = "webpage_analyzer_link_check_duration_seconds"
 	MetricLinkCheckDurationHelp  = "Time (in seconds) spent checking link accessibility"
+	MetricCacheOpDurationName    = "webpage_analyzer_cache_op_duration_seconds"
+	MetricCacheOpDurationHelp    = "Time (in seconds) spent on cache operations, by operation"
+	MetricCacheErrorsName        = "webpage_analyzer_cache_errors_total"
+	MetricCacheErrorsHelp        = "Total number of failed cache operations, by operation"
+	MetricCacheCorruptionName    = "webpage_analyzer_cache_corruption_total"
+	MetricCacheCorruptionHelp    = "Total number of cache entries deleted because they were corrupted"
+	MetricPanicsName             = "webpage_analyzer_panics_total"
+	MetricPanicsHelp             = "Total number of panics recovered while handling requests"
+	MetricRenderDurationName     = "webpage_analyzer_render_duration_seconds"
+	MetricRenderDurationHelp     = "Time (in seconds) spent rendering pages in the headless browser"
+	MetricRenderTotalName        = "webpage_analyzer_renders_total"
+	MetricRenderTotalHelp        = "Total number of javascript render attempts, by outcome"
+	MetricOutboundRequestsName   = "webpage_analyzer_outbound_requests_total"
+	MetricOutboundRequestsHelp   = "Total number of outbound requests to targets, by budget outcome"
+	MetricAnalysisOutboundName   = "webpage_analyzer_analysis_outbound_requests"
+	MetricAnalysisOutboundHelp   = "Outbound requests sent per analysis"
+	MetricFetchedBytesName       = "webpage_analyzer_fetched_bytes_total"
+	MetricFetchedBytesHelp       = "Total number of response bytes received from targets, as transferred, by source"
+	MetricPolicyViolationsName   = "webpage_analyzer_policy_violations_total"
+	MetricPolicyViolationsHelp   = "Total number of outbound requests the target policy blocks, or would block in dry-run mode, by mode"
+	MetricAuditDroppedName       = "webpage_analyzer_audit_events_dropped_total"
+	MetricAuditDroppedHelp       = "Total number of audit events dropped because the buffer was full or the sink failed"
+	MetricRateLimitDecisionsName = "webpage_analyzer_ratelimit_decisions_total"
+	MetricRateLimitDecisionsHelp = "Total number of rate limited requests, by decision and limiter key type"
+	MetricRateLimitEntriesName   = "webpage_analyzer_ratelimit_entries"
+	MetricRateLimitEntriesHelp   = "Number of client budgets the rate limiter holds in memory"
+	MetricOutboundProbeUpName    = "webpage_analyzer_outbound_probe_up"
+	MetricOutboundProbeUpHelp    = "Whether the last readiness probe of the outbound canary got a response (1) or not (0)"
+
+	ExemplarTraceIDLabel            = "trace_id"
+	NativeHistogramBucketFactor     = 1.1 // Each native bucket is at most 10% wider than the previous one
+	NativeHistogramMaxBuckets       = 160
+	NativeHistogramMinResetDuration = 1 * time.Hour
 )
 
 // Response messages
@@ -79,6 +739,44 @@ const (
 	MsgAnalysisComplete    = "analysis completed successfully"
 )
 
+// Error codes returned in ErrorResponse.ErrorCode
+const (
+	ErrorCodeInternal        = "INTERNAL"
+	ErrorCodeInvalidRequest  = "INVALID_REQUEST"
+	ErrorCodeValidation      = "VALIDATION_FAILED"
+	ErrorCodePayloadTooLarge = "PAYLOAD_TOO_LARGE"
+	ErrorCodeAnalysisFailed  = "ANALYSIS_FAILED"
+	ErrorCodeBotProtection   = "BOT_PROTECTION"
+	ErrorCodeAnalysisTimeout = "ANALYSIS_TIMEOUT"
+	ErrorCodeTargetRateLimited = "TARGET_RATE_LIMITED"
+	ErrorCodeStorageDisabled   = "STORAGE_DISABLED"
+	ErrorCodeDuplicatesDisabled = "DUPLICATES_DISABLED"
+	ErrorCodeNotAnalyzed        = "NOT_ANALYZED"
+	ErrorCodeExportUnavailable = "EXPORT_UNAVAILABLE"
+	ErrorCodeUnauthorized      = "UNAUTHORIZED"
+	ErrorCodeUnsupportedScheme = "UNSUPPORTED_SCHEME"
+	ErrorCodeDataURL           = "DATA_URL_UNSUPPORTED"
+	ErrorCodeFileURL           = "FILE_URL_UNSUPPORTED"
+	ErrorCodeFTPURL            = "FTP_URL_UNSUPPORTED"
+	ErrorCodeWebSocketURL      = "WEBSOCKET_URL_UNSUPPORTED"
+	ErrorCodeInvalidHost       = "INVALID_HOST"
+	ErrorCodeCredentialsNotAllowed = "CREDENTIALS_NOT_ALLOWED"
+	ErrorCodeTargetBlocked         = "TARGET_BLOCKED"
+	ErrorCodeEmptyDocument         = "EMPTY_DOCUMENT"
+	ErrorCodeRedirectLoop          = "REDIRECT_LOOP"
+	ErrorCodeTargetQuotaExceeded   = "TARGET_QUOTA_EXCEEDED" // rate_limit.per_target_per_minute, unlike TARGET_RATE_LIMITED, the target's own limit
+	ErrorCodeQuotaExceeded         = "QUOTA_EXCEEDED"        // rate_limit.egress.daily_bytes of the client spent
+	ErrorCodeResolveNotAllowed     = "RESOLVE_NOT_ALLOWED"   // options.resolve names another host or a private address
+	ErrorCodeSnapshotsDisabled     = "SNAPSHOTS_DISABLED"
+	ErrorCodeSnapshotNotFound      = "SNAPSHOT_NOT_FOUND"
+)
+
+// Form field names for multipart HTML submissions
+const (
+	FormFieldHTMLFile = "file"
+	FormFieldBaseURL  = "base_url"
+)
+
 // Template paths
 const (
 	IndexTemplatePath    = "web/templates/index.html"
@@ -96,14 +794,42 @@ const (
 
 // HTTP headers
 const (
-	HeaderContentType     = "Content-Type"
-	HeaderAccept         = "Accept"
-	HeaderAuthorization  = "Authorization"
-	HeaderRateLimit      = "X-RateLimit-Limit"
-	HeaderRateRemaining  = "X-RateLimit-Remaining"
-	HeaderRateReset      = "X-RateLimit-Reset"
-	HeaderCacheControl   = "Cache-Control"
-	HeaderRequestID      = "X-Request-ID"
+	HeaderContentType           = "Content-Type"
+	HeaderAccept                = "Accept"
+	HeaderAuthorization         = "Authorization"
+	HeaderCookie                = "Cookie"
+	HeaderUserAgent             = "User-Agent"
+	HeaderRateLimit             = "X-RateLimit-Limit"
+	HeaderRateRemaining         = "X-RateLimit-Remaining"
+	HeaderRateReset             = "X-RateLimit-Reset"
+	HeaderCacheControl          = "Cache-Control"
+	HeaderETag                  = "ETag"
+	HeaderIfNoneMatch           = "If-None-Match"
+	HeaderLastModified          = "Last-Modified"
+	HeaderIfModifiedSince       = "If-Modified-Since"
+	HeaderRequestID             = "X-Request-ID"
+	HeaderAPIKey                = "X-API-Key"
+	HeaderAcceptEncoding        = "Accept-Encoding"
+	HeaderContentEncoding       = "Content-Encoding"
+	HeaderAltSvc                = "Alt-Svc"
+	HeaderAcceptLanguage        = "Accept-Language"
+	HeaderContentLanguage       = "Content-Language"
+	HeaderVary                  = "Vary"
+	HeaderLocation              = "Location"
+	HeaderRetryAfter            = "Retry-After"
+	HeaderContentSecurityPolicy = "Content-Security-Policy"
+	HeaderContentDisposition    = "Content-Disposition"
+	HeaderContentTypeOptions    = "X-Content-Type-Options"
+	ContentTypeOptionsNoSniff   = "nosniff"
+	CacheControlNoStore         = "no-store"
+	CacheControlNoCache         = "no-cache"
+)
+
+// Request ID constants
+const (
+	RequestIDByteLength  = 16
+	AnalysisIDByteLength = 16
+	MaxRequestIDLength   = 128
 )
 
 // HTML Version Detection constants
@@ -140,10 +866,13 @@ const (
 	DOCTYPEKeywordXHTML10   = "XHTML 1.0"
 )
 
+// ByteOrderMark may precede the DOCTYPE of UTF-8 documents
+const ByteOrderMark = "\ufeff"
+
 // HTML Version Detection regex patterns
 const (
 	RegexXMLDeclaration     = `(?i)^\s*<\?xml[^>]*\?>\s*`
-	RegexHTMLComment       = `(?i)^\s*<!--.*?-->\s*`
+	RegexHTMLComment       = `(?is)^\s*<!--.*?-->\s*` // s: comments may span lines
 	RegexDOCTYPEExtraction = `(?i)^\s*<!DOCTYPE\s+[^>]*>`
 	RegexHTML5DOCTYPE      = `^\s*<!DOCTYPE\s+HTML\s*>\s*$`
 ) 
\ No newline at end of file
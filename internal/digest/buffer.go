@@ -0,0 +1,155 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Buffer holds the events of each destination in Redis until its digest is sent, along with
+// the last event of every URL in the previous digest. Drained events are kept as the batch
+// being delivered until Ack, so a failed or interrupted send loses none of them.
+type Buffer struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewBuffer stores events under prefix; the buffer owns the client and closes it
+func NewBuffer(client *redis.Client, prefix string) *Buffer {
+	return &Buffer{client: client, prefix: prefix}
+}
+
+func (b *Buffer) eventsKey(destination string) string {
+	return b.prefix + destination + ":events"
+}
+
+func (b *Buffer) processingKey(destination string) string {
+	return b.prefix + destination + ":processing"
+}
+
+func (b *Buffer) deliveredKey(destination string) string {
+	return b.prefix + destination + ":delivered"
+}
+
+func (b *Buffer) baselineKey(destination string) string {
+	return b.prefix + destination + ":baseline"
+}
+
+// Add appends an event to the destination's buffer
+func (b *Buffer) Add(ctx context.Context, destination string, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := b.client.RPush(ctx, b.eventsKey(destination), data).Err(); err != nil {
+		return fmt.Errorf("failed to buffer digest event for %s: %w", destination, err)
+	}
+	return nil
+}
+
+// Drain returns the batch of events being delivered to the destination. A batch left by a
+// failed or interrupted send is returned again, with leftover set; otherwise the buffered
+// events are renamed into a new batch in one step, so events added meanwhile wait for the
+// next digest. The batch stays in Redis until Ack.
+func (b *Buffer) Drain(ctx context.Context, destination string) (events []Event, leftover bool, err error) {
+	processing := b.processingKey(destination)
+	pending, err := b.client.Exists(ctx, processing).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to drain digest events of %s: %w", destination, err)
+	}
+	if pending == 0 {
+		// Nothing buffered leaves no batch to deliver
+		if err := b.client.RenameNX(ctx, b.eventsKey(destination), processing).Err(); err != nil && !isNoSuchKey(err) {
+			return nil, false, fmt.Errorf("failed to drain digest events of %s: %w", destination, err)
+		}
+	}
+
+	values, err := b.client.LRange(ctx, processing, 0, -1).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to drain digest events of %s: %w", destination, err)
+	}
+	events = make([]Event, 0, len(values))
+	for _, value := range values {
+		var event Event
+		if err := json.Unmarshal([]byte(value), &event); err != nil {
+			return nil, false, fmt.Errorf("failed to decode digest event of %s: %w", destination, err)
+		}
+		events = append(events, event)
+	}
+	return events, pending > 0, nil
+}
+
+// Delivered returns the notifiers the batch being delivered to the destination has reached
+func (b *Buffer) Delivered(ctx context.Context, destination string) (map[string]bool, error) {
+	names, err := b.client.SMembers(ctx, b.deliveredKey(destination)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest deliveries of %s: %w", destination, err)
+	}
+	delivered := make(map[string]bool, len(names))
+	for _, name := range names {
+		delivered[name] = true
+	}
+	return delivered, nil
+}
+
+// MarkDelivered records that the batch being delivered to the destination reached a notifier,
+// so a retry of the batch skips it
+func (b *Buffer) MarkDelivered(ctx context.Context, destination, notifier string) error {
+	if err := b.client.SAdd(ctx, b.deliveredKey(destination), notifier).Err(); err != nil {
+		return fmt.Errorf("failed to record digest delivery of %s: %w", destination, err)
+	}
+	return nil
+}
+
+// Ack removes the batch delivered to the destination, along with its deliveries
+func (b *Buffer) Ack(ctx context.Context, destination string) error {
+	if err := b.client.Del(ctx, b.processingKey(destination), b.deliveredKey(destination)).Err(); err != nil {
+		return fmt.Errorf("failed to remove digest events of %s: %w", destination, err)
+	}
+	return nil
+}
+
+// isNoSuchKey reports whether err is Redis refusing to rename a key that does not exist
+func isNoSuchKey(err error) bool {
+	return strings.Contains(err.Error(), "no such key")
+}
+
+// Baseline returns the last event of every URL in the destination's previous digest
+func (b *Buffer) Baseline(ctx context.Context, destination string) (map[string]Event, error) {
+	values, err := b.client.HGetAll(ctx, b.baselineKey(destination)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest baseline of %s: %w", destination, err)
+	}
+	baseline := make(map[string]Event, len(values))
+	for u, value := range values {
+		var event Event
+		if err := json.Unmarshal([]byte(value), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode digest baseline of %s: %w", destination, err)
+		}
+		baseline[u] = event
+	}
+	return baseline, nil
+}
+
+// SetBaseline records the last events of a sent digest, keeping those of URLs it did not cover
+func (b *Buffer) SetBaseline(ctx context.Context, destination string, events map[string]Event) error {
+	values := make([]any, 0, 2*len(events))
+	for u, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		values = append(values, u, data)
+	}
+	if err := b.client.HSet(ctx, b.baselineKey(destination), values...).Err(); err != nil {
+		return fmt.Errorf("failed to record digest baseline of %s: %w", destination, err)
+	}
+	return nil
+}
+
+func (b *Buffer) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,304 @@
+// Package digest summarizes the analyses of monitored URLs once a day per destination,
+// instead of notifying about each analysis. Analyses are buffered in Redis as events until
+// the scheduled send, so a restart does not lose the day's events.
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/idna"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// Event is the part of one analysis a digest summarizes
+type Event struct {
+	URL         string    `json:"url"`
+	AnalyzedAt  time.Time `json:"analyzed_at"`
+	Title       string    `json:"title"`
+	BrokenLinks int       `json:"broken_links"`
+}
+
+// Entry summarizes the analyses of one URL since the previous digest
+type Entry struct {
+	URL            string `json:"url"`
+	Analyses       int    `json:"analyses"`
+	Changed        bool   `json:"changed"`                  // The title or broken link count differs from the previous analysis
+	Title          string `json:"title"`                    // As last analyzed
+	PreviousTitle  string `json:"previous_title,omitempty"` // Only when it differs
+	BrokenLinks    int    `json:"broken_links"`             // As last analyzed
+	NewBrokenLinks int    `json:"new_broken_links"`         // Broken links added since the previous analysis
+	ResultsURL     string `json:"results_url,omitempty"`    // Stored results of the URL on this server
+}
+
+// Digest is the summary sent to one destination
+type Digest struct {
+	Destination string    `json:"destination"`
+	From        time.Time `json:"from"` // First analysis summarized
+	To          time.Time `json:"to"`   // Time of the send
+	Changed     int       `json:"changed"`
+	Entries     []Entry   `json:"entries"` // Sorted by URL
+}
+
+// Notifier delivers a digest
+type Notifier interface {
+	Send(ctx context.Context, digest Digest) error
+}
+
+type destination struct {
+	name      string
+	urls      map[string]string // Monitored URLs as configured, by canonical URL
+	notifiers []namedNotifier
+}
+
+// namedNotifier is a notifier of a destination, named so its deliveries are tracked apart
+type namedNotifier struct {
+	name string
+	Notifier
+}
+
+// Digester records the analyses of monitored URLs and sends the digests on schedule
+type Digester struct {
+	buffer       *Buffer
+	destinations []destination
+	schedule     Schedule
+	clock        Clock
+	baseURL      string
+	logger       *zap.Logger
+}
+
+// NewDigester returns a digester for cfg. Each destination gets a webhook notifier when it has
+// a webhook URL and a mail notifier when it has recipients.
+func NewDigester(cfg config.DigestConfig, buffer *Buffer, clock Clock, logger *zap.Logger) (*Digester, error) {
+	schedule, err := ParseSchedule(cfg.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digester{
+		buffer:   buffer,
+		schedule: schedule,
+		clock:    clock,
+		baseURL:  strings.TrimSuffix(cfg.BaseURL, "/"),
+		logger:   logger,
+	}
+	for _, dest := range cfg.Destinations {
+		if dest.Name == "" {
+			return nil, errors.New("digest destination without a name")
+		}
+		var notifiers []namedNotifier
+		if dest.Webhook != "" {
+			notifiers = append(notifiers, namedNotifier{name: "webhook", Notifier: NewWebhookNotifier(dest.Webhook, nil)})
+		}
+		if len(dest.Email) > 0 {
+			notifiers = append(notifiers, namedNotifier{name: "email", Notifier: NewMailNotifier(cfg.SMTP, dest.Email)})
+		}
+		if len(notifiers) == 0 {
+			return nil, fmt.Errorf("digest destination %s has neither a webhook nor email recipients", dest.Name)
+		}
+		d.addDestination(dest.Name, dest.URLs, notifiers...)
+	}
+	return d, nil
+}
+
+func (d *Digester) addDestination(name string, urls []string, notifiers ...namedNotifier) {
+	dest := destination{name: name, urls: make(map[string]string, len(urls)), notifiers: notifiers}
+	for _, u := range urls {
+		dest.urls[canonicalURL(u)] = u
+	}
+	d.destinations = append(d.destinations, dest)
+}
+
+// Save records a completed analysis for every destination monitoring its URL, however the
+// URL was spelled. It makes the digester an analysis store, so it is fed like the storage.
+func (d *Digester) Save(ctx context.Context, result *models.AnalyzeResponse) error {
+	canonical := canonicalURL(result.URL)
+	var errs []error
+	for _, dest := range d.destinations {
+		monitored, ok := dest.urls[canonical]
+		if !ok {
+			continue
+		}
+		// Events carry the URL as configured, so every spelling is summarized as one
+		errs = append(errs, d.buffer.Add(ctx, dest.name, Event{
+			URL:         monitored,
+			AnalyzedAt:  result.AnalyzedAt,
+			Title:       result.Title,
+			BrokenLinks: result.Links.Inaccessible,
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// canonicalURL returns u with its scheme and host lowercased and punycoded, without
+// credentials, fragment or default port, and with an empty path as /, the way the analyzer
+// caches it. Unparsable URLs are returned as they are.
+func canonicalURL(u string) string {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil || parsed.Host == "" {
+		return u
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		host = ascii
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	port := parsed.Port()
+	if (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	parsed.Host = host
+	if port != "" {
+		parsed.Host = net.JoinHostPort(host, port)
+	}
+	parsed.User = nil
+	parsed.Fragment, parsed.RawFragment = "", ""
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	return parsed.String()
+}
+
+// Run sends the digests at every scheduled time until ctx is done
+func (d *Digester) Run(ctx context.Context) {
+	for {
+		now := d.clock.Now()
+		next := d.schedule.Next(now)
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.clock.After(next.Sub(now)):
+		}
+		d.Flush(ctx)
+	}
+}
+
+// Flush sends every destination the digest of its buffered events. Events whose digest did not
+// reach every notifier of the destination stay buffered, and are sent at the next send to the
+// notifiers that missed them.
+func (d *Digester) Flush(ctx context.Context) {
+	for _, dest := range d.destinations {
+		if err := d.flush(ctx, dest); err != nil {
+			d.logger.Error("Failed to send digest", zap.String("destination", dest.name), zap.Error(err))
+		}
+	}
+}
+
+// flush delivers the batch a failed or interrupted send left, if any, then the events
+// buffered since
+func (d *Digester) flush(ctx context.Context, dest destination) error {
+	for {
+		events, leftover, err := d.buffer.Drain(ctx, dest.name)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		if err := d.deliver(ctx, dest, events); err != nil || !leftover {
+			return err
+		}
+	}
+}
+
+// deliver sends the digest of a batch to every notifier of the destination it has not reached
+// yet. The batch is kept until all of them have it, so a notifier that failed is tried again at
+// the next send, and only it.
+func (d *Digester) deliver(ctx context.Context, dest destination, events []Event) error {
+	baseline, err := d.buffer.Baseline(ctx, dest.name)
+	if err != nil {
+		return err
+	}
+	delivered, err := d.buffer.Delivered(ctx, dest.name)
+	if err != nil {
+		return err
+	}
+
+	digest, last := d.summarize(dest.name, events, baseline)
+	var errs []error
+	for _, notifier := range dest.notifiers {
+		if delivered[notifier.name] {
+			continue
+		}
+		if err := notifier.Send(ctx, digest); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.name, err))
+			continue
+		}
+		if err := d.buffer.MarkDelivered(ctx, dest.name, notifier.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	d.logger.Info("Digest sent",
+		zap.String("destination", dest.name),
+		zap.Int("urls", len(digest.Entries)),
+		zap.Int("changed", digest.Changed),
+	)
+	if err := d.buffer.SetBaseline(ctx, dest.name, last); err != nil {
+		return err
+	}
+	return d.buffer.Ack(ctx, dest.name)
+}
+
+// summarize aggregates the events per URL, comparing each analysis with the one before it,
+// starting from the last analysis of the previous digest. It returns the digest and the last
+// event of every URL, the baseline of the next digest.
+func (d *Digester) summarize(name string, events []Event, baseline map[string]Event) (Digest, map[string]Event) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].AnalyzedAt.Before(events[j].AnalyzedAt) })
+
+	entries := make(map[string]*Entry)
+	last := make(map[string]Event)
+	for _, event := range events {
+		entry := entries[event.URL]
+		if entry == nil {
+			entry = &Entry{URL: event.URL, ResultsURL: d.resultsURL(event.URL)}
+			entries[event.URL] = entry
+		}
+		previous, ok := last[event.URL]
+		if !ok {
+			previous, ok = baseline[event.URL]
+		}
+		if ok {
+			if previous.Title != event.Title || previous.BrokenLinks != event.BrokenLinks {
+				entry.Changed = true
+			}
+			if previous.Title != event.Title && entry.PreviousTitle == "" {
+				entry.PreviousTitle = previous.Title
+			}
+			entry.NewBrokenLinks += max(event.BrokenLinks-previous.BrokenLinks, 0)
+		}
+		entry.Analyses++
+		entry.Title = event.Title
+		entry.BrokenLinks = event.BrokenLinks
+		last[event.URL] = event
+	}
+
+	digest := Digest{Destination: name, From: events[0].AnalyzedAt, To: d.clock.Now().UTC()}
+	for _, entry := range entries {
+		if entry.PreviousTitle == entry.Title {
+			entry.PreviousTitle = ""
+		}
+		if entry.Changed {
+			digest.Changed++
+		}
+		digest.Entries = append(digest.Entries, *entry)
+	}
+	sort.Slice(digest.Entries, func(i, j int) bool { return digest.Entries[i].URL < digest.Entries[j].URL })
+	return digest, last
+}
+
+// resultsURL links to the stored analyses of a URL, or "" without a base URL
+func (d *Digester) resultsURL(u string) string {
+	if d.baseURL == "" {
+		return ""
+	}
+	return d.baseURL + "/api/v1/analyses?url=" + url.QueryEscape(u)
+}
@@ -0,0 +1,109 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// WebhookNotifier posts digests as JSON
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier posts to url; a nil client uses one with the digest send timeout
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: constants.DigestSendTimeout}
+	}
+	return &WebhookNotifier{url: url, client: client}
+}
+
+func (n *WebhookNotifier) Send(ctx context.Context, digest Digest) error {
+	body, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(constants.HeaderContentType, constants.ContentTypeJSON)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post digest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("digest webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendMailFunc matches smtp.SendMail
+type sendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// MailNotifier mails digests as plain text through an SMTP server
+type MailNotifier struct {
+	smtp     config.SMTPConfig
+	to       []string
+	sendMail sendMailFunc
+}
+
+// NewMailNotifier mails to the recipients through the configured server
+func NewMailNotifier(cfg config.SMTPConfig, to []string) *MailNotifier {
+	return &MailNotifier{smtp: cfg, to: to, sendMail: smtp.SendMail}
+}
+
+func (n *MailNotifier) Send(_ context.Context, digest Digest) error {
+	var auth smtp.Auth
+	if n.smtp.Username != "" {
+		auth = smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, n.smtp.Host)
+	}
+	addr := net.JoinHostPort(n.smtp.Host, strconv.Itoa(n.smtp.Port))
+	if err := n.sendMail(addr, auth, n.smtp.From, n.to, n.message(digest)); err != nil {
+		return fmt.Errorf("failed to mail digest: %w", err)
+	}
+	return nil
+}
+
+// message renders the digest as a plain-text mail
+func (n *MailNotifier) message(digest Digest) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.smtp.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s: %d of %d URLs changed\r\n", constants.DigestSubjectPrefix, digest.Changed, len(digest.Entries))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&b, "Analyses from %s to %s for %s\r\n", digest.From.UTC().Format(timeFormat), digest.To.UTC().Format(timeFormat), digest.Destination)
+	for _, entry := range digest.Entries {
+		status := "unchanged"
+		if entry.Changed {
+			status = "changed"
+		}
+		fmt.Fprintf(&b, "\r\n%s (%s)\r\n", entry.URL, status)
+		if entry.PreviousTitle != "" {
+			fmt.Fprintf(&b, "  Title: %s (was %s)\r\n", entry.Title, entry.PreviousTitle)
+		} else {
+			fmt.Fprintf(&b, "  Title: %s\r\n", entry.Title)
+		}
+		fmt.Fprintf(&b, "  Broken links: %d (%d new) over %d analyses\r\n", entry.BrokenLinks, entry.NewBrokenLinks, entry.Analyses)
+		if entry.ResultsURL != "" {
+			fmt.Fprintf(&b, "  Results: %s\r\n", entry.ResultsURL)
+		}
+	}
+	return []byte(b.String())
+}
+
+const timeFormat = "2006-01-02 15:04 MST"
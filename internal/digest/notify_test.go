@@ -0,0 +1,87 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+var testDigest = Digest{
+	Destination: "team",
+	From:        start,
+	To:          start.Add(20 * time.Hour),
+	Changed:     1,
+	Entries: []Entry{
+		{URL: "https://a.example", Analyses: 2, Changed: true, Title: "A v2", PreviousTitle: "A", BrokenLinks: 2, NewBrokenLinks: 2, ResultsURL: "https://analyser.example/api/v1/analyses?url=https%3A%2F%2Fa.example"},
+		{URL: "https://b.example", Analyses: 1, Title: "B", BrokenLinks: 1},
+	},
+}
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	var received Digest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, constants.ContentTypeJSON, r.Header.Get(constants.HeaderContentType))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewWebhookNotifier(server.URL, nil).Send(context.Background(), testDigest))
+	assert.Equal(t, testDigest.Entries, received.Entries)
+	assert.True(t, testDigest.To.Equal(received.To))
+}
+
+func TestWebhookNotifier_SendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL, nil).Send(context.Background(), testDigest)
+	assert.EqualError(t, err, "digest webhook returned status code 502")
+}
+
+func TestMailNotifier_Send(t *testing.T) {
+	notifier := NewMailNotifier(config.SMTPConfig{Host: "smtp.example", Port: 587, Username: "user", Password: "secret", From: "analyser@example.com"},
+		[]string{"team@example.com", "ops@example.com"})
+
+	var addr, from string
+	var to []string
+	var msg []byte
+	notifier.sendMail = func(a string, auth smtp.Auth, f string, t []string, m []byte) error {
+		addr, from, to, msg = a, f, t, m
+		return nil
+	}
+
+	require.NoError(t, notifier.Send(context.Background(), testDigest))
+	assert.Equal(t, "smtp.example:587", addr)
+	assert.Equal(t, "analyser@example.com", from)
+	assert.Equal(t, []string{"team@example.com", "ops@example.com"}, to)
+	assert.Equal(t, "From: analyser@example.com\r\n"+
+		"To: team@example.com, ops@example.com\r\n"+
+		"Subject: Webpage analysis digest: 1 of 2 URLs changed\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n"+
+		"Analyses from 2024-05-01 12:00 UTC to 2024-05-02 08:00 UTC for team\r\n"+
+		"\r\nhttps://a.example (changed)\r\n"+
+		"  Title: A v2 (was A)\r\n"+
+		"  Broken links: 2 (2 new) over 2 analyses\r\n"+
+		"  Results: https://analyser.example/api/v1/analyses?url=https%3A%2F%2Fa.example\r\n"+
+		"\r\nhttps://b.example (unchanged)\r\n"+
+		"  Title: B\r\n"+
+		"  Broken links: 1 (0 new) over 1 analyses\r\n", string(msg))
+
+	notifier.sendMail = func(string, smtp.Auth, string, []string, []byte) error { return errors.New("connection refused") }
+	assert.ErrorContains(t, notifier.Send(context.Background(), testDigest), "failed to mail digest")
+}
@@ -0,0 +1,43 @@
+package digest
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule is a daily send time in UTC
+type Schedule struct {
+	hour, minute int
+}
+
+// ParseSchedule parses a daily send time written HH:MM
+func ParseSchedule(value string) (Schedule, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("invalid digest schedule %q: want HH:MM", value)
+	}
+	return Schedule{hour: t.Hour(), minute: t.Minute()}, nil
+}
+
+// Next returns the first send time after now
+func (s Schedule) Next(now time.Time) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Clock tells the time and waits for it; tests replace it with a fake
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the wall clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// Writer encodes analyses one at a time in an export format. Rows are buffered
+// until Flush, so callers decide how often output reaches the underlying writer.
+type Writer interface {
+	Write(result *models.AnalyzeResponse) error
+	Flush() error
+}
+
+// NewWriter returns a Writer for the given format, ndjson or csv
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case constants.ExportFormatNDJSON:
+		buf := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case constants.ExportFormatCSV:
+		return &csvWriter{csv: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ContentType returns the media type of an export format
+func ContentType(format string) string {
+	if format == constants.ExportFormatCSV {
+		return constants.ContentTypeCSV
+	}
+	return constants.ContentTypeNDJSON
+}
+
+// ndjsonWriter writes each analysis as one line of JSON
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(result *models.AnalyzeResponse) error {
+	// Encode terminates every value with a newline
+	return w.enc.Encode(result)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// csvColumns is the header of CSV exports; csvRow flattens an analysis in the same order
+var csvColumns = []string{
+	"url", "analyzed_at", "html_version", "title",
+	"h1", "h2", "h3", "h4", "h5", "h6",
+	"internal_links", "external_links", "inaccessible_links", "inaccessible_internal_links", "rate_limited_links",
+	"has_login_form", "rendered_with_js", "bot_protection_detected", "consent_banner_provider",
+	"accessibility_issues", "seo_score", "strict_csp_compatible",
+}
+
+// csvWriter writes a header row followed by one flattened row per analysis
+type csvWriter struct {
+	csv         *csv.Writer
+	wroteHeader bool
+}
+
+func (w *csvWriter) Write(result *models.AnalyzeResponse) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.csv.Write(csvRow(result))
+}
+
+func (w *csvWriter) Flush() error {
+	// An empty export still carries the header
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+	return w.csv.Write(csvColumns)
+}
+
+// csvRow flattens the scalar fields of an analysis; nested lists stay in the NDJSON format
+func csvRow(result *models.AnalyzeResponse) []string {
+	accessibilityIssues := 0
+	for _, issue := range result.Accessibility.Issues {
+		accessibilityIssues += issue.Count
+	}
+
+	return []string{
+		result.URL,
+		result.AnalyzedAt.UTC().Format(time.RFC3339),
+		result.HTMLVersion,
+		result.Title,
+		strconv.Itoa(result.Headings["h1"]),
+		strconv.Itoa(result.Headings["h2"]),
+		strconv.Itoa(result.Headings["h3"]),
+		strconv.Itoa(result.Headings["h4"]),
+		strconv.Itoa(result.Headings["h5"]),
+		strconv.Itoa(result.Headings["h6"]),
+		strconv.Itoa(result.Links.Internal),
+		strconv.Itoa(result.Links.External),
+		strconv.Itoa(result.Links.Inaccessible),
+		strconv.Itoa(result.Links.InaccessibleInternal),
+		strconv.Itoa(result.Links.RateLimited),
+		strconv.FormatBool(result.HasLoginForm),
+		strconv.FormatBool(result.RenderedWithJS),
+		strconv.FormatBool(result.BotProtectionDetected),
+		result.ConsentBanner.Provider,
+		strconv.Itoa(accessibilityIssues),
+		strconv.Itoa(result.SEO.Score),
+		strconv.FormatBool(result.CSPReadiness.StrictCSPCompatible),
+	}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func testResult(url string) *models.AnalyzeResponse {
+	return &models.AnalyzeResponse{
+		URL:           url,
+		HTMLVersion:   "HTML5",
+		Title:         `Widgets, "fast" and cheap`,
+		Headings:      map[string]int{"h1": 1, "h3": 4},
+		Links:         models.LinkAnalysis{Internal: 7, External: 3, Inaccessible: 2, InaccessibleInternal: 1},
+		HasLoginForm:  true,
+		ConsentBanner: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderOneTrust},
+		Accessibility: models.AccessibilityReport{Issues: []models.AccessibilityIssue{
+			{Rule: constants.A11yRuleImageMissingAlt, Count: 2},
+			{Rule: constants.A11yRuleMissingLang, Count: 1},
+		}},
+		SEO:        models.SEOReport{Score: 75},
+		AnalyzedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestNewWriter_UnsupportedFormat(t *testing.T) {
+	_, err := NewWriter("xml", &bytes.Buffer{})
+	assert.ErrorContains(t, err, "unsupported export format")
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(constants.ExportFormatNDJSON, &buf)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(testResult("https://a.example")))
+	require.NoError(t, writer.Write(testResult("https://b.example")))
+	assert.Zero(t, buf.Len(), "rows are buffered until Flush")
+	require.NoError(t, writer.Flush())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, expected := range []string{"https://a.example", "https://b.example"} {
+		var result models.AnalyzeResponse
+		require.NoError(t, json.Unmarshal([]byte(lines[i]), &result))
+		assert.Equal(t, expected, result.URL)
+		assert.Equal(t, 75, result.SEO.Score)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(constants.ExportFormatCSV, &buf)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(testResult("https://a.example")))
+	require.NoError(t, writer.Flush())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, csvColumns, records[0])
+
+	row := map[string]string{}
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	assert.Equal(t, "https://a.example", row["url"])
+	assert.Equal(t, "2024-05-01T12:00:00Z", row["analyzed_at"])
+	assert.Equal(t, `Widgets, "fast" and cheap`, row["title"])
+	assert.Equal(t, "1", row["h1"])
+	assert.Equal(t, "0", row["h2"])
+	assert.Equal(t, "4", row["h3"])
+	assert.Equal(t, "7", row["internal_links"])
+	assert.Equal(t, "2", row["inaccessible_links"])
+	assert.Equal(t, "true", row["has_login_form"])
+	assert.Equal(t, constants.ConsentProviderOneTrust, row["consent_banner_provider"])
+	assert.Equal(t, "3", row["accessibility_issues"])
+	assert.Equal(t, "75", row["seo_score"])
+}
+
+func TestCSVWriter_EmptyExportHasHeader(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(constants.ExportFormatCSV, &buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.Flush())
+
+	assert.Equal(t, strings.Join(csvColumns, ",")+"\n", buf.String())
+}
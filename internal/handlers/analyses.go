@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/storage"
+)
+
+// AnalysesHandler serves the stored analysis records
+type AnalysesHandler struct {
+	logger *zap.Logger
+	store  storage.Store
+}
+
+// NewAnalysesHandler creates a new AnalysesHandler instance
+func NewAnalysesHandler(logger *zap.Logger, store storage.Store) *AnalysesHandler {
+	return &AnalysesHandler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// List returns stored analyses, newest first, filtered by url and an optional
+// [from, to) time range in RFC 3339, paginated with limit and offset
+func (h *AnalysesHandler) List(c *gin.Context) {
+	query, err := parseAnalysesQuery(c)
+	if err != nil {
+		writeError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", err.Error())
+		return
+	}
+
+	page, err := h.store.List(c.Request.Context(), query)
+	if errors.Is(err, storage.ErrDisabled) {
+		writeError(c, constants.StatusNotFound, constants.ErrorCodeStorageDisabled, "Analysis storage is not enabled", "")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to list analyses", zap.Error(err))
+		writeError(c, constants.StatusInternalServerError, constants.ErrorCodeInternal, "Failed to list analyses", "")
+		return
+	}
+
+	c.JSON(constants.StatusOK, page)
+}
+
+// parseAnalysesQuery reads the filters and pagination from the query string
+func parseAnalysesQuery(c *gin.Context) (storage.Query, error) {
+	query := storage.Query{
+		URL:   c.Query("url"),
+		Limit: constants.DefaultAnalysesPageSize,
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		return query, err
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		return query, err
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, fmt.Errorf("from must be before to")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > constants.MaxAnalysesPageSize {
+			return query, fmt.Errorf("limit must be between 1 and %d", constants.MaxAnalysesPageSize)
+		}
+		query.Limit = limit
+	}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return query, fmt.Errorf("offset must be a non-negative integer")
+		}
+		query.Offset = offset
+	}
+
+	return query, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 timestamp query parameter
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/storage"
+)
+
+func newAnalysesEngine(t *testing.T, store storage.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/api/v1/analyses", NewAnalysesHandler(zaptest.NewLogger(t), store).List)
+	return engine
+}
+
+func getAnalyses(engine *gin.Engine, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analyses"+query, nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestAnalysesHandler_List(t *testing.T) {
+	store, err := storage.Open(context.Background(), config.StorageConfig{
+		Driver: constants.StorageDriverSQLite,
+		DSN:    "file::memory:",
+	}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.Save(context.Background(), &models.AnalyzeResponse{
+			URL:        "https://example.com",
+			Title:      "Example",
+			AnalyzedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+	engine := newAnalysesEngine(t, store)
+
+	w := getAnalyses(engine, "?url=https://example.com&from=2024-05-01T12:30:00Z&limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var page storage.Page
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, 1, page.Limit)
+	require.Len(t, page.Records, 1)
+	assert.True(t, base.Add(2*time.Hour).Equal(page.Records[0].AnalyzedAt))
+
+	w = getAnalyses(engine, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, constants.DefaultAnalysesPageSize, page.Limit)
+	assert.Len(t, page.Records, 3)
+}
+
+func TestAnalysesHandler_ValidationErrors(t *testing.T) {
+	engine := newAnalysesEngine(t, storage.NewNoOpStore())
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"Invalid from", "?from=yesterday"},
+		{"Invalid to", "?to=2024-05-01"},
+		{"Empty range", "?from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z"},
+		{"Limit too large", "?limit=101"},
+		{"Limit zero", "?limit=0"},
+		{"Negative offset", "?offset=-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getAnalyses(engine, tt.query)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assertErrorCode(t, w, constants.ErrorCodeValidation)
+		})
+	}
+}
+
+func TestAnalysesHandler_StorageDisabled(t *testing.T) {
+	w := getAnalyses(newAnalysesEngine(t, storage.NewNoOpStore()), "")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assertErrorCode(t, w, constants.ErrorCodeStorageDisabled)
+}
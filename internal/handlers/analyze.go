@@ -1,80 +1,344 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 
+	"github.com/webpage-analyser-server/internal/audit"
+	"github.com/webpage-analyser-server/internal/config"
 	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/middleware"
 	"github.com/webpage-analyser-server/internal/models"
 	"github.com/webpage-analyser-server/internal/services"
 )
 
+// AnalyzerInterface analyzes webpages for the handler. *services.Analyzer implements it;
+// alternative implementations and test fakes can take its place.
+type AnalyzerInterface interface {
+	Analyze(ctx context.Context, targetURL string) (*models.AnalyzeResponse, error)
+	AnalyzeWithOptions(ctx context.Context, targetURL string, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error)
+	AnalyzeHTML(ctx context.Context, htmlContent, baseURL string, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error)
+}
+
 // AnalyzeHandler handles webpage analysis requests
 type AnalyzeHandler struct {
-	logger    *zap.Logger
-	analyzer  *services.Analyzer
-	validator *validator.Validate
+	logger       *zap.Logger
+	analyzer     AnalyzerInterface
+	auditor      *audit.Auditor
+	validator    *validator.Validate
+	maxBodyBytes int64
 }
 
 // NewAnalyzeHandler creates a new AnalyzeHandler instance
-func NewAnalyzeHandler(logger *zap.Logger, analyzer *services.Analyzer) *AnalyzeHandler {
+func NewAnalyzeHandler(cfg *config.Config, logger *zap.Logger, analyzer AnalyzerInterface) *AnalyzeHandler {
+	maxBodyBytes := cfg.Analyzer.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = constants.DefaultMaxBodyBytes
+	}
+
 	return &AnalyzeHandler{
-		logger:    logger,
-		analyzer:  analyzer,
-		validator: validator.New(),
+		logger:       logger,
+		analyzer:     analyzer,
+		validator:    validator.New(),
+		maxBodyBytes: maxBodyBytes,
 	}
 }
 
+// SetAuditor records an audit event for every analysis request; without one nothing is recorded
+func (h *AnalyzeHandler) SetAuditor(auditor *audit.Auditor) {
+	h.auditor = auditor
+}
+
 // Handle processes webpage analysis requests
 func (h *AnalyzeHandler) Handle(c *gin.Context) {
 	var req models.AnalyzeRequest
+	start := time.Now()
+	defer func() { h.audit(c, constants.AuditOperationAnalyze, req.URL, start) }()
 
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(constants.StatusBadRequest, models.ErrorResponse{
-			Code:    constants.StatusBadRequest,
-			Message: "Invalid request body",
-			Details: err.Error(),
-		})
+		h.respondError(c, constants.StatusBadRequest, constants.ErrorCodeInvalidRequest, "Invalid request body", err.Error())
 		return
 	}
 
 	// Validate request
 	if err := h.validator.Struct(req); err != nil {
-		c.JSON(constants.StatusBadRequest, models.ErrorResponse{
-			Code:    constants.StatusBadRequest,
-			Message: "Validation failed",
-			Details: err.Error(),
-		})
+		h.respondError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", err.Error())
 		return
 	}
 
 	// Custom validation
 	if err := req.Validate(); err != nil {
-		c.JSON(constants.StatusBadRequest, models.ErrorResponse{
-			Code:    constants.StatusBadRequest,
-			Message: "Validation failed",
-			Details: err.Error(),
-		})
+		h.respondValidationError(c, err)
 		return
 	}
 
 	// Analyze webpage
-	result, err := h.analyzer.Analyze(c.Request.Context(), req.URL)
+	result, err := h.analyzer.AnalyzeWithOptions(analysisContext(c), req.URL, req.Options)
 	if err != nil {
 		h.logger.Error("Failed to analyze webpage",
-			zap.String("url", req.URL),
+			zap.String("url", models.StripCredentials(req.URL)),
 			zap.Error(err),
 		)
 
-		c.JSON(constants.StatusInternalServerError, models.ErrorResponse{
-			Code:    constants.StatusInternalServerError,
-			Message: "Failed to analyze webpage",
-			Details: err.Error(),
-		})
+		var analysisErr *services.AnalysisError
+		if errors.As(err, &analysisErr) {
+			setRetryAfter(c, analysisErr.RetryAfter)
+			h.respondError(c, analysisErr.Status, analysisErr.Code, analysisErr.Message, err.Error())
+			return
+		}
+		h.respondError(c, constants.StatusInternalServerError, constants.ErrorCodeAnalysisFailed, "Failed to analyze webpage", err.Error())
+		return
+	}
+
+	if result.Partial {
+		h.respondPartial(c, result)
+		return
+	}
+	h.respondCacheable(c, result)
+}
+
+// HandleHTML analyzes HTML submitted either as JSON or as a multipart file upload
+func (h *AnalyzeHandler) HandleHTML(c *gin.Context) {
+	var baseURL string
+	start := time.Now()
+	defer func() { h.audit(c, constants.AuditOperationAnalyzeHTML, baseURL, start) }()
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBodyBytes+constants.MaxBodyOverheadBytes)
+
+	req, err := h.bindHTMLRequest(c)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) || errors.Is(err, errHTMLTooLarge) {
+			h.respondError(c, constants.StatusRequestEntityTooLarge, constants.ErrorCodePayloadTooLarge,
+				"Submitted HTML is too large", fmt.Sprintf("maximum size is %d bytes", h.maxBodyBytes))
+			return
+		}
+		h.respondError(c, constants.StatusBadRequest, constants.ErrorCodeInvalidRequest, "Invalid request body", err.Error())
+		return
+	}
+	baseURL = req.BaseURL
+
+	// Validate request
+	if err := h.validator.Struct(req); err != nil {
+		h.respondError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", err.Error())
+		return
+	}
+
+	// Custom validation
+	if err := req.Validate(); err != nil {
+		h.respondValidationError(c, err)
+		return
+	}
+
+	result, err := h.analyzer.AnalyzeHTML(analysisContext(c), req.HTML, req.BaseURL, req.Options)
+	if err != nil {
+		h.logger.Error("Failed to analyze submitted HTML",
+			zap.String("base_url", models.StripCredentials(req.BaseURL)),
+			zap.Error(err),
+		)
+		var analysisErr *services.AnalysisError
+		if errors.As(err, &analysisErr) {
+			setRetryAfter(c, analysisErr.RetryAfter)
+			h.respondError(c, analysisErr.Status, analysisErr.Code, analysisErr.Message, err.Error())
+			return
+		}
+		h.respondError(c, constants.StatusInternalServerError, constants.ErrorCodeAnalysisFailed, "Failed to analyze HTML", err.Error())
 		return
 	}
 
+	if result.Partial {
+		h.respondPartial(c, result)
+		return
+	}
 	c.JSON(constants.StatusOK, result)
+}
+
+var errHTMLTooLarge = errors.New("submitted HTML exceeds maximum size")
+
+// bindHTMLRequest reads an AnalyzeHTMLRequest from a JSON body or a multipart form
+func (h *AnalyzeHandler) bindHTMLRequest(c *gin.Context) (*models.AnalyzeHTMLRequest, error) {
+	var req models.AnalyzeHTMLRequest
+
+	if c.ContentType() != gin.MIMEMultipartPOSTForm {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, err
+		}
+		if int64(len(req.HTML)) > h.maxBodyBytes {
+			return nil, errHTMLTooLarge
+		}
+		return &req, nil
+	}
+
+	fileHeader, err := c.FormFile(constants.FormFieldHTMLFile)
+	if err != nil {
+		return nil, fmt.Errorf("missing %q file upload: %w", constants.FormFieldHTMLFile, err)
+	}
+	if fileHeader.Size > h.maxBodyBytes {
+		return nil, errHTMLTooLarge
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, h.maxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if int64(len(content)) > h.maxBodyBytes {
+		return nil, errHTMLTooLarge
+	}
+
+	req.HTML = string(content)
+	req.BaseURL = c.PostForm(constants.FormFieldBaseURL)
+	if err := c.ShouldBind(&req.Options); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// respondValidationError reports a rejected request, with the specific code of a rejected URL
+func (h *AnalyzeHandler) respondValidationError(c *gin.Context, err error) {
+	code := constants.ErrorCodeValidation
+	var urlErr *models.URLError
+	if errors.As(err, &urlErr) {
+		code = urlErr.Code
+	}
+	h.respondError(c, constants.StatusBadRequest, code, "Validation failed", err.Error())
+}
+
+// analysisContext returns the context of an analysis requested by c: it is charged to the
+// client and its analysis ID is the request ID
+func analysisContext(c *gin.Context) context.Context {
+	ctx := services.WithClient(c.Request.Context(), c.ClientIP())
+	return services.WithAnalysisID(ctx, middleware.GetRequestID(c))
+}
+
+// respondError writes a standard ErrorResponse tagged with the request ID
+func (h *AnalyzeHandler) respondError(c *gin.Context, status int, errorCode, message, details string) {
+	writeError(c, status, errorCode, message, details)
+}
+
+// writeError writes the standard error response body
+func writeError(c *gin.Context, status int, errorCode, message, details string) {
+	c.Set(constants.ContextKeyErrorCode, errorCode)
+	c.JSON(status, models.ErrorResponse{
+		Code:      status,
+		ErrorCode: errorCode,
+		Message:   message,
+		Details:   details,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
+
+// audit records the outcome of a finished analysis request
+func (h *AnalyzeHandler) audit(c *gin.Context, operation, targetURL string, start time.Time) {
+	status := c.Writer.Status()
+	h.auditor.Record(audit.Event{
+		Timestamp:  start,
+		RequestID:  middleware.GetRequestID(c),
+		ClientIP:   c.ClientIP(),
+		APIKeyID:   audit.KeyID(c.GetHeader(constants.HeaderAPIKey)),
+		Operation:  operation,
+		TargetURL:  models.StripCredentials(targetURL),
+		Outcome:    audit.Outcome(status),
+		Status:     status,
+		ErrorCode:  c.GetString(constants.ContextKeyErrorCode),
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+}
+
+// respondPartial writes an analysis cut short by the deadline. Partial results are never cacheable.
+func (h *AnalyzeHandler) respondPartial(c *gin.Context, result *models.AnalyzeResponse) {
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	c.JSON(constants.StatusPartialContent, result)
+}
+
+// setRetryAfter passes a retry delay on to the client, rounded up to whole seconds
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		return
+	}
+	seconds := (retryAfter + time.Second - 1) / time.Second
+	c.Header(constants.HeaderRetryAfter, fmt.Sprintf("%d", seconds))
+}
+
+// respondCacheable writes the analysis result with ETag and Cache-Control headers,
+// answering with 304 Not Modified when the client already holds the same representation
+func (h *AnalyzeHandler) respondCacheable(c *gin.Context, result *models.AnalyzeResponse) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error("Failed to marshal analysis result", zap.Error(err))
+		c.JSON(constants.StatusOK, result)
+		return
+	}
+
+	// The cache lookup time and analysis ID differ on every request; the entity tag covers the
+	// cached analysis only
+	tagged := body
+	if result.ServedFromCacheInMs != nil || result.AnalysisID != "" {
+		unserved := *result
+		unserved.ServedFromCacheInMs = nil
+		unserved.AnalysisID = ""
+		if tagged, err = json.Marshal(&unserved); err != nil {
+			tagged = body
+		}
+	}
+
+	etag := computeETag(tagged)
+	c.Header(constants.HeaderETag, etag)
+	c.Header(constants.HeaderCacheControl, cacheControlValue(result))
+
+	if etagMatches(c.GetHeader(constants.HeaderIfNoneMatch), etag) {
+		c.Status(constants.StatusNotModified)
+		return
+	}
+
+	c.Data(constants.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// computeETag returns a weak entity tag for the serialized analysis; the response body adds
+// per-request fields to it, so equal tags mean the same analysis, not the same bytes
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// cacheControlValue derives the Cache-Control directive from the remaining cache TTL
+func cacheControlValue(result *models.AnalyzeResponse) string {
+	maxAge := int(result.CacheTTL.Seconds())
+	if maxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("max-age=%d", maxAge)
+}
+
+// etagMatches reports whether an If-None-Match header value matches the given entity tag,
+// comparing tags weakly as If-None-Match requires
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
 } 
\ No newline at end of file
@@ -0,0 +1,743 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/audit"
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/middleware"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+// MockCache is a mock implementation of services.CacheInterface
+type MockCache struct {
+	mock.Mock
+}
+
+func (m *MockCache) Get(ctx context.Context, url string) (*models.AnalyzeResponse, time.Duration, error) {
+	args := m.Called(ctx, url)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).(*models.AnalyzeResponse), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockCache) Set(ctx context.Context, url string, result *models.AnalyzeResponse, ttl time.Duration) error {
+	args := m.Called(ctx, url, result, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCache) GetMulti(ctx context.Context, urls []string) (map[string]*models.AnalyzeResponse, error) {
+	args := m.Called(ctx, urls)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]*models.AnalyzeResponse), args.Error(1)
+}
+
+func (m *MockCache) SetMulti(ctx context.Context, results map[string]*models.AnalyzeResponse, ttl time.Duration) error {
+	args := m.Called(ctx, results, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCache) Stats(ctx context.Context) (*models.CacheStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CacheStats), args.Error(1)
+}
+
+func (m *MockCache) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func newTestMetrics() *metrics.Metrics {
+	return metrics.NewWithRegistry(prometheus.NewRegistry())
+}
+
+func newTestEngine(t *testing.T, cache services.CacheInterface) *gin.Engine {
+	return newTestEngineWithConfig(t, &config.Config{
+		Cache: config.CacheConfig{Enabled: true, TTL: time.Hour},
+	}, cache)
+}
+
+func newTestEngineWithConfig(t *testing.T, cfg *config.Config, cache services.CacheInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	logger := zaptest.NewLogger(t)
+	analyzer := services.NewAnalyzer(cfg, logger, newTestMetrics(), cache)
+	handler := NewAnalyzeHandler(cfg, logger, analyzer)
+
+	engine := gin.New()
+	engine.POST("/api/v1/analyze", handler.Handle)
+	engine.POST("/api/v1/analyze/html", handler.HandleHTML)
+	return engine
+}
+
+func newTargetServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Fixture</title></head><body><h1>Hello</h1></body></html>`))
+	}))
+}
+
+func doAnalyze(engine *gin.Engine, url, ifNoneMatch string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(models.AnalyzeRequest{URL: url})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/analyze", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifNoneMatch != "" {
+		req.Header.Set(constants.HeaderIfNoneMatch, ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestAnalyzeHandler_FreshResultCachingHeaders(t *testing.T) {
+	server := newTargetServer()
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+
+	w := doAnalyze(newTestEngine(t, cache), server.URL, "")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "max-age=3600", w.Header().Get(constants.HeaderCacheControl))
+
+	var result models.AnalyzeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "Fixture", result.Title)
+	// The entity tag leaves out the analysis ID, which differs on every request
+	assert.NotEmpty(t, result.AnalysisID)
+	result.AnalysisID = ""
+	untagged, err := json.Marshal(&result)
+	require.NoError(t, err)
+	assert.Equal(t, computeETag(untagged), w.Header().Get(constants.HeaderETag))
+	cache.AssertExpectations(t)
+}
+
+func TestAnalyzeHandler_AnalysisIDIsRequestID(t *testing.T) {
+	server := newTargetServer()
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.Anything, mock.Anything).Return(nil)
+	cfg := &config.Config{Cache: config.CacheConfig{Enabled: true, TTL: time.Hour}}
+	handler := NewAnalyzeHandler(cfg, zaptest.NewLogger(t), services.NewAnalyzer(cfg, zaptest.NewLogger(t), newTestMetrics(), cache))
+	engine := gin.New()
+	engine.Use(middleware.RequestID())
+	engine.POST("/api/v1/analyze", handler.Handle)
+
+	body, _ := json.Marshal(models.AnalyzeRequest{URL: server.URL})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/analyze", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.HeaderRequestID, "req-1234")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var result models.AnalyzeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "req-1234", result.AnalysisID)
+}
+
+func TestAnalyzeHandler_CachedResultUsesRemainingTTL(t *testing.T) {
+	cached := &models.AnalyzeResponse{
+		URL:        "http://example.com",
+		Title:      "Cached",
+		Headings:   map[string]int{"h1": 1},
+		AnalyzedAt: time.Date(2024, 3, 19, 10, 30, 0, 0, time.UTC),
+	}
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, "http://example.com").Return(cached, 25*time.Minute, nil)
+
+	engine := newTestEngine(t, cache)
+	first := doAnalyze(engine, "http://example.com", "")
+	second := doAnalyze(engine, "http://example.com", "")
+
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "max-age=1500", first.Header().Get(constants.HeaderCacheControl))
+	assert.NotEmpty(t, first.Header().Get(constants.HeaderETag))
+	assert.Equal(t, first.Header().Get(constants.HeaderETag), second.Header().Get(constants.HeaderETag))
+	assert.Contains(t, first.Body.String(), `"served_from_cache_in_ms":`)
+	cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnalyzeHandler_IfNoneMatchReturnsNotModified(t *testing.T) {
+	cached := &models.AnalyzeResponse{
+		URL:        "http://example.com",
+		Title:      "Cached",
+		Headings:   map[string]int{"h1": 1},
+		AnalyzedAt: time.Date(2024, 3, 19, 10, 30, 0, 0, time.UTC),
+	}
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, "http://example.com").Return(cached, 10*time.Minute, nil)
+
+	engine := newTestEngine(t, cache)
+	first := doAnalyze(engine, "http://example.com", "")
+	etag := first.Header().Get(constants.HeaderETag)
+	require.True(t, strings.HasPrefix(etag, `W/"`), "the entity tag is weak: %s", etag)
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		expected    int
+	}{
+		{name: "Matching ETag", ifNoneMatch: etag, expected: http.StatusNotModified},
+		{name: "Strong form of matching ETag", ifNoneMatch: strings.TrimPrefix(etag, "W/"), expected: http.StatusNotModified},
+		{name: "ETag in list", ifNoneMatch: `"other", ` + etag, expected: http.StatusNotModified},
+		{name: "Wildcard", ifNoneMatch: "*", expected: http.StatusNotModified},
+		{name: "Stale ETag", ifNoneMatch: `"stale"`, expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doAnalyze(engine, "http://example.com", tt.ifNoneMatch)
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Equal(t, etag, w.Header().Get(constants.HeaderETag))
+			if tt.expected == http.StatusNotModified {
+				assert.Empty(t, w.Body.Bytes())
+				assert.Equal(t, "max-age=600", w.Header().Get(constants.HeaderCacheControl))
+			}
+		})
+	}
+}
+
+func TestCacheControlValue_WithoutTTL(t *testing.T) {
+	assert.Equal(t, "no-cache", cacheControlValue(&models.AnalyzeResponse{}))
+}
+
+const fixtureHTML = `<!DOCTYPE html>
+<html>
+<head><title>Pre-publish Draft</title></head>
+<body>
+	<h1>Launch</h1>
+	<h2>Details</h2>
+	<a href="/pricing">Pricing</a>
+	<a href="https://external.example/docs">Docs</a>
+</body>
+</html>`
+
+func postJSON(engine *gin.Engine, path string, payload interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func postMultipart(t *testing.T, engine *gin.Engine, html string, fields map[string]string) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile(constants.FormFieldHTMLFile, "page.html")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(html))
+	require.NoError(t, err)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/analyze/html", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestAnalyzeHandler_HandleHTML_JSON(t *testing.T) {
+	engine := newTestEngine(t, &MockCache{})
+
+	w := postJSON(engine, "/api/v1/analyze/html", models.AnalyzeHTMLRequest{
+		HTML:    fixtureHTML,
+		BaseURL: "https://site.example/page",
+		Options: models.AnalyzeOptions{SkipLinkCheck: true},
+	})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var result models.AnalyzeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "https://site.example/page", result.URL)
+	assert.Equal(t, "Pre-publish Draft", result.Title)
+	assert.Equal(t, 1, result.Headings["h1"])
+	assert.Equal(t, 1, result.Links.Internal)
+	assert.Equal(t, 1, result.Links.External)
+	assert.Equal(t, 0, result.Links.Inaccessible)
+}
+
+func TestAnalyzeHandler_HandleHTML_Multipart(t *testing.T) {
+	engine := newTestEngine(t, &MockCache{})
+
+	w := postMultipart(t, engine, fixtureHTML, map[string]string{
+		constants.FormFieldBaseURL: "https://site.example/page",
+		"skip_link_check":          "true",
+	})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var result models.AnalyzeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "Pre-publish Draft", result.Title)
+	assert.Equal(t, 1, result.Headings["h2"])
+}
+
+func TestAnalyzeHandler_HandleHTML_Errors(t *testing.T) {
+	cfg := &config.Config{Analyzer: config.AnalyzerConfig{MaxBodyBytes: 64}}
+	engine := newTestEngineWithConfig(t, cfg, &MockCache{})
+	oversized := "<html><body>" + strings.Repeat("x", 100) + "</body></html>"
+
+	t.Run("JSON body over the size limit", func(t *testing.T) {
+		w := postJSON(engine, "/api/v1/analyze/html", models.AnalyzeHTMLRequest{HTML: oversized, BaseURL: "https://site.example"})
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodePayloadTooLarge)
+	})
+
+	t.Run("Uploaded file over the size limit", func(t *testing.T) {
+		w := postMultipart(t, engine, oversized, map[string]string{constants.FormFieldBaseURL: "https://site.example"})
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodePayloadTooLarge)
+	})
+
+	t.Run("Missing base URL", func(t *testing.T) {
+		w := postJSON(engine, "/api/v1/analyze/html", models.AnalyzeHTMLRequest{HTML: "<p>hi</p>"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeValidation)
+	})
+
+	t.Run("Unsupported base URL scheme", func(t *testing.T) {
+		w := postJSON(engine, "/api/v1/analyze/html", models.AnalyzeHTMLRequest{HTML: "<p>hi</p>", BaseURL: "ftp://site.example"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeFTPURL)
+	})
+
+	t.Run("Multipart without file", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		require.NoError(t, writer.WriteField(constants.FormFieldBaseURL, "https://site.example"))
+		require.NoError(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/analyze/html", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeInvalidRequest)
+	})
+}
+
+func TestAnalyzeHandler_BotProtectionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<html><head><title>Just a moment...</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+
+	w := doAnalyze(newTestEngine(t, cache), server.URL, "")
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assertErrorCode(t, w, constants.ErrorCodeBotProtection)
+	cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnalyzeHandler_TargetRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`<html><head><title>Slow down</title></head><body><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a><a href="/d">D</a></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+
+	w := doAnalyze(newTestEngine(t, cache), server.URL, "")
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "120", w.Header().Get(constants.HeaderRetryAfter))
+	assertErrorCode(t, w, constants.ErrorCodeTargetRateLimited)
+	cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnalyzeHandler_PerTargetRateLimit(t *testing.T) {
+	server := newTargetServer()
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	engine := newTestEngineWithConfig(t, &config.Config{
+		Cache:     config.CacheConfig{Enabled: true, TTL: time.Hour},
+		RateLimit: config.RateLimitConfig{Enabled: true, PerTargetPerMinute: 1},
+	}, cache)
+
+	// Each client stays under any limit of its own, but they all analyze the same host
+	for i, client := range []string{"192.0.2.1:1234", "192.0.2.2:1234", "198.51.100.7:1234"} {
+		body, _ := json.Marshal(models.AnalyzeRequest{URL: fmt.Sprintf("%s/page-%d", server.URL, i)})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/analyze", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = client
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		if i == 0 {
+			assert.Equal(t, http.StatusOK, w.Code)
+			continue
+		}
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeTargetQuotaExceeded)
+		assert.NotEmpty(t, w.Header().Get(constants.HeaderRetryAfter))
+	}
+}
+
+func TestAnalyzeHandler_PartialResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`<html><head><title>Partial</title></head><body><a href="/slow">Slow</a></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	engine := newTestEngineWithConfig(t, &config.Config{
+		Analyzer: config.AnalyzerConfig{AnalysisTimeout: 100 * time.Millisecond},
+	}, cache)
+
+	w := postJSON(engine, "/api/v1/analyze", models.AnalyzeRequest{
+		URL:     server.URL,
+		Options: models.AnalyzeOptions{AllowPartial: true},
+	})
+
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, constants.CacheControlNoStore, w.Header().Get(constants.HeaderCacheControl))
+	assert.Empty(t, w.Header().Get(constants.HeaderETag))
+
+	var result models.AnalyzeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.Partial)
+	assert.Equal(t, "Partial", result.Title)
+	assert.NotContains(t, result.CompletedSections, constants.SectionLinks)
+
+	t.Run("Times out without allow_partial", func(t *testing.T) {
+		w := doAnalyze(engine, server.URL, "")
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeAnalysisTimeout)
+	})
+}
+
+func assertErrorCode(t *testing.T, w *httptest.ResponseRecorder, expected string) {
+	t.Helper()
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, expected, resp.ErrorCode)
+}
+
+func TestAnalyzeHandler_RejectedSchemes(t *testing.T) {
+	engine := newTestEngine(t, &MockCache{})
+
+	tests := []struct {
+		url  string
+		code string
+	}{
+		{"data:text/html,<p>hello</p>", constants.ErrorCodeDataURL},
+		{"file:///etc/passwd", constants.ErrorCodeFileURL},
+		{"ftp://files.example.com/", constants.ErrorCodeFTPURL},
+		{"ws://example.com/socket", constants.ErrorCodeWebSocketURL},
+		{"mailto:someone@example.com", constants.ErrorCodeUnsupportedScheme},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			w := doAnalyze(engine, tt.url, "")
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assertErrorCode(t, w, tt.code)
+		})
+	}
+}
+
+// memorySink keeps audit events for inspection
+type memorySink struct {
+	events []audit.Event
+}
+
+func (s *memorySink) Write(ctx context.Context, event audit.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestAnalyzeHandler_RecordsAuditEvents(t *testing.T) {
+	server := newTargetServer()
+	defer server.Close()
+
+	gin.SetMode(gin.TestMode)
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	cfg := &config.Config{Cache: config.CacheConfig{Enabled: true, TTL: time.Hour}}
+	logger := zaptest.NewLogger(t)
+	handler := NewAnalyzeHandler(cfg, logger, services.NewAnalyzer(cfg, logger, newTestMetrics(), cache))
+	sink := &memorySink{}
+	auditor := audit.NewAuditor(sink, 10, logger, nil)
+	handler.SetAuditor(auditor)
+
+	engine := gin.New()
+	engine.POST("/api/v1/analyze", handler.Handle)
+	engine.POST("/api/v1/analyze/html", handler.HandleHTML)
+
+	target := strings.Replace(server.URL, "http://", "http://user:secret@", 1)
+	body, _ := json.Marshal(models.AnalyzeRequest{URL: target, Options: models.AnalyzeOptions{SkipLinkCheck: true}})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/analyze", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.HeaderAPIKey, "secret-key")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	postJSON(engine, "/api/v1/analyze/html", map[string]string{"html": "<html></html>", "base_url": "ftp://example.com"})
+	require.NoError(t, auditor.Close())
+
+	require.Len(t, sink.events, 2)
+	success := sink.events[0]
+	assert.Equal(t, constants.AuditOperationAnalyze, success.Operation)
+	assert.Equal(t, server.URL, success.TargetURL, "credentials are stripped")
+	assert.Equal(t, audit.KeyID("secret-key"), success.APIKeyID)
+	assert.Equal(t, constants.AuditOutcomeSuccess, success.Outcome)
+	assert.Equal(t, http.StatusOK, success.Status)
+	assert.Empty(t, success.ErrorCode)
+	assert.NotEmpty(t, success.ClientIP)
+	assert.False(t, success.Timestamp.IsZero())
+
+	rejected := sink.events[1]
+	assert.Equal(t, constants.AuditOperationAnalyzeHTML, rejected.Operation)
+	assert.Equal(t, "ftp://example.com", rejected.TargetURL)
+	assert.Equal(t, constants.AuditOutcomeRejected, rejected.Outcome)
+	assert.Equal(t, http.StatusBadRequest, rejected.Status)
+	assert.NotEmpty(t, rejected.ErrorCode)
+	assert.Empty(t, rejected.APIKeyID)
+}
+
+// MockAnalyzer is a mock implementation of AnalyzerInterface
+type MockAnalyzer struct {
+	mock.Mock
+}
+
+func (m *MockAnalyzer) Analyze(ctx context.Context, targetURL string) (*models.AnalyzeResponse, error) {
+	return m.AnalyzeWithOptions(ctx, targetURL, models.AnalyzeOptions{})
+}
+
+func (m *MockAnalyzer) AnalyzeWithOptions(ctx context.Context, targetURL string, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error) {
+	args := m.Called(ctx, targetURL, opts)
+	result, _ := args.Get(0).(*models.AnalyzeResponse)
+	return result, args.Error(1)
+}
+
+func (m *MockAnalyzer) AnalyzeHTML(ctx context.Context, htmlContent, baseURL string, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error) {
+	args := m.Called(ctx, htmlContent, baseURL, opts)
+	result, _ := args.Get(0).(*models.AnalyzeResponse)
+	return result, args.Error(1)
+}
+
+// newMockEngine serves the analyze endpoints from a mock analyzer, without any network
+func newMockEngine(t *testing.T, analyzer *MockAnalyzer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handler := NewAnalyzeHandler(&config.Config{}, zaptest.NewLogger(t), analyzer)
+
+	engine := gin.New()
+	engine.POST("/api/v1/analyze", handler.Handle)
+	engine.POST("/api/v1/analyze/html", handler.HandleHTML)
+	engine.GET("/api/v1/analyze/report.pdf", handler.HandleReport)
+	return engine
+}
+
+func TestAnalyzeHandler_MockAnalyzer_Success(t *testing.T) {
+	analyzer := &MockAnalyzer{}
+	opts := models.AnalyzeOptions{SkipLinkCheck: true}
+	analyzer.On("AnalyzeWithOptions", mock.Anything, "https://example.com", opts).
+		Return(&models.AnalyzeResponse{URL: "https://example.com", Title: "Example", CacheTTL: time.Minute}, nil)
+	analyzer.On("AnalyzeHTML", mock.Anything, "<p>hi</p>", "https://site.example", models.AnalyzeOptions{}).
+		Return(&models.AnalyzeResponse{URL: "https://site.example", Title: "Submitted"}, nil)
+	engine := newMockEngine(t, analyzer)
+
+	w := postJSON(engine, "/api/v1/analyze", models.AnalyzeRequest{URL: "https://example.com", Options: opts})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "max-age=60", w.Header().Get(constants.HeaderCacheControl))
+	var result models.AnalyzeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "Example", result.Title)
+
+	w = postJSON(engine, "/api/v1/analyze/html", models.AnalyzeHTMLRequest{HTML: "<p>hi</p>", BaseURL: "https://site.example"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "Submitted", result.Title)
+
+	analyzer.AssertExpectations(t)
+}
+
+func TestAnalyzeHandler_MockAnalyzer_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		retryAfter     string
+	}{
+		{
+			name:           "Bot protection",
+			err:            &services.AnalysisError{Code: constants.ErrorCodeBotProtection, Status: constants.StatusBadGateway, Message: "challenge"},
+			expectedStatus: http.StatusBadGateway,
+			expectedCode:   constants.ErrorCodeBotProtection,
+		},
+		{
+			name:           "Target rate limited",
+			err:            &services.AnalysisError{Code: constants.ErrorCodeTargetRateLimited, Status: constants.StatusServiceUnavailable, Message: "slow down", RetryAfter: 1500 * time.Millisecond},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   constants.ErrorCodeTargetRateLimited,
+			retryAfter:     "2",
+		},
+		{
+			name:           "Analysis timeout",
+			err:            &services.AnalysisError{Code: constants.ErrorCodeAnalysisTimeout, Status: constants.StatusGatewayTimeout, Message: "deadline", Err: context.DeadlineExceeded},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedCode:   constants.ErrorCodeAnalysisTimeout,
+		},
+		{
+			name:           "Empty document",
+			err:            &services.AnalysisError{Code: constants.ErrorCodeEmptyDocument, Status: constants.StatusUnprocessableEntity, Message: "empty"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   constants.ErrorCodeEmptyDocument,
+		},
+		{
+			name:           "Target blocked",
+			err:            &services.AnalysisError{Code: constants.ErrorCodeTargetBlocked, Status: constants.StatusForbidden, Message: "blocked"},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   constants.ErrorCodeTargetBlocked,
+		},
+		{
+			name:           "Unclassified failure",
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   constants.ErrorCodeAnalysisFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := &MockAnalyzer{}
+			analyzer.On("AnalyzeWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(nil, tt.err)
+			analyzer.On("AnalyzeHTML", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, tt.err)
+			engine := newMockEngine(t, analyzer)
+
+			for _, w := range []*httptest.ResponseRecorder{
+				postJSON(engine, "/api/v1/analyze", models.AnalyzeRequest{URL: "https://example.com"}),
+				postJSON(engine, "/api/v1/analyze/html", models.AnalyzeHTMLRequest{HTML: "<p>hi</p>", BaseURL: "https://site.example"}),
+			} {
+				assert.Equal(t, tt.expectedStatus, w.Code)
+				assertErrorCode(t, w, tt.expectedCode)
+				assert.Equal(t, tt.retryAfter, w.Header().Get(constants.HeaderRetryAfter))
+			}
+		})
+	}
+}
+
+func TestAnalyzeHandler_MockAnalyzer_PartialResult(t *testing.T) {
+	analyzer := &MockAnalyzer{}
+	analyzer.On("AnalyzeWithOptions", mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.AnalyzeResponse{Partial: true, CompletedSections: []string{constants.SectionTitle}}, nil)
+
+	w := postJSON(newMockEngine(t, analyzer), "/api/v1/analyze", models.AnalyzeRequest{URL: "https://example.com", Options: models.AnalyzeOptions{AllowPartial: true}})
+
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, constants.CacheControlNoStore, w.Header().Get(constants.HeaderCacheControl))
+}
+
+func TestAnalyzeHandler_MockAnalyzer_WarningsKeepStatus(t *testing.T) {
+	warnings := []models.Warning{{Code: constants.WarningCodeCacheWriteFailed, Message: "The result could not be cached"}}
+	analyzer := &MockAnalyzer{}
+	analyzer.On("AnalyzeWithOptions", mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.AnalyzeResponse{URL: "https://example.com", Warnings: warnings}, nil)
+
+	w := postJSON(newMockEngine(t, analyzer), "/api/v1/analyze", models.AnalyzeRequest{URL: "https://example.com"})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var result models.AnalyzeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, warnings, result.Warnings)
+}
+
+func TestAnalyzeHandler_MockAnalyzer_Validation(t *testing.T) {
+	analyzer := &MockAnalyzer{}
+	engine := newMockEngine(t, analyzer)
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		expectedCode string
+	}{
+		{name: "Malformed JSON", path: "/api/v1/analyze", body: `{"url":`, expectedCode: constants.ErrorCodeInvalidRequest},
+		{name: "Missing URL", path: "/api/v1/analyze", body: `{}`, expectedCode: constants.ErrorCodeValidation},
+		{name: "Relative URL", path: "/api/v1/analyze", body: `{"url": "/about"}`, expectedCode: constants.ErrorCodeValidation},
+		{name: "Unsupported scheme", path: "/api/v1/analyze", body: `{"url": "ftp://files.example.com/"}`, expectedCode: constants.ErrorCodeFTPURL},
+		{name: "Malformed Accept-Language", path: "/api/v1/analyze", body: `{"url": "https://example.com", "options": {"accept_language": "en;q=2"}}`, expectedCode: constants.ErrorCodeValidation},
+		{name: "Negative raw links offset", path: "/api/v1/analyze", body: `{"url": "https://example.com", "options": {"include_raw_links": true, "raw_links_offset": -1}}`, expectedCode: constants.ErrorCodeValidation},
+		{name: "Missing HTML", path: "/api/v1/analyze/html", body: `{"base_url": "https://site.example"}`, expectedCode: constants.ErrorCodeValidation},
+		{name: "Unsupported base URL scheme", path: "/api/v1/analyze/html", body: `{"html": "<p>hi</p>", "base_url": "data:text/html,x"}`, expectedCode: constants.ErrorCodeDataURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assertErrorCode(t, w, tt.expectedCode)
+		})
+	}
+
+	// Rejected requests never reach the analyzer
+	analyzer.AssertNotCalled(t, "AnalyzeWithOptions", mock.Anything, mock.Anything, mock.Anything)
+	analyzer.AssertNotCalled(t, "AnalyzeHTML", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
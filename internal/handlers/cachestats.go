@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// CacheStatsReader reports on the analysis cache
+type CacheStatsReader interface {
+	Stats(ctx context.Context) (*models.CacheStats, error)
+}
+
+// CacheStatsHandler serves the state of the analysis cache, for operators without access to
+// the metrics dashboards
+type CacheStatsHandler struct {
+	logger *zap.Logger
+	cache  CacheStatsReader
+}
+
+// NewCacheStatsHandler creates a new CacheStatsHandler instance
+func NewCacheStatsHandler(logger *zap.Logger, cache CacheStatsReader) *CacheStatsHandler {
+	return &CacheStatsHandler{
+		logger: logger,
+		cache:  cache,
+	}
+}
+
+// Stats returns the backend, the lookups and hit ratio since the process started, the number
+// of cached analyses and the configured TTL
+func (h *CacheStatsHandler) Stats(c *gin.Context) {
+	stats, err := h.cache.Stats(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to read cache stats", zap.Error(err))
+		writeError(c, constants.StatusInternalServerError, constants.ErrorCodeInternal, "Failed to read cache stats", "")
+		return
+	}
+
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	c.JSON(constants.StatusOK, stats)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func getCacheStats(t *testing.T, cache CacheStatsReader) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/admin/cache/stats", NewCacheStatsHandler(zaptest.NewLogger(t), cache).Stats)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/cache/stats", nil))
+	return w
+}
+
+func TestCacheStatsHandler_Stats(t *testing.T) {
+	stats := &models.CacheStats{
+		Backend:       constants.CacheBackendRedis,
+		Hits:          30,
+		Misses:        10,
+		HitRatio:      0.75,
+		Entries:       42,
+		EntriesMethod: constants.CacheEntriesMethodScan,
+		TTLSeconds:    3600,
+	}
+	cache := &MockCache{}
+	cache.On("Stats", mock.Anything).Return(stats, nil)
+
+	w := getCacheStats(t, cache)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, constants.CacheControlNoStore, w.Header().Get(constants.HeaderCacheControl))
+	assert.JSONEq(t, `{"backend":"redis","hits":30,"misses":10,"hit_ratio":0.75,"entries":42,"entries_method":"scan","ttl_seconds":3600}`, w.Body.String())
+}
+
+func TestCacheStatsHandler_Failure(t *testing.T) {
+	cache := &MockCache{}
+	cache.On("Stats", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	w := getCacheStats(t, cache)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, constants.ErrorCodeInternal, resp.ErrorCode)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// CapabilitiesHandler serves the features and limits of the server, so clients can adapt to
+// them instead of discovering them from failed requests
+type CapabilitiesHandler struct {
+	capabilities models.Capabilities
+}
+
+// NewCapabilitiesHandler creates a new CapabilitiesHandler instance serving capabilities
+func NewCapabilitiesHandler(capabilities models.Capabilities) *CapabilitiesHandler {
+	return &CapabilitiesHandler{capabilities: capabilities}
+}
+
+// Get returns the capabilities. The configuration is read once at startup, so they only
+// change on restart.
+func (h *CapabilitiesHandler) Get(c *gin.Context) {
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoCache)
+	c.JSON(constants.StatusOK, h.capabilities)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+func TestCapabilitiesHandler_Get(t *testing.T) {
+	cfg := &config.Config{
+		Analyzer: config.AnalyzerConfig{
+			MaxLinks:        250,
+			MaxBodyBytes:    2048,
+			AnalysisTimeout: constants.DefaultAnalysisTimeout,
+			JSRendering:     config.JSRenderingConfig{Enabled: true, Endpoint: "ws://chrome.internal:9222"},
+		},
+		Cache:     config.CacheConfig{Enabled: true, Redis: config.RedisConfig{Host: "redis.internal", Password: "cache-secret"}},
+		Admin:     config.AdminConfig{Token: "admin-secret"},
+		RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerMinute: 60},
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/v1/capabilities", NewCapabilitiesHandler(services.Capabilities(cfg)).Get)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/capabilities", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, constants.CacheControlNoCache, w.Header().Get(constants.HeaderCacheControl))
+	assert.JSONEq(t, `{
+		"limits": {
+			"max_links": 250,
+			"max_body_bytes": 2048,
+			"analysis_timeout_seconds": 25,
+			"max_redirects": 0,
+			"raw_links_page_size": 1000,
+			"requests_per_minute": 60
+		},
+		"features": {
+			"render_js": true,
+			"link_sampling": false,
+			"cache": true,
+			"analyses": false,
+			"duplicates": false,
+			"rate_limit": true,
+			"snapshots": false
+		},
+		"formats": ["json", "pdf"]
+	}`, w.Body.String())
+	for _, secret := range []string{"chrome.internal", "redis.internal", "cache-secret", "admin-secret"} {
+		assert.NotContains(t, w.Body.String(), secret)
+	}
+}
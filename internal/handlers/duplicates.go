@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+// DuplicateFinder finds analyzed pages sharing a page's title or meta description.
+// *services.Analyzer implements it.
+type DuplicateFinder interface {
+	FindDuplicates(ctx context.Context, targetURL string) (*models.Duplicates, error)
+}
+
+// DuplicatesHandler serves site-wide duplicate title and meta description detection
+type DuplicatesHandler struct {
+	logger *zap.Logger
+	finder DuplicateFinder
+}
+
+// NewDuplicatesHandler creates a new DuplicatesHandler instance
+func NewDuplicatesHandler(logger *zap.Logger, finder DuplicateFinder) *DuplicatesHandler {
+	return &DuplicatesHandler{
+		logger: logger,
+		finder: finder,
+	}
+}
+
+// List returns the other analyzed pages on the registered domain of the url query parameter
+// that share its title or meta description
+func (h *DuplicatesHandler) List(c *gin.Context) {
+	targetURL := c.Query("url")
+	if targetURL == "" {
+		writeError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", "url is required")
+		return
+	}
+
+	duplicates, err := h.finder.FindDuplicates(c.Request.Context(), targetURL)
+	if err != nil {
+		var analysisErr *services.AnalysisError
+		if errors.As(err, &analysisErr) {
+			writeError(c, analysisErr.Status, analysisErr.Code, analysisErr.Message, "")
+			return
+		}
+		h.logger.Error("Failed to find duplicates", zap.String("url", models.StripCredentials(targetURL)), zap.Error(err))
+		writeError(c, constants.StatusInternalServerError, constants.ErrorCodeInternal, "Failed to find duplicates", "")
+		return
+	}
+
+	c.JSON(constants.StatusOK, duplicates)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+// duplicateFinder returns fixed duplicates or an error, recording the URL asked for
+type duplicateFinder struct {
+	duplicates *models.Duplicates
+	err        error
+	url        string
+}
+
+func (f *duplicateFinder) FindDuplicates(_ context.Context, targetURL string) (*models.Duplicates, error) {
+	f.url = targetURL
+	return f.duplicates, f.err
+}
+
+func getDuplicates(t *testing.T, finder DuplicateFinder, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/v1/duplicates", NewDuplicatesHandler(zaptest.NewLogger(t), finder).List)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/duplicates"+query, nil))
+	return w
+}
+
+func TestDuplicatesHandler_List(t *testing.T) {
+	finder := &duplicateFinder{duplicates: &models.Duplicates{
+		URL:                 "https://example.com/a",
+		Domain:              "example.com",
+		Title:               "Welcome",
+		MetaDescription:     "",
+		SameTitle:           []string{"https://blog.example.com/"},
+		SameMetaDescription: []string{},
+	}}
+
+	w := getDuplicates(t, finder, "?url="+url.QueryEscape("https://example.com/a"))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://example.com/a", finder.url)
+	assert.JSONEq(t, `{
+		"url": "https://example.com/a",
+		"domain": "example.com",
+		"title": "Welcome",
+		"meta_description": "",
+		"same_title": ["https://blog.example.com/"],
+		"same_meta_description": []
+	}`, w.Body.String())
+}
+
+func TestDuplicatesHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		err          error
+		expectedCode int
+		errorCode    string
+	}{
+		{name: "Missing URL", expectedCode: http.StatusBadRequest, errorCode: constants.ErrorCodeValidation},
+		{
+			name:         "Not analyzed",
+			query:        "?url=https://example.com/",
+			err:          &services.AnalysisError{Code: constants.ErrorCodeNotAnalyzed, Status: constants.StatusNotFound, Message: "not analyzed"},
+			expectedCode: http.StatusNotFound,
+			errorCode:    constants.ErrorCodeNotAnalyzed,
+		},
+		{
+			name:         "Index failure",
+			query:        "?url=https://example.com/",
+			err:          errors.New("connection refused"),
+			expectedCode: http.StatusInternalServerError,
+			errorCode:    constants.ErrorCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getDuplicates(t, &duplicateFinder{err: tt.err}, tt.query)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assertErrorCode(t, w, tt.errorCode)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/export"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/storage"
+)
+
+// errExportLimit stops a source once the requested number of entries has been written
+var errExportLimit = errors.New("export limit reached")
+
+// ResultScanner iterates over every cached analysis
+type ResultScanner interface {
+	Scan(ctx context.Context, fn func(*models.AnalyzeResponse) error) error
+}
+
+// ExportHandler streams all analyses in bulk, from storage when it is enabled and
+// from the Redis cache otherwise
+type ExportHandler struct {
+	config *config.Config
+	logger *zap.Logger
+	store  storage.Store
+	cache  ResultScanner
+}
+
+// exportQuery holds the parameters of an export
+type exportQuery struct {
+	format string
+	from   time.Time
+	to     time.Time
+	limit  int // 0 exports every entry
+}
+
+// exportSource calls emit with each analysis to export until it returns an error
+type exportSource func(ctx context.Context, emit func(*models.AnalyzeResponse) error) error
+
+// NewExportHandler creates a new ExportHandler instance
+func NewExportHandler(cfg *config.Config, logger *zap.Logger, store storage.Store, cache ResultScanner) *ExportHandler {
+	return &ExportHandler{
+		config: cfg,
+		logger: logger,
+		store:  store,
+		cache:  cache,
+	}
+}
+
+// Export streams the analyses analyzed in an optional [from, to) range as NDJSON or CSV.
+// Rows are flushed to the client in chunks as they are read, so memory use does not grow
+// with the number of entries. Once the first chunk is sent the status can no longer
+// change; a failure after that point truncates the response and is logged.
+func (h *ExportHandler) Export(c *gin.Context) {
+	query, err := parseExportQuery(c)
+	if err != nil {
+		writeError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", err.Error())
+		return
+	}
+
+	var source exportSource
+	switch {
+	case h.config.Storage.Enabled:
+		source = h.fromStore(query)
+	case h.config.Cache.Enabled:
+		source = h.fromCache(query)
+	default:
+		writeError(c, constants.StatusNotFound, constants.ErrorCodeExportUnavailable, "Neither storage nor the cache is enabled", "")
+		return
+	}
+
+	writer, err := export.NewWriter(query.format, c.Writer)
+	if err != nil {
+		writeError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", err.Error())
+		return
+	}
+	stream := &exportStream{writer: writer, response: c.Writer, controller: http.NewResponseController(c.Writer), limit: query.limit}
+
+	c.Header(constants.HeaderContentType, export.ContentType(query.format))
+	c.Header(constants.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="analyses-%s.%s"`, time.Now().UTC().Format("20060102T150405Z"), query.format))
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	stream.extendDeadline()
+
+	err = source(c.Request.Context(), stream.write)
+	if errors.Is(err, errExportLimit) {
+		err = nil
+	}
+	if err == nil {
+		err = stream.flush()
+	}
+	if err == nil {
+		return
+	}
+
+	if !c.Writer.Written() {
+		// Nothing has been sent yet; report the failure as a regular error response
+		c.Writer.Header().Del(constants.HeaderContentDisposition)
+		c.Writer.Header().Del(constants.HeaderContentType)
+		h.logger.Error("Export failed", zap.Error(err))
+		writeError(c, constants.StatusInternalServerError, constants.ErrorCodeInternal, "Export failed", "")
+		return
+	}
+	h.logger.Error("Export aborted", zap.Int("rows", stream.rows), zap.Error(err))
+}
+
+// fromStore reads the matching records from storage, oldest first
+func (h *ExportHandler) fromStore(query exportQuery) exportSource {
+	return func(ctx context.Context, emit func(*models.AnalyzeResponse) error) error {
+		return h.store.Each(ctx, storage.Query{From: query.from, To: query.to, Limit: query.limit}, func(record *storage.Record) error {
+			var result models.AnalyzeResponse
+			if err := json.Unmarshal(record.Result, &result); err != nil {
+				h.logger.Warn("Skipping undecodable stored analysis", zap.Int64("id", record.ID), zap.Error(err))
+				return nil
+			}
+			return emit(&result)
+		})
+	}
+}
+
+// fromCache scans the cached analyses, in no particular order
+func (h *ExportHandler) fromCache(query exportQuery) exportSource {
+	return func(ctx context.Context, emit func(*models.AnalyzeResponse) error) error {
+		return h.cache.Scan(ctx, func(result *models.AnalyzeResponse) error {
+			if !query.from.IsZero() && result.AnalyzedAt.Before(query.from) {
+				return nil
+			}
+			if !query.to.IsZero() && !result.AnalyzedAt.Before(query.to) {
+				return nil
+			}
+			return emit(result)
+		})
+	}
+}
+
+// exportStream writes rows to the response and flushes them to the client in chunks
+type exportStream struct {
+	writer     export.Writer
+	response   http.Flusher
+	controller *http.ResponseController
+	limit      int
+	rows       int
+}
+
+func (s *exportStream) write(result *models.AnalyzeResponse) error {
+	if s.limit > 0 && s.rows >= s.limit {
+		return errExportLimit
+	}
+	if err := s.writer.Write(result); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%constants.ExportFlushInterval == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush sends the buffered rows to the client
+func (s *exportStream) flush() error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	s.response.Flush()
+	s.extendDeadline()
+	return nil
+}
+
+// extendDeadline replaces the server write timeout, which would cut off long
+// exports, with a deadline per chunk
+func (s *exportStream) extendDeadline() {
+	// Not every ResponseWriter supports deadlines; the server timeout applies then
+	_ = s.controller.SetWriteDeadline(time.Now().Add(constants.ExportWriteTimeout))
+}
+
+// parseExportQuery reads the format, time range and entry limit from the query string
+func parseExportQuery(c *gin.Context) (exportQuery, error) {
+	query := exportQuery{format: c.DefaultQuery("format", constants.ExportFormatNDJSON)}
+	if query.format != constants.ExportFormatNDJSON && query.format != constants.ExportFormatCSV {
+		return query, fmt.Errorf("format must be %s or %s", constants.ExportFormatNDJSON, constants.ExportFormatCSV)
+	}
+
+	var err error
+	if query.from, err = parseTimeParam(c, "from"); err != nil {
+		return query, err
+	}
+	if query.to, err = parseTimeParam(c, "to"); err != nil {
+		return query, err
+	}
+	if !query.from.IsZero() && !query.to.IsZero() && !query.from.Before(query.to) {
+		return query, fmt.Errorf("from must be before to")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return query, fmt.Errorf("limit must be a positive integer")
+		}
+		query.limit = limit
+	}
+
+	return query, nil
+}
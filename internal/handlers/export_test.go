@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/storage"
+)
+
+// fakeScanner emits count generated analyses. When pauseAfter is set it blocks after
+// that many entries until release is closed.
+type fakeScanner struct {
+	count      int
+	pauseAfter int
+	paused     chan struct{}
+	release    chan struct{}
+	err        error
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, fn func(*models.AnalyzeResponse) error) error {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < s.count; i++ {
+		if s.pauseAfter > 0 && i == s.pauseAfter {
+			close(s.paused)
+			<-s.release
+		}
+		result := &models.AnalyzeResponse{
+			URL:        "https://example.com/" + strconv.Itoa(i),
+			AnalyzedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func newExportEngine(t *testing.T, cfg *config.Config, store storage.Store, cache ResultScanner) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/admin/export", NewExportHandler(cfg, zaptest.NewLogger(t), store, cache).Export)
+	return engine
+}
+
+func getExport(engine *gin.Engine, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/admin/export"+query, nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func ndjsonURLs(t *testing.T, body string) []string {
+	urls := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var result models.AnalyzeResponse
+		require.NoError(t, json.Unmarshal([]byte(line), &result))
+		urls = append(urls, result.URL)
+	}
+	return urls
+}
+
+func cacheOnlyConfig() *config.Config {
+	return &config.Config{Cache: config.CacheConfig{Enabled: true}}
+}
+
+func TestExportHandler_CacheNDJSON(t *testing.T) {
+	engine := newExportEngine(t, cacheOnlyConfig(), storage.NewNoOpStore(), &fakeScanner{count: 5})
+
+	w := getExport(engine, "?from=2024-05-01T00:01:00Z&to=2024-05-01T00:04:00Z")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, constants.ContentTypeNDJSON, w.Header().Get(constants.HeaderContentType))
+	assert.Regexp(t, `^attachment; filename="analyses-\d{8}T\d{6}Z\.ndjson"$`, w.Header().Get(constants.HeaderContentDisposition))
+	assert.Equal(t, []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"}, ndjsonURLs(t, w.Body.String()))
+}
+
+func TestExportHandler_Limit(t *testing.T) {
+	scanner := &fakeScanner{count: 3 * constants.ExportFlushInterval}
+	engine := newExportEngine(t, cacheOnlyConfig(), storage.NewNoOpStore(), scanner)
+
+	w := getExport(engine, "?limit=150")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, ndjsonURLs(t, w.Body.String()), 150)
+}
+
+func TestExportHandler_StorageCSV(t *testing.T) {
+	store, err := storage.Open(context.Background(), config.StorageConfig{
+		Driver: constants.StorageDriverSQLite,
+		DSN:    "file::memory:",
+	}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.Save(context.Background(), &models.AnalyzeResponse{
+			URL:        "https://example.com/" + strconv.Itoa(i),
+			Title:      "Page " + strconv.Itoa(i),
+			AnalyzedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	// Storage takes precedence over the cache
+	cfg := &config.Config{Cache: config.CacheConfig{Enabled: true}, Storage: config.StorageConfig{Enabled: true}}
+	engine := newExportEngine(t, cfg, store, &fakeScanner{count: 10})
+
+	w := getExport(engine, "?format=csv&from=2024-05-01T13:00:00Z")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, constants.ContentTypeCSV, w.Header().Get(constants.HeaderContentType))
+	assert.Contains(t, w.Header().Get(constants.HeaderContentDisposition), `.csv"`)
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "url", records[0][0])
+	assert.Equal(t, "https://example.com/1", records[1][0])
+	assert.Equal(t, "Page 1", records[1][3])
+	assert.Equal(t, "https://example.com/2", records[2][0])
+}
+
+func TestExportHandler_StreamsIncrementally(t *testing.T) {
+	pauseAfter := 2 * constants.ExportFlushInterval
+	scanner := &fakeScanner{
+		count:      20 * constants.ExportFlushInterval,
+		pauseAfter: pauseAfter,
+		paused:     make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+	server := httptest.NewServer(newExportEngine(t, cacheOnlyConfig(), storage.NewNoOpStore(), scanner))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/admin/export")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+
+	// The rows written before the source pauses reach the client while the export is still running
+	lines := bufio.NewScanner(resp.Body)
+	received := make(chan int)
+	go func() {
+		n := 0
+		for n < pauseAfter && lines.Scan() {
+			n++
+		}
+		received <- n
+	}()
+
+	select {
+	case n := <-received:
+		assert.Equal(t, pauseAfter, n)
+	case <-time.After(5 * time.Second):
+		close(scanner.release)
+		t.Fatal("rows were not streamed before the export finished")
+	}
+	<-scanner.paused
+	close(scanner.release)
+
+	rest := 0
+	for lines.Scan() {
+		rest++
+	}
+	require.NoError(t, lines.Err())
+	assert.Equal(t, scanner.count-pauseAfter, rest)
+}
+
+func TestExportHandler_Errors(t *testing.T) {
+	t.Run("No source enabled", func(t *testing.T) {
+		w := getExport(newExportEngine(t, &config.Config{}, storage.NewNoOpStore(), &fakeScanner{}), "")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeExportUnavailable)
+	})
+
+	t.Run("Source failure before any rows are sent", func(t *testing.T) {
+		engine := newExportEngine(t, cacheOnlyConfig(), storage.NewNoOpStore(), &fakeScanner{count: 2, err: errors.New("connection refused")})
+		w := getExport(engine, "")
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get(constants.HeaderContentDisposition))
+		assertErrorCode(t, w, constants.ErrorCodeInternal)
+	})
+
+	engine := newExportEngine(t, cacheOnlyConfig(), storage.NewNoOpStore(), &fakeScanner{})
+	for _, query := range []string{
+		"?format=xml",
+		"?from=yesterday",
+		"?from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z",
+		"?limit=0",
+		"?limit=many",
+	} {
+		t.Run(query, func(t *testing.T) {
+			w := getExport(engine, query)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assertErrorCode(t, w, constants.ErrorCodeValidation)
+		})
+	}
+}
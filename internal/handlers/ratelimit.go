@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// RateLimitStatusReader reports the budget of a rate limited client
+type RateLimitStatusReader interface {
+	Status(ctx context.Context, key string) (models.RateLimitStatus, error)
+}
+
+// EgressUsageReader reports the bytes fetched today for a client; nil usage means they are
+// not counted
+type EgressUsageReader interface {
+	EgressUsage(ctx context.Context, key string) (*models.EgressUsage, error)
+}
+
+// RateLimitHandler serves the rate limit budget of a client, so support can tell why a
+// client is being limited and clients can see how much of their quota is left
+type RateLimitHandler struct {
+	logger  *zap.Logger
+	limiter RateLimitStatusReader
+	limits  config.RateLimitConfig
+	egress  EgressUsageReader
+}
+
+// NewRateLimitHandler creates a new RateLimitHandler instance
+func NewRateLimitHandler(logger *zap.Logger, limiter RateLimitStatusReader, limits config.RateLimitConfig) *RateLimitHandler {
+	return &RateLimitHandler{
+		logger:  logger,
+		limiter: limiter,
+		limits:  limits,
+	}
+}
+
+// SetEgress adds the bytes fetched today for the caller to its quota; without a reader they
+// are left out
+func (h *RateLimitHandler) SetEgress(egress EgressUsageReader) {
+	h.egress = egress
+}
+
+// Status returns the limit, remaining tokens and seconds until reset of the client IP
+// address in the key path parameter, without consuming any of its budget
+func (h *RateLimitHandler) Status(c *gin.Context) {
+	status, err := h.limiter.Status(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		h.logger.Error("Failed to read rate limit status", zap.Error(err))
+		writeError(c, constants.StatusInternalServerError, constants.ErrorCodeInternal, "Failed to read rate limit status", "")
+		return
+	}
+
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	c.JSON(constants.StatusOK, status)
+}
+
+// Quota returns the budget of the calling client, keyed by its IP address like the rate
+// limiter, along with the cost of each route. Reading it consumes none of the budget.
+func (h *RateLimitHandler) Quota(c *gin.Context) {
+	status, err := h.limiter.Status(c.Request.Context(), c.ClientIP())
+	if err != nil {
+		h.logger.Error("Failed to read rate limit status", zap.Error(err))
+		writeError(c, constants.StatusInternalServerError, constants.ErrorCodeInternal, "Failed to read quota", "")
+		return
+	}
+
+	var egress *models.EgressUsage
+	egressUnknown := false
+	if h.egress != nil {
+		egress, err = h.egress.EgressUsage(c.Request.Context(), c.ClientIP())
+		if err != nil {
+			// The budget is still worth reporting; the bytes are reported as unknown
+			h.logger.Warn("Failed to read egress usage", zap.Error(err))
+			egressUnknown = true
+		}
+	}
+
+	costs := map[string]int{"GET /api/v1/quota": 0}
+	for _, route := range RouteCosts(h.limits.Costs) {
+		costs[route.Method+" "+route.Path] = route.Tokens
+	}
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	c.JSON(constants.StatusOK, models.Quota{
+		Enabled:           h.limits.Enabled,
+		Key:               status.Key,
+		Limit:             status.Limit,
+		Used:              status.Limit - status.Remaining,
+		Remaining:         status.Remaining,
+		ResetSeconds:      status.ResetSeconds,
+		ResetAt:           time.Now().UTC().Add(time.Duration(status.ResetSeconds) * time.Second).Truncate(time.Second),
+		RequestsPerMinute: status.RequestsPerMinute,
+		Costs:             costs,
+		Egress:            egress,
+		EgressUnknown:     egressUnknown,
+	})
+}
+
+// RouteCost is the number of tokens a request to a rate limited route consumes
+type RouteCost struct {
+	Method string
+	Path   string
+	Tokens int
+}
+
+// RouteCosts lists every rate limited route with its cost. The router charges requests from
+// it and Quota reports it, so the two cannot drift apart.
+func RouteCosts(costs config.RateLimitCosts) []RouteCost {
+	return []RouteCost{
+		{Method: "POST", Path: "/api/v1/analyze", Tokens: routeCost(costs.Analyze)},
+		{Method: "POST", Path: "/api/v1/analyze/html", Tokens: routeCost(costs.AnalyzeHTML)},
+		{Method: "GET", Path: "/api/v1/analyze/report.pdf", Tokens: routeCost(costs.Analyze)},
+		{Method: "GET", Path: "/api/v1/analyses", Tokens: routeCost(costs.Analyses)},
+		{Method: "POST", Path: "/api/v1/validate", Tokens: routeCost(costs.Validate)},
+		{Method: "GET", Path: "/api/v1/duplicates", Tokens: constants.DefaultRateLimitCost},
+		{Method: "GET", Path: "/api/v1/snapshot", Tokens: constants.DefaultRateLimitCost},
+	}
+}
+
+// routeCost returns the tokens a route configured with cost consumes; the limiter charges
+// at least one
+func routeCost(cost int) int {
+	return max(cost, constants.DefaultRateLimitCost)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// statusReader returns a fixed status or error, recording the key asked for
+type statusReader struct {
+	status models.RateLimitStatus
+	err    error
+	key    string
+}
+
+func (r *statusReader) Status(_ context.Context, key string) (models.RateLimitStatus, error) {
+	r.key = key
+	return r.status, r.err
+}
+
+func getRateLimitStatus(t *testing.T, reader RateLimitStatusReader, key string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/admin/ratelimit/:key", NewRateLimitHandler(zaptest.NewLogger(t), reader, config.RateLimitConfig{}).Status)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/ratelimit/"+key, nil))
+	return w
+}
+
+func TestRateLimitHandler_Status(t *testing.T) {
+	reader := &statusReader{status: models.RateLimitStatus{
+		Key:               "2001:db8::1",
+		Tracked:           true,
+		Limit:             6,
+		Remaining:         2,
+		ResetSeconds:      4,
+		RequestsPerMinute: 60,
+	}}
+
+	w := getRateLimitStatus(t, reader, "2001:db8::1")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2001:db8::1", reader.key)
+	assert.Equal(t, constants.CacheControlNoStore, w.Header().Get(constants.HeaderCacheControl))
+	assert.JSONEq(t, `{"key":"2001:db8::1","tracked":true,"limit":6,"remaining":2,"reset_seconds":4,"requests_per_minute":60}`, w.Body.String())
+}
+
+func TestRateLimitHandler_Failure(t *testing.T) {
+	w := getRateLimitStatus(t, &statusReader{err: errors.New("connection refused")}, "192.0.2.1")
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assertErrorCode(t, w, constants.ErrorCodeInternal)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
+
+func getQuota(t *testing.T, reader RateLimitStatusReader, limits config.RateLimitConfig) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/v1/quota", NewRateLimitHandler(zaptest.NewLogger(t), reader, limits).Quota)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/quota", nil)
+	req.RemoteAddr = "203.0.113.9:5123"
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitHandler_Quota(t *testing.T) {
+	reader := &statusReader{status: models.RateLimitStatus{
+		Key:               "203.0.113.9",
+		Tracked:           true,
+		Limit:             6,
+		Remaining:         2,
+		ResetSeconds:      4,
+		RequestsPerMinute: 60,
+	}}
+	limits := config.RateLimitConfig{Enabled: true, Costs: config.RateLimitCosts{Analyze: 3, AnalyzeHTML: 2, Validate: 1}}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	w := getQuota(t, reader, limits)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "203.0.113.9", reader.key, "the caller's own budget is read")
+	assert.Equal(t, constants.CacheControlNoStore, w.Header().Get(constants.HeaderCacheControl))
+
+	var quota models.Quota
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &quota))
+	assert.True(t, quota.Enabled)
+	assert.Equal(t, "203.0.113.9", quota.Key)
+	assert.Equal(t, 6, quota.Limit)
+	assert.Equal(t, 4, quota.Used)
+	assert.Equal(t, 2, quota.Remaining)
+	assert.Equal(t, 4, quota.ResetSeconds)
+	assert.WithinDuration(t, before.Add(4*time.Second), quota.ResetAt, time.Second)
+	assert.Equal(t, 60.0, quota.RequestsPerMinute)
+	assert.Equal(t, 3, quota.Costs["POST /api/v1/analyze"])
+	assert.Equal(t, 3, quota.Costs["GET /api/v1/analyze/report.pdf"])
+	assert.Equal(t, 2, quota.Costs["POST /api/v1/analyze/html"])
+	assert.Equal(t, 1, quota.Costs["GET /api/v1/analyses"], "unset costs are charged a token")
+	assert.Equal(t, 0, quota.Costs["GET /api/v1/quota"])
+	assert.Equal(t, 1, quota.Costs["GET /api/v1/snapshot"])
+	assert.Len(t, quota.Costs, len(RouteCosts(limits.Costs))+1, "every rate limited route is listed")
+}
+
+func TestRateLimitHandler_QuotaFailure(t *testing.T) {
+	w := getQuota(t, &statusReader{err: errors.New("connection refused")}, config.RateLimitConfig{})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assertErrorCode(t, w, constants.ErrorCodeInternal)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
+
+// egressReader returns a fixed usage or error, recording the key asked for
+type egressReader struct {
+	usage *models.EgressUsage
+	err   error
+	key   string
+}
+
+func (r *egressReader) EgressUsage(_ context.Context, key string) (*models.EgressUsage, error) {
+	r.key = key
+	return r.usage, r.err
+}
+
+func getQuotaWithEgress(t *testing.T, egress EgressUsageReader) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	handler := NewRateLimitHandler(zaptest.NewLogger(t), &statusReader{}, config.RateLimitConfig{Enabled: true})
+	handler.SetEgress(egress)
+	engine := gin.New()
+	engine.GET("/api/v1/quota", handler.Quota)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/quota", nil)
+	req.RemoteAddr = "203.0.113.9:5123"
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitHandler_QuotaEgress(t *testing.T) {
+	remaining := int64(3000)
+	resetAt := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	reader := &egressReader{usage: &models.EgressUsage{UsedBytes: 7000, DailyBytes: 10000, RemainingBytes: &remaining, ResetAt: resetAt}}
+
+	w := getQuotaWithEgress(t, reader)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "203.0.113.9", reader.key, "the caller's own bytes are read")
+	var quota struct {
+		Egress json.RawMessage `json:"egress"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &quota))
+	assert.JSONEq(t, `{"used_bytes":7000,"daily_bytes":10000,"remaining_bytes":3000,"reset_at":"2024-03-20T00:00:00Z"}`, string(quota.Egress))
+
+	t.Run("Left out when bytes are not counted", func(t *testing.T) {
+		w := getQuotaWithEgress(t, &egressReader{})
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "egress")
+	})
+
+	t.Run("Unknown when the bytes cannot be read", func(t *testing.T) {
+		w := getQuotaWithEgress(t, &egressReader{err: errors.New("connection refused")})
+
+		require.Equal(t, http.StatusOK, w.Code, "the budget is reported either way")
+		var quota models.Quota
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &quota))
+		assert.Nil(t, quota.Egress)
+		assert.True(t, quota.EgressUnknown)
+		assert.NotContains(t, w.Body.String(), "connection refused")
+	})
+}
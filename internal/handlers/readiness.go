@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+// ReadinessHandler tells load balancers and orchestrators whether the server can take
+// analyses. Unlike /health, which only reports the process is up, it fails when the analyzer
+// cannot reach the internet, so traffic moves to instances that can.
+type ReadinessHandler struct {
+	outbound *services.OutboundProbe
+}
+
+// NewReadinessHandler creates a new ReadinessHandler instance; a nil probe skips the outbound check
+func NewReadinessHandler(outbound *services.OutboundProbe) *ReadinessHandler {
+	return &ReadinessHandler{outbound: outbound}
+}
+
+// Ready answers 200 with the check results when every check passes, and 503 otherwise
+func (h *ReadinessHandler) Ready(c *gin.Context) {
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoCache)
+
+	readiness := models.Readiness{Status: constants.ReadinessStatusReady}
+	if h.outbound != nil {
+		probe := h.outbound.Check(c.Request.Context())
+		readiness.Outbound = &probe
+		if !probe.Reachable {
+			readiness.Status = constants.ReadinessStatusNotReady
+		}
+	}
+
+	status := constants.StatusOK
+	if readiness.Status != constants.ReadinessStatusReady {
+		status = constants.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+func serveReadiness(t *testing.T, probe *services.OutboundProbe) (*httptest.ResponseRecorder, models.Readiness) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/ready", NewReadinessHandler(probe).Ready)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	var readiness models.Readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &readiness))
+	return w, readiness
+}
+
+func newReadinessProbe(t *testing.T, canaryURL string) *services.OutboundProbe {
+	return services.NewOutboundProbe(config.OutboundProbeConfig{CanaryURL: canaryURL, Timeout: time.Second, CacheTTL: time.Minute},
+		zaptest.NewLogger(t), metrics.NewWithRegistry(prometheus.NewRegistry()))
+}
+
+func TestReadinessHandler_Ready(t *testing.T) {
+	t.Run("Without outbound probe", func(t *testing.T) {
+		w, readiness := serveReadiness(t, nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, constants.CacheControlNoCache, w.Header().Get(constants.HeaderCacheControl))
+		assert.Equal(t, constants.ReadinessStatusReady, readiness.Status)
+		assert.Nil(t, readiness.Outbound)
+	})
+
+	t.Run("Reachable canary", func(t *testing.T) {
+		canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		t.Cleanup(canary.Close)
+
+		w, readiness := serveReadiness(t, newReadinessProbe(t, canary.URL))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, constants.ReadinessStatusReady, readiness.Status)
+		require.NotNil(t, readiness.Outbound)
+		assert.True(t, readiness.Outbound.Reachable)
+		assert.Equal(t, http.StatusOK, readiness.Outbound.StatusCode)
+		assert.Contains(t, w.Body.String(), `"age_seconds":`)
+	})
+
+	t.Run("Unreachable canary", func(t *testing.T) {
+		canary := httptest.NewServer(http.NotFoundHandler())
+		canary.Close()
+
+		w, readiness := serveReadiness(t, newReadinessProbe(t, canary.URL))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, constants.ReadinessStatusNotReady, readiness.Status)
+		require.NotNil(t, readiness.Outbound)
+		assert.False(t, readiness.Outbound.Reachable)
+		assert.NotEmpty(t, readiness.Outbound.Error)
+	})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/report"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+// HandleReport analyzes the page in the url query parameter and responds with the result as a
+// downloadable PDF report. Options the query string can carry, such as skip_link_check, apply;
+// link details are always included so the report can list the broken links.
+func (h *AnalyzeHandler) HandleReport(c *gin.Context) {
+	req := models.AnalyzeRequest{URL: c.Query("url")}
+	start := time.Now()
+	defer func() { h.audit(c, constants.AuditOperationReport, req.URL, start) }()
+
+	if err := c.ShouldBindQuery(&req.Options); err != nil {
+		h.respondError(c, constants.StatusBadRequest, constants.ErrorCodeInvalidRequest, "Invalid query parameters", err.Error())
+		return
+	}
+	req.Options.IncludeLinkDetails = true
+
+	if err := h.validator.Struct(req); err != nil {
+		h.respondError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.respondValidationError(c, err)
+		return
+	}
+
+	result, err := h.analyzer.AnalyzeWithOptions(analysisContext(c), req.URL, req.Options)
+	if err != nil {
+		h.logger.Error("Failed to analyze webpage for report",
+			zap.String("url", models.StripCredentials(req.URL)),
+			zap.Error(err),
+		)
+
+		var analysisErr *services.AnalysisError
+		if errors.As(err, &analysisErr) {
+			setRetryAfter(c, analysisErr.RetryAfter)
+			h.respondError(c, analysisErr.Status, analysisErr.Code, analysisErr.Message, err.Error())
+			return
+		}
+		h.respondError(c, constants.StatusInternalServerError, constants.ErrorCodeAnalysisFailed, "Failed to analyze webpage", err.Error())
+		return
+	}
+
+	c.Header(constants.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, reportFilename(result.URL)))
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	c.Data(constants.StatusOK, constants.ContentTypePDF, report.Render(result))
+}
+
+// reportFilename names the report after the host of the analyzed page, keeping only characters
+// that are safe in a Content-Disposition filename
+func reportFilename(target string) string {
+	host := "page"
+	if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+		host = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+				return r
+			}
+			return '-'
+		}, strings.ToLower(u.Hostname()))
+	}
+	return "analysis-" + host + ".pdf"
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+func getReport(t *testing.T, analyzer *MockAnalyzer, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analyze/report.pdf?"+query, nil)
+	w := httptest.NewRecorder()
+	newMockEngine(t, analyzer).ServeHTTP(w, req)
+	return w
+}
+
+func TestAnalyzeHandler_HandleReport(t *testing.T) {
+	analyzer := &MockAnalyzer{}
+	opts := models.AnalyzeOptions{SkipLinkCheck: true, IncludeLinkDetails: true}
+	analyzer.On("AnalyzeWithOptions", mock.Anything, "https://Example.com:8443/docs", opts).
+		Return(&models.AnalyzeResponse{URL: "https://Example.com:8443/docs", Title: "Docs"}, nil)
+
+	w := getReport(t, analyzer, "url="+url.QueryEscape("https://Example.com:8443/docs")+"&skip_link_check=true")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, constants.ContentTypePDF, w.Header().Get(constants.HeaderContentType))
+	assert.Equal(t, `attachment; filename="analysis-example.com.pdf"`, w.Header().Get(constants.HeaderContentDisposition))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	analyzer.AssertExpectations(t)
+}
+
+func TestAnalyzeHandler_HandleReportErrors(t *testing.T) {
+	t.Run("Missing URL", func(t *testing.T) {
+		w := getReport(t, &MockAnalyzer{}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeValidation)
+	})
+
+	t.Run("Invalid option", func(t *testing.T) {
+		w := getReport(t, &MockAnalyzer{}, "url=https://example.com&skip_link_check=maybe")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertErrorCode(t, w, constants.ErrorCodeInvalidRequest)
+	})
+
+	t.Run("Analysis failure", func(t *testing.T) {
+		analyzer := &MockAnalyzer{}
+		analyzer.On("AnalyzeWithOptions", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, &services.AnalysisError{Code: constants.ErrorCodeBotProtection, Status: constants.StatusBadGateway, Message: "challenge"})
+
+		w := getReport(t, analyzer, "url=https://example.com")
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		assert.Empty(t, w.Header().Get(constants.HeaderContentDisposition))
+		assertErrorCode(t, w, constants.ErrorCodeBotProtection)
+	})
+}
+
+func TestReportFilename(t *testing.T) {
+	assert.Equal(t, "analysis-example.com.pdf", reportFilename("https://example.com/path?q=1"))
+	assert.Equal(t, "analysis-xn--bcher-kva.example.pdf", reportFilename("https://xn--bcher-kva.example"))
+	assert.Equal(t, "analysis---1.pdf", reportFilename("http://[::1]:8080/"))
+	assert.Equal(t, "analysis-page.pdf", reportFilename("not a url"))
+}
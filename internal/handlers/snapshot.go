@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+// SnapshotLoader returns the stored HTML of analyzed pages. *services.Analyzer implements it.
+type SnapshotLoader interface {
+	Snapshot(ctx context.Context, targetURL string) (*models.Snapshot, error)
+}
+
+// SnapshotHandler serves the pages stored by analyses run with options.store_snapshot
+type SnapshotHandler struct {
+	logger *zap.Logger
+	loader SnapshotLoader
+}
+
+// NewSnapshotHandler creates a new SnapshotHandler instance
+func NewSnapshotHandler(logger *zap.Logger, loader SnapshotLoader) *SnapshotHandler {
+	return &SnapshotHandler{
+		logger: logger,
+		loader: loader,
+	}
+}
+
+// Get returns the latest snapshot of the url query parameter with the Content-Type it was
+// fetched with. The page is sandboxed, so its scripts never run with this server's origin.
+func (h *SnapshotHandler) Get(c *gin.Context) {
+	targetURL := c.Query("url")
+	if targetURL == "" {
+		writeError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", "url is required")
+		return
+	}
+
+	snapshot, err := h.loader.Snapshot(c.Request.Context(), targetURL)
+	if err != nil {
+		var analysisErr *services.AnalysisError
+		if errors.As(err, &analysisErr) {
+			writeError(c, analysisErr.Status, analysisErr.Code, analysisErr.Message, "")
+			return
+		}
+		h.logger.Error("Failed to load snapshot", zap.String("url", models.StripCredentials(targetURL)), zap.Error(err))
+		writeError(c, constants.StatusInternalServerError, constants.ErrorCodeInternal, "Failed to load snapshot", "")
+		return
+	}
+
+	c.Header(constants.HeaderContentSecurityPolicy, constants.SnapshotSandboxPolicy)
+	c.Header(constants.HeaderContentTypeOptions, constants.ContentTypeOptionsNoSniff)
+	c.Header(constants.HeaderLastModified, snapshot.FetchedAt.UTC().Format(http.TimeFormat))
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	c.Data(constants.StatusOK, snapshot.ContentType, snapshot.Body)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+	"github.com/webpage-analyser-server/internal/services"
+)
+
+// snapshotLoader returns a fixed snapshot or an error, recording the URL asked for
+type snapshotLoader struct {
+	snapshot *models.Snapshot
+	err      error
+	url      string
+}
+
+func (l *snapshotLoader) Snapshot(_ context.Context, targetURL string) (*models.Snapshot, error) {
+	l.url = targetURL
+	return l.snapshot, l.err
+}
+
+func getSnapshot(t *testing.T, loader SnapshotLoader, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/v1/snapshot", NewSnapshotHandler(zaptest.NewLogger(t), loader).Get)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/snapshot"+query, nil))
+	return w
+}
+
+func TestSnapshotHandler_Get(t *testing.T) {
+	loader := &snapshotLoader{snapshot: &models.Snapshot{
+		URL:         "https://example.com/a",
+		ContentType: "text/html; charset=iso-8859-1",
+		FetchedAt:   time.Date(2024, 3, 19, 8, 30, 0, 0, time.UTC),
+		Body:        []byte("<h1>R\xf6sen</h1>"),
+	}}
+
+	w := getSnapshot(t, loader, "?url="+url.QueryEscape("https://example.com/a"))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://example.com/a", loader.url)
+	assert.Equal(t, "<h1>R\xf6sen</h1>", w.Body.String())
+	assert.Equal(t, "text/html; charset=iso-8859-1", w.Header().Get(constants.HeaderContentType))
+	assert.Equal(t, "Tue, 19 Mar 2024 08:30:00 GMT", w.Header().Get(constants.HeaderLastModified))
+	assert.Equal(t, constants.SnapshotSandboxPolicy, w.Header().Get(constants.HeaderContentSecurityPolicy))
+	assert.Equal(t, constants.ContentTypeOptionsNoSniff, w.Header().Get(constants.HeaderContentTypeOptions))
+	assert.Equal(t, constants.CacheControlNoStore, w.Header().Get(constants.HeaderCacheControl))
+}
+
+func TestSnapshotHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		err          error
+		expectedCode int
+		errorCode    string
+	}{
+		{name: "Missing URL", expectedCode: http.StatusBadRequest, errorCode: constants.ErrorCodeValidation},
+		{
+			name:         "No snapshot",
+			query:        "?url=https://example.com/",
+			err:          &services.AnalysisError{Code: constants.ErrorCodeSnapshotNotFound, Status: constants.StatusNotFound, Message: "no snapshot"},
+			expectedCode: http.StatusNotFound,
+			errorCode:    constants.ErrorCodeSnapshotNotFound,
+		},
+		{
+			name:         "Store failure",
+			query:        "?url=https://example.com/",
+			err:          errors.New("connection refused"),
+			expectedCode: http.StatusInternalServerError,
+			errorCode:    constants.ErrorCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getSnapshot(t, &snapshotLoader{err: tt.err}, tt.query)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assertErrorCode(t, w, tt.errorCode)
+			assert.Empty(t, w.Header().Get(constants.HeaderContentSecurityPolicy))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// URLValidator checks URLs without analyzing them. *services.Analyzer implements it.
+type URLValidator interface {
+	ValidateURL(ctx context.Context, targetURL string) *models.ValidationReport
+}
+
+// ValidateHandler serves dry-run validations of URLs, so CI pipelines can check a URL
+// before paying for its analysis
+type ValidateHandler struct {
+	logger    *zap.Logger
+	validator URLValidator
+}
+
+// NewValidateHandler creates a new ValidateHandler instance
+func NewValidateHandler(logger *zap.Logger, validator URLValidator) *ValidateHandler {
+	return &ValidateHandler{
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// Validate returns the per-check breakdown of the URL in the request body. A URL failing a
+// check is still answered with 200: the report is the result, not an error.
+func (h *ValidateHandler) Validate(c *gin.Context) {
+	var req models.ValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, constants.StatusBadRequest, constants.ErrorCodeInvalidRequest, "Invalid request body", err.Error())
+		return
+	}
+	if req.URL == "" {
+		writeError(c, constants.StatusBadRequest, constants.ErrorCodeValidation, "Validation failed", "url is required")
+		return
+	}
+
+	report := h.validator.ValidateURL(c.Request.Context(), req.URL)
+	if !report.Valid {
+		h.logger.Debug("URL failed validation", zap.String("url", report.URL))
+	}
+	c.Header(constants.HeaderCacheControl, constants.CacheControlNoStore)
+	c.JSON(constants.StatusOK, report)
+}
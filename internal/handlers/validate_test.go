@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// fixedValidator returns a fixed report, recording the URL asked for
+type fixedValidator struct {
+	report models.ValidationReport
+	url    string
+}
+
+func (v *fixedValidator) ValidateURL(_ context.Context, targetURL string) *models.ValidationReport {
+	v.url = targetURL
+	report := v.report
+	return &report
+}
+
+func postValidate(t *testing.T, validator URLValidator, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/api/v1/validate", NewValidateHandler(zaptest.NewLogger(t), validator).Validate)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/validate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidateHandler_Validate(t *testing.T) {
+	validator := &fixedValidator{report: models.ValidationReport{
+		URL:   "https://example.com/private",
+		Valid: false,
+		Checks: []models.ValidationCheck{
+			{Check: constants.ValidationCheckLength, Pass: true},
+			{Check: constants.ValidationCheckRobots, Detail: "robots.txt disallows /private (Disallow: /private)"},
+			{Check: constants.ValidationCheckReachable, Skipped: true},
+		},
+	}}
+
+	w := postValidate(t, validator, `{"url": "https://example.com/private"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, "a failed check is a validation result, not an error")
+	assert.Equal(t, "https://example.com/private", validator.url)
+	assert.Equal(t, constants.CacheControlNoStore, w.Header().Get(constants.HeaderCacheControl))
+	assert.JSONEq(t, `{
+		"url": "https://example.com/private",
+		"valid": false,
+		"duration_ms": 0,
+		"checks": [
+			{"check": "length", "pass": true},
+			{"check": "robots", "pass": false, "detail": "robots.txt disallows /private (Disallow: /private)"},
+			{"check": "reachability", "pass": false, "skipped": true}
+		]
+	}`, w.Body.String())
+}
+
+func TestValidateHandler_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode string
+	}{
+		{name: "Malformed JSON", body: `{"url":`, expectedCode: constants.ErrorCodeInvalidRequest},
+		{name: "Missing URL", body: `{}`, expectedCode: constants.ErrorCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &fixedValidator{}
+			w := postValidate(t, validator, tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assertErrorCode(t, w, tt.expectedCode)
+			assert.Empty(t, validator.url, "rejected requests are not validated")
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package jobs runs background work, such as asynchronous analyses, crawls and cache warmups,
+// on a pool of workers and tracks its status. Each job carries a type, which selects the
+// handler that runs it, and a JSON payload; jobs are kept in a store, in memory or in Redis,
+// so that with the Redis store queued and interrupted jobs resume after a restart.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+var (
+	// ErrNotFound is returned for job IDs the store does not know, or no longer keeps
+	ErrNotFound = errors.New("job not found")
+	// ErrUnknownType is returned when enqueuing a job of a type no handler is registered for
+	ErrUnknownType = errors.New("unknown job type")
+	// ErrQueueFull is returned when enqueuing while jobs.max_queued jobs are waiting
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrClosed is returned when enqueuing on a runner that is shutting down
+	ErrClosed = errors.New("job runner is closed")
+	// ErrFinished is returned when canceling a job that already finished
+	ErrFinished = errors.New("job already finished")
+)
+
+// Job is a unit of background work and its status
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Status     string          `json:"status"`           // queued, running, succeeded, failed or canceled
+	Result     json.RawMessage `json:"result,omitempty"` // Set when the job succeeded
+	Error      string          `json:"error,omitempty"`  // Set when the job failed
+	Attempts   int             `json:"attempts"`         // Times the job started; more than one after a restart interrupted it
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	Owner      string          `json:"owner,omitempty"`       // Instance running the job
+	LeaseUntil *time.Time      `json:"lease_until,omitempty"` // Until when the owner holds the running job
+}
+
+// Finished reports whether the job succeeded, failed or was canceled
+func (j *Job) Finished() bool {
+	switch j.Status {
+	case constants.JobStatusSucceeded, constants.JobStatusFailed, constants.JobStatusCanceled:
+		return true
+	}
+	return false
+}
+
+// leasedElsewhere reports whether the job is running under an instance other than owner
+// whose lease has not run out, so it is that instance's to finish
+func (j *Job) leasedElsewhere(owner string, now time.Time) bool {
+	return j.Status == constants.JobStatusRunning && j.Owner != "" && j.Owner != owner &&
+		j.LeaseUntil != nil && now.Before(*j.LeaseUntil)
+}
+
+// Handler runs a job from its payload. The returned result is stored with the job. ctx is
+// canceled when the job is canceled, times out or is interrupted by a shutdown.
+type Handler func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error)
+
+// Filter selects jobs to list; zero values select every job
+type Filter struct {
+	Type     string
+	Statuses []string
+	Limit    int
+}
+
+// matches reports whether the filter selects job
+func (f Filter) matches(job *Job) bool {
+	if f.Type != "" && job.Type != f.Type {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, status := range f.Statuses {
+		if job.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Queue accepts jobs and reports their status
+type Queue interface {
+	Enqueue(ctx context.Context, jobType string, payload json.RawMessage) (*Job, error)
+	Get(ctx context.Context, id string) (*Job, error)
+	Cancel(ctx context.Context, id string) (*Job, error)
+	List(ctx context.Context, filter Filter) ([]*Job, error)
+}
+
+// Store persists jobs. Implementations must be safe for concurrent use.
+type Store interface {
+	// Save inserts or replaces the job
+	Save(ctx context.Context, job *Job) error
+	// Get returns the job with the ID, or ErrNotFound
+	Get(ctx context.Context, id string) (*Job, error)
+	// List returns the jobs the filter selects, most recently created first
+	List(ctx context.Context, filter Filter) ([]*Job, error)
+	Close() error
+}
+
+// newJobID generates a random hex-encoded job ID
+func newJobID() (string, error) {
+	b := make([]byte, constants.JobIDByteLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps jobs in process memory; they are lost on restart. Finished jobs are
+// forgotten once older than the retention.
+type MemoryStore struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewMemoryStore keeps finished jobs for retention; 0 keeps them until restart
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{retention: retention, now: time.Now, jobs: make(map[string]*Job)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	saved := *job
+	s.jobs[job.ID] = &saved
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (s *MemoryStore) List(_ context.Context, filter Filter) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	var jobs []*Job
+	for _, job := range s.jobs {
+		if filter.matches(job) {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	sortNewestFirst(jobs)
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// prune forgets the jobs that finished longer than the retention ago; s.mu must be held
+func (s *MemoryStore) prune() {
+	if s.retention <= 0 {
+		return
+	}
+	cutoff := s.now().Add(-s.retention)
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// sortNewestFirst orders jobs by creation time, most recent first, and by ID for equal times
+func sortNewestFirst(jobs []*Job) {
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+}
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// RedisStore keeps each job as JSON under prefix+"job:"+id, indexed by creation time in the
+// sorted set prefix+"index". Finished jobs expire after the retention; their index entries
+// are removed when a listing finds them gone.
+type RedisStore struct {
+	client    *redis.Client
+	prefix    string
+	retention time.Duration
+	batchSize int64 // Index entries List reads per round trip
+}
+
+// NewRedisStore stores jobs under prefix, keeping finished ones for retention (0 keeps them
+// forever); the store owns the client and closes it
+func NewRedisStore(client *redis.Client, prefix string, retention time.Duration) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, retention: retention, batchSize: constants.JobListBatchSize}
+}
+
+func (s *RedisStore) jobKey(id string) string {
+	return s.prefix + "job:" + id
+}
+
+func (s *RedisStore) indexKey() string {
+	return s.prefix + "index"
+}
+
+func (s *RedisStore) Save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if job.Finished() {
+		ttl = s.retention
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.jobKey(job.ID), data, ttl)
+	pipe.ZAdd(ctx, s.indexKey(), redis.Z{Score: float64(job.CreatedAt.UnixNano()), Member: job.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Job, error) {
+	data, err := s.client.Get(ctx, s.jobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %w", id, err)
+	}
+	return decodeJob(id, data)
+}
+
+// List reads the index newest first, batchSize jobs per round trip, and stops once
+// filter.Limit jobs are selected
+func (s *RedisStore) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	var jobs []*Job
+	for start := int64(0); filter.Limit <= 0 || len(jobs) < filter.Limit; start += s.batchSize {
+		ids, err := s.client.ZRevRange(ctx, s.indexKey(), start, start+s.batchSize-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		batch, expired, err := s.readBatch(ctx, ids, filter)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, batch...)
+		if len(expired) > 0 {
+			if err := s.client.ZRem(ctx, s.indexKey(), expired...).Err(); err != nil {
+				return nil, fmt.Errorf("failed to remove expired jobs from the index: %w", err)
+			}
+			// The entries after the removed ones moved up
+			start -= int64(len(expired))
+		}
+		if int64(len(ids)) < s.batchSize {
+			break
+		}
+	}
+
+	// The index orders by creation time; equal times are ordered like the memory store
+	sortNewestFirst(jobs)
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+// readBatch returns the jobs of ids the filter selects, and the IDs whose job has expired
+func (s *RedisStore) readBatch(ctx context.Context, ids []string, filter Filter) ([]*Job, []any, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.jobKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	var jobs []*Job
+	var expired []any
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		job, err := decodeJob(ids[i], []byte(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		if filter.matches(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, expired, nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func decodeJob(id string, data []byte) (*Job, error) {
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", id, err)
+	}
+	return &job, nil
+}
@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+func newTestRedisStore(server *miniredis.Miniredis) *RedisStore {
+	return NewRedisStore(redis.NewClient(&redis.Options{Addr: server.Addr()}), "jobs:", time.Hour)
+}
+
+func TestRedisStore_ResumesJobsAfterRestart(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	// The first run is shut down with one job running and one queued
+	first := newTestRunner(t, newTestRedisStore(server), config.JobsConfig{Concurrency: 1})
+	require.NoError(t, first.Start(context.Background()))
+	interrupted, err := first.Enqueue(context.Background(), "block", nil)
+	require.NoError(t, err)
+	waitForStatus(t, first, interrupted.ID, constants.JobStatusRunning)
+	queued, err := first.Enqueue(context.Background(), "echo", json.RawMessage(`"queued"`))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.NoError(t, first.Close(ctx))
+
+	// A job found running, as after a crash, runs again too
+	store := newTestRedisStore(server)
+	crashedAt := time.Now()
+	crashed := &Job{ID: "crashed", Type: "echo", Status: constants.JobStatusRunning, Attempts: 1, CreatedAt: crashedAt, StartedAt: &crashedAt}
+	require.NoError(t, store.Save(context.Background(), crashed))
+
+	second := NewRunner(store, config.JobsConfig{Concurrency: 1}, first.logger)
+	second.Register("echo", func(_ context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		return payload, nil
+	})
+	second.Register("block", func(context.Context, json.RawMessage) (json.RawMessage, error) {
+		return json.RawMessage(`"resumed"`), nil
+	})
+	require.NoError(t, second.Start(context.Background()))
+	defer closeRunner(t, second)
+
+	job := waitForStatus(t, second, interrupted.ID, constants.JobStatusSucceeded)
+	assert.Equal(t, 2, job.Attempts)
+	assert.JSONEq(t, `"resumed"`, string(job.Result))
+	job = waitForStatus(t, second, queued.ID, constants.JobStatusSucceeded)
+	assert.Equal(t, 1, job.Attempts)
+	assert.JSONEq(t, `"queued"`, string(job.Result))
+	job = waitForStatus(t, second, crashed.ID, constants.JobStatusSucceeded)
+	assert.Equal(t, 2, job.Attempts)
+}
+
+func TestRedisStore_LeavesJobsLeasedByOtherInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := newTestRedisStore(server)
+	ctx := context.Background()
+	now := time.Now()
+	leased, lapsed := now.Add(time.Hour), now.Add(-time.Minute)
+
+	jobs := []*Job{
+		{ID: "elsewhere", Owner: "other-host", LeaseUntil: &leased},
+		{ID: "lapsed", Owner: "other-host", LeaseUntil: &lapsed},
+		{ID: "own", LeaseUntil: &leased},
+	}
+	for _, job := range jobs {
+		job.Type, job.Status, job.Attempts, job.CreatedAt, job.StartedAt = "echo", constants.JobStatusRunning, 1, now, &now
+		require.NoError(t, store.Save(ctx, job))
+	}
+
+	runner := newTestRunner(t, store, config.JobsConfig{Concurrency: 1})
+	jobs[2].Owner = runner.owner
+	require.NoError(t, store.Save(ctx, jobs[2]))
+	require.NoError(t, runner.Start(ctx))
+	defer closeRunner(t, runner)
+
+	// A lapsed lease means its instance is gone; this instance's own jobs were left by a crash
+	job := waitForStatus(t, runner, "lapsed", constants.JobStatusSucceeded)
+	assert.Equal(t, runner.owner, job.Owner)
+	assert.Nil(t, job.LeaseUntil)
+	waitForStatus(t, runner, "own", constants.JobStatusSucceeded)
+
+	job, err := store.Get(ctx, "elsewhere")
+	require.NoError(t, err)
+	assert.Equal(t, constants.JobStatusRunning, job.Status, "the other instance is still running it")
+	assert.Equal(t, "other-host", job.Owner)
+}
+
+func TestRedisStore_ListReadsInBatches(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := newTestRedisStore(server)
+	store.batchSize = 2
+	ctx := context.Background()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, store.Save(ctx, &Job{ID: id, Type: "echo", Status: constants.JobStatusQueued, CreatedAt: created.Add(time.Duration(i) * time.Second)}))
+	}
+	server.Del("jobs:job:d")
+
+	jobs, err := store.List(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e", "c", "b", "a"}, jobIDs(jobs), "entries after an expired one are not skipped")
+
+	jobs, err = store.List(ctx, Filter{Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e", "c", "b"}, jobIDs(jobs))
+
+	members, err := store.client.ZCard(ctx, "jobs:index").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), members)
+}
+
+func TestRedisStore_FinishedJobsExpire(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := newTestRedisStore(server)
+	ctx := context.Background()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	finished := created.Add(time.Minute)
+
+	require.NoError(t, store.Save(ctx, &Job{ID: "old", Type: "echo", Status: constants.JobStatusSucceeded, CreatedAt: created, FinishedAt: &finished}))
+	require.NoError(t, store.Save(ctx, &Job{ID: "new", Type: "echo", Status: constants.JobStatusQueued, CreatedAt: created.Add(time.Second)}))
+
+	jobs, err := store.List(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new", "old"}, jobIDs(jobs))
+	assert.Equal(t, time.Hour, server.TTL("jobs:job:old"))
+	assert.Zero(t, server.TTL("jobs:job:new"), "unfinished jobs never expire")
+
+	server.FastForward(2 * time.Hour)
+	_, err = store.Get(ctx, "old")
+	assert.ErrorIs(t, err, ErrNotFound)
+	jobs, err = store.List(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, jobIDs(jobs))
+	members, err := store.client.ZRange(ctx, "jobs:index", 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, members, "expired jobs leave the index")
+
+	require.NoError(t, store.Close())
+}
+
+func TestRedisStore_FailsWhenServerDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := newTestRedisStore(server)
+	server.Close()
+
+	err := store.Save(context.Background(), &Job{ID: "a", Status: constants.JobStatusQueued})
+	assert.ErrorContains(t, err, "failed to save job a")
+	_, err = store.List(context.Background(), Filter{})
+	assert.Error(t, err)
+	require.NoError(t, store.Close())
+}
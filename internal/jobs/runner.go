@@ -0,0 +1,382 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+var (
+	// errCanceled is the cause of canceling a running job on request
+	errCanceled = errors.New("job canceled")
+	// errShutdown is the cause of interrupting the jobs still running when the drain timeout passes
+	errShutdown = errors.New("job runner shut down")
+)
+
+// Runner runs jobs on a pool of workers, oldest first, each under its own deadline. A job's
+// panic fails that job only. Runner implements Queue.
+type Runner struct {
+	store    Store
+	cfg      config.JobsConfig
+	logger   *zap.Logger
+	handlers map[string]Handler
+	now      func() time.Time
+	owner    string // Instance ID recorded on the jobs this runner runs
+
+	mu      sync.Mutex
+	ready   *sync.Cond // Signaled when a job is queued or the runner closes
+	pending []string   // IDs of queued jobs, oldest first
+	running map[string]context.CancelCauseFunc
+	started bool
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// NewRunner returns a runner keeping its jobs in store. Handlers are registered before Start.
+func NewRunner(store Store, cfg config.JobsConfig, logger *zap.Logger) *Runner {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = constants.DefaultJobConcurrency
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = constants.DefaultJobTimeout
+	}
+	r := &Runner{
+		store:    store,
+		cfg:      cfg,
+		logger:   logger,
+		handlers: make(map[string]Handler),
+		now:      time.Now,
+		owner:    instanceID(),
+		running:  make(map[string]context.CancelCauseFunc),
+	}
+	r.ready = sync.NewCond(&r.mu)
+	return r
+}
+
+// Register sets the handler of a job type
+func (r *Runner) Register(jobType string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = handler
+}
+
+// Start queues the jobs the store holds from a previous run, oldest first, and starts the
+// workers. Jobs found running were interrupted by a crash and run again, unless another
+// instance sharing the store runs them and its lease on them has not run out.
+func (r *Runner) Start(ctx context.Context) error {
+	stored, err := r.store.List(ctx, Filter{Statuses: []string{constants.JobStatusQueued, constants.JobStatusRunning}})
+	if err != nil {
+		return fmt.Errorf("failed to resume jobs: %w", err)
+	}
+	slices.Reverse(stored)
+
+	resumed := make([]string, 0, len(stored))
+	for _, job := range stored {
+		if job.leasedElsewhere(r.owner, r.now()) {
+			continue
+		}
+		if job.Status == constants.JobStatusRunning {
+			requeue(job)
+			if err := r.store.Save(ctx, job); err != nil {
+				return fmt.Errorf("failed to resume jobs: %w", err)
+			}
+		}
+		resumed = append(resumed, job.ID)
+	}
+	if len(resumed) > 0 {
+		r.logger.Info("Resuming jobs", zap.Int("jobs", len(resumed)))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return errors.New("job runner already started")
+	}
+	r.started = true
+	// Jobs enqueued before the start are both stored and pending already
+	resumed = slices.DeleteFunc(resumed, func(id string) bool { return slices.Contains(r.pending, id) })
+	r.pending = append(resumed, r.pending...)
+	for i := 0; i < r.cfg.Concurrency; i++ {
+		r.workers.Add(1)
+		go r.work()
+	}
+	r.ready.Broadcast()
+	return nil
+}
+
+// Enqueue stores a new job and queues it to run
+func (r *Runner) Enqueue(ctx context.Context, jobType string, payload json.RawMessage) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	job := &Job{ID: id, Type: jobType, Payload: payload, Status: constants.JobStatusQueued, CreatedAt: r.now()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrClosed
+	}
+	if _, ok := r.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownType, jobType)
+	}
+	if r.cfg.MaxQueued > 0 && len(r.pending) >= r.cfg.MaxQueued {
+		return nil, ErrQueueFull
+	}
+	// Saved under the lock, so no worker picks the job up before it is stored
+	if err := r.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	r.pending = append(r.pending, job.ID)
+	r.ready.Signal()
+	return job, nil
+}
+
+// Get returns the job with the ID, or ErrNotFound
+func (r *Runner) Get(ctx context.Context, id string) (*Job, error) {
+	return r.store.Get(ctx, id)
+}
+
+// List returns the jobs the filter selects, most recently created first
+func (r *Runner) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	return r.store.List(ctx, filter)
+}
+
+// Cancel cancels a queued job at once and a running one once its handler returns, which
+// ctx cancellation asks it to do. Canceling a finished job returns it with ErrFinished.
+func (r *Runner) Cancel(ctx context.Context, id string) (*Job, error) {
+	job, err := r.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Finished() {
+		return job, ErrFinished
+	}
+
+	// The store is read and written outside the lock, which the workers need to pick up jobs
+	r.mu.Lock()
+	if cancel, ok := r.running[id]; ok {
+		cancel(errCanceled)
+		r.mu.Unlock()
+		return job, nil
+	}
+	pending := len(r.pending)
+	r.pending = slices.DeleteFunc(r.pending, func(pending string) bool { return pending == id })
+	dequeued := len(r.pending) < pending
+	r.mu.Unlock()
+
+	// A job neither queued nor running here may have finished since it was read
+	if !dequeued {
+		if job, err = r.store.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		if job.Finished() {
+			return job, ErrFinished
+		}
+	}
+	finished := r.now()
+	job.Status = constants.JobStatusCanceled
+	job.FinishedAt = &finished
+	if err := r.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Close stops taking jobs and gives the running ones until ctx is done to finish; those
+// still running then are interrupted and queued again. Queued jobs stay in the store for
+// the next start, which only the Redis store keeps across restarts. Close closes the store.
+func (r *Runner) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.ready.Broadcast()
+	r.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		r.workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.logger.Warn("Interrupting jobs still running at shutdown", zap.Int("jobs", len(r.running)))
+		for _, cancel := range r.running {
+			cancel(errShutdown)
+		}
+		r.mu.Unlock()
+		<-drained
+	}
+	return r.store.Close()
+}
+
+// work runs queued jobs until the runner closes
+func (r *Runner) work() {
+	defer r.workers.Done()
+	for {
+		id, ctx, ok := r.next()
+		if !ok {
+			return
+		}
+		r.run(ctx, id)
+	}
+}
+
+// next waits for a queued job and marks it running, returning its context; ok is false
+// once the runner closes
+func (r *Runner) next() (id string, ctx context.Context, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.pending) == 0 && !r.closed {
+		r.ready.Wait()
+	}
+	if r.closed {
+		return "", nil, false
+	}
+
+	id = r.pending[0]
+	r.pending = r.pending[1:]
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r.running[id] = cancel
+	return id, ctx, true
+}
+
+// run runs one job and stores its outcome
+func (r *Runner) run(ctx context.Context, id string) {
+	defer func() {
+		r.mu.Lock()
+		r.running[id](nil)
+		delete(r.running, id)
+		r.mu.Unlock()
+	}()
+
+	job, err := r.load(id)
+	if err != nil {
+		r.logger.Error("Failed to read queued job", zap.String("job_id", id), zap.Error(err))
+		return
+	}
+	if job.Finished() || job.leasedElsewhere(r.owner, r.now()) {
+		return
+	}
+	r.mu.Lock()
+	handler, ok := r.handlers[job.Type]
+	r.mu.Unlock()
+	if !ok {
+		r.finish(job, constants.JobStatusFailed, nil, fmt.Errorf("%w %q", ErrUnknownType, job.Type))
+		return
+	}
+
+	started := r.now()
+	leaseUntil := started.Add(r.cfg.Timeout + constants.JobLeaseGrace)
+	job.Status = constants.JobStatusRunning
+	job.StartedAt = &started
+	job.Owner = r.owner
+	job.LeaseUntil = &leaseUntil
+	job.Attempts++
+	if err := r.save(job); err != nil {
+		r.logger.Error("Failed to start job", zap.String("job_id", id), zap.Error(err))
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	result, err := r.call(jobCtx, handler, job)
+	timedOut := errors.Is(jobCtx.Err(), context.DeadlineExceeded)
+	cancel()
+
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errCanceled):
+		r.finish(job, constants.JobStatusCanceled, nil, nil)
+	case errors.Is(cause, errShutdown):
+		// Interrupted jobs run again on the next start
+		requeue(job)
+		if err := r.save(job); err != nil {
+			r.logger.Error("Failed to requeue interrupted job", zap.String("job_id", id), zap.Error(err))
+		}
+	case timedOut:
+		r.finish(job, constants.JobStatusFailed, nil, fmt.Errorf("job exceeded its timeout of %s", r.cfg.Timeout))
+	case err != nil:
+		r.finish(job, constants.JobStatusFailed, nil, err)
+	default:
+		r.finish(job, constants.JobStatusSucceeded, result, nil)
+	}
+}
+
+// call runs the handler, turning a panic into the job's error
+func (r *Runner) call(ctx context.Context, handler Handler, job *Job) (result json.RawMessage, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r.logger.Error("Job panicked",
+				zap.String("job_id", job.ID),
+				zap.String("type", job.Type),
+				zap.Any("panic", recovered),
+				zap.ByteString("stack", debug.Stack()),
+			)
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, job.Payload)
+}
+
+// finish stores the outcome of a job
+func (r *Runner) finish(job *Job, status string, result json.RawMessage, err error) {
+	finished := r.now()
+	job.Status = status
+	job.Result = result
+	job.FinishedAt = &finished
+	job.LeaseUntil = nil
+	if err != nil {
+		job.Error = err.Error()
+		r.logger.Warn("Job failed", zap.String("job_id", job.ID), zap.String("type", job.Type), zap.Error(err))
+	}
+	if err := r.save(job); err != nil {
+		r.logger.Error("Failed to record job outcome", zap.String("job_id", job.ID), zap.String("status", status), zap.Error(err))
+	}
+}
+
+// load reads a job with a deadline of its own, as the job's context may be done
+func (r *Runner) load(id string) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.JobStoreTimeout)
+	defer cancel()
+	return r.store.Get(ctx, id)
+}
+
+// save stores a job with a deadline of its own, as the job's context may be done
+func (r *Runner) save(job *Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.JobStoreTimeout)
+	defer cancel()
+	return r.store.Save(ctx, job)
+}
+
+// requeue returns an interrupted job to the queue, releasing its lease
+func requeue(job *Job) {
+	job.Status = constants.JobStatusQueued
+	job.StartedAt = nil
+	job.Owner = ""
+	job.LeaseUntil = nil
+}
+
+// instanceID names this instance on the jobs it runs: the host name, which a restarted
+// instance keeps so it takes back the jobs a crash left running, or a random ID without one
+func instanceID() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	id, _ := newJobID()
+	return id
+}
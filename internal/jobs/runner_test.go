@@ -0,0 +1,346 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+func newTestRunner(t *testing.T, store Store, cfg config.JobsConfig) *Runner {
+	t.Helper()
+	runner := NewRunner(store, cfg, zaptest.NewLogger(t))
+	runner.Register("echo", func(_ context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		return payload, nil
+	})
+	// block runs until its job is canceled, interrupted or times out
+	runner.Register("block", func(ctx context.Context, _ json.RawMessage) (json.RawMessage, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	return runner
+}
+
+// waitForStatus waits for the job to reach the status and returns it
+func waitForStatus(t *testing.T, queue Queue, id, status string) *Job {
+	t.Helper()
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = queue.Get(context.Background(), id)
+		return err == nil && job.Status == status
+	}, 5*time.Second, 5*time.Millisecond, "job %s never became %s", id, status)
+	return job
+}
+
+func closeRunner(t *testing.T, runner *Runner) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, runner.Close(ctx))
+}
+
+func TestRunner_CompletesJobs(t *testing.T) {
+	runner := newTestRunner(t, NewMemoryStore(time.Hour), config.JobsConfig{Concurrency: 2})
+	require.NoError(t, runner.Start(context.Background()))
+	defer closeRunner(t, runner)
+
+	job, err := runner.Enqueue(context.Background(), "echo", json.RawMessage(`{"url":"https://example.com"}`))
+	require.NoError(t, err)
+	assert.Equal(t, constants.JobStatusQueued, job.Status)
+	assert.Len(t, job.ID, 2*constants.JobIDByteLength)
+
+	done := waitForStatus(t, runner, job.ID, constants.JobStatusSucceeded)
+	assert.JSONEq(t, `{"url":"https://example.com"}`, string(done.Result))
+	assert.Equal(t, 1, done.Attempts)
+	require.NotNil(t, done.StartedAt)
+	require.NotNil(t, done.FinishedAt)
+	assert.False(t, done.FinishedAt.Before(*done.StartedAt))
+	assert.Empty(t, done.Error)
+}
+
+func TestRunner_RunsJobsConcurrently(t *testing.T) {
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	runner := NewRunner(NewMemoryStore(0), config.JobsConfig{Concurrency: 3}, zaptest.NewLogger(t))
+	runner.Register("wait", func(context.Context, json.RawMessage) (json.RawMessage, error) {
+		n := running.Add(1)
+		for {
+			current := peak.Load()
+			if n <= current || peak.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return nil, nil
+	})
+	require.NoError(t, runner.Start(context.Background()))
+	defer closeRunner(t, runner)
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		job, err := runner.Enqueue(context.Background(), "wait", nil)
+		require.NoError(t, err)
+		ids = append(ids, job.ID)
+	}
+	require.Eventually(t, func() bool { return running.Load() == 3 }, 5*time.Second, 5*time.Millisecond)
+	close(release)
+
+	for _, id := range ids {
+		waitForStatus(t, runner, id, constants.JobStatusSucceeded)
+	}
+	assert.Equal(t, int32(3), peak.Load(), "no more than jobs.concurrency jobs run at once")
+}
+
+func TestRunner_Cancel(t *testing.T) {
+	runner := newTestRunner(t, NewMemoryStore(time.Hour), config.JobsConfig{Concurrency: 1})
+	require.NoError(t, runner.Start(context.Background()))
+	defer closeRunner(t, runner)
+
+	running, err := runner.Enqueue(context.Background(), "block", nil)
+	require.NoError(t, err)
+	waitForStatus(t, runner, running.ID, constants.JobStatusRunning)
+	queued, err := runner.Enqueue(context.Background(), "echo", nil)
+	require.NoError(t, err)
+
+	t.Run("Queued", func(t *testing.T) {
+		job, err := runner.Cancel(context.Background(), queued.ID)
+		require.NoError(t, err)
+		assert.Equal(t, constants.JobStatusCanceled, job.Status)
+		assert.Zero(t, job.Attempts, "a canceled queued job never starts")
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		_, err := runner.Cancel(context.Background(), running.ID)
+		require.NoError(t, err)
+
+		job := waitForStatus(t, runner, running.ID, constants.JobStatusCanceled)
+		assert.NotNil(t, job.FinishedAt)
+	})
+
+	t.Run("Finished", func(t *testing.T) {
+		job, err := runner.Cancel(context.Background(), running.ID)
+		assert.ErrorIs(t, err, ErrFinished)
+		assert.Equal(t, constants.JobStatusCanceled, job.Status)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := runner.Cancel(context.Background(), "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	// The worker the running job held is free again, and the canceled job is never run
+	next, err := runner.Enqueue(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	waitForStatus(t, runner, next.ID, constants.JobStatusSucceeded)
+	job, err := runner.Get(context.Background(), queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.JobStatusCanceled, job.Status)
+}
+
+// slowGetStore blocks reads of one job until released
+type slowGetStore struct {
+	*MemoryStore
+	id      string
+	once    sync.Once
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (s *slowGetStore) Get(ctx context.Context, id string) (*Job, error) {
+	if id == s.id {
+		s.once.Do(func() { close(s.reading) })
+		<-s.release
+	}
+	return s.MemoryStore.Get(ctx, id)
+}
+
+func TestRunner_CancelDoesNotBlockWorkers(t *testing.T) {
+	store := &slowGetStore{MemoryStore: NewMemoryStore(time.Hour), id: "slow", reading: make(chan struct{}), release: make(chan struct{})}
+	runner := newTestRunner(t, store, config.JobsConfig{Concurrency: 1})
+	require.NoError(t, runner.Start(context.Background()))
+	defer closeRunner(t, runner)
+	created := time.Now()
+	require.NoError(t, store.Save(context.Background(), &Job{ID: "slow", Type: "echo", Status: constants.JobStatusQueued, CreatedAt: created}))
+
+	canceled := make(chan error)
+	go func() {
+		_, err := runner.Cancel(context.Background(), "slow")
+		canceled <- err
+	}()
+	<-store.reading
+
+	// Jobs are enqueued and run while the store is still being read for the cancellation
+	job, err := runner.Enqueue(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	waitForStatus(t, runner, job.ID, constants.JobStatusSucceeded)
+
+	close(store.release)
+	require.NoError(t, <-canceled)
+	waitForStatus(t, runner, "slow", constants.JobStatusCanceled)
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	runner := newTestRunner(t, NewMemoryStore(time.Hour), config.JobsConfig{Concurrency: 1, Timeout: 20 * time.Millisecond})
+	require.NoError(t, runner.Start(context.Background()))
+	defer closeRunner(t, runner)
+
+	job, err := runner.Enqueue(context.Background(), "block", nil)
+	require.NoError(t, err)
+
+	failed := waitForStatus(t, runner, job.ID, constants.JobStatusFailed)
+	assert.Contains(t, failed.Error, "exceeded its timeout of 20ms")
+}
+
+func TestRunner_PanicFailsOnlyItsJob(t *testing.T) {
+	runner := newTestRunner(t, NewMemoryStore(time.Hour), config.JobsConfig{Concurrency: 1})
+	runner.Register("crash", func(context.Context, json.RawMessage) (json.RawMessage, error) {
+		panic("boom")
+	})
+	runner.Register("fail", func(context.Context, json.RawMessage) (json.RawMessage, error) {
+		return nil, errors.New("target unreachable")
+	})
+	require.NoError(t, runner.Start(context.Background()))
+	defer closeRunner(t, runner)
+
+	crash, err := runner.Enqueue(context.Background(), "crash", nil)
+	require.NoError(t, err)
+	fail, err := runner.Enqueue(context.Background(), "fail", nil)
+	require.NoError(t, err)
+	echo, err := runner.Enqueue(context.Background(), "echo", json.RawMessage(`1`))
+	require.NoError(t, err)
+
+	crashed := waitForStatus(t, runner, crash.ID, constants.JobStatusFailed)
+	assert.Equal(t, "job panicked: boom", crashed.Error)
+	failed := waitForStatus(t, runner, fail.ID, constants.JobStatusFailed)
+	assert.Equal(t, "target unreachable", failed.Error)
+	waitForStatus(t, runner, echo.ID, constants.JobStatusSucceeded)
+}
+
+func TestRunner_EnqueueRejections(t *testing.T) {
+	runner := newTestRunner(t, NewMemoryStore(time.Hour), config.JobsConfig{MaxQueued: 1})
+
+	_, err := runner.Enqueue(context.Background(), "crawl", nil)
+	assert.ErrorIs(t, err, ErrUnknownType)
+
+	// Not started, so jobs wait
+	_, err = runner.Enqueue(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	_, err = runner.Enqueue(context.Background(), "echo", nil)
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	closeRunner(t, runner)
+	_, err = runner.Enqueue(context.Background(), "echo", nil)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRunner_CloseDrainsRunningJobs(t *testing.T) {
+	release := make(chan struct{})
+	runner := newTestRunner(t, NewMemoryStore(time.Hour), config.JobsConfig{Concurrency: 1})
+	runner.Register("slow", func(context.Context, json.RawMessage) (json.RawMessage, error) {
+		<-release
+		return json.RawMessage(`"done"`), nil
+	})
+	require.NoError(t, runner.Start(context.Background()))
+
+	slow, err := runner.Enqueue(context.Background(), "slow", nil)
+	require.NoError(t, err)
+	waitForStatus(t, runner, slow.ID, constants.JobStatusRunning)
+	queued, err := runner.Enqueue(context.Background(), "echo", nil)
+	require.NoError(t, err)
+
+	closed := make(chan error)
+	go func() { closed <- runner.Close(context.Background()) }()
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the running job finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-closed)
+
+	job, err := runner.Get(context.Background(), slow.ID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.JobStatusSucceeded, job.Status)
+	job, err = runner.Get(context.Background(), queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.JobStatusQueued, job.Status, "queued jobs wait for the next start")
+}
+
+func TestRunner_CloseInterruptsJobsPastTheDrainTimeout(t *testing.T) {
+	runner := newTestRunner(t, NewMemoryStore(time.Hour), config.JobsConfig{Concurrency: 1})
+	require.NoError(t, runner.Start(context.Background()))
+
+	job, err := runner.Enqueue(context.Background(), "block", nil)
+	require.NoError(t, err)
+	waitForStatus(t, runner, job.ID, constants.JobStatusRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.NoError(t, runner.Close(ctx))
+
+	interrupted, err := runner.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.JobStatusQueued, interrupted.Status)
+	assert.Nil(t, interrupted.StartedAt)
+	assert.Equal(t, 1, interrupted.Attempts)
+}
+
+func TestRunner_List(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	runner := newTestRunner(t, store, config.JobsConfig{})
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var tick int
+	runner.now = func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Second)
+	}
+	store.now = func() time.Time { return base.Add(time.Minute) }
+
+	first, err := runner.Enqueue(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	second, err := runner.Enqueue(context.Background(), "block", nil)
+	require.NoError(t, err)
+	third, err := runner.Enqueue(context.Background(), "echo", nil)
+	require.NoError(t, err)
+	_, err = runner.Cancel(context.Background(), third.ID)
+	require.NoError(t, err)
+
+	all, err := runner.List(context.Background(), Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{third.ID, second.ID, first.ID}, jobIDs(all))
+
+	echoes, err := runner.List(context.Background(), Filter{Type: "echo", Statuses: []string{constants.JobStatusQueued}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.ID}, jobIDs(echoes))
+
+	limited, err := runner.List(context.Background(), Filter{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{third.ID}, jobIDs(limited))
+
+	// Finished jobs are forgotten after the retention
+	store.now = func() time.Time { return base.Add(2 * time.Hour) }
+	_, err = runner.Get(context.Background(), third.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = runner.Get(context.Background(), first.ID)
+	assert.NoError(t, err)
+}
+
+func jobIDs(jobs []*Job) []string {
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	return ids
+}
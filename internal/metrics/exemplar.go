@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// Observe records value on observer, attaching the trace ID of the sampled span in ctx
+// as an exemplar so a slow bucket can be followed to the trace that landed in it
+func Observe(ctx context.Context, observer prometheus.Observer, value float64) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && spanContext.IsSampled() {
+		exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{constants.ExemplarTraceIDLabel: spanContext.TraceID().String()})
+		return
+	}
+	observer.Observe(value)
+}
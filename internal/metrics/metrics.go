@@ -5,23 +5,54 @@ import (
 	"github.com/webpage-analyser-server/internal/constants"
 )
 
+// Options configures how the metrics are exposed
+type Options struct {
+	// NativeHistograms additionally exposes the latency histograms as native histograms,
+	// which Prometheus 2.40+ scrapes over the protobuf format. Classic buckets are kept.
+	NativeHistograms bool
+}
+
 // Metrics holds all Prometheus metrics for the application
 type Metrics struct {
 	RequestDuration   *prometheus.HistogramVec
 	CacheHits        prometheus.Counter
 	CacheMisses      prometheus.Counter
 	LinkCheckDuration prometheus.Histogram
+	CacheOpDuration   *prometheus.HistogramVec
+	CacheErrors       *prometheus.CounterVec
+	CacheCorruption   prometheus.Counter
+	Panics            prometheus.Counter
+	RenderDuration    prometheus.Histogram
+	RenderTotal       *prometheus.CounterVec
+	OutboundRequests  *prometheus.CounterVec
+	AnalysisOutboundRequests prometheus.Histogram
+	FetchedBytes             *prometheus.CounterVec
+	PolicyViolations         *prometheus.CounterVec
+	AuditEventsDropped       prometheus.Counter
+	RateLimitDecisions       *prometheus.CounterVec
+	RateLimitEntries         prometheus.Gauge
+	OutboundProbeUp          prometheus.Gauge
+}
+
+// New creates the application metrics and registers them with the default Prometheus registry
+func New(opts Options) *Metrics {
+	return NewWithOptions(prometheus.DefaultRegisterer, opts)
 }
 
+// NewWithRegistry creates the application metrics and registers them with the given registerer
+func NewWithRegistry(reg prometheus.Registerer) *Metrics {
+	return NewWithOptions(reg, Options{})
+}
 
-func New() *Metrics {
+// NewWithOptions creates the application metrics with the given options and registers them with reg
+func NewWithOptions(reg prometheus.Registerer, opts Options) *Metrics {
 	m := &Metrics{
 		RequestDuration: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
+			latencyHistogramOpts(opts, prometheus.HistogramOpts{
 				Name:    constants.MetricRequestDurationName,
 				Help:    constants.MetricRequestDurationHelp,
 				Buckets: prometheus.DefBuckets,
-			},
+			}),
 			[]string{"status"},
 		),
 		CacheHits: prometheus.NewCounter(
@@ -37,19 +68,137 @@ func New() *Metrics {
 			},
 		),
 		LinkCheckDuration: prometheus.NewHistogram(
-			prometheus.HistogramOpts{
+			latencyHistogramOpts(opts, prometheus.HistogramOpts{
 				Name:    constants.MetricLinkCheckDurationName,
 				Help:    constants.MetricLinkCheckDurationHelp,
 				Buckets: prometheus.DefBuckets,
+			}),
+		),
+		CacheOpDuration: prometheus.NewHistogramVec(
+			latencyHistogramOpts(opts, prometheus.HistogramOpts{
+				Name:    constants.MetricCacheOpDurationName,
+				Help:    constants.MetricCacheOpDurationHelp,
+				Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+			}),
+			[]string{"operation"},
+		),
+		CacheErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: constants.MetricCacheErrorsName,
+				Help: constants.MetricCacheErrorsHelp,
+			},
+			[]string{"operation"},
+		),
+		CacheCorruption: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: constants.MetricCacheCorruptionName,
+				Help: constants.MetricCacheCorruptionHelp,
+			},
+		),
+		Panics: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: constants.MetricPanicsName,
+				Help: constants.MetricPanicsHelp,
+			},
+		),
+		RenderDuration: prometheus.NewHistogram(
+			latencyHistogramOpts(opts, prometheus.HistogramOpts{
+				Name:    constants.MetricRenderDurationName,
+				Help:    constants.MetricRenderDurationHelp,
+				Buckets: []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30},
+			}),
+		),
+		RenderTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: constants.MetricRenderTotalName,
+				Help: constants.MetricRenderTotalHelp,
+			},
+			[]string{"outcome"},
+		),
+		OutboundRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: constants.MetricOutboundRequestsName,
+				Help: constants.MetricOutboundRequestsHelp,
+			},
+			[]string{"outcome"},
+		),
+		AnalysisOutboundRequests: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    constants.MetricAnalysisOutboundName,
+				Help:    constants.MetricAnalysisOutboundHelp,
+				Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500},
+			},
+		),
+		FetchedBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: constants.MetricFetchedBytesName,
+				Help: constants.MetricFetchedBytesHelp,
+			},
+			[]string{"source"},
+		),
+		PolicyViolations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: constants.MetricPolicyViolationsName,
+				Help: constants.MetricPolicyViolationsHelp,
+			},
+			[]string{"mode"},
+		),
+		AuditEventsDropped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: constants.MetricAuditDroppedName,
+				Help: constants.MetricAuditDroppedHelp,
+			},
+		),
+		RateLimitDecisions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: constants.MetricRateLimitDecisionsName,
+				Help: constants.MetricRateLimitDecisionsHelp,
+			},
+			[]string{"decision", "key_type"},
+		),
+		RateLimitEntries: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: constants.MetricRateLimitEntriesName,
+				Help: constants.MetricRateLimitEntriesHelp,
+			},
+		),
+		OutboundProbeUp: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: constants.MetricOutboundProbeUpName,
+				Help: constants.MetricOutboundProbeUpHelp,
 			},
 		),
 	}
 
 	// Register all metrics
-	prometheus.MustRegister(m.RequestDuration)
-	prometheus.MustRegister(m.CacheHits)
-	prometheus.MustRegister(m.CacheMisses)
-	prometheus.MustRegister(m.LinkCheckDuration)
+	reg.MustRegister(m.RequestDuration)
+	reg.MustRegister(m.CacheHits)
+	reg.MustRegister(m.CacheMisses)
+	reg.MustRegister(m.LinkCheckDuration)
+	reg.MustRegister(m.CacheOpDuration)
+	reg.MustRegister(m.CacheErrors)
+	reg.MustRegister(m.CacheCorruption)
+	reg.MustRegister(m.Panics)
+	reg.MustRegister(m.RenderDuration)
+	reg.MustRegister(m.RenderTotal)
+	reg.MustRegister(m.OutboundRequests)
+	reg.MustRegister(m.AnalysisOutboundRequests)
+	reg.MustRegister(m.FetchedBytes)
+	reg.MustRegister(m.PolicyViolations)
+	reg.MustRegister(m.AuditEventsDropped)
+	reg.MustRegister(m.RateLimitDecisions)
+	reg.MustRegister(m.RateLimitEntries)
+	reg.MustRegister(m.OutboundProbeUp)
 
 	return m
-} 
\ No newline at end of file
+}
+
+// latencyHistogramOpts enables native buckets on a latency histogram when configured
+func latencyHistogramOpts(opts Options, histogram prometheus.HistogramOpts) prometheus.HistogramOpts {
+	if opts.NativeHistograms {
+		histogram.NativeHistogramBucketFactor = constants.NativeHistogramBucketFactor
+		histogram.NativeHistogramMaxBucketNumber = constants.NativeHistogramMaxBuckets
+		histogram.NativeHistogramMinResetDuration = constants.NativeHistogramMinResetDuration
+	}
+	return histogram
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// sampledContext returns a context carrying a sampled remote span
+func sampledContext(t *testing.T, sampled bool) (context.Context, trace.TraceID) {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+
+	flags := trace.TraceFlags(0)
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: flags, Remote: true})
+	return trace.ContextWithRemoteSpanContext(context.Background(), spanContext), traceID
+}
+
+// gatherHistogram returns the single histogram of the named family
+func gatherHistogram(t *testing.T, reg *prometheus.Registry, name string) *dto.Histogram {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			require.Len(t, family.GetMetric(), 1)
+			return family.GetMetric()[0].GetHistogram()
+		}
+	}
+	t.Fatalf("metric family %s not gathered", name)
+	return nil
+}
+
+// exemplarTraceIDs lists the trace_id labels of the exemplars on the histogram's buckets
+func exemplarTraceIDs(histogram *dto.Histogram) []string {
+	ids := []string{}
+	for _, bucket := range histogram.GetBucket() {
+		for _, label := range bucket.GetExemplar().GetLabel() {
+			if label.GetName() == constants.ExemplarTraceIDLabel {
+				ids = append(ids, label.GetValue())
+			}
+		}
+	}
+	return ids
+}
+
+func TestObserve_Exemplars(t *testing.T) {
+	t.Run("Sampled span attaches its trace ID", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := NewWithRegistry(reg)
+		ctx, traceID := sampledContext(t, true)
+
+		Observe(ctx, m.LinkCheckDuration, 0.3)
+		Observe(ctx, m.RequestDuration.WithLabelValues("200"), 1.2)
+
+		assert.Equal(t, []string{traceID.String()}, exemplarTraceIDs(gatherHistogram(t, reg, constants.MetricLinkCheckDurationName)))
+		assert.Equal(t, []string{traceID.String()}, exemplarTraceIDs(gatherHistogram(t, reg, constants.MetricRequestDurationName)))
+	})
+
+	t.Run("Unsampled span and missing trace record no exemplar", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := NewWithRegistry(reg)
+		ctx, _ := sampledContext(t, false)
+
+		Observe(ctx, m.LinkCheckDuration, 0.3)
+		Observe(context.Background(), m.LinkCheckDuration, 0.4)
+
+		histogram := gatherHistogram(t, reg, constants.MetricLinkCheckDurationName)
+		assert.Equal(t, uint64(2), histogram.GetSampleCount())
+		assert.Empty(t, exemplarTraceIDs(histogram))
+	})
+}
+
+func TestNewWithOptions_NativeHistograms(t *testing.T) {
+	t.Run("Enabled", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := NewWithOptions(reg, Options{NativeHistograms: true})
+		m.RenderDuration.Observe(1.5)
+
+		histogram := gatherHistogram(t, reg, constants.MetricRenderDurationName)
+		assert.NotEmpty(t, histogram.GetPositiveSpan(), "native buckets are exposed")
+		assert.NotEmpty(t, histogram.GetBucket(), "classic buckets are kept")
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m := NewWithRegistry(reg)
+		m.RenderDuration.Observe(1.5)
+
+		histogram := gatherHistogram(t, reg, constants.MetricRenderDurationName)
+		assert.Empty(t, histogram.GetPositiveSpan())
+	})
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+)
+
+// AccessLogger logs processed requests with query redaction and sampling
+type AccessLogger struct {
+	logger     *zap.Logger
+	metrics    *metrics.Metrics
+	enabled    bool
+	sampleRate uint64
+	redacted   map[string]struct{}
+	counter    uint64
+}
+
+// NewAccessLogger creates a new AccessLogger from the access log configuration
+func NewAccessLogger(cfg config.AccessLogConfig, logger *zap.Logger, metrics *metrics.Metrics) *AccessLogger {
+	sampleRate := cfg.SampleRate
+	if sampleRate < 1 {
+		sampleRate = constants.DefaultAccessLogSampleRate
+	}
+
+	redacted := make(map[string]struct{}, len(cfg.RedactQueryParams))
+	for _, name := range cfg.RedactQueryParams {
+		redacted[strings.ToLower(name)] = struct{}{}
+	}
+
+	return &AccessLogger{
+		logger:     logger,
+		metrics:    metrics,
+		enabled:    cfg.Enabled,
+		sampleRate: uint64(sampleRate),
+		redacted:   redacted,
+	}
+}
+
+// Handler returns the access log middleware
+func (al *AccessLogger) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		query := c.Request.URL.RawQuery
+
+		c.Next()
+
+		latency := time.Since(start)
+		status := c.Writer.Status()
+
+		if al.metrics != nil {
+			metrics.Observe(c.Request.Context(), al.metrics.RequestDuration.WithLabelValues(fmt.Sprintf("%d", status)), latency.Seconds())
+		}
+
+		if !al.shouldLog(status) {
+			return
+		}
+
+		al.logger.Info("Request processed",
+			zap.String("request_id", GetRequestID(c)),
+			zap.String("path", path),
+			zap.String("query", al.redactQuery(query)),
+			zap.Int("status", status),
+			zap.Int("size", c.Writer.Size()),
+			zap.Duration("latency", latency),
+			zap.String("ip", c.ClientIP()),
+			zap.String("method", c.Request.Method),
+			zap.String("user_agent", c.Request.UserAgent()),
+		)
+	}
+}
+
+// shouldLog decides whether a request is logged: all errors, and 1-in-N successful requests
+func (al *AccessLogger) shouldLog(status int) bool {
+	if !al.enabled {
+		return false
+	}
+	if status >= constants.StatusBadRequest {
+		return true
+	}
+	n := atomic.AddUint64(&al.counter, 1)
+	return (n-1)%al.sampleRate == 0
+}
+
+// redactQuery replaces the values of sensitive query parameters, preserving parameter order
+func (al *AccessLogger) redactQuery(rawQuery string) string {
+	if rawQuery == "" || len(al.redacted) == 0 {
+		return rawQuery
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, _, hasValue := strings.Cut(param, "=")
+		if !hasValue {
+			continue
+		}
+		decoded, err := url.QueryUnescape(name)
+		if err != nil {
+			decoded = name
+		}
+		if _, ok := al.redacted[strings.ToLower(decoded)]; ok {
+			params[i] = name + "=" + constants.RedactedValue
+		}
+	}
+	return strings.Join(params, "&")
+}
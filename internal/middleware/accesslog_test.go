@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+func newObservedAccessLogger(cfg config.AccessLogConfig) (*AccessLogger, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.InfoLevel)
+	return NewAccessLogger(cfg, zap.New(core), nil), logs
+}
+
+func TestAccessLogger_RedactQuery(t *testing.T) {
+	al, _ := newObservedAccessLogger(config.AccessLogConfig{
+		Enabled:           true,
+		RedactQueryParams: []string{"token", "API_KEY"},
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{name: "Empty query", query: "", expected: ""},
+		{name: "No sensitive params", query: "page=2&sort=asc", expected: "page=2&sort=asc"},
+		{name: "Single sensitive param", query: "token=abc123", expected: "token=[redacted]"},
+		{name: "Order preserved", query: "a=1&token=secret&b=2", expected: "a=1&token=[redacted]&b=2"},
+		{name: "Case-insensitive names", query: "Token=x&api_key=y", expected: "Token=[redacted]&api_key=[redacted]"},
+		{name: "Escaped parameter name", query: "api%5Fkey=y", expected: "api%5Fkey=[redacted]"},
+		{name: "Repeated param", query: "token=a&token=b", expected: "token=[redacted]&token=[redacted]"},
+		{name: "Flag without value", query: "token&debug", expected: "token&debug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, al.redactQuery(tt.query))
+		})
+	}
+}
+
+func TestAccessLogger_ShouldLog(t *testing.T) {
+	t.Run("Samples successful requests 1-in-N", func(t *testing.T) {
+		al, _ := newObservedAccessLogger(config.AccessLogConfig{Enabled: true, SampleRate: 3})
+
+		logged := 0
+		for i := 0; i < 9; i++ {
+			if al.shouldLog(http.StatusOK) {
+				logged++
+			}
+		}
+		assert.Equal(t, 3, logged)
+	})
+
+	t.Run("Always logs errors", func(t *testing.T) {
+		al, _ := newObservedAccessLogger(config.AccessLogConfig{Enabled: true, SampleRate: 100})
+
+		for i := 0; i < 5; i++ {
+			assert.True(t, al.shouldLog(http.StatusBadRequest))
+			assert.True(t, al.shouldLog(http.StatusInternalServerError))
+		}
+	})
+
+	t.Run("Zero sample rate logs everything", func(t *testing.T) {
+		al, _ := newObservedAccessLogger(config.AccessLogConfig{Enabled: true})
+
+		for i := 0; i < 5; i++ {
+			assert.True(t, al.shouldLog(http.StatusOK))
+		}
+	})
+
+	t.Run("Disabled logs nothing", func(t *testing.T) {
+		al, _ := newObservedAccessLogger(config.AccessLogConfig{Enabled: false})
+
+		assert.False(t, al.shouldLog(http.StatusOK))
+		assert.False(t, al.shouldLog(http.StatusInternalServerError))
+	})
+}
+
+func TestAccessLogger_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	al, logs := newObservedAccessLogger(config.AccessLogConfig{
+		Enabled:           true,
+		SampleRate:        1,
+		RedactQueryParams: []string{"token"},
+	})
+
+	engine := gin.New()
+	engine.Use(RequestID(), al.Handler())
+	engine.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping?token=abc&x=1", nil)
+	req.Header.Set(constants.HeaderRequestID, "req-123")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "req-123", fields["request_id"])
+	assert.Equal(t, "token=[redacted]&x=1", fields["query"])
+	assert.Equal(t, int64(4), fields["size"])
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+	assert.Equal(t, "req-123", w.Header().Get(constants.HeaderRequestID))
+}
+
+func TestRequestID_GeneratesWhenMissingOrInvalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(RequestID())
+	engine.GET("/", func(c *gin.Context) {
+		assert.Equal(t, GetRequestID(c), RequestIDFromContext(c.Request.Context()))
+		c.Status(http.StatusOK)
+	})
+
+	for _, incoming := range []string{"", "has spaces", string(make([]byte, constants.MaxRequestIDLength+1))} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if incoming != "" {
+			req.Header.Set(constants.HeaderRequestID, incoming)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		id := w.Header().Get(constants.HeaderRequestID)
+		assert.Len(t, id, constants.RequestIDByteLength*2)
+		assert.NotEqual(t, incoming, id)
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// AdminAuth requires the configured admin token as a bearer token
+func AdminAuth(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(constants.HeaderAuthorization)), expected) != 1 {
+			c.AbortWithStatusJSON(constants.StatusUnauthorized, models.ErrorResponse{
+				Code:      constants.StatusUnauthorized,
+				ErrorCode: constants.ErrorCodeUnauthorized,
+				Message:   "Invalid or missing admin token",
+				RequestID: GetRequestID(c),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(AdminAuth("s3cret"))
+	engine.GET("/admin/export", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		expected      int
+	}{
+		{name: "Valid token", authorization: "Bearer s3cret", expected: http.StatusOK},
+		{name: "Missing token", authorization: "", expected: http.StatusUnauthorized},
+		{name: "Wrong token", authorization: "Bearer guess", expected: http.StatusUnauthorized},
+		{name: "Token without scheme", authorization: "s3cret", expected: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/export", nil)
+			if tt.authorization != "" {
+				req.Header.Set(constants.HeaderAuthorization, tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
@@ -1,54 +1,328 @@
 package middleware
 
 import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 
 	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
 	"github.com/webpage-analyser-server/internal/models"
 )
 
 // Rate limiting per IP address
 type RateLimiter struct {
-	ips   map[string]*rate.Limiter
-	mu    *sync.RWMutex
-	rate  rate.Limit
-	burst int
+	ips     map[string]*rate.Limiter
+	mu      *sync.RWMutex
+	rate    rate.Limit
+	burst   int
+	costs   map[string]CostFunc // By route path
+	store   LimiterStore        // Keeps budgets across restarts when set
+	logger  *zap.Logger
+	metrics *metrics.Metrics // Decisions and tracked clients; nil disables
+
+	// Budgets are written to the store in the background, the latest per client
+	pending   map[string]pendingState
+	pendingMu sync.Mutex
+	writeMu   sync.Mutex    // Held while pending budgets are written
+	wake      chan struct{} // Signals the writer that budgets are pending
+	written   chan struct{} // Closed once the writer has stopped
+	closeOnce sync.Once
+}
+
+// pendingState is a budget waiting to be written to the store
+type pendingState struct {
+	state LimiterState
+	ttl   time.Duration
 }
 
 
-func NewRateLimiter() *RateLimiter {
+func NewRateLimiter(m *metrics.Metrics) *RateLimiter {
 	requestsPerMinute := viper.GetFloat64("rate_limit.requests_per_minute")
 	if requestsPerMinute == 0 {
 		requestsPerMinute = constants.DefaultRequestsPerMinute
 	}
 
 	return &RateLimiter{
-		ips:   make(map[string]*rate.Limiter),
-		mu:    &sync.RWMutex{},
-		rate:  rate.Limit(requestsPerMinute / 60.0), // Convert to requests per second
-		burst: int(requestsPerMinute * constants.DefaultRateLimitBurstFactor),
+		ips:     make(map[string]*rate.Limiter),
+		mu:      &sync.RWMutex{},
+		rate:    rate.Limit(requestsPerMinute / 60.0), // Convert to requests per second
+		burst:   int(requestsPerMinute * constants.DefaultRateLimitBurstFactor),
+		costs:   make(map[string]CostFunc),
+		logger:  zap.NewNop(),
+		metrics: m,
 	}
 }
 
-// getLimiter returns the rate limiter for an IP address
-func (rl *RateLimiter) getLimiter(ip string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// SetStore keeps client budgets in store, so they survive restarts. Clients seen for the
+// first time since the start continue with their saved budget; store failures are logged
+// and the client is limited from memory alone. Budgets are written in the background, so
+// requests never wait on the store. Must be set before the limiter serves requests.
+func (rl *RateLimiter) SetStore(store LimiterStore, logger *zap.Logger) {
+	rl.store = store
+	rl.logger = logger
+	rl.pending = make(map[string]pendingState)
+	rl.wake = make(chan struct{}, 1)
+	rl.written = make(chan struct{})
+	go rl.writeStates()
+}
+
+// Close writes the budgets still pending to the store and stops the writer. The limiter must
+// serve no more requests.
+func (rl *RateLimiter) Close() {
+	if rl.store == nil {
+		return
+	}
+	rl.closeOnce.Do(func() { close(rl.wake) })
+	<-rl.written
+}
+
+// CostFunc returns the number of tokens a request consumes
+type CostFunc func(c *gin.Context) int
+
+// FixedCost charges every request the same number of tokens
+func FixedCost(tokens int) CostFunc {
+	return func(*gin.Context) int {
+		return tokens
+	}
+}
+
+// SetCost sets the cost of requests to the route registered at path. Routes without a cost
+// consume one token. Costs must be set before the limiter serves requests.
+func (rl *RateLimiter) SetCost(path string, cost CostFunc) {
+	rl.costs[path] = cost
+}
 
+// cost returns the tokens the request consumes, at least one
+func (rl *RateLimiter) cost(c *gin.Context) int {
+	cost, ok := rl.costs[c.FullPath()]
+	if !ok {
+		return constants.DefaultRateLimitCost
+	}
+	return max(cost(c), 1)
+}
+
+// getLimiter returns the rate limiter for an IP address, restoring its saved budget when
+// the address is new to this process
+func (rl *RateLimiter) getLimiter(ctx context.Context, ip string) *rate.Limiter {
+	rl.mu.RLock()
 	limiter, exists := rl.ips[ip]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.ips[ip] = limiter
+	rl.mu.RUnlock()
+	if exists {
+		return limiter
+	}
+
+	// The store is consulted without holding the lock, so other clients are not held up
+	limiter = rate.NewLimiter(rl.rate, rl.burst)
+	if state, ok := rl.loadState(ctx, ip); ok {
+		rl.restore(limiter, state, time.Now())
 	}
 
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if existing, exists := rl.ips[ip]; exists {
+		return existing
+	}
+	rl.ips[ip] = limiter
+	rl.observeEntries()
 	return limiter
 }
 
+// observeEntries records the number of tracked clients; callers hold the write lock
+func (rl *RateLimiter) observeEntries() {
+	if rl.metrics != nil {
+		rl.metrics.RateLimitEntries.Set(float64(len(rl.ips)))
+	}
+}
+
+// observeDecision counts a request the limiter allowed or limited
+func (rl *RateLimiter) observeDecision(allowed bool) {
+	if rl.metrics == nil {
+		return
+	}
+	decision := constants.RateLimitDecisionAllowed
+	if !allowed {
+		decision = constants.RateLimitDecisionLimited
+	}
+	rl.metrics.RateLimitDecisions.WithLabelValues(decision, constants.RateLimitKeyTypeIP).Inc()
+}
+
+// loadState returns the saved budget of key, if the store has one
+func (rl *RateLimiter) loadState(ctx context.Context, key string) (LimiterState, bool) {
+	if rl.store == nil {
+		return LimiterState{}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, constants.RateLimitStoreTimeout)
+	defer cancel()
+
+	state, ok, err := rl.store.Load(ctx, key)
+	if err != nil {
+		rl.logger.Warn("Failed to load rate limit state", zap.String("key", key), zap.Error(err))
+		return LimiterState{}, false
+	}
+	return state, ok
+}
+
+// saveState queues the budget of key for the store, to be kept until it is full again. Full
+// budgets are not saved: the previous state expires by the time the budget refills.
+func (rl *RateLimiter) saveState(key string, tokens float64, now time.Time) {
+	if rl.store == nil {
+		return
+	}
+	ttl := time.Duration(rl.secondsFor(float64(rl.burst)-tokens)) * time.Second
+	if ttl <= 0 {
+		return
+	}
+
+	rl.pendingMu.Lock()
+	rl.pending[key] = pendingState{state: LimiterState{Tokens: tokens, At: now}, ttl: ttl}
+	rl.pendingMu.Unlock()
+	select {
+	case rl.wake <- struct{}{}:
+	default:
+		// The writer is already due to run
+	}
+}
+
+// writeStates writes pending budgets to the store until Close, and those left at Close
+func (rl *RateLimiter) writeStates() {
+	defer close(rl.written)
+	for range rl.wake {
+		rl.flushStates()
+	}
+	rl.flushStates()
+}
+
+// flushStates writes the pending budgets to the store. Budgets queued while a write is in
+// flight are written with the next one.
+func (rl *RateLimiter) flushStates() {
+	rl.writeMu.Lock()
+	defer rl.writeMu.Unlock()
+
+	rl.pendingMu.Lock()
+	pending := rl.pending
+	rl.pending = make(map[string]pendingState)
+	rl.pendingMu.Unlock()
+
+	for key, p := range pending {
+		ctx, cancel := context.WithTimeout(context.Background(), constants.RateLimitStoreTimeout)
+		if err := rl.store.Save(ctx, key, p.state, p.ttl); err != nil {
+			rl.logger.Warn("Failed to save rate limit state", zap.String("key", key), zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+// tokensAt returns the budget of a saved state at now, refilled for the time since it was saved
+func (rl *RateLimiter) tokensAt(state LimiterState, now time.Time) float64 {
+	elapsed := max(now.Sub(state.At).Seconds(), 0)
+	return min(state.Tokens+float64(rl.rate)*elapsed, float64(rl.burst))
+}
+
+// restore brings a new limiter down to a saved budget and reports whether the budget is
+// below the limit. Whole tokens are taken slightly in the past, so that the refill since
+// leaves exactly the saved fraction.
+func (rl *RateLimiter) restore(limiter *rate.Limiter, state LimiterState, now time.Time) bool {
+	deficit := float64(rl.burst) - max(rl.tokensAt(state, now), 0)
+	if deficit <= 0 || rl.rate <= 0 {
+		return false
+	}
+	taken := math.Ceil(deficit)
+	early := time.Duration((taken - deficit) / float64(rl.rate) * float64(time.Second))
+	limiter.AllowN(now.Add(-early), int(taken))
+	return true
+}
+
+// Status returns the current budget of key without consuming any of it
+func (rl *RateLimiter) Status(ctx context.Context, key string) (models.RateLimitStatus, error) {
+	now := time.Now()
+	tokens := float64(rl.burst)
+
+	rl.mu.RLock()
+	limiter, tracked := rl.ips[key]
+	rl.mu.RUnlock()
+	if tracked {
+		tokens = limiter.TokensAt(now)
+	} else if rl.store != nil {
+		ctx, cancel := context.WithTimeout(ctx, constants.RateLimitStoreTimeout)
+		defer cancel()
+		state, ok, err := rl.store.Load(ctx, key)
+		if err != nil {
+			return models.RateLimitStatus{}, err
+		}
+		if ok {
+			tracked = true
+			tokens = rl.tokensAt(state, now)
+		}
+	}
+
+	tokens = max(tokens, 0)
+	return models.RateLimitStatus{
+		Key:               key,
+		Tracked:           tracked,
+		Limit:             rl.burst,
+		Remaining:         int(tokens),
+		ResetSeconds:      rl.secondsFor(float64(rl.burst) - tokens),
+		RequestsPerMinute: float64(rl.rate) * 60,
+	}, nil
+}
+
+// SaveSnapshot writes the budgets of the clients below their limit to path, the most
+// depleted first up to constants.RateLimitSnapshotMaxKeys, for RestoreSnapshot on the next start
+func (rl *RateLimiter) SaveSnapshot(path string) error {
+	now := time.Now()
+	states := make(map[string]LimiterState)
+	rl.mu.RLock()
+	for key, limiter := range rl.ips {
+		if tokens := limiter.TokensAt(now); tokens < float64(rl.burst) {
+			states[key] = LimiterState{Tokens: max(tokens, 0), At: now}
+		}
+	}
+	rl.mu.RUnlock()
+
+	if len(states) > constants.RateLimitSnapshotMaxKeys {
+		keys := slices.SortedFunc(maps.Keys(states), func(x, y string) int {
+			return cmp.Or(cmp.Compare(states[x].Tokens, states[y].Tokens), cmp.Compare(x, y))
+		})
+		for _, key := range keys[constants.RateLimitSnapshotMaxKeys:] {
+			delete(states, key)
+		}
+	}
+	return writeSnapshot(path, states)
+}
+
+// RestoreSnapshot restores the budgets saved by SaveSnapshot, refilled for the time since.
+// A missing snapshot restores nothing.
+func (rl *RateLimiter) RestoreSnapshot(path string) error {
+	states, err := readSnapshot(path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for key, state := range states {
+		limiter := rate.NewLimiter(rl.rate, rl.burst)
+		if rl.restore(limiter, state, now) {
+			rl.ips[key] = limiter
+		}
+	}
+	rl.observeEntries()
+	return nil
+}
+
 // RateLimit middleware implements rate limiting
 func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -59,13 +333,30 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 		}
 
 		ip := c.ClientIP()
-		limiter := rl.getLimiter(ip)
+		limiter := rl.getLimiter(c.Request.Context(), ip)
+
+		tokens := rl.cost(c)
+		now := time.Now()
+		allowed := limiter.AllowN(now, tokens)
+		rl.observeDecision(allowed)
+
+		remaining := math.Max(limiter.TokensAt(now), 0)
+		rl.saveState(ip, remaining, now)
+		c.Header(constants.HeaderRateLimit, strconv.Itoa(rl.burst))
+		c.Header(constants.HeaderRateRemaining, strconv.Itoa(int(remaining)))
+		c.Header(constants.HeaderRateReset, strconv.Itoa(rl.secondsFor(float64(rl.burst)-remaining)))
 
-		if !limiter.Allow() {
+		if !allowed {
+			details := "Please try again later"
+			if tokens > rl.burst {
+				details = fmt.Sprintf("Request costs %d tokens, more than the limit of %d", tokens, rl.burst)
+			} else {
+				c.Header(constants.HeaderRetryAfter, strconv.Itoa(rl.secondsFor(float64(tokens)-remaining)))
+			}
 			c.JSON(constants.StatusTooManyRequests, models.ErrorResponse{
 				Code:    constants.StatusTooManyRequests,
 				Message: "Rate limit exceeded",
-				Details: "Please try again later",
+				Details: details,
 			})
 			c.Abort()
 			return
@@ -75,12 +366,26 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 	}
 }
 
+// secondsFor returns the whole seconds needed to refill tokens
+func (rl *RateLimiter) secondsFor(tokens float64) int {
+	if tokens <= 0 || rl.rate <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / float64(rl.rate)))
+}
+
 // cleanup removes old limiters periodically
 func (rl *RateLimiter) cleanup() {
 	ticker := time.NewTicker(constants.DefaultRateLimitCleanupTimeout)
 	for range ticker.C {
-		rl.mu.Lock()
-		rl.ips = make(map[string]*rate.Limiter)
-		rl.mu.Unlock()
+		rl.reset()
 	}
+}
+
+// reset forgets every tracked client
+func (rl *RateLimiter) reset() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.ips = make(map[string]*rate.Limiter)
+	rl.observeEntries()
 } 
\ No newline at end of file
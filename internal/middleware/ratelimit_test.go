@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+)
+
+// newRateLimitedEngine serves a route costing 4 tokens, one costing 10 and a route without a
+// cost, limited to 60 requests per minute (a burst of 6 tokens)
+func newRateLimitedEngine(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	viper.Set("rate_limit.enabled", true)
+	viper.Set("rate_limit.requests_per_minute", 60)
+	t.Cleanup(viper.Reset)
+
+	rl := NewRateLimiter(nil)
+	rl.SetCost("/report", FixedCost(4))
+	rl.SetCost("/crawl", FixedCost(10))
+
+	engine := gin.New()
+	engine.Use(rl.RateLimit())
+	for _, path := range []string{"/report", "/crawl", "/status"} {
+		engine.GET(path, func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	}
+	return engine
+}
+
+func TestRateLimiter_CostExceedingRemainingTokens(t *testing.T) {
+	engine := newRateLimitedEngine(t)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "6", w.Header().Get(constants.HeaderRateLimit))
+	assert.Equal(t, "2", w.Header().Get(constants.HeaderRateRemaining))
+	assert.Equal(t, "4", w.Header().Get(constants.HeaderRateReset))
+
+	// Another report costs 4 tokens, of which 2 are not back yet
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get(constants.HeaderRetryAfter))
+	assert.Equal(t, "2", w.Header().Get(constants.HeaderRateRemaining))
+
+	// Routes without a cost consume one token from the same budget
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(constants.HeaderRateRemaining))
+}
+
+func TestRateLimiter_CostAboveBurst(t *testing.T) {
+	engine := newRateLimitedEngine(t)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/crawl", nil))
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "Request costs 10 tokens, more than the limit of 6")
+	assert.Empty(t, w.Header().Get(constants.HeaderRetryAfter), "waiting does not help")
+	assert.Equal(t, "6", w.Header().Get(constants.HeaderRateRemaining), "a rejected request consumes nothing")
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(constants.HeaderRateRemaining))
+}
+
+func TestRateLimiter_Metrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("rate_limit.enabled", true)
+	viper.Set("rate_limit.requests_per_minute", 60)
+	t.Cleanup(viper.Reset)
+
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	rl := NewRateLimiter(m)
+	engine := gin.New()
+	engine.Use(rl.RateLimit())
+	engine.GET("/status", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, ip := range []string{"192.0.2.1:1234", "192.0.2.2:1234"} {
+		for range 8 {
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			req.RemoteAddr = ip
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	decisions := m.RateLimitDecisions
+	assert.Equal(t, 12.0, testutil.ToFloat64(decisions.WithLabelValues(constants.RateLimitDecisionAllowed, constants.RateLimitKeyTypeIP)), "a burst of 6 per client")
+	assert.Equal(t, 4.0, testutil.ToFloat64(decisions.WithLabelValues(constants.RateLimitDecisionLimited, constants.RateLimitKeyTypeIP)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.RateLimitEntries))
+
+	rl.reset()
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.RateLimitEntries), "cleanup forgets every client")
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LimiterState is the budget of one client at a point in time
+type LimiterState struct {
+	Tokens float64   `json:"tokens"`
+	At     time.Time `json:"at"`
+}
+
+// LimiterStore keeps client budgets outside the process, so a restart does not hand every
+// client a fresh budget
+type LimiterStore interface {
+	// Load returns the saved budget of key; ok is false when none is saved
+	Load(ctx context.Context, key string) (state LimiterState, ok bool, err error)
+	// Save records the budget of key until it is full again, after ttl
+	Save(ctx context.Context, key string, state LimiterState, ttl time.Duration) error
+}
+
+// RedisLimiterStore saves budgets as JSON values under prefix+key, expiring once refilled
+type RedisLimiterStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLimiterStore saves budgets with client under prefix
+func NewRedisLimiterStore(client *redis.Client, prefix string) *RedisLimiterStore {
+	return &RedisLimiterStore{client: client, prefix: prefix}
+}
+
+func (s *RedisLimiterStore) Load(ctx context.Context, key string) (LimiterState, bool, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return LimiterState{}, false, nil
+	}
+	if err != nil {
+		return LimiterState{}, false, fmt.Errorf("failed to load rate limit state: %w", err)
+	}
+
+	var state LimiterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return LimiterState{}, false, fmt.Errorf("failed to decode rate limit state: %w", err)
+	}
+	return state, true, nil
+}
+
+func (s *RedisLimiterStore) Save(ctx context.Context, key string, state LimiterState, ttl time.Duration) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode rate limit state: %w", err)
+	}
+	if err := s.client.Set(ctx, s.prefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save rate limit state: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis client
+func (s *RedisLimiterStore) Close() error {
+	return s.client.Close()
+}
+
+// writeSnapshot writes states to path as JSON, replacing the file only once fully written
+func writeSnapshot(path string, states map[string]LimiterState) error {
+	data, err := json.Marshal(states)
+	if err != nil {
+		return fmt.Errorf("failed to encode rate limit snapshot: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write rate limit snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write rate limit snapshot: %w", err)
+	}
+	return nil
+}
+
+// readSnapshot reads the states written by writeSnapshot; a missing file holds none
+func readSnapshot(path string) (map[string]LimiterState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit snapshot: %w", err)
+	}
+
+	var states map[string]LimiterState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to decode rate limit snapshot: %w", err)
+	}
+	return states, nil
+}
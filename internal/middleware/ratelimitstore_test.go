@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// newRestartableLimiter returns a limiter of 60 requests per minute (a burst of 6 tokens,
+// refilled at one per second) and an engine serving GET /status behind it. Each call stands
+// for one process start.
+func newRestartableLimiter(t *testing.T, store LimiterStore) (*RateLimiter, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("rate_limit.enabled", true)
+	viper.Set("rate_limit.requests_per_minute", 60)
+	t.Cleanup(viper.Reset)
+
+	rl := NewRateLimiter(nil)
+	if store != nil {
+		rl.SetStore(store, zaptest.NewLogger(t))
+		t.Cleanup(rl.Close)
+	}
+	engine := gin.New()
+	engine.Use(rl.RateLimit())
+	engine.GET("/status", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return rl, engine
+}
+
+func getStatus(engine *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	return w
+}
+
+// exhaust spends the whole burst of the test client
+func exhaust(t *testing.T, engine *gin.Engine) {
+	for range 6 {
+		require.Equal(t, http.StatusOK, getStatus(engine).Code)
+	}
+	require.Equal(t, http.StatusTooManyRequests, getStatus(engine).Code)
+}
+
+func TestRateLimiter_RedisStoreSurvivesRestart(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newStore := func() *RedisLimiterStore {
+		store := NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "ratelimit:")
+		t.Cleanup(func() { store.Close() })
+		return store
+	}
+
+	rl, engine := newRestartableLimiter(t, newStore())
+	exhaust(t, engine)
+	rl.flushStates()
+	ttl := mr.TTL("ratelimit:192.0.2.1")
+	assert.Positive(t, ttl)
+	assert.LessOrEqual(t, ttl, 6*time.Second, "the state expires once the budget is full again")
+
+	// After the restart the client continues with its spent budget
+	rl, engine = newRestartableLimiter(t, newStore())
+	w := getStatus(engine)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(constants.HeaderRetryAfter))
+
+	// Other clients start with the full limit
+	status, err := rl.Status(context.Background(), "198.51.100.7")
+	require.NoError(t, err)
+	assert.False(t, status.Tracked)
+	assert.Equal(t, 6, status.Remaining)
+	assert.Zero(t, status.ResetSeconds)
+}
+
+func TestRateLimiter_RedisStoreUnavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "ratelimit:")
+	defer store.Close()
+	mr.Close()
+
+	// Requests are limited from memory alone
+	rl, engine := newRestartableLimiter(t, store)
+	exhaust(t, engine)
+
+	status, err := rl.Status(context.Background(), "192.0.2.1")
+	require.NoError(t, err, "clients in memory are reported without the store")
+	assert.True(t, status.Tracked)
+
+	_, err = rl.Status(context.Background(), "198.51.100.7")
+	assert.Error(t, err)
+}
+
+// blockingStore holds every Save until release is closed
+type blockingStore struct {
+	release chan struct{}
+	mu      sync.Mutex
+	saved   map[string]LimiterState
+}
+
+func (s *blockingStore) Load(ctx context.Context, key string) (LimiterState, bool, error) {
+	return LimiterState{}, false, nil
+}
+
+func (s *blockingStore) Save(ctx context.Context, key string, state LimiterState, ttl time.Duration) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saved[key] = state
+	return nil
+}
+
+func TestRateLimiter_SlowStoreDoesNotDelayRequests(t *testing.T) {
+	store := &blockingStore{release: make(chan struct{}), saved: make(map[string]LimiterState)}
+	rl, engine := newRestartableLimiter(t, store)
+
+	start := time.Now()
+	exhaust(t, engine)
+	assert.Less(t, time.Since(start), constants.RateLimitStoreTimeout, "requests never wait on the store")
+
+	// Close writes the latest budget of the client
+	close(store.release)
+	rl.Close()
+	require.Contains(t, store.saved, "192.0.2.1")
+	assert.Less(t, store.saved["192.0.2.1"].Tokens, 1.0)
+}
+
+func TestRateLimiter_SnapshotSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	rl, engine := newRestartableLimiter(t, nil)
+	exhaust(t, engine)
+	require.NoError(t, rl.SaveSnapshot(path))
+
+	rl, engine = newRestartableLimiter(t, nil)
+	require.NoError(t, rl.RestoreSnapshot(path))
+	assert.Equal(t, http.StatusTooManyRequests, getStatus(engine).Code)
+
+	status, err := rl.Status(context.Background(), "192.0.2.1")
+	require.NoError(t, err)
+	assert.True(t, status.Tracked)
+	assert.Equal(t, 0, status.Remaining)
+	assert.Equal(t, 6, status.Limit)
+	assert.Equal(t, 60.0, status.RequestsPerMinute)
+}
+
+func TestRateLimiter_SnapshotRefillsForElapsedTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	now := time.Now()
+	require.NoError(t, writeSnapshot(path, map[string]LimiterState{
+		"192.0.2.1":    {Tokens: 0.5, At: now.Add(-3 * time.Second)},
+		"198.51.100.7": {Tokens: 0, At: now.Add(-time.Hour)},
+	}))
+
+	rl, _ := newRestartableLimiter(t, nil)
+	require.NoError(t, rl.RestoreSnapshot(path))
+
+	status, err := rl.Status(context.Background(), "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, status.Remaining, "3.5 tokens after 3 seconds")
+	assert.Equal(t, 3, status.ResetSeconds)
+
+	status, err = rl.Status(context.Background(), "198.51.100.7")
+	require.NoError(t, err)
+	assert.False(t, status.Tracked, "budgets refilled since the snapshot are not restored")
+	assert.Equal(t, 6, status.Remaining)
+}
+
+func TestRateLimiter_SnapshotSkipsFullBudgets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	rl, engine := newRestartableLimiter(t, nil)
+	require.NoError(t, rl.SaveSnapshot(path))
+	states, err := readSnapshot(path)
+	require.NoError(t, err)
+	assert.Empty(t, states)
+
+	require.Equal(t, http.StatusOK, getStatus(engine).Code)
+	require.NoError(t, rl.SaveSnapshot(path))
+	states, err = readSnapshot(path)
+	require.NoError(t, err)
+	assert.Len(t, states, 1)
+}
+
+func TestRateLimiter_MissingSnapshot(t *testing.T) {
+	rl, engine := newRestartableLimiter(t, nil)
+	require.NoError(t, rl.RestoreSnapshot(filepath.Join(t.TempDir(), "missing.json")))
+	assert.Equal(t, http.StatusOK, getStatus(engine).Code)
+}
+
+func TestRateLimiter_StatusConsumesNoBudget(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]func() LimiterStore{
+		"Memory": func() LimiterStore { return nil },
+		"Redis": func() LimiterStore {
+			store := NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "ratelimit:")
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			mr.FlushAll()
+			rl, engine := newRestartableLimiter(t, newStore())
+			for range 2 {
+				require.Equal(t, http.StatusOK, getStatus(engine).Code)
+			}
+
+			for range 20 {
+				status, err := rl.Status(context.Background(), "192.0.2.1")
+				require.NoError(t, err)
+				assert.True(t, status.Tracked)
+				assert.Equal(t, 4, status.Remaining)
+			}
+			// The budget left is the one reported: four more requests pass
+			for range 4 {
+				require.Equal(t, http.StatusOK, getStatus(engine).Code)
+			}
+			assert.Equal(t, http.StatusTooManyRequests, getStatus(engine).Code)
+		})
+	}
+}
+
+func TestRateLimiter_StatusOfSavedBudgetConsumesNone(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newStore := func() *RedisLimiterStore {
+		store := NewRedisLimiterStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "ratelimit:")
+		t.Cleanup(func() { store.Close() })
+		return store
+	}
+	first, engine := newRestartableLimiter(t, newStore())
+	require.Equal(t, http.StatusOK, getStatus(engine).Code)
+	first.flushStates()
+	saved, err := mr.Get("ratelimit:192.0.2.1")
+	require.NoError(t, err)
+
+	// A fresh process reads the saved budget without restoring or spending it
+	rl, _ := newRestartableLimiter(t, newStore())
+	status, err := rl.Status(context.Background(), "192.0.2.1")
+	require.NoError(t, err)
+	assert.True(t, status.Tracked)
+	assert.Equal(t, 5, status.Remaining)
+	stillSaved, err := mr.Get("ratelimit:192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, saved, stillSaved)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// Recovery recovers from handler panics, logging them and responding with a JSON ErrorResponse.
+// The panic message is only exposed to clients when exposeDetails is set (debug mode).
+func Recovery(logger *zap.Logger, metrics *metrics.Metrics, exposeDetails bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			requestID := GetRequestID(c)
+			logger.Error("Recovered from panic",
+				zap.String("request_id", requestID),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("method", c.Request.Method),
+				zap.Any("panic", recovered),
+				zap.ByteString("stack", debug.Stack()),
+			)
+
+			if metrics != nil {
+				metrics.Panics.Inc()
+			}
+
+			// Nothing sensible can be sent once the response has started
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			response := models.ErrorResponse{
+				Code:      constants.StatusInternalServerError,
+				ErrorCode: constants.ErrorCodeInternal,
+				Message:   constants.ErrInternalServer,
+				RequestID: requestID,
+			}
+			if exposeDetails {
+				response.Details = fmt.Sprint(recovered)
+			}
+
+			c.AbortWithStatusJSON(constants.StatusInternalServerError, response)
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		exposeDetails   bool
+		expectedDetails string
+	}{
+		{name: "Release mode hides panic message", exposeDetails: false, expectedDetails: ""},
+		{name: "Debug mode includes panic message", exposeDetails: true, expectedDetails: "boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.ErrorLevel)
+			m := metrics.NewWithRegistry(prometheus.NewRegistry())
+
+			engine := gin.New()
+			engine.Use(RequestID(), Recovery(zap.New(core), m, tt.exposeDetails))
+			engine.GET("/panic", func(c *gin.Context) {
+				panic("boom")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+			req.Header.Set(constants.HeaderRequestID, "req-panic")
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+			var resp models.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, constants.StatusInternalServerError, resp.Code)
+			assert.Equal(t, constants.ErrorCodeInternal, resp.ErrorCode)
+			assert.Equal(t, "req-panic", resp.RequestID)
+			assert.Equal(t, tt.expectedDetails, resp.Details)
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.Panics))
+			require.Equal(t, 1, logs.Len())
+			assert.Contains(t, logs.All()[0].ContextMap(), "stack")
+		})
+	}
+}
+
+func TestRecovery_NoPanicPassesThrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+
+	engine := gin.New()
+	engine.Use(Recovery(zap.NewNop(), m, true))
+	engine.GET("/ok", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.Panics))
+}
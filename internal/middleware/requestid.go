@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+type requestIDContextKey struct{}
+
+// RequestID assigns every request an ID, reusing a well-formed incoming X-Request-ID header
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(constants.HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(constants.ContextKeyRequestID, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDContextKey{}, id))
+		c.Header(constants.HeaderRequestID, id)
+
+		c.Next()
+	}
+}
+
+// GetRequestID returns the request ID assigned by the RequestID middleware
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(constants.ContextKeyRequestID)
+}
+
+// RequestIDFromContext returns the request ID stored in a request context, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// newRequestID generates a random hex-encoded request ID
+func newRequestID() string {
+	b := make([]byte, constants.RequestIDByteLength)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to reuse
+func validRequestID(id string) bool {
+	if id == "" || len(id) > constants.MaxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+)
+
+func TestTraceContext_RequestDurationExemplar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	reg := prometheus.NewRegistry()
+	m := metrics.NewWithRegistry(reg)
+
+	var seen trace.SpanContext
+	engine := gin.New()
+	engine.Use(TraceContext(), NewAccessLogger(config.AccessLogConfig{}, zap.NewNop(), m).Handler())
+	engine.GET("/api/v1/analyses", func(c *gin.Context) {
+		seen = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analyses", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", seen.TraceID().String())
+	assert.True(t, seen.IsRemote())
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	var exemplarTraceIDs []string
+	for _, family := range families {
+		if family.GetName() != constants.MetricRequestDurationName {
+			continue
+		}
+		for _, bucket := range family.GetMetric()[0].GetHistogram().GetBucket() {
+			for _, label := range bucket.GetExemplar().GetLabel() {
+				exemplarTraceIDs = append(exemplarTraceIDs, label.GetName()+"="+label.GetValue())
+			}
+		}
+	}
+	assert.Equal(t, []string{constants.ExemplarTraceIDLabel + "=4bf92f3577b34da6a3ce929d0e0e4736"}, exemplarTraceIDs)
+}
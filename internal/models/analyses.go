@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AnalysisRecord is a stored analysis: key metrics as columns plus the full result
+type AnalysisRecord struct {
+	ID                int64           `json:"id"`
+	URL               string          `json:"url"`
+	AnalyzedAt        time.Time       `json:"analyzed_at"`
+	CreatedAt         time.Time       `json:"created_at"`
+	HTMLVersion       string          `json:"html_version"`
+	Title             string          `json:"title"`
+	InternalLinks     int             `json:"internal_links"`
+	ExternalLinks     int             `json:"external_links"`
+	InaccessibleLinks int             `json:"inaccessible_links"`
+	HasLoginForm      bool            `json:"has_login_form"`
+	SEOScore          int             `json:"seo_score"`
+	Result            json.RawMessage `json:"result"`
+}
+
+// AnalysesPage is one page of stored analyses, newest first, with the total number of matches
+type AnalysesPage struct {
+	Records []AnalysisRecord `json:"records"`
+	Total   int              `json:"total"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
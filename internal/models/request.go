@@ -2,37 +2,328 @@ package models
 
 import (
 	"fmt"
+	"net/http"
+	"net/netip"
 	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/http/httpguts"
 
 	"github.com/webpage-analyser-server/internal/constants"
 )
 
 // AnalyzeRequest represents the request payload for webpage analysis
 type AnalyzeRequest struct {
-	URL string `json:"url" validate:"required,url"`
+	URL     string         `json:"url" validate:"required"`
+	Options AnalyzeOptions `json:"options"`
+}
+
+// ValidateRequest represents the request payload for URL validation
+type ValidateRequest struct {
+	URL string `json:"url" validate:"required"`
+}
+
+// AnalyzeOptions holds per-request analysis options
+type AnalyzeOptions struct {
+	// SkipLinkCheck counts and classifies links without checking their accessibility
+	SkipLinkCheck bool `json:"skip_link_check" form:"skip_link_check"`
+	// ForceRefresh analyzes the page again instead of serving the cached result, unless the
+	// target confirms with 304 Not Modified that the page is unchanged
+	ForceRefresh bool `json:"force_refresh" form:"force_refresh"`
+	// RenderJS renders the page in a headless browser before analysis, when the server supports it
+	RenderJS bool `json:"render_js" form:"-"`
+	// OriginChecks probes the http:// and www/apex variants of the target origin for redirects
+	OriginChecks bool `json:"origin_checks" form:"-"`
+	// AllowPartial returns the sections completed before the analysis deadline instead of a timeout error
+	AllowPartial bool `json:"allow_partial" form:"allow_partial"`
+	// AllowEmpty analyzes empty or near-empty pages instead of failing with EMPTY_DOCUMENT
+	AllowEmpty bool `json:"allow_empty" form:"-"`
+	// CompareMobile fetches the page again with a mobile user agent and compares the lightweight sections
+	CompareMobile bool `json:"compare_mobile" form:"-"`
+	// FetchManifest fetches the page's web app manifest to report its name, display mode and icons under pwa
+	FetchManifest bool `json:"fetch_manifest" form:"-"`
+	// CompareCanonical fetches the canonical URL, when it is another page, and compares its
+	// visible text with the page's under canonical_similarity
+	CompareCanonical bool `json:"compare_canonical" form:"-"`
+	// IncludeHeadingText adds the text of each heading, by level, under heading_text
+	IncludeHeadingText bool `json:"include_heading_text" form:"include_heading_text"`
+	// IncludeLinkDetails adds the outcome of each checked link under links.details
+	IncludeLinkDetails bool `json:"include_link_details" form:"include_link_details"`
+	// IncludeRawLinks adds the absolute URL of every link, once each in document order, under
+	// links.raw, a page of RawLinksLimit links from RawLinksOffset on. Further pages are
+	// served from the cached analysis.
+	IncludeRawLinks bool `json:"include_raw_links" form:"include_raw_links"`
+	RawLinksOffset  int  `json:"raw_links_offset,omitempty" form:"raw_links_offset"`
+	RawLinksLimit   int  `json:"raw_links_limit,omitempty" form:"raw_links_limit"` // At most constants.MaxRawLinksLimit, the default
+	// IncludeStats adds the resources the analysis used, under stats
+	IncludeStats bool `json:"include_stats" form:"include_stats"`
+	// AcceptLanguage is sent as the Accept-Language header of the page fetch and same-host link checks
+	AcceptLanguage string `json:"accept_language,omitempty" form:"accept_language"`
+	// Auth holds credentials sent with the page fetch and same-host link checks only
+	Auth *AnalyzeAuth `json:"auth,omitempty" form:"-"`
+	// SamplingSeed draws the same sample of links on every analysis when analyzer.link_sampling
+	// is enabled; without it each analysis draws a new sample
+	SamplingSeed *uint64 `json:"sampling_seed,omitempty" form:"sampling_seed"`
+	// Resolve connects the page fetch and same-host link checks to a fixed IP address instead
+	// of the one DNS returns, like curl --resolve
+	Resolve *ResolveOverride `json:"resolve,omitempty" form:"-"`
+	// Egress names the proxy pool of analyzer.proxies the page fetch and link checks go through
+	Egress string `json:"egress,omitempty" form:"egress"`
+	// ScopeSelector is a CSS selector, such as main or #content, whose first match links,
+	// headings and images are taken from; the title, meta tags and DOCTYPE stay document-wide
+	ScopeSelector string `json:"scope_selector,omitempty" form:"scope_selector"`
+	// StoreSnapshot keeps the fetched HTML for GET /api/v1/snapshot when snapshots are enabled;
+	// pages fetched with credentials are never stored
+	StoreSnapshot bool `json:"store_snapshot" form:"-"`
+}
+
+// AnalyzeAuth holds credentials for pages behind basic auth, session cookies or tokens
+type AnalyzeAuth struct {
+	Basic   *BasicAuth        `json:"basic,omitempty"`
+	Cookies []AuthCookie      `json:"cookies,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// BasicAuth holds HTTP basic auth credentials
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AuthCookie is a cookie sent with authenticated requests
+type AuthCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ResolveOverride pins the analyzed host and port to an IP address for one analysis
+type ResolveOverride struct {
+	Host string `json:"host"`
+	IP   string `json:"ip"`
+	Port int    `json:"port"`
+}
+
+// AnalyzeHTMLRequest represents the request payload for analyzing submitted HTML
+type AnalyzeHTMLRequest struct {
+	HTML    string         `json:"html" validate:"required"`
+	BaseURL string         `json:"base_url" validate:"required"`
+	Options AnalyzeOptions `json:"options"`
+}
+
+// URLError rejects a target URL with a specific error code
+type URLError struct {
+	Code    string
+	Message string
+}
+
+func (e *URLError) Error() string {
+	return e.Message
+}
+
+// unsupportedSchemes explains why well-known schemes other than http and https cannot be analyzed
+var unsupportedSchemes = map[string]URLError{
+	"data": {Code: constants.ErrorCodeDataURL, Message: "data: URLs carry their content inline; submit the HTML to POST /api/v1/analyze/html instead"},
+	"file": {Code: constants.ErrorCodeFileURL, Message: "file: URLs point at files on your machine, which the server cannot read; upload the file to POST /api/v1/analyze/html instead"},
+	"ftp":  {Code: constants.ErrorCodeFTPURL, Message: "FTP is not supported; analyze the page over http or https"},
+	"ftps": {Code: constants.ErrorCodeFTPURL, Message: "FTP is not supported; analyze the page over http or https"},
+	"ws":   {Code: constants.ErrorCodeWebSocketURL, Message: "WebSocket URLs do not serve pages; analyze the http or https URL of the page that opens the socket"},
+	"wss":  {Code: constants.ErrorCodeWebSocketURL, Message: "WebSocket URLs do not serve pages; analyze the http or https URL of the page that opens the socket"},
+}
+
+// CheckScheme returns a URLError for schemes other than http and https
+func CheckScheme(scheme string) *URLError {
+	scheme = strings.ToLower(scheme)
+	if scheme == "http" || scheme == "https" {
+		return nil
+	}
+	if err, ok := unsupportedSchemes[scheme]; ok {
+		return &err
+	}
+	return &URLError{Code: constants.ErrorCodeUnsupportedScheme, Message: fmt.Sprintf("URL scheme %s is not supported; URL scheme must be http or https", scheme)}
+}
+
+// StripCredentials removes the user:password@ part of a URL, leaving the rest exactly as written
+func StripCredentials(rawURL string) string {
+	schemeEnd := strings.Index(rawURL, "://")
+	if schemeEnd < 0 {
+		return rawURL
+	}
+	authorityStart := schemeEnd + len("://")
+	authorityEnd := len(rawURL)
+	if i := strings.IndexAny(rawURL[authorityStart:], "/?#"); i >= 0 {
+		authorityEnd = authorityStart + i
+	}
+	// The host follows the last @, as in url.Parse
+	if at := strings.LastIndex(rawURL[authorityStart:authorityEnd], "@"); at >= 0 {
+		return rawURL[:authorityStart] + rawURL[authorityStart+at+1:]
+	}
+	return rawURL
+}
+
+// Validate performs custom validation on the request
+func (r *AnalyzeHTMLRequest) Validate() error {
+	if err := validateTargetURL(r.BaseURL); err != nil {
+		return err
+	}
+	return r.Options.Validate()
 }
 
 // Validate performs custom validation on the request
 func (r *AnalyzeRequest) Validate() error {
-	if len(r.URL) > constants.MaxURLLength {
+	if err := validateTargetURL(r.URL); err != nil {
+		return err
+	}
+	return r.Options.Validate()
+}
+
+// Validate checks the options that carry free-form values
+func (o *AnalyzeOptions) Validate() error {
+	if err := validateAcceptLanguage(o.AcceptLanguage); err != nil {
+		return err
+	}
+	if o.RawLinksOffset < 0 || o.RawLinksLimit < 0 {
+		return fmt.Errorf("raw_links_offset and raw_links_limit must not be negative")
+	}
+	if err := o.Auth.Validate(); err != nil {
+		return err
+	}
+	if err := o.Resolve.Validate(); err != nil {
+		return err
+	}
+	// A proxy resolves the hosts it connects to itself, so no address can be pinned through it
+	if o.Resolve != nil && o.Egress != "" {
+		return fmt.Errorf("resolve cannot be combined with egress")
+	}
+	return validateScopeSelector(o.ScopeSelector)
+}
+
+// validateScopeSelector accepts an empty value or a CSS selector the analyzer can match
+func validateScopeSelector(selector string) error {
+	if selector == "" {
+		return nil
+	}
+	if len(selector) > constants.MaxScopeSelectorLength {
+		return fmt.Errorf("scope_selector exceeds maximum length of %d characters", constants.MaxScopeSelectorLength)
+	}
+	if _, err := cascadia.Compile(selector); err != nil {
+		return fmt.Errorf("scope_selector: %q is not a valid CSS selector: %v", selector, err)
+	}
+	return nil
+}
+
+// languageRangePattern matches one element of an Accept-Language value (RFC 9110 section 12.5.4):
+// a language range such as en, en-US or *, optionally weighted with a q value
+var languageRangePattern = regexp.MustCompile(`^(\*|[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*)(\s*;\s*[qQ]\s*=\s*(0(\.[0-9]{0,3})?|1(\.0{0,3})?))?$`)
+
+// validateAcceptLanguage accepts an empty value or a comma-separated list of well-formed language ranges
+func validateAcceptLanguage(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > constants.MaxAcceptLanguageLength {
+		return fmt.Errorf("accept_language exceeds maximum length of %d characters", constants.MaxAcceptLanguageLength)
+	}
+	for _, element := range strings.Split(value, ",") {
+		if !languageRangePattern.MatchString(strings.TrimSpace(element)) {
+			return fmt.Errorf("accept_language: %q is not a valid language range", strings.TrimSpace(element))
+		}
+	}
+	return nil
+}
+
+// reservedAuthHeaders are managed by the analyzer or the HTTP transport and cannot be overridden
+var reservedAuthHeaders = map[string]bool{
+	"Host":              true,
+	"Accept-Encoding":   true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+}
+
+// Validate checks that the credentials can be sent as HTTP headers. Error messages never
+// include credential values.
+func (a *AnalyzeAuth) Validate() error {
+	if a == nil {
+		return nil
+	}
+	if a.Basic != nil && a.Basic.Username == "" {
+		return fmt.Errorf("auth.basic.username is required")
+	}
+	if a.Basic != nil && strings.Contains(a.Basic.Username, ":") {
+		return fmt.Errorf("auth.basic.username must not contain a colon")
+	}
+	for i, cookie := range a.Cookies {
+		if !httpguts.ValidHeaderFieldName(cookie.Name) {
+			return fmt.Errorf("auth.cookies[%d].name is not a valid cookie name", i)
+		}
+		if !httpguts.ValidHeaderFieldValue(cookie.Value) || strings.ContainsAny(cookie.Value, ";,\"\\ ") {
+			return fmt.Errorf("auth.cookies[%d].value is not a valid cookie value", i)
+		}
+	}
+	for name, value := range a.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("auth.headers: %q is not a valid header name", name)
+		}
+		if reservedAuthHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("auth.headers: %s cannot be set", http.CanonicalHeaderKey(name))
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("auth.headers: the value of %s is not a valid header value", http.CanonicalHeaderKey(name))
+		}
+	}
+	return nil
+}
+
+// Validate checks that the override names a hostname, an IP address and a port. Whether the
+// analysis may use it depends on the target and the target policy, checked by the analyzer.
+func (r *ResolveOverride) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.Host == "" {
+		return fmt.Errorf("resolve.host is required")
+	}
+	if strings.ContainsAny(r.Host, ":/@?#[] ") {
+		return fmt.Errorf("resolve.host must be a hostname without scheme, port or path")
+	}
+	if _, err := netip.ParseAddr(r.Host); err == nil {
+		return fmt.Errorf("resolve.host must be a hostname, not an IP address")
+	}
+	ip, err := netip.ParseAddr(r.IP)
+	if err != nil || ip.Zone() != "" {
+		return fmt.Errorf("resolve.ip is not a valid IP address")
+	}
+	if r.Port < 1 || r.Port > 65535 {
+		return fmt.Errorf("resolve.port must be between 1 and 65535")
+	}
+	return nil
+}
+
+// validateTargetURL checks that a URL is suitable for analysis
+func validateTargetURL(rawURL string) error {
+	if len(rawURL) > constants.MaxURLLength {
 		return fmt.Errorf("URL length exceeds maximum allowed length of %d characters", constants.MaxURLLength)
 	}
 
 	// Parse and validate URL using net/url package
-	parsedURL, err := url.Parse(r.URL)
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
 		return fmt.Errorf("invalid URL format: %w", err)
 	}
 
+	// Reject other schemes first: data: and file: URLs have no host
+	if parsedURL.Scheme != "" {
+		if err := CheckScheme(parsedURL.Scheme); err != nil {
+			return err
+		}
+	}
+
 	// Check for required URL components
 	if parsedURL.Scheme == "" || parsedURL.Host == "" {
 		return fmt.Errorf("URL must have both scheme (http/https) and host")
 	}
 
-	// Only allow HTTP and HTTPS schemes
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return fmt.Errorf("URL scheme must be http or https")
-	}
-
 	return nil
-} 
\ No newline at end of file
+}
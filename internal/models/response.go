@@ -1,16 +1,139 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
 
 // AnalyzeResponse represents the response payload for webpage analysis
 type AnalyzeResponse struct {
+	AnalysisID  string            `json:"analysis_id,omitempty"` // Tags the log lines of the analysis; the request ID when there is one
 	URL         string            `json:"url"`
+	ContentKind string            `json:"content_kind,omitempty"` // html, or sitemap and feed for XML documents, whose responses leave the HTML sections out
+	Sitemap     *Sitemap          `json:"sitemap,omitempty"`      // Set when ContentKind is sitemap
+	Feed        *Feed             `json:"feed,omitempty"`         // Set when ContentKind is feed
 	HTMLVersion string            `json:"html_version"`
+	RawDoctype  string            `json:"raw_doctype"` // The DOCTYPE declaration as written, empty when absent
 	Title       string            `json:"title"`
+	MetaDescription string        `json:"meta_description,omitempty"` // Content of the first meta description
 	Headings    map[string]int    `json:"headings"`
+	HeadingText map[string][]string `json:"heading_text,omitempty"` // Set when options.include_heading_text is requested
 	Links       LinkAnalysis      `json:"links"`
 	HasLoginForm bool             `json:"has_login_form"`
+	Forms       Forms             `json:"forms"`
+	Security    SecuritySummary   `json:"security"`
+	RenderedWithJS bool           `json:"rendered_with_js"`
+	BotProtectionDetected bool    `json:"bot_protection_detected"`
+	BotProtectionProvider string  `json:"bot_protection_provider,omitempty"`
+	Soft404Suspected bool         `json:"soft_404_suspected,omitempty"` // A "not found" page served with status 200
+	Soft404Reason string          `json:"soft_404_reason,omitempty"`    // error_phrase or error_title
+	PaywallSuspected bool         `json:"paywall_suspected"`
+	PaywallEvidence []PaywallEvidence `json:"paywall_evidence,omitempty"` // Paywall signals found, whether or not a paywall is suspected
+	PolicyWarnings []PolicyWarning `json:"policy_warnings,omitempty"`  // Requests the target policy would block, in dry-run mode
+	Warnings       []Warning       `json:"warnings,omitempty"`         // Non-fatal problems met during the analysis
+	ConsentBanner ConsentBanner   `json:"consent_banner"`
+	Interstitial Interstitial     `json:"interstitial"`
+	Cookies     []CookieAudit     `json:"cookies"`
+	Fetch       *FetchInfo        `json:"fetch,omitempty"`
+	Stats       *AnalysisStats    `json:"stats,omitempty"` // Set when options.include_stats is requested
+	OriginChecks *OriginChecks    `json:"origin_checks,omitempty"`
+	MobileComparison *MobileComparison `json:"mobile_comparison,omitempty"`
+	DocumentIssues DocumentIssues `json:"document_issues"`
+	Accessibility AccessibilityReport `json:"accessibility"`
+	CSPReadiness CSPReadiness `json:"csp_readiness"`
+	Resources   Resources         `json:"resources"`
+	ResourceHints ResourceHints   `json:"resource_hints"`
+	LegacyIE    LegacyIE          `json:"legacy_ie"`
+	SEO         SEOReport         `json:"seo"`
+	CanonicalConsistency CanonicalConsistency `json:"canonical_consistency"`
+	CanonicalSimilarity *CanonicalSimilarity `json:"canonical_similarity,omitempty"` // Set with options.compare_canonical
+	AMP         *AMP              `json:"amp,omitempty"` // Set for AMP documents and pages declaring an AMP variant
+	PWA         *PWA              `json:"pwa,omitempty"`
 	AnalyzedAt  time.Time         `json:"analyzed_at"`
+	DurationMs  int64             `json:"duration_ms"` // Time from the start of the fetch to the finished result
+	Phases      PhaseDurations    `json:"phases"`
+	ServedFromCacheInMs *int64    `json:"served_from_cache_in_ms,omitempty"` // Set on cached responses, which keep their original durations
+	Revalidated bool              `json:"revalidated,omitempty"` // The target answered a forced refresh with 304 Not Modified; the cached analysis is returned
+	TruncatedAnalysis bool        `json:"truncated_analysis,omitempty"`
+	LimitedSections []string      `json:"limited_sections,omitempty"` // Sections analyzed over part of an oversized document
+	Partial     bool              `json:"partial,omitempty"`
+	CompletedSections []string    `json:"completed_sections,omitempty"` // Set on partial results
+
+	// CacheTTL is the remaining lifetime of the cached entry backing this result.
+	// It is not serialized and is only used to derive HTTP caching headers.
+	CacheTTL time.Duration `json:"-"`
+}
+
+// xmlResponse is the JSON form of the analysis of an XML document: the fields of
+// AnalyzeResponse that do not describe an HTML page
+type xmlResponse struct {
+	URL                 string          `json:"url"`
+	ContentKind         string          `json:"content_kind"`
+	Sitemap             *Sitemap        `json:"sitemap,omitempty"`
+	Feed                *Feed           `json:"feed,omitempty"`
+	PolicyWarnings      []PolicyWarning `json:"policy_warnings,omitempty"`
+	Warnings            []Warning       `json:"warnings,omitempty"`
+	Cookies             []CookieAudit   `json:"cookies"`
+	Fetch               *FetchInfo      `json:"fetch,omitempty"`
+	Stats               *AnalysisStats  `json:"stats,omitempty"`
+	AnalyzedAt          time.Time       `json:"analyzed_at"`
+	DurationMs          int64           `json:"duration_ms"`
+	Phases              PhaseDurations  `json:"phases"`
+	ServedFromCacheInMs *int64          `json:"served_from_cache_in_ms,omitempty"`
+	Revalidated         bool            `json:"revalidated,omitempty"`
+}
+
+// MarshalJSON leaves the HTML sections out of analyses of sitemaps and feeds
+func (r AnalyzeResponse) MarshalJSON() ([]byte, error) {
+	if r.ContentKind == "" || r.ContentKind == constants.ContentKindHTML {
+		type plain AnalyzeResponse
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(xmlResponse{
+		URL:                 r.URL,
+		ContentKind:         r.ContentKind,
+		Sitemap:             r.Sitemap,
+		Feed:                r.Feed,
+		PolicyWarnings:      r.PolicyWarnings,
+		Warnings:            r.Warnings,
+		Cookies:             r.Cookies,
+		Fetch:               r.Fetch,
+		Stats:               r.Stats,
+		AnalyzedAt:          r.AnalyzedAt,
+		DurationMs:          r.DurationMs,
+		Phases:              r.Phases,
+		ServedFromCacheInMs: r.ServedFromCacheInMs,
+		Revalidated:         r.Revalidated,
+	})
+}
+
+// Sitemap summarizes an XML sitemap or sitemap index
+type Sitemap struct {
+	Index           bool       `json:"index"`                       // A sitemapindex listing sitemaps rather than a urlset listing pages
+	URLCount        int        `json:"url_count"`                   // <url> entries, or <sitemap> entries of an index
+	LastmodEarliest *time.Time `json:"lastmod_earliest,omitempty"`
+	LastmodLatest   *time.Time `json:"lastmod_latest,omitempty"`
+	InvalidLastmod  int        `json:"invalid_lastmod,omitempty"` // Entries whose lastmod is not a W3C datetime
+	Truncated       bool       `json:"truncated,omitempty"`       // More entries than are counted; the counts cover the first ones
+}
+
+// Feed summarizes an RSS or Atom feed
+type Feed struct {
+	Format       string     `json:"format"` // rss or atom
+	Title        string     `json:"title"`
+	ItemCount    int        `json:"item_count"` // <item> entries of RSS, <entry> entries of Atom
+	LatestItemAt *time.Time `json:"latest_item_at,omitempty"` // Most recent publication or update date of the items
+	Truncated    bool       `json:"truncated,omitempty"`      // More items than are counted; the counts cover the first ones
+}
+
+// PhaseDurations breaks the analysis duration down like the phase metrics. Phases
+// that did not run, such as the fetch of submitted HTML, report zero.
+type PhaseDurations struct {
+	FetchMs     int64 `json:"fetch_ms"`      // Fetching or rendering the page
+	ParseMs     int64 `json:"parse_ms"`      // Parsing the HTML document
+	LinkCheckMs int64 `json:"link_check_ms"` // Classifying and checking links
 }
 
 // LinkAnalysis represents the analysis of links in the webpage
@@ -18,11 +141,566 @@ type LinkAnalysis struct {
 	Internal     int `json:"internal"`
 	External     int `json:"external"`
 	Inaccessible int `json:"inaccessible"`
+	InaccessibleInternal int `json:"inaccessible_internal"`
+	RateLimited  int `json:"rate_limited"` // Checked links the target rate limited; not counted as inaccessible
+	Skipped      map[string]int `json:"skipped,omitempty"` // Links not checked, by reason
+	ExternalDomains map[string]int `json:"external_domains,omitempty"` // External links per domain, top domains only
+	Nofollow     int `json:"nofollow"`  // External links with rel="nofollow"
+	Sponsored    int `json:"sponsored"` // External links with rel="sponsored"
+	UGC          int `json:"ugc"`       // External links with rel="ugc"
+	UnsafeTargetBlank int `json:"unsafe_target_blank"` // target="_blank" links without rel="noopener" or "noreferrer"
+	DataURIs     int `json:"data_uris"` // Anchors with data: URIs, counted neither internal nor external
+	InternalDetail InternalLinkDetail `json:"internal_detail"`
+	Suspicious   SuspiciousLinks `json:"suspicious"` // Phishing signals among the links
+	AnchorTextStats AnchorTextStats `json:"anchor_text_stats"` // How descriptive the link texts are
+	Pagination   Pagination     `json:"pagination"`        // How a listing page exposes its further pages
+	Redirects    []LinkRedirect `json:"redirects,omitempty"` // Checked links that redirected, by URL
+	Raw          *RawLinks      `json:"raw,omitempty"`       // Set when options.include_raw_links is requested
+	OffOriginRedirects *OffOriginRedirects `json:"off_origin_redirects,omitempty"` // Set with options.include_link_details when any internal link redirected off the site
+	Failures     *LinkFailures  `json:"failures,omitempty"`  // Why the inaccessible links failed; set when any did
+	Details      *LinkDetails   `json:"details,omitempty"`   // Set when options.include_link_details is requested
+	Sampled      bool           `json:"sampled,omitempty"`   // Only a random sample of the links was checked; see sampling
+	Sampling     *LinkSampling  `json:"sampling,omitempty"`
+}
+
+// LinkSampling describes the random sample of links checked when analyzer.link_sampling is
+// enabled and a page has more unique links than analyzer.max_links. The counts of
+// inaccessible links cover the sample; EstimatedInaccessible scales them up to every link.
+type LinkSampling struct {
+	Population            int     `json:"population"`             // Unique links that could have been checked
+	SampleSize            int     `json:"sample_size"`            // Split between internal and external links like the population
+	SampledInternal       int     `json:"sampled_internal"`
+	SampledExternal       int     `json:"sampled_external"`
+	Rate                  float64 `json:"rate"`                   // sample_size / population
+	Seed                  uint64  `json:"seed"`                   // Pass as options.sampling_seed to draw the same sample again
+	EstimatedInaccessible int     `json:"estimated_inaccessible"` // Inaccessible links expected among the population
+	MarginOfError         int     `json:"margin_of_error"`        // Of the estimate, at 95% confidence
+	Confidence            string  `json:"confidence"`             // How far the estimate can be trusted
+}
+
+// Resources describes content the page carries inline instead of referencing it
+type Resources struct {
+	DataURIs DataURIs `json:"data_uris"`
+}
+
+// ResourceHints audits the preload, prefetch, preconnect and dns-prefetch links of the page
+type ResourceHints struct {
+	Count             int                     `json:"count"`
+	ByType            map[string]int          `json:"by_type,omitempty"`             // preload, prefetch, preconnect or dns-prefetch
+	PreloadsMissingAs []string                `json:"preloads_missing_as,omitempty"` // Preloads without the as attribute, which browsers ignore
+	UnusedPreconnects []string                `json:"unused_preconnects,omitempty"`  // Origins preconnected or dns-prefetched that no subresource of the document is loaded from
+	Duplicates        []ResourceHintDuplicate `json:"duplicates,omitempty"`
+}
+
+// ResourceHintDuplicate is a hint declared more than once for the same URL, or the same
+// origin for preconnect and dns-prefetch
+type ResourceHintDuplicate struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Count int    `json:"count"`
+}
+
+// LegacyIE reports markup left over from targeting Internet Explorer
+type LegacyIE struct {
+	ConditionalComments int      `json:"conditional_comments"` // <!--[if IE]> and <![if !IE]> blocks
+	Conditions          []string `json:"conditions,omitempty"` // Distinct conditions, such as "lt IE 9", sorted
+	XUACompatible       bool     `json:"x_ua_compatible"`      // <meta http-equiv="X-UA-Compatible">
+	XUACompatibleValue  string   `json:"x_ua_compatible_value,omitempty"`
+	Polyfills           []string `json:"polyfills,omitempty"` // IE-only polyfill scripts, such as html5shiv, sorted
+}
+
+// DataURIs counts the data: URIs in href and src attributes. They are never resolved or checked.
+type DataURIs struct {
+	Count        int            `json:"count"`
+	ByAttribute  map[string]int `json:"by_attribute,omitempty"` // href or src
+	DecodedBytes int64          `json:"decoded_bytes"`          // Estimated size of their content once decoded
+}
+
+// SuspiciousLinks counts links with the marks of phishing: hostnames disguised as other
+// domains, and anchor text showing one domain while the link leads to another
+type SuspiciousLinks struct {
+	PunycodeHosts int              `json:"punycode_hosts"`    // External links to hosts with xn-- labels, written so or in Unicode
+	MixedScript   int              `json:"mixed_script"`      // Links whose hostname mixes scripts within a label, such as Latin and Cyrillic
+	TextMismatch  int              `json:"text_mismatch"`     // Links whose text is a URL or domain other than the link's
+	Samples       []SuspiciousLink `json:"samples,omitempty"` // Set when options.include_link_details is requested
+}
+
+// AnchorTextStats measures how well link texts describe their targets, to search engines and
+// screen readers alike. Each anchor is counted under one issue at most.
+type AnchorTextStats struct {
+	Empty                int                  `json:"empty"`                   // Anchors without text, image alt or ARIA label
+	ImageLinks           int                  `json:"image_links"`             // Anchors whose only content is images
+	ImageLinksMissingAlt int                  `json:"image_links_missing_alt"` // Image links none of whose images has alt text
+	Generic              int                  `json:"generic"`                 // Texts such as "click here" or "read more"
+	BareURL              int                  `json:"bare_url"`                // Texts that are a URL or domain
+	AverageLength        float64              `json:"average_length"`          // Characters of the anchors named, to one decimal
+	Offenders            []AnchorTextOffender `json:"offenders,omitempty"`     // Set when options.include_link_details is requested
+}
+
+// AnchorTextOffender is a link counted under an AnchorTextStats issue
+type AnchorTextOffender struct {
+	URL   string `json:"url"`
+	Text  string `json:"text,omitempty"`
+	Issue string `json:"issue"` // empty, image_missing_alt, generic or bare_url
+}
+
+// Pagination reports whether a listing page links its further pages in ways crawlers follow,
+// or only loads them with JavaScript as the reader scrolls
+type Pagination struct {
+	HasRelNextPrev          bool   `json:"has_rel_next_prev"`         // <link rel="next"> or <link rel="prev">
+	NumberedLinks           int    `json:"numbered_links"`            // Distinct same-host links to numbered pages, such as ?page=2 or /page/2
+	InfiniteScrollSuspected bool   `json:"infinite_scroll_suspected"` // Infinite-scroll attributes or scroll sentinel elements were found
+	NextURL                 string `json:"next_url,omitempty"`        // From rel="next", resolved absolute
+}
+
+// SuspiciousLink is a link counted under SuspiciousLinks
+type SuspiciousLink struct {
+	URL     string   `json:"url"`
+	Text    string   `json:"text,omitempty"`
+	Reasons []string `json:"reasons"` // punycode_host, mixed_script or text_mismatch
+}
+
+// LinkDetails lists the outcome of every link considered for checking, grouped by
+// classification and sorted by URL within each group so that runs compare cleanly
+type LinkDetails struct {
+	Internal []LinkCheckDetail `json:"internal"`
+	External []LinkCheckDetail `json:"external"`
+}
+
+// LinkCheckDetail is the outcome of checking one link
+type LinkCheckDetail struct {
+	URL         string `json:"url"`
+	Accessible  bool   `json:"accessible"`
+	Status      int    `json:"status,omitempty"`       // Status of the response that judged the link, after redirects
+	Failure     string `json:"failure,omitempty"`      // Failure category of an inaccessible link, as in failures.by_category
+	RateLimited bool   `json:"rate_limited,omitempty"` // The target rate limited the check
+	Skipped     string `json:"skipped,omitempty"`      // Why the link was not checked, as in skipped
+}
+
+// LinkFailures splits the inaccessible links into failures that will last and failures likely
+// to pass, so clients can tell a broken link from a host that was briefly unavailable
+type LinkFailures struct {
+	Permanent  int            `json:"permanent"`   // 404, 410 and other 4xx answers, and redirect loops
+	Temporary  int            `json:"temporary"`   // Timeouts, connection errors, 5xx answers and open circuits
+	ByCategory map[string]int `json:"by_category"` // Failed links per category, such as not_found or timeout
+}
+
+// LinkRedirect is a checked link that answered with a redirect
+type LinkRedirect struct {
+	URL         string `json:"url"`
+	Hops        int    `json:"hops"` // Redirects followed
+	FinalURL    string `json:"final_url"`
+	FinalStatus int    `json:"final_status,omitempty"` // Status that judged the link; absent for loops
+	Loop        bool   `json:"loop,omitempty"`         // The chain returned to a URL it had visited
+}
+
+// RawLinks is a page of the absolute URLs of the document's links, resolved against the page
+// like the links classified, once each in document order. Cached analyses hold every link.
+type RawLinks struct {
+	Total  int      `json:"total"`
+	Offset int      `json:"offset"`
+	Limit  int      `json:"limit"`
+	Items  []string `json:"items"`
+}
+
+// OffOriginRedirects counts the internal links whose redirects ended on another registered
+// domain, such as tracking redirectors and possible open redirects
+type OffOriginRedirects struct {
+	Count   int            `json:"count"`
+	Samples []LinkRedirect `json:"samples"`
+}
+
+// InternalLinkDetail describes the internal links for information-architecture audits
+type InternalLinkDetail struct {
+	UniquePaths         int            `json:"unique_paths"`                // Distinct paths linked, ignoring query and fragment
+	MaxPathDepth        int            `json:"max_path_depth"`              // Most path segments in a linked path
+	SelfLinks           int            `json:"self_links"`                  // Links to the analyzed page itself, without a fragment
+	FragmentLinks       int            `json:"fragment_links"`              // In-page links to a #fragment of the analyzed page
+	BrokenFragmentLinks int            `json:"broken_fragment_links"`       // In-page links to ids missing from the document
+	BrokenFragments     map[string]int `json:"broken_fragments,omitempty"`  // Links per missing id, most linked first
+}
+
+// DocumentIssues lists structural malformations of the document
+type DocumentIssues struct {
+	Titles           []string       `json:"titles,omitempty"`            // Every title value, when there is more than one title tag
+	MetaDescriptions []string       `json:"meta_descriptions,omitempty"` // Every meta description, when there is more than one
+	DuplicateIDs     map[string]int `json:"duplicate_ids,omitempty"`     // Occurrences of each repeated id value
+	NestedForms      int            `json:"nested_forms"`                // form tags opened inside another form
+	NestedLinks      int            `json:"nested_links"`                // a tags opened inside another link
+}
+
+// CSPReadiness counts the inline code a strict Content-Security-Policy would block
+type CSPReadiness struct {
+	InlineEventHandlers int      `json:"inline_event_handlers"` // Elements with on* attributes
+	InlineScripts       int      `json:"inline_scripts"`        // Inline script blocks without a nonce
+	JavaScriptURLs      int      `json:"javascript_urls"`       // Elements with javascript: URLs
+	InlineStyles        int      `json:"inline_styles"`         // Elements with a style attribute
+	StrictCSPCompatible bool     `json:"strict_csp_compatible"` // No inline script that a nonce-based policy blocks
+	PolicyPresent       bool     `json:"policy_present"`        // The response set Content-Security-Policy
+	Conflicts           []string `json:"conflicts,omitempty"`   // Inline code blocked by the page's own policy
+}
+
+// AccessibilityReport groups the static accessibility issues found in the webpage
+type AccessibilityReport struct {
+	Issues []AccessibilityIssue `json:"issues"`
+}
+
+// AccessibilityIssue represents all violations of a single accessibility rule
+type AccessibilityIssue struct {
+	Rule           string `json:"rule"`
+	Count          int    `json:"count"`
+	SampleSelector string `json:"sample_selector"`
+}
+
+// FetchInfo describes how the page was transferred from the target
+type FetchInfo struct {
+	StatusCode        int     `json:"status_code"`
+	Proto             string  `json:"proto"`
+	ContentEncoding   string  `json:"content_encoding,omitempty"`
+	Compressed        bool    `json:"compressed"`
+	TransferBytes     int64   `json:"transfer_bytes"`
+	DecompressedBytes int64   `json:"decompressed_bytes"`
+	CompressionRatio  float64 `json:"compression_ratio"` // Transfer size divided by decompressed size
+	HTTP3Advertised   bool    `json:"http3_advertised"`   // The target advertised h3 via Alt-Svc
+	ContentLanguage   string  `json:"content_language,omitempty"` // The Content-Language the target answered with
+	VariesByLanguage  bool    `json:"varies_by_language"` // The response declared Vary: Accept-Language
+	OutboundRequests  int     `json:"outbound_requests"`  // Requests sent for the whole analysis
+	OutboundBudget    int     `json:"outbound_budget,omitempty"` // Per-analysis request budget; omitted when unlimited
+	Egress            string  `json:"egress,omitempty"`          // Proxy pool of options.egress the analysis went through
+	Timings           *FetchTimings `json:"timings,omitempty"`
+	Truncated         bool    `json:"truncated"`              // Only the prefix received before the size limit or a timeout was analyzed
+	TruncatedBy       string  `json:"truncated_by,omitempty"` // size_limit or timeout
+	ETag              string  `json:"etag,omitempty"`          // Validators of the page, sent to revalidate the cached result on options.force_refresh
+	LastModified      string  `json:"last_modified,omitempty"`
+}
+
+// FetchTimings breaks down the request that returned the page, the last one after redirects.
+// Phases that did not happen, such as DNS for an IP address or all of them on a reused
+// connection, are zero.
+type FetchTimings struct {
+	DNSLookupMs      int64    `json:"dns_lookup_ms"`
+	ConnectMs        int64    `json:"connect_ms"`
+	TLSHandshakeMs   int64    `json:"tls_handshake_ms"`
+	TTFBMs           int64    `json:"ttfb_ms"`                // From the start of the request to the first response byte
+	ResolvedIPs      []string `json:"resolved_ips,omitempty"` // Addresses returned by the DNS lookup
+	RemoteAddr       string   `json:"remote_addr,omitempty"`  // Address the connection was made to
+	ConnectionReused bool     `json:"connection_reused"`
+}
+
+// AnalysisStats reports the resources one analysis used. Cached results report the cache
+// lookup only.
+type AnalysisStats struct {
+	OutboundRequests    int   `json:"outbound_requests"`
+	BytesFetched        int64 `json:"bytes_fetched"`         // Page and link check responses, as transferred
+	PageBytes           int64 `json:"page_bytes"`            // Page fetches, the mobile variant's included
+	LinkCheckBytes      int64 `json:"link_check_bytes"`      // Link check responses, which are HEAD requests
+	PeakLinkConcurrency int   `json:"peak_link_concurrency"` // Most link checks of the analysis in flight at once
+	CacheRoundTrips     int   `json:"cache_round_trips"`
+	ExternalHosts       int   `json:"external_hosts"` // Distinct external hosts link checks were sent to
+}
+
+// MobileComparison compares the page served to a mobile user agent with the analyzed page
+type MobileComparison struct {
+	Match                bool           `json:"match"` // Every compared field is equal
+	TitleMatch           bool           `json:"title_match"`
+	HeadingsMatch        bool           `json:"headings_match"`
+	MetaDescriptionMatch bool           `json:"meta_description_match"`
+	ViewportMatch        bool           `json:"viewport_match"`
+	HasViewport          bool           `json:"has_viewport"`        // The analyzed page declares a viewport meta tag
+	MobileHasViewport    bool           `json:"mobile_has_viewport"` // The mobile page declares one
+	MobileTitle          string         `json:"mobile_title,omitempty"`    // Only when it differs
+	MobileHeadings       map[string]int `json:"mobile_headings,omitempty"` // Only when they differ
+	Error                string         `json:"error,omitempty"`           // The mobile fetch failed and nothing was compared
+}
+
+// OriginChecks reports whether the variants of the target origin redirect to one canonical URL
+type OriginChecks struct {
+	CanonicalURL        string       `json:"canonical_url"` // Where the target origin finally resolves
+	HTTPSEnforced       bool         `json:"https_enforced"`
+	SingleCanonicalHost bool         `json:"single_canonical_host"`
+	HTTP                OriginProbe  `json:"http"`                   // http:// variant of the target host
+	HostVariant         *OriginProbe `json:"host_variant,omitempty"` // www or apex sibling, omitted for IPs and single-label hosts
+}
+
+// OriginProbe is the redirect chain observed for one origin variant
+type OriginProbe struct {
+	URL              string `json:"url"`
+	FinalURL         string `json:"final_url,omitempty"`
+	StatusCode       int    `json:"status_code,omitempty"`
+	RedirectStatuses []int  `json:"redirect_statuses"`
+	ReachesCanonical bool   `json:"reaches_canonical"`
+	Error            string `json:"error,omitempty"`
+}
+
+// ConsentBanner reports whether the page presents a cookie consent banner and which CMP provides it
+type ConsentBanner struct {
+	Detected bool   `json:"detected"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// Forms reports where the forms of the page submit to
+type Forms struct {
+	Count int        `json:"count"`
+	Items []FormInfo `json:"items"` // The first 50 forms, in document order
+}
+
+// FormInfo describes the submission target of a form
+type FormInfo struct {
+	Action            string `json:"action"` // Resolved against the page URL after redirects; the page itself when absent
+	Method            string `json:"method"` // GET or POST
+	HasPassword       bool   `json:"has_password"`
+	InsecureAction    bool   `json:"insecure_action"`     // Submits over http from an https page
+	CrossOriginAction bool   `json:"cross_origin_action"` // Submits to another registered domain than the page's
+}
+
+// SecuritySummary rolls the security findings of the page up into page-level flags
+type SecuritySummary struct {
+	HasInsecureForms    bool `json:"has_insecure_forms"`
+	HasCrossOriginForms bool `json:"has_cross_origin_forms"`
+}
+
+// Interstitial reports overlays that likely cover the content when the page loads, which
+// search engines penalize. It is a heuristic judged from the initial HTML alone, so overlays
+// injected by scripts are missed and hidden-until-scrolled ones may be flagged.
+type Interstitial struct {
+	Suspected  bool                   `json:"suspected"`
+	Confidence string                 `json:"confidence"`         // none, low, medium or high
+	Evidence   []InterstitialEvidence `json:"evidence,omitempty"` // Matched elements, at most 10
+}
+
+// InterstitialEvidence is an element matched by an interstitial rule
+type InterstitialEvidence struct {
+	Rule    string `json:"rule"`    // full_viewport_overlay, overlay_markup, scroll_lock_style or scroll_lock_class
+	Element string `json:"element"` // Tag with the element's ID or class, such as div#newsletter-popup
+}
+
+// PaywallEvidence is a sign that the page's content is gated behind a subscription or a login
+type PaywallEvidence struct {
+	Signal string `json:"signal"`           // provider_script, not_accessible_for_free, class_marker, login_form or low_word_count
+	Detail string `json:"detail,omitempty"` // The provider, the JSON-LD type, the element or the word count
+}
+
+// CookieAudit describes a cookie set by the page response and the security problems of its
+// attributes. Cookie values are never reported.
+type CookieAudit struct {
+	Name            string   `json:"name"`
+	Domain          string   `json:"domain,omitempty"`
+	Path            string   `json:"path,omitempty"`
+	Secure          bool     `json:"secure"`
+	HTTPOnly        bool     `json:"http_only"`
+	SameSite        string   `json:"same_site,omitempty"`        // As written; empty when absent
+	Persistent      bool     `json:"persistent"`                 // Set with Expires or Max-Age, unlike session cookies
+	LifetimeSeconds int64    `json:"lifetime_seconds,omitempty"` // Remaining lifetime of persistent cookies at the fetch
+	Issues          []string `json:"issues,omitempty"`           // missing_secure, same_site_none_without_secure or long_expiry
+}
+
+// SEOReport summarizes SEO issues and the resulting 0-100 score
+type SEOReport struct {
+	Score  int        `json:"score"`
+	Issues []SEOIssue `json:"issues"`
+}
+
+// Warning is a non-fatal problem met during an analysis
+type Warning struct {
+	Code    string `json:"code"` // Stable identifier, such as LINKS_NOT_CHECKED
+	Message string `json:"message"`
+}
+
+// PolicyWarning is a request the target policy would have blocked if it were enforced
+type PolicyWarning struct {
+	URL  string `json:"url"`
+	Rule string `json:"rule"` // The matching rule, e.g. blocked_hosts:ads.example.com
+}
+
+// AMP reports the AMP variant a page declares with <link rel="amphtml">, or, for an AMP document
+// itself, the canonical page it refers back to
+type AMP struct {
+	IsAMP              bool   `json:"is_amp"` // <html ⚡> or <html amp>
+	AMPURL             string `json:"amp_url,omitempty"`
+	AMPAccessible      *bool  `json:"amp_accessible,omitempty"` // Set when links were checked
+	CanonicalURL       string `json:"canonical_url,omitempty"`  // Set for AMP documents
+	CanonicalCrossHost bool   `json:"canonical_cross_host"`      // The AMP document's canonical page is on another host
+	Error              string `json:"error,omitempty"`          // Why the amphtml or canonical URL could not be resolved
+}
+
+// PWA reports whether the page is an installable progressive web app: it declares a web app
+// manifest and is served over HTTPS
+type PWA struct {
+	HasManifest       bool   `json:"has_manifest"` // <link rel="manifest">
+	ManifestURL       string `json:"manifest_url,omitempty"`
+	ManifestFetched   bool   `json:"manifest_fetched"`  // Set with options.fetch_manifest once the manifest was parsed
+	Name              string `json:"name,omitempty"`    // The manifest's name, or its short_name
+	Display           string `json:"display,omitempty"` // The manifest's display mode, browser when it declares none
+	IconCount         int    `json:"icon_count"`
+	HTTPS             bool   `json:"https"`
+	ServiceWorkerHint bool   `json:"service_worker_hint"` // An inline script uses navigator.serviceWorker; a weak signal
+	Installable       bool   `json:"installable"`
+	Error             string `json:"error,omitempty"` // Why the manifest could not be resolved or fetched
+}
+
+// CanonicalConsistency compares the URL a page was fetched from with the URLs it declares for itself
+type CanonicalConsistency struct {
+	Match       bool     `json:"match"`
+	FetchedURL  string   `json:"fetched_url"`         // After redirects
+	Canonical   string   `json:"canonical,omitempty"` // First canonical link, as written
+	OGURL       string   `json:"og_url,omitempty"`    // og:url, as written
+	Differences []string `json:"differences"`
+}
+
+// CanonicalSimilarity compares the visible text of a page with that of the canonical URL it declares
+type CanonicalSimilarity struct {
+	CanonicalURL string  `json:"canonical_url"` // Resolved, as fetched
+	Score        float64 `json:"score"`         // 1 for the same text, around 0 for unrelated text
+	Threshold    float64 `json:"threshold"`
+	Similar      bool    `json:"similar"` // The score reaches the threshold
+}
+
+// SEOIssue represents a single violated SEO rule
+type SEOIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Penalty  int    `json:"penalty"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
-} 
\ No newline at end of file
+	Code      int    `json:"code"`
+	ErrorCode string `json:"error_code,omitempty"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+} 
+
+// CacheStats describes the analysis cache for the admin cache stats endpoint
+type CacheStats struct {
+	Backend       string  `json:"backend"` // redis, or noop when the cache is disabled
+	Hits          int64   `json:"hits"`    // Lookups since the process started
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`                // Hits over lookups; 0 before the first lookup
+	Entries       int64   `json:"entries"`                  // Cached analyses
+	EntriesMethod string  `json:"entries_method,omitempty"` // scan or dbsize
+	EntriesCapped bool    `json:"entries_capped,omitempty"` // The scan stopped early; entries is a lower bound
+	TTLSeconds    int64   `json:"ttl_seconds"`              // Configured lifetime of an entry
+}
+
+// RateLimitStatus is the current budget of a rate limited client, for support debugging
+type RateLimitStatus struct {
+	Key               string  `json:"key"`               // Client IP address
+	Tracked           bool    `json:"tracked"`           // The limiter has seen the client since the start or has its saved budget; otherwise it has the full limit
+	Limit             int     `json:"limit"`             // Burst size, as in X-RateLimit-Limit
+	Remaining         int     `json:"remaining"`         // Tokens available now, as in X-RateLimit-Remaining
+	ResetSeconds      int     `json:"reset_seconds"`     // Until the budget is full again, as in X-RateLimit-Reset
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+}
+
+// Quota is the rate limit budget of the calling client, so it can pace itself before being
+// limited
+type Quota struct {
+	Enabled           bool           `json:"enabled"`       // Requests are rate limited at all
+	Key               string         `json:"key"`           // Client IP address the budget is kept under
+	Limit             int            `json:"limit"`         // Burst size, as in X-RateLimit-Limit
+	Used              int            `json:"used"`          // Tokens spent and not refilled yet
+	Remaining         int            `json:"remaining"`     // Tokens available now, as in X-RateLimit-Remaining
+	ResetSeconds      int            `json:"reset_seconds"` // Until the budget is full again, as in X-RateLimit-Reset
+	ResetAt           time.Time      `json:"reset_at"`
+	RequestsPerMinute float64        `json:"requests_per_minute"`      // Rate the budget refills at
+	Costs             map[string]int `json:"costs"`                    // Tokens a request to each route consumes, by method and path
+	Egress            *EgressUsage   `json:"egress,omitempty"`         // Set when rate_limit.egress is enabled
+	EgressUnknown     bool           `json:"egress_unknown,omitempty"` // Set instead of Egress when the bytes could not be read
+}
+
+// EgressUsage is the number of bytes analyses fetched today for a client: page bodies and
+// link check responses, as transferred
+type EgressUsage struct {
+	UsedBytes      int64     `json:"used_bytes"`
+	DailyBytes     int64     `json:"daily_bytes,omitempty"`     // Budget of the client; 0 when not limited
+	RemainingBytes *int64    `json:"remaining_bytes,omitempty"` // Set when the bytes are limited
+	ResetAt        time.Time `json:"reset_at"`                  // Start of the next UTC day, when the count starts over
+}
+
+// Capabilities lists the features and public limits of the server, so clients can adapt to
+// it without trial and error. It is a view of the configuration: settings are only exposed
+// once added here.
+type Capabilities struct {
+	Limits   CapabilityLimits   `json:"limits"`
+	Features CapabilityFeatures `json:"features"`
+	Formats  []string           `json:"formats"` // Response formats of analyses
+	Egress   []string           `json:"egress,omitempty"` // Proxy pools options.egress selects
+}
+
+// CapabilityLimits are the effective limits analyses and clients are held to
+type CapabilityLimits struct {
+	MaxLinks               int     `json:"max_links"`                // Links checked per analysis
+	MaxBodyBytes           int64   `json:"max_body_bytes"`           // Of fetched or submitted HTML
+	AnalysisTimeoutSeconds float64 `json:"analysis_timeout_seconds"` // Deadline per analysis; see options.allow_partial
+	MaxRedirects           int     `json:"max_redirects"`            // Redirects of the page fetch followed
+	RawLinksPageSize       int     `json:"raw_links_page_size"`      // Most links per page of options.include_raw_links
+	RequestsPerMinute      float64 `json:"requests_per_minute,omitempty"`   // Per client; 0 when requests are not rate limited
+	PerTargetPerMinute     float64 `json:"per_target_per_minute,omitempty"` // Page fetches per target host; 0 when unlimited
+	DailyEgressBytes       int64   `json:"daily_egress_bytes,omitempty"`    // Bytes fetched per client and UTC day; 0 when unlimited
+}
+
+// CapabilityFeatures reports which optional features are enabled
+type CapabilityFeatures struct {
+	RenderJS     bool `json:"render_js"`     // options.render_js
+	LinkSampling bool `json:"link_sampling"` // Random samples of links on pages with too many; see options.sampling_seed
+	Cache        bool `json:"cache"`
+	Analyses     bool `json:"analyses"`   // GET /api/v1/analyses
+	Duplicates   bool `json:"duplicates"` // GET /api/v1/duplicates
+	RateLimit    bool `json:"rate_limit"` // See GET /api/v1/quota
+	Snapshots    bool `json:"snapshots"`  // options.store_snapshot and GET /api/v1/snapshot
+}
+
+// ValidationReport is the outcome of validating a URL without analyzing it
+type ValidationReport struct {
+	URL        string            `json:"url"`
+	Valid      bool              `json:"valid"` // Every check that ran passed
+	DurationMs int64             `json:"duration_ms"`
+	Checks     []ValidationCheck `json:"checks"`
+}
+
+// ValidationCheck is one check of a ValidationReport. Checks that depend on an earlier failed
+// check are skipped, neither passing nor failing.
+type ValidationCheck struct {
+	Check   string `json:"check"` // length, syntax, scheme, host, policy, dns, robots or reachability
+	Pass    bool   `json:"pass"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// Duplicates lists the other analyzed pages of a site sharing a page's title or meta description
+type Duplicates struct {
+	URL                 string   `json:"url"`
+	Domain              string   `json:"domain"` // Registered domain the pages were looked up on
+	Title               string   `json:"title"`
+	MetaDescription     string   `json:"meta_description"`
+	SameTitle           []string `json:"same_title"`
+	SameMetaDescription []string `json:"same_meta_description"`
+}
+
+// Readiness is the answer of the readiness endpoint
+type Readiness struct {
+	Status   string         `json:"status"`             // ready, or not_ready when a check fails
+	Outbound *OutboundProbe `json:"outbound,omitempty"` // Set when readiness.outbound.canary_url is configured
+}
+
+// OutboundProbe is the result of the latest HEAD request to the outbound canary. Any response,
+// whatever its status, proves the analyzer can reach the internet.
+type OutboundProbe struct {
+	URL        string    `json:"url"`
+	Reachable  bool      `json:"reachable"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	AgeSeconds float64   `json:"age_seconds"` // Since the check; results are reused for readiness.outbound.cache_ttl
+}
+
+// Snapshot is the HTML of a fetched page, as it was analyzed
+type Snapshot struct {
+	URL         string    // Canonical URL of the analyzed page
+	ContentType string    // Of the fetched response
+	FetchedAt   time.Time
+	Body        []byte
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+func TestAnalyzeResponse_MarshalJSON(t *testing.T) {
+	analyzedAt := time.Date(2024, 3, 19, 10, 30, 0, 0, time.UTC)
+
+	t.Run("Feeds leave the HTML sections out and round trip", func(t *testing.T) {
+		latest := time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC)
+		result := AnalyzeResponse{
+			URL:         "https://example.com/feed",
+			ContentKind: constants.ContentKindFeed,
+			Feed:        &Feed{Format: constants.FeedFormatRSS, Title: "Example", ItemCount: 3, LatestItemAt: &latest},
+			Headings:    map[string]int{},
+			Cookies:     []CookieAudit{},
+			AnalyzedAt:  analyzedAt,
+			DurationMs:  12,
+		}
+
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"url": "https://example.com/feed",
+			"content_kind": "feed",
+			"feed": {"format": "rss", "title": "Example", "item_count": 3, "latest_item_at": "2024-03-18T12:00:00Z"},
+			"cookies": [],
+			"analyzed_at": "2024-03-19T10:30:00Z",
+			"duration_ms": 12,
+			"phases": {"fetch_ms": 0, "parse_ms": 0, "link_check_ms": 0}
+		}`, string(data))
+
+		var decoded AnalyzeResponse
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, result.Feed, decoded.Feed)
+		assert.Equal(t, constants.ContentKindFeed, decoded.ContentKind)
+		assert.True(t, analyzedAt.Equal(decoded.AnalyzedAt))
+	})
+
+	t.Run("HTML analyses keep every field", func(t *testing.T) {
+		for _, kind := range []string{constants.ContentKindHTML, ""} {
+			data, err := json.Marshal(&AnalyzeResponse{URL: "https://example.com/", ContentKind: kind, Title: "Home", AnalyzedAt: analyzedAt})
+			require.NoError(t, err)
+
+			var fields map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(data, &fields))
+			assert.Equal(t, `"Home"`, string(fields["title"]))
+			assert.Contains(t, fields, "links")
+			assert.NotContains(t, fields, "feed")
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Page geometry in points: A4 with equal margins
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	margin       = 50
+	contentWidth = pageWidth - 2*margin
+	footerSize   = 8
+)
+
+// Fonts are the standard Helvetica faces, which every PDF reader provides, so nothing is embedded
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+// document is a minimal PDF writer for text reports. Text flows down the page from the top
+// margin and continues on a new page once it reaches the bottom margin.
+type document struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // Baseline of the next line on the current page
+}
+
+func newDocument() *document {
+	d := &document{}
+	d.newPage()
+	return d
+}
+
+func (d *document) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pageHeight - margin
+}
+
+// reserve moves to a new page unless height points fit above the bottom margin
+func (d *document) reserve(height float64) {
+	if d.y-height < margin+2*footerSize {
+		d.newPage()
+	}
+}
+
+// text draws one line at x on the current baseline without advancing
+func (d *document) text(x float64, font string, size float64, s string) {
+	fmt.Fprintf(d.page, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font, size, x, d.y, encodeText(s))
+}
+
+// line writes a line of text at the left margin and advances past it, truncating text
+// too wide for the page
+func (d *document) line(font string, size float64, s string) {
+	d.reserve(lineHeight(size))
+	d.text(margin, font, size, fit(s, contentWidth, size))
+	d.y -= lineHeight(size)
+}
+
+// paragraph writes text wrapped at word boundaries to the page width
+func (d *document) paragraph(font string, size float64, s string) {
+	for _, line := range wrap(s, contentWidth, size) {
+		d.line(font, size, line)
+	}
+}
+
+// row writes cells in columns starting at the given offsets from the left margin; each cell is
+// truncated to the space before the next column
+func (d *document) row(font string, size float64, columns []float64, cells ...string) {
+	d.reserve(lineHeight(size))
+	for i, cell := range cells {
+		width := contentWidth - columns[i]
+		if i+1 < len(columns) {
+			width = columns[i+1] - columns[i] - size/2
+		}
+		d.text(margin+columns[i], font, size, fit(cell, width, size))
+	}
+	d.y -= lineHeight(size)
+}
+
+// space leaves a vertical gap, unless the current page is still empty
+func (d *document) space(height float64) {
+	if d.y < pageHeight-margin {
+		d.y -= height
+	}
+}
+
+// bytes numbers the pages and serializes the document
+func (d *document) bytes() []byte {
+	for i, page := range d.pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+		fmt.Fprintf(page, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", fontRegular, footerSize, margin, margin, encodeText(footer))
+	}
+
+	// Objects: catalog, page tree, two fonts, then a page and its content stream per page
+	var objects []string
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	)
+	for i, page := range d.pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, fontRegular, fontBold, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+func lineHeight(size float64) float64 {
+	return size * 1.4
+}
+
+// averageCharWidth approximates the width of a Helvetica character as a share of the font size
+const averageCharWidth = 0.52
+
+func maxChars(width, size float64) int {
+	return max(int(width/(averageCharWidth*size)), 1)
+}
+
+// fit truncates s with an ellipsis to about width points
+func fit(s string, width, size float64) string {
+	limit := maxChars(width, size)
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max(limit-3, 0)]) + "..."
+}
+
+// wrap breaks s into lines of about width points at spaces; a word longer than a line gets one of its own
+func wrap(s string, width, size float64) []string {
+	limit := maxChars(width, size)
+	var lines []string
+	var current []string
+	length := 0
+	for _, word := range strings.Fields(s) {
+		n := utf8.RuneCountInString(word)
+		if len(current) > 0 && length+1+n > limit {
+			lines = append(lines, strings.Join(current, " "))
+			current, length = nil, 0
+		}
+		if len(current) > 0 {
+			length++
+		}
+		current = append(current, word)
+		length += n
+	}
+	if len(current) > 0 {
+		lines = append(lines, strings.Join(current, " "))
+	}
+	return lines
+}
+
+// winAnsi maps the characters of Windows-1252 outside Latin-1 to their codes
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encodeText converts s to a WinAnsi PDF string body. Characters the standard fonts cannot
+// show become question marks.
+func encodeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7F:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			if code, ok := winAnsi[r]; ok {
+				fmt.Fprintf(&b, "\\%03o", code)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
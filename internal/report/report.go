@@ -0,0 +1,163 @@
+// Package report renders analysis results as PDF documents for people who want something to
+// share rather than JSON. The PDF is written directly, so no external binaries are needed.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// Font sizes in points
+const (
+	titleSize   = 18
+	sectionSize = 13
+	bodySize    = 10
+)
+
+// Render returns the PDF report of a result: a summary, the headings, the link statistics
+// and broken links, and the SEO and accessibility findings. Broken links are listed from
+// links.details, so the result should be analyzed with include_link_details.
+func Render(result *models.AnalyzeResponse) []byte {
+	d := newDocument()
+	d.line(fontBold, titleSize, "Webpage Analysis Report")
+	d.space(6)
+	summary(d, result)
+	headings(d, result.Headings)
+	links(d, result.Links)
+	brokenLinks(d, result.Links.Details)
+	seo(d, result.SEO)
+	accessibility(d, result.Accessibility)
+	if len(result.Warnings) > 0 {
+		section(d, "Warnings")
+		for _, warning := range result.Warnings {
+			d.paragraph(fontRegular, bodySize, warning.Code+": "+warning.Message)
+		}
+	}
+	return d.bytes()
+}
+
+// Column offsets, in points from the left margin
+var (
+	labelColumns   = []float64{0, 150}
+	linkColumns    = []float64{0, 390}
+	findingColumns = []float64{0, 70}
+	a11yColumns    = []float64{0, 170, 220}
+)
+
+func section(d *document, title string) {
+	d.space(12)
+	// Keep the title with at least two lines of its section
+	d.reserve(lineHeight(sectionSize) + 2*lineHeight(bodySize))
+	d.line(fontBold, sectionSize, title)
+	d.space(2)
+}
+
+func summary(d *document, result *models.AnalyzeResponse) {
+	title := result.Title
+	if title == "" {
+		title = "(none)"
+	}
+	d.row(fontBold, bodySize, labelColumns, "URL", result.URL)
+	d.row(fontBold, bodySize, labelColumns, "Analyzed at", result.AnalyzedAt.UTC().Format(time.RFC1123))
+	d.row(fontBold, bodySize, labelColumns, "Title", title)
+	d.row(fontBold, bodySize, labelColumns, "HTML version", result.HTMLVersion)
+	d.row(fontBold, bodySize, labelColumns, "Login form", yesNo(result.HasLoginForm))
+	d.row(fontBold, bodySize, labelColumns, "SEO score", fmt.Sprintf("%d / 100", result.SEO.Score))
+}
+
+func headings(d *document, counts map[string]int) {
+	section(d, "Headings")
+	d.row(fontBold, bodySize, labelColumns, "Level", "Count")
+	for level := 1; level <= 6; level++ {
+		tag := "h" + strconv.Itoa(level)
+		d.row(fontRegular, bodySize, labelColumns, strings.ToUpper(tag), strconv.Itoa(counts[tag]))
+	}
+}
+
+func links(d *document, links models.LinkAnalysis) {
+	section(d, "Links")
+	d.row(fontRegular, bodySize, labelColumns, "Internal", strconv.Itoa(links.Internal))
+	d.row(fontRegular, bodySize, labelColumns, "External", strconv.Itoa(links.External))
+	d.row(fontRegular, bodySize, labelColumns, "Inaccessible", fmt.Sprintf("%d (%d internal)", links.Inaccessible, links.InaccessibleInternal))
+	if links.RateLimited > 0 {
+		d.row(fontRegular, bodySize, labelColumns, "Rate limited", strconv.Itoa(links.RateLimited))
+	}
+	reasons := make([]string, 0, len(links.Skipped))
+	for reason := range links.Skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		d.row(fontRegular, bodySize, labelColumns, "Not checked ("+reason+")", strconv.Itoa(links.Skipped[reason]))
+	}
+}
+
+func brokenLinks(d *document, details *models.LinkDetails) {
+	section(d, "Broken Links")
+	if details == nil {
+		d.line(fontRegular, bodySize, "Link details were not included in this analysis.")
+		return
+	}
+
+	var broken []models.LinkCheckDetail
+	for _, group := range [][]models.LinkCheckDetail{details.Internal, details.External} {
+		for _, link := range group {
+			if !link.Accessible && link.Skipped == "" && !link.RateLimited {
+				broken = append(broken, link)
+			}
+		}
+	}
+	if len(broken) == 0 {
+		d.line(fontRegular, bodySize, "No broken links found.")
+		return
+	}
+	sort.Slice(broken, func(i, j int) bool { return broken[i].URL < broken[j].URL })
+
+	d.row(fontBold, bodySize, linkColumns, "URL", "Problem")
+	for _, link := range broken {
+		problem := link.Failure
+		if link.Status != 0 {
+			problem = strings.TrimSpace(fmt.Sprintf("%d %s", link.Status, link.Failure))
+		}
+		d.row(fontRegular, bodySize, linkColumns, link.URL, problem)
+	}
+}
+
+func seo(d *document, report models.SEOReport) {
+	section(d, "SEO Findings")
+	if len(report.Issues) == 0 {
+		d.line(fontRegular, bodySize, "No SEO issues found.")
+		return
+	}
+	for _, issue := range report.Issues {
+		lines := wrap(issue.Message, contentWidth-findingColumns[1], bodySize)
+		d.row(fontBold, bodySize, findingColumns, issue.Severity, lines[0])
+		for _, line := range lines[1:] {
+			d.row(fontRegular, bodySize, findingColumns, "", line)
+		}
+	}
+}
+
+func accessibility(d *document, report models.AccessibilityReport) {
+	section(d, "Accessibility Findings")
+	if len(report.Issues) == 0 {
+		d.line(fontRegular, bodySize, "No accessibility issues found.")
+		return
+	}
+	d.row(fontBold, bodySize, a11yColumns, "Rule", "Count", "Example")
+	for _, issue := range report.Issues {
+		d.row(fontRegular, bodySize, a11yColumns, issue.Rule, strconv.Itoa(issue.Count), issue.SampleSelector)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
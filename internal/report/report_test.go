@@ -0,0 +1,126 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// readPDF parses a rendered report, returning its page count and text
+func readPDF(t *testing.T, data []byte) (int, string) {
+	t.Helper()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	plain, err := reader.GetPlainText()
+	require.NoError(t, err)
+	text, err := io.ReadAll(plain)
+	require.NoError(t, err)
+	return reader.NumPage(), string(text)
+}
+
+func testResult() *models.AnalyzeResponse {
+	return &models.AnalyzeResponse{
+		URL:          "https://example.com",
+		Title:        "Example (Home)",
+		HTMLVersion:  "HTML5",
+		HasLoginForm: true,
+		AnalyzedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Headings:     map[string]int{"h1": 1, "h2": 3},
+		Links: models.LinkAnalysis{
+			Internal:     4,
+			External:     2,
+			Inaccessible: 1,
+			Details: &models.LinkDetails{
+				Internal: []models.LinkCheckDetail{
+					{URL: "https://example.com/about", Accessible: true, Status: 200},
+					{URL: "https://example.com/gone", Status: 404, Failure: "client_error"},
+					{URL: "https://example.com/busy", RateLimited: true, Status: 429},
+				},
+			},
+		},
+		SEO: models.SEOReport{
+			Score:  85,
+			Issues: []models.SEOIssue{{Rule: "missing_meta_description", Severity: "warning", Message: "The page has no meta description", Penalty: 15}},
+		},
+		Accessibility: models.AccessibilityReport{
+			Issues: []models.AccessibilityIssue{{Rule: "img_missing_alt", Count: 2, SampleSelector: "img.hero"}},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := Render(testResult())
+	require.NotEmpty(t, data)
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+
+	pages, text := readPDF(t, data)
+	assert.Equal(t, 1, pages)
+	for _, expected := range []string{
+		"Webpage Analysis Report",
+		"https://example.com",
+		"Example (Home)",
+		"HTML5",
+		"85 / 100",
+		"Headings",
+		"Broken Links",
+		"https://example.com/gone",
+		"404 client_error",
+		"The page has no meta description",
+		"img_missing_alt",
+		"img.hero",
+		"Page 1 of 1",
+	} {
+		assert.Contains(t, text, expected)
+	}
+	assert.NotContains(t, text, "https://example.com/busy", "rate limited links are not broken")
+	assert.NotContains(t, text, "https://example.com/about")
+}
+
+func TestRender_WithoutDetails(t *testing.T) {
+	result := testResult()
+	result.Links.Details = nil
+	result.SEO.Issues = nil
+	result.Accessibility.Issues = nil
+
+	_, text := readPDF(t, Render(result))
+	assert.Contains(t, text, "Link details were not included in this analysis.")
+	assert.Contains(t, text, "No SEO issues found.")
+	assert.Contains(t, text, "No accessibility issues found.")
+}
+
+func TestRender_PaginatesLongLists(t *testing.T) {
+	result := testResult()
+	result.Links.Details.Internal = nil
+	for i := range 200 {
+		result.Links.Details.Internal = append(result.Links.Details.Internal,
+			models.LinkCheckDetail{URL: fmt.Sprintf("https://example.com/page-%03d", i), Status: 404, Failure: "client_error"})
+	}
+
+	pages, text := readPDF(t, Render(result))
+	assert.Greater(t, pages, 2)
+	assert.Contains(t, text, "https://example.com/page-000")
+	assert.Contains(t, text, "https://example.com/page-199")
+	assert.Contains(t, text, fmt.Sprintf("Page %d of %d", pages, pages))
+}
+
+func TestEncodeText(t *testing.T) {
+	assert.Equal(t, `a \(b\) \\ c`, encodeText(`a (b) \ c`))
+	assert.Equal(t, `caf\351 \226 \200 ?`, encodeText("café – € 日"))
+	assert.Equal(t, "a b", encodeText("a\nb"))
+}
+
+func TestWrapAndFit(t *testing.T) {
+	assert.Equal(t, "abc", fit("abc", 100, 10))
+	assert.Equal(t, "abcdefghijklm...", fit("abcdefghijklmnopqrstuvwxyz", 85, 10))
+
+	lines := wrap("the quick brown fox jumps over the lazy dog", 60, 10)
+	assert.Equal(t, []string{"the quick", "brown fox", "jumps over", "the lazy", "dog"}, lines)
+}
@@ -1,11 +1,15 @@
 package router
 
 import (
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
 	"net/http"
-	"time"
+	"path/filepath"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
@@ -14,16 +18,26 @@ import (
 	"github.com/webpage-analyser-server/internal/handlers"
 	"github.com/webpage-analyser-server/internal/metrics"
 	"github.com/webpage-analyser-server/internal/middleware"
+	"github.com/webpage-analyser-server/web"
 )
 
 
 type Router struct {
-	engine      *gin.Engine
-	config      *config.Config
-	logger      *zap.Logger
-	metrics     *metrics.Metrics
-	handler     *handlers.AnalyzeHandler
-	rateLimiter *middleware.RateLimiter
+	engine       *gin.Engine
+	config       *config.Config
+	logger       *zap.Logger
+	metrics      *metrics.Metrics
+	handler      *handlers.AnalyzeHandler
+	analyses     *handlers.AnalysesHandler
+	validate     *handlers.ValidateHandler
+	duplicates   *handlers.DuplicatesHandler
+	export       *handlers.ExportHandler
+	cacheStats   *handlers.CacheStatsHandler
+	rateLimits   *handlers.RateLimitHandler
+	capabilities *handlers.CapabilitiesHandler
+	readiness    *handlers.ReadinessHandler
+	snapshots    *handlers.SnapshotHandler
+	rateLimiter  *middleware.RateLimiter
 }
 
 
@@ -32,6 +46,15 @@ func New(
 	logger *zap.Logger,
 	metrics *metrics.Metrics,
 	handler *handlers.AnalyzeHandler,
+	analyses *handlers.AnalysesHandler,
+	validate *handlers.ValidateHandler,
+	duplicates *handlers.DuplicatesHandler,
+	export *handlers.ExportHandler,
+	cacheStats *handlers.CacheStatsHandler,
+	rateLimits *handlers.RateLimitHandler,
+	capabilities *handlers.CapabilitiesHandler,
+	readiness *handlers.ReadinessHandler,
+	snapshots *handlers.SnapshotHandler,
 	rateLimiter *middleware.RateLimiter,
 ) *Router {
 	if config.Server.Mode == "" {
@@ -40,12 +63,21 @@ func New(
 	gin.SetMode(config.Server.Mode)
 
 	r := &Router{
-		engine:      gin.New(),
-		config:      config,
-		logger:      logger,
-		metrics:     metrics,
-		handler:     handler,
-		rateLimiter: rateLimiter,
+		engine:       gin.New(),
+		config:       config,
+		logger:       logger,
+		metrics:      metrics,
+		handler:      handler,
+		analyses:     analyses,
+		validate:     validate,
+		duplicates:   duplicates,
+		export:       export,
+		cacheStats:   cacheStats,
+		rateLimits:   rateLimits,
+		capabilities: capabilities,
+		readiness:    readiness,
+		snapshots:    snapshots,
+		rateLimiter:  rateLimiter,
 	}
 
 	r.setupMiddleware()
@@ -60,55 +92,157 @@ func (r *Router) Handler() http.Handler {
 }
 
 func (r *Router) setupMiddleware() {
-	r.engine.Use(gin.Recovery())
+	r.engine.Use(middleware.RequestID())
 
-	// Add request logging middleware
-	r.engine.Use(func(c *gin.Context) {
-		start := time.Now()
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
-
-		c.Next()
-
-		latency := time.Since(start)
-		status := c.Writer.Status()
+	// The trace context must be in place before the access logger observes request latency
+	if r.config.Tracing.Enabled {
+		r.engine.Use(middleware.TraceContext())
+	}
 
-		r.logger.Info("Request processed",
-			zap.String("path", path),
-			zap.String("query", query),
-			zap.Int("status", status),
-			zap.Duration("latency", latency),
-			zap.String("ip", c.ClientIP()),
-			zap.String("method", c.Request.Method),
-			zap.String("user_agent", c.Request.UserAgent()),
-		)
+	// Add request logging middleware
+	accessLogger := middleware.NewAccessLogger(r.config.Logging.Access, r.logger, r.metrics)
+	r.engine.Use(accessLogger.Handler())
 
-		r.metrics.RequestDuration.WithLabelValues(fmt.Sprintf("%d", status)).Observe(latency.Seconds())
-	})
+	// Recovery runs inside the access logger so recovered panics are logged as 500s
+	r.engine.Use(middleware.Recovery(r.logger, r.metrics, r.config.Server.Mode == constants.ServerModeDebug))
 }
 
 func (r *Router) setupRoutes() {
-	// Serve static files
-	r.engine.Static("/static", "./web/static")
-	r.engine.LoadHTMLGlob("web/templates/*")
+	r.setupFrontend()
 
 	// Serve HTML form
 	r.engine.GET("/", func(c *gin.Context) {
 		c.HTML(constants.StatusOK, "index.html", nil)
 	})
 
+	// Reading the quota spends none of it, so it is served outside the rate limiter, as are the
+	// capabilities clients read before their first request
+	r.engine.GET("/api/v1/quota", r.rateLimits.Quota)
+	r.engine.GET("/api/v1/capabilities", r.capabilities.Get)
+
 	// API routes
 	api := r.engine.Group("/api/v1")
 	{
+		for _, route := range handlers.RouteCosts(r.config.RateLimit.Costs) {
+			r.rateLimiter.SetCost(route.Path, middleware.FixedCost(route.Tokens))
+		}
 		api.Use(r.rateLimiter.RateLimit())
 		api.POST("/analyze", r.handler.Handle)
+		api.POST("/analyze/html", r.handler.HandleHTML)
+		api.GET("/analyze/report.pdf", r.handler.HandleReport)
+		api.GET("/analyses", r.analyses.List)
+		api.POST("/validate", r.validate.Validate)
+		api.GET("/duplicates", r.duplicates.List)
+		// Snapshots are pages of third parties as fetched, so only the admin token reads them
+		if r.config.Admin.Token != "" {
+			api.GET("/snapshot", middleware.AdminAuth(r.config.Admin.Token), r.snapshots.Get)
+		}
+	}
+
+	// Admin routes are served only when a token protects them
+	if r.config.Admin.Token != "" {
+		admin := r.engine.Group("/admin")
+		admin.Use(middleware.AdminAuth(r.config.Admin.Token))
+		admin.GET("/export", r.export.Export)
+		admin.GET("/cache/stats", r.cacheStats.Stats)
+		admin.GET("/ratelimit/:key", r.rateLimits.Status)
 	}
 
 	// Metrics endpoint
-	r.engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.engine.GET("/metrics", gin.WrapH(metricsHandler()))
 
 	// Health check
 	r.engine.GET("/health", func(c *gin.Context) {
 		c.JSON(constants.StatusOK, gin.H{"status": "ok"})
 	})
-} 
\ No newline at end of file
+
+	// Readiness check, including outbound connectivity when configured
+	r.engine.GET("/ready", r.readiness.Ready)
+}
+
+// setupFrontend serves the templates and static assets embedded in the binary, or those in
+// server.web_dir when set, so frontend changes show without a rebuild
+func (r *Router) setupFrontend() {
+	static := r.engine.Group("/static")
+	if dir := r.config.Server.WebDir; dir != "" {
+		r.logger.Info("Serving frontend from disk", zap.String("dir", dir))
+		static.Use(cacheControl(constants.CacheControlNoCache))
+		static.Static("/", filepath.Join(dir, "static"))
+		// Gin reparses globbed templates on every render in debug mode
+		r.engine.LoadHTMLGlob(filepath.Join(dir, "templates", "*"))
+		return
+	}
+
+	assets, err := fs.Sub(web.Assets, "static")
+	if err != nil {
+		panic(err)
+	}
+	// Asset URLs carry no version, so browsers revalidate them against the entity tag of the
+	// embedded content instead of caching them for a fixed time
+	static.Use(cacheControl(constants.CacheControlNoCache), assetETags(assets))
+	static.StaticFS("/", filesOnlyFS{http.FS(assets)})
+	r.engine.SetHTMLTemplate(template.Must(template.ParseFS(web.Assets, "templates/*")))
+}
+
+// filesOnlyFS serves the files of a file system and no directory listings, like the disk
+// file system behind Static
+type filesOnlyFS struct {
+	http.FileSystem
+}
+
+// Open opens the named file, reporting directories as missing
+func (f filesOnlyFS) Open(name string) (http.File, error) {
+	file, err := f.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+	return file, nil
+}
+
+// assetETags sets the ETag header of every asset to the hash of its content, computed once,
+// so requests with a matching If-None-Match are answered with 304 Not Modified
+func assetETags(assets fs.FS) gin.HandlerFunc {
+	tags := make(map[string]string)
+	err := fs.WalkDir(assets, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(assets, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		tags["/"+name] = `"` + hex.EncodeToString(sum[:16]) + `"`
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return func(c *gin.Context) {
+		if tag, ok := tags[c.Param("filepath")]; ok {
+			c.Header(constants.HeaderETag, tag)
+		}
+		c.Next()
+	}
+}
+
+// cacheControl sets the Cache-Control header of every response
+func cacheControl(value string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(constants.HeaderCacheControl, value)
+		c.Next()
+	}
+}
+
+// metricsHandler serves the default registry like promhttp.Handler, additionally
+// negotiating the OpenMetrics format, which is the only text format carrying exemplars
+func metricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
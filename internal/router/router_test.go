@@ -0,0 +1,182 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/handlers"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/middleware"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func newTestRouter(t *testing.T, webDir string) http.Handler {
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "test", WebDir: webDir}}
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	return New(cfg, zaptest.NewLogger(t), m, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, middleware.NewRateLimiter(nil)).Handler()
+}
+
+func get(handler http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestRouter_ServesEmbeddedFrontendFromAnyWorkingDirectory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	handler := newTestRouter(t, "")
+
+	w := get(handler, "/")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<script src="/static/js/app.js"></script>`)
+
+	w = get(handler, "/static/js/app.js")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "API_ENDPOINT")
+	assert.Equal(t, constants.CacheControlNoCache, w.Header().Get(constants.HeaderCacheControl))
+	etag := w.Header().Get(constants.HeaderETag)
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/static/js/app.js", nil)
+	req.Header.Set(constants.HeaderIfNoneMatch, etag)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	assert.Equal(t, http.StatusNotFound, get(handler, "/static/missing.js").Code)
+	// Directories are not listed, as when serving from disk
+	assert.Equal(t, http.StatusNotFound, get(handler, "/static/").Code)
+	assert.Equal(t, http.StatusNotFound, get(handler, "/static/js/").Code)
+}
+
+func TestRouter_ServesFrontendFromWebDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "templates"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "static"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "templates", "index.html"), []byte("<p>Local build</p>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "static", "app.js"), []byte("// local"), 0o644))
+	handler := newTestRouter(t, dir)
+
+	w := get(handler, "/")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<p>Local build</p>", w.Body.String())
+
+	w = get(handler, "/static/app.js")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "// local", w.Body.String())
+	assert.Equal(t, constants.CacheControlNoCache, w.Header().Get(constants.HeaderCacheControl))
+}
+
+func TestRouter_QuotaSpendsNoBudget(t *testing.T) {
+	viper.Set("rate_limit.enabled", true)
+	viper.Set("rate_limit.requests_per_minute", 60)
+	t.Cleanup(viper.Reset)
+
+	cfg := &config.Config{
+		Server:    config.ServerConfig{Mode: "test"},
+		RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerMinute: 60},
+	}
+	logger := zaptest.NewLogger(t)
+	rateLimiter := middleware.NewRateLimiter(nil)
+	rateLimits := handlers.NewRateLimitHandler(logger, rateLimiter, cfg.RateLimit)
+	handler := New(cfg, logger, metrics.NewWithRegistry(prometheus.NewRegistry()), nil, nil, nil, nil, nil, nil, rateLimits, nil, nil, nil, rateLimiter).Handler()
+
+	for range 10 {
+		w := get(handler, "/api/v1/quota")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(constants.HeaderRateRemaining), "the quota is served outside the rate limiter")
+
+		var quota models.Quota
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &quota))
+		assert.Equal(t, 6, quota.Limit)
+		assert.Equal(t, 6, quota.Remaining)
+		assert.Zero(t, quota.Used)
+	}
+}
+
+func TestRouter_CapabilitiesSpendNoBudget(t *testing.T) {
+	viper.Set("rate_limit.enabled", true)
+	viper.Set("rate_limit.requests_per_minute", 60)
+	t.Cleanup(viper.Reset)
+
+	cfg := &config.Config{
+		Server:    config.ServerConfig{Mode: "test"},
+		RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerMinute: 60},
+	}
+	capabilities := handlers.NewCapabilitiesHandler(models.Capabilities{Limits: models.CapabilityLimits{MaxLinks: 100}})
+	handler := New(cfg, zaptest.NewLogger(t), metrics.NewWithRegistry(prometheus.NewRegistry()), nil, nil, nil, nil, nil, nil, nil, capabilities, nil, nil, middleware.NewRateLimiter(nil)).Handler()
+
+	w := get(handler, "/api/v1/capabilities")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(constants.HeaderRateRemaining), "the capabilities are served outside the rate limiter")
+
+	var served models.Capabilities
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, 100, served.Limits.MaxLinks)
+}
+
+// snapshotLoader serves a fixed snapshot
+type snapshotLoader struct{}
+
+func (snapshotLoader) Snapshot(_ context.Context, targetURL string) (*models.Snapshot, error) {
+	return &models.Snapshot{URL: targetURL, ContentType: "text/html", Body: []byte("<h1>Stored</h1>")}, nil
+}
+
+func TestRouter_SnapshotRequiresAdminToken(t *testing.T) {
+	newRouter := func(token string) http.Handler {
+		cfg := &config.Config{Server: config.ServerConfig{Mode: "test"}, Admin: config.AdminConfig{Token: token}}
+		snapshots := handlers.NewSnapshotHandler(zaptest.NewLogger(t), snapshotLoader{})
+		return New(cfg, zaptest.NewLogger(t), metrics.NewWithRegistry(prometheus.NewRegistry()), nil, nil, nil, nil, nil, nil, nil, nil, nil, snapshots, middleware.NewRateLimiter(nil)).Handler()
+	}
+	getWithToken := func(handler http.Handler, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/snapshot?url=https://example.com/", nil)
+		if token != "" {
+			req.Header.Set(constants.HeaderAuthorization, "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, getWithToken(newRouter(""), "").Code, "not served without an admin token")
+
+	handler := newRouter("admin-secret")
+	assert.Equal(t, http.StatusUnauthorized, getWithToken(handler, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, getWithToken(handler, "wrong").Code)
+	w := getWithToken(handler, "admin-secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>Stored</h1>", w.Body.String())
+}
+
+func TestRouter_EveryAPIRouteHasACost(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "test"}, Admin: config.AdminConfig{Token: "admin-secret"}}
+	r := New(cfg, zaptest.NewLogger(t), metrics.NewWithRegistry(prometheus.NewRegistry()), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, middleware.NewRateLimiter(nil))
+
+	listed := make(map[string]bool)
+	for _, route := range handlers.RouteCosts(cfg.RateLimit.Costs) {
+		listed[route.Method+" "+route.Path] = true
+	}
+
+	// The quota reports the costs the limiter charges, so every rate limited route is in the table
+	for _, route := range r.engine.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/") || route.Path == "/api/v1/quota" || route.Path == "/api/v1/capabilities" {
+			continue
+		}
+		assert.True(t, listed[route.Method+" "+route.Path], "%s %s has no cost", route.Method, route.Path)
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// simpleIDPattern matches IDs that can be used in a CSS #id selector without escaping
+var simpleIDPattern = regexp.MustCompile(`^[A-Za-z][\w-]*$`)
+
+// a11yChecker holds per-document state shared by the accessibility rules
+type a11yChecker struct {
+	doc          *goquery.Document
+	images       *goquery.Document // The region images are audited in
+	labelFor     map[string]bool
+	duplicateIDs map[string]bool
+	generic      []string // analyzer.anchor_text.generic_phrases
+	issues       []models.AccessibilityIssue
+}
+
+// checkAccessibility runs cheap static accessibility checks against the document.
+// Rules without violations are omitted; issues are reported in a fixed rule order. Images are
+// audited in the region options.scope_selector picks.
+func (a *Analyzer) checkAccessibility(ctx context.Context, doc *goquery.Document, limits domLimits) models.AccessibilityReport {
+	c := &a11yChecker{
+		doc:          doc,
+		images:       limits.scoped(doc),
+		labelFor:     make(map[string]bool),
+		duplicateIDs: make(map[string]bool),
+		generic:      a.optionsFor(ctx).AnchorText.GenericPhrases,
+		issues:       []models.AccessibilityIssue{},
+	}
+
+	doc.Find("label[for]").Each(func(_ int, l *goquery.Selection) {
+		if id := strings.TrimSpace(l.AttrOr("for", "")); id != "" {
+			c.labelFor[id] = true
+		}
+	})
+
+	duplicates := c.findDuplicateIDs()
+
+	c.checkImages()
+	c.checkInputLabels()
+	c.checkLinks()
+	c.checkDocumentLang()
+	c.checkButtons()
+	if duplicates.Length() > 0 {
+		// The ID itself is the most useful pointer when it is not unique
+		c.issues = append(c.issues, models.AccessibilityIssue{
+			Rule:           constants.A11yRuleDuplicateID,
+			Count:          duplicates.Length(),
+			SampleSelector: idSelector(duplicates.First().AttrOr("id", "")),
+		})
+	}
+
+	return models.AccessibilityReport{Issues: c.issues}
+}
+
+// report records a rule violation using the first offending element as the sample
+func (c *a11yChecker) report(rule string, offenders *goquery.Selection) {
+	if offenders.Length() == 0 {
+		return
+	}
+	c.issues = append(c.issues, models.AccessibilityIssue{
+		Rule:           rule,
+		Count:          offenders.Length(),
+		SampleSelector: c.selectorFor(offenders.First()),
+	})
+}
+
+// findDuplicateIDs returns the first element of every ID that is used more than once
+func (c *a11yChecker) findDuplicateIDs() *goquery.Selection {
+	seen := make(map[string]int)
+	c.doc.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		if id := s.AttrOr("id", ""); id != "" {
+			seen[id]++
+		}
+	})
+
+	return c.doc.Find("[id]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		id := s.AttrOr("id", "")
+		if seen[id] < 2 || c.duplicateIDs[id] {
+			return false
+		}
+		c.duplicateIDs[id] = true
+		return true
+	})
+}
+
+// checkImages flags images without an alt attribute. An empty alt marks a decorative image and passes.
+func (c *a11yChecker) checkImages() {
+	c.report(constants.A11yRuleImageMissingAlt, c.images.Find("img:not([alt])"))
+}
+
+// checkInputLabels flags form controls that have no label[for], wrapping label or ARIA label
+func (c *a11yChecker) checkInputLabels() {
+	controls := c.doc.Find("input, select, textarea").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		if goquery.NodeName(s) == "input" {
+			switch strings.ToLower(s.AttrOr("type", "text")) {
+			case "hidden", "submit", "reset", "button", "image":
+				return false
+			}
+		}
+		if hasNonEmptyAttr(s, "aria-label") || c.labelledBy(s) || hasNonEmptyAttr(s, "title") {
+			return false
+		}
+		if id := s.AttrOr("id", ""); id != "" && c.labelFor[id] {
+			return false
+		}
+		return s.Closest("label").Length() == 0
+	})
+	c.report(constants.A11yRuleInputMissingLabel, controls)
+}
+
+// checkLinks flags links with no accessible text and links whose text is generic, e.g. "click here"
+func (c *a11yChecker) checkLinks() {
+	links := c.doc.Find("a[href]")
+
+	c.report(constants.A11yRuleLinkEmptyText, links.FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return c.accessibleName(s) == ""
+	}))
+
+	c.report(constants.A11yRuleLinkGenericText, links.FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return isGenericLinkText(c.accessibleName(s), c.generic)
+	}))
+}
+
+// checkDocumentLang flags documents whose root element does not declare a language
+func (c *a11yChecker) checkDocumentLang() {
+	root := c.doc.Find("html").First()
+	if !hasNonEmptyAttr(root, "lang") && !hasNonEmptyAttr(root, "xml:lang") {
+		c.report(constants.A11yRuleMissingLang, root)
+	}
+}
+
+// checkButtons flags buttons that a screen reader would announce without a name
+func (c *a11yChecker) checkButtons() {
+	buttons := c.doc.Find("button, input[type='button'], input[type='image']").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		if goquery.NodeName(s) == "input" {
+			attr := "value"
+			if strings.EqualFold(s.AttrOr("type", ""), "image") {
+				attr = "alt"
+			}
+			return !hasNonEmptyAttr(s, attr) && !hasNonEmptyAttr(s, "aria-label") && !c.labelledBy(s)
+		}
+		return c.accessibleName(s) == ""
+	})
+	c.report(constants.A11yRuleButtonMissingName, buttons)
+}
+
+// accessibleName approximates the accessible name of an element from ARIA attributes, text and image alts
+func (c *a11yChecker) accessibleName(s *goquery.Selection) string {
+	if label := cleanText(s.AttrOr("aria-label", "")); label != "" {
+		return label
+	}
+	if c.labelledBy(s) {
+		var names []string
+		for _, id := range strings.Fields(s.AttrOr("aria-labelledby", "")) {
+			names = append(names, selectionText(c.findByID(id)))
+		}
+		return strings.Join(names, " ")
+	}
+	if text := selectionText(s); text != "" {
+		return text
+	}
+
+	var alts []string
+	s.Find("img[alt]").Each(func(_ int, img *goquery.Selection) {
+		if alt := cleanText(img.AttrOr("alt", "")); alt != "" {
+			alts = append(alts, alt)
+		}
+	})
+	if len(alts) > 0 {
+		return strings.Join(alts, " ")
+	}
+
+	return strings.TrimSpace(s.AttrOr("title", ""))
+}
+
+// labelledBy reports whether aria-labelledby references at least one element with text
+func (c *a11yChecker) labelledBy(s *goquery.Selection) bool {
+	for _, id := range strings.Fields(s.AttrOr("aria-labelledby", "")) {
+		if strings.TrimSpace(c.findByID(id).Text()) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// findByID finds elements by ID without building a selector, so arbitrary ID values are safe
+func (c *a11yChecker) findByID(id string) *goquery.Selection {
+	return c.doc.Find("[id]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.AttrOr("id", "") == id
+	})
+}
+
+// selectorFor builds a CSS selector locating the element, anchored at the nearest unique ID
+func (c *a11yChecker) selectorFor(s *goquery.Selection) string {
+	var parts []string
+	for node := s; node.Length() > 0; node = node.Parent() {
+		tag := goquery.NodeName(node)
+
+		if id := node.AttrOr("id", ""); id != "" && !c.duplicateIDs[id] {
+			parts = append(parts, tag+idSelector(id))
+			break
+		}
+
+		if tag == "html" || tag == "body" || tag == "head" {
+			parts = append(parts, tag)
+			if tag == "html" {
+				break
+			}
+			continue
+		}
+
+		sameTag := node.Parent().ChildrenFiltered(tag)
+		if sameTag.Length() > 1 {
+			tag = fmt.Sprintf("%s:nth-of-type(%d)", tag, sameTag.IndexOfSelection(node)+1)
+		}
+		parts = append(parts, tag)
+	}
+
+	// Parts were collected from the element upwards
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// idSelector returns an ID selector, falling back to an attribute selector for IDs that need escaping
+func idSelector(id string) string {
+	if simpleIDPattern.MatchString(id) {
+		return "#" + id
+	}
+	return fmt.Sprintf("[id=%q]", id)
+}
+
+// isGenericLinkText reports whether link text is one of the non-descriptive phrases, which are
+// normalized by normalizeGenericPhrases
+func isGenericLinkText(text string, phrases []string) bool {
+	return text != "" && slices.Contains(phrases, normalizeLinkText(text))
+}
+
+// normalizeLinkText lowercases link text and trims the punctuation and arrows around it
+func normalizeLinkText(text string) string {
+	return strings.Trim(strings.ToLower(text), " .!?:…>»→")
+}
+
+// normalizeGenericPhrases normalizes configured generic phrases like the link texts they are compared to
+func normalizeGenericPhrases(phrases []string) []string {
+	normalized := make([]string, 0, len(phrases))
+	for _, phrase := range phrases {
+		if phrase = normalizeLinkText(cleanText(phrase)); phrase != "" {
+			normalized = append(normalized, phrase)
+		}
+	}
+	return normalized
+}
+
+// hasNonEmptyAttr reports whether the attribute is present with a non-blank value
+func hasNonEmptyAttr(s *goquery.Selection, attr string) bool {
+	return strings.TrimSpace(s.AttrOr(attr, "")) != ""
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// a11yPage wraps a body fragment in a document that passes the language check
+func a11yPage(body string) string {
+	return `<!DOCTYPE html><html lang="en"><head><title>Test</title></head><body>` + body + `</body></html>`
+}
+
+func TestAnalyzer_CheckAccessibility(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name     string
+		html     string
+		expected []models.AccessibilityIssue
+	}{
+		{
+			name: "Accessible page has no issues",
+			html: a11yPage(`
+				<img src="/logo.png" alt="Company logo">
+				<img src="/divider.png" alt="">
+				<form>
+					<label for="email">Email</label><input id="email" type="email">
+					<label>Password <input type="password"></label>
+					<input type="search" aria-label="Search">
+					<input type="hidden" name="csrf">
+					<textarea aria-labelledby="msg-label"></textarea><span id="msg-label">Message</span>
+					<button type="submit">Send</button>
+					<input type="submit">
+				</form>
+				<a href="/annual-report">Read the annual report</a>
+				<a href="/home"><img src="/home.png" alt="Home"></a>
+				<a href="/more" aria-label="More about pricing">More</a>
+			`),
+			expected: []models.AccessibilityIssue{},
+		},
+		{
+			name: "Image missing alt",
+			html: a11yPage(`<div><img src="/a.png" alt="A"><img src="/b.png"><img src="/c.png"></div>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleImageMissingAlt, Count: 2, SampleSelector: "html > body > div > img:nth-of-type(2)"},
+			},
+		},
+		{
+			name: "Inputs without labels",
+			html: a11yPage(`<form id="signup"><input type="text" name="user"><select name="plan"></select><label for="other">Other</label><input id="mismatch"></form>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleInputMissingLabel, Count: 3, SampleSelector: "form#signup > input:nth-of-type(1)"},
+			},
+		},
+		{
+			name: "Empty aria-label does not label an input",
+			html: a11yPage(`<input id="q" aria-label="  ">`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleInputMissingLabel, Count: 1, SampleSelector: "input#q"},
+			},
+		},
+		{
+			name: "Links with empty text",
+			html: a11yPage(`<nav><a href="/x"></a><a href="/y"><img src="/y.png" alt=""></a></nav>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleLinkEmptyText, Count: 2, SampleSelector: "html > body > nav > a:nth-of-type(1)"},
+			},
+		},
+		{
+			name: "Links with generic text",
+			html: a11yPage(`<p>For details <a href="/a">click here</a>.</p><p><a href="/b">Read more…</a></p><p><a href="/c">Here</a></p>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleLinkGenericText, Count: 3, SampleSelector: "html > body > p:nth-of-type(1) > a"},
+			},
+		},
+		{
+			name: "Missing document language",
+			html: `<!DOCTYPE html><html><head><title>Test</title></head><body><p>Hello</p></body></html>`,
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleMissingLang, Count: 1, SampleSelector: "html"},
+			},
+		},
+		{
+			name:     "XHTML xml:lang satisfies the language check",
+			html:     `<html xml:lang="en"><body><p>Hello</p></body></html>`,
+			expected: []models.AccessibilityIssue{},
+		},
+		{
+			name: "Buttons without accessible name",
+			html: a11yPage(`<div id="toolbar"><button><svg></svg></button><button aria-label="Close">×</button><input type="button"><input type="image" src="/go.png"></div>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleButtonMissingName, Count: 3, SampleSelector: "div#toolbar > button:nth-of-type(1)"},
+			},
+		},
+		{
+			name: "Duplicate IDs",
+			html: a11yPage(`<div id="main"></div><div id="main"><span id="x">1</span></div><span id="x">2</span><span id="unique">3</span>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleDuplicateID, Count: 2, SampleSelector: "#main"},
+			},
+		},
+		{
+			name: "Duplicate IDs are not used as selector anchors",
+			html: a11yPage(`<section id="dup"><img src="/a.png"></section><section id="dup"></section>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleImageMissingAlt, Count: 1, SampleSelector: "html > body > section:nth-of-type(1) > img"},
+				{Rule: constants.A11yRuleDuplicateID, Count: 1, SampleSelector: "#dup"},
+			},
+		},
+		{
+			name: "IDs needing escaping use attribute selectors",
+			html: a11yPage(`<div id="1st item"><img src="/a.png"></div>`),
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleImageMissingAlt, Count: 1, SampleSelector: `div[id="1st item"] > img`},
+			},
+		},
+		{
+			name: "Issues are reported in rule order",
+			html: `<html><body><button></button><a href="/"></a><img src="/a.png"><input name="q"><p id="d"></p><p id="d"></p></body></html>`,
+			expected: []models.AccessibilityIssue{
+				{Rule: constants.A11yRuleImageMissingAlt, Count: 1, SampleSelector: "html > body > img"},
+				{Rule: constants.A11yRuleInputMissingLabel, Count: 1, SampleSelector: "html > body > input"},
+				{Rule: constants.A11yRuleLinkEmptyText, Count: 1, SampleSelector: "html > body > a"},
+				{Rule: constants.A11yRuleMissingLang, Count: 1, SampleSelector: "html"},
+				{Rule: constants.A11yRuleButtonMissingName, Count: 1, SampleSelector: "html > body > button"},
+				{Rule: constants.A11yRuleDuplicateID, Count: 1, SampleSelector: "#d"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			report := analyzer.checkAccessibility(context.Background(), doc, domLimits{})
+			assert.Equal(t, tt.expected, report.Issues)
+		})
+	}
+}
+
+func TestIsGenericLinkText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"click here", true},
+		{"Click Here!", true},
+		{"Read more »", true},
+		{"learn more", true},
+		{"Read the annual report", false},
+		{"Here is the pricing page", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isGenericLinkText(tt.text, constants.DefaultGenericLinkTexts))
+		})
+	}
+}
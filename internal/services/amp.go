@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// isAMPDocument reports whether the root element carries the ⚡ or amp attribute
+func isAMPDocument(doc *goquery.Document) bool {
+	root := doc.Find("html").First()
+	if root.Length() == 0 {
+		return false
+	}
+	for _, attr := range root.Nodes[0].Attr {
+		if attr.Key == "⚡" || strings.EqualFold(attr.Key, "amp") {
+			return true
+		}
+	}
+	return false
+}
+
+// ampLink returns the href of the first amphtml link, or ""
+func ampLink(doc *goquery.Document) string {
+	href := ""
+	doc.Find("link[rel]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if value := strings.TrimSpace(s.AttrOr("href", "")); hasRelToken(s, "amphtml") && value != "" {
+			href = value
+			return false
+		}
+		return true
+	})
+	return href
+}
+
+// checkAMP reports the page's AMP variant, checking it through the link pool when checkLinks
+// is set, and for AMP documents their canonical page. Pages that are not AMP documents and
+// declare no amphtml link report nil.
+func (a *Analyzer) checkAMP(ctx context.Context, doc *goquery.Document, pageURL *url.URL, checkLinks bool) *models.AMP {
+	href := ampLink(doc)
+	isAMP := isAMPDocument(doc)
+	if href == "" && !isAMP {
+		return nil
+	}
+	amp := &models.AMP{IsAMP: isAMP}
+
+	if href != "" {
+		resolved, err := pageURL.Parse(href)
+		if err != nil || resolved.Host == "" {
+			amp.Error = fmt.Sprintf("amphtml URL %q is invalid", href)
+		} else {
+			amp.AMPURL = resolved.String()
+			if checkLinks {
+				amp.AMPAccessible = a.checkAMPLink(ctx, resolved, pageURL)
+			}
+		}
+	}
+
+	if isAMP {
+		if hrefs := canonicalLinks(doc); len(hrefs) > 0 {
+			canonical, err := pageURL.Parse(hrefs[0])
+			if err != nil || canonical.Host == "" {
+				amp.Error = fmt.Sprintf("canonical URL %q is invalid", hrefs[0])
+			} else {
+				amp.CanonicalURL = canonical.String()
+				amp.CanonicalCrossHost = !strings.EqualFold(canonical.Hostname(), pageURL.Hostname())
+			}
+		}
+	}
+	return amp
+}
+
+// checkAMPLink checks the AMP URL with the page's link checks and returns whether it is
+// accessible, or nil when the check could not run before the deadline or within the budget
+func (a *Analyzer) checkAMPLink(ctx context.Context, ampURL, pageURL *url.URL) *bool {
+	results := make(chan linkCheckResult, 1)
+	job := linkJob{
+		ctx:     ctx,
+		req:     linkCheckRequest{url: ampURL.String(), isInternal: a.isInternalLink(ctx, pageURL, ampURL), onPageHost: onPageHost(ampURL.String(), pageHost(pageURL))},
+		results: results,
+	}
+	if !a.linkPool.submit(ctx, job) {
+		return nil
+	}
+
+	result := <-results
+	if result.skipped || result.overBudget || result.rateLimited {
+		return nil
+	}
+	return &result.accessible
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_CheckAMP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing/amp" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	accessible, inaccessible := true, false
+
+	tests := []struct {
+		name       string
+		html       string
+		checkLinks bool
+		expected   *models.AMP
+	}{
+		{
+			name:       "Canonical page with an amphtml link",
+			html:       `<html><head><link rel="amphtml" href="/article/amp"></head></html>`,
+			checkLinks: true,
+			expected:   &models.AMP{AMPURL: server.URL + "/article/amp", AMPAccessible: &accessible},
+		},
+		{
+			name:       "Dead amphtml link",
+			html:       `<html><head><link rel="amphtml" href="/missing/amp"></head></html>`,
+			checkLinks: true,
+			expected:   &models.AMP{AMPURL: server.URL + "/missing/amp", AMPAccessible: &inaccessible},
+		},
+		{
+			name:     "Links not checked",
+			html:     `<html><head><link rel="amphtml" href="/article/amp"></head></html>`,
+			expected: &models.AMP{AMPURL: server.URL + "/article/amp"},
+		},
+		{
+			name:     "AMP page",
+			html:     `<!doctype html><html ⚡ lang="en"><head><link rel="canonical" href="/article"></head></html>`,
+			expected: &models.AMP{IsAMP: true, CanonicalURL: server.URL + "/article"},
+		},
+		{
+			name:     "AMP page with a canonical page on another host",
+			html:     `<html amp><head><link rel="canonical" href="https://www.example.com/article"></head></html>`,
+			expected: &models.AMP{IsAMP: true, CanonicalURL: "https://www.example.com/article", CanonicalCrossHost: true},
+		},
+		{
+			name:     "Invalid amphtml URL",
+			html:     `<html><head><link rel="amphtml" href="http://[::1"></head></html>`,
+			expected: &models.AMP{Error: `amphtml URL "http://[::1" is invalid`},
+		},
+		{
+			name: "Neither",
+			html: `<html><head><link rel="canonical" href="/article"></head></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := newTestAnalyzer(t, nil)
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			require.NoError(t, err)
+			pageURL, _ := url.Parse(server.URL + "/article")
+
+			assert.Equal(t, tt.expected, analyzer.checkAMP(context.Background(), doc, pageURL, tt.checkLinks))
+		})
+	}
+}
+
+func TestAnalyzer_Analyze_ReportsAMP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Article</title><link rel="amphtml" href="/article/amp"></head><body></body></html>`))
+	}))
+	defer server.Close()
+	analyzer := newTestAnalyzer(t, nil)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/article", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, result.AMP)
+	assert.Equal(t, server.URL+"/article/amp", result.AMP.AMPURL)
+	require.NotNil(t, result.AMP.AMPAccessible)
+	assert.True(t, *result.AMP.AMPAccessible)
+	assert.False(t, result.AMP.IsAMP)
+}
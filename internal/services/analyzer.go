@@ -1,18 +1,24 @@
 package services
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 
 	"github.com/webpage-analyser-server/internal/config"
 	"github.com/webpage-analyser-server/internal/constants"
@@ -22,15 +28,65 @@ import (
 
 // CacheInterface defines the interface for cache operations
 type CacheInterface interface {
-	Get(ctx context.Context, url string) (*models.AnalyzeResponse, error)
-	Set(ctx context.Context, url string, result *models.AnalyzeResponse) error
+	Get(ctx context.Context, url string) (*models.AnalyzeResponse, time.Duration, error)
+	// Set stores a result for ttl, or for the configured TTL when ttl is 0
+	Set(ctx context.Context, url string, result *models.AnalyzeResponse, ttl time.Duration) error
+	// GetMulti retrieves the results cached for urls, leaving out those without one
+	GetMulti(ctx context.Context, urls []string) (map[string]*models.AnalyzeResponse, error)
+	// SetMulti stores results by URL like Set; a KeyErrors error reports those not stored
+	SetMulti(ctx context.Context, results map[string]*models.AnalyzeResponse, ttl time.Duration) error
+	// Stats reports the lookups since the process started and the number of cached analyses
+	Stats(ctx context.Context) (*models.CacheStats, error)
 	Close() error
 }
 
+// AnalysisStore durably records completed analyses
+type AnalysisStore interface {
+	Save(ctx context.Context, result *models.AnalyzeResponse) error
+}
+
+// AnalysisStores records every analysis in each of its stores
+type AnalysisStores []AnalysisStore
+
+func (s AnalysisStores) Save(ctx context.Context, result *models.AnalyzeResponse) error {
+	var errs []error
+	for _, store := range s {
+		errs = append(errs, store.Save(ctx, result))
+	}
+	return errors.Join(errs...)
+}
+
 // linkCheckRequest represents a request to check a link's accessibility
 type linkCheckRequest struct {
 	url        string
 	isInternal bool
+	onPageHost bool // Links to the page's own host skip the host circuit and are not counted as external hosts
+}
+
+// fetchResult is a loaded page together with the response metadata analysis depends on
+type fetchResult struct {
+	body           string
+	finalURL       *url.URL // After redirects
+	statusCode     int
+	header         http.Header
+	renderedWithJS bool
+	notModified    bool // The target answered a conditional fetch with 304; body is empty
+	fetch          *models.FetchInfo // Nil for rendered pages
+}
+
+// linkCheckResult represents the outcome of a link check
+type linkCheckResult struct {
+	link       linkCheckRequest // The checked link, classified as when it was queued
+	accessible bool
+	status     int  // Status of the response that judged the link; 0 when none was received
+	skipped    bool // Not checked because the analysis deadline passed
+	circuitOpen bool // Not checked because the host's circuit was open
+	rateLimited bool // The target answered with 429, or 503 and Retry-After
+	overBudget  bool // Not checked because the outbound request budget was exhausted
+	blocked     bool // Not checked, or not followed to the end, because the target policy blocks the host
+	hostCapped  bool // Not followed to the end because a redirect reached a host beyond analyzer.max_external_hosts
+	redirect    *models.LinkRedirect // Set when the link redirected
+	failure     string // Category of the failure of an inaccessible link
 }
 
 // Analyzer handles webpage analysis
@@ -38,118 +94,769 @@ type Analyzer struct {
 	logger     *zap.Logger
 	metrics    *metrics.Metrics
 	httpClient *http.Client
+	linkClient *http.Client // External link checks, with their own redirect policy
 	cache      CacheInterface
-	config     *config.Config
+	options    analyzerOptions // Resolved configuration; the configuration passed in is never read again
+	renderer   Renderer
+	openHosts  *openHostCache // Hosts with an open link circuit, shared across analyses
+	policy     *targetPolicy  // Hosts requests may be sent to; nil allows all
+	linkOverrides linkCheckOverrides // Per-host changes to link checks, most specific first
+	errorTitles *errorTitleCache // Titles of the error pages seen per host, shared across analyses
+	outboundLimiter *rate.Limiter // Global outbound request rate, shared across analyses
+	store      AnalysisStore // Optional durable record of completed analyses
+	snapshots  SnapshotStore // Pages of analyses run with options.store_snapshot; nil when disabled
+	linkPool   *linkPool     // Link check workers, shared across analyses
+	targetLimiter *targetLimiter // Page fetches per target host, shared across clients; nil when unlimited
+	egress     *egressQuota  // Bytes fetched per client and day; nil when not counted
+	proxies    proxyPools    // Egress proxies options.egress selects, by name
+	lookupHost func(ctx context.Context, host string) ([]string, error) // Resolves hosts for URL validation
 }
 
 
 func NewAnalyzer(cfg *config.Config, logger *zap.Logger, metrics *metrics.Metrics, cache CacheInterface) *Analyzer {
-	if cfg.Analyzer.MaxLinks == 0 {
-		cfg.Analyzer.MaxLinks = constants.DefaultMaxLinks
-	}
-	if cfg.Analyzer.LinkTimeout == 0 {
-		cfg.Analyzer.LinkTimeout = constants.DefaultLinkTimeout
-	}
-	if cfg.Analyzer.MaxWorkers == 0 {
-		cfg.Analyzer.MaxWorkers = constants.DefaultMaxWorkers
+	options := resolveOptions(cfg)
+
+	var openHosts *openHostCache
+	if options.LinkCircuit.Enabled && options.LinkCircuit.SharedTTL > 0 {
+		openHosts = newOpenHostCache(options.LinkCircuit.SharedTTL)
 	}
-	if cfg.Analyzer.MaxRedirects == 0 {
-		cfg.Analyzer.MaxRedirects = constants.DefaultMaxRedirects
+
+	var renderer Renderer
+	if options.JSRendering.Enabled && options.JSRendering.Endpoint != "" {
+		renderer = NewChromeRenderer(options.JSRendering, logger)
 	}
 
-	return &Analyzer{
+	policy := newTargetPolicy(options.TargetPolicy, logger, metrics)
+	proxies := newProxyPools(options.Proxies, logger)
+
+	analyzer := &Analyzer{
 		logger:  logger,
 		metrics: metrics,
 		httpClient: &http.Client{
-			Timeout: cfg.Analyzer.LinkTimeout,
+			Transport: newResolvingTransport(newTransport(options.Timeouts.Page), proxies),
+			Timeout:   options.Timeouts.Page.Overall,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= cfg.Analyzer.MaxRedirects {
+				if len(via) >= options.MaxRedirects {
 					return http.ErrUseLastResponse
 				}
-				return nil
+				if err := checkRedirectLoop(req, via); err != nil {
+					return err
+				}
+				if err := policy.check(req.Context(), req.URL); err != nil {
+					return err
+				}
+				// Credentials follow redirects on the analyzed host only
+				credentialsFrom(req.Context()).apply(req)
+				// Every followed redirect is another outbound request
+				return budgetFrom(req.Context()).acquire(req.Context())
 			},
 		},
-		cache:  cache,
-		config: cfg,
+		cache:     cache,
+		options:   options,
+		renderer:  renderer,
+		openHosts: openHosts,
+		policy:    policy,
+		proxies:   proxies,
+		linkOverrides: newLinkCheckOverrides(options.LinkCheckOverrides),
+		errorTitles: newErrorTitleCache(options.Soft404.ErrorTitleTTL),
+		outboundLimiter: newOutboundLimiter(options.Budget.GlobalRequestsPerSecond, options.Budget.GlobalBurst),
+		targetLimiter: newTargetLimiter(options.RateLimit),
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+	analyzer.linkClient = &http.Client{
+		Transport:     newResolvingTransport(newTransport(options.Timeouts.Links), proxies),
+		Timeout:       options.Timeouts.Links.Overall,
+		CheckRedirect: analyzer.followLinkRedirect,
 	}
+	analyzer.linkPool = newLinkPool(options.LinkPoolSize, analyzer.checkQueuedLink)
+	return analyzer
+}
+
+// newTransport returns a transport bounding the connect, TLS handshake and response header
+// stages of its requests by timeouts
+func newTransport(timeouts config.FetchTimeouts) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Negotiate HTTP/2 even when TLS settings are customized
+	transport.ForceAttemptHTTP2 = true
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeouts.Connect,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = timeouts.TLSHandshake
+	transport.ResponseHeaderTimeout = timeouts.ResponseHeader
+	return transport
 }
 
 // Analyze performs the webpage analysis
 func (a *Analyzer) Analyze(ctx context.Context, targetURL string) (*models.AnalyzeResponse, error) {
-	// Check cache first
-	if result, err := a.cache.Get(ctx, targetURL); err != nil {
-		a.logger.Error("Failed to get from cache", zap.Error(err))
-	} else if result != nil {
-		return result, nil
+	return a.AnalyzeWithOptions(ctx, targetURL, models.AnalyzeOptions{})
+}
+
+// AnalyzeWithOptions performs the webpage analysis with per-request options
+func (a *Analyzer) AnalyzeWithOptions(ctx context.Context, targetURL string, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error) {
+	ctx, analysisID := a.withAnalysisLogger(ctx, targetURL)
+	ctx, stats := a.withStats(ctx)
+	ctx, warnings := withPolicyWarnings(ctx)
+	// Problems serving this request, such as a cache failure, are reported but never cached
+	ctx, requestWarnings := withWarnings(ctx)
+	result, err := a.analyzeURL(ctx, targetURL, opts)
+	a.egress.record(ctx, stats)
+	if err != nil {
+		return nil, err
 	}
+	// Set once the result is cached, so cached results never carry the stats, warnings or ID of another run
+	result.AnalysisID = analysisID
+	if opts.IncludeStats {
+		result.Stats = stats.report()
+	}
+	result.PolicyWarnings = warnings.list()
+	result.Warnings = append(result.Warnings, requestWarnings.list()...)
+	result.Links.Raw = pageRawLinks(result.Links.Raw, opts.RawLinksOffset, opts.RawLinksLimit)
+	return result, nil
+}
+
+// analyzeURL fetches and analyzes the webpage, serving it from the cache when possible
+func (a *Analyzer) analyzeURL(ctx context.Context, targetURL string, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error) {
+	ctx, options := a.withOptions(ctx)
+	ctx, cancel := context.WithTimeout(ctx, options.AnalysisTimeout)
+	defer cancel()
 
 	// Parse and validate URL
 	parsedURL, err := a.parseAndValidateURL(targetURL)
 	if err != nil {
 		return nil, err
 	}
-
-	// Fetch webpage content
-	htmlContent, err := a.fetchWebpage(targetURL)
+	if err := a.policy.check(ctx, parsedURL); err != nil {
+		return nil, targetBlockedError(err)
+	}
+	ctx, parsedURL, opts, err = a.prepareAnalysis(ctx, parsedURL, opts)
 	if err != nil {
 		return nil, err
 	}
+	defer resolveOverrideFrom(ctx).close()
+	// Credentials in the URL are sent with the fetch but never logged, cached or echoed.
+	// The response echoes the URL as requested, Unicode hostnames included.
+	targetURL = models.StripCredentials(targetURL)
+	key := cacheKey(canonicalURL(parsedURL), opts)
+	// A snapshot must be of the page the returned analysis saw, so the page is fetched again
+	snapshot := a.snapshotWanted(ctx, parsedURL, opts)
+
+	// Check cache first; a forced refresh only uses the cached result to revalidate it
+	lookupStart := time.Now()
+	cached, cachedTTL, err := a.cache.Get(ctx, key)
+	if err != nil {
+		a.log(ctx).Error("Failed to get from cache", zap.Error(err))
+		warningsFrom(ctx).add(constants.WarningCodeCacheReadFailed, "The cache could not be read; the page was analyzed again")
+	} else if cached != nil && !opts.ForceRefresh && !snapshot {
+		servedIn := time.Since(lookupStart).Milliseconds()
+		cached.URL = targetURL
+		cached.CacheTTL = cachedTTL
+		cached.ServedFromCacheInMs = &servedIn
+		return cached, nil
+	}
+
+	// Cached results are always served; fetching the page again counts against the client's
+	// daily bytes and its host's limit
+	if err := a.egress.allow(ctx); err != nil {
+		a.log(ctx).Warn("Client egress quota exceeded", zap.String("client", clientFrom(ctx)))
+		return nil, err
+	}
+	if err := a.targetLimiter.allow(parsedURL.Hostname()); err != nil {
+		a.log(ctx).Warn("Target host analysis limit exceeded", zap.String("host", parsedURL.Hostname()))
+		return nil, err
+	}
+
+	ctx, budget := a.withBudget(ctx)
+	defer budget.observe()
+	// Problems of the analysis itself are cached with the result
+	requestWarnings := warningsFrom(ctx)
+	ctx, warnings := withWarnings(ctx)
+
+	// Fetch webpage content, rendering it in a browser when requested.
+	// Error pages are kept so that bot-protection challenges can be recognized.
+	start := time.Now()
+	validators := validatorsOf(cached)
+	if snapshot {
+		// A 304 carries no body to store
+		validators = nil
+	}
+	page, fetchErr := a.loadPage(ctx, parsedURL.String(), opts, validators)
+	if errors.Is(fetchErr, errTargetBlocked) {
+		return nil, targetBlockedError(fetchErr)
+	}
+	if errors.Is(fetchErr, errRedirectLoop) {
+		return nil, redirectLoopError(fetchErr)
+	}
+	if page == nil {
+		return nil, fetchErr
+	}
+	if page.notModified {
+		return a.revalidated(ctx, key, targetURL, cached, requestWarnings), nil
+	}
+	if page.fetch != nil && page.fetch.Truncated {
+		a.log(ctx).Warn("Analyzing a truncated page body",
+			zap.String("url", targetURL),
+			zap.String("truncated_by", page.fetch.TruncatedBy),
+			zap.Int("received_bytes", len(page.body)),
+		)
+		warnings.add(constants.WarningCodeBodyTruncated, fmt.Sprintf("The page body was cut short by the %s; the %d bytes received were analyzed", strings.ReplaceAll(page.fetch.TruncatedBy, "_", " "), len(page.body)))
+	}
+	fetchDuration := time.Since(start)
 
 	// Parse HTML document
-	doc, err := a.parseHTML(htmlContent)
+	parseStart := time.Now()
+	doc, err := a.parseHTML(page.body)
 	if err != nil {
 		return nil, err
 	}
+	parseDuration := time.Since(parseStart)
+
+	// Challenge pages must never be analyzed as if they were the real content
+	provider := detectBotProtection(page, doc)
+	if provider != "" {
+		a.log(ctx).Warn("Bot protection challenge detected",
+			zap.String("url", targetURL),
+			zap.String("provider", provider),
+			zap.Int("status", page.statusCode),
+		)
+		if options.BotProtection.Action != constants.BotProtectionActionTag {
+			return nil, &AnalysisError{
+				Code:    constants.ErrorCodeBotProtection,
+				Status:  constants.StatusBadGateway,
+				Message: fmt.Sprintf("Target returned a %s bot-protection challenge instead of its content", provider),
+				Err:     fetchErr,
+			}
+		}
+	} else if isRateLimited(page.statusCode, page.header) {
+		retryAfter, _ := parseRetryAfter(page.header.Get(constants.HeaderRetryAfter), time.Now())
+		a.log(ctx).Warn("Target rate limited the page fetch",
+			zap.String("url", targetURL),
+			zap.Int("status", page.statusCode),
+			zap.Duration("retry_after", retryAfter),
+		)
+		return nil, &AnalysisError{
+			Code:       constants.ErrorCodeTargetRateLimited,
+			Status:     constants.StatusServiceUnavailable,
+			Message:    "Target is rate limiting requests",
+			Err:        fetchErr,
+			RetryAfter: retryAfter,
+		}
+	} else if fetchErr != nil {
+		// A real error page teaches what the host's soft 404 pages look like
+		a.rememberErrorTitle(page, doc, parsedURL)
+		return nil, fetchErr
+	}
+	if snapshot {
+		a.saveSnapshot(ctx, parsedURL, page, requestWarnings)
+	}
+	// Sitemaps and feeds are summarized instead of analyzed as HTML
+	if result := a.analyzeXML(ctx, targetURL, page); result != nil {
+		result.Phases.FetchMs = fetchDuration.Milliseconds()
+		result.DurationMs = time.Since(start).Milliseconds()
+		result.Warnings = warnings.list()
+		result.Cookies = auditResponseCookies(page.header, page.finalURL, time.Now())
+		a.fillFetchInfo(ctx, result, page)
+		opts.RenderJS = page.renderedWithJS
+		a.cacheResult(ctx, parsedURL, opts, result, requestWarnings)
+		return result, nil
+	}
+	empty := a.checkEmptyDocument(ctx, page.body, doc)
+	if empty != nil && !opts.AllowEmpty {
+		a.log(ctx).Warn("Target returned an empty document", zap.String("url", targetURL), zap.Error(empty))
+		return nil, empty
+	}
+
+	// Probe the origin variants while the page is analyzed
+	var originChecks chan *models.OriginChecks
+	if opts.OriginChecks {
+		originChecks = make(chan *models.OriginChecks, 1)
+		go func() {
+			originChecks <- a.checkOrigin(ctx, parsedURL)
+		}()
+	}
+
+	// Fetch the mobile variant while the page is analyzed, under the same deadline and budget
+	var mobileComparison chan *models.MobileComparison
+	if opts.CompareMobile {
+		mobileComparison = make(chan *models.MobileComparison, 1)
+		go func() {
+			mobileComparison <- a.compareMobile(ctx, parsedURL.String(), doc)
+		}()
+	}
 
 	// Perform comprehensive analysis
-	result := a.performWebpageAnalysis(ctx, targetURL, htmlContent, doc, parsedURL)
+	fetchedURL := parsedURL
+	if page.finalURL != nil {
+		fetchedURL = withoutCredentials(page.finalURL)
+	}
+	result := a.performWebpageAnalysis(ctx, targetURL, page.body, doc, parsedURL, fetchedURL, opts)
+	result.RenderedWithJS = page.renderedWithJS
+	addCSPConflicts(&result.CSPReadiness, page.header)
+	result.CanonicalConsistency = a.checkCanonicalConsistency(ctx, doc, fetchedURL)
+	if opts.CompareCanonical {
+		result.CanonicalSimilarity = a.compareCanonical(ctx, doc, fetchedURL)
+	}
+	result.AMP = a.checkAMP(ctx, doc, fetchedURL, !opts.SkipLinkCheck)
+	result.PWA = a.checkPWA(ctx, doc, fetchedURL, opts.FetchManifest)
+	if reason := a.detectSoft404(ctx, doc, parsedURL); reason != "" {
+		a.log(ctx).Warn("Soft 404 suspected", zap.String("url", targetURL), zap.String("reason", reason))
+		result.Soft404Suspected = true
+		result.Soft404Reason = reason
+	}
+	if originChecks != nil {
+		result.OriginChecks = <-originChecks
+	}
+	if mobileComparison != nil {
+		result.MobileComparison = <-mobileComparison
+	}
+	result.Phases.FetchMs = fetchDuration.Milliseconds()
+	result.Phases.ParseMs = parseDuration.Milliseconds()
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.Warnings = warnings.list()
+	if result.Partial {
+		// Partial results are returned on request but never cached
+		return a.partialResult(ctx, result, opts)
+	}
+	result.Cookies = auditResponseCookies(page.header, page.finalURL, time.Now())
+	a.fillFetchInfo(ctx, result, page)
+	if provider != "" {
+		// Tagged challenge results are returned but not cached
+		result.BotProtectionDetected = true
+		result.BotProtectionProvider = provider
+		return result, nil
+	}
+	if result.Soft404Suspected {
+		// A missing page must not be served from the cache as if it were healthy
+		return result, nil
+	}
+	if empty != nil {
+		// Analyses of empty documents are returned on request but never cached
+		return result, nil
+	}
+	if result.Links.RateLimited > 0 {
+		// Rate-limited link checks say nothing lasting about the links; check them again next time
+		return result, nil
+	}
+
+	// Cache the result under the variant that was actually produced
+	opts.RenderJS = page.renderedWithJS
+	a.cacheResult(ctx, parsedURL, opts, result, requestWarnings)
+	return result, nil
+}
+
+// prepareAnalysis checks the credentials, egress and resolve override of an analysis of
+// target and attaches them, and the other per-analysis options, to the context. It returns
+// target without its URL credentials, which from here on travel in the context only so no URL
+// resolved against the page carries them, and opts as the analysis runs with them. The caller
+// closes the resolve override of the returned context once the analysis is done.
+func (a *Analyzer) prepareAnalysis(ctx context.Context, target *url.URL, opts models.AnalyzeOptions) (context.Context, *url.URL, models.AnalyzeOptions, error) {
+	if err := a.checkCredentialsAllowed(ctx, target, opts.Auth); err != nil {
+		return nil, nil, opts, err
+	}
+	opts.Auth = withURLCredentials(target, opts.Auth)
+	var err error
+	if opts.Egress, err = a.selectEgress(opts.Egress); err != nil {
+		return nil, nil, opts, err
+	}
+	override, err := a.resolveOverride(ctx, target, opts.Resolve)
+	if err != nil {
+		return nil, nil, opts, err
+	}
+	ctx = withResolveOverride(ctx, override)
+	ctx = withEgress(ctx, opts.Egress)
+	ctx = withCredentials(ctx, target, opts.Auth)
+	ctx = withAcceptLanguage(ctx, target, opts.AcceptLanguage)
+	opts.SamplingSeed = samplingSeedFor(opts, a.optionsFor(ctx))
+	ctx = withSamplingSeed(ctx, opts.SamplingSeed)
+	return ctx, withoutCredentials(target), opts, nil
+}
+
+// fillFetchInfo reports how the page was fetched, with the outbound requests the analysis
+// running under ctx sent
+func (a *Analyzer) fillFetchInfo(ctx context.Context, result *models.AnalyzeResponse, page *fetchResult) {
+	if page.fetch != nil {
+		page.fetch.OutboundRequests = budgetFrom(ctx).consumed()
+		page.fetch.OutboundBudget = a.optionsFor(ctx).Budget.MaxOutboundRequestsPerAnalysis
+		page.fetch.Egress = egressFrom(ctx)
+	}
+	result.Fetch = page.fetch
+}
+
+// cacheResult caches and stores a complete result, under the cache key of opts
+func (a *Analyzer) cacheResult(ctx context.Context, parsedURL *url.URL, opts models.AnalyzeOptions, result *models.AnalyzeResponse, requestWarnings *analysisWarnings) {
+	options := a.optionsFor(ctx)
+	// Links that failed temporarily are checked again sooner
+	ttl := a.cacheTTL(ctx, result)
+	if err := a.cache.Set(ctx, cacheKey(canonicalURL(parsedURL), opts), result, ttl); err != nil {
+		a.log(ctx).Error("Failed to cache result", zap.Error(err))
+		requestWarnings.add(constants.WarningCodeCacheWriteFailed, "The result could not be cached")
+	} else if options.Cache.Enabled {
+		result.CacheTTL = cmp.Or(ttl, options.Cache.TTL)
+	}
+	if err := a.persist(ctx, result); err != nil {
+		requestWarnings.add(constants.WarningCodeStorageWriteFailed, "The result could not be stored")
+	}
+}
+
+// revalidated returns the cached result of a page the target confirmed unchanged, caching it
+// for another TTL
+func (a *Analyzer) revalidated(ctx context.Context, key, targetURL string, cached *models.AnalyzeResponse, requestWarnings *analysisWarnings) *models.AnalyzeResponse {
+	options := a.optionsFor(ctx)
+	a.log(ctx).Debug("Page not modified, keeping the cached analysis", zap.String("url", targetURL))
+	ttl := a.cacheTTL(ctx, cached)
+	if err := a.cache.Set(ctx, key, cached, ttl); err != nil {
+		a.log(ctx).Error("Failed to cache result", zap.Error(err))
+		requestWarnings.add(constants.WarningCodeCacheWriteFailed, "The result could not be cached")
+	} else if options.Cache.Enabled {
+		cached.CacheTTL = cmp.Or(ttl, options.Cache.TTL)
+	}
+	cached.URL = targetURL
+	cached.Revalidated = true
+	return cached
+}
+
+// SetStore records every completed analysis in store from now on
+func (a *Analyzer) SetStore(store AnalysisStore) {
+	a.store = store
+}
+
+// persist saves a completed analysis to the store, if any. Failures are logged and do not fail the analysis.
+func (a *Analyzer) persist(ctx context.Context, result *models.AnalyzeResponse) error {
+	if a.store == nil {
+		return nil
+	}
+
+	// The write must not be cut short by the analysis deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constants.StorageWriteTimeout)
+	defer cancel()
+	if err := a.store.Save(ctx, result); err != nil {
+		a.log(ctx).Error("Failed to store analysis", zap.String("url", result.URL), zap.Error(err))
+		return err
+	}
+	return nil
+}
 
-	// Cache the result
-	if err := a.cache.Set(ctx, targetURL, result); err != nil {
-		a.logger.Error("Failed to cache result", zap.Error(err))
+// Close waits for running link checks and releases resources held by the analyzer
+func (a *Analyzer) Close() error {
+	a.linkPool.close()
+	if a.renderer != nil {
+		return a.renderer.Close()
 	}
+	return nil
+}
 
+// AnalyzeHTML analyzes HTML content supplied by the caller instead of fetching it.
+// baseURL is used to resolve and classify links; results are never cached.
+func (a *Analyzer) AnalyzeHTML(ctx context.Context, htmlContent, baseURL string, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error) {
+	ctx, analysisID := a.withAnalysisLogger(ctx, baseURL)
+	ctx, options := a.withOptions(ctx)
+	parsedURL, err := a.parseAndValidateURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, parsedURL, opts, err = a.prepareAnalysis(ctx, parsedURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resolveOverrideFrom(ctx).close()
+	ctx, stats := a.withStats(ctx)
+	// The submitted page is not fetched, but its links are checked under the policy
+	ctx, warnings := withPolicyWarnings(ctx)
+	ctx, analysisWarnings := withWarnings(ctx)
+
+	if int64(len(htmlContent)) > options.MaxBodyBytes {
+		return nil, fmt.Errorf("HTML content exceeds maximum size of %d bytes", options.MaxBodyBytes)
+	}
+	// The submitted page costs nothing, but its link checks fetch bytes for the client
+	if err := a.egress.allow(ctx); err != nil {
+		a.log(ctx).Warn("Client egress quota exceeded", zap.String("client", clientFrom(ctx)))
+		return nil, err
+	}
+	defer a.egress.record(ctx, stats)
+
+	start := time.Now()
+	doc, err := a.parseHTML(htmlContent)
+	if err != nil {
+		return nil, err
+	}
+	parseDuration := time.Since(start)
+
+	ctx, cancel := context.WithTimeout(ctx, options.AnalysisTimeout)
+	defer cancel()
+	ctx, budget := a.withBudget(ctx)
+	defer budget.observe()
+
+	result := a.performWebpageAnalysis(ctx, models.StripCredentials(baseURL), htmlContent, doc, parsedURL, parsedURL, opts)
+	result.CanonicalConsistency = a.checkCanonicalConsistency(ctx, doc, parsedURL)
+	if opts.CompareCanonical {
+		result.CanonicalSimilarity = a.compareCanonical(ctx, doc, parsedURL)
+	}
+	result.AMP = a.checkAMP(ctx, doc, parsedURL, !opts.SkipLinkCheck)
+	result.PWA = a.checkPWA(ctx, doc, parsedURL, opts.FetchManifest)
+	result.Phases.ParseMs = parseDuration.Milliseconds()
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.AnalysisID = analysisID
+	if opts.IncludeStats {
+		result.Stats = stats.report()
+	}
+	result.PolicyWarnings = warnings.list()
+	result.Warnings = analysisWarnings.list()
+	result.Links.Raw = pageRawLinks(result.Links.Raw, opts.RawLinksOffset, opts.RawLinksLimit)
+	if result.Partial {
+		return a.partialResult(ctx, result, opts)
+	}
 	return result, nil
 }
 
-// parseAndValidateURL parses and validates the target URL
+// partialResult returns an analysis cut short by the deadline when the caller allows partial
+// results, and an ANALYSIS_TIMEOUT error otherwise
+func (a *Analyzer) partialResult(ctx context.Context, result *models.AnalyzeResponse, opts models.AnalyzeOptions) (*models.AnalyzeResponse, error) {
+	a.log(ctx).Warn("Analysis deadline exceeded",
+		zap.String("url", result.URL),
+		zap.Strings("completed_sections", result.CompletedSections),
+		zap.Int("skipped_links", result.Links.Skipped[constants.LinkSkipReasonDeadline]),
+	)
+	if opts.AllowPartial {
+		return result, nil
+	}
+	return nil, &AnalysisError{
+		Code:    constants.ErrorCodeAnalysisTimeout,
+		Status:  constants.StatusGatewayTimeout,
+		Message: "Analysis did not complete before the deadline",
+		Err:     context.DeadlineExceeded,
+	}
+}
+
+// parseAndValidateURL parses and validates the target URL. Internationalized hostnames
+// are converted to punycode so that the returned URL can be fetched.
 func (a *Analyzer) parseAndValidateURL(targetURL string) (*url.URL, error) {
 	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 	
+	// Validate that the scheme is http or https; data: and file: URLs have no host
+	if parsedURL.Scheme != "" {
+		if urlErr := models.CheckScheme(parsedURL.Scheme); urlErr != nil {
+			return nil, &AnalysisError{Code: urlErr.Code, Status: constants.StatusBadRequest, Message: urlErr.Message}
+		}
+	}
+	
 	// Validate that the URL has a scheme and host
 	if parsedURL.Scheme == "" || parsedURL.Host == "" {
 		return nil, fmt.Errorf("invalid URL: missing scheme or host")
 	}
 	
-	// Validate that the scheme is http or https
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return nil, fmt.Errorf("invalid URL: unsupported scheme %s", parsedURL.Scheme)
+	if err := toASCIIHost(parsedURL); err != nil {
+		return nil, &AnalysisError{
+			Code:    constants.ErrorCodeInvalidHost,
+			Status:  constants.StatusBadRequest,
+			Message: "URL host is not a valid internationalized domain name",
+			Err:     err,
+		}
 	}
+	// https://example.com:443 is cached and classified as https://example.com
+	stripDefaultPort(parsedURL)
 	
 	return parsedURL, nil
 }
 
-// fetchWebpage fetches the webpage content via HTTP
-func (a *Analyzer) fetchWebpage(targetURL string) (string, error) {
-	resp, err := a.httpClient.Get(targetURL)
+// loadPage returns the page, rendered with JavaScript when requested and available.
+// Rendering failures fall back to a static fetch, as do pages fetched with credentials,
+// which the browser would not send, or from a pinned address.
+func (a *Analyzer) loadPage(ctx context.Context, targetURL string, opts models.AnalyzeOptions, validators *pageValidators) (*fetchResult, error) {
+	// The browser sends neither credentials nor the requested language, nor resolves as pinned
+	if opts.RenderJS && a.renderer != nil && opts.Auth == nil && opts.AcceptLanguage == "" && opts.Resolve == nil && opts.Egress == "" {
+		htmlContent, err := a.renderPage(ctx, targetURL)
+		if err == nil {
+			return &fetchResult{body: htmlContent, statusCode: constants.StatusOK, renderedWithJS: true}, nil
+		}
+		a.log(ctx).Warn("JavaScript rendering failed, falling back to static analysis",
+			zap.String("url", targetURL),
+			zap.Error(err),
+		)
+		warningsFrom(ctx).add(constants.WarningCodeJSRenderingFailed, "JavaScript rendering failed; the page was analyzed without it")
+	}
+
+	return a.fetchWebpage(ctx, targetURL, validators)
+}
+
+// renderPage renders the page through the configured renderer, recording duration and outcome
+func (a *Analyzer) renderPage(ctx context.Context, targetURL string) (string, error) {
+	options := a.optionsFor(ctx)
+	start := time.Now()
+	// The browser's own subresource requests are not counted; the navigation is
+	if err := budgetFrom(ctx).acquire(ctx); err != nil {
+		return "", err
+	}
+	htmlContent, err := a.renderer.Render(ctx, targetURL)
+	metrics.Observe(ctx, a.metrics.RenderDuration, time.Since(start).Seconds())
+
+	outcome := constants.RenderOutcomeSuccess
+	switch {
+	case errors.Is(err, ErrRenderTimeout):
+		outcome = constants.RenderOutcomeTimeout
+	case errors.Is(err, ErrRendererUnavailable):
+		outcome = constants.RenderOutcomeUnavailable
+	case err != nil:
+		outcome = constants.RenderOutcomeError
+	}
+	a.metrics.RenderTotal.WithLabelValues(outcome).Inc()
+
+	if err == nil && int64(len(htmlContent)) > options.MaxBodyBytes {
+		return "", fmt.Errorf("rendered page exceeds maximum size of %d bytes", options.MaxBodyBytes)
+	}
+	return htmlContent, err
+}
+
+// cacheKey returns the cache key for a URL analyzed with the given options;
+// options that change the result produce distinct keys
+func cacheKey(targetURL string, opts models.AnalyzeOptions) string {
+	var variants []string
+	if opts.RenderJS {
+		variants = append(variants, constants.CacheVariantRenderJS)
+	}
+	if opts.SkipLinkCheck {
+		variants = append(variants, constants.CacheVariantSkipLinkCheck)
+	}
+	if opts.OriginChecks {
+		variants = append(variants, constants.CacheVariantOriginChecks)
+	}
+	if opts.CompareMobile {
+		variants = append(variants, constants.CacheVariantCompareMobile)
+	}
+	if opts.FetchManifest {
+		variants = append(variants, constants.CacheVariantFetchManifest)
+	}
+	if opts.CompareCanonical {
+		variants = append(variants, constants.CacheVariantCompareCanonical)
+	}
+	if opts.IncludeHeadingText {
+		variants = append(variants, constants.CacheVariantHeadingText)
+	}
+	if opts.IncludeLinkDetails {
+		variants = append(variants, constants.CacheVariantLinkDetails)
+	}
+	// Every page of the raw links is served from the same entry
+	if opts.IncludeRawLinks {
+		variants = append(variants, constants.CacheVariantRawLinks)
+	}
+	if opts.AcceptLanguage != "" {
+		variants = append(variants, constants.CacheVariantAcceptLanguage+"="+normalizeAcceptLanguage(opts.AcceptLanguage))
+	}
+	if opts.Auth != nil {
+		variants = append(variants, authCacheVariant(opts.Auth))
+	}
+	if opts.SamplingSeed != nil {
+		variants = append(variants, constants.CacheVariantSamplingSeed+"="+strconv.FormatUint(*opts.SamplingSeed, 10))
+	}
+	if opts.Resolve != nil {
+		variants = append(variants, resolveCacheVariant(opts.Resolve))
+	}
+	if opts.Egress != "" {
+		variants = append(variants, constants.CacheVariantEgress+"="+strings.ToLower(strings.TrimSpace(opts.Egress)))
+	}
+	if opts.ScopeSelector != "" {
+		variants = append(variants, constants.CacheVariantScopeSelector+"="+url.QueryEscape(opts.ScopeSelector))
+	}
+	if len(variants) == 0 {
+		return targetURL
+	}
+	return targetURL + "|" + strings.Join(variants, ",")
+}
+
+// fetchWebpage fetches the webpage content via HTTP.
+// On a non-OK status the page is returned along with the error so callers can inspect it.
+// With validators the fetch is conditional, and a page unchanged since is returned without
+// a body and with notModified set.
+func (a *Analyzer) fetchWebpage(ctx context.Context, targetURL string, validators *pageValidators) (*fetchResult, error) {
+	return a.fetchWebpageAs(ctx, targetURL, "", validators)
+}
+
+// fetchWebpageAs fetches the webpage like fetchWebpage, sending userAgent unless it is empty
+func (a *Analyzer) fetchWebpageAs(ctx context.Context, targetURL, userAgent string, validators *pageValidators) (*fetchResult, error) {
+	options := a.optionsFor(ctx)
+	traceCtx, timings := withFetchTrace(ctx)
+	req, err := http.NewRequestWithContext(traceCtx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webpage: %w", err)
+	}
+	if userAgent != "" {
+		req.Header.Set(constants.HeaderUserAgent, userAgent)
+	}
+	// Negotiating compression ourselves stops the transport from decompressing transparently,
+	// so the transfer size and encoding can be reported
+	req.Header.Set(constants.HeaderAcceptEncoding, constants.AcceptEncodingSupported)
+	acceptLanguageFrom(ctx).apply(req)
+	credentialsFrom(ctx).apply(req)
+	validators.apply(req)
+
+	if err := budgetFrom(ctx).acquire(ctx); err != nil {
+		return nil, fmt.Errorf("failed to fetch webpage: %w", err)
+	}
+	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch webpage: %w", err)
+		return nil, fmt.Errorf("failed to fetch webpage: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != constants.StatusOK {
-		return "", fmt.Errorf("webpage returned status code %d", resp.StatusCode)
+	// Only a conditional fetch can be answered with 304, which has no body to decode
+	if validators != nil && resp.StatusCode == constants.StatusNotModified {
+		statsFrom(ctx).received(constants.FetchSourcePage, 0)
+		return &fetchResult{
+			finalURL:    resp.Request.URL,
+			statusCode:  resp.StatusCode,
+			header:      resp.Header,
+			notModified: true,
+			fetch:       newFetchInfo(resp, "", 0, 0),
+		}, nil
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	wire := &countingReader{r: resp.Body}
+	body, encoding, err := decodeBody(wire, resp.Header.Get(constants.HeaderContentEncoding))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	defer body.Close()
+
+	// Read incrementally, so the prefix received before a size limit or timeout is kept for
+	// pages that never stop streaming
+	var buf bytes.Buffer
+	_, readErr := buf.ReadFrom(io.LimitReader(body, options.MaxBodyBytes+1))
+	statsFrom(ctx).received(constants.FetchSourcePage, wire.n)
+	truncatedBy := ""
+	switch {
+	case readErr != nil && isTimeout(readErr):
+		truncatedBy = constants.FetchTruncatedByTimeout
+	case readErr != nil:
+		return nil, fmt.Errorf("failed to read response body: %w", readErr)
+	case int64(buf.Len()) > options.MaxBodyBytes:
+		truncatedBy = constants.FetchTruncatedBySizeLimit
+		buf.Truncate(int(options.MaxBodyBytes))
+	}
+	// A prefix is only worth analyzing once the whole head arrived
+	if truncatedBy != "" && !headReceived(buf.Bytes()) {
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", readErr)
+		}
+		return nil, fmt.Errorf("response body exceeds maximum size of %d bytes", options.MaxBodyBytes)
+	}
+
+	page := &fetchResult{
+		body:       buf.String(),
+		finalURL:   resp.Request.URL,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		fetch:      newFetchInfo(resp, encoding, wire.n, int64(buf.Len())),
+	}
+	page.fetch.Timings = timings.report()
+	page.fetch.Truncated = truncatedBy != ""
+	page.fetch.TruncatedBy = truncatedBy
+	if resp.StatusCode != constants.StatusOK {
+		return page, fmt.Errorf("webpage returned status code %d", resp.StatusCode)
 	}
 
-	return string(bodyBytes), nil
+	return page, nil
 }
 
 // parseHTML parses the HTML content into a goquery document
@@ -161,35 +868,144 @@ func (a *Analyzer) parseHTML(htmlContent string) (*goquery.Document, error) {
 	return doc, nil
 }
 
-// performWebpageAnalysis performs comprehensive analysis of the webpage
-func (a *Analyzer) performWebpageAnalysis(ctx context.Context, targetURL, htmlContent string, doc *goquery.Document, parsedURL *url.URL) *models.AnalyzeResponse {
+// performWebpageAnalysis performs comprehensive analysis of the webpage. pageURL is the URL
+// the document was served from, after redirects.
+func (a *Analyzer) performWebpageAnalysis(ctx context.Context, targetURL, htmlContent string, doc *goquery.Document, parsedURL, pageURL *url.URL, opts models.AnalyzeOptions) *models.AnalyzeResponse {
 	result := &models.AnalyzeResponse{
-		URL:        targetURL,
-		AnalyzedAt: time.Now(),
-		Headings:   make(map[string]int),
+		URL:         targetURL,
+		ContentKind: constants.ContentKindHTML,
+		AnalyzedAt:  time.Now(),
+		Headings:    make(map[string]int),
+		Cookies:     []models.CookieAudit{},
 	}
 
-	// Detect HTML version
-	result.HTMLVersion = a.detectHTMLVersion(htmlContent)
-
-	// Extract page title
-	result.Title = a.extractPageTitle(doc)
-
-	// Count headings
-	result.Headings = a.countHeadings(doc)
+	// Extracted text is sanitized by cleanText, so the response and the cached result are valid JSON
+	if needsSanitizing(htmlContent) {
+		warningsFrom(ctx).add(constants.WarningCodeTextSanitized,
+			"The page contains invalid UTF-8 or control characters; they were replaced or stripped from the extracted text")
+	}
 
-	// Analyze links
-	result.Links = a.analyzeLinks(ctx, doc, parsedURL)
+	// Enormous documents are analyzed with bounded traversals
+	limits := a.domLimitsFor(ctx, doc)
+	if len(limits.limited) > 0 {
+		result.TruncatedAnalysis = true
+		result.LimitedSections = limits.limited
+		warningsFrom(ctx).add(constants.WarningCodeAnalysisTruncated,
+			fmt.Sprintf("The document is too large to analyze in full; limited sections: %s", strings.Join(limits.limited, ", ")))
+	}
+	limits.scope = a.selectScope(ctx, doc, opts.ScopeSelector)
+
+	// Sections run concurrently and accumulate into the result; see runSections. Link checking
+	// is the only slow section and is started last, so the others do not wait for a slot behind it.
+	sections := []analysisSection{
+		{name: constants.SectionHTMLVersion, run: func() bool {
+			result.HTMLVersion, result.RawDoctype = a.detectHTMLVersion(htmlContent)
+			result.RawDoctype = sanitizeText(result.RawDoctype)
+			return true
+		}},
+		{name: constants.SectionTitle, run: func() bool {
+			result.Title = a.extractPageTitle(doc)
+			result.MetaDescription = extractMetaDescription(doc)
+			return true
+		}},
+		{name: constants.SectionHeadings, run: func() bool {
+			result.Headings = a.countHeadings(limits.scoped(doc))
+			if opts.IncludeHeadingText {
+				result.HeadingText = a.extractHeadingText(limits.scoped(doc))
+			}
+			return true
+		}},
+		{name: constants.SectionDocumentIssues, run: func() bool {
+			result.DocumentIssues = a.checkDocumentIssues(htmlContent, doc)
+			return true
+		}},
+		{name: constants.SectionLoginForm, run: func() bool {
+			result.HasLoginForm = a.detectLoginForm(doc, limits)
+			return true
+		}},
+		{name: constants.SectionForms, run: func() bool {
+			result.Forms, result.Security = auditForms(doc, pageURL, limits)
+			return true
+		}},
+		{name: constants.SectionConsentBanner, run: func() bool {
+			result.ConsentBanner = a.detectConsentBanner(doc, limits)
+			return true
+		}},
+		{name: constants.SectionInterstitial, run: func() bool {
+			result.Interstitial = detectInterstitial(doc)
+			return true
+		}},
+		{name: constants.SectionPaywall, after: constants.SectionLoginForm, run: func() bool {
+			result.PaywallSuspected, result.PaywallEvidence = detectPaywall(doc, result.HasLoginForm)
+			return true
+		}},
+		{name: constants.SectionAccessibility, run: func() bool {
+			result.Accessibility = a.checkAccessibility(ctx, doc, limits)
+			return true
+		}},
+		{name: constants.SectionCSPReadiness, run: func() bool {
+			result.CSPReadiness = a.checkCSPReadiness(doc)
+			return true
+		}},
+		{name: constants.SectionResources, run: func() bool {
+			result.Resources = a.summarizeResources(doc)
+			return true
+		}},
+		{name: constants.SectionResourceHints, run: func() bool {
+			result.ResourceHints = auditResourceHints(doc, pageURL)
+			return true
+		}},
+		{name: constants.SectionLegacyIE, run: func() bool {
+			result.LegacyIE = a.detectLegacyIE(htmlContent, doc)
+			return true
+		}},
+		{name: constants.SectionLinks, run: func() bool {
+			start := time.Now()
+			defer func() { result.Phases.LinkCheckMs = time.Since(start).Milliseconds() }()
+
+			// Analyze links, optionally without outbound accessibility checks
+			if opts.SkipLinkCheck {
+				result.Links, _, _ = a.classifyLinks(ctx, doc, parsedURL, limits)
+			} else {
+				result.Links = a.analyzeLinks(ctx, doc, parsedURL, limits)
+			}
+			if !opts.IncludeLinkDetails {
+				result.Links.Details = nil
+				result.Links.Suspicious.Samples = nil
+				result.Links.AnchorTextStats.Offenders = nil
+				result.Links.OffOriginRedirects = nil
+			}
+			if !opts.IncludeRawLinks {
+				result.Links.Raw = nil
+			}
+			return result.Links.Skipped[constants.LinkSkipReasonDeadline] == 0
+		}},
+		{name: constants.SectionSEO, final: true, run: func() bool {
+			// Broken links are only known when they were checked
+			result.SEO = a.scoreSEO(ctx, doc, result, !opts.SkipLinkCheck)
+			return true
+		}},
+	}
 
-	// Check for login form
-	result.HasLoginForm = a.detectLoginForm(doc)
+	completed := a.runSections(ctx, sections)
+	if len(completed) < len(sections) {
+		result.Partial = true
+		result.CompletedSections = completed
+	}
 
 	return result
 }
 
-// extractPageTitle extracts the page title from the document
+// extractPageTitle extracts the page title from the document: the first title element, as
+// browsers show it, rather than every title including those of inline SVG images
 func (a *Analyzer) extractPageTitle(doc *goquery.Document) string {
-	return strings.TrimSpace(doc.Find("title").Text())
+	return cleanText(doc.Find("title").Not("svg title").First().Text())
+}
+
+// extractMetaDescription returns the cleaned content of the first meta description
+func extractMetaDescription(doc *goquery.Document) string {
+	description, _ := doc.Find("meta[name='description' i]").First().Attr("content")
+	return cleanText(description)
 }
 
 // countHeadings counts all heading elements (h1-h6) in the document
@@ -204,9 +1020,39 @@ func (a *Analyzer) countHeadings(doc *goquery.Document) map[string]int {
 	return headings
 }
 
-func (a *Analyzer) detectHTMLVersion(htmlContent string) string {
+// extractHeadingText returns the text of the headings of each level present, cleaned by
+// selectionText. Texts are truncated to constants.MaxHeadingTextLength characters and capped at
+// constants.MaxHeadingTextsPerLevel per level; empty headings are kept as empty strings.
+func (a *Analyzer) extractHeadingText(doc *goquery.Document) map[string][]string {
+	texts := make(map[string][]string)
+
+	for i := 1; i <= 6; i++ {
+		selector := fmt.Sprintf("h%d", i)
+		doc.Find(selector).EachWithBreak(func(n int, heading *goquery.Selection) bool {
+			if n >= constants.MaxHeadingTextsPerLevel {
+				return false
+			}
+			text := []rune(selectionText(heading))
+			if len(text) > constants.MaxHeadingTextLength {
+				text = text[:constants.MaxHeadingTextLength]
+			}
+			texts[selector] = append(texts[selector], string(text))
+			return true
+		})
+	}
+
+	return texts
+}
+
+// detectHTMLVersion returns the HTML version the DOCTYPE declares and the declaration as written
+func (a *Analyzer) detectHTMLVersion(htmlContent string) (version, rawDoctype string) {
 	// Extract and clean DOCTYPE
-	doctype := a.extractDOCTYPE(htmlContent)
+	doctype, rawDoctype := a.extractDOCTYPE(htmlContent)
+	return a.versionFromDOCTYPE(doctype), rawDoctype
+}
+
+// versionFromDOCTYPE maps an uppercased DOCTYPE declaration to its HTML version
+func (a *Analyzer) versionFromDOCTYPE(doctype string) string {
 	if doctype == "" {
 		return constants.HTMLVersion5 // No DOCTYPE found - assume HTML5
 	}
@@ -256,25 +1102,31 @@ func (a *Analyzer) detectHTMLVersion(htmlContent string) string {
 	return constants.HTMLVersionUnknown
 }
 
-// extractDOCTYPE extracts and cleans the DOCTYPE declaration from HTML content
-func (a *Analyzer) extractDOCTYPE(htmlContent string) string {
-	// Remove leading whitespace
-	cleanedHTML := strings.TrimSpace(htmlContent)
-	
-	// Remove XML declaration if present (for XHTML)
+// extractDOCTYPE extracts the DOCTYPE declaration from HTML content, uppercased for matching
+// and as written; both are empty when the content declares none
+func (a *Analyzer) extractDOCTYPE(htmlContent string) (doctype, raw string) {
 	xmlDeclRegex := regexp.MustCompile(constants.RegexXMLDeclaration)
-	cleanedHTML = xmlDeclRegex.ReplaceAllString(cleanedHTML, "")
-	
-	// Remove any leading comments
 	commentRegex := regexp.MustCompile(constants.RegexHTMLComment)
-	cleanedHTML = commentRegex.ReplaceAllString(cleanedHTML, "")
+
+	// Strip whatever may precede the DOCTYPE until nothing changes: the byte order mark,
+	// whitespace, the XML declaration of XHTML and any number of comments, in any order
+	cleanedHTML := htmlContent
+	for {
+		stripped := strings.TrimSpace(strings.TrimPrefix(cleanedHTML, constants.ByteOrderMark))
+		stripped = xmlDeclRegex.ReplaceAllString(stripped, "")
+		stripped = commentRegex.ReplaceAllString(stripped, "")
+		if stripped == cleanedHTML {
+			break
+		}
+		cleanedHTML = stripped
+	}
 	
 	// Extract DOCTYPE declaration
 	doctypeRegex := regexp.MustCompile(constants.RegexDOCTYPEExtraction)
-	matches := doctypeRegex.FindString(cleanedHTML)
+	raw = doctypeRegex.FindString(cleanedHTML)
 	
 	// Convert to uppercase for easier matching
-	return strings.ToUpper(matches)
+	return strings.ToUpper(raw), raw
 }
 
 // checkHTMLVersionWithVariants checks for HTML versions that have Strict/Transitional/Frameset variants
@@ -300,126 +1152,356 @@ func (a *Analyzer) checkHTMLVersionWithVariants(doctype, keyword, baseVersion, s
 	return baseVersion
 }
 
-// analyzeLinks analyzes all links in the document
-func (a *Analyzer) analyzeLinks(ctx context.Context, doc *goquery.Document, baseURL *url.URL) models.LinkAnalysis {
-	var analysis models.LinkAnalysis
-	var wg sync.WaitGroup
-	linkChan := make(chan linkCheckRequest, a.config.Analyzer.MaxLinks)
-	resultChan := make(chan bool, a.config.Analyzer.MaxLinks)
-
-	// Start worker pool
-	for i := 0; i < a.config.Analyzer.MaxWorkers; i++ {
-		go a.linkWorker(ctx, &wg, linkChan, resultChan)
+// analyzeLinks analyzes all links in the document, or the first limits.anchors of them.
+// Checks run on the shared link pool, at most MaxWorkers at a time for this analysis.
+func (a *Analyzer) analyzeLinks(ctx context.Context, doc *goquery.Document, baseURL *url.URL, limits domLimits) models.LinkAnalysis {
+	options := a.optionsFor(ctx)
+	// Failing external hosts stop being checked once their circuit opens
+	var circuit *hostCircuit
+	if options.LinkCircuit.Enabled {
+		circuit = newHostCircuit(ctx, options.LinkCircuit.FailureThreshold, a.openHosts)
+		defer circuit.release()
 	}
 
 	// Collect all links first
-	var externalLinks []string
-	var internalLinks []string
+	analysis, internalLinks, externalLinks := a.classifyLinks(ctx, doc, baseURL, limits)
+	// Links the configuration skips are counted without taking up check capacity
+	externalLinks, skippedExternal := a.linkOverrides.withoutSkipped(externalLinks)
+	internalLinks, skippedInternal := a.linkOverrides.withoutSkipped(internalLinks)
+	skippedByConfig := len(skippedExternal) + len(skippedInternal)
+	details := newLinkDetails()
+	details.addSkipped(skippedExternal, false, constants.LinkSkipReasonConfig)
+	details.addSkipped(skippedInternal, true, constants.LinkSkipReasonConfig)
+	// Links to hosts beyond analyzer.max_external_hosts are counted without being checked. Internal
+	// links to other hosts of the site count against the cap like external ones.
+	hosts := newHostCap(options.MaxExternalHosts, baseURL)
+	externalLinks, skippedByHostCap := hosts.admit(externalLinks)
+	details.addSkipped(skippedByHostCap, false, constants.LinkSkipReasonHostCap)
+	internalLinks, skippedInternalByHostCap := hosts.admit(internalLinks)
+	details.addSkipped(skippedInternalByHostCap, true, constants.LinkSkipReasonHostCap)
+	skippedByHostCap = append(skippedByHostCap, skippedInternalByHostCap...)
+	ctx = withHostCap(ctx, hosts)
 
-	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+	// Check links with priority (external first, then internal up to limit)
+	maxLinksToCheck := options.MaxLinks
+	// Pages with too many links can have a random sample checked instead of their first links
+	var sample *linkSample
+	if options.LinkSampling.Enabled {
+		externalLinks, internalLinks, sample = sampleLinks(externalLinks, internalLinks, maxLinksToCheck, samplingSeedFrom(ctx))
+	}
+	if sample != nil {
+		warningsFrom(ctx).add(constants.WarningCodeLinksNotChecked,
+			fmt.Sprintf("%d links were not checked; analyzer.max_links is %d, so a random sample of the %d unique links was checked", sample.report.Population-sample.report.SampleSize, maxLinksToCheck, sample.report.Population))
+	}
+	externalLinksToCheck := min(len(externalLinks), maxLinksToCheck)
+	// Add internal links if we have capacity (limit to prevent performance issues)
+	internalLinksToCheck := min(len(internalLinks), maxLinksToCheck-externalLinksToCheck)
+	if notChecked := len(externalLinks) + len(internalLinks) - externalLinksToCheck - internalLinksToCheck; notChecked > 0 {
+		warningsFrom(ctx).add(constants.WarningCodeLinksNotChecked,
+			fmt.Sprintf("%d links were not checked; analyzer.max_links is %d", notChecked, maxLinksToCheck))
+	}
+
+	queue := make([]linkCheckRequest, 0, externalLinksToCheck+internalLinksToCheck)
+	for _, link := range externalLinks[:externalLinksToCheck] {
+		queue = append(queue, linkCheckRequest{url: link, isInternal: false})
+	}
+	host := pageHost(baseURL)
+	for _, link := range internalLinks[:internalLinksToCheck] {
+		queue = append(queue, linkCheckRequest{url: link, isInternal: true, onPageHost: onPageHost(link, host)})
+	}
+
+	// Results are buffered for every job in flight, so pool workers never wait on this analysis
+	maxInFlight := min(options.MaxWorkers, len(queue))
+	resultChan := make(chan linkCheckResult, maxInFlight)
+
+	// Links never dispatched or cut short by the deadline are skipped rather than inaccessible
+	skipped := 0
+	circuitOpen := 0
+	overBudget := 0
+	blocked := 0
+	hostCapped := 0
+
+	dispatched, inFlight := 0, 0
+	for {
+		// Stop dispatching once the deadline has passed
+		for dispatched < len(queue) && inFlight < maxInFlight && ctx.Err() == nil {
+			job := linkJob{ctx: ctx, req: queue[dispatched], circuit: circuit, results: resultChan}
+			if !a.linkPool.submit(ctx, job) {
+				break
+			}
+			dispatched++
+			inFlight++
+		}
+		if inFlight == 0 {
+			break
+		}
+
+		result := <-resultChan
+		inFlight--
+		details.add(result)
+		if result.skipped {
+			skipped++
+			continue
+		}
+		if result.overBudget {
+			overBudget++
+			continue
+		}
+		if result.blocked {
+			blocked++
+			continue
+		}
+		if result.hostCapped {
+			hostCapped++
+			continue
+		}
+		if result.circuitOpen {
+			circuitOpen++
+		}
+		if result.redirect != nil && len(analysis.Redirects) < constants.LinkMaxRedirectsReported {
+			analysis.Redirects = append(analysis.Redirects, *result.redirect)
+		}
+		if result.redirect != nil && result.link.isInternal {
+			a.addOffOriginRedirect(ctx, &analysis.OffOriginRedirects, baseURL, *result.redirect)
+		}
+		if result.rateLimited {
+			analysis.RateLimited++
+			continue
+		}
+		sample.judged(result.link.isInternal)
+		if !result.accessible {
+			analysis.Inaccessible++
+			if result.link.isInternal {
+				analysis.InaccessibleInternal++
+			}
+			addLinkFailure(&analysis.Failures, result.failure)
+		}
+	}
+	skipped += len(queue) - dispatched
+	for _, link := range queue[dispatched:] {
+		details.addSkipped([]string{link.url}, link.isInternal, constants.LinkSkipReasonDeadline)
+	}
+	if skipped > 0 || circuitOpen > 0 || overBudget > 0 || blocked > 0 || skippedByConfig > 0 || len(skippedByHostCap)+hostCapped > 0 {
+		analysis.Skipped = make(map[string]int)
+	}
+	if skipped > 0 {
+		analysis.Skipped[constants.LinkSkipReasonDeadline] = skipped
+	}
+	if circuitOpen > 0 {
+		analysis.Skipped[constants.LinkSkipReasonCircuitOpen] = circuitOpen
+	}
+	if overBudget > 0 {
+		analysis.Skipped[constants.LinkSkipReasonBudget] = overBudget
+		warningsFrom(ctx).add(constants.WarningCodeBudgetExhausted,
+			fmt.Sprintf("%d links were not checked because the outbound request budget ran out", overBudget))
+	}
+	if blocked > 0 {
+		analysis.Skipped[constants.LinkSkipReasonPolicy] = blocked
+	}
+	if skippedByConfig > 0 {
+		analysis.Skipped[constants.LinkSkipReasonConfig] = skippedByConfig
+	}
+	if len(skippedByHostCap)+hostCapped > 0 {
+		analysis.Skipped[constants.LinkSkipReasonHostCap] = len(skippedByHostCap) + hostCapped
+	}
+	// Checks complete in any order
+	sort.Slice(analysis.Redirects, func(i, j int) bool {
+		return analysis.Redirects[i].URL < analysis.Redirects[j].URL
+	})
+	analysis.Details = details.result()
+	if sample != nil {
+		analysis.Sampled = true
+		analysis.Sampling = sample.estimate(analysis.InaccessibleInternal, analysis.Inaccessible-analysis.InaccessibleInternal)
+	}
+
+	return analysis
+}
+
+// classifyLinks resolves the anchors against the base URL and splits them into internal and external links,
+// as analyzer.internal_scope defines them. Only the first limits.anchors anchors are classified.
+func (a *Analyzer) classifyLinks(ctx context.Context, doc *goquery.Document, baseURL *url.URL, limits domLimits) (models.LinkAnalysis, []string, []string) {
+	var analysis models.LinkAnalysis
+	var internalLinks []string
+	var externalLinks []string
+	domains := make(map[string]int)
+	internal := newInternalLinkTally(doc, baseURL)
+	var suspicious suspiciousLinkTally
+	anchorText := a.newAnchorTextTally(ctx, doc)
+	pagination := newPaginationTally(doc, baseURL)
+	raw := newRawLinkList()
+
+	firstN(limits.scoped(doc).Find("a[href]"), limits.anchors).Each(func(_ int, s *goquery.Selection) {
 		if href, exists := s.Attr("href"); exists {
+			// Inline content is counted apart; resolving it would make a bogus external link
+			if isDataURI(href) {
+				analysis.DataURIs++
+				return
+			}
 			linkURL, err := baseURL.Parse(href)
 			if err != nil {
 				return
 			}
-
-			if linkURL.Host == baseURL.Host {
+			// Compare hosts in the punycode form of the base URL, without default ports; invalid hosts stay as written
+			_ = toASCIIHost(linkURL)
+			stripDefaultPort(linkURL)
+
+			isInternal := a.isInternalLink(ctx, baseURL, linkURL)
+			tallyLinkRel(&analysis, s, !isInternal)
+			suspicious.add(s, linkURL, !isInternal)
+			anchorText.add(s, linkURL)
+			pagination.add(s, linkURL, !isInternal)
+			raw.add(linkURL.String())
+			if isInternal {
 				analysis.Internal++
+				internal.add(href, linkURL)
 				internalLinks = append(internalLinks, linkURL.String())
 			} else {
 				analysis.External++
 				externalLinks = append(externalLinks, linkURL.String())
+				if domain := a.externalDomain(ctx, linkURL); domain != "" {
+					domains[domain]++
+				}
 			}
 		}
 	})
+	analysis.ExternalDomains = topCounts(domains, a.optionsFor(ctx).ExternalDomains.TopN)
+	analysis.InternalDetail = internal.result()
+	analysis.Suspicious = suspicious.result
+	analysis.AnchorTextStats = anchorText.result()
+	analysis.Pagination = pagination.pagination()
+	analysis.Raw = raw.result()
+
+	return analysis, internalLinks, externalLinks
+}
 
-	// Check links with priority (external first, then internal up to limit)
-	linksToCheck := 0
-	maxLinksToCheck := a.config.Analyzer.MaxLinks
+// checkQueuedLink checks a dispatched link unless the deadline passed or the link's host circuit is open
+func (a *Analyzer) checkQueuedLink(ctx context.Context, linkReq linkCheckRequest, circuit *hostCircuit) linkCheckResult {
+	// Drain queued links without checking them once the deadline has passed
+	if ctx.Err() != nil {
+		return linkCheckResult{skipped: true}
+	}
 
-	// Add external links first (higher priority)
-	for _, link := range externalLinks {
-		if linksToCheck >= maxLinksToCheck {
-			break
+	// Links to the analyzed page's own host are never short-circuited
+	checkCtx := ctx
+	host := ""
+	if circuit != nil && !linkReq.onPageHost {
+		host = linkHost(linkReq.url)
+		var open bool
+		if checkCtx, open = circuit.acquire(host); open {
+			return linkCheckResult{circuitOpen: true, failure: constants.LinkFailureCircuitOpen}
 		}
-		wg.Add(1)
-		linkChan <- linkCheckRequest{url: link, isInternal: false}
-		linksToCheck++
 	}
 
-	// Add internal links if we have capacity (limit to prevent performance issues)
-	remainingCapacity := maxLinksToCheck - linksToCheck
-	internalLinksToCheck := len(internalLinks)
-	if internalLinksToCheck > remainingCapacity {
-		internalLinksToCheck = remainingCapacity
+	start := time.Now()
+	checkCtx, trace := withRedirectTrace(checkCtx)
+	done := statsFrom(ctx).linkCheckStarted()
+	accessible, rateLimited, err := a.probeLink(checkCtx, linkReq.url, linkReq.isInternal)
+	done()
+	metrics.Observe(ctx, a.metrics.LinkCheckDuration, time.Since(start).Seconds())
+	if errors.Is(err, errBudgetExhausted) {
+		return linkCheckResult{overBudget: true}
 	}
-
-	for i := 0; i < internalLinksToCheck; i++ {
-		wg.Add(1)
-		linkChan <- linkCheckRequest{url: internalLinks[i], isInternal: true}
-		linksToCheck++
+	if errors.Is(err, errTargetBlocked) {
+		return linkCheckResult{blocked: true}
 	}
-
-	// Close link channel and wait for workers
-	close(linkChan)
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Count inaccessible links
-	for accessible := range resultChan {
-		if !accessible {
-			analysis.Inaccessible++
-		}
+	if !linkReq.onPageHost {
+		statsFrom(ctx).externalHost(linkHost(linkReq.url))
+	}
+	if errors.Is(err, errHostCapReached) {
+		// The link itself was requested; the host it redirected to was not
+		return linkCheckResult{hostCapped: true}
+	}
+	if rateLimited {
+		// Being throttled is neither a failure nor a success for the host's circuit
+		return linkCheckResult{rateLimited: true}
 	}
 
-	return analysis
-}
-
-// linkWorker checks if links are accessible
-func (a *Analyzer) linkWorker(ctx context.Context, wg *sync.WaitGroup, links <-chan linkCheckRequest, results chan<- bool) {
-	for linkReq := range links {
-		start := time.Now()
-		accessible := a.checkLinkWithTimeout(ctx, linkReq.url, linkReq.isInternal)
-		a.metrics.LinkCheckDuration.Observe(time.Since(start).Seconds())
-		results <- accessible
-		wg.Done()
+	switch {
+	case !accessible && ctx.Err() != nil:
+		// A check cut short by the deadline says nothing about the link
+		return linkCheckResult{skipped: true}
+	case !accessible && checkCtx.Err() != nil:
+		// The circuit opened while this check was in flight
+		return linkCheckResult{circuitOpen: true, failure: constants.LinkFailureCircuitOpen}
+	}
+	if host != "" {
+		circuit.record(host, accessible)
+	}
+	result := linkCheckResult{accessible: accessible, status: trace.finalStatus, redirect: trace.redirect(linkReq.url)}
+	if !accessible {
+		result.failure = linkFailureCategory(trace.finalStatus, err)
+		a.log(ctx).Debug("Link check failed",
+			zap.String("url", models.StripCredentials(linkReq.url)),
+			zap.Int("status", trace.finalStatus),
+			zap.String("failure", result.failure),
+			zap.Error(err),
+		)
 	}
+	return result
 }
 
 // checkLinkWithTimeout checks if a link is accessible with different timeouts for internal vs external links
 func (a *Analyzer) checkLinkWithTimeout(ctx context.Context, link string, isInternal bool) bool {
+	accessible, _, _ := a.probeLink(ctx, link, isInternal)
+	return accessible
+}
+
+// probeLink checks a link and reports whether it is accessible, or whether the target rate limited the check.
+// err is set when no response was received.
+func (a *Analyzer) probeLink(ctx context.Context, link string, isInternal bool) (accessible, rateLimited bool, err error) {
 	// Create a client with appropriate timeout
 	var client *http.Client
 	if isInternal {
 		// Use shorter timeout for internal links
 		client = &http.Client{
-			Timeout: constants.DefaultInternalLinkTimeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= a.config.Analyzer.MaxRedirects {
-					return http.ErrUseLastResponse
-				}
-				return nil
-			},
+			Transport:     a.linkClient.Transport,
+			Timeout:       constants.DefaultInternalLinkTimeout,
+			CheckRedirect: a.followLinkRedirect,
 		}
 	} else {
 		// Use the configured timeout for external links
-		client = a.httpClient
+		client = a.linkClient
+	}
+
+	// Hosts with an override are checked with its method and timeout
+	method := http.MethodHead
+	if override := a.linkOverrides.lookup(link); override != nil {
+		if override.Method != "" {
+			method = override.Method
+		}
+		if override.Timeout > 0 {
+			overridden := *client
+			overridden.Timeout = override.Timeout
+			client = &overridden
+		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
 	if err != nil {
-		return false
+		return false, false, err
+	}
+	if err := a.policy.check(ctx, req.URL); err != nil {
+		return false, false, err
 	}
+	// Only same-host links are checked with the caller's language and credentials
+	acceptLanguageFrom(ctx).apply(req)
+	credentialsFrom(ctx).apply(req)
 
+	if err := budgetFrom(ctx).acquire(ctx); err != nil {
+		return false, false, err
+	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return false
+		return false, false, err
 	}
 	defer resp.Body.Close()
+	drainLinkResponse(ctx, resp.Body)
+	redirectTraceFrom(ctx).record(resp)
 
-	return resp.StatusCode < constants.StatusBadRequest
+	if isRateLimited(resp.StatusCode, resp.Header) {
+		return false, true, nil
+	}
+	return resp.StatusCode < constants.StatusBadRequest, false, nil
 }
 
 // checkLink checks if a link is accessible (kept for backward compatibility)
@@ -427,13 +1509,14 @@ func (a *Analyzer) checkLink(ctx context.Context, link string) bool {
 	return a.checkLinkWithTimeout(ctx, link, false)
 }
 
-// detectLoginForm checks for the presence of a login form using a scoring system
-func (a *Analyzer) detectLoginForm(doc *goquery.Document) bool {
+// detectLoginForm checks for the presence of a login form using a scoring system.
+// Only the first limits.forms forms are scored.
+func (a *Analyzer) detectLoginForm(doc *goquery.Document, limits domLimits) bool {
 	score := 0
 	requiredScore := constants.DefaultLoginFormThreshold // Using constant instead of hardcoded value
 
 	// Check for forms with both username/email and password fields
-	doc.Find("form").Each(func(_ int, form *goquery.Selection) {
+	firstN(doc.Find("form"), limits.forms).Each(func(_ int, form *goquery.Selection) {
 		formScore := 0
 
 		// Check form attributes
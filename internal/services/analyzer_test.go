@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -27,19 +29,40 @@ type MockCache struct {
 	mock.Mock
 }
 
-func (m *MockCache) Get(ctx context.Context, url string) (*models.AnalyzeResponse, error) {
+func (m *MockCache) Get(ctx context.Context, url string) (*models.AnalyzeResponse, time.Duration, error) {
 	args := m.Called(ctx, url)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).(*models.AnalyzeResponse), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockCache) Set(ctx context.Context, url string, result *models.AnalyzeResponse, ttl time.Duration) error {
+	args := m.Called(ctx, url, result, ttl)
+	return args.Error(0)
+}
+
+func (m *MockCache) GetMulti(ctx context.Context, urls []string) (map[string]*models.AnalyzeResponse, error) {
+	args := m.Called(ctx, urls)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.AnalyzeResponse), args.Error(1)
+	return args.Get(0).(map[string]*models.AnalyzeResponse), args.Error(1)
 }
 
-func (m *MockCache) Set(ctx context.Context, url string, result *models.AnalyzeResponse) error {
-	args := m.Called(ctx, url, result)
+func (m *MockCache) SetMulti(ctx context.Context, results map[string]*models.AnalyzeResponse, ttl time.Duration) error {
+	args := m.Called(ctx, results, ttl)
 	return args.Error(0)
 }
 
+func (m *MockCache) Stats(ctx context.Context) (*models.CacheStats, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CacheStats), args.Error(1)
+}
+
 func (m *MockCache) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -54,33 +77,7 @@ type MockMetrics struct {
 }
 
 func NewMockMetrics() *metrics.Metrics {
-	return &metrics.Metrics{
-		RequestDuration: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name: "test_request_duration_seconds",
-				Help: "Test metric",
-			},
-			[]string{"status"},
-		),
-		CacheHits: prometheus.NewCounter(
-			prometheus.CounterOpts{
-				Name: "test_cache_hits_total",
-				Help: "Test metric",
-			},
-		),
-		CacheMisses: prometheus.NewCounter(
-			prometheus.CounterOpts{
-				Name: "test_cache_misses_total",
-				Help: "Test metric",
-			},
-		),
-		LinkCheckDuration: prometheus.NewHistogram(
-			prometheus.HistogramOpts{
-				Name: "test_link_check_duration_seconds",
-				Help: "Test metric",
-			},
-		),
-	}
+	return metrics.NewWithRegistry(prometheus.NewRegistry())
 }
 
 
@@ -95,6 +92,19 @@ func createTestConfig() *config.Config {
 	}
 }
 
+// newTestAnalyzer returns an analyzer on the test config, changed by configure when it is not
+// nil, whose cache misses on every read and accepts every write
+func newTestAnalyzer(t *testing.T, configure func(*config.Config)) *Analyzer {
+	cfg := createTestConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), cache)
+}
+
 
 func TestNewAnalyzer(t *testing.T) {
 	logger := zaptest.NewLogger(t)
@@ -108,7 +118,7 @@ func TestNewAnalyzer(t *testing.T) {
 	assert.Equal(t, logger, analyzer.logger)
 	assert.Equal(t, metrics, analyzer.metrics)
 	assert.Equal(t, cache, analyzer.cache)
-	assert.Equal(t, cfg, analyzer.config)
+	assert.Equal(t, cfg.Analyzer.MaxLinks, analyzer.options.MaxLinks)
 	assert.NotNil(t, analyzer.httpClient)
 }
 
@@ -126,12 +136,65 @@ func TestNewAnalyzer_WithZeroConfig(t *testing.T) {
 		},
 	}
 
-	NewAnalyzer(cfg, logger, metrics, cache)
+	analyzer := NewAnalyzer(cfg, logger, metrics, cache)
+
+	assert.Equal(t, constants.DefaultMaxLinks, analyzer.options.MaxLinks)
+	assert.Equal(t, constants.DefaultLinkTimeout, analyzer.options.LinkTimeout)
+	assert.Equal(t, constants.DefaultMaxWorkers, analyzer.options.MaxWorkers)
+	assert.Equal(t, constants.DefaultMaxRedirects, analyzer.options.MaxRedirects)
+	assert.Equal(t, constants.DefaultSEOWeights, analyzer.options.SEO.Weights)
+}
+
+func TestNewAnalyzer_LeavesConfigUntouched(t *testing.T) {
+	cfg := &config.Config{
+		Analyzer: config.AnalyzerConfig{
+			MaxWorkers: 3,
+			SEO:        config.SEOConfig{Weights: map[string]int{constants.SEORuleMissingTitle: 40}},
+		},
+	}
+	before := &config.Config{
+		Analyzer: config.AnalyzerConfig{
+			MaxWorkers: 3,
+			SEO:        config.SEOConfig{Weights: map[string]int{constants.SEORuleMissingTitle: 40}},
+		},
+	}
+
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	assert.Equal(t, before, cfg, "defaults are applied to the analyzer's own copy")
+	assert.Equal(t, 3, analyzer.options.MaxWorkers)
+	assert.Equal(t, constants.DefaultMaxLinks, analyzer.options.MaxLinks)
+	assert.Equal(t, 40, analyzer.options.SEO.Weights[constants.SEORuleMissingTitle])
+	assert.Len(t, analyzer.options.SEO.Weights, len(constants.DefaultSEOWeights))
+
+	// Analyzers built from one configuration do not share settings
+	other := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	other.options.SEO.Weights[constants.SEORuleMissingTitle] = 10
+	assert.Equal(t, 40, analyzer.options.SEO.Weights[constants.SEORuleMissingTitle])
+	assert.Equal(t, 40, cfg.Analyzer.SEO.Weights[constants.SEORuleMissingTitle])
+}
+
+func TestAnalyzer_OptionsPerAnalysis(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head><title>Test</title></head><body><p>Content</p></body></html>"))
+	}))
+	defer server.Close()
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	// Outside an analysis the analyzer's own options apply
+	assert.Same(t, &analyzer.options, analyzer.optionsFor(context.Background()))
+
+	ctx, options := analyzer.withOptions(context.Background())
+	require.Same(t, options, analyzer.optionsFor(ctx))
+	options.MaxBodyBytes = 10
+	assert.NotEqual(t, int64(10), analyzer.options.MaxBodyBytes, "changing one analysis leaves the analyzer's options alone")
 
-	assert.Equal(t, constants.DefaultMaxLinks, cfg.Analyzer.MaxLinks)
-	assert.Equal(t, constants.DefaultLinkTimeout, cfg.Analyzer.LinkTimeout)
-	assert.Equal(t, constants.DefaultMaxWorkers, cfg.Analyzer.MaxWorkers)
-	assert.Equal(t, constants.DefaultMaxRedirects, cfg.Analyzer.MaxRedirects)
+	// Internal methods read the options of the analysis they run in
+	_, err := analyzer.fetchWebpage(ctx, server.URL, nil)
+	assert.ErrorContains(t, err, "maximum size of 10 bytes")
+	page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	assert.Contains(t, page.body, "Content")
 }
 
 
@@ -149,8 +212,71 @@ func TestAnalyzer_DetectHTMLVersion(t *testing.T) {
 <body><h1>Hello</h1></body>
 </html>`
 
-	version := analyzer.detectHTMLVersion(html)
+	version, rawDoctype := analyzer.detectHTMLVersion(html)
 	assert.Equal(t, "HTML5", version)
+	assert.Equal(t, "<!DOCTYPE html>", rawDoctype)
+}
+
+func TestAnalyzer_DetectHTMLVersion_RawDoctype(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name       string
+		html       string
+		version    string
+		rawDoctype string
+	}{
+		{
+			name:       "Lowercase HTML5 DOCTYPE",
+			html:       "<!doctype html><html></html>",
+			version:    constants.HTMLVersion5,
+			rawDoctype: "<!doctype html>",
+		},
+		{
+			name:       "HTML 4.01 Transitional",
+			html:       `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd"><html></html>`,
+			version:    constants.HTMLVersionHTML401Transitional,
+			rawDoctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`,
+		},
+		{
+			name:       "Unknown DOCTYPE",
+			html:       `<!DOCTYPE Something-Else><html></html>`,
+			version:    constants.HTMLVersionUnknown,
+			rawDoctype: "<!DOCTYPE Something-Else>",
+		},
+		{
+			name:       "No DOCTYPE",
+			html:       "<html><head><title>Test</title></head></html>",
+			version:    constants.HTMLVersion5,
+			rawDoctype: "",
+		},
+		{
+			name:       "Whitespace before DOCTYPE",
+			html:       "  \n\t<!DocType HTML><html></html>",
+			version:    constants.HTMLVersion5,
+			rawDoctype: "<!DocType HTML>",
+		},
+		{
+			name:       "HTML 4.01 after a byte order mark",
+			html:       "\ufeff<!DOCTYPE HTML PUBLIC \"-//W3C//DTD HTML 4.01 Frameset//EN\"><html></html>",
+			version:    constants.HTMLVersionHTML401Frameset,
+			rawDoctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Frameset//EN">`,
+		},
+		{
+			name:       "XHTML 1.0 after multi-line comments",
+			html:       "<!--\n header\n-->\n<!-- nav -->\n<!DOCTYPE html PUBLIC \"-//W3C//DTD XHTML 1.0 Strict//EN\"><html></html>",
+			version:    constants.HTMLVersionXHTML10Strict,
+			rawDoctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN">`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, rawDoctype := analyzer.detectHTMLVersion(tt.html)
+			assert.Equal(t, tt.version, version)
+			assert.Equal(t, tt.rawDoctype, rawDoctype)
+		})
+	}
 }
 
 
@@ -255,7 +381,7 @@ func TestAnalyzer_DetectLoginForm(t *testing.T) {
 			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
 			require.NoError(t, err)
 
-			result := analyzer.detectLoginForm(doc)
+			result := analyzer.detectLoginForm(doc, domLimits{})
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -361,7 +487,7 @@ func TestAnalyzer_AnalyzeLinks(t *testing.T) {
 	require.NoError(t, err)
 
 	ctx := context.Background()
-	result := analyzer.analyzeLinks(ctx, doc, baseURL)
+	result := analyzer.analyzeLinks(ctx, doc, baseURL, domLimits{})
 
 	// Should have 2 internal links
 	assert.Equal(t, 2, result.Internal)
@@ -393,16 +519,41 @@ func TestAnalyzer_Analyze_CacheHit(t *testing.T) {
 		AnalyzedAt:   time.Now(),
 	}
 
-	cache.On("Get", mock.Anything, "http://example.com").Return(expectedResult, nil)
+	cache.On("Get", mock.Anything, "http://example.com").Return(expectedResult, 30*time.Minute, nil)
 
 	ctx := context.Background()
 	result, err := analyzer.Analyze(ctx, "http://example.com")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedResult, result)
+	assert.Equal(t, 30*time.Minute, result.CacheTTL)
+	require.NotNil(t, result.ServedFromCacheInMs)
+	assert.GreaterOrEqual(t, *result.ServedFromCacheInMs, int64(0))
 	cache.AssertExpectations(t)
 }
 
+func TestAnalyzer_Analyze_ReportsDurations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Slow</title></head><body><a href="/next">Next</a></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	result, err := analyzer.Analyze(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, result.Phases.FetchMs, int64(20))
+	assert.GreaterOrEqual(t, result.Phases.LinkCheckMs, int64(20))
+	assert.GreaterOrEqual(t, result.DurationMs, result.Phases.FetchMs+result.Phases.ParseMs+result.Phases.LinkCheckMs)
+	assert.Nil(t, result.ServedFromCacheInMs)
+}
+
 
 func TestAnalyzer_Analyze_CacheMiss(t *testing.T) {
 	logger := zaptest.NewLogger(t)
@@ -430,8 +581,8 @@ func TestAnalyzer_Analyze_CacheMiss(t *testing.T) {
 	}))
 	defer server.Close()
 
-	cache.On("Get", mock.Anything, server.URL).Return(nil, nil)
-	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse")).Return(nil)
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
 
 	ctx := context.Background()
 	result, err := analyzer.Analyze(ctx, server.URL)
@@ -457,7 +608,7 @@ func TestAnalyzer_Analyze_InvalidURL(t *testing.T) {
 	cfg := createTestConfig()
 	analyzer := NewAnalyzer(cfg, logger, metrics, cache)
 
-	cache.On("Get", mock.Anything, "invalid-url").Return(nil, nil)
+	cache.On("Get", mock.Anything, "invalid-url").Return(nil, time.Duration(0), nil)
 
 	ctx := context.Background()
 	result, err := analyzer.Analyze(ctx, "invalid-url")
@@ -481,7 +632,7 @@ func TestAnalyzer_Analyze_HTTPError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	cache.On("Get", mock.Anything, server.URL).Return(nil, nil)
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
 
 	ctx := context.Background()
 	result, err := analyzer.Analyze(ctx, server.URL)
@@ -518,8 +669,8 @@ func TestAnalyzer_Analyze_WithLoginForm(t *testing.T) {
 	}))
 	defer server.Close()
 
-	cache.On("Get", mock.Anything, server.URL).Return(nil, nil)
-	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse")).Return(nil)
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
 
 	ctx := context.Background()
 	result, err := analyzer.Analyze(ctx, server.URL)
@@ -546,8 +697,8 @@ func TestAnalyzer_Analyze_MalformedHTML(t *testing.T) {
 	}))
 	defer server.Close()
 
-	cache.On("Get", mock.Anything, server.URL).Return(nil, nil)
-	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse")).Return(nil)
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
 
 	ctx := context.Background()
 	result, err := analyzer.Analyze(ctx, server.URL)
@@ -584,8 +735,8 @@ func BenchmarkAnalyzer_Analyze(b *testing.B) {
 	}))
 	defer server.Close()
 
-	cache.On("Get", mock.Anything, server.URL).Return(nil, nil)
-	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse")).Return(nil)
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
 
 	ctx := context.Background()
 
@@ -663,9 +814,10 @@ func TestAnalyzer_FetchWebpage(t *testing.T) {
 		}))
 		defer server.Close()
 
-		content, err := analyzer.fetchWebpage(server.URL)
+		page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
 		assert.NoError(t, err)
-		assert.Equal(t, expectedHTML, content)
+		assert.Equal(t, expectedHTML, page.body)
+		assert.Equal(t, http.StatusOK, page.statusCode)
 	})
 
 	t.Run("Server returns error status", func(t *testing.T) {
@@ -674,16 +826,20 @@ func TestAnalyzer_FetchWebpage(t *testing.T) {
 		}))
 		defer server.Close()
 
-		content, err := analyzer.fetchWebpage(server.URL)
+		page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
 		assert.Error(t, err)
-		assert.Empty(t, content)
 		assert.Contains(t, err.Error(), "status code 500")
+
+		// The error page is still returned for inspection
+		require.NotNil(t, page)
+		assert.Equal(t, http.StatusInternalServerError, page.statusCode)
+		assert.Empty(t, page.body)
 	})
 
 	t.Run("Invalid URL", func(t *testing.T) {
-		content, err := analyzer.fetchWebpage("invalid-url")
+		page, err := analyzer.fetchWebpage(context.Background(), "invalid-url", nil)
 		assert.Error(t, err)
-		assert.Empty(t, content)
+		assert.Nil(t, page)
 		assert.Contains(t, err.Error(), "failed to fetch webpage")
 	})
 }
@@ -800,6 +956,58 @@ func TestAnalyzer_CountHeadings(t *testing.T) {
 	assert.Equal(t, expected, headings)
 }
 
+func TestAnalyzer_ExtractHeadingText(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	long := strings.Repeat("é", constants.MaxHeadingTextLength+20)
+	var many strings.Builder
+	for i := 0; i < constants.MaxHeadingTextsPerLevel+5; i++ {
+		fmt.Fprintf(&many, "<h3>Section %d</h3>", i)
+	}
+	html := `
+	<html>
+		<body>
+			<h1>  Lorem
+				ipsum  </h1>
+			<h2>TODO</h2>
+			<h2></h2>
+			<h2>` + long + `</h2>
+			` + many.String() + `
+		</body>
+	</html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	require.NoError(t, err)
+
+	texts := analyzer.extractHeadingText(doc)
+
+	assert.Equal(t, []string{"Lorem ipsum"}, texts["h1"])
+	assert.Equal(t, []string{"TODO", "", strings.Repeat("é", constants.MaxHeadingTextLength)}, texts["h2"], "empty headings are kept, long ones truncated")
+	require.Len(t, texts["h3"], constants.MaxHeadingTextsPerLevel)
+	assert.Equal(t, "Section 0", texts["h3"][0])
+	assert.Equal(t, fmt.Sprintf("Section %d", constants.MaxHeadingTextsPerLevel-1), texts["h3"][constants.MaxHeadingTextsPerLevel-1])
+	assert.NotContains(t, texts, "h4", "levels without headings are left out")
+}
+
+func TestAnalyzer_Analyze_IncludeHeadingText(t *testing.T) {
+	cache := &MockCache{}
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	html := `<html><head><title>Headings</title></head><body><h1>Welcome</h1><h2>Lorem ipsum</h2></body></html>`
+
+	result, err := analyzer.AnalyzeHTML(context.Background(), html, "https://site.example", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Nil(t, result.HeadingText, "heading text is opt-in")
+
+	result, err = analyzer.AnalyzeHTML(context.Background(), html, "https://site.example", models.AnalyzeOptions{SkipLinkCheck: true, IncludeHeadingText: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"h1": {"Welcome"}, "h2": {"Lorem ipsum"}}, result.HeadingText)
+	assert.Equal(t, 1, result.Headings["h2"])
+
+	assert.Equal(t, "http://example.com|"+constants.CacheVariantHeadingText,
+		cacheKey("http://example.com", models.AnalyzeOptions{IncludeHeadingText: true}))
+}
+
 func TestAnalyzer_ExtractDOCTYPE(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	metrics := NewMockMetrics()
@@ -811,48 +1019,87 @@ func TestAnalyzer_ExtractDOCTYPE(t *testing.T) {
 		name     string
 		html     string
 		expected string
+		raw      string
 	}{
 		{
 			name:     "HTML5 DOCTYPE",
 			html:     "<!DOCTYPE html><html></html>",
 			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
 		},
 		{
 			name:     "HTML 4.01 Strict DOCTYPE",
 			html:     `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd"><html></html>`,
 			expected: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "HTTP://WWW.W3.ORG/TR/HTML4/STRICT.DTD">`,
+			raw:      `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">`,
 		},
 		{
 			name:     "XHTML 1.0 DOCTYPE",
 			html:     `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd"><html></html>`,
 			expected: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD XHTML 1.0 TRANSITIONAL//EN" "HTTP://WWW.W3.ORG/TR/XHTML1/DTD/XHTML1-TRANSITIONAL.DTD">`,
+			raw:      `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`,
 		},
 		{
 			name:     "With XML declaration",
 			html:     `<?xml version="1.0" encoding="UTF-8"?><!DOCTYPE html><html></html>`,
 			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
 		},
 		{
 			name:     "With HTML comments",
 			html:     `<!-- This is a comment --><!DOCTYPE html><html></html>`,
 			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
 		},
 		{
 			name:     "No DOCTYPE",
 			html:     "<html></html>",
 			expected: "",
+			raw:      "",
 		},
 		{
 			name:     "Whitespace before DOCTYPE",
 			html:     "   \n\t<!DOCTYPE html><html></html>",
 			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
+		},
+		{
+			name:     "Byte order mark",
+			html:     "\ufeff<!DOCTYPE HTML PUBLIC \"-//W3C//DTD HTML 4.01//EN\"><html></html>",
+			expected: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN">`,
+			raw:      `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN">`,
+		},
+		{
+			name:     "Several comments",
+			html:     "<!-- build 42 -->\n<!-- generated -->  <!---->\n<!DOCTYPE html><html></html>",
+			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
+		},
+		{
+			name:     "Multi-line comment",
+			html:     "<!--\n  Copyright Example\n  All rights reserved\n-->\n<!DOCTYPE html><html></html>",
+			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
+		},
+		{
+			name:     "BOM, XML declaration and comments",
+			html:     "\ufeff<?xml version=\"1.0\"?>\n<!-- a -->\n<!--\nb\n--><!DOCTYPE html><html></html>",
+			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
+		},
+		{
+			name:     "Comment before XML declaration",
+			html:     "<!-- a --><?xml version=\"1.0\"?><!DOCTYPE html><html></html>",
+			expected: "<!DOCTYPE HTML>",
+			raw:      "<!DOCTYPE html>",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := analyzer.extractDOCTYPE(tt.html)
+			result, raw := analyzer.extractDOCTYPE(tt.html)
 			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.raw, raw, "the declaration keeps its original case")
 		})
 	}
 }
@@ -1088,7 +1335,7 @@ func TestAnalyzer_DetectHTMLVersion_AdditionalCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := analyzer.detectHTMLVersion(tt.html)
+			result, _ := analyzer.detectHTMLVersion(tt.html)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -1126,7 +1373,7 @@ func TestAnalyzer_PerformWebpageAnalysis(t *testing.T) {
 	require.NoError(t, err)
 
 	ctx := context.Background()
-	result := analyzer.performWebpageAnalysis(ctx, "http://example.com", html, doc, baseURL)
+	result := analyzer.performWebpageAnalysis(ctx, "http://example.com", html, doc, baseURL, baseURL, models.AnalyzeOptions{})
 
 	assert.Equal(t, "http://example.com", result.URL)
 	assert.Equal(t, "HTML5", result.HTMLVersion)
@@ -1139,4 +1386,249 @@ func TestAnalyzer_PerformWebpageAnalysis(t *testing.T) {
 	assert.Equal(t, 0, result.Headings["h6"])
 	assert.True(t, result.HasLoginForm)
 	assert.NotZero(t, result.AnalyzedAt)
-} 
\ No newline at end of file
+}
+
+func TestAnalyzer_AnalyzeHTML(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	metrics := NewMockMetrics()
+	cache := &MockCache{}
+	cfg := createTestConfig()
+	analyzer := NewAnalyzer(cfg, logger, metrics, cache)
+
+	accessibleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer accessibleServer.Close()
+
+	html := `<!DOCTYPE html>
+<html>
+<head><title>Draft Article</title></head>
+<body>
+	<h1>Headline</h1>
+	<h2>Section</h2>
+	<a href="/related">Related</a>
+	<a href="` + accessibleServer.URL + `/ok">Reference</a>
+	<form action="/login">
+		<input type="text" name="username" />
+		<input type="password" name="password" />
+		<button type="submit">Login</button>
+	</form>
+</body>
+</html>`
+
+	t.Run("Skips link checks when requested", func(t *testing.T) {
+		result, err := analyzer.AnalyzeHTML(context.Background(), html, "https://site.example/drafts/1", models.AnalyzeOptions{SkipLinkCheck: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://site.example/drafts/1", result.URL)
+		assert.Equal(t, "Draft Article", result.Title)
+		assert.Equal(t, 1, result.Headings["h1"])
+		assert.Equal(t, 1, result.Links.Internal)
+		assert.Equal(t, 1, result.Links.External)
+		assert.Equal(t, 0, result.Links.Inaccessible)
+		assert.True(t, result.HasLoginForm)
+	})
+
+	t.Run("Resolves links against the base URL", func(t *testing.T) {
+		result, err := analyzer.AnalyzeHTML(context.Background(), html, accessibleServer.URL+"/draft", models.AnalyzeOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Links.Internal)
+		assert.Equal(t, 0, result.Links.External)
+		assert.Equal(t, 0, result.Links.Inaccessible)
+	})
+
+	t.Run("Rejects invalid base URL", func(t *testing.T) {
+		_, err := analyzer.AnalyzeHTML(context.Background(), html, "not-a-url", models.AnalyzeOptions{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects oversized HTML", func(t *testing.T) {
+		small := createTestConfig()
+		small.Analyzer.MaxBodyBytes = 16
+		limited := NewAnalyzer(small, logger, metrics, cache)
+
+		_, err := limited.AnalyzeHTML(context.Background(), html, "https://site.example", models.AnalyzeOptions{})
+		assert.ErrorContains(t, err, "exceeds maximum size")
+	})
+
+	// Submitted HTML is never cached
+	cache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnalyzer_FetchWebpage_BodyTooLarge(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Analyzer.MaxBodyBytes = 32
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 64)))
+	}))
+	defer server.Close()
+
+	_, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+	assert.ErrorContains(t, err, "exceeds maximum size")
+}
+
+// newSlowLinkServer serves a page whose links all hang until the client gives up
+func newSlowLinkServer(links int) *httptest.Server {
+	var page strings.Builder
+	page.WriteString(`<html><head><title>Slow links</title></head><body><h1>Slow</h1>`)
+	for i := 0; i < links; i++ {
+		fmt.Fprintf(&page, `<a href="/slow/%d">Link %d</a>`, i, i)
+	}
+	page.WriteString(`</body></html>`)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/slow/") {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page.String()))
+	}))
+}
+
+func TestAnalyzer_PartialResultsOnDeadline(t *testing.T) {
+	server := newSlowLinkServer(10)
+	defer server.Close()
+
+	newDeadlineAnalyzer := func(cache *MockCache) *Analyzer {
+		cfg := createTestConfig()
+		cfg.Analyzer.AnalysisTimeout = 200 * time.Millisecond
+		cfg.Analyzer.MaxWorkers = 2
+		return NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), cache)
+	}
+
+	t.Run("Returns completed sections when partial results are allowed", func(t *testing.T) {
+		cache := &MockCache{}
+		opts := models.AnalyzeOptions{AllowPartial: true}
+		cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+
+		start := time.Now()
+		result, err := newDeadlineAnalyzer(cache).AnalyzeWithOptions(context.Background(), server.URL, opts)
+
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.True(t, result.Partial)
+		assert.Equal(t, []string{
+			constants.SectionHTMLVersion,
+			constants.SectionTitle,
+			constants.SectionHeadings,
+			constants.SectionDocumentIssues,
+			constants.SectionLoginForm,
+			constants.SectionForms,
+			constants.SectionConsentBanner,
+			constants.SectionInterstitial,
+			constants.SectionPaywall,
+			constants.SectionAccessibility,
+			constants.SectionCSPReadiness,
+			constants.SectionResources,
+			constants.SectionResourceHints,
+			constants.SectionLegacyIE,
+		}, result.CompletedSections)
+		assert.Equal(t, "Slow links", result.Title)
+		assert.Equal(t, 1, result.Headings["h1"])
+		assert.Equal(t, 10, result.Links.Internal)
+		assert.Equal(t, 0, result.Links.Inaccessible)
+		assert.Equal(t, map[string]int{constants.LinkSkipReasonDeadline: 10}, result.Links.Skipped)
+		cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fails with ANALYSIS_TIMEOUT by default", func(t *testing.T) {
+		cache := &MockCache{}
+		cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+
+		result, err := newDeadlineAnalyzer(cache).Analyze(context.Background(), server.URL)
+
+		assert.Nil(t, result)
+		var analysisErr *AnalysisError
+		require.ErrorAs(t, err, &analysisErr)
+		assert.Equal(t, constants.ErrorCodeAnalysisTimeout, analysisErr.Code)
+		assert.Equal(t, constants.StatusGatewayTimeout, analysisErr.Status)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Complete analyses are not partial", func(t *testing.T) {
+		cache := &MockCache{}
+		opts := models.AnalyzeOptions{AllowPartial: true, SkipLinkCheck: true}
+		cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), nil)
+		cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		result, err := newDeadlineAnalyzer(cache).AnalyzeWithOptions(context.Background(), server.URL, opts)
+
+		require.NoError(t, err)
+		assert.False(t, result.Partial)
+		assert.Nil(t, result.CompletedSections)
+		assert.Nil(t, result.Links.Skipped)
+	})
+}
+
+// MockStore is a mock implementation of AnalysisStore
+type MockStore struct {
+	mock.Mock
+}
+
+func (m *MockStore) Save(ctx context.Context, result *models.AnalyzeResponse) error {
+	args := m.Called(ctx, result)
+	return args.Error(0)
+}
+
+func TestAnalyzer_Analyze_PersistsResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Stored</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+
+	t.Run("Completed analysis is saved", func(t *testing.T) {
+		store := &MockStore{}
+		store.On("Save", mock.Anything, mock.MatchedBy(func(result *models.AnalyzeResponse) bool {
+			return result.URL == server.URL && result.Title == "Stored"
+		})).Return(nil).Once()
+
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+		analyzer.SetStore(store)
+
+		_, err := analyzer.Analyze(context.Background(), server.URL)
+		require.NoError(t, err)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("Every store saves the analysis", func(t *testing.T) {
+		first, second := &MockStore{}, &MockStore{}
+		first.On("Save", mock.Anything, mock.Anything).Return(nil).Once()
+		second.On("Save", mock.Anything, mock.Anything).Return(nil).Once()
+
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+		analyzer.SetStore(AnalysisStores{first, second})
+
+		_, err := analyzer.Analyze(context.Background(), server.URL)
+		require.NoError(t, err)
+		first.AssertExpectations(t)
+		second.AssertExpectations(t)
+	})
+
+	t.Run("Storage failure does not fail the analysis", func(t *testing.T) {
+		store := &MockStore{}
+		store.On("Save", mock.Anything, mock.Anything).Return(errors.New("database is locked")).Once()
+
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+		analyzer.SetStore(store)
+
+		result, err := analyzer.Analyze(context.Background(), server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "Stored", result.Title)
+		assert.Equal(t, []models.Warning{{Code: constants.WarningCodeStorageWriteFailed, Message: "The result could not be stored"}}, result.Warnings)
+		store.AssertExpectations(t)
+	})
+}
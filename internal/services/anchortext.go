@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// anchorTextIssues are the anchor text issues, worst first, the order offenders are listed in
+var anchorTextIssues = []string{
+	constants.AnchorTextEmpty,
+	constants.AnchorTextImageMissingAlt,
+	constants.AnchorTextGeneric,
+	constants.AnchorTextBareURL,
+}
+
+// anchorTextTally collects the anchor text quality of the anchors classified
+type anchorTextTally struct {
+	names     *a11yChecker // Names anchors as the accessibility checks do
+	stats     models.AnchorTextStats
+	named     int
+	length    int
+	offenders map[string][]models.AnchorTextOffender
+}
+
+func (a *Analyzer) newAnchorTextTally(ctx context.Context, doc *goquery.Document) *anchorTextTally {
+	return &anchorTextTally{
+		names:     &a11yChecker{doc: doc, generic: a.optionsFor(ctx).AnchorText.GenericPhrases},
+		offenders: make(map[string][]models.AnchorTextOffender),
+	}
+}
+
+// add judges the text of one resolved anchor, naming it from its text, image alts and ARIA
+// labels like a screen reader would
+func (t *anchorTextTally) add(s *goquery.Selection, link *url.URL) {
+	name := t.names.accessibleName(s)
+	imageLink := selectionText(s) == "" && s.Find("img").Length() > 0
+	if imageLink {
+		t.stats.ImageLinks++
+	}
+	if name != "" {
+		t.named++
+		t.length += utf8.RuneCountInString(name)
+	}
+
+	var issue string
+	switch {
+	case name == "" && imageLink:
+		t.stats.ImageLinksMissingAlt++
+		issue = constants.AnchorTextImageMissingAlt
+	case name == "":
+		t.stats.Empty++
+		issue = constants.AnchorTextEmpty
+	case isGenericLinkText(name, t.names.generic):
+		t.stats.Generic++
+		issue = constants.AnchorTextGeneric
+	case textDomain(name) != "":
+		t.stats.BareURL++
+		issue = constants.AnchorTextBareURL
+	default:
+		return
+	}
+
+	if len(t.offenders[issue]) < constants.AnchorTextMaxOffenders {
+		if runes := []rune(name); len(runes) > constants.AnchorTextMaxTextLength {
+			name = string(runes[:constants.AnchorTextMaxTextLength])
+		}
+		t.offenders[issue] = append(t.offenders[issue], models.AnchorTextOffender{URL: link.String(), Text: name, Issue: issue})
+	}
+}
+
+// result returns the collected stats, with the worst offenders first
+func (t *anchorTextTally) result() models.AnchorTextStats {
+	stats := t.stats
+	if t.named > 0 {
+		stats.AverageLength = math.Round(float64(t.length)/float64(t.named)*10) / 10
+	}
+	for _, issue := range anchorTextIssues {
+		for _, offender := range t.offenders[issue] {
+			if len(stats.Offenders) == constants.AnchorTextMaxOffenders {
+				return stats
+			}
+			stats.Offenders = append(stats.Offenders, offender)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_AnchorTextStats(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	html := loadFixture(t, "anchor_text.html")
+	pageURL := "https://notes.example.com/spring"
+
+	result, err := analyzer.AnalyzeHTML(context.Background(), html, pageURL, models.AnalyzeOptions{SkipLinkCheck: true, IncludeLinkDetails: true})
+	require.NoError(t, err)
+
+	stats := result.Links.AnchorTextStats
+	assert.Equal(t, 1, stats.Empty)
+	assert.Equal(t, 3, stats.ImageLinks)
+	assert.Equal(t, 2, stats.ImageLinksMissingAlt)
+	assert.Equal(t, 3, stats.Generic)
+	assert.Equal(t, 2, stats.BareURL)
+	// "Garden Notes home", "Open the menu", "Click here", "Read more »", "Weiterlesen", the URL,
+	// "example.org" and the chart link: 142 characters over 8 named anchors
+	assert.Equal(t, 17.8, stats.AverageLength)
+	assert.Equal(t, []models.AnchorTextOffender{
+		{URL: "https://notes.example.com/cart", Issue: constants.AnchorTextEmpty},
+		{URL: "https://notes.example.com/search", Issue: constants.AnchorTextImageMissingAlt},
+		{URL: "https://notes.example.com/gallery", Issue: constants.AnchorTextImageMissingAlt},
+		{URL: "https://notes.example.com/guides/tulips", Text: "Click here", Issue: constants.AnchorTextGeneric},
+		{URL: "https://notes.example.com/guides/crocus", Text: "Read more »", Issue: constants.AnchorTextGeneric},
+		{URL: "https://notes.example.com/guides/daffodils", Text: "Weiterlesen", Issue: constants.AnchorTextGeneric},
+		{URL: "https://bulbs.example.net/spring", Text: "https://bulbs.example.net/spring", Issue: constants.AnchorTextBareURL},
+		{URL: "https://www.example.org/", Text: "example.org", Issue: constants.AnchorTextBareURL},
+	}, stats.Offenders)
+
+	t.Run("Offenders only with link details", func(t *testing.T) {
+		result, err := analyzer.AnalyzeHTML(context.Background(), html, pageURL, models.AnalyzeOptions{SkipLinkCheck: true})
+		require.NoError(t, err)
+		assert.Nil(t, result.Links.AnchorTextStats.Offenders)
+		assert.Equal(t, 3, result.Links.AnchorTextStats.Generic)
+	})
+}
+
+func TestAnalyzer_AnchorTextStats_ConfiguredPhrases(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Analyzer.AnchorText.GenericPhrases = []string{"  Voir Plus ", "read more"}
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html lang="fr"><body>
+		<a href="/a">Voir plus…</a>
+		<a href="/b">Read more</a>
+		<a href="/c">Click here</a>
+		<a href="/d">Voir plus de recettes</a>
+	</body></html>`))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse("https://example.com/")
+
+	analysis, _, _ := analyzer.classifyLinks(context.Background(), doc, baseURL, domLimits{})
+	assert.Equal(t, 2, analysis.AnchorTextStats.Generic, "only the configured phrases, matched whole, are generic")
+
+	// The accessibility rule reads the same phrases
+	report := analyzer.checkAccessibility(context.Background(), doc, domLimits{})
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, constants.A11yRuleLinkGenericText, report.Issues[0].Rule)
+	assert.Equal(t, 2, report.Issues[0].Count)
+}
+
+func TestAnchorTextTally_Offenders(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	body := strings.Repeat(`<a href="/more">more</a>`, constants.AnchorTextMaxOffenders) + `<a href="/empty"></a>` +
+		`<a href="/long">https://example.com/` + strings.Repeat("a", constants.AnchorTextMaxTextLength) + `</a>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>` + body + `</body></html>`))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse("https://example.com/")
+
+	analysis, _, _ := analyzer.classifyLinks(context.Background(), doc, baseURL, domLimits{})
+	stats := analysis.AnchorTextStats
+	assert.Equal(t, constants.AnchorTextMaxOffenders, stats.Generic)
+	assert.Equal(t, 1, stats.BareURL)
+	require.Len(t, stats.Offenders, constants.AnchorTextMaxOffenders)
+	assert.Equal(t, constants.AnchorTextEmpty, stats.Offenders[0].Issue, "the worst offenders come first")
+	assert.Equal(t, constants.AnchorTextGeneric, stats.Offenders[len(stats.Offenders)-1].Issue)
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(`<html><body>` + body[strings.Index(body, `<a href="/long">`):] + `</body></html>`))
+	require.NoError(t, err)
+	analysis, _, _ = analyzer.classifyLinks(context.Background(), doc, baseURL, domLimits{})
+	require.Len(t, analysis.AnchorTextStats.Offenders, 1)
+	assert.Len(t, analysis.AnchorTextStats.Offenders[0].Text, constants.AnchorTextMaxTextLength)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// credentials are the caller's options.auth, sent to the analyzed host only
+type credentials struct {
+	scheme string
+	host   string
+	auth   *models.AnalyzeAuth
+}
+
+type credentialsContextKey struct{}
+
+// withCredentials attaches the credentials for the target's host to the context; nil auth attaches nothing
+func withCredentials(ctx context.Context, target *url.URL, auth *models.AnalyzeAuth) context.Context {
+	if auth == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialsContextKey{}, &credentials{scheme: target.Scheme, host: target.Host, auth: auth})
+}
+
+// withURLCredentials returns auth with the user:password@ of the target URL as its basic
+// credentials, so URL credentials are sent, and cached apart, like options.auth. Basic
+// credentials in auth take precedence, as they would on the wire.
+func withURLCredentials(target *url.URL, auth *models.AnalyzeAuth) *models.AnalyzeAuth {
+	if target.User == nil || (auth != nil && auth.Basic != nil) {
+		return auth
+	}
+	merged := &models.AnalyzeAuth{}
+	if auth != nil {
+		*merged = *auth
+	}
+	password, _ := target.User.Password()
+	merged.Basic = &models.BasicAuth{Username: target.User.Username(), Password: password}
+	return merged
+}
+
+// credentialsFrom returns the credentials of the analysis running under ctx, if any
+func credentialsFrom(ctx context.Context) *credentials {
+	creds, _ := ctx.Value(credentialsContextKey{}).(*credentials)
+	return creds
+}
+
+// apply sets the credentials on a request to the analyzed host, and removes them from a request
+// to any other host or downgraded to plain http, such as a redirect leaving the site
+func (c *credentials) apply(req *http.Request) {
+	if c == nil {
+		return
+	}
+
+	if normalizedHost(req.URL) != c.host || (c.scheme == "https" && req.URL.Scheme != "https") {
+		for name := range c.auth.Headers {
+			req.Header.Del(name)
+		}
+		if c.auth.Basic != nil {
+			req.Header.Del(constants.HeaderAuthorization)
+		}
+		if len(c.auth.Cookies) > 0 {
+			req.Header.Del(constants.HeaderCookie)
+		}
+		return
+	}
+
+	if c.auth.Basic != nil {
+		req.SetBasicAuth(c.auth.Basic.Username, c.auth.Basic.Password)
+	}
+	if len(c.auth.Cookies) > 0 {
+		// Redirects copy the cookies of the original request; set them once
+		req.Header.Del(constants.HeaderCookie)
+		for _, cookie := range c.auth.Cookies {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+	for name, value := range c.auth.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// checkCredentialsAllowed rejects credentials, from options.auth or the URL, for hosts
+// the configuration marks as public-only
+func (a *Analyzer) checkCredentialsAllowed(ctx context.Context, target *url.URL, auth *models.AnalyzeAuth) error {
+	if auth == nil && target.User == nil {
+		return nil
+	}
+
+	host := strings.ToLower(target.Hostname())
+	for _, publicOnly := range a.optionsFor(ctx).Auth.PublicOnlyHosts {
+		publicOnly = strings.ToLower(strings.TrimSpace(publicOnly))
+		if publicOnly != "" && hostMatches(host, publicOnly) {
+			return &AnalysisError{
+				Code:    constants.ErrorCodeCredentialsNotAllowed,
+				Status:  constants.StatusBadRequest,
+				Message: fmt.Sprintf("Host %s is analyzed without credentials only", host),
+			}
+		}
+	}
+	return nil
+}
+
+// authCacheVariant returns the cache key variant of a set of credentials. The key holds a hash
+// of the credentials, never the credentials themselves, so views behind different credentials are
+// cached apart.
+func authCacheVariant(auth *models.AnalyzeAuth) string {
+	// Sort cookies and headers so the same credentials always hash the same
+	cookies := make([]models.AuthCookie, len(auth.Cookies))
+	copy(cookies, auth.Cookies)
+	sort.Slice(cookies, func(i, j int) bool {
+		if cookies[i].Name != cookies[j].Name {
+			return cookies[i].Name < cookies[j].Name
+		}
+		return cookies[i].Value < cookies[j].Value
+	})
+	headers := make(map[string]string, len(auth.Headers))
+	for name, value := range auth.Headers {
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	// Maps are marshaled with sorted keys
+	data, _ := json.Marshal(models.AnalyzeAuth{Basic: auth.Basic, Cookies: cookies, Headers: headers})
+	sum := sha256.Sum256(data)
+	return constants.CacheVariantAuth + "=" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// Signatures of bot-protection challenge pages. Markers are matched against the lowercased body.
+var (
+	cloudflareChallengeMarkers = []string{"cf_chl_opt", "cf-challenge", "cf-browser-verification", "challenges.cloudflare.com/turnstile"}
+	cloudflareChallengeTitles  = []string{"just a moment...", "attention required! | cloudflare", "please wait... | cloudflare"}
+	akamaiBlockMarkers         = []string{"errors.edgesuite.net", "akamai reference"}
+	dataDomeMarkers            = []string{"captcha-delivery.com", "geo.captcha-delivery.com"}
+	perimeterXMarkers          = []string{"px-captcha", "_pxappid"}
+	genericChallengeTitles     = []string{"access denied", "security check", "are you a robot?", "verify you are human", "please verify you are a human", "captcha"}
+	recaptchaScriptMarkers     = []string{"google.com/recaptcha/", "gstatic.com/recaptcha/", "recaptcha.net/recaptcha/"}
+	hcaptchaScriptMarkers      = []string{"hcaptcha.com/1/api.js", "js.hcaptcha.com"}
+)
+
+// detectBotProtection returns the provider whose challenge page was served instead of the content,
+// or an empty string when the page looks genuine
+func detectBotProtection(page *fetchResult, doc *goquery.Document) string {
+	body := strings.ToLower(page.body)
+	title := strings.ToLower(strings.TrimSpace(doc.Find("title").First().Text()))
+	server := strings.ToLower(page.header.Get("Server"))
+	blocked := page.statusCode == constants.StatusForbidden ||
+		page.statusCode == constants.StatusTooManyRequests ||
+		page.statusCode == constants.StatusServiceUnavailable
+
+	// Cloudflare flags challenges explicitly; otherwise require a challenge marker or title
+	if strings.EqualFold(page.header.Get("Cf-Mitigated"), "challenge") {
+		return constants.BotProviderCloudflare
+	}
+	cloudflareTitle := slices.Contains(cloudflareChallengeTitles, title)
+	if containsAny(body, cloudflareChallengeMarkers) && (blocked || cloudflareTitle) {
+		return constants.BotProviderCloudflare
+	}
+	if cloudflareTitle && (blocked || strings.Contains(server, "cloudflare")) {
+		return constants.BotProviderCloudflare
+	}
+
+	if blocked && (strings.Contains(server, "akamaighost") || containsAny(body, akamaiBlockMarkers)) {
+		return constants.BotProviderAkamai
+	}
+	if (blocked && page.header.Get("X-Datadome") != "") || containsAny(body, dataDomeMarkers) {
+		return constants.BotProviderDataDome
+	}
+	if blocked && containsAny(body, perimeterXMarkers) {
+		return constants.BotProviderPerimeterX
+	}
+
+	// Captcha widgets are common on genuine pages, so they only count on a page that is otherwise a challenge
+	challengeLike := blocked || containsAny(title, genericChallengeTitles) ||
+		doc.Find("a[href]").Length() <= constants.BotChallengeMaxLinks
+	if !challengeLike {
+		return ""
+	}
+	if hasScriptSrc(doc, recaptchaScriptMarkers) || doc.Find(".g-recaptcha").Length() > 0 {
+		return constants.BotProviderRecaptcha
+	}
+	if hasScriptSrc(doc, hcaptchaScriptMarkers) || doc.Find(".h-captcha").Length() > 0 {
+		return constants.BotProviderHCaptcha
+	}
+
+	return ""
+}
+
+// hasScriptSrc reports whether any script src contains one of the markers
+func hasScriptSrc(doc *goquery.Document, markers []string) bool {
+	found := false
+	doc.Find("script[src]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		found = containsAny(strings.ToLower(s.AttrOr("src", "")), markers)
+		return !found
+	})
+	return found
+}
+
+// containsAny reports whether s contains one of the candidates
+func containsAny(s string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if strings.Contains(s, candidate) {
+			return true
+		}
+	}
+	return false
+}
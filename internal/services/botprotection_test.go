@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+func loadFixture(t *testing.T, name string) string {
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestDetectBotProtection(t *testing.T) {
+	cloudflare := loadFixture(t, "cloudflare_challenge.html")
+	recaptcha := loadFixture(t, "recaptcha_challenge.html")
+	contact := loadFixture(t, "contact_with_recaptcha.html")
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		header   http.Header
+		expected string
+	}{
+		{
+			name:     "Cloudflare challenge flagged by header",
+			body:     cloudflare,
+			status:   http.StatusForbidden,
+			header:   http.Header{"Cf-Mitigated": {"challenge"}, "Server": {"cloudflare"}},
+			expected: constants.BotProviderCloudflare,
+		},
+		{
+			name:     "Cloudflare challenge recognized from markup",
+			body:     cloudflare,
+			status:   http.StatusServiceUnavailable,
+			header:   http.Header{},
+			expected: constants.BotProviderCloudflare,
+		},
+		{
+			name:     "Cloudflare challenge title served with 200",
+			body:     `<html><head><title>Just a moment...</title></head><body></body></html>`,
+			status:   http.StatusOK,
+			header:   http.Header{"Server": {"cloudflare"}},
+			expected: constants.BotProviderCloudflare,
+		},
+		{
+			name:     "reCAPTCHA interstitial",
+			body:     recaptcha,
+			status:   http.StatusOK,
+			header:   http.Header{},
+			expected: constants.BotProviderRecaptcha,
+		},
+		{
+			name:     "hCaptcha interstitial",
+			body:     `<html><head><title>Security check</title><script src="https://js.hcaptcha.com/1/api.js"></script></head><body><div class="h-captcha"></div></body></html>`,
+			status:   http.StatusOK,
+			header:   http.Header{},
+			expected: constants.BotProviderHCaptcha,
+		},
+		{
+			name:     "Akamai block page",
+			body:     `<html><head><title>Access Denied</title></head><body><h1>Access Denied</h1>Reference #18.7d2c1402.1684863076.1a2b3c</body></html>`,
+			status:   http.StatusForbidden,
+			header:   http.Header{"Server": {"AkamaiGHost"}},
+			expected: constants.BotProviderAkamai,
+		},
+		{
+			name:     "DataDome captcha",
+			body:     `<html><body><script src="https://ct.captcha-delivery.com/c.js"></script></body></html>`,
+			status:   http.StatusForbidden,
+			header:   http.Header{"X-Datadome": {"protected"}},
+			expected: constants.BotProviderDataDome,
+		},
+		{
+			name:     "Genuine page with a reCAPTCHA form",
+			body:     contact,
+			status:   http.StatusOK,
+			header:   http.Header{"Server": {"cloudflare"}},
+			expected: "",
+		},
+		{
+			name:     "Plain forbidden page",
+			body:     `<html><head><title>403 Forbidden</title></head><body><h1>Forbidden</h1></body></html>`,
+			status:   http.StatusForbidden,
+			header:   http.Header{"Server": {"nginx"}},
+			expected: "",
+		},
+		{
+			name:     "Rendered page without headers",
+			body:     `<html><head><title>Home</title></head><body><h1>Welcome</h1></body></html>`,
+			status:   http.StatusOK,
+			header:   nil,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.body))
+			require.NoError(t, err)
+
+			page := &fetchResult{body: tt.body, statusCode: tt.status, header: tt.header}
+			assert.Equal(t, tt.expected, detectBotProtection(page, doc))
+		})
+	}
+}
+
+func newChallengeServer(t *testing.T) *httptest.Server {
+	body := loadFixture(t, "cloudflare_challenge.html")
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestAnalyzer_BotProtection(t *testing.T) {
+	server := newChallengeServer(t)
+	defer server.Close()
+
+	t.Run("Fails with BOT_PROTECTION by default", func(t *testing.T) {
+		cache := &MockCache{}
+		cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+		result, err := analyzer.Analyze(context.Background(), server.URL)
+
+		assert.Nil(t, result)
+		var analysisErr *AnalysisError
+		require.True(t, errors.As(err, &analysisErr))
+		assert.Equal(t, constants.ErrorCodeBotProtection, analysisErr.Code)
+		assert.Equal(t, constants.StatusBadGateway, analysisErr.Status)
+		assert.Contains(t, err.Error(), "status code 403")
+		cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Tags the result without caching it", func(t *testing.T) {
+		cfg := createTestConfig()
+		cfg.Analyzer.BotProtection.Action = constants.BotProtectionActionTag
+		cache := &MockCache{}
+		cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+		analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+		result, err := analyzer.Analyze(context.Background(), server.URL)
+
+		require.NoError(t, err)
+		assert.True(t, result.BotProtectionDetected)
+		assert.Equal(t, constants.BotProviderCloudflare, result.BotProtectionProvider)
+		assert.Equal(t, "Just a moment...", result.Title)
+		assert.Zero(t, result.CacheTTL)
+		cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
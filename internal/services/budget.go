@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+)
+
+// errBudgetExhausted is returned for outbound requests beyond the per-analysis budget
+var errBudgetExhausted = errors.New("outbound request budget exhausted")
+
+// requestBudget bounds the outbound requests of one analysis: the page fetch, redirects,
+// link checks and origin probes. A nil budget allows every request.
+type requestBudget struct {
+	limit   int64         // Maximum requests for the analysis; 0 means unlimited
+	limiter *rate.Limiter // Global limiter shared across analyses, or nil
+	metrics *metrics.Metrics
+	used    atomic.Int64
+}
+
+type budgetContextKey struct{}
+
+// withBudget attaches a fresh request budget for one analysis to the context
+func (a *Analyzer) withBudget(ctx context.Context) (context.Context, *requestBudget) {
+	budget := &requestBudget{
+		limit:   int64(a.optionsFor(ctx).Budget.MaxOutboundRequestsPerAnalysis),
+		limiter: a.outboundLimiter,
+		metrics: a.metrics,
+	}
+	return context.WithValue(ctx, budgetContextKey{}, budget), budget
+}
+
+// budgetFrom returns the request budget of the analysis running under ctx, if any
+func budgetFrom(ctx context.Context) *requestBudget {
+	budget, _ := ctx.Value(budgetContextKey{}).(*requestBudget)
+	return budget
+}
+
+// acquire reserves one outbound request, waiting on the global limiter when configured
+func (b *requestBudget) acquire(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	for {
+		used := b.used.Load()
+		if b.limit > 0 && used >= b.limit {
+			b.metrics.OutboundRequests.WithLabelValues(constants.OutboundOutcomeBudgetExhausted).Inc()
+			return errBudgetExhausted
+		}
+		if b.used.CompareAndSwap(used, used+1) {
+			break
+		}
+	}
+
+	if b.limiter != nil {
+		if err := b.limiter.Wait(ctx); err != nil {
+			// The request was never sent; give the slot back
+			b.used.Add(-1)
+			return err
+		}
+	}
+	b.metrics.OutboundRequests.WithLabelValues(constants.OutboundOutcomeSent).Inc()
+	statsFrom(ctx).request()
+	return nil
+}
+
+// consumed returns the number of outbound requests sent so far
+func (b *requestBudget) consumed() int {
+	if b == nil {
+		return 0
+	}
+	return int(b.used.Load())
+}
+
+// observe records the budget consumption of a finished analysis
+func (b *requestBudget) observe() {
+	if b == nil {
+		return
+	}
+	b.metrics.AnalysisOutboundRequests.Observe(float64(b.consumed()))
+}
+
+// newOutboundLimiter returns the global outbound limiter, or nil when no rate is configured
+func newOutboundLimiter(requestsPerSecond float64, burst int) *rate.Limiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = max(1, int(requestsPerSecond))
+	}
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+}
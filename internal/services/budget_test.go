@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newCountingLinkServer serves a page with the given number of internal links and counts every request it receives
+func newCountingLinkServer(t *testing.T, links int) (*httptest.Server, *atomic.Int64) {
+	var hits atomic.Int64
+	var page strings.Builder
+	page.WriteString(`<html><head><title>Link farm</title></head><body><h1>Links</h1>`)
+	for i := 0; i < links; i++ {
+		fmt.Fprintf(&page, `<a href="/page/%d">Page %d</a>`, i, i)
+	}
+	page.WriteString(`</body></html>`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page.String()))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+// outboundBudget caps the outbound requests of each analysis at maxRequests
+func outboundBudget(maxRequests int) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Analyzer.Budget.MaxOutboundRequestsPerAnalysis = maxRequests
+	}
+}
+
+func TestAnalyzer_BudgetCapsLinkChecksAndProbes(t *testing.T) {
+	const links = 10
+	const budget = 5
+	server, hits := newCountingLinkServer(t, links)
+	analyzer := newTestAnalyzer(t, outboundBudget(budget))
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{OriginChecks: true})
+	require.NoError(t, err)
+
+	// The page fetch, the origin probes and the link checks share one budget
+	assert.Equal(t, int64(budget), hits.Load())
+	require.NotNil(t, result.Fetch)
+	assert.Equal(t, budget, result.Fetch.OutboundRequests)
+	assert.Equal(t, budget, result.Fetch.OutboundBudget)
+
+	overBudget := result.Links.Skipped[constants.LinkSkipReasonBudget]
+	assert.GreaterOrEqual(t, overBudget, links-(budget-1))
+	assert.Zero(t, result.Links.Inaccessible)
+
+	sent := testutil.ToFloat64(analyzer.metrics.OutboundRequests.WithLabelValues(constants.OutboundOutcomeSent))
+	assert.Equal(t, float64(budget), sent)
+	denied := testutil.ToFloat64(analyzer.metrics.OutboundRequests.WithLabelValues(constants.OutboundOutcomeBudgetExhausted))
+	assert.GreaterOrEqual(t, denied, float64(overBudget))
+}
+
+func TestAnalyzer_BudgetUnlimitedByDefault(t *testing.T) {
+	const links = 6
+	server, hits := newCountingLinkServer(t, links)
+	analyzer := newTestAnalyzer(t, nil)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(links+1), hits.Load())
+	assert.Equal(t, links+1, result.Fetch.OutboundRequests)
+	assert.Zero(t, result.Fetch.OutboundBudget)
+	assert.Nil(t, result.Links.Skipped)
+}
+
+func TestAnalyzer_BudgetAppliesToSubmittedHTML(t *testing.T) {
+	server, hits := newCountingLinkServer(t, 0)
+	analyzer := newTestAnalyzer(t, outboundBudget(2))
+
+	html := `<html><body><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a><a href="/d">D</a></body></html>`
+	result, err := analyzer.AnalyzeHTML(context.Background(), html, server.URL, models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), hits.Load())
+	assert.Equal(t, 2, result.Links.Skipped[constants.LinkSkipReasonBudget])
+}
+
+func TestRequestBudget_GlobalLimiter(t *testing.T) {
+	analyzer := newTestAnalyzer(t, nil)
+	analyzer.outboundLimiter = newOutboundLimiter(20, 1)
+
+	// The limiter is shared, so requests of separate analyses are paced together
+	ctxA, budgetA := analyzer.withBudget(context.Background())
+	ctxB, budgetB := analyzer.withBudget(context.Background())
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, budgetA.acquire(ctxA))
+		require.NoError(t, budgetB.acquire(ctxB))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Equal(t, 3, budgetA.consumed())
+
+	// Waiting past the deadline fails without consuming the budget
+	ctx, cancel := context.WithTimeout(ctxA, 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, budgetA.acquire(ctx))
+	assert.Equal(t, 3, budgetA.consumed())
+}
+
+func TestRequestBudget_Nil(t *testing.T) {
+	var budget *requestBudget
+	assert.NoError(t, budget.acquire(context.Background()))
+	assert.Zero(t, budget.consumed())
+	assert.Nil(t, budgetFrom(context.Background()))
+}
+
+func TestNewOutboundLimiter(t *testing.T) {
+	assert.Nil(t, newOutboundLimiter(0, 10))
+
+	limiter := newOutboundLimiter(5, 0)
+	require.NotNil(t, limiter)
+	assert.Equal(t, 5, limiter.Burst())
+
+	assert.Equal(t, 1, newOutboundLimiter(0.5, 0).Burst())
+}
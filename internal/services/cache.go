@@ -1,9 +1,15 @@
 package services
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -17,10 +23,19 @@ import (
 
 
 type Cache struct {
-	client  *redis.Client
-	logger  *zap.Logger
-	metrics *metrics.Metrics
-	ttl     time.Duration
+	client    *redis.Client
+	logger    *zap.Logger
+	metrics   *metrics.Metrics
+	ttl       time.Duration
+	ttlJitter float64 // Fraction of the TTL entry expiries are spread over, either way
+	dedicated bool    // The Redis DB holds nothing but cache entries
+	scanLimit int64   // Keys Stats counts before reporting the count as capped
+	duplicates    bool // Index titles and meta descriptions of the cached entries
+	duplicatesMax int  // URLs indexed per title or meta description of a site
+
+	// Lookups since the process started, for Stats
+	hits   atomic.Int64
+	misses atomic.Int64
 }
 
 // NoOpCache implements the CacheInterface but doesn't cache anything
@@ -55,46 +70,80 @@ func NewCache(cfg *config.Config, logger *zap.Logger, metrics *metrics.Metrics)
 	}
 
 	return &Cache{
-		client:  client,
-		logger:  logger,
-		metrics: metrics,
-		ttl:     cfg.Cache.TTL,
+		client:    client,
+		logger:    logger,
+		metrics:   metrics,
+		ttl:       cfg.Cache.TTL,
+		ttlJitter: min(max(cfg.Cache.TTLJitter, 0), 1),
+		dedicated: cfg.Cache.DedicatedDB,
+		scanLimit: constants.CacheStatsMaxScannedKeys,
+		duplicates:    cfg.Cache.Duplicates.Enabled,
+		duplicatesMax: cmp.Or(cfg.Cache.Duplicates.MaxURLs, constants.DefaultDuplicateIndexMaxURLs),
 	}, nil
 }
 
-// Get retrieves cached analysis results
-func (c *Cache) Get(ctx context.Context, url string) (*models.AnalyzeResponse, error) {
+// Get retrieves cached analysis results along with the remaining TTL of the entry. A
+// corrupted entry is deleted and reported as a miss, so the analysis that follows rewrites it.
+func (c *Cache) Get(ctx context.Context, url string) (*models.AnalyzeResponse, time.Duration, error) {
 	// If this is a no-op cache (client is nil), always return cache miss
 	if c.client == nil {
 		c.logger.Debug("No-op cache: skipping get", zap.String("url", url))
-		return nil, nil
+		c.misses.Add(1)
+		return nil, 0, nil
+	}
+
+	start := time.Now()
+
+	// Fetch the value and its TTL in a single round trip
+	var getCmd *redis.StringCmd
+	var ttlCmd *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(ctx, c.key(url))
+		ttlCmd = pipe.PTTL(ctx, c.key(url))
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		c.observe(ctx, constants.CacheOpGet, start, err)
+		return nil, 0, fmt.Errorf("failed to get from cache: %w", err)
 	}
 
-	data, err := c.client.Get(ctx, c.key(url)).Bytes()
+	data, err := getCmd.Bytes()
 	if err == redis.Nil {
+		c.observe(ctx, constants.CacheOpGet, start, nil)
+		c.misses.Add(1)
 		if c.metrics != nil {
 			c.metrics.CacheMisses.Inc()
 		}
-		return nil, nil
+		return nil, 0, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get from cache: %w", err)
+		c.observe(ctx, constants.CacheOpGet, start, err)
+		return nil, 0, fmt.Errorf("failed to get from cache: %w", err)
 	}
 
-	var result models.AnalyzeResponse
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal cached data: %w", err)
+	c.observe(ctx, constants.CacheOpGet, start, nil)
+	result, err := decodeEntry(data)
+	if err != nil {
+		c.dropCorrupted(ctx, map[string]corruptedEntry{url: {data: string(data), err: err}})
+		return nil, 0, nil
+	}
+
+	// Negative values mean the key has no expiry or has just expired
+	ttl := ttlCmd.Val()
+	if ttl < 0 {
+		ttl = 0
 	}
 
+	c.hits.Add(1)
 	if c.metrics != nil {
 		c.metrics.CacheHits.Inc()
 	}
-	c.logger.Debug("Cache hit", zap.String("url", url))
-	return &result, nil
+	c.logger.Debug("Cache hit", zap.String("url", url), zap.Duration("ttl", ttl))
+	return result, ttl, nil
 }
 
-// Set stores analysis results in cache
-func (c *Cache) Set(ctx context.Context, url string, result *models.AnalyzeResponse) error {
+// Set stores analysis results in cache for ttl, or for the configured TTL when ttl is 0
+func (c *Cache) Set(ctx context.Context, url string, result *models.AnalyzeResponse, ttl time.Duration) error {
 	// If this is a no-op cache (client is nil), do nothing
 	if c.client == nil {
 		c.logger.Debug("No-op cache: skipping set", zap.String("url", url))
@@ -106,13 +155,338 @@ func (c *Cache) Set(ctx context.Context, url string, result *models.AnalyzeRespo
 		return fmt.Errorf("failed to marshal data: %w", err)
 	}
 
-	if err := c.client.Set(ctx, c.key(url), data, c.ttl).Err(); err != nil {
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+	ttl = c.entryTTL(ttl)
+	start := time.Now()
+	err = c.client.Set(ctx, c.key(url), data, ttl).Err()
+	c.observe(ctx, constants.CacheOpSet, start, err)
+	if err != nil {
 		return fmt.Errorf("failed to set cache: %w", err)
 	}
 
+	// The entry is cached either way; a stale index only misses duplicates
+	if c.duplicates {
+		if err := c.indexDuplicates(ctx, url, result, ttl); err != nil {
+			c.logger.Warn("Failed to index duplicates", zap.String("url", url), zap.Error(err))
+		}
+	}
 	return nil
 }
 
+// GetMulti retrieves the cached analysis results of urls in a single MGET round trip. URLs
+// without an entry are left out of the map, as are those whose entry is corrupted, which is
+// deleted like on Get.
+func (c *Cache) GetMulti(ctx context.Context, urls []string) (map[string]*models.AnalyzeResponse, error) {
+	results := make(map[string]*models.AnalyzeResponse, len(urls))
+	// If this is a no-op cache (client is nil), every URL misses
+	if c.client == nil {
+		c.logger.Debug("No-op cache: skipping get", zap.Int("urls", len(urls)))
+		c.misses.Add(int64(len(urls)))
+		return results, nil
+	}
+	if len(urls) == 0 {
+		return results, nil
+	}
+
+	keys := make([]string, len(urls))
+	for i, url := range urls {
+		keys[i] = c.key(url)
+	}
+	start := time.Now()
+	values, err := c.client.MGet(ctx, keys...).Result()
+	c.observe(ctx, constants.CacheOpGetMulti, start, err)
+	if err != nil {
+		return results, fmt.Errorf("failed to get from cache: %w", err)
+	}
+
+	corrupted := make(map[string]corruptedEntry)
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			c.misses.Add(1)
+			if c.metrics != nil {
+				c.metrics.CacheMisses.Inc()
+			}
+			continue
+		}
+		result, err := decodeEntry([]byte(data))
+		if err != nil {
+			corrupted[urls[i]] = corruptedEntry{data: data, err: err}
+			continue
+		}
+		c.hits.Add(1)
+		if c.metrics != nil {
+			c.metrics.CacheHits.Inc()
+		}
+		results[urls[i]] = result
+	}
+	c.dropCorrupted(ctx, corrupted)
+	c.logger.Debug("Cache multi-get", zap.Int("urls", len(urls)), zap.Int("hits", len(results)))
+	return results, nil
+}
+
+// decodeEntry unmarshals a cached analysis and checks the fields every stored analysis has,
+// so that truncated or hand-edited values are told apart from results
+func decodeEntry(data []byte) (*models.AnalyzeResponse, error) {
+	var result models.AnalyzeResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached data: %w", err)
+	}
+	if result.URL == "" {
+		return nil, errors.New("cached analysis has no URL")
+	}
+	if result.AnalyzedAt.IsZero() {
+		return nil, errors.New("cached analysis has no analysis time")
+	}
+	return &result, nil
+}
+
+// corruptedEntry is a cached value that failed to decode, and why
+type corruptedEntry struct {
+	data string
+	err  error
+}
+
+// deleteUnchanged deletes each of KEYS whose value is still the ARGV of the same index, so
+// an entry rewritten since it was read is kept. It returns the number of keys deleted.
+var deleteUnchanged = redis.NewScript(`
+local deleted = 0
+for i, key in ipairs(KEYS) do
+	if redis.call("GET", key) == ARGV[i] then
+		deleted = deleted + redis.call("DEL", key)
+	end
+end
+return deleted
+`)
+
+// dropCorrupted counts the corrupted entries of the URLs as misses and deletes them, best
+// effort, so the next Set rewrites them instead of every lookup failing on them. An entry a
+// concurrent Set rewrote since it was read is left alone.
+func (c *Cache) dropCorrupted(ctx context.Context, corrupted map[string]corruptedEntry) {
+	if len(corrupted) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(corrupted))
+	values := make([]any, 0, len(corrupted))
+	for url, entry := range corrupted {
+		c.logger.Warn("Deleting corrupted cache entry", zap.String("url", url), zap.Error(entry.err))
+		keys = append(keys, c.key(url))
+		values = append(values, entry.data)
+	}
+	c.misses.Add(int64(len(corrupted)))
+	if c.metrics != nil {
+		c.metrics.CacheMisses.Add(float64(len(corrupted)))
+		c.metrics.CacheCorruption.Add(float64(len(corrupted)))
+	}
+
+	start := time.Now()
+	err := deleteUnchanged.Run(ctx, c.client, keys, values...).Err()
+	c.observe(ctx, constants.CacheOpDelete, start, err)
+	if err != nil {
+		c.logger.Warn("Failed to delete corrupted cache entries", zap.Int("keys", len(keys)), zap.Error(err))
+	}
+}
+
+// SetMulti stores analysis results by URL like Set, pipelining the writes into a single
+// round trip. The URLs whose result was not stored are reported in a KeyErrors.
+func (c *Cache) SetMulti(ctx context.Context, results map[string]*models.AnalyzeResponse, ttl time.Duration) error {
+	// If this is a no-op cache (client is nil), do nothing
+	if c.client == nil {
+		c.logger.Debug("No-op cache: skipping set", zap.Int("urls", len(results)))
+		return nil
+	}
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+
+	failed := KeyErrors{}
+	cmds := make(map[string]*redis.StatusCmd, len(results))
+	ttls := make(map[string]time.Duration, len(results))
+	start := time.Now()
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for url, result := range results {
+			data, err := json.Marshal(result)
+			if err != nil {
+				failed[url] = fmt.Errorf("failed to marshal data: %w", err)
+				continue
+			}
+			ttls[url] = c.entryTTL(ttl)
+			cmds[url] = pipe.Set(ctx, c.key(url), data, ttls[url])
+		}
+		return nil
+	})
+	if len(cmds) > 0 {
+		c.observe(ctx, constants.CacheOpSetMulti, start, err)
+	}
+
+	for url, cmd := range cmds {
+		// Commands keep no error of their own when the pipeline failed to run at all
+		if err := cmp.Or(cmd.Err(), err); err != nil {
+			failed[url] = fmt.Errorf("failed to set cache: %w", err)
+			continue
+		}
+		// The entry is cached either way; a stale index only misses duplicates
+		if c.duplicates {
+			if err := c.indexDuplicates(ctx, url, results[url], ttls[url]); err != nil {
+				c.logger.Warn("Failed to index duplicates", zap.String("url", url), zap.Error(err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// KeyErrors reports the URLs a multi-key cache operation failed for; the operation succeeded
+// for the others
+type KeyErrors map[string]error
+
+func (e KeyErrors) Error() string {
+	urls := slices.Sorted(maps.Keys(e))
+	if len(urls) == 1 {
+		return fmt.Sprintf("cache operation failed for %s: %v", urls[0], e[urls[0]])
+	}
+	return fmt.Sprintf("cache operation failed for %d URLs, first %s: %v", len(urls), urls[0], e[urls[0]])
+}
+
+// entryTTL returns the TTL an entry is stored with: ttl moved randomly by up to the jitter
+// fraction either way, so entries cached at the same moment do not all expire together
+func (c *Cache) entryTTL(ttl time.Duration) time.Duration {
+	if c.ttlJitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	offset := (rand.Float64()*2 - 1) * c.ttlJitter * float64(ttl)
+	if jittered := ttl + time.Duration(offset); jittered > 0 {
+		return jittered
+	}
+	return ttl
+}
+
+// Delete removes a cached analysis result
+func (c *Cache) Delete(ctx context.Context, url string) error {
+	// If this is a no-op cache (client is nil), do nothing
+	if c.client == nil {
+		c.logger.Debug("No-op cache: skipping delete", zap.String("url", url))
+		return nil
+	}
+
+	start := time.Now()
+	err := c.client.Del(ctx, c.key(url)).Err()
+	c.observe(ctx, constants.CacheOpDelete, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete from cache: %w", err)
+	}
+
+	return nil
+}
+
+// Scan calls fn with every cached analysis, fetching the webpage:* keyspace in SCAN batches so
+// memory stays bounded by the batch size. Entries that expire or fail to decode during the scan are
+// skipped. SCAN may return a key more than once under concurrent rehashing, so fn can see duplicates.
+// An error returned by fn stops the scan and is returned as is.
+func (c *Cache) Scan(ctx context.Context, fn func(*models.AnalyzeResponse) error) error {
+	// If this is a no-op cache (client is nil), there is nothing to scan
+	if c.client == nil {
+		return nil
+	}
+
+	var cursor uint64
+	for {
+		start := time.Now()
+		keys, next, err := c.client.Scan(ctx, cursor, constants.CacheKeyPrefix+"*", constants.CacheScanBatchSize).Result()
+		if err != nil {
+			c.observe(ctx, constants.CacheOpScan, start, err)
+			return fmt.Errorf("failed to scan cache: %w", err)
+		}
+
+		var values []interface{}
+		if len(keys) > 0 {
+			values, err = c.client.MGet(ctx, keys...).Result()
+		}
+		c.observe(ctx, constants.CacheOpScan, start, err)
+		if err != nil {
+			return fmt.Errorf("failed to scan cache: %w", err)
+		}
+
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				// Expired between SCAN and MGET
+				continue
+			}
+			var result models.AnalyzeResponse
+			if err := json.Unmarshal([]byte(data), &result); err != nil {
+				c.logger.Warn("Skipping undecodable cache entry", zap.String("key", keys[i]), zap.Error(err))
+				continue
+			}
+			if err := fn(&result); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// Stats reports the lookups since the process started and the number of cached analyses. The
+// entries are counted with DBSIZE when the DB is dedicated to the cache, and otherwise by
+// scanning the webpage:* keyspace, reporting at most constants.CacheStatsMaxScannedKeys entries.
+func (c *Cache) Stats(ctx context.Context) (*models.CacheStats, error) {
+	stats := &models.CacheStats{
+		Backend:    constants.CacheBackendRedis,
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		TTLSeconds: int64(c.ttl.Seconds()),
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	// If this is a no-op cache (client is nil), it holds nothing
+	if c.client == nil {
+		stats.Backend = constants.CacheBackendNoOp
+		return stats, nil
+	}
+
+	if c.dedicated {
+		stats.EntriesMethod = constants.CacheEntriesMethodDBSize
+		start := time.Now()
+		n, err := c.client.DBSize(ctx).Result()
+		c.observe(ctx, constants.CacheOpScan, start, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count cache entries: %w", err)
+		}
+		stats.Entries = n
+		return stats, nil
+	}
+
+	stats.EntriesMethod = constants.CacheEntriesMethodScan
+	var cursor uint64
+	for {
+		start := time.Now()
+		keys, next, err := c.client.Scan(ctx, cursor, constants.CacheKeyPrefix+"*", constants.CacheScanBatchSize).Result()
+		c.observe(ctx, constants.CacheOpScan, start, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count cache entries: %w", err)
+		}
+		stats.Entries += int64(len(keys))
+		if stats.Entries > c.scanLimit || (stats.Entries == c.scanLimit && next != 0) {
+			stats.Entries = c.scanLimit
+			stats.EntriesCapped = true
+			return stats, nil
+		}
+		cursor = next
+		if cursor == 0 {
+			return stats, nil
+		}
+	}
+}
+
 // Close closes the Redis connection
 func (c *Cache) Close() error {
 	// If this is a no-op cache (client is nil), do nothing
@@ -123,7 +497,19 @@ func (c *Cache) Close() error {
 	return c.client.Close()
 }
 
+// observe records the latency and outcome of a Redis round trip
+func (c *Cache) observe(ctx context.Context, operation string, start time.Time, err error) {
+	statsFrom(ctx).cacheRoundTrip()
+	if c.metrics == nil {
+		return
+	}
+	metrics.Observe(ctx, c.metrics.CacheOpDuration.WithLabelValues(operation), time.Since(start).Seconds())
+	if err != nil {
+		c.metrics.CacheErrors.WithLabelValues(operation).Inc()
+	}
+}
+
 // key generates a cache key for a URL
 func (c *Cache) key(url string) string {
-	return fmt.Sprintf("webpage:%s", url)
+	return constants.CacheKeyPrefix + url
 } 
\ No newline at end of file
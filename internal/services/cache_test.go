@@ -0,0 +1,533 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newTestCache creates a Redis-backed cache connected to an in-process miniredis server
+func newTestCache(t *testing.T) (*Cache, *miniredis.Miniredis, *metrics.Metrics) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Cache: config.CacheConfig{
+			Enabled: true,
+			TTL:     time.Hour,
+			Redis:   config.RedisConfig{Host: mr.Host(), Port: port},
+		},
+	}
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+
+	cache, err := NewCache(cfg, zaptest.NewLogger(t), m)
+	require.NoError(t, err)
+	return cache, mr, m
+}
+
+// testEntry returns an analysis of url with the fields every cached analysis has
+func testEntry(url, title string) *models.AnalyzeResponse {
+	return &models.AnalyzeResponse{URL: url, Title: title, AnalyzedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestCache_GetSet(t *testing.T) {
+	cache, mr, m := newTestCache(t)
+	defer cache.Close()
+	ctx := context.Background()
+
+	result, ttl, err := cache.Get(ctx, "http://example.com")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Zero(t, ttl)
+
+	stored := &models.AnalyzeResponse{
+		URL:        "http://example.com",
+		Title:      "Example",
+		Headings:   map[string]int{"h1": 1},
+		AnalyzedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	require.NoError(t, cache.Set(ctx, "http://example.com", stored, 0))
+
+	mr.FastForward(10 * time.Minute)
+
+	result, ttl, err = cache.Get(ctx, "http://example.com")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, "Example", result.Title)
+	assert.Equal(t, 50*time.Minute, ttl)
+
+	require.NoError(t, cache.Delete(ctx, "http://example.com"))
+	assert.False(t, mr.Exists("webpage:http://example.com"))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheHits))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheMisses))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.CacheOpDuration, constants.MetricCacheOpDurationName))
+	assert.Equal(t, 0, testutil.CollectAndCount(m.CacheErrors))
+}
+
+func TestCache_GetHealsCorruptedEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "Garbage bytes", value: "\x00\xffnot json"},
+		{name: "Truncated JSON", value: `{"url":"http://example.com","title":"Exam`},
+		{name: "Not an object", value: `["http://example.com"]`},
+		{name: "Missing URL", value: `{"title":"Example","analyzed_at":"2024-01-01T00:00:00Z"}`},
+		{name: "Missing analysis time", value: `{"url":"http://example.com","title":"Example"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, mr, m := newTestCache(t)
+			defer cache.Close()
+			ctx := context.Background()
+			require.NoError(t, mr.Set("webpage:http://example.com", tt.value))
+
+			result, ttl, err := cache.Get(ctx, "http://example.com")
+			require.NoError(t, err, "a corrupted entry is a miss")
+			assert.Nil(t, result)
+			assert.Zero(t, ttl)
+			assert.False(t, mr.Exists("webpage:http://example.com"), "the entry is deleted")
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheCorruption))
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheMisses))
+			assert.Equal(t, 0, testutil.CollectAndCount(m.CacheErrors))
+
+			require.NoError(t, cache.Set(ctx, "http://example.com", testEntry("http://example.com", "Example"), 0))
+			result, _, err = cache.Get(ctx, "http://example.com")
+			require.NoError(t, err)
+			require.NotNil(t, result, "the rewritten entry hits")
+			assert.Equal(t, "Example", result.Title)
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheCorruption))
+		})
+	}
+}
+
+func TestCache_DropCorruptedKeepsRewrittenEntries(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	defer cache.Close()
+	ctx := context.Background()
+
+	// A Set rewrote the entry between the read that found it corrupted and the delete
+	require.NoError(t, cache.Set(ctx, "http://example.com", testEntry("http://example.com", "Example"), 0))
+	require.NoError(t, mr.Set("webpage:http://other.example", "not json"))
+	cache.dropCorrupted(ctx, map[string]corruptedEntry{
+		"http://example.com":   {data: "not json", err: errors.New("corrupted")},
+		"http://other.example": {data: "not json", err: errors.New("corrupted")},
+	})
+
+	result, _, err := cache.Get(ctx, "http://example.com")
+	require.NoError(t, err)
+	require.NotNil(t, result, "the rewritten entry is kept")
+	assert.Equal(t, "Example", result.Title)
+	assert.False(t, mr.Exists("webpage:http://other.example"), "the unchanged entry is deleted")
+}
+
+func TestCache_SetTTLOverride(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	defer cache.Close()
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "http://example.com", &models.AnalyzeResponse{}, 5*time.Minute))
+	assert.Equal(t, 5*time.Minute, mr.TTL("webpage:http://example.com"))
+
+	require.NoError(t, cache.Set(ctx, "http://example.org", &models.AnalyzeResponse{}, 0))
+	assert.Equal(t, time.Hour, mr.TTL("webpage:http://example.org"), "0 uses the configured TTL")
+}
+
+func TestCache_SetTTLJitter(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	defer cache.Close()
+	cache.ttlJitter = 0.1
+	ctx := context.Background()
+
+	seen := make(map[time.Duration]bool)
+	for i := range 200 {
+		key := fmt.Sprintf("http://example.com/%d", i)
+		require.NoError(t, cache.Set(ctx, key, &models.AnalyzeResponse{}, 0))
+		ttl := mr.TTL("webpage:" + key)
+		assert.GreaterOrEqual(t, ttl, 54*time.Minute)
+		assert.LessOrEqual(t, ttl, 66*time.Minute)
+		seen[ttl] = true
+	}
+	assert.Greater(t, len(seen), 1, "entries cached together expire at different times")
+}
+
+func TestCache_EntryTTL(t *testing.T) {
+	cache := &Cache{ttlJitter: 0.2}
+	for range 1000 {
+		ttl := cache.entryTTL(10 * time.Minute)
+		assert.GreaterOrEqual(t, ttl, 8*time.Minute)
+		assert.LessOrEqual(t, ttl, 12*time.Minute)
+	}
+	for range 1000 {
+		assert.Positive(t, (&Cache{ttlJitter: 1}).entryTTL(time.Second), "jittered TTLs stay positive")
+	}
+	assert.Equal(t, 10*time.Minute, (&Cache{}).entryTTL(10*time.Minute), "no jitter keeps the TTL")
+}
+
+func TestCache_ErrorsCountedWhenClientClosed(t *testing.T) {
+	cache, _, m := newTestCache(t)
+	ctx := context.Background()
+	require.NoError(t, cache.Close())
+
+	_, _, err := cache.Get(ctx, "http://example.com")
+	assert.Error(t, err)
+
+	err = cache.Set(ctx, "http://example.com", &models.AnalyzeResponse{URL: "http://example.com"}, 0)
+	assert.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues(constants.CacheOpGet)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues(constants.CacheOpSet)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues(constants.CacheOpDelete)))
+}
+
+func TestNoOpCache_SkipsMetrics(t *testing.T) {
+	cache := NewNoOpCache(zaptest.NewLogger(t))
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "http://example.com", &models.AnalyzeResponse{}, 0))
+	result, ttl, err := cache.Get(ctx, "http://example.com")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Zero(t, ttl)
+	assert.NoError(t, cache.Delete(ctx, "http://example.com"))
+}
+
+func TestCache_Scan(t *testing.T) {
+	cache, mr, m := newTestCache(t)
+	defer cache.Close()
+	ctx := context.Background()
+
+	// More entries than one SCAN batch, plus keys the scan must skip
+	const entries = 3*constants.CacheScanBatchSize + 7
+	for i := 0; i < entries; i++ {
+		url := "https://example.com/" + strconv.Itoa(i)
+		require.NoError(t, cache.Set(ctx, url, testEntry(url, ""), 0))
+	}
+	require.NoError(t, mr.Set("webpage:https://corrupt.example", "{not json"))
+	require.NoError(t, mr.Set("session:abc", `{"url":"https://other.example"}`))
+
+	seen := map[string]bool{}
+	require.NoError(t, cache.Scan(ctx, func(result *models.AnalyzeResponse) error {
+		seen[result.URL] = true
+		return nil
+	}))
+	assert.Len(t, seen, entries)
+	assert.True(t, seen["https://example.com/0"])
+	assert.False(t, seen["https://other.example"])
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues(constants.CacheOpScan)))
+
+	t.Run("Callback error stops the scan", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := cache.Scan(ctx, func(result *models.AnalyzeResponse) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestNoOpCache_Scan(t *testing.T) {
+	cache := NewNoOpCache(zaptest.NewLogger(t))
+
+	calls := 0
+	require.NoError(t, cache.Scan(context.Background(), func(result *models.AnalyzeResponse) error {
+		calls++
+		return nil
+	}))
+	assert.Zero(t, calls)
+}
+
+func TestCache_Stats(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	defer cache.Close()
+	ctx := context.Background()
+
+	stats, err := cache.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &models.CacheStats{Backend: constants.CacheBackendRedis, EntriesMethod: constants.CacheEntriesMethodScan, TTLSeconds: 3600}, stats)
+
+	for i := 0; i < 3; i++ {
+		url := "https://example.com/" + strconv.Itoa(i)
+		require.NoError(t, cache.Set(ctx, url, testEntry(url, ""), 0))
+	}
+	require.NoError(t, mr.Set("session:abc", "other data"))
+	for _, url := range []string{"https://example.com/0", "https://example.com/1", "https://example.com/2", "https://missing.example"} {
+		_, _, err := cache.Get(ctx, url)
+		require.NoError(t, err)
+	}
+
+	stats, err = cache.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 0.75, stats.HitRatio)
+	assert.Equal(t, int64(3), stats.Entries, "keys outside webpage:* are not counted")
+	assert.False(t, stats.EntriesCapped)
+
+	t.Run("Dedicated DB", func(t *testing.T) {
+		cache.dedicated = true
+		defer func() { cache.dedicated = false }()
+
+		stats, err := cache.Stats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, constants.CacheEntriesMethodDBSize, stats.EntriesMethod)
+		assert.Equal(t, int64(4), stats.Entries, "DBSIZE counts every key")
+	})
+
+	t.Run("Capped scan", func(t *testing.T) {
+		for i := 3; i < 3*constants.CacheScanBatchSize; i++ {
+			url := "https://example.com/" + strconv.Itoa(i)
+			require.NoError(t, cache.Set(ctx, url, testEntry(url, ""), 0))
+		}
+		cache.scanLimit = 10
+		defer func() { cache.scanLimit = constants.CacheStatsMaxScannedKeys }()
+
+		stats, err := cache.Stats(ctx)
+		require.NoError(t, err)
+		assert.True(t, stats.EntriesCapped)
+		assert.Equal(t, int64(10), stats.Entries)
+	})
+
+	t.Run("Unreachable server", func(t *testing.T) {
+		mr.Close()
+		_, err := cache.Stats(ctx)
+		assert.ErrorContains(t, err, "failed to count cache entries")
+	})
+}
+
+func TestNoOpCache_Stats(t *testing.T) {
+	cache := NewNoOpCache(zaptest.NewLogger(t))
+	_, _, err := cache.Get(context.Background(), "http://example.com")
+	require.NoError(t, err)
+
+	stats, err := cache.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &models.CacheStats{Backend: constants.CacheBackendNoOp, Misses: 1}, stats)
+}
+
+func TestCache_GetMulti(t *testing.T) {
+	cache, mr, m := newTestCache(t)
+	defer cache.Close()
+	stats := &analysisStats{}
+	ctx := context.WithValue(context.Background(), statsContextKey{}, stats)
+
+	require.NoError(t, cache.Set(ctx, "http://example.com/a", testEntry("http://example.com/a", "A"), 0))
+	require.NoError(t, cache.Set(ctx, "http://example.com/b", testEntry("http://example.com/b", "B"), 0))
+	stats.cacheRoundTrips.Store(0)
+
+	results, err := cache.GetMulti(ctx, []string{"http://example.com/a", "http://example.com/missing", "http://example.com/b"})
+
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "A", results["http://example.com/a"].Title)
+	assert.Equal(t, "B", results["http://example.com/b"].Title)
+	assert.NotContains(t, results, "http://example.com/missing")
+	assert.Equal(t, int64(1), stats.cacheRoundTrips.Load(), "all URLs are fetched in one round trip")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.CacheHits))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheMisses))
+	cacheStats, err := cache.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cacheStats.Hits)
+	assert.Equal(t, int64(1), cacheStats.Misses)
+
+	t.Run("Corrupted entries miss and are deleted", func(t *testing.T) {
+		require.NoError(t, mr.Set("webpage:http://example.com/corrupt", "{not json"))
+		require.NoError(t, mr.Set("webpage:http://example.com/no-url", `{"analyzed_at":"2024-01-01T00:00:00Z"}`))
+
+		results, err := cache.GetMulti(ctx, []string{"http://example.com/a", "http://example.com/corrupt", "http://example.com/no-url"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://example.com/a"}, slices.Collect(maps.Keys(results)), "the intact entries are returned")
+		assert.False(t, mr.Exists("webpage:http://example.com/corrupt"))
+		assert.False(t, mr.Exists("webpage:http://example.com/no-url"))
+		assert.Equal(t, 2.0, testutil.ToFloat64(m.CacheCorruption))
+		assert.Equal(t, 3.0, testutil.ToFloat64(m.CacheMisses))
+	})
+
+	results, err = cache.GetMulti(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestCache_SetMulti(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	defer cache.Close()
+	stats := &analysisStats{}
+	ctx := context.WithValue(context.Background(), statsContextKey{}, stats)
+
+	require.NoError(t, cache.SetMulti(ctx, map[string]*models.AnalyzeResponse{
+		"http://example.com/a": testEntry("http://example.com/a", "A"),
+		"http://example.com/b": testEntry("http://example.com/b", "B"),
+		"http://example.com/c": testEntry("http://example.com/c", "C"),
+	}, 5*time.Minute))
+	assert.Equal(t, int64(1), stats.cacheRoundTrips.Load(), "the writes are pipelined")
+	assert.Equal(t, 5*time.Minute, mr.TTL("webpage:http://example.com/a"))
+
+	require.NoError(t, cache.SetMulti(ctx, map[string]*models.AnalyzeResponse{"http://example.org": {URL: "http://example.org"}}, 0))
+	assert.Equal(t, time.Hour, mr.TTL("webpage:http://example.org"), "0 uses the configured TTL")
+
+	results, err := cache.GetMulti(ctx, []string{"http://example.com/a", "http://example.com/b", "http://example.com/c"})
+	require.NoError(t, err)
+	assert.Equal(t, "C", results["http://example.com/c"].Title)
+}
+
+func TestCache_SetMultiIndexesDuplicates(t *testing.T) {
+	cache, _, _ := newTestCache(t)
+	defer cache.Close()
+	cache.duplicates = true
+	cache.duplicatesMax = constants.DefaultDuplicateIndexMaxURLs
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetMulti(ctx, map[string]*models.AnalyzeResponse{
+		"https://site.example/a": {URL: "https://site.example/a", Title: "Home"},
+		"https://site.example/b": {URL: "https://site.example/b", Title: "Home"},
+	}, 0))
+
+	duplicates, err := cache.Duplicates(ctx, "https://site.example/a")
+	require.NoError(t, err)
+	require.NotNil(t, duplicates)
+	assert.Equal(t, []string{"https://site.example/b"}, duplicates.SameTitle)
+}
+
+func TestCache_MultiErrorsWhenClientClosed(t *testing.T) {
+	cache, _, m := newTestCache(t)
+	ctx := context.Background()
+	require.NoError(t, cache.Close())
+
+	results, err := cache.GetMulti(ctx, []string{"http://example.com/a", "http://example.com/b"})
+	assert.Error(t, err)
+	assert.Empty(t, results)
+
+	err = cache.SetMulti(ctx, map[string]*models.AnalyzeResponse{
+		"http://example.com/a": {URL: "http://example.com/a"},
+		"http://example.com/b": {URL: "http://example.com/b"},
+	}, 0)
+	var keyErrors KeyErrors
+	require.ErrorAs(t, err, &keyErrors)
+	assert.Len(t, keyErrors, 2, "every unsaved URL is reported")
+	assert.Contains(t, err.Error(), "2 URLs")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues(constants.CacheOpGetMulti)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.CacheErrors.WithLabelValues(constants.CacheOpSetMulti)))
+}
+
+func TestNoOpCache_Multi(t *testing.T) {
+	cache := NewNoOpCache(zaptest.NewLogger(t))
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetMulti(ctx, map[string]*models.AnalyzeResponse{"http://example.com": {}}, 0))
+	results, err := cache.GetMulti(ctx, []string{"http://example.com"})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+// benchmarkBatchURLs caches n results and returns their URLs
+func benchmarkBatchURLs(b *testing.B, cache *Cache, n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://example.com/page/%d", i)
+		require.NoError(b, cache.Set(context.Background(), urls[i], testEntry(urls[i], "Page"), 0))
+	}
+	return urls
+}
+
+// newBenchmarkCache returns a cache backed by miniredis
+func newBenchmarkCache(b *testing.B) *Cache {
+	mr := miniredis.RunT(b)
+	port, err := strconv.Atoi(mr.Port())
+	require.NoError(b, err)
+	cache, err := NewCache(&config.Config{Cache: config.CacheConfig{
+		Enabled: true,
+		TTL:     time.Hour,
+		Redis:   config.RedisConfig{Host: mr.Host(), Port: port},
+	}}, zap.NewNop(), nil)
+	require.NoError(b, err)
+	b.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+// BenchmarkCache_GetBatch compares looking up a mostly cached batch one URL at a time with a
+// single multi-get, reporting the Redis round trips of each
+func BenchmarkCache_GetBatch(b *testing.B) {
+	cache := newBenchmarkCache(b)
+	urls := benchmarkBatchURLs(b, cache, 50)
+
+	b.Run("Get", func(b *testing.B) {
+		stats := &analysisStats{}
+		ctx := context.WithValue(context.Background(), statsContextKey{}, stats)
+		for b.Loop() {
+			for _, url := range urls {
+				if _, _, err := cache.Get(ctx, url); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(stats.cacheRoundTrips.Load())/float64(b.N), "round-trips/op")
+	})
+	b.Run("GetMulti", func(b *testing.B) {
+		stats := &analysisStats{}
+		ctx := context.WithValue(context.Background(), statsContextKey{}, stats)
+		for b.Loop() {
+			if _, err := cache.GetMulti(ctx, urls); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(stats.cacheRoundTrips.Load())/float64(b.N), "round-trips/op")
+	})
+}
+
+// BenchmarkCache_SetBatch compares storing a batch one URL at a time with pipelined writes
+func BenchmarkCache_SetBatch(b *testing.B) {
+	cache := newBenchmarkCache(b)
+	results := make(map[string]*models.AnalyzeResponse, 50)
+	for i := range 50 {
+		url := fmt.Sprintf("https://example.com/page/%d", i)
+		results[url] = testEntry(url, "Page")
+	}
+
+	b.Run("Set", func(b *testing.B) {
+		stats := &analysisStats{}
+		ctx := context.WithValue(context.Background(), statsContextKey{}, stats)
+		for b.Loop() {
+			for url, result := range results {
+				if err := cache.Set(ctx, url, result, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(stats.cacheRoundTrips.Load())/float64(b.N), "round-trips/op")
+	})
+	b.Run("SetMulti", func(b *testing.B) {
+		stats := &analysisStats{}
+		ctx := context.WithValue(context.Background(), statsContextKey{}, stats)
+		for b.Loop() {
+			if err := cache.SetMulti(ctx, results, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(stats.cacheRoundTrips.Load())/float64(b.N), "round-trips/op")
+	})
+}
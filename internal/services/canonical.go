@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// canonicalLinks returns the non-empty hrefs of the canonical links in document order
+func canonicalLinks(doc *goquery.Document) []string {
+	hrefs := []string{}
+	doc.Find("link[rel]").Each(func(_ int, s *goquery.Selection) {
+		if href := strings.TrimSpace(s.AttrOr("href", "")); hasRelToken(s, "canonical") && href != "" {
+			hrefs = append(hrefs, href)
+		}
+	})
+	return hrefs
+}
+
+// checkCanonicalConsistency compares the URL the page was fetched from with its first canonical
+// link and its og:url after normalization. The Open Graph URL is compared with the canonical URL
+// when there is one, so a page declaring both consistently reports a single difference.
+func (a *Analyzer) checkCanonicalConsistency(ctx context.Context, doc *goquery.Document, fetchedURL *url.URL) models.CanonicalConsistency {
+	consistency := models.CanonicalConsistency{FetchedURL: canonicalURL(fetchedURL), Differences: []string{}}
+	ignoreSlash := a.optionsFor(ctx).SEO.IgnoreTrailingSlash
+
+	fetched := normalizePageURL(fetchedURL, ignoreSlash)
+	reference, referenceName := fetched, "fetched URL"
+
+	if hrefs := canonicalLinks(doc); len(hrefs) > 0 {
+		consistency.Canonical = hrefs[0]
+		canonical, err := resolvePageURL(fetchedURL, hrefs[0], ignoreSlash)
+		if err != nil {
+			consistency.Differences = append(consistency.Differences, fmt.Sprintf("canonical URL %q is invalid", hrefs[0]))
+		} else {
+			consistency.Differences = append(consistency.Differences, compareURLs("canonical URL", canonical, referenceName, reference)...)
+			reference, referenceName = canonical, "canonical URL"
+		}
+	}
+
+	if content, exists := doc.Find("meta[property='og:url' i]").First().Attr("content"); exists && strings.TrimSpace(content) != "" {
+		consistency.OGURL = strings.TrimSpace(content)
+		ogURL, err := resolvePageURL(fetchedURL, consistency.OGURL, ignoreSlash)
+		if err != nil {
+			consistency.Differences = append(consistency.Differences, fmt.Sprintf("og:url %q is invalid", consistency.OGURL))
+		} else {
+			consistency.Differences = append(consistency.Differences, compareURLs("og:url", ogURL, referenceName, reference)...)
+		}
+	}
+
+	consistency.Match = len(consistency.Differences) == 0
+	return consistency
+}
+
+// resolvePageURL resolves a declared URL against the fetched URL and normalizes it
+func resolvePageURL(base *url.URL, ref string, ignoreTrailingSlash bool) (*url.URL, error) {
+	resolved, err := base.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if resolved.Host == "" {
+		return nil, fmt.Errorf("URL has no host")
+	}
+	if err := toASCIIHost(resolved); err != nil {
+		return nil, err
+	}
+	return normalizePageURL(resolved, ignoreTrailingSlash), nil
+}
+
+// normalizePageURL lowercases scheme and host and drops credentials, default ports and the fragment.
+// With ignoreTrailingSlash, /docs/ and /docs are the same page.
+func normalizePageURL(u *url.URL, ignoreTrailingSlash bool) *url.URL {
+	normalized := &url.URL{
+		Scheme:   strings.ToLower(u.Scheme),
+		Host:     strings.ToLower(u.Hostname()),
+		Path:     u.Path,
+		RawPath:  u.RawPath,
+		RawQuery: u.RawQuery,
+	}
+	if port := u.Port(); port != "" && !(normalized.Scheme == "http" && port == "80") && !(normalized.Scheme == "https" && port == "443") {
+		normalized.Host = withPort(normalized.Host, port)
+	} else if strings.Contains(normalized.Host, ":") {
+		normalized.Host = withPort(normalized.Host, "")
+	}
+	if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+	if ignoreTrailingSlash && len(normalized.Path) > 1 {
+		normalized.Path = strings.TrimRight(normalized.Path, "/")
+		normalized.RawPath = strings.TrimRight(normalized.RawPath, "/")
+	}
+	return normalized
+}
+
+// compareURLs describes how two normalized URLs differ, component by component
+func compareURLs(name string, u *url.URL, otherName string, other *url.URL) []string {
+	var differences []string
+	describe := func(component, value, otherValue string) {
+		if value != otherValue {
+			differences = append(differences, fmt.Sprintf("%s %s %q differs from %s %s %q", name, component, value, otherName, component, otherValue))
+		}
+	}
+	describe("scheme", u.Scheme, other.Scheme)
+	describe("host", u.Host, other.Host)
+	describe("path", u.EscapedPath(), other.EscapedPath())
+	describe("query", u.RawQuery, other.RawQuery)
+	return differences
+}
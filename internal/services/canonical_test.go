@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_CheckCanonicalConsistency(t *testing.T) {
+	tests := []struct {
+		name                string
+		fetched             string
+		head                string
+		ignoreTrailingSlash bool
+		expected            models.CanonicalConsistency
+	}{
+		{
+			name:    "Canonical and og:url match the fetched URL",
+			fetched: "https://example.com/products",
+			head:    `<link rel="canonical" href="https://EXAMPLE.com:443/products#top"><meta property="og:url" content="/products">`,
+			expected: models.CanonicalConsistency{
+				Match:       true,
+				FetchedURL:  "https://example.com/products",
+				Canonical:   "https://EXAMPLE.com:443/products#top",
+				OGURL:       "/products",
+				Differences: []string{},
+			},
+		},
+		{
+			name:     "No declared URLs",
+			fetched:  "https://example.com/",
+			expected: models.CanonicalConsistency{Match: true, FetchedURL: "https://example.com/", Differences: []string{}},
+		},
+		{
+			name:                "Trailing slash tolerated",
+			fetched:             "https://example.com/docs/",
+			head:                `<link rel="canonical" href="https://example.com/docs">`,
+			ignoreTrailingSlash: true,
+			expected: models.CanonicalConsistency{
+				Match:       true,
+				FetchedURL:  "https://example.com/docs/",
+				Canonical:   "https://example.com/docs",
+				Differences: []string{},
+			},
+		},
+		{
+			name:    "Trailing slash not tolerated",
+			fetched: "https://example.com/docs/",
+			head:    `<link rel="canonical" href="https://example.com/docs">`,
+			expected: models.CanonicalConsistency{
+				FetchedURL:  "https://example.com/docs/",
+				Canonical:   "https://example.com/docs",
+				Differences: []string{`canonical URL path "/docs" differs from fetched URL path "/docs/"`},
+			},
+		},
+		{
+			name:                "Conflicting hosts",
+			fetched:             "https://example.com/pricing",
+			head:                `<link rel="canonical" href="https://www.example.com/pricing"><meta property="og:url" content="http://shop.example.net/pricing?ref=og">`,
+			ignoreTrailingSlash: true,
+			expected: models.CanonicalConsistency{
+				FetchedURL: "https://example.com/pricing",
+				Canonical:  "https://www.example.com/pricing",
+				OGURL:      "http://shop.example.net/pricing?ref=og",
+				Differences: []string{
+					`canonical URL host "www.example.com" differs from fetched URL host "example.com"`,
+					`og:url scheme "http" differs from canonical URL scheme "https"`,
+					`og:url host "shop.example.net" differs from canonical URL host "www.example.com"`,
+					`og:url query "ref=og" differs from canonical URL query ""`,
+				},
+			},
+		},
+		{
+			name:    "og:url compared with the fetched URL without a canonical link",
+			fetched: "https://example.com/a",
+			head:    `<meta property="og:url" content="https://example.com/b">`,
+			expected: models.CanonicalConsistency{
+				FetchedURL:  "https://example.com/a",
+				OGURL:       "https://example.com/b",
+				Differences: []string{`og:url path "/b" differs from fetched URL path "/a"`},
+			},
+		},
+		{
+			name:    "Internationalized canonical host",
+			fetched: "https://xn--mnchen-3ya.de/",
+			head:    `<link rel="canonical" href="https://münchen.de/">`,
+			expected: models.CanonicalConsistency{
+				Match:       true,
+				FetchedURL:  "https://xn--mnchen-3ya.de/",
+				Canonical:   "https://münchen.de/",
+				Differences: []string{},
+			},
+		},
+		{
+			name:    "Invalid canonical URL",
+			fetched: "https://example.com/",
+			head:    `<link rel="canonical" href="https://exa mple.com/">`,
+			expected: models.CanonicalConsistency{
+				FetchedURL:  "https://example.com/",
+				Canonical:   "https://exa mple.com/",
+				Differences: []string{`canonical URL "https://exa mple.com/" is invalid`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.Analyzer.SEO.IgnoreTrailingSlash = tt.ignoreTrailingSlash
+			analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head>` + tt.head + `</head><body></body></html>`))
+			require.NoError(t, err)
+			fetched, _ := url.Parse(tt.fetched)
+
+			assert.Equal(t, tt.expected, analyzer.checkCanonicalConsistency(context.Background(), doc, fetched))
+		})
+	}
+}
+
+func TestAnalyzer_Analyze_CanonicalConsistencyAfterRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>New</title><link rel="canonical" href="/new"></head><body></body></html>`))
+	})
+
+	analyzer := newTestAnalyzer(t, maxRedirects(5))
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/old", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+
+	assert.True(t, result.CanonicalConsistency.Match)
+	assert.Equal(t, server.URL+"/new", result.CanonicalConsistency.FetchedURL)
+	assert.Equal(t, server.URL+"/old", result.URL)
+}
+
+func TestAnalyzer_ScoreSEO_MultipleCanonical(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	report := analyzeSEO(t, analyzer, seoFixture(seoPerfectHead+`<link rel="canonical" href="https://example.com/products?page=1">`, seoPerfectBody))
+
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, models.SEOIssue{
+		Rule:     constants.SEORuleMultipleCanonical,
+		Severity: constants.SEOSeverityCritical,
+		Message:  "Page has 2 canonical links",
+		Penalty:  constants.DefaultSEOWeights[constants.SEORuleMultipleCanonical],
+	}, report.Issues[0])
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// compareCanonical fetches the page's first canonical link, when it points at another page, and
+// scores how similar its visible text is to the analyzed document's. The fetch counts against the
+// outbound budget; when it fails, a warning is reported and nothing is compared. Invalid canonical
+// links are left to canonical_consistency.
+func (a *Analyzer) compareCanonical(ctx context.Context, doc *goquery.Document, fetchedURL *url.URL) *models.CanonicalSimilarity {
+	options := a.optionsFor(ctx)
+	hrefs := canonicalLinks(doc)
+	if len(hrefs) == 0 {
+		return nil
+	}
+	ignoreSlash := options.SEO.IgnoreTrailingSlash
+	canonical, err := resolvePageURL(fetchedURL, hrefs[0], ignoreSlash)
+	if err != nil || (canonical.Scheme != "http" && canonical.Scheme != "https") {
+		return nil
+	}
+	if canonical.String() == normalizePageURL(fetchedURL, ignoreSlash).String() {
+		return nil
+	}
+
+	// The page is fetched as declared; normalization may have dropped a trailing slash it needs
+	target, _ := fetchedURL.Parse(hrefs[0])
+	target.Fragment, target.RawFragment = "", ""
+	if err := toASCIIHost(target); err != nil {
+		return nil
+	}
+	text, err := a.fetchVisibleText(ctx, target)
+	if err != nil {
+		warningsFrom(ctx).add(constants.WarningCodeCanonicalFetchFailed,
+			fmt.Sprintf("The canonical URL %s could not be fetched, so its content was not compared: %v", models.StripCredentials(target.String()), err))
+		return nil
+	}
+
+	threshold := options.SEO.CanonicalSimilarityThreshold
+	score := simHashSimilarity(simHash(visibleText(doc)), simHash(text))
+	return &models.CanonicalSimilarity{
+		CanonicalURL: models.StripCredentials(target.String()),
+		Score:        score,
+		Threshold:    threshold,
+		Similar:      score >= threshold,
+	}
+}
+
+// fetchVisibleText fetches a page like the analyzed one and returns its visible text
+func (a *Analyzer) fetchVisibleText(ctx context.Context, target *url.URL) (string, error) {
+	if err := a.policy.check(ctx, target); err != nil {
+		return "", fmt.Errorf("failed to fetch webpage: %w", err)
+	}
+	page, err := a.fetchWebpage(ctx, target.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	doc, err := a.parseHTML(page.body)
+	if err != nil {
+		return "", err
+	}
+	return visibleText(doc), nil
+}
+
+// shingles splits text into lowercase words and returns every run of
+// constants.CanonicalShingleWords consecutive words. Shorter texts are a single shingle.
+func shingles(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) <= constants.CanonicalShingleWords {
+		return []string{strings.Join(words, " ")}
+	}
+	result := make([]string, 0, len(words)-constants.CanonicalShingleWords+1)
+	for i := 0; i+constants.CanonicalShingleWords <= len(words); i++ {
+		result = append(result, strings.Join(words[i:i+constants.CanonicalShingleWords], " "))
+	}
+	return result
+}
+
+// simHash returns the 64-bit SimHash of the shingles of text: each bit is set when more
+// shingle hashes have it set than not, so texts sharing most shingles differ in few bits
+func simHash(text string) uint64 {
+	var weights [64]int
+	for _, shingle := range shingles(text) {
+		h := hashShingle(shingle)
+		for bit := range weights {
+			if h&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// hashShingle hashes a shingle with FNV-1a, mixed so every bit depends on the whole shingle
+func hashShingle(shingle string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(shingle))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// simHashSimilarity scores two fingerprints from 1, when they are equal, to 0. Unrelated texts
+// agree on about half of the bits by chance, so that agreement already scores 0.
+func simHashSimilarity(a, b uint64) float64 {
+	distance := bits.OnesCount64(a ^ b)
+	return max(0, 1-float64(distance)/32)
+}
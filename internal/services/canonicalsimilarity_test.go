@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// fixtureText returns the visible text of an HTML fixture
+func fixtureText(t *testing.T, analyzer *Analyzer, name string) string {
+	doc, err := analyzer.parseHTML(loadFixture(t, name))
+	require.NoError(t, err)
+	return visibleText(doc)
+}
+
+func TestShingles(t *testing.T) {
+	assert.Nil(t, shingles(" \n "))
+	assert.Equal(t, []string{"fiddle leaf"}, shingles("Fiddle-Leaf"))
+	assert.Equal(t, []string{"how to repot", "to repot a", "repot a fig"}, shingles("How to repot a fig!"))
+}
+
+func TestSimHashSimilarity_Fixtures(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	original := simHash(fixtureText(t, analyzer, "canonical_original.html"))
+	syndicated := simHash(fixtureText(t, analyzer, "canonical_syndicated.html"))
+	unrelated := simHash(fixtureText(t, analyzer, "canonical_unrelated.html"))
+
+	assert.Equal(t, 1.0, simHashSimilarity(original, original))
+	assert.GreaterOrEqual(t, simHashSimilarity(original, syndicated), constants.DefaultCanonicalSimilarityThreshold,
+		"a copy with its own header and footer is the same content")
+	assert.Less(t, simHashSimilarity(original, unrelated), 0.5, "another page of the same site is not")
+	assert.Less(t, simHashSimilarity(original, simHash("")), 0.5, "an empty page is not")
+}
+
+func TestSimHash_IgnoresMarkupAndCase(t *testing.T) {
+	assert.Equal(t, simHash("Water the plant a day before repotting."), simHash("water THE plant   a day, before repotting"))
+}
+
+// newCanonicalServer serves the HTML fixtures by path, counting the requests
+func newCanonicalServer(t *testing.T, pages map[string]string, requests *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(constants.HeaderContentType, "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_CompareCanonical(t *testing.T) {
+	original := loadFixture(t, "canonical_original.html")
+	syndicated := loadFixture(t, "canonical_syndicated.html")
+	unrelated := loadFixture(t, "canonical_unrelated.html")
+	withCanonical := func(page, href string) string {
+		return strings.Replace(page, "https://journal.example.com/guides/repot-fiddle-leaf-fig", href, 1)
+	}
+
+	tests := []struct {
+		name        string
+		pages       map[string]string
+		maxOutbound int
+		similar     bool
+		warning     bool
+		requests    int32
+	}{
+		{
+			name:     "Canonical serves the same content",
+			pages:    map[string]string{"/copy": withCanonical(syndicated, "/guide"), "/guide": original},
+			similar:  true,
+			requests: 2,
+		},
+		{
+			name:     "Canonical serves other content",
+			pages:    map[string]string{"/copy": withCanonical(syndicated, "/guide#top"), "/guide": unrelated},
+			requests: 2,
+		},
+		{
+			name:     "Canonical not found",
+			pages:    map[string]string{"/copy": withCanonical(syndicated, "/guide")},
+			warning:  true,
+			requests: 2,
+		},
+		{
+			name:        "Budget exhausted",
+			pages:       map[string]string{"/copy": withCanonical(syndicated, "/guide"), "/guide": original},
+			maxOutbound: 1,
+			warning:     true,
+			requests:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := newCanonicalServer(t, tt.pages, &requests)
+			analyzer := newTestAnalyzer(t, outboundBudget(tt.maxOutbound))
+
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/copy", models.AnalyzeOptions{
+				SkipLinkCheck:    true,
+				CompareCanonical: true,
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.requests, requests.Load())
+			if tt.warning {
+				assert.Nil(t, result.CanonicalSimilarity)
+				require.Len(t, result.Warnings, 1)
+				assert.Equal(t, constants.WarningCodeCanonicalFetchFailed, result.Warnings[0].Code)
+				assert.Contains(t, result.Warnings[0].Message, server.URL+"/guide")
+				return
+			}
+			require.NotNil(t, result.CanonicalSimilarity)
+			assert.Equal(t, server.URL+"/guide", result.CanonicalSimilarity.CanonicalURL)
+			assert.Equal(t, constants.DefaultCanonicalSimilarityThreshold, result.CanonicalSimilarity.Threshold)
+			assert.Equal(t, tt.similar, result.CanonicalSimilarity.Similar)
+			assert.Empty(t, result.Warnings)
+		})
+	}
+}
+
+func TestAnalyzer_CompareCanonical_SelfCanonical(t *testing.T) {
+	var requests atomic.Int32
+	server := newCanonicalServer(t, nil, &requests)
+	cfg := createTestConfig()
+	cfg.Analyzer.SEO.IgnoreTrailingSlash = true
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	page := strings.Replace(loadFixture(t, "canonical_original.html"), "https://journal.example.com/guides/repot-fiddle-leaf-fig", "/guide/", 1)
+
+	result, err := analyzer.AnalyzeHTML(context.Background(), page, server.URL+"/guide", models.AnalyzeOptions{
+		SkipLinkCheck:    true,
+		CompareCanonical: true,
+	})
+
+	require.NoError(t, err)
+	assert.Nil(t, result.CanonicalSimilarity, "a page canonical to itself is not fetched again")
+	assert.Zero(t, requests.Load())
+}
+
+func TestCacheKey_CompareCanonical(t *testing.T) {
+	assert.Equal(t, "http://example.com|"+constants.CacheVariantCompareCanonical, cacheKey("http://example.com", models.AnalyzeOptions{CompareCanonical: true}))
+}
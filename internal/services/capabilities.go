@@ -0,0 +1,48 @@
+package services
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// Capabilities returns the public view of the configuration: the effective limits analyses
+// run with, defaults applied, and the optional features enabled. Settings such as hosts,
+// credentials and tokens stay out of it, as only the fields copied here are exposed.
+func Capabilities(cfg *config.Config) models.Capabilities {
+	o := resolveOptions(cfg)
+
+	capabilities := models.Capabilities{
+		Limits: models.CapabilityLimits{
+			MaxLinks:               o.MaxLinks,
+			MaxBodyBytes:           o.MaxBodyBytes,
+			AnalysisTimeoutSeconds: o.AnalysisTimeout.Seconds(),
+			MaxRedirects:           o.MaxRedirects,
+			RawLinksPageSize:       constants.MaxRawLinksLimit,
+		},
+		Features: models.CapabilityFeatures{
+			RenderJS:     o.JSRendering.Enabled && o.JSRendering.Endpoint != "",
+			LinkSampling: o.LinkSampling.Enabled,
+			Cache:        o.Cache.Enabled,
+			Analyses:     cfg.Storage.Enabled,
+			Duplicates:   o.Cache.Enabled && o.Cache.Duplicates.Enabled,
+			RateLimit:    o.RateLimit.Enabled,
+			Snapshots:    cfg.Snapshots.Enabled,
+		},
+		Formats: []string{constants.ResponseFormatJSON, constants.ResponseFormatPDF},
+	}
+	// Only the names of the proxy pools are public, never their addresses
+	if proxies := newProxyPools(o.Proxies, zap.NewNop()); len(proxies) > 0 {
+		capabilities.Egress = proxies.names()
+	}
+	if o.RateLimit.Enabled {
+		capabilities.Limits.RequestsPerMinute = o.RateLimit.RequestsPerMinute
+		capabilities.Limits.PerTargetPerMinute = o.RateLimit.PerTargetPerMinute
+		if o.RateLimit.Egress.Enabled {
+			capabilities.Limits.DailyEgressBytes = o.RateLimit.Egress.DailyBytes
+		}
+	}
+	return capabilities
+}
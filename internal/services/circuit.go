@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostCircuit tracks consecutive link check failures per external host within one analysis.
+// Once a host reaches the failure threshold its circuit opens: in-flight checks to the host
+// are cancelled and its remaining links are reported inaccessible without being checked.
+type hostCircuit struct {
+	ctx       context.Context
+	threshold int
+	shared    *openHostCache // Optional memory of open circuits across analyses
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState is the circuit state of a single host
+type hostState struct {
+	failures int
+	open     bool
+	ctx      context.Context // Cancelled when the circuit opens
+	cancel   context.CancelFunc
+}
+
+func newHostCircuit(ctx context.Context, threshold int, shared *openHostCache) *hostCircuit {
+	return &hostCircuit{
+		ctx:       ctx,
+		threshold: threshold,
+		shared:    shared,
+		hosts:     make(map[string]*hostState),
+	}
+}
+
+// acquire returns the context to check a link to host with, or open=true when the host's circuit is open
+func (c *hostCircuit) acquire(host string) (ctx context.Context, open bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.state(host)
+	if !state.open && c.shared != nil && c.shared.isOpen(host) {
+		c.trip(host, state)
+	}
+	return state.ctx, state.open
+}
+
+// record registers the outcome of a completed check; successes reset the failure count
+func (c *hostCircuit) record(host string, accessible bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := c.state(host)
+	if state.open {
+		return
+	}
+	if accessible {
+		state.failures = 0
+		return
+	}
+	state.failures++
+	if state.failures >= c.threshold {
+		c.trip(host, state)
+		if c.shared != nil {
+			c.shared.open(host)
+		}
+	}
+}
+
+// release cancels the per-host contexts once the analysis is done
+func (c *hostCircuit) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, state := range c.hosts {
+		state.cancel()
+	}
+}
+
+// state returns the state for host, creating it on first use. The caller must hold c.mu.
+func (c *hostCircuit) state(host string) *hostState {
+	state, ok := c.hosts[host]
+	if !ok {
+		ctx, cancel := context.WithCancel(c.ctx)
+		state = &hostState{ctx: ctx, cancel: cancel}
+		c.hosts[host] = state
+	}
+	return state
+}
+
+// trip opens the circuit of host. The caller must hold c.mu.
+func (c *hostCircuit) trip(host string, state *hostState) {
+	state.open = true
+	state.cancel()
+}
+
+// openHostCache remembers hosts whose circuit opened for a short time, so that
+// following analyses skip them right away
+type openHostCache struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newOpenHostCache(ttl time.Duration) *openHostCache {
+	return &openHostCache{ttl: ttl, until: make(map[string]time.Time)}
+}
+
+func (o *openHostCache) isOpen(host string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	until, ok := o.until[host]
+	if ok && time.Now().After(until) {
+		delete(o.until, host)
+		return false
+	}
+	return ok
+}
+
+func (o *openHostCache) open(host string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	// Drop expired entries so the cache only holds currently open hosts
+	for h, until := range o.until {
+		if now.After(until) {
+			delete(o.until, h)
+		}
+	}
+	o.until[host] = now.Add(o.ttl)
+}
+
+// linkHost returns the host (with port) a link points to
+func linkHost(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newDeadHostFixture serves a page on 127.0.0.1 linking many times to a hanging host reached via localhost
+func newDeadHostFixture(t *testing.T, links int) string {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(dead.Close)
+	deadURL, err := url.Parse(dead.URL)
+	require.NoError(t, err)
+
+	var page strings.Builder
+	page.WriteString(`<html><head><title>Dead host</title></head><body><h1>Dead</h1>`)
+	for i := 0; i < links; i++ {
+		fmt.Fprintf(&page, `<a href="http://localhost:%s/page/%d">Link %d</a>`, deadURL.Port(), i, i)
+	}
+	page.WriteString(`</body></html>`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page.String()))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// circuitConfig sets up the link circuit with short link checks against the dead host
+func circuitConfig(enabled bool, sharedTTL time.Duration) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Analyzer.LinkTimeout = 300 * time.Millisecond
+		cfg.Analyzer.MaxWorkers = 4
+		cfg.Analyzer.LinkCircuit.Enabled = enabled
+		cfg.Analyzer.LinkCircuit.FailureThreshold = 3
+		cfg.Analyzer.LinkCircuit.SharedTTL = sharedTTL
+	}
+}
+
+func TestAnalyzer_LinkCircuitBoundsDeadHost(t *testing.T) {
+	const links = 40
+	target := newDeadHostFixture(t, links)
+	analyzer := newTestAnalyzer(t, circuitConfig(true, 0))
+
+	start := time.Now()
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), target, models.AnalyzeOptions{})
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+
+	// Without the circuit the 40 links would take 10 rounds of 300ms on 4 workers
+	assert.Less(t, elapsed, 1500*time.Millisecond)
+	assert.Equal(t, links, result.Links.External)
+	assert.Equal(t, links, result.Links.Inaccessible)
+	assert.Equal(t, 0, result.Links.InaccessibleInternal)
+
+	circuitOpen := result.Links.Skipped[constants.LinkSkipReasonCircuitOpen]
+	assert.GreaterOrEqual(t, circuitOpen, links-analyzer.options.MaxWorkers)
+	assert.LessOrEqual(t, circuitOpen, links-analyzer.options.LinkCircuit.FailureThreshold)
+	assert.NotContains(t, result.Links.Skipped, constants.LinkSkipReasonDeadline)
+	assert.False(t, result.Partial)
+}
+
+func TestAnalyzer_LinkCircuitDisabled(t *testing.T) {
+	const links = 8
+	target := newDeadHostFixture(t, links)
+	analyzer := newTestAnalyzer(t, circuitConfig(false, 0))
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), target, models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, links, result.Links.Inaccessible)
+	assert.Nil(t, result.Links.Skipped)
+}
+
+func TestAnalyzer_LinkCircuitSharedAcrossAnalyses(t *testing.T) {
+	const links = 8
+	target := newDeadHostFixture(t, links)
+	analyzer := newTestAnalyzer(t, circuitConfig(true, time.Minute))
+
+	_, err := analyzer.AnalyzeWithOptions(context.Background(), target, models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	// The second analysis skips the remembered host without waiting on it
+	start := time.Now()
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), target+"/?again", models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+	assert.Equal(t, links, result.Links.Inaccessible)
+	assert.Equal(t, links, result.Links.Skipped[constants.LinkSkipReasonCircuitOpen])
+}
+
+func TestHostCircuit(t *testing.T) {
+	circuit := newHostCircuit(context.Background(), 2, nil)
+	defer circuit.release()
+
+	// A success resets the consecutive failure count
+	circuit.record("a.example", false)
+	circuit.record("a.example", true)
+	circuit.record("a.example", false)
+	ctx, open := circuit.acquire("a.example")
+	assert.False(t, open)
+	assert.NoError(t, ctx.Err())
+
+	// Reaching the threshold opens the circuit and cancels in-flight checks
+	circuit.record("a.example", false)
+	_, open = circuit.acquire("a.example")
+	assert.True(t, open)
+	assert.Error(t, ctx.Err())
+
+	// Other hosts are unaffected
+	_, open = circuit.acquire("b.example")
+	assert.False(t, open)
+}
+
+func TestOpenHostCache(t *testing.T) {
+	cache := newOpenHostCache(50 * time.Millisecond)
+	cache.open("a.example")
+
+	assert.True(t, cache.isOpen("a.example"))
+	assert.False(t, cache.isOpen("b.example"))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, cache.isOpen("a.example"))
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// OutboundProbe checks that the analyzer can reach the internet with a HEAD request to a canary
+// URL. A result is reused for readiness.outbound.cache_ttl, so readiness polls send the canary
+// at most one request per period whatever their rate.
+type OutboundProbe struct {
+	config  config.OutboundProbeConfig
+	client  *http.Client
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+	now     func() time.Time
+
+	mu   sync.Mutex
+	last *models.OutboundProbe
+}
+
+// NewOutboundProbe creates the outbound probe, or returns nil when no canary URL is configured
+func NewOutboundProbe(cfg config.OutboundProbeConfig, logger *zap.Logger, m *metrics.Metrics) *OutboundProbe {
+	if cfg.CanaryURL == "" {
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Every probe dials anew, so DNS, connecting and TLS are checked rather than a pooled connection
+	transport.DisableKeepAlives = true
+	return &OutboundProbe{
+		config: cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger:  logger,
+		metrics: m,
+		now:     time.Now,
+	}
+}
+
+// Check returns the last probe result while it is younger than the cache TTL, and probes the
+// canary otherwise. Concurrent checks wait for a single probe.
+func (p *OutboundProbe) Check(ctx context.Context) models.OutboundProbe {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.last == nil || p.now().Sub(p.last.CheckedAt) >= p.config.CacheTTL {
+		// A readiness request that goes away must not leave a failed probe behind for the next polls
+		p.last = p.probe(context.WithoutCancel(ctx))
+	}
+	result := *p.last
+	result.AgeSeconds = p.now().Sub(result.CheckedAt).Seconds()
+	return result
+}
+
+// probe sends the HEAD request to the canary and records its outcome in the gauge
+func (p *OutboundProbe) probe(ctx context.Context) *models.OutboundProbe {
+	result := &models.OutboundProbe{URL: models.StripCredentials(p.config.CanaryURL), CheckedAt: p.now()}
+	defer func() {
+		result.DurationMs = p.now().Sub(result.CheckedAt).Milliseconds()
+		if result.Reachable {
+			p.metrics.OutboundProbeUp.Set(1)
+		} else {
+			p.metrics.OutboundProbeUp.Set(0)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.config.CanaryURL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		p.logger.Warn("Outbound canary unreachable", zap.String("url", result.URL), zap.Error(err))
+		return result
+	}
+	resp.Body.Close()
+
+	result.Reachable = true
+	result.StatusCode = resp.StatusCode
+	return result
+}
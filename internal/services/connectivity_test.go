@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/metrics"
+)
+
+// newCanary serves 204 to HEAD requests and counts them
+func newCanary(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			requests.Add(1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newTestOutboundProbe(t *testing.T, canaryURL string) (*OutboundProbe, *metrics.Metrics) {
+	m := NewMockMetrics()
+	probe := NewOutboundProbe(config.OutboundProbeConfig{CanaryURL: canaryURL, Timeout: time.Second, CacheTTL: time.Minute}, zaptest.NewLogger(t), m)
+	require.NotNil(t, probe)
+	return probe, m
+}
+
+func TestOutboundProbe_ReachableCanary(t *testing.T) {
+	canary, requests := newCanary(t)
+	probe, m := newTestOutboundProbe(t, canary.URL)
+
+	result := probe.Check(context.Background())
+
+	assert.True(t, result.Reachable)
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, canary.URL, result.URL)
+	assert.False(t, result.CheckedAt.IsZero())
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.OutboundProbeUp))
+}
+
+func TestOutboundProbe_UnreachableCanary(t *testing.T) {
+	canary := httptest.NewServer(http.NotFoundHandler())
+	canaryURL := canary.URL
+	canary.Close()
+	probe, m := newTestOutboundProbe(t, canaryURL)
+	m.OutboundProbeUp.Set(1)
+
+	result := probe.Check(context.Background())
+
+	assert.False(t, result.Reachable)
+	assert.Zero(t, result.StatusCode)
+	assert.NotEmpty(t, result.Error)
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.OutboundProbeUp))
+}
+
+func TestOutboundProbe_ErrorStatusIsReachable(t *testing.T) {
+	canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(canary.Close)
+	probe, _ := newTestOutboundProbe(t, canary.URL)
+
+	result := probe.Check(context.Background())
+
+	assert.True(t, result.Reachable, "any response proves outbound connectivity")
+	assert.Equal(t, http.StatusServiceUnavailable, result.StatusCode)
+}
+
+func TestOutboundProbe_CachesResults(t *testing.T) {
+	canary, requests := newCanary(t)
+	probe, _ := newTestOutboundProbe(t, canary.URL)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	probe.now = func() time.Time { return now }
+
+	first := probe.Check(context.Background())
+	assert.Zero(t, first.AgeSeconds)
+
+	now = now.Add(20 * time.Second)
+	cached := probe.Check(context.Background())
+	assert.Equal(t, int32(1), requests.Load(), "polls within the cache TTL reuse the result")
+	assert.Equal(t, first.CheckedAt, cached.CheckedAt)
+	assert.Equal(t, float64(20), cached.AgeSeconds)
+
+	now = now.Add(time.Minute)
+	fresh := probe.Check(context.Background())
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, now, fresh.CheckedAt)
+	assert.Zero(t, fresh.AgeSeconds)
+}
+
+func TestOutboundProbe_CanceledRequestProbesAnyway(t *testing.T) {
+	canary, requests := newCanary(t)
+	probe, _ := newTestOutboundProbe(t, canary.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := probe.Check(ctx)
+
+	assert.True(t, result.Reachable)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestNewOutboundProbe_DisabledWithoutCanary(t *testing.T) {
+	assert.Nil(t, NewOutboundProbe(config.OutboundProbeConfig{Timeout: time.Second}, zaptest.NewLogger(t), NewMockMetrics()))
+}
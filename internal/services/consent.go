@@ -0,0 +1,113 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// consentSignature identifies a consent management platform by its script sources and banner elements
+type consentSignature struct {
+	provider  string
+	scripts   []string // Substrings of script src attributes
+	selectors []string // Elements the CMP injects or ships in the markup
+}
+
+// consentSignatures lists the recognized consent management platforms in detection order
+var consentSignatures = []consentSignature{
+	{
+		provider:  constants.ConsentProviderOneTrust,
+		scripts:   []string{"cdn.cookielaw.org", "optanon.blob.core.windows.net", "otsdkstub.js", "onetrust"},
+		selectors: []string{"#onetrust-banner-sdk", "#onetrust-consent-sdk", ".optanon-alert-box-wrapper"},
+	},
+	{
+		provider:  constants.ConsentProviderCookiebot,
+		scripts:   []string{"consent.cookiebot.com", "consentcdn.cookiebot.com"},
+		selectors: []string{"#CybotCookiebotDialog", "script#Cookiebot"},
+	},
+	{
+		provider:  constants.ConsentProviderQuantcast,
+		scripts:   []string{"cmp.quantcast.com", "quantcast.mgr.consensu.org"},
+		selectors: []string{"#qc-cmp2-container", ".qc-cmp2-container", "#qcCmpUi"},
+	},
+	{
+		provider:  constants.ConsentProviderDidomi,
+		scripts:   []string{"sdk.privacy-center.org", "didomi"},
+		selectors: []string{"#didomi-host", "#didomi-notice", ".didomi-popup-container"},
+	},
+	{
+		provider:  constants.ConsentProviderTrustArc,
+		scripts:   []string{"consent.trustarc.com", "consent.truste.com"},
+		selectors: []string{"#truste-consent-track", "#teconsent", "#truste-consent-button"},
+	},
+}
+
+// consentButtonWords are button texts that accept or reject cookies
+var consentButtonWords = []string{"accept", "agree", "allow", "reject", "decline", "deny", "got it", "ok"}
+
+// detectConsentBanner detects well-known consent management platforms, falling back to
+// the IAB TCF API marker and a heuristic for cookie notices with accept/reject buttons.
+// The heuristic reads the text of every container and is skipped for oversized documents.
+func (a *Analyzer) detectConsentBanner(doc *goquery.Document, limits domLimits) models.ConsentBanner {
+	for _, signature := range consentSignatures {
+		if hasScriptSrc(doc, signature.scripts) || doc.Find(strings.Join(signature.selectors, ", ")).Length() > 0 {
+			return models.ConsentBanner{Detected: true, Provider: signature.provider}
+		}
+	}
+
+	tcfStub := false
+	doc.Find("script:not([src])").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		tcfStub = strings.Contains(s.Text(), "__tcfapi")
+		return !tcfStub
+	})
+	if tcfStub || doc.Find("iframe[name='__tcfapiLocator']").Length() > 0 {
+		return models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderIABTCF}
+	}
+
+	if !limits.oversized && hasGenericCookieNotice(doc) {
+		return models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderGeneric}
+	}
+
+	return models.ConsentBanner{}
+}
+
+// hasGenericCookieNotice looks for a small visible element mentioning cookies that offers a consent button
+func hasGenericCookieNotice(doc *goquery.Document) bool {
+	found := false
+	doc.Find("div, section, aside, dialog, form, footer").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		text := strings.ToLower(strings.Join(strings.Fields(s.Text()), " "))
+		if len(text) > constants.ConsentBannerMaxTextLength || !strings.Contains(text, "cookie") || isHidden(s) {
+			return true
+		}
+
+		s.Find("button, a, input[type='button'], input[type='submit']").EachWithBreak(func(_ int, btn *goquery.Selection) bool {
+			label := strings.ToLower(strings.TrimSpace(btn.Text() + " " + btn.AttrOr("value", "")))
+			for _, word := range consentButtonWords {
+				if label == word || strings.HasPrefix(label, word+" ") {
+					found = true
+					break
+				}
+			}
+			return !found
+		})
+		return !found
+	})
+	return found
+}
+
+// isHidden reports whether the element or one of its ancestors is statically hidden
+func isHidden(s *goquery.Selection) bool {
+	for node := s; node.Length() > 0; node = node.Parent() {
+		if _, hidden := node.Attr("hidden"); hidden || node.AttrOr("aria-hidden", "") == "true" {
+			return true
+		}
+		style := strings.ReplaceAll(strings.ToLower(node.AttrOr("style", "")), " ", "")
+		if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+			return true
+		}
+	}
+	return false
+}
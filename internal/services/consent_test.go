@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_DetectConsentBanner(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name     string
+		html     string
+		expected models.ConsentBanner
+	}{
+		{
+			name:     "OneTrust script",
+			html:     `<html><head><script src="https://cdn.cookielaw.org/scripttemplates/otSDKStub.js" data-domain-script="0f1e2d3c"></script></head><body></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderOneTrust},
+		},
+		{
+			name:     "OneTrust banner markup",
+			html:     `<html><body><div id="onetrust-consent-sdk"><div id="onetrust-banner-sdk" class="otFlat"><button id="onetrust-accept-btn-handler">Accept All Cookies</button></div></div></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderOneTrust},
+		},
+		{
+			name:     "Cookiebot",
+			html:     `<html><head><script id="Cookiebot" src="https://consent.cookiebot.com/uc.js" data-cbid="00000000-0000-0000-0000-000000000000" type="text/javascript" async></script></head><body></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderCookiebot},
+		},
+		{
+			name:     "Quantcast Choice",
+			html:     `<html><head><script async src="https://cmp.quantcast.com/choice/AbCdEf/example.com/choice.js?tag_version=V2"></script></head><body><div id="qc-cmp2-container"></div></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderQuantcast},
+		},
+		{
+			name:     "Didomi",
+			html:     `<html><head><script>window.didomiConfig = {};</script></head><body><div id="didomi-host" data-nosnippet="true" aria-hidden="true"></div></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderDidomi},
+		},
+		{
+			name:     "TrustArc",
+			html:     `<html><head><script async src="//consent.trustarc.com/notice?domain=example.com&c=teconsent&js=nj&noticeType=bb"></script></head><body><div id="teconsent"></div></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderTrustArc},
+		},
+		{
+			name:     "IAB TCF stub",
+			html:     `<html><head><script>!function(){window.__tcfapi=function(){var a=arguments;(window.__tcfapi.a=window.__tcfapi.a||[]).push(a)}}();</script></head><body></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderIABTCF},
+		},
+		{
+			name:     "Generic cookie notice",
+			html:     `<html><body><main><h1>Shop</h1></main><div class="notice"><p>We use cookies to improve your experience.</p><button>Accept</button><button>Reject all</button></div></body></html>`,
+			expected: models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderGeneric},
+		},
+		{
+			name:     "Hidden cookie notice",
+			html:     `<html><body><div style="display: none"><p>We use cookies.</p><button>Accept</button></div></body></html>`,
+			expected: models.ConsentBanner{},
+		},
+		{
+			name:     "Cookie recipe page",
+			html:     `<html><body><article><h1>Chocolate chip cookies</h1><p>Mix the cookie dough.</p><a href="/recipes">More recipes</a></article></body></html>`,
+			expected: models.ConsentBanner{},
+		},
+		{
+			name:     "No banner",
+			html:     `<html><head><script src="/app.js"></script></head><body><h1>Hello</h1></body></html>`,
+			expected: models.ConsentBanner{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, analyzer.detectConsentBanner(doc, domLimits{}))
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeReportsCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "do-not-leak", HttpOnly: true})
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><script src="https://consent.cookiebot.com/uc.js"></script></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	result, err := analyzer.Analyze(context.Background(), server.URL)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ConsentBanner{Detected: true, Provider: constants.ConsentProviderCookiebot}, result.ConsentBanner)
+	assert.Equal(t, []models.CookieAudit{{Name: "sid", HTTPOnly: true}}, result.Cookies)
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// auditResponseCookies reports the cookies set by the response of pageURL by name and
+// attributes only, along with the security problems of those attributes at now
+func auditResponseCookies(header http.Header, pageURL *url.URL, now time.Time) []models.CookieAudit {
+	cookies := []models.CookieAudit{}
+	https := pageURL != nil && pageURL.Scheme == "https"
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		audit := models.CookieAudit{
+			Name:       cookie.Name,
+			Domain:     cookie.Domain,
+			Path:       cookie.Path,
+			Secure:     cookie.Secure,
+			HTTPOnly:   cookie.HttpOnly,
+			SameSite:   rawSameSite(cookie.Raw),
+			Persistent: cookie.MaxAge > 0 || !cookie.Expires.IsZero() || cookie.RawExpires != "",
+		}
+		lifetime := cookieLifetime(cookie, now)
+		audit.LifetimeSeconds = int64(lifetime / time.Second)
+
+		if https && !cookie.Secure {
+			audit.Issues = append(audit.Issues, constants.CookieIssueMissingSecure)
+		}
+		if audit.SameSite == "None" && !cookie.Secure {
+			audit.Issues = append(audit.Issues, constants.CookieIssueSameSiteNoneInsecure)
+		}
+		if lifetime > constants.CookieMaxLifetime {
+			audit.Issues = append(audit.Issues, constants.CookieIssueLongExpiry)
+		}
+		cookies = append(cookies, audit)
+	}
+	return cookies
+}
+
+// cookieLifetime returns how long a cookie lives from now, Max-Age taking precedence over
+// Expires as in browsers. Session cookies and cookies being deleted have none.
+func cookieLifetime(cookie *http.Cookie, now time.Time) time.Duration {
+	switch {
+	case cookie.MaxAge > 0:
+		return time.Duration(cookie.MaxAge) * time.Second
+	case cookie.MaxAge < 0 || cookie.Expires.IsZero() || !cookie.Expires.After(now):
+		return 0
+	default:
+		return cookie.Expires.Sub(now)
+	}
+}
+
+// rawSameSite returns the SameSite attribute of a Set-Cookie line as written, normalizing
+// the case of known values. net/http reports unknown values the same as an absent attribute,
+// which would hide the misspelled ones.
+func rawSameSite(line string) string {
+	sameSite := ""
+	_, attributes, _ := strings.Cut(line, ";")
+	for attribute := range strings.SplitSeq(attributes, ";") {
+		name, value, _ := strings.Cut(attribute, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "SameSite") {
+			continue
+		}
+		// The last occurrence of an attribute wins
+		sameSite = strings.TrimSpace(value)
+		for _, known := range []string{"Lax", "Strict", "None"} {
+			if strings.EqualFold(sameSite, known) {
+				sameSite = known
+			}
+		}
+	}
+	return sameSite
+}
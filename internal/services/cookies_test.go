@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAuditResponseCookies(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	httpsPage, _ := url.Parse("https://example.com/")
+	httpPage, _ := url.Parse("http://example.com/")
+
+	tests := []struct {
+		name     string
+		page     *url.URL
+		cookie   string
+		expected models.CookieAudit
+	}{
+		{
+			name:     "Secure session cookie",
+			page:     httpsPage,
+			cookie:   "session=secret-value; Path=/; Secure; HttpOnly; SameSite=Lax",
+			expected: models.CookieAudit{Name: "session", Path: "/", Secure: true, HTTPOnly: true, SameSite: "Lax"},
+		},
+		{
+			name:     "Persistent cookie by Max-Age",
+			page:     httpsPage,
+			cookie:   "prefs=dark; Domain=example.com; Max-Age=31536000; SameSite=None; Secure",
+			expected: models.CookieAudit{Name: "prefs", Domain: "example.com", Secure: true, SameSite: "None", Persistent: true, LifetimeSeconds: 31536000},
+		},
+		{
+			name:   "Persistent cookie by Expires",
+			page:   httpPage,
+			cookie: "tracking=abc; Expires=Wed, 21 Oct 2037 07:28:00 GMT",
+			expected: models.CookieAudit{
+				Name:            "tracking",
+				Persistent:      true,
+				LifetimeSeconds: int64(time.Date(2037, 10, 21, 7, 28, 0, 0, time.UTC).Sub(now) / time.Second),
+				Issues:          []string{constants.CookieIssueLongExpiry},
+			},
+		},
+		{
+			name:     "Insecure cookie on an https page",
+			page:     httpsPage,
+			cookie:   "sid=1; HttpOnly",
+			expected: models.CookieAudit{Name: "sid", HTTPOnly: true, Issues: []string{constants.CookieIssueMissingSecure}},
+		},
+		{
+			name:     "Insecure cookie on an http page",
+			page:     httpPage,
+			cookie:   "sid=1; HttpOnly",
+			expected: models.CookieAudit{Name: "sid", HTTPOnly: true},
+		},
+		{
+			name:     "SameSite=None without Secure",
+			page:     httpPage,
+			cookie:   "widget=1; SameSite=none",
+			expected: models.CookieAudit{Name: "widget", SameSite: "None", Issues: []string{constants.CookieIssueSameSiteNoneInsecure}},
+		},
+		{
+			name:   "Every issue",
+			page:   httpsPage,
+			cookie: "ad=1; SameSite=None; Max-Age=63072000",
+			expected: models.CookieAudit{
+				Name:            "ad",
+				SameSite:        "None",
+				Persistent:      true,
+				LifetimeSeconds: 63072000,
+				Issues:          []string{constants.CookieIssueMissingSecure, constants.CookieIssueSameSiteNoneInsecure, constants.CookieIssueLongExpiry},
+			},
+		},
+		{
+			name:     "Unknown SameSite value kept as written",
+			page:     httpPage,
+			cookie:   "theme=1; SameSite=Relaxed",
+			expected: models.CookieAudit{Name: "theme", SameSite: "Relaxed"},
+		},
+		{
+			name:     "Last SameSite attribute wins",
+			page:     httpsPage,
+			cookie:   "pref=1; Secure; SameSite=None; samesite=STRICT",
+			expected: models.CookieAudit{Name: "pref", Secure: true, SameSite: "Strict"},
+		},
+		{
+			name:     "Max-Age takes precedence over Expires",
+			page:     httpsPage,
+			cookie:   "pref=1; Secure; Expires=Wed, 21 Oct 2099 07:28:00 GMT; Max-Age=3600",
+			expected: models.CookieAudit{Name: "pref", Secure: true, Persistent: true, LifetimeSeconds: 3600},
+		},
+		{
+			name:     "Deleted cookie",
+			page:     httpsPage,
+			cookie:   "old=; Secure; Expires=Thu, 01 Jan 1970 00:00:00 GMT",
+			expected: models.CookieAudit{Name: "old", Secure: true, Persistent: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Add("Set-Cookie", tt.cookie)
+			assert.Equal(t, []models.CookieAudit{tt.expected}, auditResponseCookies(header, tt.page, now))
+		})
+	}
+
+	header := http.Header{}
+	header.Add("Set-Cookie", "first=1")
+	header.Add("Set-Cookie", "not a cookie")
+	header.Add("Set-Cookie", "second=2")
+	cookies := auditResponseCookies(header, httpPage, now)
+	require.Len(t, cookies, 2, "invalid Set-Cookie lines are ignored")
+	assert.Equal(t, "first", cookies[0].Name)
+	assert.Equal(t, "second", cookies[1].Name)
+
+	assert.Empty(t, auditResponseCookies(nil, nil, now))
+}
+
+func TestAnalyzer_AnalyzeAuditsCookies(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "do-not-leak", Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		http.SetCookie(w, &http.Cookie{Name: "tracker", Value: "do-not-leak", MaxAge: 5 * 365 * 24 * 60 * 60, SameSite: http.SameSiteNoneMode})
+		w.Header().Set(constants.HeaderContentType, "text/html")
+		w.Write([]byte(`<html><head><title>Shop</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil)
+	transport := analyzer.httpClient.Transport.(*resolvingTransport).base
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	result, err := analyzer.Analyze(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.CookieAudit{
+		{Name: "sid", Path: "/", Secure: true, HTTPOnly: true, SameSite: "Strict"},
+		{
+			Name:            "tracker",
+			SameSite:        "None",
+			Persistent:      true,
+			LifetimeSeconds: 5 * 365 * 24 * 60 * 60,
+			Issues:          []string{constants.CookieIssueMissingSecure, constants.CookieIssueSameSiteNoneInsecure, constants.CookieIssueLongExpiry},
+		},
+	}, result.Cookies)
+}
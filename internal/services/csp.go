@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// urlAttributes are the attributes that can navigate to or load a javascript: URL
+var urlAttributes = []string{"href", "src", "action", "formaction"}
+
+// checkCSPReadiness counts the inline code a strict Content-Security-Policy would block.
+// A strict (nonce-based) policy governs scripts, so inline styles are reported but do not
+// affect the verdict.
+func (a *Analyzer) checkCSPReadiness(doc *goquery.Document) models.CSPReadiness {
+	readiness := models.CSPReadiness{}
+
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+		handler, jsURL, style := false, false, false
+		for _, attr := range node.Attr {
+			name := strings.ToLower(attr.Key)
+			switch {
+			case strings.HasPrefix(name, "on") && len(name) > 2:
+				handler = true
+			case name == "style" && strings.TrimSpace(attr.Val) != "":
+				style = true
+			case isURLAttribute(name) && isJavaScriptURL(attr.Val):
+				jsURL = true
+			}
+		}
+		if handler {
+			readiness.InlineEventHandlers++
+		}
+		if jsURL {
+			readiness.JavaScriptURLs++
+		}
+		if style {
+			readiness.InlineStyles++
+		}
+	})
+
+	doc.Find("script:not([src])").Each(func(_ int, s *goquery.Selection) {
+		if _, hasNonce := s.Attr("nonce"); hasNonce || strings.TrimSpace(s.Text()) == "" || !isExecutableScript(s) {
+			return
+		}
+		readiness.InlineScripts++
+	})
+
+	readiness.StrictCSPCompatible = readiness.InlineEventHandlers == 0 &&
+		readiness.InlineScripts == 0 &&
+		readiness.JavaScriptURLs == 0
+
+	return readiness
+}
+
+// addCSPConflicts notes the inline code that the page's own enforced policies block
+func addCSPConflicts(readiness *models.CSPReadiness, header http.Header) {
+	policies := parseCSP(header.Values(constants.HeaderContentSecurityPolicy))
+	readiness.PolicyPresent = len(policies) > 0
+
+	for _, policy := range policies {
+		if directive, blocked := blocksInline(policy, "script-src-attr", "script-src", "default-src"); blocked {
+			if readiness.InlineEventHandlers > 0 {
+				readiness.Conflicts = append(readiness.Conflicts, fmt.Sprintf("%s blocks %d inline event handlers", directive, readiness.InlineEventHandlers))
+			}
+			if readiness.JavaScriptURLs > 0 {
+				readiness.Conflicts = append(readiness.Conflicts, fmt.Sprintf("%s blocks %d javascript: URLs", directive, readiness.JavaScriptURLs))
+			}
+		}
+		if directive, blocked := blocksInline(policy, "script-src-elem", "script-src", "default-src"); blocked && readiness.InlineScripts > 0 {
+			readiness.Conflicts = append(readiness.Conflicts, fmt.Sprintf("%s blocks %d inline scripts without a nonce, unless allowed by hash", directive, readiness.InlineScripts))
+		}
+		if directive, blocked := blocksInline(policy, "style-src-attr", "style-src", "default-src"); blocked && readiness.InlineStyles > 0 {
+			readiness.Conflicts = append(readiness.Conflicts, fmt.Sprintf("%s blocks %d inline style attributes", directive, readiness.InlineStyles))
+		}
+	}
+}
+
+// parseCSP parses Content-Security-Policy header values into policies of directive name to source list.
+// Several headers, or comma-separated policies within one, are all enforced.
+func parseCSP(values []string) []map[string][]string {
+	var policies []map[string][]string
+	for _, value := range values {
+		for _, serialized := range strings.Split(value, ",") {
+			policy := make(map[string][]string)
+			for _, directive := range strings.Split(serialized, ";") {
+				fields := strings.Fields(directive)
+				if len(fields) == 0 {
+					continue
+				}
+				name := strings.ToLower(fields[0])
+				// Only the first occurrence of a directive counts
+				if _, seen := policy[name]; !seen {
+					policy[name] = fields[1:]
+				}
+			}
+			if len(policy) > 0 {
+				policies = append(policies, policy)
+			}
+		}
+	}
+	return policies
+}
+
+// blocksInline returns the directive governing inline code, taking the first present of the
+// fallback chain, and whether it disallows inline code. 'unsafe-inline' is ignored when a
+// nonce, hash or 'strict-dynamic' is present.
+func blocksInline(policy map[string][]string, chain ...string) (string, bool) {
+	for _, name := range chain {
+		sources, ok := policy[name]
+		if !ok {
+			continue
+		}
+		unsafeInline := false
+		for _, source := range sources {
+			source = strings.ToLower(source)
+			switch {
+			case source == "'unsafe-inline'":
+				unsafeInline = true
+			case source == "'strict-dynamic'", strings.HasPrefix(source, "'nonce-"),
+				strings.HasPrefix(source, "'sha256-"), strings.HasPrefix(source, "'sha384-"), strings.HasPrefix(source, "'sha512-"):
+				return name, true
+			}
+		}
+		return name, !unsafeInline
+	}
+	return "", false
+}
+
+func isURLAttribute(name string) bool {
+	for _, attr := range urlAttributes {
+		if name == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// isJavaScriptURL reports whether a URL uses the javascript: scheme. Browsers ignore
+// whitespace and control characters around and inside the scheme.
+func isJavaScriptURL(value string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, value)
+	return len(cleaned) >= len("javascript:") && strings.EqualFold(cleaned[:len("javascript:")], "javascript:")
+}
+
+// isExecutableScript reports whether a script element runs code rather than holding data
+// such as JSON-LD or templates. Import maps are subject to script-src as well.
+func isExecutableScript(s *goquery.Selection) bool {
+	scriptType := strings.ToLower(strings.TrimSpace(s.AttrOr("type", "")))
+	switch scriptType {
+	case "", "module", "importmap":
+		return true
+	}
+	return strings.Contains(scriptType, "javascript") || strings.Contains(scriptType, "ecmascript")
+}
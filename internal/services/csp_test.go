@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func checkCSPFixture(t *testing.T, htmlContent string) models.CSPReadiness {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	require.NoError(t, err)
+	return analyzer.checkCSPReadiness(doc)
+}
+
+func TestAnalyzer_CheckCSPReadiness(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected models.CSPReadiness
+	}{
+		{
+			name:     "No inline code",
+			html:     `<html><head><script src="/app.js"></script></head><body><a href="/about">About</a></body></html>`,
+			expected: models.CSPReadiness{StrictCSPCompatible: true},
+		},
+		{
+			name:     "Inline event handlers count elements",
+			html:     `<html><body><button onclick="a()" onfocus="b()">A</button><svg onload="c()"></svg><div data-onclick="x"></div></body></html>`,
+			expected: models.CSPReadiness{InlineEventHandlers: 2},
+		},
+		{
+			name:     "Inline scripts without nonce",
+			html:     `<html><head><script>a()</script><script nonce="abc">b()</script><script type="text/javascript">c()</script><script type="importmap">{}</script><script></script><script type="application/json">{}</script></head></html>`,
+			expected: models.CSPReadiness{InlineScripts: 3},
+		},
+		{
+			name:     "javascript: URLs",
+			html:     `<html><body><a href="javascript:void(0)">A</a><a href="  JAVASCRIPT:go()">B</a><a href="java&#x09;script:go()">C</a><iframe src="javascript:''"></iframe><a href="/javascript:">D</a></body></html>`,
+			expected: models.CSPReadiness{JavaScriptURLs: 4},
+		},
+		{
+			name:     "Inline styles do not affect the verdict",
+			html:     `<html><body><div style="color:red"></div><p style=" "></p></body></html>`,
+			expected: models.CSPReadiness{InlineStyles: 1, StrictCSPCompatible: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, checkCSPFixture(t, tt.html))
+		})
+	}
+}
+
+func TestAnalyzer_CheckCSPReadiness_Fixture(t *testing.T) {
+	readiness := checkCSPFixture(t, loadFixture(t, "csp_inline.html"))
+
+	assert.Equal(t, models.CSPReadiness{
+		InlineEventHandlers: 4, // body, button, img, menu link
+		InlineScripts:       2, // dataLayer and the module
+		JavaScriptURLs:      4, // menu and back links, form action, formaction
+		InlineStyles:        2,
+	}, readiness)
+}
+
+func TestAddCSPConflicts(t *testing.T) {
+	inline := models.CSPReadiness{InlineEventHandlers: 2, InlineScripts: 1, JavaScriptURLs: 3, InlineStyles: 4}
+
+	tests := []struct {
+		name              string
+		policies          []string
+		expectedConflicts []string
+	}{
+		{
+			name:     "Default-src without unsafe-inline blocks everything",
+			policies: []string{"default-src 'self'"},
+			expectedConflicts: []string{
+				"default-src blocks 2 inline event handlers",
+				"default-src blocks 3 javascript: URLs",
+				"default-src blocks 1 inline scripts without a nonce, unless allowed by hash",
+				"default-src blocks 4 inline style attributes",
+			},
+		},
+		{
+			name:     "Unsafe-inline allows inline code",
+			policies: []string{"default-src 'self' 'unsafe-inline'"},
+		},
+		{
+			name:     "Nonces disable unsafe-inline for scripts",
+			policies: []string{"script-src 'nonce-abc' 'unsafe-inline'; style-src 'unsafe-inline'"},
+			expectedConflicts: []string{
+				"script-src blocks 2 inline event handlers",
+				"script-src blocks 3 javascript: URLs",
+				"script-src blocks 1 inline scripts without a nonce, unless allowed by hash",
+			},
+		},
+		{
+			name:     "More specific directives take precedence",
+			policies: []string{"script-src 'self'; script-src-attr 'unsafe-inline'; style-src-attr 'unsafe-inline'; style-src 'self'"},
+			expectedConflicts: []string{
+				"script-src blocks 1 inline scripts without a nonce, unless allowed by hash",
+			},
+		},
+		{
+			name:     "Every enforced policy applies",
+			policies: []string{"script-src * 'unsafe-inline'", "img-src 'self', STYLE-SRC 'self'"},
+			expectedConflicts: []string{
+				"style-src blocks 4 inline style attributes",
+			},
+		},
+		{
+			name:     "Policies without relevant directives",
+			policies: []string{"img-src 'self'; frame-ancestors 'none'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, policy := range tt.policies {
+				header.Add(constants.HeaderContentSecurityPolicy, policy)
+			}
+			readiness := inline
+
+			addCSPConflicts(&readiness, header)
+
+			assert.True(t, readiness.PolicyPresent)
+			assert.Equal(t, tt.expectedConflicts, readiness.Conflicts)
+		})
+	}
+
+	t.Run("No policy", func(t *testing.T) {
+		readiness := inline
+		addCSPConflicts(&readiness, http.Header{})
+		assert.False(t, readiness.PolicyPresent)
+		assert.Empty(t, readiness.Conflicts)
+
+		addCSPConflicts(&readiness, nil)
+		assert.False(t, readiness.PolicyPresent)
+	})
+}
+
+func TestAnalyzer_CSPReadinessUsesResponsePolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(constants.HeaderContentSecurityPolicy, "script-src 'self'")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>CSP</title></head><body><button onclick="go()">Go</button></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, server.URL, mock.Anything, mock.Anything).Return(nil)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	result, err := analyzer.Analyze(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.False(t, result.CSPReadiness.StrictCSPCompatible)
+	assert.True(t, result.CSPReadiness.PolicyPresent)
+	assert.Equal(t, []string{"script-src blocks 1 inline event handlers"}, result.CSPReadiness.Conflicts)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// isDataURI reports whether an attribute value is a data: URI, which carries its content
+// inline and is neither resolved against the base URL nor checked
+func isDataURI(value string) bool {
+	value = strings.TrimSpace(value)
+	return len(value) >= len("data:") && strings.EqualFold(value[:len("data:")], "data:")
+}
+
+// summarizeResources counts the data: URIs in href and src attributes and estimates the size
+// of their content once decoded
+func (a *Analyzer) summarizeResources(doc *goquery.Document) models.Resources {
+	var resources models.Resources
+	for _, attr := range []string{"href", "src"} {
+		doc.Find("[" + attr + "]").Each(func(_ int, s *goquery.Selection) {
+			value := s.AttrOr(attr, "")
+			if !isDataURI(value) {
+				return
+			}
+			if resources.DataURIs.ByAttribute == nil {
+				resources.DataURIs.ByAttribute = make(map[string]int)
+			}
+			resources.DataURIs.Count++
+			resources.DataURIs.ByAttribute[attr]++
+			resources.DataURIs.DecodedBytes += dataURIDecodedSize(value)
+		})
+	}
+	return resources
+}
+
+// dataURIDecodedSize estimates the size of the content of a data: URI without decoding it:
+// three bytes per four base64 characters, or one byte per character with percent escapes
+// counted once. A URI without a comma carries nothing.
+func dataURIDecodedSize(uri string) int64 {
+	header, payload, found := strings.Cut(uri, ",")
+	if !found {
+		return 0
+	}
+	if strings.HasSuffix(strings.ToLower(strings.TrimSpace(header)), ";base64") {
+		chars := 0
+		for i := 0; i < len(payload); i++ {
+			switch c := payload[i]; {
+			case c == '=' || c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			default:
+				chars++
+			}
+		}
+		return int64(chars) * 3 / 4
+	}
+	return int64(max(len(payload)-2*strings.Count(payload, "%"), 0))
+}
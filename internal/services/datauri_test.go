@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestIsDataURI(t *testing.T) {
+	assert.True(t, isDataURI("data:image/png;base64,iVBORw0KGgo="))
+	assert.True(t, isDataURI("  DATA:text/plain,hi"))
+	assert.False(t, isDataURI("/data:thing"))
+	assert.False(t, isDataURI("https://example.com/data:"))
+	assert.False(t, isDataURI("data"))
+}
+
+func TestDataURIDecodedSize(t *testing.T) {
+	tests := []struct {
+		uri      string
+		expected int64
+	}{
+		{"data:image/png;base64," + base64.StdEncoding.EncodeToString(make([]byte, 300)), 300},
+		{"data:image/gif;base64," + base64.StdEncoding.EncodeToString(make([]byte, 301)), 301},
+		{"data:;BASE64,AAAA\nAAAA", 6},
+		{"data:text/plain,hello%20world", 11},
+		{"data:,", 0},
+		{"data:text/plain", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			assert.Equal(t, tt.expected, dataURIDecodedSize(tt.uri))
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeHTML_DataURIs(t *testing.T) {
+	image := "data:image/png;base64," + base64.StdEncoding.EncodeToString(make([]byte, 30000))
+	var page strings.Builder
+	page.WriteString(`<!DOCTYPE html><html><head><title>Gallery</title>
+		<link rel="icon" href="data:image/svg+xml,%3Csvg%3E%3C/svg%3E">
+		<style>body { background: url(data:image/png;base64,AAAA) }</style>
+		</head><body>`)
+	for range 20 {
+		page.WriteString(`<img src="` + image + `" alt="Photo">`)
+	}
+	page.WriteString(`<a href="data:text/html,%3Cp%3Ehi%3C%2Fp%3E">Inline page</a>
+		<a href="/about">About</a>
+		</body></html>`)
+
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	result, err := analyzer.AnalyzeHTML(context.Background(), page.String(), "https://site.example", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.Links.Internal)
+	assert.Zero(t, result.Links.External, "data: links are not resolved into external links")
+	assert.Equal(t, 1, result.Links.DataURIs)
+	assert.Empty(t, result.Links.ExternalDomains)
+
+	assert.Equal(t, 22, result.Resources.DataURIs.Count)
+	assert.Equal(t, map[string]int{"href": 2, "src": 20}, result.Resources.DataURIs.ByAttribute)
+	// 20 images, the 11 byte icon and the 9 byte page; CSS urls are not attributes
+	assert.Equal(t, int64(20*30000+11+9), result.Resources.DataURIs.DecodedBytes)
+}
+
+func TestAnalyzer_AnalyzeHTML_DataURILinksAreNotChecked(t *testing.T) {
+	html := `<html><head><title>Inline</title></head><body>
+		<a href="data:text/plain,hello">Hello</a>
+		<a href="DATA:image/png;base64,AAAA">Pixel</a>
+	</body></html>`
+
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	result, err := analyzer.AnalyzeHTML(context.Background(), html, "https://site.example", models.AnalyzeOptions{IncludeLinkDetails: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Links.DataURIs)
+	assert.Zero(t, result.Links.Internal+result.Links.External+result.Links.Inaccessible)
+	require.NotNil(t, result.Links.Details)
+	assert.Empty(t, result.Links.Details.Internal)
+	assert.Empty(t, result.Links.Details.External)
+}
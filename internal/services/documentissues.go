@@ -0,0 +1,84 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// checkDocumentIssues flags structural malformations: repeated title and meta description
+// tags, repeated id values and nested forms or links
+func (a *Analyzer) checkDocumentIssues(htmlContent string, doc *goquery.Document) models.DocumentIssues {
+	issues := models.DocumentIssues{}
+
+	// SVG documents carry their own title elements
+	titles := doc.Find("title").Not("svg title")
+	if titles.Length() > 1 {
+		issues.Titles = titles.Map(func(_ int, s *goquery.Selection) string {
+			return cleanText(s.Text())
+		})
+	}
+
+	descriptions := doc.Find("meta[name='description' i]")
+	if descriptions.Length() > 1 {
+		issues.MetaDescriptions = descriptions.Map(func(_ int, s *goquery.Selection) string {
+			return cleanText(s.AttrOr("content", ""))
+		})
+	}
+
+	ids := make(map[string]int)
+	doc.Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		if id := s.AttrOr("id", ""); id != "" {
+			ids[id]++
+		}
+	})
+	duplicates := make(map[string]int)
+	for id, count := range ids {
+		if count > 1 {
+			duplicates[id] = count
+		}
+	}
+	issues.DuplicateIDs = topCounts(duplicates, constants.DocumentIssuesMaxDuplicateIDs)
+
+	issues.NestedForms, issues.NestedLinks = countNestedFormsAndLinks(htmlContent)
+
+	return issues
+}
+
+// countNestedFormsAndLinks counts form and a start tags opened inside an element of the same kind.
+// The HTML parser repairs such nesting, dropping the inner form and closing the outer link, so it
+// is only visible in the token stream.
+func countNestedFormsAndLinks(htmlContent string) (nestedForms, nestedLinks int) {
+	depth := map[string]int{"form": 0, "a": 0}
+	tokenizer := html.NewTokenizer(strings.NewReader(htmlContent))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// End of input; the content was already read in full
+			return nestedForms, nestedLinks
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if _, tracked := depth[tag]; !tracked {
+				continue
+			}
+			if depth[tag] > 0 {
+				if tag == "form" {
+					nestedForms++
+				} else {
+					nestedLinks++
+				}
+			}
+			depth[tag]++
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if tag := string(name); depth[tag] > 0 {
+				depth[tag]--
+			}
+		}
+	}
+}
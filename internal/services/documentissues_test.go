@@ -0,0 +1,88 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_CheckDocumentIssues(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name     string
+		html     string
+		expected models.DocumentIssues
+	}{
+		{
+			name:     "Well-formed document",
+			html:     `<html><head><title>Home</title><meta name="description" content="Home page"></head><body><div id="a"><form><a href="/">Home</a></form></div><form></form></body></html>`,
+			expected: models.DocumentIssues{},
+		},
+		{
+			name:     "Multiple titles report every value",
+			html:     `<html><head><title> First </title><title>Second</title></head><body><title>Third</title></body></html>`,
+			expected: models.DocumentIssues{Titles: []string{"First", "Second", "Third"}},
+		},
+		{
+			name:     "SVG titles are not document titles",
+			html:     `<html><head><title>Page</title></head><body><svg><title>Icon</title></svg></body></html>`,
+			expected: models.DocumentIssues{},
+		},
+		{
+			name:     "Multiple meta descriptions",
+			html:     `<html><head><meta name="description" content="One"><meta name="DESCRIPTION" content=" Two "><meta name="keywords" content="x"></head></html>`,
+			expected: models.DocumentIssues{MetaDescriptions: []string{"One", "Two"}},
+		},
+		{
+			name:     "Duplicate ids with counts",
+			html:     `<html><body><p id="x"></p><p id="x"></p><p id="x"></p><p id="y"></p><p id="y"></p><p id="z"></p><p id=""></p><p id=""></p></body></html>`,
+			expected: models.DocumentIssues{DuplicateIDs: map[string]int{"x": 3, "y": 2}},
+		},
+		{
+			name:     "Nested forms",
+			html:     `<html><body><form><form><form></form></form></form><form></form></body></html>`,
+			expected: models.DocumentIssues{NestedForms: 2},
+		},
+		{
+			name:     "Nested and unclosed links",
+			html:     `<html><body><a href="/a"><span><a href="/b">B</a></span></a><a href="/c">C<a href="/d">D</a></body></html>`,
+			expected: models.DocumentIssues{NestedLinks: 2},
+		},
+		{
+			name:     "Tags inside scripts are not counted",
+			html:     `<html><body><form><script>document.write("<form><a href='/'><a>")</script></form></body></html>`,
+			expected: models.DocumentIssues{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, analyzer.checkDocumentIssues(tt.html, doc))
+		})
+	}
+}
+
+func TestAnalyzer_CheckDocumentIssues_Fixture(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	htmlContent := loadFixture(t, "document_malformed.html")
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	require.NoError(t, err)
+
+	issues := analyzer.checkDocumentIssues(htmlContent, doc)
+
+	assert.Equal(t, []string{"Store - Home", "Untitled Document"}, issues.Titles)
+	assert.Equal(t, []string{"The best store in town.", "Legacy description left behind by the old theme."}, issues.MetaDescriptions)
+	assert.Equal(t, map[string]int{"main": 2, "promo": 3}, issues.DuplicateIDs)
+	assert.Equal(t, 1, issues.NestedForms)
+	assert.Equal(t, 2, issues.NestedLinks)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// domLimits bounds the per-element analyses of a document, in extent and, with
+// options.scope_selector, in region. A zero limit means unbounded.
+type domLimits struct {
+	anchors   int  // Anchors classified as links
+	forms     int  // Forms scanned for login fields
+	oversized bool // The document exceeds analyzer.max_dom_elements
+	limited   []string
+	scope     *goquery.Document // Region links, headings and images are taken from; nil for the whole document
+}
+
+// scoped returns the part of doc that links, headings and images are taken from
+func (l domLimits) scoped(doc *goquery.Document) *goquery.Document {
+	if l.scope == nil {
+		return doc
+	}
+	return l.scope
+}
+
+// domLimitsFor counts the elements of a parsed document and, when there are more than
+// analyzer.max_dom_elements, bounds the analyses that visit every anchor, form or container
+func (a *Analyzer) domLimitsFor(ctx context.Context, doc *goquery.Document) domLimits {
+	options := a.optionsFor(ctx)
+	maxElements := options.MaxDOMElements
+	if maxElements <= 0 || len(doc.Nodes) == 0 {
+		return domLimits{}
+	}
+
+	elements, anchors, forms := countElements(doc.Nodes[0])
+	if elements <= maxElements {
+		return domLimits{}
+	}
+
+	limits := domLimits{anchors: options.MaxDOMAnchors, forms: options.MaxDOMForms, oversized: true}
+	if limits.forms > 0 && forms > limits.forms {
+		limits.limited = append(limits.limited, constants.SectionLoginForm, constants.SectionForms)
+	}
+	// The generic cookie notice heuristic reads the text of every container
+	limits.limited = append(limits.limited, constants.SectionConsentBanner)
+	if limits.anchors > 0 && anchors > limits.anchors {
+		limits.limited = append(limits.limited, constants.SectionLinks)
+	}
+	return limits
+}
+
+// countElements walks the tree iteratively, so deeply nested markup cannot exhaust the stack,
+// and counts its elements, anchors with an href and forms
+func countElements(root *html.Node) (elements, anchors, forms int) {
+	for node := root; node != nil; {
+		if node.Type == html.ElementNode {
+			elements++
+			switch node.Data {
+			case "a":
+				if hasAttr(node, "href") {
+					anchors++
+				}
+			case "form":
+				forms++
+			}
+		}
+
+		if node.FirstChild != nil {
+			node = node.FirstChild
+			continue
+		}
+		for node != root && node.NextSibling == nil {
+			node = node.Parent
+		}
+		if node == root {
+			break
+		}
+		node = node.NextSibling
+	}
+	return elements, anchors, forms
+}
+
+// hasAttr reports whether an element carries the attribute
+func hasAttr(node *html.Node, key string) bool {
+	for _, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// firstN returns at most n elements of the selection; n <= 0 keeps them all
+func firstN(s *goquery.Selection, n int) *goquery.Selection {
+	if n <= 0 || s.Length() <= n {
+		return s
+	}
+	return s.Slice(0, n)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// hugeDOMFixture generates a page with deeply nested containers, many harmless forms followed
+// by a login form, and the given number of anchors
+func hugeDOMFixture(depth, forms, anchors int) string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html lang="en"><head><title>Huge</title></head><body>`)
+	b.WriteString(strings.Repeat("<div>", depth))
+	for i := 0; i < forms; i++ {
+		fmt.Fprintf(&b, `<form action="/search%d"><input type="search" name="q"></form>`, i)
+	}
+	b.WriteString(`<form action="/login"><input type="email" name="email"><input type="password" name="password"><button type="submit">Log in</button></form>`)
+	for i := 0; i < anchors; i++ {
+		fmt.Fprintf(&b, `<a href="/p%d">p</a>`, i)
+	}
+	b.WriteString(strings.Repeat("</div>", depth))
+	b.WriteString(`</body></html>`)
+	return b.String()
+}
+
+func TestCountElements(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body><a href="/">Home</a><a name="top"></a><form></form></body></html>`))
+	require.NoError(t, err)
+
+	elements, anchors, forms := countElements(doc.Nodes[0])
+	assert.Equal(t, 6, elements) // html, head, body, two anchors and a form
+	assert.Equal(t, 1, anchors)
+	assert.Equal(t, 1, forms)
+}
+
+func TestAnalyzer_DOMLimits(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Analyzer.MaxDOMElements = 10000
+	cfg.Analyzer.MaxDOMAnchors = 500
+	cfg.Analyzer.MaxDOMForms = 10
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	parsedURL, _ := url.Parse("https://example.com/")
+
+	analyze := func(t *testing.T, htmlContent string) *models.AnalyzeResponse {
+		doc, err := analyzer.parseHTML(htmlContent)
+		require.NoError(t, err)
+		return analyzer.performWebpageAnalysis(context.Background(), parsedURL.String(), htmlContent, doc, parsedURL, parsedURL, models.AnalyzeOptions{SkipLinkCheck: true})
+	}
+
+	t.Run("Huge document is analyzed within bounds", func(t *testing.T) {
+		htmlContent := hugeDOMFixture(2000, 50, 200000)
+
+		// Measured against parsing, so the bound holds on slow runners and under the race detector;
+		// unbounded, the analysis takes about sixty times as long as the parse
+		start := time.Now()
+		doc, err := analyzer.parseHTML(htmlContent)
+		require.NoError(t, err)
+		parsing := time.Since(start)
+
+		start = time.Now()
+		result := analyzer.performWebpageAnalysis(context.Background(), parsedURL.String(), htmlContent, doc, parsedURL, parsedURL, models.AnalyzeOptions{SkipLinkCheck: true})
+		elapsed := time.Since(start)
+
+		t.Logf("parsing %v, analysis %v", parsing, elapsed)
+		assert.Less(t, elapsed, 10*parsing)
+		assert.False(t, result.Partial)
+		assert.True(t, result.TruncatedAnalysis)
+		assert.Equal(t, []string{constants.SectionLoginForm, constants.SectionForms, constants.SectionConsentBanner, constants.SectionLinks}, result.LimitedSections)
+		assert.Equal(t, 500, result.Links.Internal)
+		// The login form comes after the scanned forms
+		assert.False(t, result.HasLoginForm)
+		assert.Equal(t, "Huge", result.Title)
+	})
+
+	t.Run("Sections within their limits are not reported", func(t *testing.T) {
+		result := analyze(t, hugeDOMFixture(10000, 0, 100))
+
+		assert.True(t, result.TruncatedAnalysis)
+		assert.Equal(t, []string{constants.SectionConsentBanner}, result.LimitedSections)
+		assert.Equal(t, 100, result.Links.Internal)
+		assert.True(t, result.HasLoginForm)
+	})
+
+	t.Run("Documents under the element limit are analyzed in full", func(t *testing.T) {
+		result := analyze(t, hugeDOMFixture(10, 50, 1000))
+
+		assert.False(t, result.TruncatedAnalysis)
+		assert.Empty(t, result.LimitedSections)
+		assert.Equal(t, 1000, result.Links.Internal)
+		assert.True(t, result.HasLoginForm)
+	})
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// errDuplicatesDisabled is returned by Duplicates when cache.duplicates is not enabled
+var errDuplicatesDisabled = errors.New("duplicate index is not enabled")
+
+// DuplicateIndex finds the analyzed pages of a site sharing a page's title or meta description.
+// *Cache implements it when cache.duplicates is enabled.
+type DuplicateIndex interface {
+	// Duplicates returns the duplicates of pageURL, a canonical URL, or nil when it is not indexed
+	Duplicates(ctx context.Context, pageURL string) (*models.Duplicates, error)
+}
+
+// indexDuplicates records the title and meta description of a cached entry. Each page keeps
+// a record of both under webpage-duplicates:page:<url>, and each distinct value a sorted set
+// of the URLs of the site sharing it, scored by when their entry expires, so members expire
+// with their entries and only the latest cache.duplicates.max_urls are kept.
+func (c *Cache) indexDuplicates(ctx context.Context, key string, result *models.AnalyzeResponse, ttl time.Duration) error {
+	// Only the default view of a page is indexed. Variants may have seen another page, such as
+	// the one behind options.auth, and must neither be served to anyone nor overwrite it.
+	if strings.Contains(key, "|") {
+		return nil
+	}
+	pageURL := key
+	domain := siteDomain(pageURL)
+	if domain == "" {
+		return nil
+	}
+	now := time.Now()
+	expires := float64(now.Add(ttl).UnixMilli())
+
+	start := time.Now()
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pageKey := constants.DuplicateIndexKeyPrefix + constants.DuplicateIndexPageKey + pageURL
+		pipe.HSet(ctx, pageKey, constants.DuplicateKindTitle, result.Title, constants.DuplicateKindDescription, result.MetaDescription)
+		pipe.PExpire(ctx, pageKey, ttl)
+		for kind, value := range map[string]string{constants.DuplicateKindTitle: result.Title, constants.DuplicateKindDescription: result.MetaDescription} {
+			if normalizeDuplicateValue(value) == "" {
+				continue
+			}
+			valueKey := duplicateValueKey(kind, domain, value)
+			pipe.ZAdd(ctx, valueKey, redis.Z{Score: expires, Member: pageURL})
+			pipe.ZRemRangeByScore(ctx, valueKey, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+			pipe.ZRemRangeByRank(ctx, valueKey, 0, int64(-c.duplicatesMax-1))
+			// The set lives as long as its latest entry
+			pipe.ExpireNX(ctx, valueKey, ttl)
+			pipe.ExpireGT(ctx, valueKey, ttl)
+		}
+		return nil
+	})
+	c.observe(ctx, constants.CacheOpSet, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to index duplicates: %w", err)
+	}
+	return nil
+}
+
+// Duplicates returns the other pages on the registered domain of pageURL whose cached
+// analyses share its title or meta description, each list sorted, or nil when pageURL has
+// no cached analysis.
+func (c *Cache) Duplicates(ctx context.Context, pageURL string) (*models.Duplicates, error) {
+	if c.client == nil || !c.duplicates {
+		return nil, errDuplicatesDisabled
+	}
+	domain := siteDomain(pageURL)
+
+	start := time.Now()
+	page, err := c.client.HGetAll(ctx, constants.DuplicateIndexKeyPrefix+constants.DuplicateIndexPageKey+pageURL).Result()
+	c.observe(ctx, constants.CacheOpGet, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read duplicate index: %w", err)
+	}
+	if len(page) == 0 || domain == "" {
+		return nil, nil
+	}
+
+	duplicates := &models.Duplicates{
+		URL:             pageURL,
+		Domain:          domain,
+		Title:           page[constants.DuplicateKindTitle],
+		MetaDescription: page[constants.DuplicateKindDescription],
+	}
+	if duplicates.SameTitle, err = c.sharing(ctx, constants.DuplicateKindTitle, domain, pageURL, duplicates.Title); err != nil {
+		return nil, err
+	}
+	if duplicates.SameMetaDescription, err = c.sharing(ctx, constants.DuplicateKindDescription, domain, pageURL, duplicates.MetaDescription); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// sharing returns the indexed URLs of domain other than pageURL whose kind still has value.
+// A page analyzed again with another value stays in the set of its old value until its
+// previous entry would have expired, so candidates are checked against their page record.
+func (c *Cache) sharing(ctx context.Context, kind, domain, pageURL, value string) ([]string, error) {
+	shared := []string{}
+	normalized := normalizeDuplicateValue(value)
+	if normalized == "" {
+		return shared, nil
+	}
+
+	start := time.Now()
+	members, err := c.client.ZRangeByScore(ctx, duplicateValueKey(kind, domain, value), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		c.observe(ctx, constants.CacheOpGet, start, err)
+		return nil, fmt.Errorf("failed to read duplicate index: %w", err)
+	}
+	members = slices.DeleteFunc(members, func(member string) bool { return member == pageURL })
+
+	values := make([]*redis.StringCmd, len(members))
+	_, err = c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			values[i] = pipe.HGet(ctx, constants.DuplicateIndexKeyPrefix+constants.DuplicateIndexPageKey+member, kind)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		c.observe(ctx, constants.CacheOpGet, start, err)
+		return nil, fmt.Errorf("failed to read duplicate index: %w", err)
+	}
+	c.observe(ctx, constants.CacheOpGet, start, nil)
+
+	for i, member := range members {
+		if normalizeDuplicateValue(values[i].Val()) == normalized {
+			shared = append(shared, member)
+		}
+	}
+	slices.Sort(shared)
+	return shared, nil
+}
+
+// siteDomain returns the registered domain of a URL's host, or "" when it has no host
+func siteDomain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+	return registeredDomain(strings.TrimSuffix(strings.ToLower(parsed.Hostname()), "."))
+}
+
+// normalizeDuplicateValue compares titles and descriptions case-insensitively, ignoring spacing
+func normalizeDuplicateValue(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// duplicateValueKey returns the key of the set of URLs of domain sharing value
+func duplicateValueKey(kind, domain, value string) string {
+	sum := sha256.Sum256([]byte(normalizeDuplicateValue(value)))
+	return constants.DuplicateIndexKeyPrefix + kind + ":" + domain + ":" + hex.EncodeToString(sum[:])
+}
+
+// FindDuplicates returns the other analyzed pages of targetURL's site sharing its title or
+// meta description, from the index cache.duplicates maintains
+func (a *Analyzer) FindDuplicates(ctx context.Context, targetURL string) (*models.Duplicates, error) {
+	parsedURL, err := a.parseAndValidateURL(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	disabled := &AnalysisError{
+		Code:    constants.ErrorCodeDuplicatesDisabled,
+		Status:  constants.StatusNotFound,
+		Message: "Duplicate detection is not enabled",
+	}
+	index, ok := a.cache.(DuplicateIndex)
+	if !ok {
+		return nil, disabled
+	}
+
+	duplicates, err := index.Duplicates(ctx, canonicalURL(parsedURL))
+	if errors.Is(err, errDuplicatesDisabled) {
+		return nil, disabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if duplicates == nil {
+		return nil, &AnalysisError{
+			Code:    constants.ErrorCodeNotAnalyzed,
+			Status:  constants.StatusNotFound,
+			Message: "The page has no cached analysis; analyze it first",
+		}
+	}
+	duplicates.URL = models.StripCredentials(targetURL)
+	return duplicates, nil
+}
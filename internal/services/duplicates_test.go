@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newTitledSite serves pages titled and described as set in pages, by path
+func newTitledSite(t *testing.T) (*httptest.Server, func(path, title, description string)) {
+	var mu sync.Mutex
+	pages := map[string][2]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		page := pages[r.URL.Path]
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>%s</title><meta name="description" content="%s"></head><body><p>Some content</p></body></html>`, page[0], page[1])
+	}))
+	t.Cleanup(server.Close)
+	return server, func(path, title, description string) {
+		mu.Lock()
+		defer mu.Unlock()
+		pages[path] = [2]string{title, description}
+	}
+}
+
+func newDuplicatesAnalyzer(t *testing.T) (*Analyzer, *Cache, func(time.Duration)) {
+	cache, mr, m := newTestCache(t)
+	t.Cleanup(func() { cache.Close() })
+	cache.duplicates = true
+	cache.duplicatesMax = constants.DefaultDuplicateIndexMaxURLs
+	cfg := createTestConfig()
+	cfg.Cache.Enabled = true
+	return NewAnalyzer(cfg, zaptest.NewLogger(t), m, cache), cache, mr.FastForward
+}
+
+func TestAnalyzer_FindDuplicates(t *testing.T) {
+	server, setPage := newTitledSite(t)
+	setPage("/a", "Welcome to Example", "The best example site")
+	setPage("/b", "welcome  to example", "Another description")
+	setPage("/c", "Contact us", "The best example site")
+	analyzer, _, _ := newDuplicatesAnalyzer(t)
+	ctx := context.Background()
+	opts := models.AnalyzeOptions{}
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		_, err := analyzer.AnalyzeWithOptions(ctx, server.URL+path, opts)
+		require.NoError(t, err)
+	}
+	// Another site sharing the title is not a duplicate
+	other := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	_, err := analyzer.AnalyzeWithOptions(ctx, other+"/a", opts)
+	require.NoError(t, err)
+
+	duplicates, err := analyzer.FindDuplicates(ctx, server.URL+"/a")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Duplicates{
+		URL:                 server.URL + "/a",
+		Domain:              "127.0.0.1",
+		Title:               "Welcome to Example",
+		MetaDescription:     "The best example site",
+		SameTitle:           []string{server.URL + "/b"},
+		SameMetaDescription: []string{server.URL + "/c"},
+	}, duplicates, "titles match ignoring case and spacing")
+
+	duplicates, err = analyzer.FindDuplicates(ctx, server.URL+"/b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/a"}, duplicates.SameTitle)
+	assert.Empty(t, duplicates.SameMetaDescription)
+}
+
+func TestAnalyzer_FindDuplicates_RetitledPage(t *testing.T) {
+	server, setPage := newTitledSite(t)
+	setPage("/a", "Home", "")
+	setPage("/b", "Home", "")
+	analyzer, _, _ := newDuplicatesAnalyzer(t)
+	ctx := context.Background()
+
+	for _, path := range []string{"/a", "/b"} {
+		_, err := analyzer.AnalyzeWithOptions(ctx, server.URL+path, models.AnalyzeOptions{})
+		require.NoError(t, err)
+	}
+	setPage("/b", "About", "")
+	_, err := analyzer.AnalyzeWithOptions(ctx, server.URL+"/b", models.AnalyzeOptions{ForceRefresh: true})
+	require.NoError(t, err)
+
+	duplicates, err := analyzer.FindDuplicates(ctx, server.URL+"/a")
+	require.NoError(t, err)
+	assert.Empty(t, duplicates.SameTitle, "the page no longer has the title")
+	assert.Empty(t, duplicates.SameMetaDescription, "empty descriptions are not duplicates")
+}
+
+func TestAnalyzer_FindDuplicates_ExpiresWithCache(t *testing.T) {
+	server, setPage := newTitledSite(t)
+	setPage("/a", "Home", "")
+	setPage("/b", "Home", "")
+	analyzer, _, fastForward := newDuplicatesAnalyzer(t)
+	ctx := context.Background()
+
+	_, err := analyzer.AnalyzeWithOptions(ctx, server.URL+"/a", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	fastForward(50 * time.Minute)
+	_, err = analyzer.AnalyzeWithOptions(ctx, server.URL+"/b", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	fastForward(20 * time.Minute)
+
+	_, err = analyzer.FindDuplicates(ctx, server.URL+"/a")
+	var analysisErr *AnalysisError
+	require.ErrorAs(t, err, &analysisErr)
+	assert.Equal(t, constants.ErrorCodeNotAnalyzed, analysisErr.Code, "the entry of /a expired, and its index entries with it")
+}
+
+func TestAnalyzer_FindDuplicates_DefaultViewOnly(t *testing.T) {
+	server, setPage := newTitledSite(t)
+	setPage("/a", "Home", "")
+	setPage("/b", "Home", "")
+	analyzer, _, _ := newDuplicatesAnalyzer(t)
+	ctx := context.Background()
+
+	_, err := analyzer.AnalyzeWithOptions(ctx, server.URL+"/a", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	// Variants, credentialed ones included, neither index the page nor overwrite its record
+	setPage("/a", "Staff only", "")
+	setPage("/b", "Staff only", "")
+	for _, opts := range []models.AnalyzeOptions{
+		{Auth: &models.AnalyzeAuth{Headers: map[string]string{"Authorization": "Bearer t0ken"}}},
+		{AcceptLanguage: "de"},
+		{SkipLinkCheck: true},
+	} {
+		for _, path := range []string{"/a", "/b"} {
+			_, err := analyzer.AnalyzeWithOptions(ctx, server.URL+path, opts)
+			require.NoError(t, err)
+		}
+	}
+	_, err = analyzer.AnalyzeWithOptions(ctx, strings.Replace(server.URL, "://", "://alice:s3cret@", 1)+"/b", models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	duplicates, err := analyzer.FindDuplicates(ctx, server.URL+"/a")
+	require.NoError(t, err)
+	assert.Equal(t, "Home", duplicates.Title)
+	assert.Empty(t, duplicates.SameTitle)
+
+	_, err = analyzer.FindDuplicates(ctx, server.URL+"/b")
+	assertAnalysisError(t, err, constants.ErrorCodeNotAnalyzed)
+}
+
+func TestCache_IndexDuplicates_Bounded(t *testing.T) {
+	cache, mr, _ := newTestCache(t)
+	defer cache.Close()
+	cache.duplicates = true
+	cache.duplicatesMax = 2
+	ctx := context.Background()
+
+	for i := range 4 {
+		result := &models.AnalyzeResponse{Title: "Home"}
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("https://site.example/%d", i), result, time.Duration(i+1)*time.Minute))
+	}
+
+	key := duplicateValueKey(constants.DuplicateKindTitle, "site.example", "Home")
+	members, err := mr.ZMembers(key)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"https://site.example/2", "https://site.example/3"}, members, "the latest expiring are kept")
+	assert.Equal(t, 4*time.Minute, mr.TTL(key), "the set lives as long as its latest entry")
+	assert.False(t, mr.Exists("webpage:"+key), "index keys stay out of the cached entries' keyspace")
+}
+
+func TestAnalyzer_FindDuplicates_Disabled(t *testing.T) {
+	cache, mr, m := newTestCache(t)
+	defer cache.Close()
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), m, cache)
+
+	require.NoError(t, cache.Set(context.Background(), "https://site.example/", &models.AnalyzeResponse{Title: "Home"}, 0))
+	assert.Equal(t, []string{"webpage:https://site.example/"}, mr.Keys(), "the index is not written unless enabled")
+
+	_, err := analyzer.FindDuplicates(context.Background(), "https://site.example/")
+	var analysisErr *AnalysisError
+	require.ErrorAs(t, err, &analysisErr)
+	assert.Equal(t, constants.ErrorCodeDuplicatesDisabled, analysisErr.Code)
+
+	analyzer = NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	_, err = analyzer.FindDuplicates(context.Background(), "https://site.example/")
+	require.ErrorAs(t, err, &analysisErr)
+	assert.Equal(t, constants.ErrorCodeDuplicatesDisabled, analysisErr.Code)
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// EgressMeter counts the bytes fetched for each client per UTC day
+type EgressMeter interface {
+	// Add counts n more bytes fetched for key on the day of day
+	Add(ctx context.Context, key string, day time.Time, n int64) error
+	// Used returns the bytes counted for key on the day of day
+	Used(ctx context.Context, key string, day time.Time) (int64, error)
+}
+
+// RedisEgressMeter counts bytes with INCRBY under prefix+"egress:"+day+":"+key, so every
+// instance sharing the Redis server adds to the same daily counters
+type RedisEgressMeter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisEgressMeter counts bytes with client under prefix
+func NewRedisEgressMeter(client *redis.Client, prefix string) *RedisEgressMeter {
+	return &RedisEgressMeter{client: client, prefix: prefix}
+}
+
+func (m *RedisEgressMeter) Add(ctx context.Context, key string, day time.Time, n int64) error {
+	redisKey := m.key(key, day)
+	pipe := m.client.TxPipeline()
+	pipe.IncrBy(ctx, redisKey, n)
+	pipe.Expire(ctx, redisKey, constants.EgressKeyTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to count egress bytes: %w", err)
+	}
+	return nil
+}
+
+func (m *RedisEgressMeter) Used(ctx context.Context, key string, day time.Time) (int64, error) {
+	used, err := m.client.Get(ctx, m.key(key, day)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read egress bytes: %w", err)
+	}
+	return used, nil
+}
+
+// Close closes the Redis client
+func (m *RedisEgressMeter) Close() error {
+	return m.client.Close()
+}
+
+func (m *RedisEgressMeter) key(key string, day time.Time) string {
+	return m.prefix + constants.EgressKeyPrefix + day.UTC().Format(constants.EgressDayLayout) + ":" + key
+}
+
+// MemoryEgressMeter counts bytes in the process, for single instances. Only the counters of
+// the latest day are kept.
+type MemoryEgressMeter struct {
+	mu   sync.Mutex
+	day  string
+	used map[string]int64
+}
+
+// NewMemoryEgressMeter creates a new MemoryEgressMeter instance
+func NewMemoryEgressMeter() *MemoryEgressMeter {
+	return &MemoryEgressMeter{used: make(map[string]int64)}
+}
+
+func (m *MemoryEgressMeter) Add(_ context.Context, key string, day time.Time, n int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d := day.UTC().Format(constants.EgressDayLayout); d != m.day {
+		m.day = d
+		m.used = make(map[string]int64)
+	}
+	m.used[key] += n
+	return nil
+}
+
+func (m *MemoryEgressMeter) Used(_ context.Context, key string, day time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if day.UTC().Format(constants.EgressDayLayout) != m.day {
+		return 0, nil
+	}
+	return m.used[key], nil
+}
+
+type clientContextKey struct{}
+
+// WithClient attributes the bytes fetched by analyses running under ctx to the client key,
+// the IP address the rate limiter keeps the client's budget under
+func WithClient(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, key)
+}
+
+// clientFrom returns the client of the analysis running under ctx, or "" when none is known
+func clientFrom(ctx context.Context) string {
+	key, _ := ctx.Value(clientContextKey{}).(string)
+	return key
+}
+
+// egressQuota counts the bytes each client's analyses fetch and holds clients to their daily
+// budget. A nil quota counts nothing.
+type egressQuota struct {
+	meter      EgressMeter
+	dailyBytes int64 // 0 counts without limiting
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// SetEgressMeter counts the bytes fetched for each client in meter and, with
+// rate_limit.egress.daily_bytes set, fails new analyses of clients past it
+func (a *Analyzer) SetEgressMeter(meter EgressMeter) {
+	a.egress = &egressQuota{
+		meter:      meter,
+		dailyBytes: a.options.RateLimit.Egress.DailyBytes,
+		logger:     a.logger,
+		now:        time.Now,
+	}
+}
+
+// allow fails with QUOTA_EXCEEDED once the client of ctx has fetched its daily bytes. Like
+// the rate limiter's store, a failing meter never holds up analyses.
+func (q *egressQuota) allow(ctx context.Context) error {
+	key := clientFrom(ctx)
+	if q == nil || q.dailyBytes <= 0 || key == "" {
+		return nil
+	}
+	now := q.now().UTC()
+	used, err := q.used(ctx, key, now)
+	if err != nil {
+		loggerFrom(ctx, q.logger).Warn("Failed to read egress bytes", zap.String("key", key), zap.Error(err))
+		return nil
+	}
+	if used < q.dailyBytes {
+		return nil
+	}
+	return &AnalysisError{
+		Code:       constants.ErrorCodeQuotaExceeded,
+		Status:     constants.StatusTooManyRequests,
+		Message:    fmt.Sprintf("Daily quota of %d fetched bytes exceeded; try again tomorrow", q.dailyBytes),
+		RetryAfter: nextDay(now).Sub(now),
+	}
+}
+
+// record counts the bytes the analysis fetched so far against its client. It runs once the
+// analysis is over, so it still counts after the deadline cancelled ctx.
+func (q *egressQuota) record(ctx context.Context, stats *analysisStats) {
+	key := clientFrom(ctx)
+	if q == nil || stats == nil || key == "" {
+		return
+	}
+	n := stats.pageBytes.Load() + stats.linkBytes.Load()
+	if n == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constants.RateLimitStoreTimeout)
+	defer cancel()
+
+	if err := q.meter.Add(ctx, key, q.now(), n); err != nil {
+		loggerFrom(ctx, q.logger).Warn("Failed to count egress bytes", zap.String("key", key), zap.Int64("bytes", n), zap.Error(err))
+	}
+}
+
+func (q *egressQuota) used(ctx context.Context, key string, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.RateLimitStoreTimeout)
+	defer cancel()
+	return q.meter.Used(ctx, key, now)
+}
+
+// EgressUsage returns the bytes fetched today for the client key and its daily budget, or
+// nil when fetched bytes are not counted
+func (a *Analyzer) EgressUsage(ctx context.Context, key string) (*models.EgressUsage, error) {
+	if a.egress == nil {
+		return nil, nil
+	}
+	now := a.egress.now().UTC()
+	used, err := a.egress.used(ctx, key, now)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.EgressUsage{UsedBytes: used, ResetAt: nextDay(now)}
+	if a.egress.dailyBytes > 0 {
+		remaining := max(a.egress.dailyBytes-used, 0)
+		usage.DailyBytes = a.egress.dailyBytes
+		usage.RemainingBytes = &remaining
+	}
+	return usage, nil
+}
+
+// nextDay returns the start of the UTC day after now
+func nextDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
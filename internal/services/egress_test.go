@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_EgressQuota(t *testing.T) {
+	// One page larger than the whole daily budget
+	page := "<!DOCTYPE html><html><head><title>Large</title></head><body><p>" + strings.Repeat("lorem ipsum ", 2000) + "</p></body></html>"
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, func(cfg *config.Config) {
+		cfg.RateLimit.Egress.DailyBytes = 10000
+	})
+	meter := NewMemoryEgressMeter()
+	analyzer.SetEgressMeter(meter)
+	opts := models.AnalyzeOptions{SkipLinkCheck: true}
+	client := WithClient(context.Background(), "203.0.113.9")
+
+	_, err := analyzer.AnalyzeWithOptions(client, server.URL, opts)
+	require.NoError(t, err, "the budget is checked before fetching, so the page that exhausts it is analyzed")
+
+	usage, err := analyzer.EgressUsage(context.Background(), "203.0.113.9")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(page)), usage.UsedBytes)
+	assert.Equal(t, int64(10000), usage.DailyBytes)
+	assert.Equal(t, int64(0), *usage.RemainingBytes)
+	assert.True(t, usage.ResetAt.After(time.Now()))
+
+	_, err = analyzer.AnalyzeWithOptions(client, server.URL, opts)
+	var analysisErr *AnalysisError
+	require.ErrorAs(t, err, &analysisErr)
+	assert.Equal(t, constants.ErrorCodeQuotaExceeded, analysisErr.Code)
+	assert.Equal(t, constants.StatusTooManyRequests, analysisErr.Status)
+	assert.Positive(t, analysisErr.RetryAfter)
+	assert.LessOrEqual(t, analysisErr.RetryAfter, 24*time.Hour)
+	assert.Equal(t, int32(1), requests.Load(), "the page is not fetched past the budget")
+
+	_, err = analyzer.AnalyzeHTML(client, page, server.URL, opts)
+	require.ErrorAs(t, err, &analysisErr, "submitted HTML is held to the budget too")
+	assert.Equal(t, constants.ErrorCodeQuotaExceeded, analysisErr.Code)
+
+	_, err = analyzer.AnalyzeWithOptions(WithClient(context.Background(), "198.51.100.7"), server.URL, opts)
+	assert.NoError(t, err, "other clients keep their own budget")
+	_, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL, opts)
+	assert.NoError(t, err, "analyses of no known client are not counted")
+	used, err := meter.Used(context.Background(), "", time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, used)
+}
+
+func TestAnalyzer_EgressCountsLinkChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("linked page"))
+	}))
+	defer server.Close()
+
+	cfg := createTestConfig()
+	// Link checks read response bodies only with GET
+	cfg.Analyzer.LinkCheckOverrides = []config.LinkCheckOverride{{Pattern: "127.0.0.1", Method: http.MethodGet}}
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	analyzer.SetEgressMeter(NewMemoryEgressMeter())
+	html := `<html><body><a href="/a">A</a><a href="/b">B</a></body></html>`
+
+	result, err := analyzer.AnalyzeHTML(WithClient(context.Background(), "203.0.113.9"), html, server.URL, models.AnalyzeOptions{IncludeStats: true})
+	require.NoError(t, err)
+
+	usage, err := analyzer.EgressUsage(context.Background(), "203.0.113.9")
+	require.NoError(t, err)
+	assert.Positive(t, result.Stats.LinkCheckBytes)
+	assert.Equal(t, result.Stats.BytesFetched, usage.UsedBytes)
+	assert.Zero(t, usage.DailyBytes, "without daily_bytes the bytes are only counted")
+	assert.Nil(t, usage.RemainingBytes)
+}
+
+func TestAnalyzer_EgressUsageNotCounted(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	usage, err := analyzer.EgressUsage(context.Background(), "203.0.113.9")
+
+	require.NoError(t, err)
+	assert.Nil(t, usage)
+}
+
+func TestRedisEgressMeter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	meter := NewRedisEgressMeter(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:")
+	ctx := context.Background()
+	day := time.Date(2024, 3, 19, 23, 30, 0, 0, time.UTC)
+
+	require.NoError(t, meter.Add(ctx, "203.0.113.9", day, 4000))
+	require.NoError(t, meter.Add(ctx, "203.0.113.9", day.Add(20*time.Minute), 2500))
+
+	used, err := meter.Used(ctx, "203.0.113.9", day)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6500), used)
+	assert.Equal(t, "6500", mustGet(t, mr, "test:egress:2024-03-19:203.0.113.9"))
+	assert.Equal(t, constants.EgressKeyTTL, mr.TTL("test:egress:2024-03-19:203.0.113.9"))
+
+	used, err = meter.Used(ctx, "203.0.113.9", day.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, used, "each UTC day starts over")
+	used, err = meter.Used(ctx, "198.51.100.7", day)
+	require.NoError(t, err)
+	assert.Zero(t, used)
+
+	mr.Close()
+	_, err = meter.Used(ctx, "203.0.113.9", day)
+	assert.Error(t, err)
+}
+
+func TestMemoryEgressMeter(t *testing.T) {
+	meter := NewMemoryEgressMeter()
+	ctx := context.Background()
+	day := time.Date(2024, 3, 19, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, meter.Add(ctx, "203.0.113.9", day, 4000))
+	require.NoError(t, meter.Add(ctx, "203.0.113.9", day, 2500))
+	used, _ := meter.Used(ctx, "203.0.113.9", day)
+	assert.Equal(t, int64(6500), used)
+
+	require.NoError(t, meter.Add(ctx, "198.51.100.7", day.Add(24*time.Hour), 100))
+	used, _ = meter.Used(ctx, "203.0.113.9", day.Add(24*time.Hour))
+	assert.Zero(t, used, "each UTC day starts over")
+	used, _ = meter.Used(ctx, "198.51.100.7", day.Add(24*time.Hour))
+	assert.Equal(t, int64(100), used)
+}
+
+func mustGet(t *testing.T, mr *miniredis.Miniredis, key string) string {
+	value, err := mr.Get(key)
+	require.NoError(t, err)
+	return value
+}
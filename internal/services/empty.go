@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// checkEmptyDocument rejects a fetched page with nothing to analyze: an empty body, a body
+// smaller than analyzer.min_body_bytes, or a document whose head and body hold no content.
+// Such pages would otherwise produce an analysis of empty strings and zeros.
+func (a *Analyzer) checkEmptyDocument(ctx context.Context, body string, doc *goquery.Document) error {
+	options := a.optionsFor(ctx)
+	reason := ""
+	switch {
+	case len(body) == 0:
+		reason = "empty body"
+	case int64(len(body)) < options.MinBodyBytes:
+		reason = fmt.Sprintf("body smaller than %d bytes", options.MinBodyBytes)
+	case !hasContent(doc.Find("head")) && !hasContent(doc.Find("body")):
+		reason = "no content in head or body"
+	default:
+		return nil
+	}
+
+	return &AnalysisError{
+		Code:    constants.ErrorCodeEmptyDocument,
+		Status:  constants.StatusUnprocessableEntity,
+		Message: fmt.Sprintf("Target returned an empty document (%d bytes)", len(body)),
+		Err:     fmt.Errorf("%s", reason),
+	}
+}
+
+// hasContent reports whether a selection holds an element or non-blank text
+func hasContent(sel *goquery.Selection) bool {
+	return sel.Contents().FilterFunction(func(_ int, node *goquery.Selection) bool {
+		switch node.Nodes[0].Type {
+		case html.ElementNode:
+			return true
+		case html.TextNode:
+			return strings.TrimSpace(node.Nodes[0].Data) != ""
+		}
+		return false
+	}).Length() > 0
+}
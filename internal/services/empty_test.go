@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// minBodyBytes rejects documents under the default minimum size as empty
+func minBodyBytes(cfg *config.Config) {
+	cfg.Analyzer.MinBodyBytes = constants.DefaultMinBodyBytes
+}
+
+func TestAnalyzer_Analyze_EmptyDocument(t *testing.T) {
+	blank := "<html><head></head><body>" + strings.Repeat(" \n", 40) + "<!-- nothing here --></body></html>"
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength string
+		expectedBytes string
+	}{
+		{name: "Empty body", body: "", expectedBytes: "(0 bytes)"},
+		{name: "Content-Length 0", contentLength: "0", expectedBytes: "(0 bytes)"},
+		{name: "Body under the minimum", body: "<p>Hi</p>", expectedBytes: "(9 bytes)"},
+		{name: "No head or body content", body: blank, expectedBytes: "(" + strconv.Itoa(len(blank)) + " bytes)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				if tt.contentLength != "" {
+					w.Header().Set("Content-Length", tt.contentLength)
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			t.Run("Rejected", func(t *testing.T) {
+				analyzer := newTestAnalyzer(t, minBodyBytes)
+				cache := analyzer.cache.(*MockCache)
+
+				_, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{})
+				var analysisErr *AnalysisError
+				require.True(t, errors.As(err, &analysisErr))
+				assert.Equal(t, constants.ErrorCodeEmptyDocument, analysisErr.Code)
+				assert.Equal(t, constants.StatusUnprocessableEntity, analysisErr.Status)
+				assert.Contains(t, analysisErr.Message, tt.expectedBytes)
+				cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+
+			t.Run("Allowed", func(t *testing.T) {
+				analyzer := newTestAnalyzer(t, minBodyBytes)
+				cache := analyzer.cache.(*MockCache)
+
+				result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{AllowEmpty: true})
+				require.NoError(t, err)
+				assert.Empty(t, result.Title)
+				cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+	}
+}
+
+func TestAnalyzer_Analyze_SmallDocumentWithContent(t *testing.T) {
+	page := "<html><head><title>Small</title></head><body><h1>Small page</h1></body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+	analyzer := newTestAnalyzer(t, minBodyBytes)
+	cache := analyzer.cache.(*MockCache)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Small", result.Title)
+	cache.AssertCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,24 @@
+package services
+
+import "time"
+
+// AnalysisError is an analysis failure that carries a stable error code and HTTP status for API clients
+type AnalysisError struct {
+	Code    string
+	Status  int
+	Message string
+	Err     error
+	// RetryAfter is how long the client should wait before retrying; zero when unknown
+	RetryAfter time.Duration
+}
+
+func (e *AnalysisError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AnalysisError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodeBody wraps the response body in a decompressor for its Content-Encoding.
+// It returns the normalized encoding, empty for identity.
+func decodeBody(body io.Reader, contentEncoding string) (io.ReadCloser, string, error) {
+	encoding := strings.ToLower(strings.TrimSpace(contentEncoding))
+	switch encoding {
+	case "", "identity":
+		return io.NopCloser(body), "", nil
+	case constants.EncodingGzip, "x-gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid gzip body: %w", err)
+		}
+		return reader, constants.EncodingGzip, nil
+	case constants.EncodingDeflate:
+		// "deflate" should be zlib-wrapped, but some servers send raw DEFLATE streams
+		buffered := bufio.NewReader(body)
+		if header, err := buffered.Peek(2); err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			reader, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid deflate body: %w", err)
+			}
+			return reader, constants.EncodingDeflate, nil
+		}
+		return flate.NewReader(buffered), constants.EncodingDeflate, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+}
+
+// pageValidators are the ETag and Last-Modified headers of a previously fetched page, sent to
+// fetch the page only if it changed since
+type pageValidators struct {
+	etag         string
+	lastModified string
+}
+
+// validatorsOf returns the validators of the page behind a cached result, or nil when the
+// target sent none
+func validatorsOf(result *models.AnalyzeResponse) *pageValidators {
+	if result == nil || result.Fetch == nil || (result.Fetch.ETag == "" && result.Fetch.LastModified == "") {
+		return nil
+	}
+	return &pageValidators{etag: result.Fetch.ETag, lastModified: result.Fetch.LastModified}
+}
+
+// apply makes the request conditional on the page having changed
+func (v *pageValidators) apply(req *http.Request) {
+	if v == nil {
+		return
+	}
+	if v.etag != "" {
+		req.Header.Set(constants.HeaderIfNoneMatch, v.etag)
+	}
+	if v.lastModified != "" {
+		req.Header.Set(constants.HeaderIfModifiedSince, v.lastModified)
+	}
+}
+
+// newFetchInfo builds the fetch metadata reported for a page response
+func newFetchInfo(resp *http.Response, encoding string, transferBytes, decompressedBytes int64) *models.FetchInfo {
+	info := &models.FetchInfo{
+		StatusCode:        resp.StatusCode,
+		Proto:             resp.Proto,
+		ContentEncoding:   encoding,
+		Compressed:        encoding != "",
+		TransferBytes:     transferBytes,
+		DecompressedBytes: decompressedBytes,
+		HTTP3Advertised:   advertisesHTTP3(resp.Header.Values(constants.HeaderAltSvc)),
+		ContentLanguage:   strings.TrimSpace(resp.Header.Get(constants.HeaderContentLanguage)),
+		VariesByLanguage:  variesBy(resp.Header.Values(constants.HeaderVary), constants.HeaderAcceptLanguage),
+		ETag:              resp.Header.Get(constants.HeaderETag),
+		LastModified:      resp.Header.Get(constants.HeaderLastModified),
+	}
+	if decompressedBytes > 0 {
+		info.CompressionRatio = float64(transferBytes) / float64(decompressedBytes)
+	}
+	return info
+}
+
+// variesBy reports whether the Vary header values name the given request header, or every header
+func variesBy(vary []string, header string) bool {
+	for _, value := range vary {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" || strings.EqualFold(name, header) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// advertisesHTTP3 reports whether Alt-Svc offers an HTTP/3 alternative, e.g. h3=":443"; ma=86400
+func advertisesHTTP3(altSvc []string) bool {
+	for _, value := range altSvc {
+		for _, alternative := range strings.Split(value, ",") {
+			protocol, _, _ := strings.Cut(strings.TrimSpace(alternative), "=")
+			if strings.HasPrefix(protocol, constants.AltSvcHTTP3Prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headReceived reports whether a body prefix holds the complete head of the document: its
+// closing tag, or the start of the body that implies it
+func headReceived(prefix []byte) bool {
+	lower := bytes.ToLower(prefix)
+	return bytes.Contains(lower, []byte("</head")) || bytes.Contains(lower, []byte("<body"))
+}
+
+// isTimeout reports whether err ended a request by a deadline or timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+var compressiblePage = "<html><body>" + strings.Repeat("<p>Lorem ipsum dolor sit amet</p>", 200) + "</body></html>"
+
+func compress(t *testing.T, encoding, content string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		var err error
+		w, err = flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+	}
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestAnalyzer_FetchWebpage_HTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(constants.HeaderAltSvc, `h3=":443"; ma=86400, h2=":443"`)
+		w.Write([]byte("<html><body>HTTP/2</body></html>"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	// Trust the test certificate; HTTP/2 must still be negotiated with custom TLS settings
+	transport := analyzer.httpClient.Transport.(*resolvingTransport).base
+	transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, page.fetch)
+	assert.Equal(t, "HTTP/2.0", page.fetch.Proto)
+	assert.Equal(t, http.StatusOK, page.fetch.StatusCode)
+	assert.True(t, page.fetch.HTTP3Advertised)
+}
+
+func TestAnalyzer_FetchWebpage_Compression(t *testing.T) {
+	tests := []struct {
+		name             string
+		contentEncoding  string
+		body             func(t *testing.T) []byte
+		expectedEncoding string
+	}{
+		{
+			name:             "Identity",
+			body:             func(t *testing.T) []byte { return []byte(compressiblePage) },
+			expectedEncoding: "",
+		},
+		{
+			name:             "Gzip",
+			contentEncoding:  "gzip",
+			body:             func(t *testing.T) []byte { return compress(t, "gzip", compressiblePage) },
+			expectedEncoding: constants.EncodingGzip,
+		},
+		{
+			name:             "Zlib deflate",
+			contentEncoding:  "deflate",
+			body:             func(t *testing.T) []byte { return compress(t, "deflate", compressiblePage) },
+			expectedEncoding: constants.EncodingDeflate,
+		},
+		{
+			name:             "Raw deflate",
+			contentEncoding:  "deflate",
+			body:             func(t *testing.T) []byte { return compress(t, "raw-deflate", compressiblePage) },
+			expectedEncoding: constants.EncodingDeflate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, constants.AcceptEncodingSupported, r.Header.Get(constants.HeaderAcceptEncoding))
+				if tt.contentEncoding != "" {
+					w.Header().Set(constants.HeaderContentEncoding, tt.contentEncoding)
+				}
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+			page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+
+			require.NoError(t, err)
+			assert.Equal(t, compressiblePage, page.body)
+			assert.Equal(t, "HTTP/1.1", page.fetch.Proto)
+			assert.Equal(t, tt.expectedEncoding, page.fetch.ContentEncoding)
+			assert.Equal(t, tt.expectedEncoding != "", page.fetch.Compressed)
+			assert.Equal(t, int64(len(body)), page.fetch.TransferBytes)
+			assert.Equal(t, int64(len(compressiblePage)), page.fetch.DecompressedBytes)
+			assert.InDelta(t, float64(len(body))/float64(len(compressiblePage)), page.fetch.CompressionRatio, 0.0001)
+			assert.False(t, page.fetch.HTTP3Advertised)
+		})
+	}
+}
+
+func TestAnalyzer_FetchWebpage_UnsupportedEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(constants.HeaderContentEncoding, "br")
+		w.Write([]byte{0x0b, 0x02, 0x80})
+	}))
+	defer server.Close()
+
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	_, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+
+	assert.ErrorContains(t, err, `unsupported content encoding "br"`)
+}
+
+func TestAdvertisesHTTP3(t *testing.T) {
+	assert.True(t, advertisesHTTP3([]string{`h3=":443"; ma=86400`}))
+	assert.True(t, advertisesHTTP3([]string{`h2=":443"`, `h3-29=":443"; ma=3600`}))
+	assert.False(t, advertisesHTTP3([]string{`h2=":443"; ma=86400`}))
+	assert.False(t, advertisesHTTP3([]string{"clear"}))
+	assert.False(t, advertisesHTTP3(nil))
+}
+
+// versionedServer serves a page under the ETag of its current version, answering requests
+// for that version with 304 Not Modified. It counts the full responses and the 304s.
+type versionedServer struct {
+	*httptest.Server
+	version     atomic.Value
+	full        atomic.Int32
+	notModified atomic.Int32
+}
+
+func newVersionedServer(t *testing.T) *versionedServer {
+	s := &versionedServer{}
+	s.version.Store("v1")
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := s.version.Load().(string)
+		w.Header().Set(constants.HeaderETag, `"`+version+`"`)
+		w.Header().Set(constants.HeaderLastModified, "Tue, 13 Oct 2026 08:00:00 GMT")
+		if r.Header.Get(constants.HeaderIfNoneMatch) == `"`+version+`"` {
+			// Some servers repeat the encoding of the full response
+			w.Header().Set(constants.HeaderContentEncoding, "gzip")
+			s.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.full.Add(1)
+		w.Write([]byte("<html><head><title>Version " + version + "</title></head><body></body></html>"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestAnalyzer_FetchWebpage_Conditional(t *testing.T) {
+	server := newVersionedServer(t)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	assert.False(t, page.notModified)
+	assert.Equal(t, `"v1"`, page.fetch.ETag)
+	assert.Equal(t, "Tue, 13 Oct 2026 08:00:00 GMT", page.fetch.LastModified)
+
+	page, err = analyzer.fetchWebpage(context.Background(), server.URL, &pageValidators{etag: `"v1"`, lastModified: page.fetch.LastModified})
+	require.NoError(t, err)
+	assert.True(t, page.notModified)
+	assert.Equal(t, constants.StatusNotModified, page.statusCode)
+	assert.Empty(t, page.body)
+
+	page, err = analyzer.fetchWebpage(context.Background(), server.URL, &pageValidators{etag: `"v0"`})
+	require.NoError(t, err)
+	assert.False(t, page.notModified, "a changed page is fetched in full")
+	assert.Contains(t, page.body, "Version v1")
+}
+
+func TestValidatorsOf(t *testing.T) {
+	assert.Nil(t, validatorsOf(nil))
+	assert.Nil(t, validatorsOf(&models.AnalyzeResponse{}))
+	assert.Nil(t, validatorsOf(&models.AnalyzeResponse{Fetch: &models.FetchInfo{}}), "the target sent no validators")
+	assert.Equal(t, &pageValidators{etag: `"v1"`}, validatorsOf(&models.AnalyzeResponse{Fetch: &models.FetchInfo{ETag: `"v1"`}}))
+}
+
+func TestAnalyzer_ForceRefresh_Revalidates(t *testing.T) {
+	server := newVersionedServer(t)
+	cache, mr, m := newTestCache(t)
+	defer cache.Close()
+	cfg := createTestConfig()
+	cfg.Cache.Enabled = true
+	cfg.Cache.TTL = time.Hour
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), m, cache)
+	ctx := context.Background()
+	key := "webpage:" + server.URL + "/"
+
+	first, err := analyzer.AnalyzeWithOptions(ctx, server.URL+"/", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Version v1", first.Title)
+	assert.False(t, first.Revalidated)
+
+	// Without force_refresh the cached result is served without contacting the target
+	_, err = analyzer.AnalyzeWithOptions(ctx, server.URL+"/", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), server.full.Load())
+	assert.Equal(t, int32(0), server.notModified.Load())
+
+	// An unchanged page keeps its analysis, cached for another TTL
+	mr.FastForward(50 * time.Minute)
+	result, err := analyzer.AnalyzeWithOptions(ctx, server.URL+"/", models.AnalyzeOptions{ForceRefresh: true})
+	require.NoError(t, err)
+	assert.True(t, result.Revalidated)
+	assert.Equal(t, "Version v1", result.Title)
+	assert.True(t, first.AnalyzedAt.Equal(result.AnalyzedAt), "the analysis was not run again")
+	assert.Equal(t, time.Hour, result.CacheTTL)
+	assert.Equal(t, time.Hour, mr.TTL(key))
+	assert.Equal(t, int32(1), server.full.Load())
+	assert.Equal(t, int32(1), server.notModified.Load())
+
+	cached, _, err := cache.Get(ctx, server.URL+"/")
+	require.NoError(t, err)
+	assert.False(t, cached.Revalidated, "the flag describes the response, not the cached result")
+
+	// A changed page is analyzed again
+	server.version.Store("v2")
+	result, err = analyzer.AnalyzeWithOptions(ctx, server.URL+"/", models.AnalyzeOptions{ForceRefresh: true})
+	require.NoError(t, err)
+	assert.False(t, result.Revalidated)
+	assert.Equal(t, "Version v2", result.Title)
+	assert.Equal(t, `"v2"`, result.Fetch.ETag)
+	assert.Equal(t, int32(2), server.full.Load())
+}
+
+func TestAnalyzer_ForceRefresh_WithoutCachedResult(t *testing.T) {
+	server := newVersionedServer(t)
+	cache, _, m := newTestCache(t)
+	defer cache.Close()
+	cfg := createTestConfig()
+	cfg.Cache.Enabled = true
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), m, cache)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{ForceRefresh: true})
+	require.NoError(t, err)
+	assert.False(t, result.Revalidated)
+	assert.Equal(t, int32(1), server.full.Load(), "nothing to revalidate, so the fetch is unconditional")
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// auditForms resolves where each form submits to against pageURL, the URL the document was
+// served from, and flags forms submitting over http from an https page or to another
+// registered domain. Only forms submitting over http or https are judged. Actions are reported
+// without credentials, whether the page URL or the action carried them.
+func auditForms(doc *goquery.Document, pageURL *url.URL, limits domLimits) (models.Forms, models.SecuritySummary) {
+	forms := models.Forms{Items: []models.FormInfo{}}
+	var summary models.SecuritySummary
+	pageDomain := registeredDomain(strings.TrimSuffix(strings.ToLower(pageURL.Hostname()), "."))
+
+	firstN(doc.Find("form"), limits.forms).Each(func(_ int, form *goquery.Selection) {
+		forms.Count++
+		info := models.FormInfo{
+			Action:      models.StripCredentials(pageURL.String()),
+			Method:      strings.ToUpper(strings.TrimSpace(form.AttrOr("method", ""))),
+			HasPassword: form.Find("input[type='password' i]").Length() > 0,
+		}
+		if info.Method != "POST" {
+			info.Method = "GET"
+		}
+
+		// An empty action submits to the page itself
+		if action := strings.TrimSpace(form.AttrOr("action", "")); action != "" {
+			if resolved, err := pageURL.Parse(action); err == nil {
+				info.Action = models.StripCredentials(resolved.String())
+				if resolved.Scheme == "http" || resolved.Scheme == "https" {
+					info.InsecureAction = pageURL.Scheme == "https" && resolved.Scheme == "http"
+					info.CrossOriginAction = registeredDomain(strings.TrimSuffix(strings.ToLower(resolved.Hostname()), ".")) != pageDomain
+				}
+			}
+		}
+
+		summary.HasInsecureForms = summary.HasInsecureForms || info.InsecureAction
+		summary.HasCrossOriginForms = summary.HasCrossOriginForms || info.CrossOriginAction
+		if len(forms.Items) < constants.FormsMaxReported {
+			forms.Items = append(forms.Items, info)
+		}
+	})
+	return forms, summary
+}
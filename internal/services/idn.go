@@ -0,0 +1,43 @@
+package services
+
+import (
+	"net/url"
+
+	"golang.org/x/net/idna"
+)
+
+// toASCIIHost converts an internationalized hostname to its punycode form in place,
+// leaving ASCII hostnames, including IP literals, untouched
+func toASCIIHost(u *url.URL) error {
+	hostname := u.Hostname()
+	if isASCII(hostname) {
+		return nil
+	}
+	ascii, err := idna.Lookup.ToASCII(hostname)
+	if err != nil {
+		return err
+	}
+	u.Host = withPort(ascii, u.Port())
+	return nil
+}
+
+// canonicalURL returns the fetchable form of a validated URL without its credentials
+func canonicalURL(u *url.URL) string {
+	return withoutCredentials(u).String()
+}
+
+// withoutCredentials returns a copy of u without its user:password@, leaving u untouched
+func withoutCredentials(u *url.URL) *url.URL {
+	stripped := *u
+	stripped.User = nil
+	return &stripped
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// isInternalLink reports whether link belongs to the site of the page at base, following
+// analyzer.internal_scope: the page's host and port only, any host of the page's registered
+// domain, or, with suffixes, the page's host and the hosts under analyzer.internal_suffixes when
+// the page is under them too. IP literal hosts have no registered domain and only match themselves.
+// In every scope a host on another port than the page's, default ports aside, is another site.
+func (a *Analyzer) isInternalLink(ctx context.Context, base, link *url.URL) bool {
+	linkHost, baseHost := siteHost(link), siteHost(base)
+	if linkHost == "" || explicitPort(link) != explicitPort(base) {
+		return false
+	}
+
+	switch a.optionsFor(ctx).InternalScope {
+	case constants.InternalScopeExactHost:
+		return linkHost == baseHost
+	case constants.InternalScopeSuffixes:
+		return linkHost == baseHost || (a.underInternalSuffix(ctx, linkHost) && a.underInternalSuffix(ctx, baseHost))
+	}
+	return registeredDomain(linkHost) == registeredDomain(baseHost)
+}
+
+// underInternalSuffix reports whether host is one of analyzer.internal_suffixes or their subdomains
+func (a *Analyzer) underInternalSuffix(ctx context.Context, host string) bool {
+	return slices.ContainsFunc(a.optionsFor(ctx).InternalSuffixes, func(suffix string) bool {
+		return hostMatches(host, suffix)
+	})
+}
+
+// siteHost returns the lowercase host name of u without its port and trailing dot
+func siteHost(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// explicitPort returns the port of u unless it is the default port of its scheme
+func explicitPort(u *url.URL) string {
+	if port := u.Port(); port != defaultPorts[u.Scheme] {
+		return port
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// internalScope classifies links as internal by scope, and by suffixes under the suffixes scope
+func internalScope(scope string, suffixes ...string) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Analyzer.InternalScope = scope
+		cfg.Analyzer.InternalSuffixes = suffixes
+	}
+}
+
+func TestAnalyzer_IsInternalLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		link     string
+		exact    bool
+		domain   bool
+		suffixes bool // With analyzer.internal_suffixes [example.com, example-cdn.net]
+	}{
+		{name: "Same host", base: "https://www.example.com/", link: "https://www.example.com/about", exact: true, domain: true, suffixes: true},
+		{name: "Host case and trailing dot", base: "https://www.example.com/", link: "https://WWW.Example.com./about", exact: true, domain: true, suffixes: true},
+		{name: "Subdomain", base: "https://www.example.com/", link: "https://blog.example.com/post", domain: true, suffixes: true},
+		{name: "Apex domain", base: "https://www.example.com/", link: "https://example.com/", domain: true, suffixes: true},
+		{name: "Other registered domain", base: "https://www.example.com/", link: "https://example.org/"},
+		{name: "Listed sibling domain", base: "https://www.example.com/", link: "https://static.example-cdn.net/app.js", suffixes: true},
+		{name: "Subdomain under a co.uk suffix", base: "https://www.example.co.uk/", link: "https://shop.example.co.uk/", domain: true},
+		{name: "Other domain under a co.uk suffix", base: "https://www.example.co.uk/", link: "https://www.other.co.uk/"},
+		{name: "Hosts of a private suffix", base: "https://alice.github.io/", link: "https://bob.github.io/"},
+		{name: "Same IP literal", base: "http://192.0.2.10/", link: "http://192.0.2.10/status", exact: true, domain: true, suffixes: true},
+		{name: "Other IP literal", base: "http://192.0.2.10/", link: "http://192.0.2.11/status"},
+		{name: "IPv6 literal", base: "http://[2001:db8::1]/", link: "http://[2001:DB8::1]/status", exact: true, domain: true, suffixes: true},
+		{name: "Default port written out", base: "https://www.example.com/", link: "https://blog.example.com:443/", domain: true, suffixes: true},
+		{name: "Other port", base: "https://www.example.com/", link: "https://blog.example.com:8443/"},
+		{name: "No host", base: "https://www.example.com/", link: "mailto:team@example.com"},
+	}
+
+	analyzers := map[string]*Analyzer{
+		constants.InternalScopeExactHost:        newTestAnalyzer(t, internalScope(constants.InternalScopeExactHost)),
+		constants.InternalScopeRegisteredDomain: newTestAnalyzer(t, internalScope(constants.InternalScopeRegisteredDomain)),
+		constants.InternalScopeSuffixes:         newTestAnalyzer(t, internalScope(constants.InternalScopeSuffixes, "Example.com.", " example-cdn.net")),
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := url.Parse(tt.base)
+			require.NoError(t, err)
+			link, err := url.Parse(tt.link)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.exact, analyzers[constants.InternalScopeExactHost].isInternalLink(context.Background(), base, link), "exact_host")
+			assert.Equal(t, tt.domain, analyzers[constants.InternalScopeRegisteredDomain].isInternalLink(context.Background(), base, link), "registered_domain")
+			assert.Equal(t, tt.suffixes, analyzers[constants.InternalScopeSuffixes].isInternalLink(context.Background(), base, link), "suffixes")
+		})
+	}
+}
+
+func TestAnalyzer_IsInternalLink_SuffixesOfAnotherSite(t *testing.T) {
+	analyzer := newTestAnalyzer(t, internalScope(constants.InternalScopeSuffixes, "example.com"))
+	base, _ := url.Parse("https://www.other.org/")
+	sameHost, _ := url.Parse("https://www.other.org/about")
+	listed, _ := url.Parse("https://www.example.com/")
+
+	assert.True(t, analyzer.isInternalLink(context.Background(), base, sameHost))
+	assert.False(t, analyzer.isInternalLink(context.Background(), base, listed), "the suffixes are internal to their own site only")
+}
+
+func TestAnalyzer_ClassifyLinks_InternalScope(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+		<a href="/">Home</a>
+		<a href="https://blog.example.com/">Blog</a>
+		<a href="https://blog.example.com/docs/setup">Setup</a>
+		<a href="https://example.com/about">About</a>
+		<a href="https://www.example.org/">Elsewhere</a>
+	</body></html>`))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse("https://www.example.com/")
+
+	t.Run("Registered domain by default", func(t *testing.T) {
+		analysis, internal, external := newTestAnalyzer(t, internalScope("")).classifyLinks(context.Background(), doc, baseURL, domLimits{})
+
+		assert.Equal(t, 4, analysis.Internal)
+		assert.Equal(t, 1, analysis.External)
+		assert.Equal(t, []string{"https://www.example.com/", "https://blog.example.com/", "https://blog.example.com/docs/setup", "https://example.com/about"}, internal)
+		assert.Equal(t, []string{"https://www.example.org/"}, external)
+		// Only the page's own host links back to it
+		assert.Equal(t, models.InternalLinkDetail{UniquePaths: 4, MaxPathDepth: 2, SelfLinks: 1}, analysis.InternalDetail)
+	})
+
+	t.Run("Exact host", func(t *testing.T) {
+		analysis, internal, _ := newTestAnalyzer(t, internalScope(constants.InternalScopeExactHost)).classifyLinks(context.Background(), doc, baseURL, domLimits{})
+
+		assert.Equal(t, 1, analysis.Internal)
+		assert.Equal(t, 4, analysis.External)
+		assert.Equal(t, []string{"https://www.example.com/"}, internal)
+		assert.Equal(t, map[string]int{"example.com": 3, "example.org": 1}, analysis.ExternalDomains)
+	})
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// interstitialRule is one signal of an intrusive interstitial. An element matches when it
+// meets every condition the rule sets.
+type interstitialRule struct {
+	name         string
+	weight       int      // Added to the score once, however many elements match
+	selector     string   // Candidate elements
+	positions    []string // Inline position values, one of which is required
+	fullViewport bool     // The inline style must stretch the element over the viewport
+	overflow     []string // Inline overflow values, one of which is required
+	words        []string // Words of the class or ID, split at hyphens and underscores, one of which is required
+	classes      []string // Whole class names, one of which is required
+	visibleOnly  bool     // Skip statically hidden elements, such as modals shown later by scripts
+}
+
+// interstitialRules are the signals of an intrusive interstitial, strongest first
+var interstitialRules = []interstitialRule{
+	{
+		name:         constants.InterstitialRuleFullViewportOverlay,
+		weight:       2,
+		selector:     "body [style]",
+		positions:    []string{"fixed", "absolute"},
+		fullViewport: true,
+		visibleOnly:  true,
+	},
+	{
+		name:        constants.InterstitialRuleOverlayMarkup,
+		weight:      1,
+		selector:    "body [class], body [id]",
+		words:       []string{"modal", "overlay", "popup", "interstitial"},
+		visibleOnly: true,
+	},
+	{
+		name:     constants.InterstitialRuleScrollLockStyle,
+		weight:   1,
+		selector: "html[style], body[style]",
+		overflow: []string{"hidden"},
+	},
+	{
+		name:     constants.InterstitialRuleScrollLockClass,
+		weight:   1,
+		selector: "html[class], body[class]",
+		classes:  []string{"modal-open", "no-scroll", "noscroll", "overflow-hidden", "scroll-lock"},
+	},
+}
+
+// interstitialExemptWords mark consent banners and age gates in classes and IDs; search
+// engines do not penalize interstitials shown for legal obligations
+var interstitialExemptWords = []string{"cookie", "consent", "gdpr", "age"}
+
+// detectInterstitial scores the page against interstitialRules. Confidence follows the
+// summed weights of the rules matched, and an interstitial is suspected from medium on.
+func detectInterstitial(doc *goquery.Document) models.Interstitial {
+	result := models.Interstitial{Confidence: constants.InterstitialConfidenceNone}
+	score := 0
+	for _, rule := range interstitialRules {
+		matched := make(map[*html.Node]bool)
+		doc.Find(rule.selector).Each(func(_ int, s *goquery.Selection) {
+			// The parts of a matched overlay, such as .modal-dialog in .modal, add no evidence
+			if (len(matched) > 0 && insideMatched(s, matched)) || !rule.matches(s) {
+				return
+			}
+			matched[s.Get(0)] = true
+			if len(result.Evidence) < constants.InterstitialMaxEvidence {
+				result.Evidence = append(result.Evidence, models.InterstitialEvidence{Rule: rule.name, Element: elementLabel(s)})
+			}
+		})
+		if len(matched) > 0 {
+			score += rule.weight
+		}
+	}
+
+	switch {
+	case score >= constants.InterstitialHighScore:
+		result.Confidence = constants.InterstitialConfidenceHigh
+	case score >= constants.InterstitialSuspectedScore:
+		result.Confidence = constants.InterstitialConfidenceMedium
+	case score > 0:
+		result.Confidence = constants.InterstitialConfidenceLow
+	}
+	result.Suspected = score >= constants.InterstitialSuspectedScore
+	return result
+}
+
+// matches reports whether the element meets every condition of the rule
+func (r interstitialRule) matches(s *goquery.Selection) bool {
+	if hasAnyWord(elementWords(s), interstitialExemptWords) {
+		return false
+	}
+	style := inlineStyle(s.AttrOr("style", ""))
+	if len(r.positions) > 0 && !slices.Contains(r.positions, style["position"]) {
+		return false
+	}
+	if r.fullViewport && !coversViewport(style) {
+		return false
+	}
+	if len(r.overflow) > 0 && !slices.Contains(r.overflow, style["overflow"]) && !slices.Contains(r.overflow, style["overflow-y"]) {
+		return false
+	}
+	if len(r.words) > 0 && !hasAnyWord(elementWords(s), r.words) {
+		return false
+	}
+	if len(r.classes) > 0 && !hasAnyWord(strings.Fields(strings.ToLower(s.AttrOr("class", ""))), r.classes) {
+		return false
+	}
+	return !r.visibleOnly || !isHidden(s)
+}
+
+// insideMatched reports whether an ancestor of the element is in matched
+func insideMatched(s *goquery.Selection, matched map[*html.Node]bool) bool {
+	for node := s.Get(0).Parent; node != nil; node = node.Parent {
+		if matched[node] {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineStyle parses a style attribute into lower-case properties and values, without
+// whitespace or !important
+func inlineStyle(attr string) map[string]string {
+	style := make(map[string]string)
+	for declaration := range strings.SplitSeq(strings.ToLower(attr), ";") {
+		property, value, ok := strings.Cut(declaration, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSuffix(strings.ReplaceAll(value, " ", ""), "!important")
+		style[strings.TrimSpace(property)] = value
+	}
+	return style
+}
+
+// coversViewport reports whether an inline style stretches a positioned element over the
+// whole viewport, by its insets or by a full size at the top left corner
+func coversViewport(style map[string]string) bool {
+	zero := func(property string) bool { return style[property] == "0" || style[property] == "0px" }
+	full := func(property, unit string) bool { return style[property] == "100%" || style[property] == "100"+unit }
+
+	if zero("inset") {
+		return true
+	}
+	if zero("top") && zero("left") && (zero("right") || full("width", "vw")) && (zero("bottom") || full("height", "vh")) {
+		return true
+	}
+	return false
+}
+
+// elementWords returns the lower-case words of an element's classes and ID, split at
+// hyphens and underscores
+func elementWords(s *goquery.Selection) []string {
+	return strings.FieldsFunc(strings.ToLower(s.AttrOr("class", "")+" "+s.AttrOr("id", "")), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '-' || r == '_'
+	})
+}
+
+// hasAnyWord reports whether words holds one of wanted
+func hasAnyWord(words, wanted []string) bool {
+	return slices.ContainsFunc(words, func(word string) bool { return slices.Contains(wanted, word) })
+}
+
+// elementLabel names an element by its tag and ID, or its first class when it has no ID
+func elementLabel(s *goquery.Selection) string {
+	label := goquery.NodeName(s)
+	if id := s.AttrOr("id", ""); id != "" {
+		return label + idSelector(id)
+	}
+	if classes := strings.Fields(s.AttrOr("class", "")); len(classes) > 0 {
+		return label + "." + classes[0]
+	}
+	return label
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestDetectInterstitial_NewsletterPopup(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(loadFixture(t, "newsletter_popup.html")))
+	require.NoError(t, err)
+
+	assert.Equal(t, models.Interstitial{
+		Suspected:  true,
+		Confidence: constants.InterstitialConfidenceHigh,
+		Evidence: []models.InterstitialEvidence{
+			{Rule: constants.InterstitialRuleFullViewportOverlay, Element: "div#newsletter-popup"},
+			{Rule: constants.InterstitialRuleOverlayMarkup, Element: "div#newsletter-popup"},
+			{Rule: constants.InterstitialRuleScrollLockStyle, Element: "body.home"},
+			{Rule: constants.InterstitialRuleScrollLockClass, Element: "body.home"},
+		},
+	}, detectInterstitial(doc), "the cookie banner and the hidden modal add no evidence, nor do the popup's parts")
+}
+
+func TestDetectInterstitial(t *testing.T) {
+	tests := []struct {
+		name       string
+		html       string
+		suspected  bool
+		confidence string
+		rules      []string
+	}{
+		{
+			name:       "Clean page",
+			html:       `<html><body><header class="site-header"><a href="/">Home</a></header><main><h1>About</h1><p>Hello</p></main></body></html>`,
+			confidence: constants.InterstitialConfidenceNone,
+		},
+		{
+			name:       "Sticky header is not full viewport",
+			html:       `<html><body><header style="position: fixed; top: 0; left: 0; width: 100%; height: 60px">Menu</header></body></html>`,
+			confidence: constants.InterstitialConfidenceNone,
+		},
+		{
+			name:       "Fixed overlay by insets",
+			html:       `<html><body><div style="position:fixed;top:0;right:0;bottom:0;left:0">Sign up</div></body></html>`,
+			suspected:  true,
+			confidence: constants.InterstitialConfidenceMedium,
+			rules:      []string{constants.InterstitialRuleFullViewportOverlay},
+		},
+		{
+			name:       "Absolute overlay with inset and !important",
+			html:       `<html><body><section style="POSITION: absolute !important; inset: 0">Offer</section></body></html>`,
+			suspected:  true,
+			confidence: constants.InterstitialConfidenceMedium,
+			rules:      []string{constants.InterstitialRuleFullViewportOverlay},
+		},
+		{
+			name:       "Viewport units",
+			html:       `<html><body><div style="position: fixed; top: 0; left: 0; width: 100vw; height: 100vh">Offer</div></body></html>`,
+			suspected:  true,
+			confidence: constants.InterstitialConfidenceMedium,
+			rules:      []string{constants.InterstitialRuleFullViewportOverlay},
+		},
+		{
+			name:       "Visible modal markup alone",
+			html:       `<html><body><div class="newsletter_interstitial">Subscribe</div></body></html>`,
+			confidence: constants.InterstitialConfidenceLow,
+			rules:      []string{constants.InterstitialRuleOverlayMarkup},
+		},
+		{
+			name:       "Modal markup with scroll lock",
+			html:       `<html style="overflow-y: hidden"><body><div id="promo-modal">Sale</div></body></html>`,
+			suspected:  true,
+			confidence: constants.InterstitialConfidenceMedium,
+			rules:      []string{constants.InterstitialRuleOverlayMarkup, constants.InterstitialRuleScrollLockStyle},
+		},
+		{
+			name:       "Hidden modal",
+			html:       `<html><body><div class="modal" hidden>Later</div><div class="overlay" aria-hidden="true"></div></body></html>`,
+			confidence: constants.InterstitialConfidenceNone,
+		},
+		{
+			name:       "Consent overlay exempt",
+			html:       `<html><body class="consent-open"><div class="gdpr-overlay" style="position:fixed;inset:0">Cookies</div></body></html>`,
+			confidence: constants.InterstitialConfidenceNone,
+		},
+		{
+			name:       "Words are matched whole",
+			html:       `<html><body><div class="modalities popups-list">Text</div><div class="no-scrollbar">Text</div></body></html>`,
+			confidence: constants.InterstitialConfidenceNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			result := detectInterstitial(doc)
+			assert.Equal(t, tt.suspected, result.Suspected)
+			assert.Equal(t, tt.confidence, result.Confidence)
+			var rules []string
+			for _, evidence := range result.Evidence {
+				rules = append(rules, evidence.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestDetectInterstitial_EvidenceCapped(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>` + strings.Repeat(`<div class="popup">Offer</div>`, 20) + `</body></html>`))
+	require.NoError(t, err)
+
+	result := detectInterstitial(doc)
+	assert.Len(t, result.Evidence, constants.InterstitialMaxEvidence)
+	assert.Equal(t, constants.InterstitialConfidenceLow, result.Confidence, "a rule counts once however many elements match")
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// acceptLanguage is the caller's options.accept_language, sent to the analyzed host only
+type acceptLanguage struct {
+	host  string
+	value string
+}
+
+type acceptLanguageContextKey struct{}
+
+// withAcceptLanguage attaches the Accept-Language value for the target's host to the context;
+// an empty value attaches nothing
+func withAcceptLanguage(ctx context.Context, target *url.URL, value string) context.Context {
+	if value == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, acceptLanguageContextKey{}, &acceptLanguage{host: target.Host, value: normalizeAcceptLanguage(value)})
+}
+
+// acceptLanguageFrom returns the Accept-Language of the analysis running under ctx, if any
+func acceptLanguageFrom(ctx context.Context) *acceptLanguage {
+	lang, _ := ctx.Value(acceptLanguageContextKey{}).(*acceptLanguage)
+	return lang
+}
+
+// apply sets the Accept-Language header on a request to the analyzed host. Redirects of the
+// request keep it, so a page that moves to another host is still fetched in the language asked for.
+func (l *acceptLanguage) apply(req *http.Request) {
+	if l == nil || normalizedHost(req.URL) != l.host {
+		return
+	}
+	req.Header.Set(constants.HeaderAcceptLanguage, l.value)
+}
+
+// normalizeAcceptLanguage lowercases a validated Accept-Language value and removes its
+// whitespace, so equivalent values share a cache entry
+func normalizeAcceptLanguage(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), ""))
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newLanguageServer negotiates the page language: German for Accept-Language de, English otherwise
+func newLanguageServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set(constants.HeaderVary, "Accept-Encoding, Accept-Language")
+		if strings.HasPrefix(r.Header.Get(constants.HeaderAcceptLanguage), "de") {
+			w.Header().Set(constants.HeaderContentLanguage, "de")
+			w.Write([]byte(`<html><head><title>Willkommen</title></head><body></body></html>`))
+			return
+		}
+		w.Header().Set(constants.HeaderContentLanguage, "en")
+		w.Write([]byte(`<html><head><title>Welcome</title></head><body></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_AcceptLanguage(t *testing.T) {
+	server := newLanguageServer(t)
+	analyzer := newTestAnalyzer(t, nil)
+	cache := analyzer.cache.(*MockCache)
+
+	german, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{SkipLinkCheck: true, AcceptLanguage: "de-DE, de;q=0.9"})
+	require.NoError(t, err)
+	assert.Equal(t, "Willkommen", german.Title)
+	require.NotNil(t, german.Fetch)
+	assert.Equal(t, "de", german.Fetch.ContentLanguage)
+	assert.True(t, german.Fetch.VariesByLanguage)
+
+	english, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome", english.Title)
+	assert.Equal(t, "en", english.Fetch.ContentLanguage)
+
+	// The languages are cached apart
+	cache.AssertCalled(t, "Set", mock.Anything, server.URL+"|"+constants.CacheVariantSkipLinkCheck+","+constants.CacheVariantAcceptLanguage+"=de-de,de;q=0.9", german, mock.Anything)
+	cache.AssertCalled(t, "Set", mock.Anything, server.URL+"|"+constants.CacheVariantSkipLinkCheck, english, mock.Anything)
+}
+
+func TestAnalyzer_AnalyzeLinks_AcceptLanguageSameHostOnly(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]string{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.Host] = r.Header.Get(constants.HeaderAcceptLanguage)
+	})
+	site := httptest.NewServer(handler)
+	defer site.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+
+	analyzer := newTestAnalyzer(t, nil)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="/about">About</a><a href="` + other.URL + `/">Other</a>`))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse(site.URL + "/")
+	otherURL, _ := url.Parse(other.URL)
+
+	ctx := withAcceptLanguage(context.Background(), baseURL, "fr")
+	analyzer.analyzeLinks(ctx, doc, baseURL, domLimits{})
+
+	assert.Equal(t, "fr", received[baseURL.Host])
+	assert.Empty(t, received[otherURL.Host])
+}
+
+func TestCacheKey_AcceptLanguageNormalized(t *testing.T) {
+	assert.Equal(t,
+		cacheKey("http://example.com", models.AnalyzeOptions{AcceptLanguage: "en-US, en;q=0.8"}),
+		cacheKey("http://example.com", models.AnalyzeOptions{AcceptLanguage: "en-us,en;q=0.8"}),
+	)
+	assert.NotEqual(t,
+		cacheKey("http://example.com", models.AnalyzeOptions{AcceptLanguage: "en"}),
+		cacheKey("http://example.com", models.AnalyzeOptions{AcceptLanguage: "de"}),
+	)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// detectLegacyIE reports the conditional comments, X-UA-Compatible meta tag and IE-only
+// polyfills of a page. Conditional comments are comments to the parser, so they and the scripts
+// they load are found in the token stream of the HTML, whose size the fetch already bounds.
+func (a *Analyzer) detectLegacyIE(htmlContent string, doc *goquery.Document) models.LegacyIE {
+	legacy := models.LegacyIE{}
+
+	if meta := doc.Find("meta[http-equiv='X-UA-Compatible' i]").First(); meta.Length() > 0 {
+		legacy.XUACompatible = true
+		legacy.XUACompatibleValue = strings.TrimSpace(meta.AttrOr("content", ""))
+	}
+
+	scan := &legacyIEScan{conditions: make(map[string]bool), polyfills: make(map[string]bool)}
+	scan.tokens(htmlContent)
+
+	legacy.ConditionalComments = scan.conditionals
+	for condition := range scan.conditions {
+		legacy.Conditions = append(legacy.Conditions, condition)
+	}
+	slices.Sort(legacy.Conditions)
+	if len(legacy.Conditions) > constants.LegacyIEMaxConditions {
+		legacy.Conditions = legacy.Conditions[:constants.LegacyIEMaxConditions]
+	}
+	for name := range scan.polyfills {
+		legacy.Polyfills = append(legacy.Polyfills, name)
+	}
+	slices.Sort(legacy.Polyfills)
+	return legacy
+}
+
+// legacyIEScan accumulates the conditional comments and polyfill scripts of a token stream
+type legacyIEScan struct {
+	conditionals int
+	conditions   map[string]bool
+	polyfills    map[string]bool
+}
+
+// tokens scans HTML for conditional comments and the polyfills its scripts load, those inside
+// conditional comments included
+func (s *legacyIEScan) tokens(htmlContent string) {
+	tokenizer := html.NewTokenizer(strings.NewReader(htmlContent))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "script" {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key == "src" {
+					if name := legacyIEPolyfill(attr.Val); name != "" {
+						s.polyfills[name] = true
+					}
+				}
+			}
+		case html.CommentToken:
+			comment := string(tokenizer.Text())
+			condition, body, ok := conditionalComment(comment)
+			if !ok {
+				continue
+			}
+			s.conditionals++
+			s.conditions[condition] = true
+			// Downlevel-hidden comments carry their markup inside the comment
+			s.tokens(body)
+		}
+	}
+}
+
+// conditionalComment parses the text of a comment token. Downlevel-hidden comments,
+// <!--[if IE]>markup<![endif]-->, carry their markup inside; downlevel-revealed ones,
+// <![if !IE]> and <![endif]>, are bogus comments around markup every browser sees.
+func conditionalComment(comment string) (condition, body string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(comment), "[if ")
+	if !found {
+		return "", "", false
+	}
+	condition, body, found = strings.Cut(rest, "]")
+	if !found {
+		return "", "", false
+	}
+	body = strings.TrimPrefix(body, ">")
+	body, _, _ = strings.Cut(body, "<![endif]")
+	return strings.Join(strings.Fields(condition), " "), body, true
+}
+
+// legacyIEPolyfill returns the name of the IE-only polyfill a script URL loads, or ""
+func legacyIEPolyfill(src string) string {
+	src, _, _ = strings.Cut(src, "?")
+	src, _, _ = strings.Cut(src, "#")
+	name := strings.ToLower(path.Base(strings.TrimSpace(src)))
+	name = strings.TrimSuffix(name, ".js")
+	name = strings.TrimSuffix(name, ".min")
+	if slices.Contains(constants.LegacyIEPolyfills, name) {
+		return name
+	}
+	return ""
+}
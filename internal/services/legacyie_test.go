@@ -0,0 +1,75 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_DetectLegacyIE(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name     string
+		html     string
+		expected models.LegacyIE
+	}{
+		{
+			name: "IE-era page",
+			html: loadFixture(t, "legacy_ie.html"),
+			expected: models.LegacyIE{
+				ConditionalComments: 6,
+				Conditions:          []string{"!IE", "IE 7", "lt IE 7", "lt IE 9", "lte IE 8"},
+				XUACompatible:       true,
+				XUACompatibleValue:  "IE=edge,chrome=1",
+				Polyfills:           []string{"es5-shim", "html5shiv", "ie9", "respond", "selectivizr"},
+			},
+		},
+		{
+			name:     "Modern page",
+			html:     `<!DOCTYPE html><html><head><!-- build 42 --><script src="/app.js"></script><script src="/respond-form.js"></script></head></html>`,
+			expected: models.LegacyIE{},
+		},
+		{
+			name:     "X-UA-Compatible without content",
+			html:     `<html><head><META HTTP-EQUIV="x-ua-compatible"></head></html>`,
+			expected: models.LegacyIE{XUACompatible: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := analyzer.parseHTML(tt.html)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, analyzer.detectLegacyIE(tt.html, doc))
+		})
+	}
+}
+
+func TestConditionalComment(t *testing.T) {
+	tests := []struct {
+		comment   string
+		condition string
+		body      string
+		ok        bool
+	}{
+		{comment: `[if lt IE 9]><script src="a.js"></script><![endif]`, condition: "lt IE 9", body: `<script src="a.js"></script>`, ok: true},
+		{comment: `[if !IE]`, condition: "!IE", ok: true},
+		{comment: ` [if  (gt IE 5)&(lt IE 7) ]> x <![endif]`, condition: "(gt IE 5)&(lt IE 7)", body: " x ", ok: true},
+		{comment: `[endif]`},
+		{comment: ` Google Analytics `},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			condition, body, ok := conditionalComment(tt.comment)
+			assert.Equal(t, tt.condition, condition)
+			assert.Equal(t, tt.body, body)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// internalLinkTally accumulates the internal link details of one document
+type internalLinkTally struct {
+	doc    *goquery.Document
+	host   string // Host of the analyzed page; internal links can point at other hosts of the site
+	page   string // Path and query of the analyzed page
+	paths  map[string]struct{}
+	ids    map[string]struct{} // Fragment targets, collected on the first in-page link
+	broken map[string]int
+	detail models.InternalLinkDetail
+}
+
+// newInternalLinkTally starts a tally for the links of doc, which was served from base
+func newInternalLinkTally(doc *goquery.Document, base *url.URL) *internalLinkTally {
+	return &internalLinkTally{
+		doc:    doc,
+		host:   strings.ToLower(normalizedHost(base)),
+		page:   pageKey(base),
+		paths:  make(map[string]struct{}),
+		broken: make(map[string]int),
+	}
+}
+
+// add records an internal link as written in href and resolved against the base URL
+func (t *internalLinkTally) add(href string, link *url.URL) {
+	path := link.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	host := strings.ToLower(normalizedHost(link))
+	if host == t.host {
+		t.paths[path] = struct{}{}
+	} else {
+		t.paths[host+path] = struct{}{}
+	}
+	t.detail.MaxPathDepth = max(t.detail.MaxPathDepth, pathDepth(path))
+
+	if host != t.host || pageKey(link) != t.page {
+		return
+	}
+	// A "#" in href makes it in-page navigation even when the fragment is empty
+	if !strings.Contains(href, "#") {
+		t.detail.SelfLinks++
+		return
+	}
+
+	t.detail.FragmentLinks++
+	if !t.hasTarget(link.Fragment) {
+		t.detail.BrokenFragmentLinks++
+		t.broken[link.Fragment]++
+	}
+}
+
+// result returns the collected details
+func (t *internalLinkTally) result() models.InternalLinkDetail {
+	t.detail.UniquePaths = len(t.paths)
+	t.detail.BrokenFragments = topCounts(t.broken, constants.LinkMaxBrokenFragments)
+	return t.detail
+}
+
+// hasTarget reports whether navigating to the fragment finds an element. As in browsers, the empty
+// fragment and "top" scroll to the top of the page, and named anchors are targets too.
+func (t *internalLinkTally) hasTarget(fragment string) bool {
+	if fragment == "" || strings.EqualFold(fragment, "top") {
+		return true
+	}
+	if t.ids == nil {
+		t.ids = make(map[string]struct{})
+		t.doc.Find("[id], a[name]").Each(func(_ int, s *goquery.Selection) {
+			for _, attr := range s.Nodes[0].Attr {
+				if attr.Namespace == "" && (attr.Key == "id" || (attr.Key == "name" && s.Nodes[0].Data == "a")) {
+					t.ids[attr.Val] = struct{}{}
+				}
+			}
+		})
+	}
+	_, found := t.ids[fragment]
+	return found
+}
+
+// pageKey identifies the page a URL points at, ignoring the fragment
+func pageKey(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		return path + "?" + u.RawQuery
+	}
+	return path
+}
+
+// pathDepth counts the non-empty segments of a path; "/" has depth 0
+func pathDepth(path string) int {
+	depth := 0
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			depth++
+		}
+	}
+	return depth
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_InternalLinkDetail(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected models.InternalLinkDetail
+	}{
+		{
+			name:     "Unique paths and depth",
+			body:     `<a href="/">Home</a><a href="/docs/guide/install">Install</a><a href="/docs/guide/install?lang=de">Install (de)</a><a href="/docs/">Docs</a><a href="https://other.com/a/b/c/d">Other</a>`,
+			expected: models.InternalLinkDetail{UniquePaths: 3, MaxPathDepth: 3},
+		},
+		{
+			name:     "Self-links",
+			body:     `<a href="/products">Products</a><a href="">Reload</a><a href="https://example.com/products">Absolute</a><a href="/products?page=2">Next page</a>`,
+			expected: models.InternalLinkDetail{UniquePaths: 1, MaxPathDepth: 1, SelfLinks: 3},
+		},
+		{
+			name: "Fragments to existing ids",
+			body: `<h2 id="pricing">Pricing</h2><a name="legacy"></a>` +
+				`<a href="#pricing">Pricing</a><a href="/products#legacy">Legacy</a><a href="#">Top</a><a href="#top">Top</a>`,
+			expected: models.InternalLinkDetail{UniquePaths: 1, MaxPathDepth: 1, FragmentLinks: 4},
+		},
+		{
+			name: "Fragments to missing ids",
+			body: `<div id="faq"></div><span name="contact"></span>` +
+				`<a href="#FAQ">FAQ</a><a href="#contact">Contact</a><a href="#contact">Contact again</a><a href="#caf%C3%A9">Café</a>`,
+			expected: models.InternalLinkDetail{
+				UniquePaths:         1,
+				MaxPathDepth:        1,
+				FragmentLinks:       4,
+				BrokenFragmentLinks: 4,
+				BrokenFragments:     map[string]int{"FAQ": 1, "contact": 2, "café": 1},
+			},
+		},
+		{
+			name:     "Fragments on other pages are not checked",
+			body:     `<a href="/about#missing">About</a><a href="https://other.com/#missing">Other</a>`,
+			expected: models.InternalLinkDetail{UniquePaths: 1, MaxPathDepth: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>` + tt.body + `</body></html>`))
+			require.NoError(t, err)
+			baseURL, _ := url.Parse("https://example.com/products")
+
+			analysis, _, _ := analyzer.classifyLinks(context.Background(), doc, baseURL, domLimits{})
+			assert.Equal(t, tt.expected, analysis.InternalDetail)
+		})
+	}
+}
+
+func TestPathDepth(t *testing.T) {
+	assert.Equal(t, 0, pathDepth("/"))
+	assert.Equal(t, 1, pathDepth("/docs/"))
+	assert.Equal(t, 3, pathDepth("/docs//guide/install"))
+}
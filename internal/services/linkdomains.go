@@ -0,0 +1,58 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// externalDomain returns the key an external link is counted under: its host, or the
+// registered domain of the host, depending on the configured mode. Links without a
+// host, such as mailto: links, return an empty string.
+func (a *Analyzer) externalDomain(ctx context.Context, link *url.URL) string {
+	host := strings.TrimSuffix(strings.ToLower(link.Hostname()), ".")
+	if host == "" || a.optionsFor(ctx).ExternalDomains.Mode == constants.ExternalDomainModeHost || net.ParseIP(host) != nil {
+		return host
+	}
+
+	// Single-label hosts and bare public suffixes have no registered domain
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// topCounts keeps the n keys with the highest counts, breaking ties alphabetically
+func topCounts(counts map[string]int, n int) map[string]int {
+	if len(counts) == 0 {
+		return nil
+	}
+	if n <= 0 || len(counts) <= n {
+		return counts
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(x, y string) int {
+		if c := cmp.Compare(counts[y], counts[x]); c != 0 {
+			return c
+		}
+		return cmp.Compare(x, y)
+	})
+
+	top := make(map[string]int, n)
+	for _, key := range keys[:n] {
+		top[key] = counts[key]
+	}
+	return top
+}
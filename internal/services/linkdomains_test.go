@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+const externalDomainsPage = `<html><body>
+	<a href="/about">About</a>
+	<a href="https://example.com/contact">Contact</a>
+	<a href="https://twitter.com/example">Twitter</a>
+	<a href="https://mobile.twitter.com/example">Twitter mobile</a>
+	<a href="https://Twitter.com./other">Twitter FQDN</a>
+	<a href="https://github.com/example">GitHub</a>
+	<a href="https://gist.github.com/example/1">Gist</a>
+	<a href="https://api.github.com:8443/meta">GitHub API</a>
+	<a href="https://blog.example.co.uk/post">UK blog</a>
+	<a href="https://shop.example.co.uk/">UK shop</a>
+	<a href="https://example.github.io/">GitHub Pages</a>
+	<a href="http://192.0.2.10/status">IP</a>
+	<a href="mailto:team@example.com">Mail</a>
+</body></html>`
+
+func TestAnalyzer_ExternalDomains(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		topN     int
+		expected map[string]int
+	}{
+		{
+			name: "Registered domain groups subdomains",
+			mode: constants.ExternalDomainModeRegistered,
+			expected: map[string]int{
+				"twitter.com":       3,
+				"github.com":        3,
+				"example.co.uk":     2,
+				"example.github.io": 1, // github.io is a public suffix
+				"192.0.2.10":        1,
+			},
+		},
+		{
+			name: "Raw host keeps subdomains apart",
+			mode: constants.ExternalDomainModeHost,
+			expected: map[string]int{
+				"twitter.com":        2,
+				"mobile.twitter.com": 1,
+				"github.com":         1,
+				"gist.github.com":    1,
+				"api.github.com":     1,
+				"blog.example.co.uk": 1,
+				"shop.example.co.uk": 1,
+				"example.github.io":  1,
+				"192.0.2.10":         1,
+			},
+		},
+		{
+			name: "Top domains only, ties broken alphabetically",
+			mode: constants.ExternalDomainModeRegistered,
+			topN: 3,
+			expected: map[string]int{
+				"github.com":    3,
+				"twitter.com":   3,
+				"example.co.uk": 2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.Analyzer.ExternalDomains.Mode = tt.mode
+			cfg.Analyzer.ExternalDomains.TopN = tt.topN
+			analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(externalDomainsPage))
+			require.NoError(t, err)
+			baseURL, _ := url.Parse("https://example.com/")
+
+			analysis, _, _ := analyzer.classifyLinks(context.Background(), doc, baseURL, domLimits{})
+			assert.Equal(t, 2, analysis.Internal)
+			assert.Equal(t, 11, analysis.External)
+			assert.Equal(t, tt.expected, analysis.ExternalDomains)
+		})
+	}
+}
+
+func TestAnalyzer_ExternalDomainsDefaults(t *testing.T) {
+	cfg := createTestConfig()
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	assert.Equal(t, constants.DefaultExternalDomainMode, analyzer.options.ExternalDomains.Mode)
+	assert.Equal(t, constants.DefaultExternalDomainsTopN, analyzer.options.ExternalDomains.TopN)
+}
+
+func TestTopCounts(t *testing.T) {
+	assert.Nil(t, topCounts(map[string]int{}, 5))
+	assert.Equal(t, map[string]int{"a.com": 1}, topCounts(map[string]int{"a.com": 1}, 5))
+	assert.Equal(t, map[string]int{"b.com": 5, "a.com": 2},
+		topCounts(map[string]int{"a.com": 2, "b.com": 5, "c.com": 2, "d.com": 1}, 2))
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// linkFailureCategory classifies why a link check failed, from the status of the
+// response that judged the link or the error that ended the check
+func linkFailureCategory(status int, err error) string {
+	switch {
+	case errors.Is(err, errRedirectLoop):
+		return constants.LinkFailureRedirectLoop
+	case err != nil:
+		if isTimeout(err) {
+			return constants.LinkFailureTimeout
+		}
+		return constants.LinkFailureConnection
+	case status == http.StatusNotFound:
+		return constants.LinkFailureNotFound
+	case status == http.StatusGone:
+		return constants.LinkFailureGone
+	case status >= http.StatusInternalServerError:
+		return constants.LinkFailureServerError
+	default:
+		return constants.LinkFailureClientError
+	}
+}
+
+// isTemporaryLinkFailure reports whether a failure category is likely to pass on its own
+func isTemporaryLinkFailure(category string) bool {
+	switch category {
+	case constants.LinkFailureServerError, constants.LinkFailureTimeout, constants.LinkFailureConnection, constants.LinkFailureCircuitOpen:
+		return true
+	}
+	return false
+}
+
+// addLinkFailure counts a failed link in failures, allocating them on the first failure
+func addLinkFailure(failures **models.LinkFailures, category string) {
+	if *failures == nil {
+		*failures = &models.LinkFailures{ByCategory: make(map[string]int)}
+	}
+	(*failures).ByCategory[category]++
+	if isTemporaryLinkFailure(category) {
+		(*failures).Temporary++
+	} else {
+		(*failures).Permanent++
+	}
+}
+
+// cacheTTL returns the TTL to cache a result for: the shortened cache.temporary_failures.ttl
+// when temporary failures make up more than cache.temporary_failures.threshold of the failed
+// links, and 0, the configured TTL, otherwise
+func (a *Analyzer) cacheTTL(ctx context.Context, result *models.AnalyzeResponse) time.Duration {
+	options := a.optionsFor(ctx)
+	failures := result.Links.Failures
+	shortened := options.Cache.TemporaryFailures.TTL
+	if failures == nil || failures.Temporary == 0 || shortened <= 0 || shortened >= options.Cache.TTL {
+		return 0
+	}
+	share := float64(failures.Temporary) / float64(failures.Temporary+failures.Permanent)
+	if share <= options.Cache.TemporaryFailures.Threshold {
+		return 0
+	}
+	a.log(ctx).Info("Caching result briefly: its link failures are mostly temporary",
+		zap.String("url", result.URL),
+		zap.Int("temporary_failures", failures.Temporary),
+		zap.Int("permanent_failures", failures.Permanent),
+		zap.Duration("ttl", shortened),
+	)
+	return shortened
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestLinkFailureCategory(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		err       error
+		expected  string
+		temporary bool
+	}{
+		{name: "Not found", status: http.StatusNotFound, expected: constants.LinkFailureNotFound},
+		{name: "Gone", status: http.StatusGone, expected: constants.LinkFailureGone},
+		{name: "Forbidden", status: http.StatusForbidden, expected: constants.LinkFailureClientError},
+		{name: "Redirect loop", err: fmt.Errorf("head: %w", errRedirectLoop), expected: constants.LinkFailureRedirectLoop},
+		{name: "Server error", status: http.StatusBadGateway, expected: constants.LinkFailureServerError, temporary: true},
+		{name: "Timeout", err: context.DeadlineExceeded, expected: constants.LinkFailureTimeout, temporary: true},
+		{name: "Network timeout", err: &net.DNSError{IsTimeout: true}, expected: constants.LinkFailureTimeout, temporary: true},
+		{name: "Connection refused", err: &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}, expected: constants.LinkFailureConnection, temporary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category := linkFailureCategory(tt.status, tt.err)
+			assert.Equal(t, tt.expected, category)
+			assert.Equal(t, tt.temporary, isTemporaryLinkFailure(category))
+		})
+	}
+	assert.True(t, isTemporaryLinkFailure(constants.LinkFailureCircuitOpen))
+}
+
+// newFlakyHostServer serves a page linking to paths that answer with their status code,
+// /404 with 404 and /503 with 503, plus one link to a closed port
+func newFlakyHostServer(t *testing.T, paths ...string) *httptest.Server {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			var status int
+			fmt.Sscanf(r.URL.Path, "/%d", &status)
+			w.WriteHeader(status)
+			return
+		}
+		var page strings.Builder
+		page.WriteString(`<html><head><title>Links</title></head><body>`)
+		for _, path := range paths {
+			fmt.Fprintf(&page, `<a href="%s%s">Link</a>`, server.URL, path)
+		}
+		fmt.Fprintf(&page, `<a href="%s/">Down</a></body></html>`, closedURL)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page.String()))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_TemporaryLinkFailuresShortenCacheTTL(t *testing.T) {
+	tests := []struct {
+		name             string
+		paths            []string
+		expectedFailures *models.LinkFailures
+		expectedTTL      time.Duration // Passed to Cache.Set
+		expectedCacheTTL time.Duration // Reported in the response
+	}{
+		{
+			name:  "Flaky host",
+			paths: []string{"/503", "/404"},
+			expectedFailures: &models.LinkFailures{Permanent: 1, Temporary: 2, ByCategory: map[string]int{
+				constants.LinkFailureServerError: 1,
+				constants.LinkFailureConnection:  1,
+				constants.LinkFailureNotFound:    1,
+			}},
+			expectedTTL:      5 * time.Minute,
+			expectedCacheTTL: 5 * time.Minute,
+		},
+		{
+			name:  "Mostly broken links",
+			paths: []string{"/404", "/410", "/200"},
+			expectedFailures: &models.LinkFailures{Permanent: 2, Temporary: 1, ByCategory: map[string]int{
+				constants.LinkFailureNotFound:   1,
+				constants.LinkFailureGone:       1,
+				constants.LinkFailureConnection: 1,
+			}},
+			expectedCacheTTL: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFlakyHostServer(t, tt.paths...)
+			cache := &MockCache{}
+			cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), nil)
+			cache.On("Set", mock.Anything, mock.Anything, mock.Anything, tt.expectedTTL).Return(nil)
+			cfg := createTestConfig()
+			cfg.Cache.Enabled = true
+			cfg.Cache.TTL = time.Hour
+			cfg.Cache.TemporaryFailures.Threshold = constants.DefaultTemporaryFailureThreshold
+			cfg.Cache.TemporaryFailures.TTL = 5 * time.Minute
+			analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedFailures, result.Links.Failures)
+			assert.Equal(t, tt.expectedCacheTTL, result.CacheTTL)
+			cache.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// errHostCapReached stops a link check redirected to a host beyond analyzer.max_external_hosts
+var errHostCapReached = errors.New("external host cap reached")
+
+// hostCap admits the links of the first maxHosts distinct hosts, in page order, across every
+// list it is given. Links to a host already admitted are always kept, and so are links to the
+// analyzed page's own host, which never count against the cap. A maxHosts of 0 keeps every link.
+// Link checks admit the hosts their redirects reach concurrently.
+type hostCap struct {
+	maxHosts int
+	pageHost string
+
+	mu    sync.Mutex
+	hosts map[string]bool
+}
+
+// newHostCap caps the hosts other than that of pageURL
+func newHostCap(maxHosts int, pageURL *url.URL) *hostCap {
+	return &hostCap{maxHosts: maxHosts, pageHost: pageHost(pageURL), hosts: make(map[string]bool)}
+}
+
+// admit splits links into the links to admitted hosts, admitting new hosts while there is room,
+// and the links to further hosts, which are not checked
+func (c *hostCap) admit(links []string) (checked, skipped []string) {
+	if c.maxHosts <= 0 {
+		return links, nil
+	}
+	for _, link := range links {
+		if !c.allow(link) {
+			skipped = append(skipped, link)
+			continue
+		}
+		checked = append(checked, link)
+	}
+	return checked, skipped
+}
+
+// allow reports whether link may be checked, admitting its host if there is room
+func (c *hostCap) allow(link string) bool {
+	if c.maxHosts <= 0 || onPageHost(link, c.pageHost) {
+		return true
+	}
+	host := linkHost(link)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.hosts[host] {
+		if len(c.hosts) >= c.maxHosts {
+			return false
+		}
+		c.hosts[host] = true
+	}
+	return true
+}
+
+// reach admits the host a link check was redirected to, recording it in the analysis stats
+// unless it is the page's own, and returns errHostCapReached when there is no room for it
+func (c *hostCap) reach(ctx context.Context, target *url.URL) error {
+	link := target.String()
+	if !c.allow(link) {
+		return errHostCapReached
+	}
+	if !onPageHost(link, c.pageHost) {
+		statsFrom(ctx).externalHost(target.Host)
+	}
+	return nil
+}
+
+type hostCapContextKey struct{}
+
+// withHostCap attaches the host cap of an analysis's link checks to the context
+func withHostCap(ctx context.Context, hosts *hostCap) context.Context {
+	return context.WithValue(ctx, hostCapContextKey{}, hosts)
+}
+
+// hostCapFrom returns the host cap of the link checks running under ctx, if any
+func hostCapFrom(ctx context.Context) *hostCap {
+	hosts, _ := ctx.Value(hostCapContextKey{}).(*hostCap)
+	return hosts
+}
+
+// pageHost returns the host of the analyzed page as onPageHost compares it
+func pageHost(pageURL *url.URL) string {
+	return strings.ToLower(normalizedHost(pageURL))
+}
+
+// onPageHost reports whether link points at host, as returned by pageHost. Whatever
+// analyzer.internal_scope counts as internal, only such links are exempt from the per-host
+// caps: other hosts of the site may fail or be slow like any external host.
+func onPageHost(link, host string) bool {
+	parsed, err := url.Parse(link)
+	return err == nil && strings.ToLower(normalizedHost(parsed)) == host
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestHostCap(t *testing.T) {
+	pageURL, err := url.Parse("https://site.example/")
+	require.NoError(t, err)
+	links := []string{
+		"https://a.example/1",
+		"https://b.example/",
+		"https://c.example/",
+		"https://a.example/2",
+		"https://a.example:8443/",
+	}
+
+	checked, skipped := newHostCap(2, pageURL).admit(links)
+	assert.Equal(t, []string{"https://a.example/1", "https://b.example/", "https://a.example/2"}, checked)
+	assert.Equal(t, []string{"https://c.example/", "https://a.example:8443/"}, skipped, "another port is another host")
+
+	checked, skipped = newHostCap(0, pageURL).admit(links)
+	assert.Equal(t, links, checked)
+	assert.Empty(t, skipped)
+
+	// The cap spans every list, and the page's own host never counts against it
+	hosts := newHostCap(1, pageURL)
+	checked, skipped = hosts.admit([]string{"https://a.example/"})
+	assert.Equal(t, []string{"https://a.example/"}, checked)
+	assert.Empty(t, skipped)
+	checked, skipped = hosts.admit([]string{"https://SITE.example:443/about", "https://blog.site.example/", "https://site.example/contact"})
+	assert.Equal(t, []string{"https://SITE.example:443/about", "https://site.example/contact"}, checked)
+	assert.Equal(t, []string{"https://blog.site.example/"}, skipped)
+}
+
+func TestAnalyzer_AnalyzeLinks_MaxExternalHosts(t *testing.T) {
+	var mu sync.Mutex
+	contacted := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "site.example" {
+			var page strings.Builder
+			page.WriteString(`<html><head><title>Links</title></head><body>`)
+			for i := 1; i <= 30; i++ {
+				fmt.Fprintf(&page, `<a href="http://host%02d.example/">Host %d</a>`, i, i)
+			}
+			// A second link to a host within the cap is still checked
+			page.WriteString(`<a href="http://host01.example/other">Again</a></body></html>`)
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page.String()))
+			return
+		}
+		mu.Lock()
+		contacted[r.Host]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, func(cfg *config.Config) {
+		cfg.Analyzer.MaxExternalHosts = 20
+	})
+	dialServer(analyzer, server)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://site.example/",
+		models.AnalyzeOptions{IncludeStats: true, IncludeLinkDetails: true})
+	require.NoError(t, err)
+
+	assert.Len(t, contacted, 20)
+	for i := 1; i <= 20; i++ {
+		assert.Contains(t, contacted, fmt.Sprintf("host%02d.example", i))
+	}
+	assert.Equal(t, 2, contacted["host01.example"])
+	assert.Equal(t, 31, result.Links.External)
+	assert.Equal(t, map[string]int{constants.LinkSkipReasonHostCap: 10}, result.Links.Skipped)
+	assert.Zero(t, result.Links.Inaccessible)
+	require.NotNil(t, result.Stats)
+	assert.Equal(t, 20, result.Stats.ExternalHosts)
+
+	require.NotNil(t, result.Links.Details)
+	skipped := 0
+	for _, link := range result.Links.Details.External {
+		if link.Skipped == constants.LinkSkipReasonHostCap {
+			skipped++
+		}
+	}
+	assert.Equal(t, 10, skipped)
+}
+
+func TestAnalyzer_AnalyzeLinks_OtherSiteHostsCapped(t *testing.T) {
+	var mu sync.Mutex
+	contacted := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "www.site.example" && r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Links</title></head><body>
+				<a href="/about">About</a>
+				<a href="http://blog.site.example/1">Blog 1</a>
+				<a href="http://blog.site.example/2">Blog 2</a>
+				<a href="http://blog.site.example/3">Blog 3</a>
+				<a href="http://shop.site.example/">Shop</a>
+			</body></html>`))
+			return
+		}
+		mu.Lock()
+		contacted[r.Host]++
+		mu.Unlock()
+		if r.Host == "blog.site.example" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, func(cfg *config.Config) {
+		cfg.Analyzer.InternalScope = constants.InternalScopeRegisteredDomain
+		cfg.Analyzer.MaxExternalHosts = 1
+		cfg.Analyzer.MaxWorkers = 1
+		cfg.Analyzer.LinkCircuit.Enabled = true
+		cfg.Analyzer.LinkCircuit.FailureThreshold = 1
+	})
+	dialServer(analyzer, server)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://www.site.example/", models.AnalyzeOptions{IncludeStats: true})
+	require.NoError(t, err)
+
+	// Other hosts of the site are internal, but capped, counted and short-circuited like external ones
+	assert.Equal(t, 5, result.Links.Internal)
+	assert.Equal(t, map[string]int{"www.site.example": 1, "blog.site.example": 1}, contacted)
+	assert.Equal(t, map[string]int{constants.LinkSkipReasonHostCap: 1, constants.LinkSkipReasonCircuitOpen: 2}, result.Links.Skipped)
+	require.NotNil(t, result.Stats)
+	assert.Equal(t, 1, result.Stats.ExternalHosts)
+}
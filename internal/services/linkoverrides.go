@@ -0,0 +1,67 @@
+package services
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/config"
+)
+
+// linkCheckOverrides are the analyzer.link_check_overrides entries, most specific first:
+// exact hosts before wildcards, and longer wildcards before shorter ones
+type linkCheckOverrides []config.LinkCheckOverride
+
+// newLinkCheckOverrides normalizes and orders the configured overrides
+func newLinkCheckOverrides(cfg []config.LinkCheckOverride) linkCheckOverrides {
+	var overrides linkCheckOverrides
+	for _, override := range cfg {
+		override.Pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(override.Pattern)), ".")
+		override.Method = strings.ToUpper(strings.TrimSpace(override.Method))
+		if override.Pattern != "" {
+			overrides = append(overrides, override)
+		}
+	}
+	sort.SliceStable(overrides, func(i, j int) bool {
+		iWildcard := strings.HasPrefix(overrides[i].Pattern, "*.")
+		jWildcard := strings.HasPrefix(overrides[j].Pattern, "*.")
+		if iWildcard != jWildcard {
+			return !iWildcard
+		}
+		return len(overrides[i].Pattern) > len(overrides[j].Pattern)
+	})
+	return overrides
+}
+
+// lookup returns the most specific override for a link's host, or nil when none matches
+func (o linkCheckOverrides) lookup(link string) *config.LinkCheckOverride {
+	if len(o) == 0 {
+		return nil
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	for i := range o {
+		if matchHostPattern(host, o[i].Pattern) {
+			return &o[i]
+		}
+	}
+	return nil
+}
+
+// withoutSkipped splits the links into those left to check and those an override skips
+func (o linkCheckOverrides) withoutSkipped(links []string) (checked, skipped []string) {
+	if len(o) == 0 {
+		return links, nil
+	}
+	for _, link := range links {
+		if override := o.lookup(link); override != nil && override.Skip {
+			skipped = append(skipped, link)
+			continue
+		}
+		checked = append(checked, link)
+	}
+	return checked, skipped
+}
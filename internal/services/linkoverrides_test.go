@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+func TestLinkCheckOverrides_Lookup(t *testing.T) {
+	overrides := newLinkCheckOverrides([]config.LinkCheckOverride{
+		{Pattern: "*.example.com", Method: "get"},
+		{Pattern: "*.static.example.com", Skip: true},
+		{Pattern: " CDN.Example.com ", Timeout: time.Second},
+		{Pattern: ""},
+	})
+	require.Len(t, overrides, 3, "empty patterns are dropped")
+
+	tests := []struct {
+		link     string
+		expected string // Pattern of the override applied
+	}{
+		{link: "https://cdn.example.com/app.js", expected: "cdn.example.com"},
+		{link: "https://CDN.EXAMPLE.COM./app.js", expected: "cdn.example.com"},
+		{link: "https://img.static.example.com/logo.png", expected: "*.static.example.com"},
+		{link: "https://static.example.com/", expected: "*.example.com"},
+		{link: "https://www.example.com/", expected: "*.example.com"},
+		{link: "https://example.com/", expected: ""},
+		{link: "https://example.org/", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			override := overrides.lookup(tt.link)
+			if tt.expected == "" {
+				assert.Nil(t, override)
+				return
+			}
+			require.NotNil(t, override)
+			assert.Equal(t, tt.expected, override.Pattern)
+		})
+	}
+	assert.Equal(t, http.MethodGet, overrides.lookup("https://www.example.com/").Method)
+}
+
+func TestAnalyzer_AnalyzeLinks_CheckOverrides(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/headless":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	localhost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`
+		<a href="` + localhost + `/headless">Rejects HEAD</a>
+		<a href="` + server.URL + `/slow">Slow</a>
+		<a href="http://pixel.tracker.invalid/">Tracker</a>
+		<a href="http://beacon.tracker.invalid/">Tracker</a>`))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse("https://example.com/")
+
+	t.Run("Without overrides", func(t *testing.T) {
+		cfg := createTestConfig()
+		cfg.Analyzer.LinkTimeout = 5 * time.Second
+		analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+		analysis := analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+
+		assert.Equal(t, 3, analysis.Inaccessible, "HEAD rejected and the tracker host does not resolve")
+		assert.Empty(t, analysis.Skipped)
+	})
+
+	t.Run("With overrides", func(t *testing.T) {
+		cfg := createTestConfig()
+		cfg.Analyzer.LinkTimeout = 5 * time.Second
+		cfg.Analyzer.LinkCheckOverrides = []config.LinkCheckOverride{
+			{Pattern: "localhost", Method: http.MethodGet},
+			{Pattern: "127.0.0.1", Timeout: 50 * time.Millisecond},
+			{Pattern: "*.tracker.invalid", Skip: true},
+		}
+		analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+		analysis := analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+
+		assert.Equal(t, 1, analysis.Inaccessible, "only the slow link times out")
+		require.NotNil(t, analysis.Failures)
+		assert.Equal(t, map[string]int{constants.LinkFailureTimeout: 1}, analysis.Failures.ByCategory)
+		assert.Equal(t, map[string]int{constants.LinkSkipReasonConfig: 2}, analysis.Skipped)
+		assert.Equal(t, 4, analysis.External, "skipped links are still counted")
+	})
+}
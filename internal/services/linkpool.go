@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// linkJob is a link check submitted to the shared pool on behalf of one analysis
+type linkJob struct {
+	ctx     context.Context // The analysis context; cancelling it only affects this analysis' jobs
+	req     linkCheckRequest
+	circuit *hostCircuit
+	results chan<- linkCheckResult // Buffered by the submitter for every job it has in flight
+}
+
+// linkPool is a bounded set of long-lived workers checking links for all analyses.
+// Workers are started on demand, up to size, and stay until the pool is closed.
+type linkPool struct {
+	check func(ctx context.Context, req linkCheckRequest, circuit *hostCircuit) linkCheckResult
+	size  int
+	jobs  chan linkJob // Unbuffered: a completed send means a worker took the job
+	quit  chan struct{}
+
+	mu      sync.Mutex
+	workers int
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func newLinkPool(size int, check func(ctx context.Context, req linkCheckRequest, circuit *hostCircuit) linkCheckResult) *linkPool {
+	return &linkPool{
+		check: check,
+		size:  size,
+		jobs:  make(chan linkJob),
+		quit:  make(chan struct{}),
+	}
+}
+
+// submit hands a job to an idle worker, starting a new one while the pool is below its size,
+// and otherwise waits for a worker. It reports false when ctx is done or the pool is closed
+// before a worker took the job.
+func (p *linkPool) submit(ctx context.Context, job linkJob) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+	}
+
+	if p.startWorker(job) {
+		return true
+	}
+
+	select {
+	case p.jobs <- job:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-p.quit:
+		return false
+	}
+}
+
+// startWorker starts a worker with job as its first job unless the pool is full or closed
+func (p *linkPool) startWorker(job linkJob) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || p.workers >= p.size {
+		return false
+	}
+	p.workers++
+	p.wg.Add(1)
+	go p.work(job)
+	return true
+}
+
+// work runs jobs until the pool is closed
+func (p *linkPool) work(job linkJob) {
+	defer p.wg.Done()
+	for {
+		result := p.check(job.ctx, job.req, job.circuit)
+		result.link = job.req
+		job.results <- result
+		select {
+		case job = <-p.jobs:
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// close stops accepting jobs and waits for the running checks to finish
+func (p *linkPool) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.quit)
+	p.mu.Unlock()
+	p.wg.Wait()
+}
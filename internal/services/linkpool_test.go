@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// linkPage builds a document linking to n paths below base
+func linkPage(t testing.TB, base string, n int) *goquery.Document {
+	var b strings.Builder
+	b.WriteString(`<html><body>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<a href="%s/p%d">p</a>`, base, i)
+	}
+	b.WriteString(`</body></html>`)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(b.String()))
+	require.NoError(t, err)
+	return doc
+}
+
+// goroutinePeak samples the goroutine count until stop is called and returns the highest count seen
+func goroutinePeak() (stop func() int) {
+	var peak atomic.Int64
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if n := int64(runtime.NumGoroutine()); n > peak.Load() {
+				peak.Store(n)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() int {
+		close(done)
+		<-finished
+		return int(peak.Load())
+	}
+}
+
+func TestLinkPool_ReusesBoundedWorkers(t *testing.T) {
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	pool := newLinkPool(3, func(ctx context.Context, req linkCheckRequest, circuit *hostCircuit) linkCheckResult {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		<-release
+		running.Add(-1)
+		return linkCheckResult{accessible: true}
+	})
+	defer pool.close()
+
+	results := make(chan linkCheckResult, 10)
+	submitted := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			submitted <- pool.submit(context.Background(), linkJob{ctx: context.Background(), results: results})
+		}()
+	}
+	// The pool grows to its size and no further
+	require.Eventually(t, func() bool { return running.Load() == 3 }, time.Second, time.Millisecond)
+	close(release)
+	for i := 0; i < 10; i++ {
+		assert.True(t, <-submitted)
+		assert.True(t, (<-results).accessible)
+	}
+
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	pool.mu.Lock()
+	assert.Equal(t, 3, pool.workers)
+	pool.mu.Unlock()
+}
+
+func TestLinkPool_SubmitAfterCancelOrClose(t *testing.T) {
+	block := make(chan struct{})
+	pool := newLinkPool(1, func(ctx context.Context, req linkCheckRequest, circuit *hostCircuit) linkCheckResult {
+		<-block
+		return linkCheckResult{}
+	})
+
+	// Occupy the only worker
+	results := make(chan linkCheckResult, 1)
+	require.True(t, pool.submit(context.Background(), linkJob{ctx: context.Background(), results: results}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, pool.submit(ctx, linkJob{ctx: ctx, results: results}))
+
+	closed := make(chan struct{})
+	go func() {
+		pool.close()
+		close(closed)
+	}()
+	// Close waits for the running check
+	select {
+	case <-closed:
+		t.Fatal("close returned before the running check finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(block)
+	<-closed
+	<-results
+
+	assert.False(t, pool.submit(context.Background(), linkJob{ctx: context.Background(), results: results}))
+}
+
+func TestAnalyzer_AnalyzeLinks_ConcurrentAnalysesShareThePool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	cfg := createTestConfig()
+	cfg.Analyzer.MaxWorkers = 5
+	cfg.Analyzer.LinkPoolSize = 8
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	defer analyzer.Close()
+	baseURL, _ := url.Parse(server.URL)
+	doc := linkPage(t, server.URL, 20)
+
+	// One analysis is cancelled while the others run; its jobs must not affect theirs
+	cancelled, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	const analyses = 12
+	results := make([]int, analyses)
+	var wg sync.WaitGroup
+	for i := 0; i < analyses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			if i == 0 {
+				ctx = cancelled
+			}
+			analysis := analyzer.analyzeLinks(ctx, doc, baseURL, domLimits{})
+			results[i] = analysis.Inaccessible + analysis.Skipped[constants.LinkSkipReasonDeadline]
+			if i != 0 {
+				assert.Equal(t, 20, analysis.Internal)
+				assert.Zero(t, analysis.Inaccessible)
+				assert.Empty(t, analysis.Skipped)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Positive(t, results[0], "the cancelled analysis skips its remaining links")
+	analyzer.linkPool.mu.Lock()
+	assert.LessOrEqual(t, analyzer.linkPool.workers, 8)
+	analyzer.linkPool.mu.Unlock()
+}
+
+func BenchmarkAnalyzer_AnalyzeLinks(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	analyzer := NewAnalyzer(createTestConfig(), zap.NewNop(), NewMockMetrics(), &MockCache{})
+	defer analyzer.Close()
+	baseURL, _ := url.Parse(server.URL)
+	doc := linkPage(b, server.URL, 50)
+
+	const concurrentAnalyses = 20
+	b.ReportAllocs()
+	b.ResetTimer()
+	stop := goroutinePeak()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < concurrentAnalyses; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+			}()
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(stop()), "peak-goroutines")
+}
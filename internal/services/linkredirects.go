@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// errRedirectLoop stops a page fetch or link check whose redirects return to a URL already visited
+var errRedirectLoop = errors.New("redirect loop")
+
+// redirectTrace records the redirects followed by one link check
+type redirectTrace struct {
+	hops        int
+	loop        bool
+	finalURL    string
+	finalStatus int
+}
+
+type redirectTraceContextKey struct{}
+
+// withRedirectTrace attaches a fresh redirect trace for one link check to the context
+func withRedirectTrace(ctx context.Context) (context.Context, *redirectTrace) {
+	trace := &redirectTrace{}
+	return context.WithValue(ctx, redirectTraceContextKey{}, trace), trace
+}
+
+// redirectTraceFrom returns the redirect trace of the link check running under ctx, if any
+func redirectTraceFrom(ctx context.Context) *redirectTrace {
+	trace, _ := ctx.Value(redirectTraceContextKey{}).(*redirectTrace)
+	return trace
+}
+
+// followLinkRedirect is the CheckRedirect policy of link checks. It follows up to
+// analyzer.link_max_redirects hops, after which the last response judges the link, and
+// abandons chains that loop.
+func (a *Analyzer) followLinkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > a.optionsFor(req.Context()).LinkMaxRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	trace := redirectTraceFrom(req.Context())
+	for _, visited := range via {
+		if visited.URL.String() == req.URL.String() {
+			if trace != nil {
+				trace.loop = true
+				trace.finalURL = req.URL.String()
+			}
+			return errRedirectLoop
+		}
+	}
+	if trace != nil {
+		trace.hops = len(via)
+	}
+
+	if err := a.policy.check(req.Context(), req.URL); err != nil {
+		return err
+	}
+	// The hosts redirects reach are capped and counted like the hosts the page links to
+	if hosts := hostCapFrom(req.Context()); hosts != nil {
+		if err := hosts.reach(req.Context(), req.URL); err != nil {
+			return err
+		}
+	}
+	// Credentials follow redirects on the analyzed host only
+	credentialsFrom(req.Context()).apply(req)
+	// Every followed redirect is another outbound request
+	return budgetFrom(req.Context()).acquire(req.Context())
+}
+
+// record notes the response that judged the link
+func (t *redirectTrace) record(resp *http.Response) {
+	if t == nil {
+		return
+	}
+	t.finalURL = resp.Request.URL.String()
+	t.finalStatus = resp.StatusCode
+}
+
+// redirect returns the link's redirect details, or nil when it did not redirect
+func (t *redirectTrace) redirect(link string) *models.LinkRedirect {
+	if t.hops == 0 && !t.loop && (t.finalStatus < http.StatusMultipleChoices || t.finalStatus >= http.StatusBadRequest) {
+		return nil
+	}
+	return &models.LinkRedirect{
+		URL:         link,
+		Hops:        t.hops,
+		FinalURL:    t.finalURL,
+		FinalStatus: t.finalStatus,
+		Loop:        t.loop,
+	}
+}
+
+// addOffOriginRedirect counts the redirect of an internal link when it ended outside the
+// analyzed page's site, as analyzer.internal_scope defines it
+func (a *Analyzer) addOffOriginRedirect(ctx context.Context, redirects **models.OffOriginRedirects, baseURL *url.URL, redirect models.LinkRedirect) {
+	final, err := url.Parse(redirect.FinalURL)
+	if err != nil || final.Hostname() == "" || a.isInternalLink(ctx, baseURL, final) {
+		return
+	}
+
+	if *redirects == nil {
+		*redirects = &models.OffOriginRedirects{}
+	}
+	(*redirects).Count++
+	if len((*redirects).Samples) < constants.LinkMaxOffOriginRedirectSamples {
+		(*redirects).Samples = append((*redirects).Samples, redirect)
+	}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newRedirectServer serves redirect chains: /chain/N redirects N times before reaching /ok,
+// /broken redirects once to a 404 and /loop/a and /loop/b redirect to each other
+func newRedirectServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/ok":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/broken":
+			http.Redirect(w, r, "/missing", http.StatusFound)
+		case r.URL.Path == "/loop/a":
+			http.Redirect(w, r, "/loop/b", http.StatusFound)
+		case r.URL.Path == "/loop/b":
+			http.Redirect(w, r, "/loop/a", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/chain/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/chain/"))
+			next := "/ok"
+			if n > 1 {
+				next = fmt.Sprintf("/chain/%d", n-1)
+			}
+			http.Redirect(w, r, next, http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_AnalyzeLinks_FollowsRedirects(t *testing.T) {
+	server := newRedirectServer(t)
+
+	tests := []struct {
+		name             string
+		path             string
+		maxRedirects     int
+		inaccessible     int
+		expectedRedirect *models.LinkRedirect
+	}{
+		{
+			name:             "One hop to a 404",
+			path:             "/broken",
+			maxRedirects:     5,
+			inaccessible:     1,
+			expectedRedirect: &models.LinkRedirect{Hops: 1, FinalURL: "/missing", FinalStatus: http.StatusNotFound},
+		},
+		{
+			name:             "Three hops",
+			path:             "/chain/3",
+			maxRedirects:     5,
+			expectedRedirect: &models.LinkRedirect{Hops: 3, FinalURL: "/ok", FinalStatus: http.StatusOK},
+		},
+		{
+			name:             "Chain longer than the limit",
+			path:             "/chain/3",
+			maxRedirects:     2,
+			expectedRedirect: &models.LinkRedirect{Hops: 2, FinalURL: "/chain/1", FinalStatus: http.StatusMovedPermanently},
+		},
+		{
+			name:             "Redirects not followed",
+			path:             "/broken",
+			expectedRedirect: &models.LinkRedirect{FinalURL: "/broken", FinalStatus: http.StatusFound},
+		},
+		{
+			name:             "Loop",
+			path:             "/loop/a",
+			maxRedirects:     5,
+			inaccessible:     1,
+			expectedRedirect: &models.LinkRedirect{Hops: 1, FinalURL: "/loop/a", Loop: true},
+		},
+		{
+			name:         "No redirect",
+			path:         "/ok",
+			maxRedirects: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.Analyzer.LinkMaxRedirects = tt.maxRedirects
+			analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+			// Checked as an external link, through the shared link client
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="` + server.URL + tt.path + `">Link</a>`))
+			require.NoError(t, err)
+			baseURL, _ := url.Parse("https://example.com/")
+
+			analysis := analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+
+			assert.Equal(t, tt.inaccessible, analysis.Inaccessible)
+			if tt.expectedRedirect == nil {
+				assert.Empty(t, analysis.Redirects)
+				return
+			}
+			expected := *tt.expectedRedirect
+			expected.URL = server.URL + tt.path
+			expected.FinalURL = server.URL + expected.FinalURL
+			assert.Equal(t, []models.LinkRedirect{expected}, analysis.Redirects)
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeLinks_InternalRedirects(t *testing.T) {
+	server := newRedirectServer(t)
+	cfg := createTestConfig()
+	cfg.Analyzer.LinkMaxRedirects = 5
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="/broken">Broken</a><a href="/chain/1">Moved</a><a href="/ok">OK</a>`))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse(server.URL + "/")
+
+	analysis := analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+
+	assert.Equal(t, 1, analysis.InaccessibleInternal)
+	assert.Equal(t, []models.LinkRedirect{
+		{URL: server.URL + "/broken", Hops: 1, FinalURL: server.URL + "/missing", FinalStatus: http.StatusNotFound},
+		{URL: server.URL + "/chain/1", Hops: 1, FinalURL: server.URL + "/ok", FinalStatus: http.StatusOK},
+	}, analysis.Redirects)
+}
+
+// newOffOriginSite serves a site whose /out redirects to another server, addressed by name so
+// that its host differs from the site's IP address, and whose /moved redirects within the site.
+// It returns the site and the URL /out lands on.
+func newOffOriginSite(t *testing.T) (*httptest.Server, string) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(external.Close)
+	landing := strings.Replace(external.URL, "127.0.0.1", "localhost", 1) + "/landing?utm_source=site"
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/out":
+			http.Redirect(w, r, landing, http.StatusFound)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(site.Close)
+	return site, landing
+}
+
+func TestAnalyzer_AnalyzeLinks_OffOriginRedirects(t *testing.T) {
+	site, landing := newOffOriginSite(t)
+	cfg := createTestConfig()
+	cfg.Analyzer.LinkMaxRedirects = 5
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="/out">Partner</a><a href="/moved">Moved</a><a href="/ok">OK</a>`))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse(site.URL + "/")
+
+	analysis := analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+
+	assert.Equal(t, &models.OffOriginRedirects{
+		Count: 1,
+		Samples: []models.LinkRedirect{
+			{URL: site.URL + "/out", Hops: 1, FinalURL: landing, FinalStatus: http.StatusOK},
+		},
+	}, analysis.OffOriginRedirects)
+	assert.Len(t, analysis.Redirects, 2, "redirects within the site are listed but not off-origin")
+}
+
+func TestAnalyzer_OffOriginRedirectsNeedDetails(t *testing.T) {
+	site, _ := newOffOriginSite(t)
+	cfg := createTestConfig()
+	cfg.Analyzer.LinkMaxRedirects = 5
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	html := `<a href="/out">Partner</a>`
+	result, err := analyzer.AnalyzeHTML(context.Background(), html, site.URL+"/", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, result.Links.OffOriginRedirects)
+
+	result, err = analyzer.AnalyzeHTML(context.Background(), html, site.URL+"/", models.AnalyzeOptions{IncludeLinkDetails: true})
+	require.NoError(t, err)
+	require.NotNil(t, result.Links.OffOriginRedirects)
+	assert.Equal(t, 1, result.Links.OffOriginRedirects.Count)
+
+	// Without following redirects no link is known to leave the site
+	cfg.Analyzer.LinkMaxRedirects = 0
+	analyzer = NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	result, err = analyzer.AnalyzeHTML(context.Background(), html, site.URL+"/", models.AnalyzeOptions{IncludeLinkDetails: true})
+	require.NoError(t, err)
+	assert.Nil(t, result.Links.OffOriginRedirects)
+}
+
+func TestAnalyzer_LinkRedirects_ExternalHosts(t *testing.T) {
+	var mu sync.Mutex
+	contacted := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		contacted[r.Host]++
+		mu.Unlock()
+		if r.Host == "a.example" {
+			http.Redirect(w, r, "http://b.example/", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	html := `<a href="http://a.example/">A</a><a href="http://c.example/">C</a>`
+
+	tests := []struct {
+		name          string
+		maxHosts      int
+		contacted     map[string]int
+		externalHosts int
+		skipped       map[string]int
+	}{
+		{name: "Hosts reached by redirects are counted", contacted: map[string]int{"a.example": 1, "b.example": 1, "c.example": 1}, externalHosts: 3},
+		{name: "Redirects stop at the cap", maxHosts: 2, contacted: map[string]int{"a.example": 1, "c.example": 1}, externalHosts: 2,
+			skipped: map[string]int{constants.LinkSkipReasonHostCap: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(contacted)
+			cfg := createTestConfig()
+			cfg.Analyzer.LinkMaxRedirects = 5
+			cfg.Analyzer.MaxExternalHosts = tt.maxHosts
+			analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+			dialServer(analyzer, server)
+
+			result, err := analyzer.AnalyzeHTML(context.Background(), html, "http://site.example/", models.AnalyzeOptions{IncludeStats: true})
+			require.NoError(t, err)
+			assert.Equal(t, tt.contacted, contacted)
+			require.NotNil(t, result.Stats)
+			assert.Equal(t, tt.externalHosts, result.Stats.ExternalHosts)
+			assert.Equal(t, tt.skipped, result.Links.Skipped)
+			assert.Zero(t, result.Links.Inaccessible)
+		})
+	}
+}
+
+func TestAddOffOriginRedirect(t *testing.T) {
+	baseURL, _ := url.Parse("https://www.example.co.uk/")
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	var redirects *models.OffOriginRedirects
+
+	analyzer.addOffOriginRedirect(context.Background(), &redirects, baseURL, models.LinkRedirect{URL: "https://www.example.co.uk/a", FinalURL: "https://shop.example.co.uk/a"})
+	analyzer.addOffOriginRedirect(context.Background(), &redirects, baseURL, models.LinkRedirect{URL: "https://www.example.co.uk/b", FinalURL: "https://EXAMPLE.co.uk./b"})
+	assert.Nil(t, redirects, "subdomains of the same registered domain are the same site")
+
+	analyzer.addOffOriginRedirect(context.Background(), &redirects, baseURL, models.LinkRedirect{URL: "https://www.example.co.uk/c", FinalURL: "https://tracker.example.com/c"})
+	analyzer.addOffOriginRedirect(context.Background(), &redirects, baseURL, models.LinkRedirect{URL: "https://www.example.co.uk/d", FinalURL: "mailto:info@example.com"})
+	require.NotNil(t, redirects)
+	assert.Equal(t, 1, redirects.Count)
+
+	// With the exact host scope a redirect to another subdomain leaves the site
+	cfg := createTestConfig()
+	cfg.Analyzer.InternalScope = constants.InternalScopeExactHost
+	analyzer = NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	redirects = nil
+	analyzer.addOffOriginRedirect(context.Background(), &redirects, baseURL, models.LinkRedirect{URL: "https://www.example.co.uk/a", FinalURL: "https://shop.example.co.uk/a"})
+	analyzer.addOffOriginRedirect(context.Background(), &redirects, baseURL, models.LinkRedirect{URL: "https://www.example.co.uk/b", FinalURL: "https://WWW.example.co.uk:443/b"})
+	require.NotNil(t, redirects)
+	assert.Equal(t, 1, redirects.Count)
+}
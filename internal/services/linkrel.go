@@ -0,0 +1,31 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// tallyLinkRel counts the rel qualifiers of outbound links and target="_blank" links
+// that leave window.opener exposed to the opened page
+func tallyLinkRel(analysis *models.LinkAnalysis, s *goquery.Selection, external bool) {
+	if external {
+		if hasRelToken(s, "nofollow") {
+			analysis.Nofollow++
+		}
+		if hasRelToken(s, "sponsored") {
+			analysis.Sponsored++
+		}
+		if hasRelToken(s, "ugc") {
+			analysis.UGC++
+		}
+	}
+
+	// noreferrer implies noopener
+	target := strings.TrimSpace(s.AttrOr("target", ""))
+	if strings.EqualFold(target, "_blank") && !hasRelToken(s, "noopener") && !hasRelToken(s, "noreferrer") {
+		analysis.UnsafeTargetBlank++
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_LinkRel(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name     string
+		links    string
+		expected models.LinkAnalysis
+	}{
+		{
+			name:     "Followed links",
+			links:    `<a href="https://other.com/">Other</a><a href="/about">About</a>`,
+			expected: models.LinkAnalysis{Internal: 1, External: 1},
+		},
+		{
+			name:     "Single rel values",
+			links:    `<a href="https://a.com/" rel="nofollow">A</a><a href="https://b.com/" rel="sponsored">B</a><a href="https://c.com/" rel="ugc">C</a>`,
+			expected: models.LinkAnalysis{External: 3, Nofollow: 1, Sponsored: 1, UGC: 1},
+		},
+		{
+			name:     "Combined and mixed-case rel values",
+			links:    `<a href="https://a.com/" rel="Sponsored NOFOLLOW">A</a><a href="https://b.com/" rel="  ugc	nofollow  noopener ">B</a>`,
+			expected: models.LinkAnalysis{External: 2, Nofollow: 2, Sponsored: 1, UGC: 1},
+		},
+		{
+			name:     "Tokens are matched whole",
+			links:    `<a href="https://a.com/" rel="nofollowed">A</a><a href="https://b.com/" rel="ugc-like">B</a>`,
+			expected: models.LinkAnalysis{External: 2},
+		},
+		{
+			name:     "Internal links do not count as outbound rel",
+			links:    `<a href="/partner" rel="nofollow sponsored">Partner</a>`,
+			expected: models.LinkAnalysis{Internal: 1},
+		},
+		{
+			name: "Unsafe target blank",
+			links: `<a href="https://a.com/" target="_blank">A</a>` +
+				`<a href="/help" target="_BLANK" rel="nofollow">Help</a>` +
+				`<a href="https://b.com/" target=" _blank " rel="external">B</a>`,
+			expected: models.LinkAnalysis{Internal: 1, External: 2, UnsafeTargetBlank: 3},
+		},
+		{
+			name: "Safe target blank",
+			links: `<a href="https://a.com/" target="_blank" rel="noopener">A</a>` +
+				`<a href="https://b.com/" target="_blank" rel="NoReferrer">B</a>` +
+				`<a href="https://c.com/" target="_blank" rel="nofollow noopener noreferrer">C</a>` +
+				`<a href="https://d.com/" target="_self">D</a>` +
+				`<a href="https://e.com/" target="blank">E</a>`,
+			expected: models.LinkAnalysis{External: 5, Nofollow: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>` + tt.links + `</body></html>`))
+			require.NoError(t, err)
+			baseURL, _ := url.Parse("https://example.com/")
+
+			analysis, _, _ := analyzer.classifyLinks(context.Background(), doc, baseURL, domLimits{})
+			analysis.ExternalDomains = nil
+			analysis.InternalDetail = models.InternalLinkDetail{}
+			analysis.AnchorTextStats = models.AnchorTextStats{}
+			analysis.Raw = nil
+			assert.Equal(t, tt.expected, analysis)
+		})
+	}
+}
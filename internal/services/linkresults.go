@@ -0,0 +1,66 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// linkDetails collects the outcome of each link of an analysis, for options.include_link_details.
+// Checks complete in worker order; result sorts them so repeated runs compare cleanly.
+type linkDetails struct {
+	internal []models.LinkCheckDetail
+	external []models.LinkCheckDetail
+}
+
+func newLinkDetails() *linkDetails {
+	return &linkDetails{internal: []models.LinkCheckDetail{}, external: []models.LinkCheckDetail{}}
+}
+
+// add records the outcome of a dispatched link check
+func (d *linkDetails) add(result linkCheckResult) {
+	detail := models.LinkCheckDetail{
+		URL:         result.link.url,
+		Accessible:  result.accessible,
+		Status:      result.status,
+		Failure:     result.failure,
+		RateLimited: result.rateLimited,
+	}
+	switch {
+	case result.skipped:
+		detail.Skipped = constants.LinkSkipReasonDeadline
+	case result.overBudget:
+		detail.Skipped = constants.LinkSkipReasonBudget
+	case result.blocked:
+		detail.Skipped = constants.LinkSkipReasonPolicy
+	case result.hostCapped:
+		detail.Skipped = constants.LinkSkipReasonHostCap
+	case result.circuitOpen:
+		detail.Skipped = constants.LinkSkipReasonCircuitOpen
+	}
+	d.append(detail, result.link.isInternal)
+}
+
+// addSkipped records links that were not dispatched for reason
+func (d *linkDetails) addSkipped(links []string, isInternal bool, reason string) {
+	for _, link := range links {
+		d.append(models.LinkCheckDetail{URL: link, Skipped: reason}, isInternal)
+	}
+}
+
+func (d *linkDetails) append(detail models.LinkCheckDetail, isInternal bool) {
+	if isInternal {
+		d.internal = append(d.internal, detail)
+	} else {
+		d.external = append(d.external, detail)
+	}
+}
+
+// result returns the details grouped by classification and sorted by URL
+func (d *linkDetails) result() *models.LinkDetails {
+	for _, group := range [][]models.LinkCheckDetail{d.internal, d.external} {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].URL < group[j].URL })
+	}
+	return &models.LinkDetails{Internal: d.internal, External: d.external}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// newLinkDetailsServer answers the links of testdata/link_details.html. It is reached as
+// 127.0.0.1 for internal links and as localhost for external ones.
+func newLinkDetailsServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old-page":
+			http.Redirect(w, r, "/about", http.StatusMovedPermanently)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/outage":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_AnalyzeLinks_DetailsGolden(t *testing.T) {
+	server := newLinkDetailsServer(t)
+	external := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	placeholders := strings.NewReplacer(server.URL, "{{internal}}", external, "{{external}}")
+
+	cfg := createTestConfig()
+	cfg.Analyzer.LinkCheckOverrides = []config.LinkCheckOverride{{Pattern: "*.tracker.invalid", Skip: true}}
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	baseURL, _ := url.Parse(server.URL + "/")
+	fixture := strings.ReplaceAll(loadFixture(t, "link_details.html"), "{{external}}", external)
+
+	// Workers finish in a different order on every run; the details must not
+	var runs []string
+	for i := 0; i < 5; i++ {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(fixture))
+		require.NoError(t, err)
+
+		analysis := analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+		data, err := json.MarshalIndent(analysis.Details, "", "  ")
+		require.NoError(t, err)
+		runs = append(runs, placeholders.Replace(string(data))+"\n")
+	}
+
+	golden := filepath.Join("testdata", "link_details.golden.json")
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(golden, []byte(runs[0]), 0o644))
+	}
+	expected := loadFixture(t, "link_details.golden.json")
+	for i, run := range runs {
+		assert.Equal(t, expected, run, "run %d", i+1)
+	}
+}
+
+func TestAnalyzer_Analyze_IncludeLinkDetails(t *testing.T) {
+	server := newLinkDetailsServer(t)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	html := `<html><head><title>Links</title></head><body><a href="/missing">Missing</a></body></html>`
+
+	result, err := analyzer.AnalyzeHTML(context.Background(), html, server.URL, models.AnalyzeOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, result.Links.Details, "link details are opt-in")
+
+	result, err = analyzer.AnalyzeHTML(context.Background(), html, server.URL, models.AnalyzeOptions{IncludeLinkDetails: true})
+	require.NoError(t, err)
+	assert.Equal(t, &models.LinkDetails{
+		Internal: []models.LinkCheckDetail{{URL: server.URL + "/missing", Status: http.StatusNotFound, Failure: "not_found"}},
+		External: []models.LinkCheckDetail{},
+	}, result.Links.Details)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+type samplingSeedContextKey struct{}
+
+// withSamplingSeed attaches the caller's options.sampling_seed to the context; nil attaches nothing
+func withSamplingSeed(ctx context.Context, seed *uint64) context.Context {
+	if seed == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, samplingSeedContextKey{}, *seed)
+}
+
+// samplingSeedFrom returns the sampling seed of the analysis running under ctx, or a random
+// one when the caller gave none
+func samplingSeedFrom(ctx context.Context) uint64 {
+	if seed, ok := ctx.Value(samplingSeedContextKey{}).(uint64); ok {
+		return seed
+	}
+	return rand.Uint64()
+}
+
+// samplingSeedFor returns the caller's options.sampling_seed when the links of the analysis
+// can be sampled at all, and nil when link sampling is off or no link is checked, so a seed
+// that changes nothing does not split the cache entry
+func samplingSeedFor(opts models.AnalyzeOptions, options *analyzerOptions) *uint64 {
+	if !options.LinkSampling.Enabled || opts.SkipLinkCheck {
+		return nil
+	}
+	return opts.SamplingSeed
+}
+
+// linkSample tracks the links of a sample judged accessible or not, by stratum, to
+// extrapolate the inaccessible links of the whole population once the checks are done
+type linkSample struct {
+	report             *models.LinkSampling
+	internalPopulation int
+	externalPopulation int
+	judgedInternal     int
+	judgedExternal     int
+}
+
+// sampleLinks draws maxLinks of the unique external and internal links at random when there
+// are more of them, split between the two in proportion to their numbers and kept in document
+// order. Otherwise the links are returned as they are, with a nil sample.
+func sampleLinks(external, internal []string, maxLinks int, seed uint64) ([]string, []string, *linkSample) {
+	uniqueExternal, uniqueInternal := uniqueLinks(external), uniqueLinks(internal)
+	population := len(uniqueExternal) + len(uniqueInternal)
+	if population <= maxLinks {
+		return external, internal, nil
+	}
+
+	externalSize := int(math.Round(float64(maxLinks) * float64(len(uniqueExternal)) / float64(population)))
+	// Both kinds of link present are sampled, so that each can be extrapolated
+	externalSize = min(max(externalSize, min(len(uniqueExternal), 1)), len(uniqueExternal), maxLinks-min(len(uniqueInternal), 1))
+	internalSize := min(maxLinks-externalSize, len(uniqueInternal))
+
+	r := rand.New(rand.NewPCG(seed, seed))
+	sample := &linkSample{
+		report: &models.LinkSampling{
+			Population:      population,
+			SampleSize:      externalSize + internalSize,
+			SampledInternal: internalSize,
+			SampledExternal: externalSize,
+			Rate:            float64(externalSize+internalSize) / float64(population),
+			Seed:            seed,
+		},
+		internalPopulation: len(uniqueInternal),
+		externalPopulation: len(uniqueExternal),
+	}
+	return pickLinks(r, uniqueExternal, externalSize), pickLinks(r, uniqueInternal, internalSize), sample
+}
+
+// uniqueLinks returns links without repeats, in the order they first appear
+func uniqueLinks(links []string) []string {
+	seen := make(map[string]bool, len(links))
+	unique := make([]string, 0, len(links))
+	for _, link := range links {
+		if !seen[link] {
+			seen[link] = true
+			unique = append(unique, link)
+		}
+	}
+	return unique
+}
+
+// pickLinks returns n of links chosen uniformly at random, in their original order
+func pickLinks(r *rand.Rand, links []string, n int) []string {
+	indexes := r.Perm(len(links))[:n]
+	slices.Sort(indexes)
+	picked := make([]string, n)
+	for i, index := range indexes {
+		picked[i] = links[index]
+	}
+	return picked
+}
+
+// judged counts a sampled link found accessible or not; skipped and rate limited ones say
+// nothing about the population
+func (s *linkSample) judged(isInternal bool) {
+	if s == nil {
+		return
+	}
+	if isInternal {
+		s.judgedInternal++
+	} else {
+		s.judgedExternal++
+	}
+}
+
+// estimate scales the inaccessible links of each stratum up to its population and reports
+// the estimate with its 95% margin of error
+func (s *linkSample) estimate(inaccessibleInternal, inaccessibleExternal int) *models.LinkSampling {
+	if s == nil {
+		return nil
+	}
+
+	var estimate, variance float64
+	var unchecked []string
+	for _, stratum := range []struct {
+		kind                             string
+		population, judged, inaccessible int
+	}{
+		{"internal", s.internalPopulation, s.judgedInternal, inaccessibleInternal},
+		{"external", s.externalPopulation, s.judgedExternal, inaccessibleExternal},
+	} {
+		if stratum.population == 0 {
+			continue
+		}
+		if stratum.judged == 0 {
+			unchecked = append(unchecked, stratum.kind)
+			continue
+		}
+		population, judged := float64(stratum.population), float64(stratum.judged)
+		p := float64(stratum.inaccessible) / judged
+		estimate += population * p
+		if stratum.judged > 1 {
+			variance += population * population * (1 - judged/population) * p * (1 - p) / (judged - 1)
+		}
+	}
+	s.report.EstimatedInaccessible = int(math.Round(estimate))
+	s.report.MarginOfError = int(math.Ceil(constants.LinkSamplingZ95 * math.Sqrt(variance)))
+
+	judged := s.judgedInternal + s.judgedExternal
+	notes := []string{fmt.Sprintf("Estimated from %d of %d unique links chosen at random, within ±%d at 95%% confidence", judged, s.report.Population, s.report.MarginOfError)}
+	if missed := s.report.SampleSize - judged; missed > 0 {
+		notes = append(notes, fmt.Sprintf("%d sampled links could not be checked, which makes the estimate less reliable", missed))
+	}
+	if len(unchecked) > 0 {
+		notes = append(notes, fmt.Sprintf("no %s link could be checked, so those are left out of the estimate", strings.Join(unchecked, " or ")))
+	}
+	if judged < constants.LinkSamplingMinReliable {
+		notes = append(notes, "too few links were checked for a reliable estimate")
+	}
+	s.report.Confidence = strings.Join(notes, "; ")
+	return s.report
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newSampledSite serves a page of 700 internal links, a tenth of them broken, and 300 external
+// ones addressed by name so their host differs from the page's. It records the paths checked.
+func newSampledSite(t *testing.T) (*httptest.Server, *goquery.Document, func() []string) {
+	var mu sync.Mutex
+	var checked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		checked = append(checked, r.URL.Path)
+		mu.Unlock()
+		var page int
+		if _, err := fmt.Sscanf(r.URL.Path, "/page/%d", &page); err == nil && page%10 == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	external := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	var html strings.Builder
+	html.WriteString("<html><body>")
+	for i := range 700 {
+		fmt.Fprintf(&html, `<a href="/page/%d">Page</a>`, i)
+		if i < 300 {
+			fmt.Fprintf(&html, `<a href="%s/ext/%d">Partner</a>`, external, i)
+		}
+	}
+	// Repeats are sampled once
+	html.WriteString(`<a href="/page/1">Again</a></body></html>`)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html.String()))
+	require.NoError(t, err)
+
+	return server, doc, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		paths := checked
+		checked = nil
+		return paths
+	}
+}
+
+func TestAnalyzer_AnalyzeLinks_Sampling(t *testing.T) {
+	server, doc, checked := newSampledSite(t)
+	cfg := createTestConfig()
+	cfg.Analyzer.LinkSampling.Enabled = true
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	baseURL, _ := url.Parse(server.URL + "/")
+	seed := uint64(42)
+	ctx := withSamplingSeed(context.Background(), &seed)
+
+	analysis := analyzer.analyzeLinks(ctx, doc, baseURL, domLimits{})
+
+	assert.True(t, analysis.Sampled)
+	require.NotNil(t, analysis.Sampling)
+	sampling := *analysis.Sampling
+	assert.Equal(t, 1000, sampling.Population, "repeated links count once")
+	assert.Equal(t, 100, sampling.SampleSize)
+	assert.Equal(t, 70, sampling.SampledInternal, "the sample is split like the population")
+	assert.Equal(t, 30, sampling.SampledExternal)
+	assert.Equal(t, 0.1, sampling.Rate)
+	assert.Equal(t, seed, sampling.Seed)
+
+	paths := checked()
+	assert.Len(t, paths, 100)
+	internal := 0
+	for _, path := range paths {
+		if strings.HasPrefix(path, "/page/") {
+			internal++
+		}
+	}
+	assert.Equal(t, 70, internal)
+	assert.Equal(t, 701, analysis.Internal, "every link is still counted")
+	assert.Equal(t, 300, analysis.External)
+
+	// The 70 broken internal links are estimated from those sampled
+	assert.Equal(t, analysis.InaccessibleInternal, analysis.Inaccessible)
+	assert.Equal(t, analysis.InaccessibleInternal*10, sampling.EstimatedInaccessible)
+	assert.InDelta(t, 70, sampling.EstimatedInaccessible, float64(2*sampling.MarginOfError))
+	assert.Positive(t, sampling.MarginOfError)
+	assert.Contains(t, sampling.Confidence, "Estimated from 100 of 1000 unique links chosen at random")
+
+	t.Run("The same seed draws the same sample", func(t *testing.T) {
+		again := analyzer.analyzeLinks(ctx, doc, baseURL, domLimits{})
+		assert.ElementsMatch(t, paths, checked())
+		assert.Equal(t, analysis.Sampling, again.Sampling)
+	})
+
+	t.Run("Another seed draws another sample", func(t *testing.T) {
+		other := uint64(7)
+		analyzer.analyzeLinks(withSamplingSeed(context.Background(), &other), doc, baseURL, domLimits{})
+		assert.NotElementsMatch(t, paths, checked())
+	})
+}
+
+func TestAnalyzer_AnalyzeLinks_SamplingDisabled(t *testing.T) {
+	server, doc, checked := newSampledSite(t)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	baseURL, _ := url.Parse(server.URL + "/")
+
+	analysis := analyzer.analyzeLinks(context.Background(), doc, baseURL, domLimits{})
+
+	assert.False(t, analysis.Sampled)
+	assert.Nil(t, analysis.Sampling)
+	paths := checked()
+	assert.Len(t, paths, 100)
+	assert.NotContains(t, paths, "/page/99", "the external links fill the checks, in document order")
+}
+
+func TestSampleLinks(t *testing.T) {
+	links := func(prefix string, n int) []string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = fmt.Sprintf("%s/%d", prefix, i)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name             string
+		external         int
+		internal         int
+		maxLinks         int
+		sampledExternal  int
+		sampledInternal  int
+		expectNoSampling bool
+	}{
+		{name: "Within the limit", external: 40, internal: 60, maxLinks: 100, expectNoSampling: true},
+		{name: "Proportional", external: 250, internal: 750, maxLinks: 100, sampledExternal: 25, sampledInternal: 75},
+		{name: "Internal only", internal: 500, maxLinks: 100, sampledInternal: 100},
+		{name: "Rare external links are still sampled", external: 2, internal: 5000, maxLinks: 100, sampledExternal: 1, sampledInternal: 99},
+		{name: "Rare internal links are still sampled", external: 5000, internal: 1, maxLinks: 10, sampledExternal: 9, sampledInternal: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			external, internal := links("https://other.example", tt.external), links("https://site.example", tt.internal)
+			sampledExternal, sampledInternal, sample := sampleLinks(external, internal, tt.maxLinks, 1)
+			if tt.expectNoSampling {
+				assert.Nil(t, sample)
+				assert.Equal(t, external, sampledExternal)
+				assert.Equal(t, internal, sampledInternal)
+				return
+			}
+			require.NotNil(t, sample)
+			assert.Len(t, sampledExternal, tt.sampledExternal)
+			assert.Len(t, sampledInternal, tt.sampledInternal)
+			assert.Equal(t, tt.sampledExternal, sample.report.SampledExternal)
+			assert.Equal(t, tt.sampledInternal, sample.report.SampledInternal)
+			assert.IsIncreasing(t, sampledIndexes(t, sampledInternal), "the sample keeps document order")
+		})
+	}
+}
+
+// sampledIndexes returns the trailing numbers of the sampled links
+func sampledIndexes(t *testing.T, links []string) []int {
+	indexes := make([]int, len(links))
+	for i, link := range links {
+		_, err := fmt.Sscanf(link[strings.LastIndex(link, "/")+1:], "%d", &indexes[i])
+		require.NoError(t, err)
+	}
+	return indexes
+}
+
+func TestLinkSample_Estimate(t *testing.T) {
+	sample := &linkSample{report: &models.LinkSampling{Population: 1000, SampleSize: 100}, internalPopulation: 800, externalPopulation: 200}
+	for range 80 {
+		sample.judged(true)
+	}
+	for range 15 {
+		sample.judged(false)
+	}
+
+	report := sample.estimate(8, 3)
+
+	assert.Equal(t, 120, report.EstimatedInaccessible, "10% of 800 internal and 20% of 200 external links")
+	assert.Equal(t, 65, report.MarginOfError)
+	assert.Equal(t, "Estimated from 95 of 1000 unique links chosen at random, within ±65 at 95% confidence; 5 sampled links could not be checked, which makes the estimate less reliable", report.Confidence)
+
+	empty := &linkSample{report: &models.LinkSampling{Population: 300, SampleSize: 10}, internalPopulation: 200, externalPopulation: 100}
+	for range 7 {
+		empty.judged(true)
+	}
+	assert.Contains(t, empty.estimate(0, 0).Confidence, "no external link could be checked, so those are left out of the estimate; too few links were checked for a reliable estimate")
+	assert.Nil(t, (*linkSample)(nil).estimate(0, 0))
+}
+
+func TestCacheKey_SamplingSeed(t *testing.T) {
+	seed, other := uint64(1), uint64(2)
+	assert.Equal(t, "http://example.com|sampling_seed=1", cacheKey("http://example.com", models.AnalyzeOptions{SamplingSeed: &seed}))
+	assert.NotEqual(t,
+		cacheKey("http://example.com", models.AnalyzeOptions{SamplingSeed: &seed}),
+		cacheKey("http://example.com", models.AnalyzeOptions{SamplingSeed: &other}),
+	)
+	assert.Equal(t, "http://example.com", cacheKey("http://example.com", models.AnalyzeOptions{}), "unseeded samples share the entry")
+}
+
+func TestAnalyzer_SamplingSeedCachedOnlyWhenSampling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Seeded</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+	seed := uint64(1)
+
+	tests := []struct {
+		name     string
+		sampling bool
+		opts     models.AnalyzeOptions
+		key      string
+	}{
+		{name: "Sampling enabled", sampling: true, opts: models.AnalyzeOptions{SamplingSeed: &seed}, key: server.URL + "|sampling_seed=1"},
+		{name: "Sampling disabled", opts: models.AnalyzeOptions{SamplingSeed: &seed}, key: server.URL},
+		{name: "Links not checked", sampling: true, opts: models.AnalyzeOptions{SamplingSeed: &seed, SkipLinkCheck: true}, key: server.URL + "|skip_link_check"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.Analyzer.LinkSampling.Enabled = tt.sampling
+			cache := &MockCache{}
+			cache.On("Get", mock.Anything, tt.key).Return(nil, time.Duration(0), nil)
+			cache.On("Set", mock.Anything, tt.key, mock.Anything, mock.Anything).Return(nil)
+			analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+			_, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, tt.opts)
+			require.NoError(t, err)
+			cache.AssertExpectations(t)
+		})
+	}
+}
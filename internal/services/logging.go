@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+type analysisIDContextKey struct{}
+
+type loggerContextKey struct{}
+
+// WithAnalysisID sets the ID of the analyses running under ctx, such as the ID of the request
+// asking for them. Analyses without one generate their own.
+func WithAnalysisID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, analysisIDContextKey{}, id)
+}
+
+// withAnalysisLogger starts the logging of an analysis of targetURL: it returns the analysis ID
+// and a context carrying a child logger tagged with the ID and the target host, which every
+// log line of the analysis is written with, those of the link workers included
+func (a *Analyzer) withAnalysisLogger(ctx context.Context, targetURL string) (context.Context, string) {
+	id, _ := ctx.Value(analysisIDContextKey{}).(string)
+	if id == "" {
+		id = newAnalysisID()
+	}
+	logger := a.logger.With(zap.String("analysis_id", id), zap.String("target_host", targetHost(targetURL)))
+	return context.WithValue(ctx, loggerContextKey{}, logger), id
+}
+
+// loggerFrom returns the logger of the analysis running under ctx, or fallback outside analyses
+func loggerFrom(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// log returns the logger of the analysis running under ctx, or the analyzer's
+func (a *Analyzer) log(ctx context.Context) *zap.Logger {
+	return loggerFrom(ctx, a.logger)
+}
+
+// targetHost returns the lowercase host of a URL as requested, before validation, so the
+// analyses of invalid URLs are tagged too; "" when it has none
+func targetHost(targetURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(targetURL))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// newAnalysisID generates a random hex-encoded analysis ID
+func newAnalysisID() string {
+	b := make([]byte, constants.AnalysisIDByteLength)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
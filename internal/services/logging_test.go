@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newBrokenLinkServer serves a page linking to a missing page
+func newBrokenLinkServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(constants.HeaderContentType, "text/html")
+		w.Write([]byte(`<html><head><title>Home</title></head><body><a href="/missing">Missing</a></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_LinkCheckLogsCarryAnalysisFields(t *testing.T) {
+	server := newBrokenLinkServer(t)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		analyze func(a *Analyzer, ctx context.Context) (*models.AnalyzeResponse, error)
+	}{
+		{
+			name: "Fetched page",
+			analyze: func(a *Analyzer, ctx context.Context) (*models.AnalyzeResponse, error) {
+				return a.AnalyzeWithOptions(ctx, server.URL, models.AnalyzeOptions{})
+			},
+		},
+		{
+			name: "Submitted page",
+			analyze: func(a *Analyzer, ctx context.Context) (*models.AnalyzeResponse, error) {
+				return a.AnalyzeHTML(ctx, `<a href="/missing">Missing</a>`, server.URL, models.AnalyzeOptions{})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			analyzer := newTestAnalyzer(t, nil)
+			analyzer.logger = zap.New(core)
+
+			result, err := tt.analyze(analyzer, WithAnalysisID(context.Background(), "req-1234"))
+			require.NoError(t, err)
+			assert.Equal(t, "req-1234", result.AnalysisID)
+			assert.Equal(t, 1, result.Links.Inaccessible)
+
+			failed := logs.FilterMessage("Link check failed").All()
+			require.Len(t, failed, 1)
+			fields := failed[0].ContextMap()
+			assert.Equal(t, "req-1234", fields["analysis_id"])
+			assert.Equal(t, serverURL.Hostname(), fields["target_host"])
+			assert.Equal(t, server.URL+"/missing", fields["url"])
+			assert.Equal(t, int64(http.StatusNotFound), fields["status"])
+		})
+	}
+}
+
+func TestAnalyzer_AnalysisIDGenerated(t *testing.T) {
+	server := newBrokenLinkServer(t)
+	core, logs := observer.New(zap.DebugLevel)
+	analyzer := newTestAnalyzer(t, nil)
+	analyzer.logger = zap.New(core)
+
+	first, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{})
+	require.NoError(t, err)
+	second, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Len(t, first.AnalysisID, 2*constants.AnalysisIDByteLength)
+	assert.NotEqual(t, first.AnalysisID, second.AnalysisID)
+
+	failed := logs.FilterMessage("Link check failed").All()
+	require.NotEmpty(t, failed)
+	assert.Equal(t, first.AnalysisID, failed[0].ContextMap()["analysis_id"])
+}
+
+func TestAnalyzer_AnalysisLoggerOnValidationErrors(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	analyzer := newTestAnalyzer(t, nil)
+	analyzer.logger = zap.New(core)
+	ctx, id := analyzer.withAnalysisLogger(WithAnalysisID(context.Background(), "req-5678"), "HTTP://Example.COM:8080/path")
+
+	analyzer.log(ctx).Warn("test")
+
+	assert.Equal(t, "req-5678", id)
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "req-5678", fields["analysis_id"])
+	assert.Equal(t, "example.com", fields["target_host"])
+	assert.Equal(t, "", targetHost("://not a url"))
+	assert.Same(t, analyzer.logger, analyzer.log(context.Background()))
+}
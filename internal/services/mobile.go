@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"maps"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// pageSummary holds the lightweight sections compared between the desktop and mobile pages
+type pageSummary struct {
+	title           string
+	headings        map[string]int
+	metaDescription string
+	hasViewport     bool
+}
+
+// summarizePage extracts the lightweight sections of a document
+func (a *Analyzer) summarizePage(doc *goquery.Document) pageSummary {
+	return pageSummary{
+		title:           a.extractPageTitle(doc),
+		headings:        a.countHeadings(doc),
+		metaDescription: extractMetaDescription(doc),
+		hasViewport:     doc.Find("meta[name='viewport' i]").Length() > 0,
+	}
+}
+
+// compareMobile fetches the page with the configured mobile user agent and compares its
+// lightweight sections with the analyzed document. The fetch counts against the request
+// budget of the analysis; a failed fetch is reported rather than failing the analysis.
+func (a *Analyzer) compareMobile(ctx context.Context, targetURL string, desktopDoc *goquery.Document) *models.MobileComparison {
+	desktop := a.summarizePage(desktopDoc)
+	comparison := &models.MobileComparison{HasViewport: desktop.hasViewport}
+
+	page, err := a.fetchWebpageAs(ctx, targetURL, a.optionsFor(ctx).Mobile.UserAgent, nil)
+	if err != nil {
+		comparison.Error = err.Error()
+		return comparison
+	}
+	doc, err := a.parseHTML(page.body)
+	if err != nil {
+		comparison.Error = err.Error()
+		return comparison
+	}
+	mobile := a.summarizePage(doc)
+
+	comparison.MobileHasViewport = mobile.hasViewport
+	comparison.TitleMatch = desktop.title == mobile.title
+	comparison.HeadingsMatch = maps.Equal(desktop.headings, mobile.headings)
+	comparison.MetaDescriptionMatch = desktop.metaDescription == mobile.metaDescription
+	comparison.ViewportMatch = desktop.hasViewport == mobile.hasViewport
+	comparison.Match = comparison.TitleMatch && comparison.HeadingsMatch && comparison.MetaDescriptionMatch && comparison.ViewportMatch
+	if !comparison.TitleMatch {
+		comparison.MobileTitle = mobile.title
+	}
+	if !comparison.HeadingsMatch {
+		comparison.MobileHeadings = mobile.headings
+	}
+	return comparison
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+const (
+	desktopPage = `<html><head><title>Shop</title><meta name="description" content="Everything for your home">
+		<meta name="viewport" content="width=device-width"></head><body><h1>Shop</h1><h2>Offers</h2></body></html>`
+	responsivePage = desktopPage
+	mobileSitePage = `<html><head><title>Shop - Mobile</title><meta name="description" content="Everything for your home">
+		</head><body><h1>Shop</h1></body></html>`
+)
+
+// newUserAgentServer serves desktopPage to every user agent but the mobile one, which gets mobile
+func newUserAgentServer(t *testing.T, mobile string, mobileStatus int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if strings.Contains(r.UserAgent(), "iPhone") {
+			w.WriteHeader(mobileStatus)
+			w.Write([]byte(mobile))
+			return
+		}
+		w.Write([]byte(desktopPage))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_CompareMobile(t *testing.T) {
+	tests := []struct {
+		name         string
+		mobile       string
+		mobileStatus int
+		maxOutbound  int
+		expected     *models.MobileComparison
+	}{
+		{
+			name:         "Responsive page",
+			mobile:       responsivePage,
+			mobileStatus: http.StatusOK,
+			expected: &models.MobileComparison{
+				Match:                true,
+				TitleMatch:           true,
+				HeadingsMatch:        true,
+				MetaDescriptionMatch: true,
+				ViewportMatch:        true,
+				HasViewport:          true,
+				MobileHasViewport:    true,
+			},
+		},
+		{
+			name:         "Dynamic serving",
+			mobile:       mobileSitePage,
+			mobileStatus: http.StatusOK,
+			expected: &models.MobileComparison{
+				MetaDescriptionMatch: true,
+				HasViewport:          true,
+				MobileTitle:          "Shop - Mobile",
+				MobileHeadings:       map[string]int{"h1": 1, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0},
+			},
+		},
+		{
+			name:         "Mobile fetch fails",
+			mobile:       "unavailable",
+			mobileStatus: http.StatusServiceUnavailable,
+			expected:     &models.MobileComparison{HasViewport: true, Error: "webpage returned status code 503"},
+		},
+		{
+			name:         "Mobile fetch over the outbound budget",
+			mobile:       responsivePage,
+			mobileStatus: http.StatusOK,
+			maxOutbound:  1,
+			expected:     &models.MobileComparison{HasViewport: true, Error: "failed to fetch webpage: " + errBudgetExhausted.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newUserAgentServer(t, tt.mobile, tt.mobileStatus)
+			analyzer := newTestAnalyzer(t, outboundBudget(tt.maxOutbound))
+
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{CompareMobile: true, SkipLinkCheck: true})
+			require.NoError(t, err)
+
+			assert.Equal(t, "Shop", result.Title, "the analysis itself uses the desktop page")
+			assert.Equal(t, tt.expected, result.MobileComparison)
+		})
+	}
+}
+
+func TestAnalyzer_Analyze_CompareMobileCountsAgainstBudget(t *testing.T) {
+	server := newUserAgentServer(t, responsivePage, http.StatusOK)
+	analyzer := newTestAnalyzer(t, nil)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{CompareMobile: true, SkipLinkCheck: true})
+	require.NoError(t, err)
+	require.NotNil(t, result.Fetch)
+	assert.Equal(t, 2, result.Fetch.OutboundRequests)
+
+	result, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Nil(t, result.MobileComparison, "the comparison is opt-in")
+	assert.Equal(t, 1, result.Fetch.OutboundRequests)
+}
+
+func TestCacheKey_CompareMobile(t *testing.T) {
+	assert.Equal(t, "http://example.com|"+constants.CacheVariantCompareMobile, cacheKey("http://example.com", models.AnalyzeOptions{CompareMobile: true}))
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"maps"
+	"runtime"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// analyzerOptions are the settings analyses run with: the analyzer, cache, rate limit and snapshot
+// configuration with the defaults applied. They are resolved once by NewAnalyzer into a value of their own, so the
+// configuration passed in is never modified and nothing reads it once the analyzer is built.
+type analyzerOptions struct {
+	config.AnalyzerConfig
+	Cache     config.CacheConfig
+	RateLimit config.RateLimitConfig
+	Snapshots config.SnapshotsConfig
+}
+
+type analyzerOptionsContextKey struct{}
+
+// withOptions attaches the options of one analysis to the context: a copy of the analyzer's own,
+// so a caller override applied to it changes that analysis alone. AnalyzeOptions overrides no
+// analyzer setting yet.
+func (a *Analyzer) withOptions(ctx context.Context) (context.Context, *analyzerOptions) {
+	options := a.options
+	return context.WithValue(ctx, analyzerOptionsContextKey{}, &options), &options
+}
+
+// optionsFor returns the options of the analysis running under ctx, or the analyzer's own
+// outside an analysis
+func (a *Analyzer) optionsFor(ctx context.Context) *analyzerOptions {
+	if options, ok := ctx.Value(analyzerOptionsContextKey{}).(*analyzerOptions); ok {
+		return options
+	}
+	return &a.options
+}
+
+// resolveOptions copies the configuration and fills in the defaults of unset settings
+func resolveOptions(cfg *config.Config) analyzerOptions {
+	o := analyzerOptions{AnalyzerConfig: cfg.Analyzer, Cache: cfg.Cache, RateLimit: cfg.RateLimit, Snapshots: cfg.Snapshots}
+
+	if o.MaxLinks == 0 {
+		o.MaxLinks = constants.DefaultMaxLinks
+	}
+	if o.LinkTimeout == 0 {
+		o.LinkTimeout = constants.DefaultLinkTimeout
+	}
+	o.Timeouts.Page = resolveFetchTimeouts(o.Timeouts.Page, config.FetchTimeouts{
+		Connect:        constants.DefaultPageConnectTimeout,
+		TLSHandshake:   constants.DefaultPageTLSHandshakeTimeout,
+		ResponseHeader: constants.DefaultPageResponseHeaderTimeout,
+		Overall:        o.LinkTimeout,
+	})
+	o.Timeouts.Links = resolveFetchTimeouts(o.Timeouts.Links, config.FetchTimeouts{
+		Connect:        constants.DefaultLinkConnectTimeout,
+		TLSHandshake:   constants.DefaultLinkTLSHandshakeTimeout,
+		ResponseHeader: constants.DefaultLinkResponseHeaderTimeout,
+		Overall:        o.LinkTimeout,
+	})
+	if o.MaxWorkers == 0 {
+		o.MaxWorkers = constants.DefaultMaxWorkers
+	}
+	if o.LinkPoolSize == 0 {
+		o.LinkPoolSize = constants.DefaultLinkPoolSize
+	}
+	if o.SectionConcurrency <= 0 {
+		o.SectionConcurrency = runtime.GOMAXPROCS(0)
+	}
+	if o.MaxRedirects == 0 {
+		o.MaxRedirects = constants.DefaultMaxRedirects
+	}
+	if o.MaxBodyBytes == 0 {
+		o.MaxBodyBytes = constants.DefaultMaxBodyBytes
+	}
+	if o.AnalysisTimeout == 0 {
+		o.AnalysisTimeout = constants.DefaultAnalysisTimeout
+	}
+	if o.BotProtection.Action == "" {
+		o.BotProtection.Action = constants.DefaultBotProtectionAction
+	}
+	if o.ExternalDomains.Mode == "" {
+		o.ExternalDomains.Mode = constants.DefaultExternalDomainMode
+	}
+	if o.ExternalDomains.TopN == 0 {
+		o.ExternalDomains.TopN = constants.DefaultExternalDomainsTopN
+	}
+	if o.InternalScope == "" {
+		o.InternalScope = constants.DefaultInternalScope
+	}
+	o.InternalSuffixes = normalizeHostRules(o.InternalSuffixes)
+	if o.AnchorText.GenericPhrases == nil {
+		o.AnchorText.GenericPhrases = constants.DefaultGenericLinkTexts
+	}
+	o.AnchorText.GenericPhrases = normalizeGenericPhrases(o.AnchorText.GenericPhrases)
+	if o.SEO.CanonicalSimilarityThreshold == 0 {
+		o.SEO.CanonicalSimilarityThreshold = constants.DefaultCanonicalSimilarityThreshold
+	}
+	if o.Mobile.UserAgent == "" {
+		o.Mobile.UserAgent = constants.DefaultMobileUserAgent
+	}
+	if o.Soft404.MaxTextLength == 0 {
+		o.Soft404.MaxTextLength = constants.DefaultSoft404MaxTextLength
+	}
+	if o.Soft404.Phrases == nil {
+		o.Soft404.Phrases = constants.DefaultSoft404Phrases
+	}
+	if o.Soft404.ErrorTitleTTL == 0 {
+		o.Soft404.ErrorTitleTTL = constants.DefaultSoft404ErrorTitleTTL
+	}
+	if o.LinkCircuit.FailureThreshold == 0 {
+		o.LinkCircuit.FailureThreshold = constants.DefaultLinkCircuitFailureThreshold
+	}
+	if o.Snapshots.MaxBytes == 0 {
+		o.Snapshots.MaxBytes = constants.DefaultSnapshotMaxBytes
+	}
+	if o.Snapshots.TTL == 0 {
+		o.Snapshots.TTL = constants.DefaultSnapshotTTL
+	}
+
+	// Configured weights override the defaults rule by rule; the map is the analyzer's own
+	weights := maps.Clone(constants.DefaultSEOWeights)
+	maps.Copy(weights, cfg.Analyzer.SEO.Weights)
+	o.SEO.Weights = weights
+
+	return o
+}
+
+// resolveFetchTimeouts fills the unset timeouts of t with those of defaults
+func resolveFetchTimeouts(t, defaults config.FetchTimeouts) config.FetchTimeouts {
+	if t.Connect == 0 {
+		t.Connect = defaults.Connect
+	}
+	if t.TLSHandshake == 0 {
+		t.TLSHandshake = defaults.TLSHandshake
+	}
+	if t.ResponseHeader == 0 {
+		t.ResponseHeader = defaults.ResponseHeader
+	}
+	if t.Overall == 0 {
+		t.Overall = defaults.Overall
+	}
+	return t
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// checkOrigin probes the target origin, its http:// variant and its www/apex sibling
+// with HEAD requests and reports whether they all redirect to one canonical URL
+func (a *Analyzer) checkOrigin(ctx context.Context, target *url.URL) *models.OriginChecks {
+	root := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/"}
+	httpRoot := &url.URL{Scheme: "http", Host: httpHost(target), Path: "/"}
+
+	var sibling *url.URL
+	if host := siblingHost(target.Hostname()); host != "" {
+		sibling = &url.URL{Scheme: target.Scheme, Host: withPort(host, target.Port()), Path: "/"}
+	}
+
+	// The probes are independent; run them together to bound the added latency
+	var wg sync.WaitGroup
+	var canonical, httpProbe, siblingProbe models.OriginProbe
+	wg.Add(2)
+	go func() { defer wg.Done(); canonical = a.probeOrigin(ctx, root) }()
+	go func() { defer wg.Done(); httpProbe = a.probeOrigin(ctx, httpRoot) }()
+	if sibling != nil {
+		wg.Add(1)
+		go func() { defer wg.Done(); siblingProbe = a.probeOrigin(ctx, sibling) }()
+	}
+	wg.Wait()
+
+	checks := &models.OriginChecks{CanonicalURL: canonical.FinalURL, HTTP: httpProbe}
+	if checks.CanonicalURL == "" {
+		// The target origin itself did not resolve; judge the variants against the requested origin
+		checks.CanonicalURL = root.String()
+	}
+
+	checks.HTTP.ReachesCanonical = sameOrigin(checks.HTTP.FinalURL, checks.CanonicalURL)
+	checks.HTTPSEnforced = strings.HasPrefix(checks.HTTP.FinalURL, "https://")
+	checks.SingleCanonicalHost = true
+	if sibling != nil {
+		siblingProbe.ReachesCanonical = sameOrigin(siblingProbe.FinalURL, checks.CanonicalURL)
+		checks.HostVariant = &siblingProbe
+		checks.SingleCanonicalHost = siblingProbe.ReachesCanonical
+	}
+
+	return checks
+}
+
+// probeOrigin sends one HEAD request for an origin through the shared client. A redirect is
+// not followed: its Location is where the origin sends visitors, and following it would cost
+// more than the one cheap request per probe origin checks allow.
+func (a *Analyzer) probeOrigin(ctx context.Context, origin *url.URL) models.OriginProbe {
+	probe := models.OriginProbe{URL: origin.String(), RedirectStatuses: []int{}}
+
+	client := *a.httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, origin.String(), nil)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	if err := a.policy.check(ctx, origin); err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	if err := budgetFrom(ctx).acquire(ctx); err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	resp.Body.Close()
+
+	probe.StatusCode = resp.StatusCode
+	location := resp.Header.Get(constants.HeaderLocation)
+	if resp.StatusCode < constants.StatusMultipleChoices || resp.StatusCode >= constants.StatusBadRequest || location == "" {
+		probe.FinalURL = origin.String()
+		return probe
+	}
+
+	probe.RedirectStatuses = append(probe.RedirectStatuses, resp.StatusCode)
+	next, err := origin.Parse(location)
+	if err != nil {
+		probe.Error = fmt.Sprintf("invalid redirect location: %v", err)
+		return probe
+	}
+	probe.FinalURL = next.String()
+	return probe
+}
+
+// siblingHost returns the www or apex counterpart of a host, or an empty string
+// for IP addresses and single-label hosts such as localhost
+func siblingHost(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	if apex, ok := strings.CutPrefix(host, constants.WWWPrefix); ok {
+		if !strings.Contains(apex, ".") {
+			return ""
+		}
+		return apex
+	}
+	if !strings.Contains(host, ".") {
+		return ""
+	}
+	return constants.WWWPrefix + host
+}
+
+// httpHost returns the host to probe over plain HTTP; an explicit port is kept only for http targets
+func httpHost(target *url.URL) string {
+	if target.Scheme == "http" {
+		return target.Host
+	}
+	return withPort(target.Hostname(), "")
+}
+
+// withPort joins a hostname and an optional port
+func withPort(host, port string) string {
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port == "" {
+		return host
+	}
+	return host + ":" + port
+}
+
+// sameOrigin reports whether two URLs share scheme and host
+func sameOrigin(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// originFixture serves several hosts from one HTTP and one HTTPS test server.
+// The analyzer's client resolves every host to them by port.
+type originFixture struct {
+	httpServer  *httptest.Server
+	httpsServer *httptest.Server
+}
+
+func newOriginFixture(httpHandler, httpsHandler http.HandlerFunc) *originFixture {
+	httpsServer := httptest.NewUnstartedServer(httpsHandler)
+	httpsServer.StartTLS()
+	return &originFixture{
+		httpServer:  httptest.NewServer(httpHandler),
+		httpsServer: httpsServer,
+	}
+}
+
+func (f *originFixture) Close() {
+	f.httpServer.Close()
+	f.httpsServer.Close()
+}
+
+// analyzer returns an analyzer whose client dials port 443 to the HTTPS server and everything else to the HTTP server
+func (f *originFixture) analyzer(t *testing.T, cache CacheInterface) *Analyzer {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	transport := analyzer.httpClient.Transport.(*resolvingTransport).base
+	transport.TLSClientConfig = f.httpsServer.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		target := f.httpServer.Listener.Addr().String()
+		if _, port, _ := net.SplitHostPort(addr); port == "443" {
+			target = f.httpsServer.Listener.Addr().String()
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, target)
+	}
+	return analyzer
+}
+
+func redirectTo(location string, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, location, status)
+	}
+}
+
+func TestAnalyzer_CheckOrigin(t *testing.T) {
+	target, _ := url.Parse("https://www.example.com/products")
+
+	t.Run("Canonical origin", func(t *testing.T) {
+		fixture := newOriginFixture(
+			redirectTo("https://www.example.com/", http.StatusMovedPermanently),
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Host == "example.com" {
+					http.Redirect(w, r, "https://www.example.com/", http.StatusPermanentRedirect)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+		)
+		defer fixture.Close()
+
+		checks := fixture.analyzer(t, &MockCache{}).checkOrigin(context.Background(), target)
+
+		assert.Equal(t, "https://www.example.com/", checks.CanonicalURL)
+		assert.True(t, checks.HTTPSEnforced)
+		assert.True(t, checks.SingleCanonicalHost)
+		assert.Equal(t, models.OriginProbe{
+			URL:              "http://www.example.com/",
+			FinalURL:         "https://www.example.com/",
+			StatusCode:       http.StatusMovedPermanently,
+			RedirectStatuses: []int{http.StatusMovedPermanently},
+			ReachesCanonical: true,
+		}, checks.HTTP)
+		require.NotNil(t, checks.HostVariant)
+		assert.Equal(t, models.OriginProbe{
+			URL:              "https://example.com/",
+			FinalURL:         "https://www.example.com/",
+			StatusCode:       http.StatusPermanentRedirect,
+			RedirectStatuses: []int{http.StatusPermanentRedirect},
+			ReachesCanonical: true,
+		}, *checks.HostVariant)
+	})
+
+	t.Run("No redirects", func(t *testing.T) {
+		ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+		fixture := newOriginFixture(ok, ok)
+		defer fixture.Close()
+
+		checks := fixture.analyzer(t, &MockCache{}).checkOrigin(context.Background(), target)
+
+		assert.Equal(t, "https://www.example.com/", checks.CanonicalURL)
+		assert.False(t, checks.HTTPSEnforced)
+		assert.False(t, checks.SingleCanonicalHost)
+		assert.False(t, checks.HTTP.ReachesCanonical)
+		assert.Equal(t, "http://www.example.com/", checks.HTTP.FinalURL)
+		assert.Empty(t, checks.HTTP.RedirectStatuses)
+		assert.Equal(t, "https://example.com/", checks.HostVariant.FinalURL)
+		assert.False(t, checks.HostVariant.ReachesCanonical)
+	})
+
+	t.Run("Redirects are not followed", func(t *testing.T) {
+		var requests atomic.Int32
+		fixture := newOriginFixture(
+			func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				http.Redirect(w, r, "http://www.example.com/", http.StatusFound)
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(http.StatusOK)
+			},
+		)
+		defer fixture.Close()
+
+		checks := fixture.analyzer(t, &MockCache{}).checkOrigin(context.Background(), target)
+
+		assert.Equal(t, int32(3), requests.Load(), "one request per probe, even for a redirect loop")
+		assert.Empty(t, checks.HTTP.Error)
+		assert.Equal(t, "http://www.example.com/", checks.HTTP.FinalURL)
+		assert.Equal(t, []int{http.StatusFound}, checks.HTTP.RedirectStatuses)
+		assert.False(t, checks.HTTPSEnforced)
+	})
+
+	t.Run("IP targets have no host variant", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		ipTarget, _ := url.Parse(server.URL)
+
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+		checks := analyzer.checkOrigin(context.Background(), ipTarget)
+
+		assert.Nil(t, checks.HostVariant)
+		assert.Equal(t, server.URL+"/", checks.CanonicalURL)
+		assert.True(t, checks.HTTP.ReachesCanonical)
+		assert.True(t, checks.SingleCanonicalHost)
+	})
+}
+
+func TestAnalyzer_AnalyzeWithOriginChecks(t *testing.T) {
+	fixture := newOriginFixture(
+		redirectTo("https://www.example.com/", http.StatusMovedPermanently),
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Example</title></head><body></body></html>`))
+		},
+	)
+	defer fixture.Close()
+
+	targetURL := "https://www.example.com/"
+	opts := models.AnalyzeOptions{OriginChecks: true, SkipLinkCheck: true}
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, targetURL+"|skip_link_check,origin_checks").Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, targetURL+"|skip_link_check,origin_checks", mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+
+	result, err := fixture.analyzer(t, cache).AnalyzeWithOptions(context.Background(), targetURL, opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Example", result.Title)
+	require.NotNil(t, result.OriginChecks)
+	assert.True(t, result.OriginChecks.HTTPSEnforced)
+	assert.False(t, result.OriginChecks.SingleCanonicalHost)
+	cache.AssertExpectations(t)
+}
+
+func TestSiblingHost(t *testing.T) {
+	tests := map[string]string{
+		"example.com":      "www.example.com",
+		"www.example.com":  "example.com",
+		"blog.example.com": "www.blog.example.com",
+		"localhost":        "",
+		"www.localhost":    "",
+		"127.0.0.1":        "",
+		"::1":              "",
+	}
+
+	for host, expected := range tests {
+		t.Run(host, func(t *testing.T) {
+			assert.Equal(t, expected, siblingHost(host))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// paginationTally collects the pagination of a listing page: its rel="next"/"prev" link
+// elements and infinite-scroll markers up front, and its numbered page links from the anchors
+// as they are classified
+type paginationTally struct {
+	base     *url.URL
+	numbered map[string]struct{}
+	result   models.Pagination
+}
+
+// newPaginationTally inspects the link elements and infinite-scroll markers of doc, which was served from base
+func newPaginationTally(doc *goquery.Document, base *url.URL) *paginationTally {
+	t := &paginationTally{base: base, numbered: make(map[string]struct{})}
+	doc.Find("link[rel]").Each(func(_ int, s *goquery.Selection) {
+		next := hasRelToken(s, "next")
+		if !next && !hasRelToken(s, "prev") && !hasRelToken(s, "previous") {
+			return
+		}
+		t.result.HasRelNextPrev = true
+		if next && t.result.NextURL == "" {
+			t.result.NextURL = t.resolve(s.AttrOr("href", ""))
+		}
+	})
+	t.result.InfiniteScrollSuspected = hasInfiniteScrollMarkers(doc)
+	return t
+}
+
+// add records an anchor resolved against the base URL, on the base host or not
+func (t *paginationTally) add(s *goquery.Selection, link *url.URL, external bool) {
+	// Anchors marked rel="next" name the next page when no link element does
+	if t.result.NextURL == "" && hasRelToken(s, "next") {
+		t.result.NextURL = t.resolve(s.AttrOr("href", ""))
+	}
+	if external || !isNumberedPageLink(link) {
+		return
+	}
+	page := *link
+	page.Fragment, page.RawFragment = "", ""
+	t.numbered[page.String()] = struct{}{}
+}
+
+// resolve returns href resolved absolute against the base URL without its fragment and
+// credentials, or "" when it is not a web URL
+func (t *paginationTally) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	resolved, err := t.base.Parse(href)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") || resolved.Host == "" {
+		return ""
+	}
+	resolved.Fragment, resolved.RawFragment = "", ""
+	resolved.User = nil
+	return resolved.String()
+}
+
+// pagination returns the collected pagination
+func (t *paginationTally) pagination() models.Pagination {
+	t.result.NumberedLinks = len(t.numbered)
+	return t.result
+}
+
+// isNumberedPageLink reports whether a link leads to a numbered page of a listing, by a page
+// number query parameter such as ?page=2 or a path ending in a page segment such as /page/2
+func isNumberedPageLink(link *url.URL) bool {
+	for name, values := range link.Query() {
+		if slices.Contains(constants.PaginationQueryParams, strings.ToLower(name)) && len(values) > 0 && isPageNumber(values[0]) {
+			return true
+		}
+	}
+	segments := strings.Split(strings.Trim(link.Path, "/"), "/")
+	if len(segments) < 2 {
+		return false
+	}
+	return slices.Contains(constants.PaginationPathSegments, strings.ToLower(segments[len(segments)-2])) && isPageNumber(segments[len(segments)-1])
+}
+
+// isPageNumber reports whether s is a positive page number
+func isPageNumber(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0 && !strings.HasPrefix(s, "+")
+}
+
+// hasInfiniteScrollMarkers reports whether doc carries the attributes of infinite-scroll
+// libraries or elements whose class names mark them as infinite-scroll containers or sentinels
+func hasInfiniteScrollMarkers(doc *goquery.Document) bool {
+	for _, attr := range constants.InfiniteScrollAttributes {
+		if doc.Find("["+attr+"]").Length() > 0 {
+			return true
+		}
+	}
+	found := false
+	doc.Find("[class]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		class := strings.ToLower(s.AttrOr("class", ""))
+		for _, marker := range constants.InfiniteScrollClassMarkers {
+			if strings.Contains(class, marker) {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
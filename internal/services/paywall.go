@@ -0,0 +1,182 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// paywallSignature identifies a paywall provider by its scripts and the elements it ships in the markup
+type paywallSignature struct {
+	provider  string
+	scripts   []string // Substrings of script src attributes
+	inline    []string // Substrings of inline scripts, lowercased
+	selectors []string // Elements of the provider's paywall
+}
+
+// paywallSignatures lists the recognized paywall providers in detection order
+var paywallSignatures = []paywallSignature{
+	{
+		provider: constants.PaywallProviderPiano,
+		scripts:  []string{"cdn.piano.io", "experience.piano.io", "api.piano.io"},
+		inline:   []string{"piano.io", `tp.push(["setaid"`},
+	},
+	{
+		provider: constants.PaywallProviderTinypass,
+		scripts:  []string{"cdn.tinypass.com", "experience.tinypass.com", "tinypass.min.js"},
+		inline:   []string{"tinypass.com", "tinypass.min.js"},
+	},
+	{
+		provider:  constants.PaywallProviderLaterPay,
+		scripts:   []string{"laterpay.net", "connectormwi.laterpay"},
+		inline:    []string{"laterpay"},
+		selectors: []string{"script#laterpay-connector"},
+	},
+}
+
+// detectPaywall looks for signs that the page's content is gated: paywall provider scripts,
+// JSON-LD declaring the content not free, class names marking gated content and a login form.
+// A paywall is suspected when one of them comes with fewer visible article words than
+// constants.PaywallMaxVisibleWords, a truncated body; on their own they are reported as evidence only.
+// Sign-in pages are short too, so a login form alone only counts on a page marked up as an article.
+func detectPaywall(doc *goquery.Document, hasLoginForm bool) (bool, []models.PaywallEvidence) {
+	var evidence []models.PaywallEvidence
+	for _, signature := range paywallSignatures {
+		if signature.matches(doc) {
+			evidence = append(evidence, models.PaywallEvidence{Signal: constants.PaywallSignalProviderScript, Detail: signature.provider})
+		}
+	}
+	if kind, ok := notAccessibleForFree(doc); ok {
+		evidence = append(evidence, models.PaywallEvidence{Signal: constants.PaywallSignalNotFree, Detail: kind})
+	}
+	if element := paywallClassMarker(doc); element != "" {
+		evidence = append(evidence, models.PaywallEvidence{Signal: constants.PaywallSignalClassMarker, Detail: element})
+	}
+	if hasLoginForm {
+		evidence = append(evidence, models.PaywallEvidence{Signal: constants.PaywallSignalLoginForm})
+	}
+	if len(evidence) == 0 {
+		return false, nil
+	}
+	if len(evidence) == 1 && hasLoginForm && findArticles(doc).Length() == 0 {
+		return false, evidence
+	}
+
+	words := articleWordCount(doc)
+	if words >= constants.PaywallMaxVisibleWords {
+		return false, evidence
+	}
+	evidence = append(evidence, models.PaywallEvidence{Signal: constants.PaywallSignalLowWordCount, Detail: fmt.Sprintf("%d words", words)})
+	return true, evidence
+}
+
+// matches reports whether the page loads the provider's scripts or ships its paywall elements
+func (p paywallSignature) matches(doc *goquery.Document) bool {
+	if hasScriptSrc(doc, p.scripts) {
+		return true
+	}
+	if len(p.selectors) > 0 && doc.Find(strings.Join(p.selectors, ", ")).Length() > 0 {
+		return true
+	}
+	found := false
+	doc.Find("script:not([src])").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		found = containsAny(strings.ToLower(s.Text()), p.inline)
+		return !found
+	})
+	return found
+}
+
+// notAccessibleForFree looks through the page's JSON-LD for an item declaring
+// isAccessibleForFree false, and returns its @type
+func notAccessibleForFree(doc *goquery.Document) (string, bool) {
+	kind, found := "", false
+	doc.Find("script[type='application/ld+json' i]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		var data any
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return true
+		}
+		kind, found = findNotFree(data)
+		return !found
+	})
+	return kind, found
+}
+
+// findNotFree walks a JSON-LD value, graphs and nested parts included, for an item whose
+// isAccessibleForFree is false, written as a boolean, a string or a schema.org URL
+func findNotFree(value any) (string, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		if free, ok := v["isAccessibleForFree"]; ok && isFalse(free) {
+			kind, _ := v["@type"].(string)
+			return kind, true
+		}
+		for _, child := range v {
+			if kind, ok := findNotFree(child); ok {
+				return kind, true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if kind, ok := findNotFree(child); ok {
+				return kind, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isFalse reports whether a JSON-LD value is false
+func isFalse(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return !v
+	case string:
+		v = strings.ToLower(strings.TrimSpace(v))
+		return v == "false" || strings.HasSuffix(v, "schema.org/false")
+	}
+	return false
+}
+
+// paywallClassMarker returns the label of the first element with a class name marking gated or
+// metered content, or "". Class names are matched whole, so no-paywall marks nothing.
+func paywallClassMarker(doc *goquery.Document) string {
+	label := ""
+	doc.Find("body [class]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, class := range strings.Fields(strings.ToLower(s.AttrOr("class", ""))) {
+			if slices.Contains(constants.PaywallClassMarkers, class) {
+				label = elementLabel(s)
+				break
+			}
+		}
+		return label == ""
+	})
+	return label
+}
+
+// findArticles returns the page's article markup
+func findArticles(doc *goquery.Document) *goquery.Selection {
+	return doc.Find("article, [itemprop='articleBody']")
+}
+
+// articleWordCount counts the visible words of the page's articles, or of its body when it
+// marks up no article, so that navigation and footers do not make a teaser look complete
+func articleWordCount(doc *goquery.Document) int {
+	articles := findArticles(doc)
+	if articles.Length() == 0 {
+		return len(strings.Fields(visibleText(doc)))
+	}
+	var b strings.Builder
+	// Articles nested in one another are counted once
+	articles.Not("article article, article [itemprop='articleBody'], [itemprop='articleBody'] article").Each(func(_ int, s *goquery.Selection) {
+		for _, node := range s.Nodes {
+			appendText(&b, node, " ")
+		}
+	})
+	return len(strings.Fields(b.String()))
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_Paywall(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name      string
+		fixture   string
+		suspected bool
+		evidence  []models.PaywallEvidence
+	}{
+		{
+			name:      "Piano teaser",
+			fixture:   "paywall_piano.html",
+			suspected: true,
+			evidence: []models.PaywallEvidence{
+				{Signal: constants.PaywallSignalProviderScript, Detail: constants.PaywallProviderPiano},
+				{Signal: constants.PaywallSignalProviderScript, Detail: constants.PaywallProviderTinypass},
+				{Signal: constants.PaywallSignalNotFree, Detail: "NewsArticle"},
+				{Signal: constants.PaywallSignalClassMarker, Detail: "div.paywall"},
+				{Signal: constants.PaywallSignalLowWordCount, Detail: "44 words"}, // The article, without the navigation and footer
+			},
+		},
+		{
+			name:    "Free article",
+			fixture: "paywall_free.html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := analyzer.AnalyzeHTML(context.Background(), loadFixture(t, tt.fixture), "https://ledger.example.com/news/story", models.AnalyzeOptions{SkipLinkCheck: true})
+			require.NoError(t, err)
+			assert.Equal(t, tt.suspected, result.PaywallSuspected)
+			assert.Equal(t, tt.evidence, result.PaywallEvidence)
+		})
+	}
+}
+
+func TestDetectPaywall(t *testing.T) {
+	longArticle := "<article>" + strings.Repeat("<p>Word word word word word word word word word word.</p>", 25) + "</article>"
+
+	tests := []struct {
+		name         string
+		html         string
+		hasLoginForm bool
+		suspected    bool
+		signals      []string
+	}{
+		{
+			name:    "Short page without signals",
+			html:    `<html><body><p>Contact us</p></body></html>`,
+			signals: nil,
+		},
+		{
+			name:    "Provider script on a full article",
+			html:    `<html><head><script src="https://cdn.piano.io/api/composer.js"></script></head><body>` + longArticle + `</body></html>`,
+			signals: []string{constants.PaywallSignalProviderScript},
+		},
+		{
+			name:         "Login form and truncated body",
+			html:         `<html><body><article><p>Members only.</p></article><form><input type="email"><input type="password"></form></body></html>`,
+			hasLoginForm: true,
+			suspected:    true,
+			signals:      []string{constants.PaywallSignalLoginForm, constants.PaywallSignalLowWordCount},
+		},
+		{
+			name:         "Login form alone on a sign-in page",
+			html:         `<html><body><h1>Sign in</h1><form><input type="email"><input type="password"></form></body></html>`,
+			hasLoginForm: true,
+			signals:      []string{constants.PaywallSignalLoginForm},
+		},
+		{
+			name:    "Class names are matched whole",
+			html:    `<html><body><div class="story no-paywall paywalled-ad"><p>Teaser</p></div></body></html>`,
+			signals: nil,
+		},
+		{
+			name:      "LaterPay connector",
+			html:      `<html><body><script type="application/json" id="laterpay-connector">{}</script><article><p>Teaser</p></article></body></html>`,
+			suspected: true,
+			signals:   []string{constants.PaywallSignalProviderScript, constants.PaywallSignalLowWordCount},
+		},
+		{
+			name:      "Metered content class",
+			html:      `<html><body><div class="story meteredContent"><p>Teaser</p></div></body></html>`,
+			suspected: true,
+			signals:   []string{constants.PaywallSignalClassMarker, constants.PaywallSignalLowWordCount},
+		},
+		{
+			name:      "Not free in a JSON-LD graph",
+			html:      `<html><head><script type="application/ld+json">{"@graph":[{"@type":"WebSite"},{"@type":"Article","isAccessibleForFree":false}]}</script></head><body><p>Teaser</p></body></html>`,
+			suspected: true,
+			signals:   []string{constants.PaywallSignalNotFree, constants.PaywallSignalLowWordCount},
+		},
+		{
+			name:    "Free and malformed JSON-LD",
+			html:    `<html><head><script type="application/ld+json">{"isAccessibleForFree": "True"}</script><script type="application/ld+json">{not json</script></head><body><p>Teaser</p></body></html>`,
+			signals: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			suspected, evidence := detectPaywall(doc, tt.hasLoginForm)
+			assert.Equal(t, tt.suspected, suspected)
+			var signals []string
+			for _, e := range evidence {
+				signals = append(signals, e.Signal)
+			}
+			assert.Equal(t, tt.signals, signals)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// errTargetBlocked stops a request to a host the enforced target policy blocks
+var errTargetBlocked = errors.New("blocked by the target policy")
+
+// targetPolicy decides which hosts and ports analyses may send requests to. In dry-run mode every
+// request is allowed and the would-be blocks are logged, counted and reported instead, so
+// switching the mode to enforce is the only change needed to start blocking.
+// A nil policy allows everything.
+type targetPolicy struct {
+	mode    string
+	blocked []string
+	allowed []string
+	ports   map[int]bool // Allowed ports; empty allows every port
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+}
+
+// newTargetPolicy compiles analyzer.target_policy, returning nil when it has no rules
+func newTargetPolicy(cfg config.TargetPolicyConfig, logger *zap.Logger, m *metrics.Metrics) *targetPolicy {
+	policy := &targetPolicy{
+		mode:    cfg.Mode,
+		blocked: normalizeHostRules(cfg.BlockedHosts),
+		allowed: normalizeHostRules(cfg.AllowedHosts),
+		ports:   make(map[int]bool, len(cfg.AllowedPorts)),
+		logger:  logger,
+		metrics: m,
+	}
+	for _, port := range cfg.AllowedPorts {
+		policy.ports[port] = true
+	}
+	if len(policy.blocked) == 0 && len(policy.allowed) == 0 && len(policy.ports) == 0 {
+		return nil
+	}
+	if policy.mode == "" {
+		policy.mode = constants.DefaultTargetPolicyMode
+	}
+	return policy
+}
+
+func normalizeHostRules(hosts []string) []string {
+	var rules []string
+	for _, host := range hosts {
+		if host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), "."); host != "" {
+			rules = append(rules, host)
+		}
+	}
+	return rules
+}
+
+// matchHostPattern reports whether host matches pattern, an exact host or *.example.com
+// for the subdomains of example.com
+func matchHostPattern(host, pattern string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// hostMatches reports whether host is rule or one of its subdomains
+func hostMatches(host, rule string) bool {
+	return matchHostPattern(host, rule) || matchHostPattern(host, "*."+rule)
+}
+
+// rule returns the rule that blocks host, or "" when the host is allowed
+func (p *targetPolicy) rule(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, blocked := range p.blocked {
+		if hostMatches(host, blocked) {
+			return constants.TargetPolicyRuleBlocked + ":" + blocked
+		}
+	}
+	if len(p.allowed) == 0 {
+		return ""
+	}
+	for _, allowed := range p.allowed {
+		if hostMatches(host, allowed) {
+			return ""
+		}
+	}
+	return constants.TargetPolicyRuleNotAllowed
+}
+
+// portRule returns the rule that blocks a request to u's port, or "" when the port is allowed
+func (p *targetPolicy) portRule(u *url.URL) string {
+	if len(p.ports) == 0 || p.ports[effectivePort(u)] {
+		return ""
+	}
+	return constants.TargetPolicyRulePortNotAllowed
+}
+
+// check evaluates a request URL. An enforced block returns an error wrapping errTargetBlocked;
+// in dry-run mode the block is recorded as a policy warning of the analysis and nil is returned.
+func (p *targetPolicy) check(ctx context.Context, u *url.URL) error {
+	if p == nil {
+		return nil
+	}
+	rule := p.rule(u.Hostname())
+	if rule == "" {
+		rule = p.portRule(u)
+	}
+	if rule == "" {
+		return nil
+	}
+
+	p.metrics.PolicyViolations.WithLabelValues(p.mode).Inc()
+	requestURL := models.StripCredentials(u.String())
+	if p.mode == constants.TargetPolicyModeDryRun {
+		loggerFrom(ctx, p.logger).Warn("Target policy would block request",
+			zap.String("url", requestURL),
+			zap.String("rule", rule),
+		)
+		policyWarningsFrom(ctx).add(models.PolicyWarning{URL: requestURL, Rule: rule})
+		return nil
+	}
+
+	loggerFrom(ctx, p.logger).Warn("Target policy blocked request",
+		zap.String("url", requestURL),
+		zap.String("rule", rule),
+	)
+	return fmt.Errorf("%s %w (%s)", normalizedHost(u), errTargetBlocked, rule)
+}
+
+// targetBlockedError reports a page fetch the target policy stopped, at the target or one of its redirects
+func targetBlockedError(err error) error {
+	return &AnalysisError{
+		Code:    constants.ErrorCodeTargetBlocked,
+		Status:  constants.StatusForbidden,
+		Message: "Target is blocked by the target policy",
+		Err:     err,
+	}
+}
+
+// policyWarnings collects the would-be blocks of one analysis in dry-run mode
+type policyWarnings struct {
+	mu       sync.Mutex
+	warnings map[string]models.PolicyWarning
+}
+
+type policyWarningsContextKey struct{}
+
+// withPolicyWarnings attaches an empty policy warning list for one analysis to the context
+func withPolicyWarnings(ctx context.Context) (context.Context, *policyWarnings) {
+	warnings := &policyWarnings{warnings: make(map[string]models.PolicyWarning)}
+	return context.WithValue(ctx, policyWarningsContextKey{}, warnings), warnings
+}
+
+// policyWarningsFrom returns the policy warnings of the analysis running under ctx, if any
+func policyWarningsFrom(ctx context.Context) *policyWarnings {
+	warnings, _ := ctx.Value(policyWarningsContextKey{}).(*policyWarnings)
+	return warnings
+}
+
+// add records a warning once per URL
+func (w *policyWarnings) add(warning models.PolicyWarning) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warnings[warning.URL] = warning
+}
+
+// list returns the warnings sorted by URL, or nil when there are none
+func (w *policyWarnings) list() []models.PolicyWarning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.warnings) == 0 {
+		return nil
+	}
+	list := make([]models.PolicyWarning, 0, len(w.warnings))
+	for _, warning := range w.warnings {
+		list = append(list, warning)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+	return list
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newPolicyServer serves a page linking to itself under the localhost name
+func newPolicyServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		link := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/page"
+		w.Write([]byte(`<html><head><title>Policy</title></head><body><a href="` + link + `">Link</a></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// policyConfig applies policy to analyses following up to five redirects
+func policyConfig(policy config.TargetPolicyConfig) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Analyzer.MaxRedirects = 5
+		cfg.Analyzer.TargetPolicy = policy
+	}
+}
+
+func TestTargetPolicy_Rule(t *testing.T) {
+	policy := newTargetPolicy(config.TargetPolicyConfig{
+		BlockedHosts: []string{" Ads.Example.com "},
+		AllowedHosts: []string{"example.com"},
+	}, nil, nil)
+	require.NotNil(t, policy)
+	assert.Equal(t, constants.TargetPolicyModeEnforce, policy.mode)
+
+	assert.Equal(t, "", policy.rule("example.com"))
+	assert.Equal(t, "", policy.rule("www.EXAMPLE.com."))
+	assert.Equal(t, "blocked_hosts:ads.example.com", policy.rule("ads.example.com"))
+	assert.Equal(t, "blocked_hosts:ads.example.com", policy.rule("cdn.ads.example.com"))
+	assert.Equal(t, "allowed_hosts", policy.rule("example.org"))
+	assert.Equal(t, "allowed_hosts", policy.rule("notexample.com"))
+
+	assert.Nil(t, newTargetPolicy(config.TargetPolicyConfig{BlockedHosts: []string{" "}}, nil, nil), "no rules, no policy")
+}
+
+func TestTargetPolicy_PortRule(t *testing.T) {
+	policy := newTargetPolicy(config.TargetPolicyConfig{AllowedPorts: []int{80, 443, 8080}}, nil, nil)
+	require.NotNil(t, policy)
+
+	for target, expected := range map[string]string{
+		"http://example.com/":       "",
+		"https://example.com/":      "",
+		"https://example.com:443/":  "",
+		"http://example.com:8080/":  "",
+		"https://example.com:8443/": constants.TargetPolicyRulePortNotAllowed,
+		"http://example.com:22/":    constants.TargetPolicyRulePortNotAllowed,
+	} {
+		u, err := url.Parse(target)
+		require.NoError(t, err)
+		assert.Equal(t, expected, policy.portRule(u), target)
+	}
+}
+
+func TestAnalyzer_Analyze_TargetPolicy(t *testing.T) {
+	server := newPolicyServer(t)
+	localhost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	tests := []struct {
+		name             string
+		policy           config.TargetPolicyConfig
+		target           string
+		expectedCode     string
+		expectedSkipped  map[string]int
+		expectedWarnings []models.PolicyWarning
+		violations       float64
+	}{
+		{
+			name:         "Enforced block of the target",
+			policy:       config.TargetPolicyConfig{Mode: constants.TargetPolicyModeEnforce, BlockedHosts: []string{"127.0.0.1"}},
+			target:       server.URL,
+			expectedCode: constants.ErrorCodeTargetBlocked,
+			violations:   1,
+		},
+		{
+			name:         "Enforced block of a redirect",
+			policy:       config.TargetPolicyConfig{Mode: constants.TargetPolicyModeEnforce, BlockedHosts: []string{"localhost"}},
+			target:       server.URL + "/moved",
+			expectedCode: constants.ErrorCodeTargetBlocked,
+			violations:   1,
+		},
+		{
+			name:            "Enforced block of a link",
+			policy:          config.TargetPolicyConfig{Mode: constants.TargetPolicyModeEnforce, BlockedHosts: []string{"localhost"}},
+			target:          server.URL,
+			expectedSkipped: map[string]int{constants.LinkSkipReasonPolicy: 1},
+			violations:      1,
+		},
+		{
+			name:             "Dry run of a link outside the allow-list",
+			policy:           config.TargetPolicyConfig{Mode: constants.TargetPolicyModeDryRun, AllowedHosts: []string{"127.0.0.1"}},
+			target:           server.URL,
+			expectedWarnings: []models.PolicyWarning{{URL: localhost + "/page", Rule: constants.TargetPolicyRuleNotAllowed}},
+			violations:       1,
+		},
+		{
+			name:         "Enforced block of the target port",
+			policy:       config.TargetPolicyConfig{Mode: constants.TargetPolicyModeEnforce, AllowedPorts: []int{80, 443}},
+			target:       server.URL,
+			expectedCode: constants.ErrorCodeTargetBlocked,
+			violations:   1,
+		},
+		{
+			name:   "Dry run of ports outside the allow-list",
+			policy: config.TargetPolicyConfig{Mode: constants.TargetPolicyModeDryRun, AllowedPorts: []int{80, 443}},
+			target: server.URL,
+			expectedWarnings: []models.PolicyWarning{
+				{URL: server.URL, Rule: constants.TargetPolicyRulePortNotAllowed},
+				{URL: localhost + "/page", Rule: constants.TargetPolicyRulePortNotAllowed},
+			},
+			violations: 2,
+		},
+		{
+			name:   "Dry run of the target and its link",
+			policy: config.TargetPolicyConfig{Mode: constants.TargetPolicyModeDryRun, BlockedHosts: []string{"127.0.0.1", "localhost"}},
+			target: server.URL,
+			expectedWarnings: []models.PolicyWarning{
+				{URL: server.URL, Rule: "blocked_hosts:127.0.0.1"},
+				{URL: localhost + "/page", Rule: "blocked_hosts:localhost"},
+			},
+			violations: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := newTestAnalyzer(t, policyConfig(tt.policy))
+
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), tt.target, models.AnalyzeOptions{})
+			violations := testutil.ToFloat64(analyzer.metrics.PolicyViolations.WithLabelValues(tt.policy.Mode))
+			assert.Equal(t, tt.violations, violations)
+
+			if tt.expectedCode != "" {
+				var analysisErr *AnalysisError
+				require.True(t, errors.As(err, &analysisErr))
+				assert.Equal(t, tt.expectedCode, analysisErr.Code)
+				assert.Equal(t, constants.StatusForbidden, analysisErr.Status)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Policy", result.Title)
+			assert.Equal(t, tt.expectedWarnings, result.PolicyWarnings)
+			if tt.expectedSkipped != nil {
+				assert.Equal(t, tt.expectedSkipped, result.Links.Skipped)
+				assert.Equal(t, 0, result.Links.Inaccessible)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Analyze_TargetPolicyUnset(t *testing.T) {
+	server := newPolicyServer(t)
+	analyzer := newTestAnalyzer(t, maxRedirects(5))
+	assert.Nil(t, analyzer.policy)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, result.PolicyWarnings)
+	assert.Empty(t, result.Links.Skipped)
+}
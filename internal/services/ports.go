@@ -0,0 +1,33 @@
+package services
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// defaultPorts are the ports implied by each scheme
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// normalizedHost returns the host of u without the default port of its scheme, so that
+// example.com and example.com:443 compare equal for https while other ports stay significant
+func normalizedHost(u *url.URL) string {
+	if port := u.Port(); port != "" && port == defaultPorts[u.Scheme] {
+		return withPort(u.Hostname(), "")
+	}
+	return u.Host
+}
+
+// stripDefaultPort removes the default port of the scheme from u in place
+func stripDefaultPort(u *url.URL) {
+	u.Host = normalizedHost(u)
+}
+
+// effectivePort returns the port a request to u connects to, or 0 when it is unknown
+func effectivePort(u *url.URL) int {
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[u.Scheme]
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestNormalizedHost(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+		port     int
+	}{
+		{url: "https://example.com:443/", expected: "example.com", port: 443},
+		{url: "http://example.com:80/", expected: "example.com", port: 80},
+		{url: "https://example.com/", expected: "example.com", port: 443},
+		{url: "http://example.com:443/", expected: "example.com:443", port: 443},
+		{url: "https://example.com:80/", expected: "example.com:80", port: 80},
+		{url: "http://internal.example:8443/", expected: "internal.example:8443", port: 8443},
+		{url: "https://[::1]:443/", expected: "[::1]", port: 443},
+		{url: "https://[::1]:8443/", expected: "[::1]:8443", port: 8443},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, normalizedHost(u))
+			assert.Equal(t, tt.port, effectivePort(u))
+		})
+	}
+}
+
+func TestAnalyzer_ClassifyLinks_DefaultPorts(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	tests := []struct {
+		name             string
+		base             string
+		expectedInternal []string
+		expectedExternal []string
+	}{
+		{
+			name:             "Default ports on the links",
+			base:             "https://example.com/",
+			expectedInternal: []string{"https://example.com/a", "http://example.com/b", "https://example.com/e"},
+			expectedExternal: []string{"https://example.com:8443/c", "http://example.com:443/d", "http://example.com:8080/f"},
+		},
+		{
+			name:             "Default port on the base",
+			base:             "https://example.com:443/",
+			expectedInternal: []string{"https://example.com/a", "http://example.com/b", "https://example.com/e"},
+			expectedExternal: []string{"https://example.com:8443/c", "http://example.com:443/d", "http://example.com:8080/f"},
+		},
+		{
+			name:             "Non-default port on the base",
+			base:             "https://example.com:8443/",
+			expectedInternal: []string{"https://example.com:8443/c", "https://example.com:8443/e"},
+			expectedExternal: []string{"https://example.com/a", "http://example.com/b", "http://example.com:443/d", "http://example.com:8080/f"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<a href="https://example.com:443/a">A</a>` +
+				`<a href="http://example.com:80/b">B</a><a href="https://example.com:8443/c">C</a>` +
+				`<a href="http://example.com:443/d">D</a><a href="/e">E</a><a href="http://example.com:8080/f">F</a>`))
+			require.NoError(t, err)
+			baseURL, err := analyzer.parseAndValidateURL(tt.base)
+			require.NoError(t, err)
+
+			_, internal, external := analyzer.classifyLinks(context.Background(), doc, baseURL, domLimits{})
+			assert.Equal(t, tt.expectedInternal, internal)
+			assert.Equal(t, tt.expectedExternal, external)
+		})
+	}
+}
+
+func TestCacheKey_DefaultPort(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	key := func(target string) string {
+		parsed, err := analyzer.parseAndValidateURL(target)
+		require.NoError(t, err)
+		return cacheKey(canonicalURL(parsed), models.AnalyzeOptions{})
+	}
+
+	assert.Equal(t, key("https://example.com/page"), key("https://example.com:443/page"))
+	assert.Equal(t, key("http://example.com/page"), key("http://example.com:80/page"))
+	assert.NotEqual(t, key("https://example.com/page"), key("https://example.com:8443/page"))
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// proxyPools are the egress proxies of analyzer.proxies, by lowercase name
+type proxyPools map[string]*url.URL
+
+// newProxyPools parses the configured proxy URLs. Entries without a usable URL are logged and
+// left out, so selecting them fails like selecting an unknown pool.
+func newProxyPools(cfg map[string]string, logger *zap.Logger) proxyPools {
+	if len(cfg) == 0 {
+		return nil
+	}
+	pools := make(proxyPools, len(cfg))
+	for name, rawURL := range cfg {
+		proxyURL, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil || proxyURL.Host == "" || !slices.Contains(constants.SupportedProxySchemes, proxyURL.Scheme) {
+			logger.Warn("Ignoring egress proxy without a valid URL", zap.String("egress", name))
+			continue
+		}
+		pools[strings.ToLower(name)] = proxyURL
+	}
+	return pools
+}
+
+// names returns the pool names, sorted
+func (p proxyPools) names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// transports returns a clone of base per pool, sending its requests through the pool's proxy
+func (p proxyPools) transports(base *http.Transport) map[string]*http.Transport {
+	if len(p) == 0 {
+		return nil
+	}
+	transports := make(map[string]*http.Transport, len(p))
+	for name, proxyURL := range p {
+		proxied := base.Clone()
+		proxied.Proxy = http.ProxyURL(proxyURL)
+		transports[name] = proxied
+	}
+	return transports
+}
+
+type egressContextKey struct{}
+
+// withEgress attaches the proxy pool the analysis goes through to the context; "" attaches nothing
+func withEgress(ctx context.Context, egress string) context.Context {
+	if egress == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, egressContextKey{}, egress)
+}
+
+// egressFrom returns the proxy pool of the analysis running under ctx, or "" for direct requests
+func egressFrom(ctx context.Context) string {
+	egress, _ := ctx.Value(egressContextKey{}).(string)
+	return egress
+}
+
+// selectEgress checks options.egress against the configured pools and returns its normalized name
+func (a *Analyzer) selectEgress(egress string) (string, error) {
+	if egress == "" {
+		return "", nil
+	}
+	name := strings.ToLower(strings.TrimSpace(egress))
+	if _, ok := a.proxies[name]; ok {
+		return name, nil
+	}
+	message := "egress is not available: no egress proxies are configured"
+	if len(a.proxies) > 0 {
+		message = fmt.Sprintf("egress %q is not configured; available: %s", egress, strings.Join(a.proxies.names(), ", "))
+	}
+	return "", &AnalysisError{
+		Code:    constants.ErrorCodeValidation,
+		Status:  constants.StatusBadRequest,
+		Message: message,
+	}
+}
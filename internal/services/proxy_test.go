@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// geoHost is reachable through the test proxies only; the .test TLD never resolves
+const geoHost = "geo.example.test"
+
+// testProxy is a forward proxy answering for geoHost itself, with a page titled after the
+// proxy, and recording the URLs requested through it
+type testProxy struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	urls []string
+}
+
+func newTestProxy(t *testing.T, title string) *testProxy {
+	proxy := &testProxy{}
+	proxy.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxy.mu.Lock()
+		proxy.urls = append(proxy.urls, r.URL.String())
+		proxy.mu.Unlock()
+		if r.URL.Host != geoHost {
+			http.Error(w, "unexpected host "+r.URL.Host, http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set(constants.HeaderContentType, "text/html")
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><a href="/about">About</a></body></html>`, title)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func (p *testProxy) requested() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.urls)
+}
+
+// egressProxies configures the named egress proxy pools
+func egressProxies(proxies map[string]string) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Analyzer.Proxies = proxies
+	}
+}
+
+func TestAnalyzer_Egress(t *testing.T) {
+	us := newTestProxy(t, "United States")
+	de := newTestProxy(t, "Deutschland")
+	analyzer := newTestAnalyzer(t, egressProxies(map[string]string{"US": us.URL, "de": de.URL}))
+	target := "http://" + geoHost + "/"
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), target, models.AnalyzeOptions{Egress: "de"})
+	require.NoError(t, err)
+	assert.Equal(t, "Deutschland", result.Title)
+	assert.Equal(t, "de", result.Fetch.Egress)
+	assert.Equal(t, 1, result.Links.Internal)
+	assert.Equal(t, 0, result.Links.Inaccessible, "the link check went through the proxy too")
+	assert.ElementsMatch(t, []string{target, target + "about"}, de.requested())
+	assert.Empty(t, us.requested())
+
+	// Pool names are matched case-insensitively
+	result, err = analyzer.AnalyzeWithOptions(context.Background(), target, models.AnalyzeOptions{Egress: "Us", SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Equal(t, "United States", result.Title)
+	assert.Equal(t, "us", result.Fetch.Egress)
+	assert.Equal(t, []string{target}, us.requested())
+	assert.Len(t, de.requested(), 2)
+}
+
+func TestAnalyzer_Egress_Unknown(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxies  map[string]string
+		expected string
+	}{
+		{
+			name:     "Unknown pool",
+			proxies:  map[string]string{"us": "http://127.0.0.1:1", "de": "http://127.0.0.1:2", "fr": "not a proxy"},
+			expected: `egress "jp" is not configured; available: de, us`,
+		},
+		{
+			name:     "No pools",
+			expected: "egress is not available: no egress proxies are configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := newTestAnalyzer(t, egressProxies(tt.proxies))
+
+			_, err := analyzer.AnalyzeWithOptions(context.Background(), "http://"+geoHost+"/", models.AnalyzeOptions{Egress: "jp"})
+
+			var analysisErr *AnalysisError
+			require.True(t, errors.As(err, &analysisErr))
+			assert.Equal(t, constants.ErrorCodeValidation, analysisErr.Code)
+			assert.Equal(t, constants.StatusBadRequest, analysisErr.Status)
+			assert.Equal(t, tt.expected, analysisErr.Message)
+
+			_, err = analyzer.AnalyzeHTML(context.Background(), "<html></html>", "http://"+geoHost+"/", models.AnalyzeOptions{Egress: "jp"})
+			assert.ErrorAs(t, err, &analysisErr)
+		})
+	}
+}
+
+func TestCacheKey_Egress(t *testing.T) {
+	assert.Equal(t, "http://example.com|"+constants.CacheVariantEgress+"=de", cacheKey("http://example.com", models.AnalyzeOptions{Egress: "DE"}))
+	assert.NotEqual(t, cacheKey("http://example.com", models.AnalyzeOptions{Egress: "us"}), cacheKey("http://example.com", models.AnalyzeOptions{Egress: "de"}))
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// webManifest holds the members of a web app manifest the analysis reports
+type webManifest struct {
+	Name      string            `json:"name"`
+	ShortName string            `json:"short_name"`
+	Display   string            `json:"display"`
+	Icons     []json.RawMessage `json:"icons"`
+}
+
+// manifestLink returns the href of the first manifest link, or ""
+func manifestLink(doc *goquery.Document) string {
+	href := ""
+	doc.Find("link[rel]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if value := strings.TrimSpace(s.AttrOr("href", "")); hasRelToken(s, "manifest") && value != "" {
+			href = value
+			return false
+		}
+		return true
+	})
+	return href
+}
+
+// hasServiceWorkerHint reports whether an inline script uses the service worker API. Workers
+// registered from external scripts are missed, so the hint never decides installability.
+func hasServiceWorkerHint(doc *goquery.Document) bool {
+	found := false
+	doc.Find("script:not([src])").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		found = strings.Contains(s.Text(), "serviceWorker")
+		return !found
+	})
+	return found
+}
+
+// checkPWA reports the page's web app manifest and whether the page is installable. The
+// manifest is fetched and parsed only when fetchManifest is set; otherwise a declared manifest
+// served over HTTPS is taken as installable.
+func (a *Analyzer) checkPWA(ctx context.Context, doc *goquery.Document, pageURL *url.URL, fetchManifest bool) *models.PWA {
+	pwa := &models.PWA{
+		HTTPS:             strings.EqualFold(pageURL.Scheme, "https"),
+		ServiceWorkerHint: hasServiceWorkerHint(doc),
+	}
+	href := manifestLink(doc)
+	if href == "" {
+		return pwa
+	}
+	pwa.HasManifest = true
+
+	manifestURL, err := pageURL.Parse(href)
+	if err != nil || manifestURL.Host == "" {
+		pwa.Error = fmt.Sprintf("manifest URL %q is invalid", href)
+		return pwa
+	}
+	pwa.ManifestURL = manifestURL.String()
+	if !fetchManifest {
+		pwa.Installable = pwa.HTTPS
+		return pwa
+	}
+
+	manifest, err := a.fetchManifest(ctx, manifestURL)
+	if err != nil {
+		pwa.Error = err.Error()
+		return pwa
+	}
+	pwa.ManifestFetched = true
+	pwa.Name = cmp.Or(manifest.Name, manifest.ShortName)
+	pwa.Display = cmp.Or(manifest.Display, constants.ManifestDisplayBrowser)
+	pwa.IconCount = len(manifest.Icons)
+	pwa.Installable = pwa.HTTPS && pwa.Name != "" && pwa.IconCount > 0 &&
+		slices.Contains(constants.InstallableDisplayModes, pwa.Display)
+	return pwa
+}
+
+// fetchManifest fetches and parses a web app manifest. The fetch counts against the outbound
+// budget, and manifests over constants.MaxManifestBytes are rejected.
+func (a *Analyzer) fetchManifest(ctx context.Context, manifestURL *url.URL) (*webManifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	if err := a.policy.check(ctx, manifestURL); err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	if err := budgetFrom(ctx).acquire(ctx); err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	wire := &countingReader{r: resp.Body}
+	body, err := io.ReadAll(io.LimitReader(wire, constants.MaxManifestBytes+1))
+	statsFrom(ctx).received(constants.FetchSourcePage, wire.n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if resp.StatusCode != constants.StatusOK {
+		return nil, fmt.Errorf("manifest returned status code %d", resp.StatusCode)
+	}
+	if len(body) > constants.MaxManifestBytes {
+		return nil, fmt.Errorf("manifest exceeds maximum size of %d bytes", constants.MaxManifestBytes)
+	}
+
+	var manifest webManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &manifest, nil
+}
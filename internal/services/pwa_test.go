@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+const pwaPage = `<html><head>
+<title>Notes</title>
+<link rel="manifest" href="/app.webmanifest">
+<script>if ("serviceWorker" in navigator) { navigator.serviceWorker.register("/sw.js"); }</script>
+</head><body><h1>Notes</h1></body></html>`
+
+const pwaManifest = `{
+	"name": "Notes App",
+	"short_name": "Notes",
+	"display": "standalone",
+	"start_url": "/",
+	"icons": [
+		{"src": "/icon-192.png", "sizes": "192x192", "type": "image/png"},
+		{"src": "/icon-512.png", "sizes": "512x512", "type": "image/png"}
+	]
+}`
+
+// newPWAServer serves pwaPage at / and manifest at /app.webmanifest over TLS, counting the manifest fetches
+func newPWAServer(t *testing.T, manifest string, fetches *atomic.Int32) *httptest.Server {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/app.webmanifest" {
+			fetches.Add(1)
+			w.Header().Set(constants.HeaderContentType, "application/manifest+json")
+			w.Write([]byte(manifest))
+			return
+		}
+		w.Write([]byte(pwaPage))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_PWA(t *testing.T) {
+	tests := []struct {
+		name          string
+		manifest      string
+		fetchManifest bool
+		maxOutbound   int
+		expected      func(manifestURL string) *models.PWA
+		fetches       int32
+	}{
+		{
+			name:     "Manifest declared",
+			manifest: pwaManifest,
+			expected: func(manifestURL string) *models.PWA {
+				return &models.PWA{HasManifest: true, ManifestURL: manifestURL, HTTPS: true, ServiceWorkerHint: true, Installable: true}
+			},
+		},
+		{
+			name:          "Manifest fetched",
+			manifest:      pwaManifest,
+			fetchManifest: true,
+			expected: func(manifestURL string) *models.PWA {
+				return &models.PWA{
+					HasManifest: true, ManifestURL: manifestURL, ManifestFetched: true,
+					Name: "Notes App", Display: "standalone", IconCount: 2,
+					HTTPS: true, ServiceWorkerHint: true, Installable: true,
+				}
+			},
+			fetches: 1,
+		},
+		{
+			name:          "Browser display mode",
+			manifest:      `{"short_name": "Notes", "icons": [{"src": "/icon.png"}]}`,
+			fetchManifest: true,
+			expected: func(manifestURL string) *models.PWA {
+				return &models.PWA{
+					HasManifest: true, ManifestURL: manifestURL, ManifestFetched: true,
+					Name: "Notes", Display: constants.ManifestDisplayBrowser, IconCount: 1,
+					HTTPS: true, ServiceWorkerHint: true,
+				}
+			},
+			fetches: 1,
+		},
+		{
+			name:          "Invalid manifest",
+			manifest:      `{"name": "Notes", "icons": {}}`,
+			fetchManifest: true,
+			expected: func(manifestURL string) *models.PWA {
+				return &models.PWA{
+					HasManifest: true, ManifestURL: manifestURL, HTTPS: true, ServiceWorkerHint: true,
+					Error: "invalid manifest: ",
+				}
+			},
+			fetches: 1,
+		},
+		{
+			name:          "Oversized manifest",
+			manifest:      `{"name": "` + strings.Repeat("x", constants.MaxManifestBytes) + `"}`,
+			fetchManifest: true,
+			expected: func(manifestURL string) *models.PWA {
+				return &models.PWA{
+					HasManifest: true, ManifestURL: manifestURL, HTTPS: true, ServiceWorkerHint: true,
+					Error: "manifest exceeds maximum size of 65536 bytes",
+				}
+			},
+			fetches: 1,
+		},
+		{
+			name:          "Budget exhausted by the page fetch",
+			manifest:      pwaManifest,
+			fetchManifest: true,
+			maxOutbound:   1,
+			expected: func(manifestURL string) *models.PWA {
+				return &models.PWA{
+					HasManifest: true, ManifestURL: manifestURL, HTTPS: true, ServiceWorkerHint: true,
+					Error: "failed to fetch manifest: " + errBudgetExhausted.Error(),
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			server := newPWAServer(t, tt.manifest, &fetches)
+			analyzer := newTestAnalyzer(t, outboundBudget(tt.maxOutbound))
+			transport := analyzer.httpClient.Transport.(*resolvingTransport).base
+			transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{
+				SkipLinkCheck: true,
+				FetchManifest: tt.fetchManifest,
+			})
+
+			require.NoError(t, err)
+			expected := tt.expected(server.URL + "/app.webmanifest")
+			if expected.Error != "" {
+				// Errors are compared by prefix; the decoder's wording is its own
+				assert.True(t, strings.HasPrefix(result.PWA.Error, expected.Error), "got %q", result.PWA.Error)
+				result.PWA.Error = expected.Error
+			}
+			assert.Equal(t, expected, result.PWA)
+			assert.Equal(t, tt.fetches, fetches.Load())
+		})
+	}
+}
+
+func TestAnalyzer_CheckPWA_Detection(t *testing.T) {
+	analyzer := newTestAnalyzer(t, nil)
+
+	tests := []struct {
+		name     string
+		html     string
+		pageURL  string
+		expected *models.PWA
+	}{
+		{
+			name:     "No manifest",
+			html:     `<html><head><script src="/sw-register.js"></script></head></html>`,
+			pageURL:  "https://example.com/",
+			expected: &models.PWA{HTTPS: true},
+		},
+		{
+			name:     "Served over HTTP",
+			html:     `<link rel="manifest" href="manifest.json">`,
+			pageURL:  "http://example.com/app/",
+			expected: &models.PWA{HasManifest: true, ManifestURL: "http://example.com/app/manifest.json"},
+		},
+		{
+			name:     "Service worker without manifest",
+			html:     `<script>navigator.serviceWorker.register("/sw.js")</script>`,
+			pageURL:  "https://example.com/",
+			expected: &models.PWA{HTTPS: true, ServiceWorkerHint: true},
+		},
+		{
+			name:    "Invalid manifest URL",
+			html:    `<link rel="manifest" href="https://">`,
+			pageURL: "https://example.com/",
+			expected: &models.PWA{
+				HasManifest: true, HTTPS: true,
+				Error: `manifest URL "https://" is invalid`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := analyzer.parseHTML(tt.html)
+			require.NoError(t, err)
+			pageURL, err := url.Parse(tt.pageURL)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, analyzer.checkPWA(context.Background(), doc, pageURL, false))
+		})
+	}
+}
+
+func TestCacheKey_FetchManifest(t *testing.T) {
+	assert.Equal(t, "http://example.com|"+constants.CacheVariantFetchManifest, cacheKey("http://example.com", models.AnalyzeOptions{FetchManifest: true}))
+}
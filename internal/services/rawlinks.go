@@ -0,0 +1,48 @@
+package services
+
+import (
+	"cmp"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// rawLinkList collects the resolved links of a document, once each in document order
+type rawLinkList struct {
+	seen  map[string]bool
+	items []string
+}
+
+func newRawLinkList() *rawLinkList {
+	return &rawLinkList{seen: make(map[string]bool)}
+}
+
+func (l *rawLinkList) add(link string) {
+	if l.seen[link] {
+		return
+	}
+	l.seen[link] = true
+	l.items = append(l.items, link)
+}
+
+// result returns every link collected, as a single page
+func (l *rawLinkList) result() *models.RawLinks {
+	return &models.RawLinks{Total: len(l.items), Limit: len(l.items), Items: l.items}
+}
+
+// pageRawLinks returns the page of limit links from offset on, limit defaulting to and capped
+// at constants.MaxRawLinksLimit. The full list is left untouched, as it may be the cached one.
+func pageRawLinks(raw *models.RawLinks, offset, limit int) *models.RawLinks {
+	if raw == nil {
+		return nil
+	}
+	limit = min(cmp.Or(limit, constants.MaxRawLinksLimit), constants.MaxRawLinksLimit)
+	start := min(max(offset, 0), len(raw.Items))
+	end := min(start+limit, len(raw.Items))
+	return &models.RawLinks{
+		Total:  raw.Total,
+		Offset: offset,
+		Limit:  limit,
+		Items:  append([]string{}, raw.Items[start:end]...),
+	}
+}
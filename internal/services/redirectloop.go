@@ -0,0 +1,39 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// checkRedirectLoop returns an error wrapping errRedirectLoop, listing the cycle, when req
+// leads back to a URL of the chain that redirected to it. URLs are compared normalized, so
+// http://Example.com:80/ and http://example.com/#top are the same page.
+func checkRedirectLoop(req *http.Request, via []*http.Request) error {
+	next := normalizePageURL(req.URL, false).String()
+	for i, prev := range via {
+		if normalizePageURL(prev.URL, false).String() != next {
+			continue
+		}
+		cycle := make([]string, 0, len(via)-i+1)
+		for _, hop := range via[i:] {
+			cycle = append(cycle, models.StripCredentials(hop.URL.String()))
+		}
+		cycle = append(cycle, models.StripCredentials(req.URL.String()))
+		return fmt.Errorf("%w: %s", errRedirectLoop, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// redirectLoopError reports a page fetch stopped by checkRedirectLoop
+func redirectLoopError(err error) error {
+	return &AnalysisError{
+		Code:    constants.ErrorCodeRedirectLoop,
+		Status:  constants.StatusBadGateway,
+		Message: "Target redirects in a loop",
+		Err:     err,
+	}
+}
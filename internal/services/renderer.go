@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+var (
+	// ErrRendererUnavailable is returned when the headless browser cannot be reached
+	ErrRendererUnavailable = errors.New("javascript renderer unavailable")
+	// ErrRenderTimeout is returned when rendering does not finish within the configured timeout
+	ErrRenderTimeout = errors.New("javascript rendering timed out")
+)
+
+// Renderer renders a page in a JavaScript-capable browser and returns the resulting DOM
+type Renderer interface {
+	Render(ctx context.Context, targetURL string) (string, error)
+	Close() error
+}
+
+// ChromeRenderer renders pages in a remote headless Chrome over the DevTools protocol.
+// A single browser connection is shared and each render opens its own tab.
+type ChromeRenderer struct {
+	logger       *zap.Logger
+	endpoint     string
+	timeout      time.Duration
+	waitSelector string
+	slots        chan struct{}
+
+	mu            sync.Mutex
+	browserCtx    context.Context
+	cancelBrowser context.CancelFunc
+}
+
+// NewChromeRenderer creates a renderer connected lazily to the configured CDP endpoint
+func NewChromeRenderer(cfg config.JSRenderingConfig, logger *zap.Logger) *ChromeRenderer {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = constants.DefaultRenderTimeout
+	}
+	maxConcurrent := cfg.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = constants.DefaultRenderMaxConcurrent
+	}
+
+	return &ChromeRenderer{
+		logger:       logger,
+		endpoint:     cfg.Endpoint,
+		timeout:      timeout,
+		waitSelector: cfg.WaitSelector,
+		slots:        make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Render navigates to the URL in a new tab, waits for the page to settle and returns its outer HTML
+func (r *ChromeRenderer) Render(ctx context.Context, targetURL string) (string, error) {
+	// Bound the number of concurrently open tabs
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	browserCtx, err := r.browser()
+	if err != nil {
+		return "", err
+	}
+
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+	defer cancelTab()
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, r.timeout)
+	defer cancelTimeout()
+
+	// Abort the tab when the caller gives up
+	stop := context.AfterFunc(ctx, cancelTab)
+	defer stop()
+
+	var html string
+	err = chromedp.Run(tabCtx,
+		r.navigateAndWait(targetURL),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	if err != nil {
+		if errors.Is(tabCtx.Err(), context.DeadlineExceeded) {
+			return "", ErrRenderTimeout
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		// A broken browser connection is dropped so the next render reconnects
+		r.resetIfDisconnected(browserCtx)
+		return "", fmt.Errorf("failed to render page: %w", err)
+	}
+
+	return html, nil
+}
+
+// Close closes the browser connection
+func (r *ChromeRenderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancelBrowser != nil {
+		r.cancelBrowser()
+		r.browserCtx, r.cancelBrowser = nil, nil
+	}
+	return nil
+}
+
+// browser returns the shared browser context, connecting on first use or after a disconnect
+func (r *ChromeRenderer) browser() (context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.browserCtx != nil && r.browserCtx.Err() == nil {
+		return r.browserCtx, nil
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewRemoteAllocator(context.Background(), r.endpoint)
+	browserCtx, cancelBrowserCtx := chromedp.NewContext(allocCtx)
+	cancel := func() {
+		cancelBrowserCtx()
+		cancelAlloc()
+	}
+
+	// Running with no actions establishes the connection
+	connectCtx, cancelConnect := context.WithTimeout(browserCtx, constants.RenderConnectTimeout)
+	defer cancelConnect()
+	if err := chromedp.Run(connectCtx); err != nil {
+		cancel()
+		r.logger.Warn("Failed to connect to javascript renderer", zap.String("endpoint", r.endpoint), zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrRendererUnavailable, err)
+	}
+
+	r.browserCtx, r.cancelBrowser = browserCtx, cancel
+	return browserCtx, nil
+}
+
+// resetIfDisconnected drops the shared browser context if it has been cancelled
+func (r *ChromeRenderer) resetIfDisconnected(browserCtx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.browserCtx == browserCtx && browserCtx.Err() != nil {
+		r.cancelBrowser()
+		r.browserCtx, r.cancelBrowser = nil, nil
+	}
+}
+
+// navigateAndWait loads the page and waits for the configured selector or for network idle
+func (r *ChromeRenderer) navigateAndWait(targetURL string) chromedp.Action {
+	if r.waitSelector != "" {
+		return chromedp.Tasks{
+			chromedp.Navigate(targetURL),
+			chromedp.WaitReady(r.waitSelector, chromedp.ByQuery),
+		}
+	}
+
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var navigated atomic.Bool
+		idle := make(chan struct{})
+		var once sync.Once
+
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			if e, ok := ev.(*page.EventLifecycleEvent); ok && e.Name == constants.RenderNetworkIdleEvent && navigated.Load() {
+				once.Do(func() { close(idle) })
+			}
+		})
+
+		if err := page.SetLifecycleEventsEnabled(true).Do(ctx); err != nil {
+			return err
+		}
+		if err := chromedp.Navigate(targetURL).Do(ctx); err != nil {
+			return err
+		}
+		navigated.Store(true)
+
+		// Pages that keep long-polling never go idle; take the snapshot after a bounded wait
+		select {
+		case <-idle:
+		case <-time.After(constants.RenderNetworkIdleWait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	})
+}
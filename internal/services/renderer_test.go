@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// spaShell is what a single-page app serves before JavaScript runs
+const spaShell = `<!DOCTYPE html>
+<html>
+<head><title>SPA</title><script src="/bundle.js"></script></head>
+<body><div id="root"></div></body>
+</html>`
+
+// spaRendered is the DOM after the app has mounted
+const spaRendered = `<html><head><title>SPA</title></head>
+<body><div id="root">
+	<h1>Dashboard</h1>
+	<h2>Reports</h2>
+	<a href="/reports">Reports</a>
+	<form action="/login">
+		<input type="email" name="email" />
+		<input type="password" name="password" />
+		<button type="submit">Sign in</button>
+	</form>
+</div></body></html>`
+
+// MockRenderer is a mock implementation of the Renderer interface
+type MockRenderer struct {
+	mock.Mock
+}
+
+func (m *MockRenderer) Render(ctx context.Context, targetURL string) (string, error) {
+	args := m.Called(ctx, targetURL)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRenderer) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func newSPAServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(spaShell))
+	}))
+}
+
+func TestAnalyzer_RenderJS(t *testing.T) {
+	server := newSPAServer()
+	defer server.Close()
+
+	opts := models.AnalyzeOptions{RenderJS: true, SkipLinkCheck: true}
+
+	t.Run("Uses rendered DOM", func(t *testing.T) {
+		metrics := NewMockMetrics()
+		cache := &MockCache{}
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), metrics, cache)
+		renderer := &MockRenderer{}
+		analyzer.renderer = renderer
+
+		renderer.On("Render", mock.Anything, server.URL).Return(spaRendered, nil)
+		cache.On("Get", mock.Anything, cacheKey(server.URL, opts)).Return(nil, time.Duration(0), nil)
+		cache.On("Set", mock.Anything, cacheKey(server.URL, opts), mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+
+		result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, opts)
+
+		require.NoError(t, err)
+		assert.True(t, result.RenderedWithJS)
+		assert.Equal(t, 1, result.Headings["h1"])
+		assert.Equal(t, 1, result.Links.Internal)
+		assert.True(t, result.HasLoginForm)
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.RenderTotal.WithLabelValues(constants.RenderOutcomeSuccess)))
+		renderer.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("Falls back to static analysis when renderer is unavailable", func(t *testing.T) {
+		metrics := NewMockMetrics()
+		cache := &MockCache{}
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), metrics, cache)
+		renderer := &MockRenderer{}
+		analyzer.renderer = renderer
+
+		staticOpts := opts
+		staticOpts.RenderJS = false
+
+		renderer.On("Render", mock.Anything, server.URL).Return("", ErrRendererUnavailable)
+		cache.On("Get", mock.Anything, cacheKey(server.URL, opts)).Return(nil, time.Duration(0), nil)
+		cache.On("Set", mock.Anything, cacheKey(server.URL, staticOpts), mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+
+		result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, opts)
+
+		require.NoError(t, err)
+		assert.False(t, result.RenderedWithJS)
+		assert.Equal(t, "SPA", result.Title)
+		assert.Equal(t, 0, result.Headings["h1"])
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.RenderTotal.WithLabelValues(constants.RenderOutcomeUnavailable)))
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("Renders statically when rendering is not configured", func(t *testing.T) {
+		analyzer := newTestAnalyzer(t, nil)
+		require.Nil(t, analyzer.renderer)
+
+		result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, opts)
+
+		require.NoError(t, err)
+		assert.False(t, result.RenderedWithJS)
+	})
+}
+
+func TestCacheKey_Variants(t *testing.T) {
+	assert.Equal(t, "http://example.com", cacheKey("http://example.com", models.AnalyzeOptions{}))
+	assert.Equal(t, "http://example.com|render_js", cacheKey("http://example.com", models.AnalyzeOptions{RenderJS: true}))
+	assert.Equal(t, "http://example.com|render_js,skip_link_check",
+		cacheKey("http://example.com", models.AnalyzeOptions{RenderJS: true, SkipLinkCheck: true}))
+}
+
+func TestChromeRenderer_UnavailableEndpoint(t *testing.T) {
+	renderer := NewChromeRenderer(config.JSRenderingConfig{
+		Endpoint: "ws://127.0.0.1:1",
+		Timeout:  2 * time.Second,
+	}, zaptest.NewLogger(t))
+	defer renderer.Close()
+
+	_, err := renderer.Render(context.Background(), "http://example.com")
+	assert.ErrorIs(t, err, ErrRendererUnavailable)
+}
+
+// TestChromeRenderer_Integration renders the fixture SPA in a real browser.
+// It only runs when CDP_ENDPOINT points at a reachable headless Chrome.
+func TestChromeRenderer_Integration(t *testing.T) {
+	endpoint := os.Getenv("CDP_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("CDP_ENDPOINT not set; skipping headless browser integration test")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>SPA</title></head><body><div id="root"></div>
+<script>document.getElementById('root').innerHTML = '<h1>Mounted</h1>';</script></body></html>`))
+	}))
+	defer server.Close()
+
+	renderer := NewChromeRenderer(config.JSRenderingConfig{Endpoint: endpoint, WaitSelector: "#root h1"}, zaptest.NewLogger(t))
+	defer renderer.Close()
+
+	html, err := renderer.Render(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Contains(t, html, "<h1>Mounted</h1>")
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/idna"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// resolveOverride is the caller's options.resolve: requests to host and port connect to addr
+// instead of the addresses DNS returns. Their connections are pooled by per-analysis transports,
+// so a pinned connection is never reused by another analysis, nor a shared one by this analysis.
+type resolveOverride struct {
+	host string
+	port int
+	addr string // ip:port dialed instead
+
+	mu         sync.Mutex
+	transports map[*http.Transport]*http.Transport // Pinned clones of the analyzer's transports
+}
+
+type resolveOverrideContextKey struct{}
+
+// withResolveOverride attaches the override for the analysis to the context; nil attaches nothing
+func withResolveOverride(ctx context.Context, override *resolveOverride) context.Context {
+	if override == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, resolveOverrideContextKey{}, override)
+}
+
+// resolveOverrideFrom returns the override of the analysis running under ctx, if any
+func resolveOverrideFrom(ctx context.Context) *resolveOverride {
+	override, _ := ctx.Value(resolveOverrideContextKey{}).(*resolveOverride)
+	return override
+}
+
+// matches reports whether a request to u connects to the pinned address
+func (o *resolveOverride) matches(u *url.URL) bool {
+	return o != nil && strings.EqualFold(strings.TrimSuffix(u.Hostname(), "."), o.host) && effectivePort(u) == o.port
+}
+
+// transport returns the pinned clone of base, created on first use. TLS is still verified
+// against the hostname, as the request URL keeps it.
+func (o *resolveOverride) transport(base *http.Transport) *http.Transport {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if pinned, ok := o.transports[base]; ok {
+		return pinned
+	}
+	pinned := base.Clone()
+	dial := base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	pinned.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dial(ctx, network, o.addr)
+	}
+	o.transports[base] = pinned
+	return pinned
+}
+
+// close releases the idle connections of the pinned transports once the analysis is done
+func (o *resolveOverride) close() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, pinned := range o.transports {
+		pinned.CloseIdleConnections()
+	}
+}
+
+// resolvingTransport sends requests through base, or through the proxy of the pool the analysis
+// selects with options.egress, unless the analysis pins their host and port with options.resolve
+type resolvingTransport struct {
+	base    *http.Transport
+	proxied map[string]*http.Transport // Clones of base per egress proxy pool
+}
+
+// newResolvingTransport returns a transport sending requests through base or the proxy pools
+func newResolvingTransport(base *http.Transport, proxies proxyPools) *resolvingTransport {
+	return &resolvingTransport{base: base, proxied: proxies.transports(base)}
+}
+
+func (t *resolvingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if egress := egressFrom(req.Context()); egress != "" {
+		proxied, ok := t.proxied[egress]
+		if !ok {
+			return nil, fmt.Errorf("egress %q is not configured", egress)
+		}
+		base = proxied
+	}
+	if override := resolveOverrideFrom(req.Context()); override.matches(req.URL) {
+		return override.transport(base).RoundTrip(req)
+	}
+	return base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the shared transports, for http.Client
+func (t *resolvingTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+	for _, proxied := range t.proxied {
+		proxied.CloseIdleConnections()
+	}
+}
+
+// resolveOverride checks options.resolve against the target and the target policy. The override
+// must name the target's host and port, and may pin them to a private, loopback or link-local
+// address only when analyzer.target_policy.allow_private_resolve is set.
+func (a *Analyzer) resolveOverride(ctx context.Context, target *url.URL, resolve *models.ResolveOverride) (*resolveOverride, error) {
+	if resolve == nil {
+		return nil, nil
+	}
+
+	host, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.ToLower(resolve.Host), "."))
+	if err != nil || !strings.EqualFold(host, strings.TrimSuffix(target.Hostname(), ".")) || resolve.Port != effectivePort(target) {
+		return nil, &AnalysisError{
+			Code:    constants.ErrorCodeResolveNotAllowed,
+			Status:  constants.StatusBadRequest,
+			Message: fmt.Sprintf("resolve must name the host and port of the analyzed URL, %s:%d", target.Hostname(), effectivePort(target)),
+			Err:     err,
+		}
+	}
+
+	ip, err := netip.ParseAddr(resolve.IP)
+	if err != nil {
+		return nil, &AnalysisError{
+			Code:    constants.ErrorCodeResolveNotAllowed,
+			Status:  constants.StatusBadRequest,
+			Message: "resolve.ip is not a valid IP address",
+			Err:     err,
+		}
+	}
+	ip = ip.Unmap()
+	if isPrivateAddr(ip) && !a.optionsFor(ctx).TargetPolicy.AllowPrivateResolve {
+		a.log(ctx).Warn("Resolve override to a private address rejected",
+			zap.String("host", host),
+			zap.String("ip", ip.String()),
+		)
+		return nil, &AnalysisError{
+			Code:    constants.ErrorCodeResolveNotAllowed,
+			Status:  constants.StatusForbidden,
+			Message: fmt.Sprintf("resolve cannot pin %s to the private address %s", host, ip),
+		}
+	}
+
+	return &resolveOverride{
+		host:       host,
+		port:       resolve.Port,
+		addr:       net.JoinHostPort(ip.String(), strconv.Itoa(resolve.Port)),
+		transports: make(map[*http.Transport]*http.Transport),
+	}, nil
+}
+
+// privatePrefixes are the ranges only reachable from inside a network or the host itself, or
+// not unicast at all. IPv4 addresses embedded in IPv6 ones are checked against them unwrapped.
+var privatePrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // This network
+	netip.MustParsePrefix("10.0.0.0/8"),     // Private
+	netip.MustParsePrefix("100.64.0.0/10"),  // Carrier-grade NAT, cloud metadata such as 100.100.100.200
+	netip.MustParsePrefix("127.0.0.0/8"),    // Loopback
+	netip.MustParsePrefix("169.254.0.0/16"), // Link-local, cloud metadata such as 169.254.169.254
+	netip.MustParsePrefix("172.16.0.0/12"),  // Private
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("192.168.0.0/16"), // Private
+	netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking
+	netip.MustParsePrefix("224.0.0.0/4"),    // Multicast
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reserved and broadcast
+	netip.MustParsePrefix("::/128"),         // Unspecified
+	netip.MustParsePrefix("::1/128"),        // Loopback
+	netip.MustParsePrefix("::/96"),          // Deprecated IPv4-compatible
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use NAT64
+	netip.MustParsePrefix("fc00::/7"),       // Unique local
+	netip.MustParsePrefix("fe80::/10"),      // Link-local
+	netip.MustParsePrefix("fec0::/10"),      // Site-local
+	netip.MustParsePrefix("ff00::/8"),       // Multicast
+}
+
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96") // Well-known NAT64, the IPv4 address in the last 32 bits
+	sixToFour   = netip.MustParsePrefix("2002::/16")    // 6to4, the IPv4 address in the 32 bits after the prefix
+)
+
+// isPrivateAddr reports whether ip is only reachable from inside a network or the host itself,
+// directly or through the IPv4 address a mapped, NAT64 or 6to4 IPv6 address embeds
+func isPrivateAddr(ip netip.Addr) bool {
+	ip = embeddedIPv4(ip)
+	for _, prefix := range privatePrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedIPv4 returns the IPv4 address a mapped, NAT64 or 6to4 IPv6 address embeds, or ip
+func embeddedIPv4(ip netip.Addr) netip.Addr {
+	ip = ip.Unmap().WithZone("")
+	b := ip.As16()
+	switch {
+	case nat64Prefix.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[12:16]))
+	case sixToFour.Contains(ip):
+		return netip.AddrFrom4([4]byte(b[2:6]))
+	}
+	return ip
+}
+
+// resolveCacheVariant returns the cache key variant of a validated override, so pages fetched
+// from a pinned address are cached apart from those DNS resolves
+func resolveCacheVariant(resolve *models.ResolveOverride) string {
+	ip := resolve.IP
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		ip = addr.Unmap().String()
+	}
+	return constants.CacheVariantResolve + "=" + ip
+}
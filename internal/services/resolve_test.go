@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// pinnedHost is reachable through options.resolve only; the .test TLD never resolves
+const pinnedHost = "staging.example.test"
+
+// newPinnedServer starts a server on ip answering with a page titled title, whose links point
+// back at pinnedHost, and counts the requests it receives
+func newPinnedServer(t *testing.T, ip string, port int, title string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", ip, err)
+	}
+	port = listener.Addr().(*net.TCPAddr).Port
+	var requests atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Host != net.JoinHostPort(pinnedHost, strconv.Itoa(port)) {
+			http.Error(w, "unexpected host "+r.Host, http.StatusMisdirectedRequest)
+			return
+		}
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><a href="/about">About</a><a href="http://%s:%d/contact">Contact</a></body></html>`, title, pinnedHost, port)
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// allowPrivateResolve sets whether options.resolve may pin a host to a private address
+func allowPrivateResolve(allow bool) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Analyzer.TargetPolicy.AllowPrivateResolve = allow
+	}
+}
+
+func pinnedOptions(ip string, port int) models.AnalyzeOptions {
+	return models.AnalyzeOptions{Resolve: &models.ResolveOverride{Host: pinnedHost, IP: ip, Port: port}}
+}
+
+func TestAnalyzer_ResolveOverride(t *testing.T) {
+	server, requests := newPinnedServer(t, "127.0.0.1", 0, "Staging")
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+	analyzer := newTestAnalyzer(t, allowPrivateResolve(true))
+
+	target := fmt.Sprintf("http://%s:%d/", pinnedHost, port)
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), target, pinnedOptions("127.0.0.1", port))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Staging", result.Title)
+	assert.Equal(t, 2, result.Links.Internal)
+	assert.Zero(t, result.Links.Inaccessible, "same-host link checks connect to the pinned address too")
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestAnalyzer_ResolveOverrideIsolated(t *testing.T) {
+	first, firstRequests := newPinnedServer(t, "127.0.0.1", 0, "First")
+	port := first.Listener.Addr().(*net.TCPAddr).Port
+	_, secondRequests := newPinnedServer(t, "127.0.0.2", port, "Second")
+	analyzer := newTestAnalyzer(t, allowPrivateResolve(true))
+	target := fmt.Sprintf("http://%s:%d/", pinnedHost, port)
+
+	// Concurrent analyses of the same host and port, each pinned to its own address
+	var wg sync.WaitGroup
+	titles := make([]string, 2)
+	for i, ip := range []string{"127.0.0.1", "127.0.0.2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), target, pinnedOptions(ip, port))
+			if assert.NoError(t, err) {
+				titles[i] = result.Title
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"First", "Second"}, titles)
+	assert.Equal(t, int32(3), firstRequests.Load())
+	assert.Equal(t, int32(3), secondRequests.Load())
+
+	// An analysis without the override resolves the host with DNS instead of reusing a pinned connection
+	_, err := analyzer.AnalyzeWithOptions(context.Background(), target, models.AnalyzeOptions{SkipLinkCheck: true})
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), firstRequests.Load())
+	assert.Equal(t, int32(3), secondRequests.Load())
+}
+
+func TestAnalyzer_ResolveOverrideRejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowPrivate bool
+		url          string
+		resolve      models.ResolveOverride
+		status       int
+	}{
+		{name: "Private address", url: "https://staging.example.com/", resolve: models.ResolveOverride{Host: "staging.example.com", IP: "10.0.5.7", Port: 443}, status: constants.StatusForbidden},
+		{name: "Loopback address", url: "https://staging.example.com/", resolve: models.ResolveOverride{Host: "staging.example.com", IP: "127.0.0.1", Port: 443}, status: constants.StatusForbidden},
+		{name: "CGNAT metadata address", url: "https://staging.example.com/", resolve: models.ResolveOverride{Host: "staging.example.com", IP: "100.100.100.200", Port: 443}, status: constants.StatusForbidden},
+		{name: "NAT64 link-local address", url: "https://staging.example.com/", resolve: models.ResolveOverride{Host: "staging.example.com", IP: "64:ff9b::169.254.169.254", Port: 443}, status: constants.StatusForbidden},
+		{name: "Mapped link-local address", url: "https://staging.example.com/", resolve: models.ResolveOverride{Host: "staging.example.com", IP: "::ffff:169.254.169.254", Port: 443}, status: constants.StatusForbidden},
+		{name: "Other host", allowPrivate: true, url: "https://staging.example.com/", resolve: models.ResolveOverride{Host: "example.com", IP: "10.0.5.7", Port: 443}, status: constants.StatusBadRequest},
+		{name: "Other port", allowPrivate: true, url: "https://staging.example.com/", resolve: models.ResolveOverride{Host: "staging.example.com", IP: "10.0.5.7", Port: 80}, status: constants.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := newTestAnalyzer(t, allowPrivateResolve(tt.allowPrivate))
+
+			_, err := analyzer.AnalyzeWithOptions(context.Background(), tt.url, models.AnalyzeOptions{Resolve: &tt.resolve})
+			var analysisErr *AnalysisError
+			require.True(t, errors.As(err, &analysisErr))
+			assert.Equal(t, constants.ErrorCodeResolveNotAllowed, analysisErr.Code)
+			assert.Equal(t, tt.status, analysisErr.Status)
+		})
+	}
+
+	t.Run("Public addresses need no permission", func(t *testing.T) {
+		analyzer := newTestAnalyzer(t, nil)
+		target, err := analyzer.parseAndValidateURL("https://Staging.Example.com./")
+		require.NoError(t, err)
+
+		override, err := analyzer.resolveOverride(context.Background(), target, &models.ResolveOverride{Host: "staging.example.com", IP: "203.0.113.7", Port: 443})
+		require.NoError(t, err)
+		assert.Equal(t, "203.0.113.7:443", override.addr)
+	})
+}
+
+func TestIsPrivateAddr(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		{"10.0.5.7", true},
+		{"172.31.255.1", true},
+		{"192.168.1.1", true},
+		{"127.0.0.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"224.0.0.251", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"::ffff:10.0.5.7", true},
+		{"::127.0.0.1", true},
+		{"fc00::1", true},
+		{"fe80::1%eth0", true},
+		{"fec0::1", true},
+		{"ff02::1", true},
+		{"64:ff9b::a9fe:a9fe", true}, // NAT64 of 169.254.169.254
+		{"64:ff9b::7f00:1", true},    // NAT64 of 127.0.0.1
+		{"64:ff9b:1::1", true},       // Local-use NAT64
+		{"2002:a00:507::1", true},    // 6to4 of 10.0.5.7
+		{"2002:6464:64c8::1", true},  // 6to4 of 100.100.100.200
+		{"203.0.113.7", false},
+		{"8.8.8.8", false},
+		{"100.128.0.1", false},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::808:808", false}, // NAT64 of 8.8.8.8
+		{"2002:808:808::1", false},  // 6to4 of 8.8.8.8
+		{"2001:4860:4860::8888", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.private, isPrivateAddr(netip.MustParseAddr(tt.ip)))
+		})
+	}
+}
+
+func TestCacheKey_Resolve(t *testing.T) {
+	pinned := cacheKey("https://staging.example.com/", models.AnalyzeOptions{Resolve: &models.ResolveOverride{Host: "staging.example.com", IP: "::ffff:10.0.5.7", Port: 443}})
+
+	assert.Equal(t, "https://staging.example.com/|resolve=10.0.5.7", pinned)
+	assert.NotEqual(t, cacheKey("https://staging.example.com/", models.AnalyzeOptions{}), pinned)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// subresourceSelector matches the elements whose URL attributes the browser loads along
+// with the page. Anchors and forms are navigations, not subresources.
+const subresourceSelector = "[src], [srcset], link[href], video[poster], object[data]"
+
+// resourceHintTypes are the rel tokens audited as resource hints
+var resourceHintTypes = []string{
+	constants.ResourceHintPreload,
+	constants.ResourceHintPrefetch,
+	constants.ResourceHintPreconnect,
+	constants.ResourceHintDNSPrefetch,
+}
+
+// auditResourceHints counts the resource hints of the page and flags preloads missing as,
+// connection hints to hosts no subresource is loaded from, and hints declared twice. Hint
+// URLs are resolved against pageURL; hints without a usable href are only counted.
+func auditResourceHints(doc *goquery.Document, pageURL *url.URL) models.ResourceHints {
+	var hints models.ResourceHints
+	seen := make(map[[2]string]int)
+	var order [][2]string
+	var connections []string
+
+	doc.Find("link[rel]").Each(func(_ int, s *goquery.Selection) {
+		for _, hint := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			if !slices.Contains(resourceHintTypes, hint) {
+				continue
+			}
+			if hints.ByType == nil {
+				hints.ByType = make(map[string]int)
+			}
+			hints.Count++
+			hints.ByType[hint]++
+
+			target := hintTarget(s, hint, pageURL)
+			if target == "" {
+				continue
+			}
+			key := [2]string{hint, target}
+			if seen[key] == 0 {
+				order = append(order, key)
+				if hint == constants.ResourceHintPreconnect || hint == constants.ResourceHintDNSPrefetch {
+					if !slices.Contains(connections, target) {
+						connections = append(connections, target)
+					}
+				}
+			}
+			seen[key]++
+
+			if hint == constants.ResourceHintPreload && strings.TrimSpace(s.AttrOr("as", "")) == "" {
+				hints.PreloadsMissingAs = appendReported(hints.PreloadsMissingAs, target)
+			}
+		}
+	})
+
+	hosts := subresourceHosts(doc, pageURL)
+	for _, origin := range connections {
+		if parsed, err := url.Parse(origin); err == nil && !hosts[hintHost(parsed)] {
+			hints.UnusedPreconnects = appendReported(hints.UnusedPreconnects, origin)
+		}
+	}
+	for _, key := range order {
+		if count := seen[key]; count > 1 && len(hints.Duplicates) < constants.ResourceHintsMaxReported {
+			hints.Duplicates = append(hints.Duplicates, models.ResourceHintDuplicate{Type: key[0], URL: key[1], Count: count})
+		}
+	}
+	return hints
+}
+
+// hintTarget resolves the href of a hint: the origin for preconnect and dns-prefetch, the
+// full URL otherwise, without credentials either way. It returns "" for hints without an
+// http or https href.
+func hintTarget(s *goquery.Selection, hint string, pageURL *url.URL) string {
+	href := strings.TrimSpace(s.AttrOr("href", ""))
+	if href == "" {
+		return ""
+	}
+	resolved, err := pageURL.Parse(href)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") || resolved.Host == "" {
+		return ""
+	}
+	if hint == constants.ResourceHintPreconnect || hint == constants.ResourceHintDNSPrefetch {
+		return resolved.Scheme + "://" + strings.ToLower(normalizedHost(resolved))
+	}
+	return withoutCredentials(resolved).String()
+}
+
+// subresourceHosts returns the hosts the subresources of the document are loaded from,
+// connection hints aside. Preloads and prefetches count, as the browser requests them.
+func subresourceHosts(doc *goquery.Document, pageURL *url.URL) map[string]bool {
+	hosts := make(map[string]bool)
+	add := func(ref string) {
+		if ref = strings.TrimSpace(ref); ref == "" {
+			return
+		}
+		if resolved, err := pageURL.Parse(ref); err == nil && resolved.Host != "" {
+			hosts[hintHost(resolved)] = true
+		}
+	}
+
+	doc.Find(subresourceSelector).Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "link" && isConnectionHint(s) {
+			return
+		}
+		for _, attr := range []string{"src", "poster", "data"} {
+			add(s.AttrOr(attr, ""))
+		}
+		if goquery.NodeName(s) == "link" {
+			add(s.AttrOr("href", ""))
+		}
+		// Each candidate of a srcset is a URL followed by an optional descriptor
+		for candidate := range strings.SplitSeq(s.AttrOr("srcset", ""), ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				add(fields[0])
+			}
+		}
+	})
+	return hosts
+}
+
+// isConnectionHint reports whether a link only hints at a connection, as preconnect and
+// dns-prefetch links do
+func isConnectionHint(s *goquery.Selection) bool {
+	rels := strings.Fields(strings.ToLower(s.AttrOr("rel", "")))
+	return len(rels) > 0 && !slices.ContainsFunc(rels, func(rel string) bool {
+		return rel != constants.ResourceHintPreconnect && rel != constants.ResourceHintDNSPrefetch
+	})
+}
+
+// hintHost returns the lowercased hostname of u, without a trailing dot
+func hintHost(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// appendReported appends value unless list already holds it or is full
+func appendReported(list []string, value string) []string {
+	if len(list) >= constants.ResourceHintsMaxReported || slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// isRateLimited reports whether a response asks the client to back off rather than
+// signalling a broken resource: 429, or 503 together with a Retry-After header
+func isRateLimited(statusCode int, header http.Header) bool {
+	switch statusCode {
+	case constants.StatusTooManyRequests:
+		return true
+	case constants.StatusServiceUnavailable:
+		return header.Get(constants.HeaderRetryAfter) != ""
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After value given either as delay-seconds or as an HTTP-date.
+// Dates in the past yield zero and delays are capped at constants.MaxRetryAfter.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		if seconds > int64(constants.MaxRetryAfter/time.Second) {
+			return constants.MaxRetryAfter, true
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = max(date.Sub(now), 0)
+	} else {
+		return 0, false
+	}
+
+	return min(delay, constants.MaxRetryAfter), true
+}
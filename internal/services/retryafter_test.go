@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 19, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "Delay seconds", value: "120", expected: 2 * time.Minute, ok: true},
+		{name: "Zero seconds", value: "0", expected: 0, ok: true},
+		{name: "Surrounding whitespace", value: " 30 ", expected: 30 * time.Second, ok: true},
+		{name: "HTTP-date", value: "Tue, 19 Mar 2024 10:35:00 GMT", expected: 5 * time.Minute, ok: true},
+		{name: "RFC 850 date", value: "Tuesday, 19-Mar-24 10:31:00 GMT", expected: time.Minute, ok: true},
+		{name: "Date in the past", value: "Tue, 19 Mar 2024 10:00:00 GMT", expected: 0, ok: true},
+		{name: "Capped delay", value: "999999999999", expected: constants.MaxRetryAfter, ok: true},
+		{name: "Capped date", value: "Fri, 19 Apr 2024 10:30:00 GMT", expected: constants.MaxRetryAfter, ok: true},
+		{name: "Negative seconds", value: "-5", ok: false},
+		{name: "Empty", value: "", ok: false},
+		{name: "Garbage", value: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, delay)
+		})
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	withRetryAfter := http.Header{}
+	withRetryAfter.Set(constants.HeaderRetryAfter, "10")
+
+	assert.True(t, isRateLimited(http.StatusTooManyRequests, http.Header{}))
+	assert.True(t, isRateLimited(http.StatusServiceUnavailable, withRetryAfter))
+	assert.False(t, isRateLimited(http.StatusServiceUnavailable, http.Header{}))
+	assert.False(t, isRateLimited(http.StatusNotFound, withRetryAfter))
+	assert.False(t, isRateLimited(http.StatusOK, http.Header{}))
+}
+
+func TestAnalyzer_TargetRateLimited(t *testing.T) {
+	retryAt := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(constants.HeaderRetryAfter, retryAt)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`<html><head><title>Maintenance</title></head><body><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a><a href="/d">D</a></body></html>`))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	result, err := analyzer.Analyze(context.Background(), server.URL)
+	assert.Nil(t, result)
+
+	var analysisErr *AnalysisError
+	require.True(t, errors.As(err, &analysisErr))
+	assert.Equal(t, constants.ErrorCodeTargetRateLimited, analysisErr.Code)
+	assert.Equal(t, constants.StatusServiceUnavailable, analysisErr.Status)
+	assert.InDelta(t, 90*time.Second, analysisErr.RetryAfter, float64(2*time.Second))
+	cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnalyzer_RateLimitedLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Links</title></head><body>
+				<a href="/throttled">Throttled</a>
+				<a href="/busy">Busy</a>
+				<a href="/down">Down</a>
+				<a href="/missing">Missing</a>
+				<a href="/ok">OK</a>
+			</body></html>`))
+		case "/throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/busy":
+			w.Header().Set(constants.HeaderRetryAfter, "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, server.URL).Return(nil, time.Duration(0), nil)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Links.RateLimited)
+	assert.Equal(t, 2, result.Links.Inaccessible)
+	assert.Equal(t, 2, result.Links.InaccessibleInternal)
+	// Results with rate-limited links are not cached so they are checked again next time
+	cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
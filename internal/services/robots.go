@@ -0,0 +1,93 @@
+package services
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// robotsRules are the path patterns of the robots.txt group for every crawler
+type robotsRules struct {
+	allow    []string
+	disallow []string
+}
+
+// parseRobots reads the rules of the "User-agent: *" groups of a robots.txt (RFC 9309).
+// The server sends no crawler token of its own, so groups naming specific crawlers never apply.
+func parseRobots(r io.Reader) robotsRules {
+	var rules robotsRules
+	applies, inAgents := false, false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "user-agent":
+			// Consecutive user-agent lines share the group that follows them
+			if !inAgents {
+				applies, inAgents = false, true
+			}
+			if value == "*" {
+				applies = true
+			}
+		case "allow":
+			inAgents = false
+			if applies && value != "" {
+				rules.allow = append(rules.allow, value)
+			}
+		case "disallow":
+			inAgents = false
+			if applies && value != "" {
+				rules.disallow = append(rules.disallow, value)
+			}
+		}
+	}
+	return rules
+}
+
+// allows reports whether the rules allow path, returning the matching rule that decided.
+// The longest matching pattern wins, and allow wins ties; paths no rule matches are allowed.
+func (r robotsRules) allows(path string) (bool, string) {
+	allowed, decided := true, ""
+	for _, pattern := range r.disallow {
+		if robotsMatch(pattern, path) && len(pattern) > len(decided) {
+			allowed, decided = false, pattern
+		}
+	}
+	for _, pattern := range r.allow {
+		if robotsMatch(pattern, path) && len(pattern) >= len(decided) {
+			allowed, decided = true, pattern
+		}
+	}
+	return allowed, decided
+}
+
+// robotsMatch reports whether a robots.txt path pattern matches path. Patterns match path
+// prefixes; * matches any characters and a trailing $ the end of the path.
+func robotsMatch(pattern, path string) bool {
+	pattern, anchored := strings.CutSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	rest, ok := strings.CutPrefix(path, parts[0])
+	if !ok {
+		return false
+	}
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := parseRobots(strings.NewReader(`# Rules for everyone
+User-agent: Googlebot
+Disallow: /
+
+User-agent: *
+User-agent: OtherBot
+Disallow: /private # not for crawlers
+Allow: /private/press
+Disallow: /*.pdf$
+Disallow:
+
+Sitemap: https://site.example/sitemap.xml
+`))
+	assert.Equal(t, robotsRules{allow: []string{"/private/press"}, disallow: []string{"/private", "/*.pdf$"}}, robots, "groups naming other crawlers do not apply")
+
+	tests := []struct {
+		path    string
+		allowed bool
+		rule    string
+	}{
+		{path: "/", allowed: true},
+		{path: "/private", allowed: false, rule: "/private"},
+		{path: "/private/team", allowed: false, rule: "/private"},
+		{path: "/private/press/2024", allowed: true, rule: "/private/press"},
+		{path: "/docs/report.pdf", allowed: false, rule: "/*.pdf$"},
+		{path: "/docs/report.pdf?download=1", allowed: true},
+	}
+	for _, tt := range tests {
+		allowed, rule := robots.allows(tt.path)
+		assert.Equal(t, tt.allowed, allowed, tt.path)
+		assert.Equal(t, tt.rule, rule, tt.path)
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	assert.True(t, robotsMatch("/", "/anything"))
+	assert.True(t, robotsMatch("/a*c", "/abbbc/d"))
+	assert.True(t, robotsMatch("/a*c$", "/abcbc"))
+	assert.False(t, robotsMatch("/a*c$", "/abcd"))
+	assert.False(t, robotsMatch("/index$", "/index.html"))
+	assert.True(t, robotsMatch("/index$", "/index"))
+	assert.False(t, robotsMatch("/b", "/a/b"))
+}
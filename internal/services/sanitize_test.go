@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// assertCleanJSON checks that data is valid JSON without invalid UTF-8 or escaped control
+// characters other than whitespace
+func assertCleanJSON(t *testing.T, data []byte) {
+	t.Helper()
+	assert.True(t, json.Valid(data))
+	assert.True(t, utf8.Valid(data))
+	assert.NotContains(t, string(data), `\u0000`)
+	assert.NotContains(t, string(data), `��`, "runs of invalid bytes become a single replacement")
+	for _, escaped := range []string{`\u0001`, `\u0002`, `\u0007`, `\u0008`, `\u001b`, `\u007f`} {
+		assert.NotContains(t, string(data), escaped)
+	}
+}
+
+func TestAnalyzer_SanitizesExtractedText(t *testing.T) {
+	fixture := loadFixture(t, "invalid_text.html")
+	require.False(t, utf8.ValidString(fixture), "the fixture holds invalid UTF-8")
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	result, err := analyzer.AnalyzeHTML(context.Background(), fixture, "https://cafe.example/", models.AnalyzeOptions{SkipLinkCheck: true, IncludeHeadingText: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Caf� �Menu[31m �Specials", result.Title)
+	assert.Equal(t, "Fresh coffee �( daily", result.MetaDescription)
+	assert.Equal(t, []string{"Welcome to the café"}, result.HeadingText["h1"])
+	assert.Equal(t, []string{"Opening hours"}, result.HeadingText["h2"], "tabs and other whitespace are kept, then collapsed")
+	assert.Contains(t, result.Warnings, models.Warning{
+		Code:    constants.WarningCodeTextSanitized,
+		Message: "The page contains invalid UTF-8 or control characters; they were replaced or stripped from the extracted text",
+	})
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assertCleanJSON(t, data)
+}
+
+func TestAnalyzer_CleanPageNotSanitized(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	// CRLF line endings and tabs are ordinary whitespace
+	result, err := analyzer.AnalyzeHTML(context.Background(), "<html>\r\n<head><title>\tCafé</title></head>\r\n</html>", "https://cafe.example/", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Café", result.Title)
+	assert.Empty(t, result.Warnings)
+}
+
+func TestAnalyzer_CachesSanitizedText(t *testing.T) {
+	fixture := loadFixture(t, "invalid_text.html")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(fixture))
+	}))
+	defer server.Close()
+
+	cache, mr, m := newTestCache(t)
+	defer cache.Close()
+	cfg := createTestConfig()
+	cfg.Cache.Enabled = true
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), m, cache)
+
+	_, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+
+	keys := mr.Keys()
+	require.Len(t, keys, 1)
+	cached, err := mr.Get(keys[0])
+	require.NoError(t, err)
+	assertCleanJSON(t, []byte(cached))
+	assert.Contains(t, cached, constants.WarningCodeTextSanitized, "the warning is cached with the result")
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	require.NotNil(t, result.ServedFromCacheInMs)
+	assert.Equal(t, "Caf� �Menu[31m �Specials", result.Title)
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// selectScope returns the region of doc that options.scope_selector picks, its first match, for
+// links, headings and images to be taken from. It returns nil for the whole document when there
+// is no selector, or when the selector matches nothing and analyzer.scope_unmatched falls back to
+// the document; an unmatched selector is reported as a warning either way.
+func (a *Analyzer) selectScope(ctx context.Context, doc *goquery.Document, selector string) *goquery.Document {
+	if selector == "" {
+		return nil
+	}
+	if match := doc.Find(selector).First(); match.Length() > 0 {
+		return goquery.NewDocumentFromNode(match.Get(0))
+	}
+	if a.optionsFor(ctx).ScopeUnmatched == constants.ScopeUnmatchedEmpty {
+		warningsFrom(ctx).add(constants.WarningCodeScopeNotFound,
+			fmt.Sprintf("scope_selector %q matched no element, so no links, headings or images were analyzed", selector))
+		return goquery.NewDocumentFromNode(&html.Node{Type: html.DocumentNode})
+	}
+	warningsFrom(ctx).add(constants.WarningCodeScopeNotFound,
+		fmt.Sprintf("scope_selector %q matched no element, so the whole document was analyzed", selector))
+	return nil
+}
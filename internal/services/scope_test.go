@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// imagesMissingAlt returns the count of the image-missing-alt accessibility rule
+func imagesMissingAlt(result *models.AnalyzeResponse) int {
+	for _, issue := range result.Accessibility.Issues {
+		if issue.Rule == constants.A11yRuleImageMissingAlt {
+			return issue.Count
+		}
+	}
+	return 0
+}
+
+func TestAnalyzer_ScopeSelector(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	html := loadFixture(t, "scoped_page.html")
+	analyze := func(selector string) *models.AnalyzeResponse {
+		result, err := analyzer.AnalyzeHTML(context.Background(), html, "https://journal.example.com/guides/repot-fig",
+			models.AnalyzeOptions{SkipLinkCheck: true, IncludeHeadingText: true, ScopeSelector: selector})
+		require.NoError(t, err)
+		return result
+	}
+
+	whole := analyze("")
+	assert.Equal(t, map[string]int{"h1": 1, "h2": 3, "h3": 1, "h4": 0, "h5": 0, "h6": 0}, whole.Headings)
+	assert.Equal(t, 8, whole.Links.Internal)
+	assert.Equal(t, 2, whole.Links.External)
+	assert.Equal(t, 3, imagesMissingAlt(whole))
+
+	for _, selector := range []string{"main", "#content", "main > article"} {
+		t.Run(selector, func(t *testing.T) {
+			scoped := analyze(selector)
+			assert.Equal(t, map[string]int{"h1": 1, "h2": 2, "h3": 0, "h4": 0, "h5": 0, "h6": 0}, scoped.Headings)
+			assert.Equal(t, []string{"Choosing a pot", "Aftercare"}, scoped.HeadingText["h2"])
+			assert.Equal(t, 1, scoped.Links.Internal)
+			assert.Equal(t, 1, scoped.Links.External)
+			assert.Equal(t, map[string]int{"example.net": 1}, scoped.Links.ExternalDomains)
+			assert.Equal(t, 1, imagesMissingAlt(scoped))
+			assert.Empty(t, scoped.Warnings)
+
+			// Document-level facts stay global
+			assert.Equal(t, whole.Title, scoped.Title)
+			assert.Equal(t, whole.MetaDescription, scoped.MetaDescription)
+			assert.Equal(t, whole.HTMLVersion, scoped.HTMLVersion)
+		})
+	}
+}
+
+func TestAnalyzer_ScopeSelector_NoMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		unmatched string
+		internal  int
+		h1        int
+	}{
+		{name: "Falls back to the document", internal: 8, h1: 1},
+		{name: "Configured fallback to the document", unmatched: constants.ScopeUnmatchedDocument, internal: 8, h1: 1},
+		{name: "Configured empty scope", unmatched: constants.ScopeUnmatchedEmpty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.Analyzer.ScopeUnmatched = tt.unmatched
+			analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+			result, err := analyzer.AnalyzeHTML(context.Background(), loadFixture(t, "scoped_page.html"), "https://journal.example.com/guides/repot-fig",
+				models.AnalyzeOptions{SkipLinkCheck: true, ScopeSelector: "#sidebar"})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.internal, result.Links.Internal)
+			assert.Equal(t, tt.h1, result.Headings["h1"])
+			require.Len(t, result.Warnings, 1)
+			assert.Equal(t, constants.WarningCodeScopeNotFound, result.Warnings[0].Code)
+			assert.Contains(t, result.Warnings[0].Message, "#sidebar")
+		})
+	}
+}
+
+func TestCacheKey_ScopeSelector(t *testing.T) {
+	assert.Equal(t, "http://example.com|"+constants.CacheVariantScopeSelector+"=main+%3E+article%2C%23content",
+		cacheKey("http://example.com", models.AnalyzeOptions{ScopeSelector: "main > article,#content"}))
+	assert.NotEqual(t, cacheKey("http://example.com", models.AnalyzeOptions{ScopeSelector: "main"}), cacheKey("http://example.com", models.AnalyzeOptions{}))
+}
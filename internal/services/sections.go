@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// analysisSection is one part of the analysis of a document, accumulating into the result
+type analysisSection struct {
+	name  string
+	after string      // Section whose result this one reads; it runs right after it, in the same goroutine
+	final bool        // Reads the whole result, so runs once every other section completed
+	run   func() bool // Reports whether the section completed
+}
+
+// sectionPanic carries a panic out of the goroutine of the section that raised it
+type sectionPanic struct {
+	section string
+	value   any
+	stack   []byte
+}
+
+func (p *sectionPanic) Error() string {
+	return fmt.Sprintf("panic in analysis section %s: %v\n%s", p.section, p.value, p.stack)
+}
+
+// runSections runs the sections of one document and returns the names of those that completed,
+// in the order of sections. The sections only read the parsed document, which is safe for
+// concurrent traversal as nothing modifies it, and each writes its own part of the result, so up
+// to analyzer.section_concurrency of them run at once, started in order. Once the context is done
+// the sections not started yet are skipped, so a deadline still yields everything analyzed so far.
+// A panic in a section is raised again in the caller's goroutine once the others have returned.
+func (a *Analyzer) runSections(ctx context.Context, sections []analysisSection) []string {
+	done := make([]bool, len(sections))
+	var panicOnce sync.Once
+	var panicked *sectionPanic
+
+	// runChain runs a section, then the sections reading its result
+	var runChain func(i int)
+	runChain = func(i int) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				panicOnce.Do(func() { panicked = &sectionPanic{section: sections[i].name, value: recovered, stack: debug.Stack()} })
+			}
+		}()
+		if ctx.Err() != nil || !sections[i].run() {
+			return
+		}
+		done[i] = true
+		for j, next := range sections {
+			if next.after == sections[i].name {
+				runChain(j)
+			}
+		}
+	}
+
+	var g errgroup.Group
+	g.SetLimit(a.optionsFor(ctx).SectionConcurrency)
+	var finals []int
+	for i, section := range sections {
+		switch {
+		case section.final:
+			finals = append(finals, i)
+		case section.after == "":
+			g.Go(func() error {
+				runChain(i)
+				return nil
+			})
+		}
+	}
+	g.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+
+	// Final sections need every other section's result
+	for i, section := range sections {
+		if !section.final && !done[i] {
+			return completedSections(sections, done)
+		}
+	}
+	for _, i := range finals {
+		if ctx.Err() != nil || !sections[i].run() {
+			break
+		}
+		done[i] = true
+	}
+	return completedSections(sections, done)
+}
+
+// completedSections returns the names of the sections done, in order
+func completedSections(sections []analysisSection, done []bool) []string {
+	var names []string
+	for i, section := range sections {
+		if done[i] {
+			names = append(names, section.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func newSectionAnalyzer(concurrency int) *Analyzer {
+	cfg := createTestConfig()
+	cfg.Analyzer.SectionConcurrency = concurrency
+	return NewAnalyzer(cfg, zap.NewNop(), NewMockMetrics(), &MockCache{})
+}
+
+func TestAnalyzer_RunSections(t *testing.T) {
+	t.Run("Independent sections run concurrently", func(t *testing.T) {
+		// Each section waits for the other, so they only complete when they run at once
+		var started sync.WaitGroup
+		started.Add(2)
+		meet := func() bool {
+			started.Done()
+			waited := make(chan struct{})
+			go func() { started.Wait(); close(waited) }()
+			select {
+			case <-waited:
+				return true
+			case <-time.After(time.Second):
+				return false
+			}
+		}
+
+		completed := newSectionAnalyzer(2).runSections(context.Background(), []analysisSection{
+			{name: "a", run: meet},
+			{name: "b", run: meet},
+		})
+		assert.Equal(t, []string{"a", "b"}, completed)
+	})
+
+	t.Run("One at a time in order", func(t *testing.T) {
+		var order []string
+		section := func(name string) analysisSection {
+			return analysisSection{name: name, run: func() bool { order = append(order, name); return true }}
+		}
+
+		completed := newSectionAnalyzer(1).runSections(context.Background(), []analysisSection{section("a"), section("b"), section("c")})
+		assert.Equal(t, []string{"a", "b", "c"}, order)
+		assert.Equal(t, []string{"a", "b", "c"}, completed)
+	})
+
+	t.Run("Dependent and final sections read earlier results", func(t *testing.T) {
+		var loginForm, paywall bool
+		var seo []bool
+		completed := newSectionAnalyzer(4).runSections(context.Background(), []analysisSection{
+			{name: "seo", final: true, run: func() bool { seo = []bool{loginForm, paywall}; return true }},
+			{name: "paywall", after: "login_form", run: func() bool { paywall = loginForm; return true }},
+			{name: "login_form", run: func() bool { time.Sleep(10 * time.Millisecond); loginForm = true; return true }},
+		})
+		assert.Equal(t, []string{"seo", "paywall", "login_form"}, completed)
+		assert.True(t, paywall)
+		assert.Equal(t, []bool{true, true}, seo)
+	})
+
+	t.Run("Incomplete sections skip their dependents and the final sections", func(t *testing.T) {
+		ran := false
+		completed := newSectionAnalyzer(4).runSections(context.Background(), []analysisSection{
+			{name: "headings", run: func() bool { return true }},
+			{name: "links", run: func() bool { return false }},
+			{name: "redirects", after: "links", run: func() bool { ran = true; return true }},
+			{name: "seo", final: true, run: func() bool { ran = true; return true }},
+		})
+		assert.Equal(t, []string{"headings"}, completed)
+		assert.False(t, ran)
+	})
+
+	t.Run("Done context skips sections not started", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		completed := newSectionAnalyzer(1).runSections(ctx, []analysisSection{
+			{name: "a", run: func() bool { cancel(); return true }},
+			{name: "b", run: func() bool { return true }},
+		})
+		assert.Equal(t, []string{"a"}, completed)
+	})
+
+	t.Run("Panics are raised in the caller", func(t *testing.T) {
+		other := false
+		defer func() {
+			recovered := recover()
+			require.NotNil(t, recovered)
+			assert.Contains(t, fmt.Sprint(recovered), "panic in analysis section forms: boom")
+			assert.True(t, other, "the other sections ran to completion first")
+		}()
+		newSectionAnalyzer(2).runSections(context.Background(), []analysisSection{
+			{name: "forms", run: func() bool { panic("boom") }},
+			{name: "title", run: func() bool { time.Sleep(10 * time.Millisecond); other = true; return true }},
+		})
+	})
+}
+
+// largeDocument builds an article page with n sections of headings, paragraphs, images,
+// forms and links
+func largeDocument(n int) string {
+	var page strings.Builder
+	page.WriteString(`<!DOCTYPE html><html lang="en"><head><title>Large page</title>` +
+		`<meta name="description" content="A page with many sections"><script src="https://cdn.example.net/app.js"></script></head><body><main>`)
+	for i := range n {
+		fmt.Fprintf(&page, `<section id="s%d"><h2>Section %d</h2><p>Paragraph %d with <a href="/articles/%d">a related article</a> `+
+			`and <a href="https://partner%d.example.org/">a partner</a>.</p><img src="/img/%d.jpg"%s>`+
+			`<form action="/subscribe"><label for="e%d">Email</label><input id="e%d" type="email"></form>`+
+			`<a href="#s%d">Back to top</a><button>Share</button></section>`,
+			i, i, i, i, i%50, i, map[bool]string{true: ` alt="Figure"`}[i%3 == 0], i, i, i)
+	}
+	page.WriteString(`</main></body></html>`)
+	return page.String()
+}
+
+func TestAnalyzer_ConcurrentSectionsMatchSequential(t *testing.T) {
+	html := largeDocument(200)
+	analyze := func(a *Analyzer) *models.AnalyzeResponse {
+		result, err := a.AnalyzeHTML(context.Background(), html, "https://www.example.com/large",
+			models.AnalyzeOptions{SkipLinkCheck: true, IncludeLinkDetails: true, IncludeHeadingText: true})
+		require.NoError(t, err)
+		result.AnalysisID, result.AnalyzedAt, result.DurationMs, result.Phases = "", time.Time{}, 0, models.PhaseDurations{}
+		return result
+	}
+
+	sequential := analyze(newSectionAnalyzer(1))
+	concurrent := newSectionAnalyzer(8)
+
+	// Several analyses at once, each running its sections concurrently, for the race detector
+	var wg sync.WaitGroup
+	results := make([]*models.AnalyzeResponse, 4)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = analyze(concurrent)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		assert.Equal(t, sequential, result)
+	}
+}
+
+func BenchmarkAnalyzer_Sections(b *testing.B) {
+	html := largeDocument(2000)
+	for _, bm := range []struct {
+		name        string
+		concurrency int
+	}{
+		{name: "sequential", concurrency: 1},
+		{name: "concurrent", concurrency: 0},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			analyzer := newSectionAnalyzer(bm.concurrency)
+			b.ResetTimer()
+			for b.Loop() {
+				if _, err := analyzer.AnalyzeHTML(context.Background(), html, "https://www.example.com/large", models.AnalyzeOptions{SkipLinkCheck: true}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
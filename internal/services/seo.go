@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// seoRule is a single SEO check. check returns a message when the rule is violated.
+type seoRule struct {
+	name     string
+	severity string
+	check    func(page *seoPage) (string, bool)
+}
+
+// seoPage is the data the SEO rules are evaluated against
+type seoPage struct {
+	title           string
+	headings        map[string]int
+	metaDescription *string
+	canonicalLinks  int
+	noindex         bool
+	imagesNoAlt     int
+	linksChecked    bool
+	brokenInternal  int
+}
+
+// seoRules lists the SEO checks in the order their issues are reported.
+// Their penalties come from analyzer.seo.weights, see constants.DefaultSEOWeights.
+var seoRules = []seoRule{
+	{constants.SEORuleMissingTitle, constants.SEOSeverityCritical, func(p *seoPage) (string, bool) {
+		return "Page has no title", p.title == ""
+	}},
+	{constants.SEORuleTitleTooLong, constants.SEOSeverityWarning, func(p *seoPage) (string, bool) {
+		length := utf8.RuneCountInString(p.title)
+		return fmt.Sprintf("Title is %d characters, longer than %d", length, constants.SEOMaxTitleLength), length > constants.SEOMaxTitleLength
+	}},
+	{constants.SEORuleMissingMetaDescription, constants.SEOSeverityCritical, func(p *seoPage) (string, bool) {
+		return "Page has no meta description", p.metaDescription == nil || *p.metaDescription == ""
+	}},
+	{constants.SEORuleMetaDescriptionLength, constants.SEOSeverityWarning, func(p *seoPage) (string, bool) {
+		if p.metaDescription == nil || *p.metaDescription == "" {
+			return "", false
+		}
+		length := utf8.RuneCountInString(*p.metaDescription)
+		return fmt.Sprintf("Meta description is %d characters, expected %d-%d", length, constants.SEOMinMetaDescriptionLength, constants.SEOMaxMetaDescriptionLength),
+			length < constants.SEOMinMetaDescriptionLength || length > constants.SEOMaxMetaDescriptionLength
+	}},
+	{constants.SEORuleMissingH1, constants.SEOSeverityCritical, func(p *seoPage) (string, bool) {
+		return "Page has no h1 heading", p.headings["h1"] == 0
+	}},
+	{constants.SEORuleMultipleH1, constants.SEOSeverityWarning, func(p *seoPage) (string, bool) {
+		return fmt.Sprintf("Page has %d h1 headings", p.headings["h1"]), p.headings["h1"] > 1
+	}},
+	{constants.SEORuleImagesMissingAlt, constants.SEOSeverityWarning, func(p *seoPage) (string, bool) {
+		return fmt.Sprintf("%d images have no alt attribute", p.imagesNoAlt), p.imagesNoAlt > 0
+	}},
+	{constants.SEORuleMissingCanonical, constants.SEOSeverityWarning, func(p *seoPage) (string, bool) {
+		return "Page has no canonical link", p.canonicalLinks == 0
+	}},
+	{constants.SEORuleMultipleCanonical, constants.SEOSeverityCritical, func(p *seoPage) (string, bool) {
+		// Search engines ignore all of them
+		return fmt.Sprintf("Page has %d canonical links", p.canonicalLinks), p.canonicalLinks > 1
+	}},
+	{constants.SEORuleNoindex, constants.SEOSeverityCritical, func(p *seoPage) (string, bool) {
+		return "Page asks search engines not to index it", p.noindex
+	}},
+	{constants.SEORuleBrokenInternalLinks, constants.SEOSeverityCritical, func(p *seoPage) (string, bool) {
+		return fmt.Sprintf("%d internal links are inaccessible", p.brokenInternal), p.linksChecked && p.brokenInternal > 0
+	}},
+}
+
+// scoreSEO evaluates the SEO rules against the document and the already extracted analysis.
+// The score starts at SEOMaxScore and each violated rule subtracts its configured weight.
+func (a *Analyzer) scoreSEO(ctx context.Context, doc *goquery.Document, result *models.AnalyzeResponse, linksChecked bool) models.SEOReport {
+	page := &seoPage{
+		title:          result.Title,
+		headings:       result.Headings,
+		canonicalLinks: len(canonicalLinks(doc)),
+		noindex:        hasNoindex(doc),
+		linksChecked:   linksChecked,
+		brokenInternal: result.Links.InaccessibleInternal,
+	}
+	if description, exists := doc.Find("meta[name='description' i]").First().Attr("content"); exists {
+		description = cleanText(description)
+		page.metaDescription = &description
+	}
+	for _, issue := range result.Accessibility.Issues {
+		if issue.Rule == constants.A11yRuleImageMissingAlt {
+			page.imagesNoAlt = issue.Count
+		}
+	}
+
+	report := models.SEOReport{Score: constants.SEOMaxScore, Issues: []models.SEOIssue{}}
+	for _, rule := range seoRules {
+		message, violated := rule.check(page)
+		if !violated {
+			continue
+		}
+		penalty := a.optionsFor(ctx).SEO.Weights[rule.name]
+		report.Score -= penalty
+		report.Issues = append(report.Issues, models.SEOIssue{
+			Rule:     rule.name,
+			Severity: rule.severity,
+			Message:  message,
+			Penalty:  penalty,
+		})
+	}
+	if report.Score < 0 {
+		report.Score = 0
+	}
+
+	return report
+}
+
+// hasNoindex reports whether a robots meta tag excludes the page from indexing
+func hasNoindex(doc *goquery.Document) bool {
+	found := false
+	doc.Find("meta[name='robots' i], meta[name='googlebot' i]").Each(func(_ int, s *goquery.Selection) {
+		for _, directive := range strings.Split(strings.ToLower(s.AttrOr("content", "")), ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "noindex" || directive == "none" {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+// hasRelToken reports whether the space-separated rel attribute contains the token
+func hasRelToken(s *goquery.Selection, token string) bool {
+	for _, rel := range strings.Fields(s.AttrOr("rel", "")) {
+		if strings.EqualFold(rel, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+const seoPerfectHead = `<title>Example Products - Fast and Reliable Widgets</title>
+<meta name="description" content="Example builds fast, reliable widgets for teams of every size. Compare plans and start a free trial today.">
+<link rel="canonical" href="https://example.com/products">`
+
+const seoPerfectBody = `<h1>Products</h1><img src="/widget.png" alt="Widget"><a href="/pricing">Pricing</a>`
+
+// seoFixture builds a document from head and body fragments
+func seoFixture(head, body string) string {
+	return `<!DOCTYPE html><html lang="en"><head>` + head + `</head><body>` + body + `</body></html>`
+}
+
+// analyzeSEO runs the static analysis over the fixture and returns its SEO report
+func analyzeSEO(t *testing.T, analyzer *Analyzer, htmlContent string) models.SEOReport {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	require.NoError(t, err)
+	parsedURL, _ := url.Parse("https://example.com/products")
+
+	result := analyzer.performWebpageAnalysis(context.Background(), parsedURL.String(), htmlContent, doc, parsedURL, parsedURL, models.AnalyzeOptions{SkipLinkCheck: true})
+	return result.SEO
+}
+
+func seoRuleNames(report models.SEOReport) []string {
+	names := []string{}
+	for _, issue := range report.Issues {
+		names = append(names, issue.Rule)
+	}
+	return names
+}
+
+func TestAnalyzer_ScoreSEO(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	weights := constants.DefaultSEOWeights
+
+	tests := []struct {
+		name          string
+		html          string
+		expectedRules []string
+		expectedScore int
+	}{
+		{
+			name:          "Perfect page",
+			html:          seoFixture(seoPerfectHead, seoPerfectBody),
+			expectedRules: []string{},
+			expectedScore: constants.SEOMaxScore,
+		},
+		{
+			name:          "Missing title",
+			html:          seoFixture(strings.Replace(seoPerfectHead, "<title>Example Products - Fast and Reliable Widgets</title>", "", 1), seoPerfectBody),
+			expectedRules: []string{constants.SEORuleMissingTitle},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleMissingTitle],
+		},
+		{
+			name:          "Title too long",
+			html:          seoFixture(strings.Replace(seoPerfectHead, "Fast and Reliable Widgets", "Fast and Reliable Widgets for Every Team, Budget and Use Case", 1), seoPerfectBody),
+			expectedRules: []string{constants.SEORuleTitleTooLong},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleTitleTooLong],
+		},
+		{
+			name:          "Missing meta description",
+			html:          seoFixture(`<title>Example Products</title><link rel="canonical" href="https://example.com/products">`, seoPerfectBody),
+			expectedRules: []string{constants.SEORuleMissingMetaDescription},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleMissingMetaDescription],
+		},
+		{
+			name:          "Meta description too short",
+			html:          seoFixture(`<title>Example Products</title><meta name="Description" content="Widgets."><link rel="canonical" href="/products">`, seoPerfectBody),
+			expectedRules: []string{constants.SEORuleMetaDescriptionLength},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleMetaDescriptionLength],
+		},
+		{
+			name:          "Missing h1",
+			html:          seoFixture(seoPerfectHead, `<h2>Products</h2>`),
+			expectedRules: []string{constants.SEORuleMissingH1},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleMissingH1],
+		},
+		{
+			name:          "Multiple h1",
+			html:          seoFixture(seoPerfectHead, seoPerfectBody+`<h1>More products</h1>`),
+			expectedRules: []string{constants.SEORuleMultipleH1},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleMultipleH1],
+		},
+		{
+			name:          "Images missing alt",
+			html:          seoFixture(seoPerfectHead, seoPerfectBody+`<img src="/a.png"><img src="/b.png">`),
+			expectedRules: []string{constants.SEORuleImagesMissingAlt},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleImagesMissingAlt],
+		},
+		{
+			name:          "Missing canonical",
+			html:          seoFixture(strings.Replace(seoPerfectHead, `<link rel="canonical" href="https://example.com/products">`, "", 1), seoPerfectBody),
+			expectedRules: []string{constants.SEORuleMissingCanonical},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleMissingCanonical],
+		},
+		{
+			name:          "Noindex",
+			html:          seoFixture(seoPerfectHead+`<meta name="robots" content="NOINDEX, follow">`, seoPerfectBody),
+			expectedRules: []string{constants.SEORuleNoindex},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleNoindex],
+		},
+		{
+			name:          "Robots none implies noindex",
+			html:          seoFixture(seoPerfectHead+`<meta name="googlebot" content="none">`, seoPerfectBody),
+			expectedRules: []string{constants.SEORuleNoindex},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleNoindex],
+		},
+		{
+			name: "Empty page violates every static rule",
+			html: `<html><body></body></html>`,
+			expectedRules: []string{
+				constants.SEORuleMissingTitle,
+				constants.SEORuleMissingMetaDescription,
+				constants.SEORuleMissingH1,
+				constants.SEORuleMissingCanonical,
+			},
+			expectedScore: constants.SEOMaxScore - weights[constants.SEORuleMissingTitle] - weights[constants.SEORuleMissingMetaDescription] -
+				weights[constants.SEORuleMissingH1] - weights[constants.SEORuleMissingCanonical],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeSEO(t, analyzer, tt.html)
+			assert.Equal(t, tt.expectedRules, seoRuleNames(report))
+			assert.Equal(t, tt.expectedScore, report.Score)
+		})
+	}
+}
+
+func TestAnalyzer_ScoreSEO_IssueDetails(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	report := analyzeSEO(t, analyzer, seoFixture(seoPerfectHead, seoPerfectBody+`<h1>Again</h1>`))
+
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, models.SEOIssue{
+		Rule:     constants.SEORuleMultipleH1,
+		Severity: constants.SEOSeverityWarning,
+		Message:  "Page has 2 h1 headings",
+		Penalty:  constants.DefaultSEOWeights[constants.SEORuleMultipleH1],
+	}, report.Issues[0])
+}
+
+func TestAnalyzer_ScoreSEO_ConfiguredWeights(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Analyzer.SEO.Weights = map[string]int{
+		constants.SEORuleMissingTitle:     80,
+		constants.SEORuleMissingCanonical: 0,
+	}
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	// Rules not present in the configuration keep their default weight
+	assert.Equal(t, constants.DefaultSEOWeights[constants.SEORuleNoindex], analyzer.options.SEO.Weights[constants.SEORuleNoindex])
+
+	report := analyzeSEO(t, analyzer, seoFixture(`<meta name="description" content="Example builds fast, reliable widgets for teams of every size.">`, seoPerfectBody))
+	assert.Equal(t, []string{constants.SEORuleMissingTitle, constants.SEORuleMissingCanonical}, seoRuleNames(report))
+	assert.Equal(t, 20, report.Score)
+
+	// The score never drops below zero
+	report = analyzeSEO(t, analyzer, `<html><body></body></html>`)
+	assert.Equal(t, 0, report.Score)
+}
+
+func TestAnalyzer_ScoreSEO_BrokenInternalLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(seoFixture(seoPerfectHead, `<h1>Products</h1><a href="/missing">Missing</a><a href="/gone">Gone</a>`)))
+	})
+
+	cache := &MockCache{}
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	htmlContent := page.body
+	doc, err := analyzer.parseHTML(htmlContent)
+	require.NoError(t, err)
+	parsedURL, _ := url.Parse(server.URL)
+
+	t.Run("Checked links", func(t *testing.T) {
+		result := analyzer.performWebpageAnalysis(context.Background(), server.URL, htmlContent, doc, parsedURL, parsedURL, models.AnalyzeOptions{})
+
+		assert.Equal(t, 2, result.Links.InaccessibleInternal)
+		assert.Equal(t, []string{constants.SEORuleBrokenInternalLinks}, seoRuleNames(result.SEO))
+		assert.Equal(t, constants.SEOMaxScore-constants.DefaultSEOWeights[constants.SEORuleBrokenInternalLinks], result.SEO.Score)
+	})
+
+	t.Run("Skipped link check does not penalize", func(t *testing.T) {
+		result := analyzer.performWebpageAnalysis(context.Background(), server.URL, htmlContent, doc, parsedURL, parsedURL, models.AnalyzeOptions{SkipLinkCheck: true})
+
+		assert.Empty(t, result.SEO.Issues)
+		assert.Equal(t, constants.SEOMaxScore, result.SEO.Score)
+	})
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// SnapshotStore keeps the HTML of fetched pages, the latest per canonical URL
+type SnapshotStore interface {
+	// Save stores the snapshot for ttl, replacing the previous one of its URL
+	Save(ctx context.Context, snapshot *models.Snapshot, ttl time.Duration) error
+	// Load returns the snapshot of pageURL, a canonical URL, or nil when there is none
+	Load(ctx context.Context, pageURL string) (*models.Snapshot, error)
+}
+
+// RedisSnapshotStore keeps snapshots gzip-compressed in a hash under prefix+URL, apart from
+// the cached analyses so they expire on their own TTL
+type RedisSnapshotStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisSnapshotStore stores snapshots with client under prefix
+func NewRedisSnapshotStore(client *redis.Client, prefix string) *RedisSnapshotStore {
+	return &RedisSnapshotStore{client: client, prefix: prefix}
+}
+
+func (s *RedisSnapshotStore) Save(ctx context.Context, snapshot *models.Snapshot, ttl time.Duration) error {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(snapshot.Body); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	key := s.prefix + snapshot.URL
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key,
+		constants.SnapshotFieldBody, body.Bytes(),
+		constants.SnapshotFieldContentType, snapshot.ContentType,
+		constants.SnapshotFieldFetchedAt, snapshot.FetchedAt.UTC().Format(time.RFC3339Nano),
+	)
+	pipe.PExpire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisSnapshotStore) Load(ctx context.Context, pageURL string) (*models.Snapshot, error) {
+	fields, err := s.client.HGetAll(ctx, s.prefix+pageURL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader([]byte(fields[constants.SnapshotFieldBody])))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	fetchedAt, err := time.Parse(time.RFC3339Nano, fields[constants.SnapshotFieldFetchedAt])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return &models.Snapshot{
+		URL:         pageURL,
+		ContentType: fields[constants.SnapshotFieldContentType],
+		FetchedAt:   fetchedAt,
+		Body:        body,
+	}, nil
+}
+
+// Close closes the Redis client
+func (s *RedisSnapshotStore) Close() error {
+	return s.client.Close()
+}
+
+// SetSnapshotStore keeps the pages of analyses run with options.store_snapshot in store
+func (a *Analyzer) SetSnapshotStore(store SnapshotStore) {
+	a.snapshots = store
+}
+
+// snapshotWanted reports whether the page of an analysis is to be stored as a snapshot. A
+// request the analyzer cannot honor is analyzed as usual, with a warning saying why nothing
+// was stored; pages fetched with credentials, from options.auth or the URL, are never stored.
+// A URL has one snapshot, of its page as fetched by default, so views fetched in another
+// language, from a pinned address or through an egress pool are not stored either.
+func (a *Analyzer) snapshotWanted(ctx context.Context, target *url.URL, opts models.AnalyzeOptions) bool {
+	if !opts.StoreSnapshot {
+		return false
+	}
+	switch {
+	case a.snapshots == nil:
+		warningsFrom(ctx).add(constants.WarningCodeSnapshotNotStored, "Snapshots are not enabled on this server")
+	case opts.Auth != nil || target.User != nil:
+		warningsFrom(ctx).add(constants.WarningCodeSnapshotNotStored, "Pages fetched with credentials are never stored as snapshots")
+	case opts.AcceptLanguage != "" || opts.Resolve != nil || opts.Egress != "":
+		warningsFrom(ctx).add(constants.WarningCodeSnapshotNotStored, "Only pages fetched by default are stored as snapshots, not those fetched with accept_language, resolve or egress")
+	default:
+		return true
+	}
+	return false
+}
+
+// saveSnapshot stores the fetched page under its canonical URL. Rendered pages and pages over
+// snapshots.max_bytes are not stored, and failures are logged; either way requestWarnings says
+// so and the analysis goes on.
+func (a *Analyzer) saveSnapshot(ctx context.Context, target *url.URL, page *fetchResult, requestWarnings *analysisWarnings) {
+	options := a.optionsFor(ctx)
+	if page.renderedWithJS {
+		requestWarnings.add(constants.WarningCodeSnapshotNotStored, "Only pages fetched by default are stored as snapshots, not those rendered with render_js")
+		return
+	}
+	if size := int64(len(page.body)); size > options.Snapshots.MaxBytes {
+		requestWarnings.add(constants.WarningCodeSnapshotNotStored,
+			fmt.Sprintf("The page is %d bytes, over the snapshot limit of %d bytes, so it was not stored", size, options.Snapshots.MaxBytes))
+		return
+	}
+
+	contentType := constants.SnapshotContentTypeHTML
+	if page.header != nil && page.header.Get(constants.HeaderContentType) != "" {
+		contentType = page.header.Get(constants.HeaderContentType)
+	}
+	snapshot := &models.Snapshot{
+		URL:         canonicalURL(target),
+		ContentType: contentType,
+		FetchedAt:   time.Now(),
+		Body:        []byte(page.body),
+	}
+
+	// The write must not be cut short by the analysis deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), constants.SnapshotStoreTimeout)
+	defer cancel()
+	if err := a.snapshots.Save(ctx, snapshot, options.Snapshots.TTL); err != nil {
+		a.log(ctx).Error("Failed to store snapshot", zap.String("url", snapshot.URL), zap.Error(err))
+		requestWarnings.add(constants.WarningCodeSnapshotNotStored, "The snapshot could not be stored")
+	}
+}
+
+// Snapshot returns the latest snapshot of targetURL stored by an analysis with
+// options.store_snapshot
+func (a *Analyzer) Snapshot(ctx context.Context, targetURL string) (*models.Snapshot, error) {
+	parsedURL, err := a.parseAndValidateURL(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	if a.snapshots == nil {
+		return nil, &AnalysisError{
+			Code:    constants.ErrorCodeSnapshotsDisabled,
+			Status:  constants.StatusNotFound,
+			Message: "Snapshots are not enabled",
+		}
+	}
+
+	snapshot, err := a.snapshots.Load(ctx, canonicalURL(parsedURL))
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, &AnalysisError{
+			Code:    constants.ErrorCodeSnapshotNotFound,
+			Status:  constants.StatusNotFound,
+			Message: "The page has no snapshot; analyze it with options.store_snapshot first",
+		}
+	}
+	return snapshot, nil
+}
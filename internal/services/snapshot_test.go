@@ -0,0 +1,247 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+const snapshotPage = `<html><head><title>Garden</title></head><body><h1>Roses</h1><a href="/care">Care</a></body></html>`
+
+func TestRedisSnapshotStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store := NewRedisSnapshotStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:")
+	ctx := context.Background()
+	fetchedAt := time.Date(2024, 3, 19, 8, 30, 0, 0, time.UTC)
+
+	require.NoError(t, store.Save(ctx, &models.Snapshot{
+		URL:         "https://example.com/garden",
+		ContentType: "text/html; charset=iso-8859-1",
+		FetchedAt:   fetchedAt,
+		Body:        []byte(snapshotPage),
+	}, 10*time.Minute))
+
+	snapshot, err := store.Load(ctx, "https://example.com/garden")
+	require.NoError(t, err)
+	assert.Equal(t, &models.Snapshot{
+		URL:         "https://example.com/garden",
+		ContentType: "text/html; charset=iso-8859-1",
+		FetchedAt:   fetchedAt,
+		Body:        []byte(snapshotPage),
+	}, snapshot)
+	assert.Equal(t, 10*time.Minute, mr.TTL("test:https://example.com/garden"))
+
+	// The body is kept compressed
+	stored := mr.HGet("test:https://example.com/garden", constants.SnapshotFieldBody)
+	zr, err := gzip.NewReader(strings.NewReader(stored))
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, snapshotPage, string(body))
+
+	// A later snapshot replaces the earlier one
+	require.NoError(t, store.Save(ctx, &models.Snapshot{URL: "https://example.com/garden", ContentType: "text/html", FetchedAt: fetchedAt.Add(time.Hour), Body: []byte("<p>Later</p>")}, time.Minute))
+	snapshot, err = store.Load(ctx, "https://example.com/garden")
+	require.NoError(t, err)
+	assert.Equal(t, "<p>Later</p>", string(snapshot.Body))
+	assert.Equal(t, time.Minute, mr.TTL("test:https://example.com/garden"))
+
+	snapshot, err = store.Load(ctx, "https://example.com/other")
+	require.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	mr.HSet("test:https://example.com/corrupt", constants.SnapshotFieldBody, "not gzip")
+	_, err = store.Load(ctx, "https://example.com/corrupt")
+	assert.Error(t, err)
+
+	mr.Close()
+	_, err = store.Load(ctx, "https://example.com/garden")
+	assert.Error(t, err)
+}
+
+// newSnapshotServer serves snapshotPage, counting the page fetches
+func newSnapshotServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fetches.Add(1)
+		}
+		w.Header().Set(constants.HeaderContentType, "text/html; charset=iso-8859-1")
+		w.Write([]byte(snapshotPage))
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+// newSnapshotAnalyzer returns an analyzer storing snapshots in miniredis, with cached, or nothing, in its cache
+func newSnapshotAnalyzer(t *testing.T, cfg *config.Config, cached *models.AnalyzeResponse) *Analyzer {
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, mock.Anything).Return(cached, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), cache)
+	analyzer.SetSnapshotStore(NewRedisSnapshotStore(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}), "test:"))
+	return analyzer
+}
+
+// warningCodes returns the codes of the result's warnings
+func warningCodes(result *models.AnalyzeResponse) []string {
+	var codes []string
+	for _, warning := range result.Warnings {
+		codes = append(codes, warning.Code)
+	}
+	return codes
+}
+
+func TestAnalyzer_StoreSnapshot(t *testing.T) {
+	server, fetches := newSnapshotServer(t)
+	analyzer := newSnapshotAnalyzer(t, createTestConfig(), &models.AnalyzeResponse{URL: server.URL, Title: "Cached", AnalyzedAt: time.Now()})
+
+	// Without the option the cached result is served and nothing is stored
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Cached", result.Title)
+	_, err = analyzer.Snapshot(context.Background(), server.URL)
+	assertAnalysisError(t, err, constants.ErrorCodeSnapshotNotFound)
+
+	// With it the page is fetched again, so the snapshot is of the returned analysis
+	before := time.Now()
+	result, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{SkipLinkCheck: true, StoreSnapshot: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Garden", result.Title)
+	assert.Empty(t, result.Warnings)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	snapshot, err := analyzer.Snapshot(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, snapshot.URL)
+	assert.Equal(t, "text/html; charset=iso-8859-1", snapshot.ContentType)
+	assert.Equal(t, snapshotPage, string(snapshot.Body))
+	assert.WithinRange(t, snapshot.FetchedAt, before, time.Now())
+}
+
+func TestAnalyzer_StoreSnapshot_NotStored(t *testing.T) {
+	server, _ := newSnapshotServer(t)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	withUser := *serverURL
+	withUser.User = url.UserPassword("reader", "secret")
+
+	tests := []struct {
+		name           string
+		maxBytes       int64
+		url            string
+		auth           *models.AnalyzeAuth
+		acceptLanguage string
+		disabled       bool
+		message        string
+	}{
+		{name: "Over the size limit", maxBytes: int64(len(snapshotPage)) - 1, url: server.URL, message: "over the snapshot limit"},
+		{name: "At the size limit is stored", maxBytes: int64(len(snapshotPage)), url: server.URL},
+		{name: "Credentials in options", url: server.URL, auth: &models.AnalyzeAuth{Headers: map[string]string{"Authorization": "Bearer token"}}, message: "credentials"},
+		{name: "Credentials in the URL", url: withUser.String(), message: "credentials"},
+		{name: "Snapshots disabled", url: server.URL, disabled: true, message: "not enabled"},
+		{name: "Another language", url: server.URL, acceptLanguage: "de", message: "accept_language"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.Snapshots.MaxBytes = tt.maxBytes
+			analyzer := newSnapshotAnalyzer(t, cfg, nil)
+			if tt.disabled {
+				analyzer.SetSnapshotStore(nil)
+			}
+
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), tt.url, models.AnalyzeOptions{SkipLinkCheck: true, StoreSnapshot: true, Auth: tt.auth, AcceptLanguage: tt.acceptLanguage})
+			require.NoError(t, err)
+			assert.Equal(t, "Garden", result.Title, "the page is analyzed either way")
+
+			_, err = analyzer.Snapshot(context.Background(), server.URL)
+			if tt.message == "" {
+				assert.NoError(t, err)
+				assert.Empty(t, result.Warnings)
+				return
+			}
+			require.Equal(t, []string{constants.WarningCodeSnapshotNotStored}, warningCodes(result))
+			assert.Contains(t, result.Warnings[0].Message, tt.message)
+			if tt.disabled {
+				assertAnalysisError(t, err, constants.ErrorCodeSnapshotsDisabled)
+			} else {
+				assertAnalysisError(t, err, constants.ErrorCodeSnapshotNotFound)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_StoreSnapshot_SubmittedHTML(t *testing.T) {
+	analyzer := newSnapshotAnalyzer(t, createTestConfig(), nil)
+
+	// Submitted HTML was never fetched, so there is nothing to keep
+	_, err := analyzer.AnalyzeHTML(context.Background(), snapshotPage, "https://example.com/garden", models.AnalyzeOptions{SkipLinkCheck: true, StoreSnapshot: true})
+	require.NoError(t, err)
+	_, err = analyzer.Snapshot(context.Background(), "https://example.com/garden")
+	assertAnalysisError(t, err, constants.ErrorCodeSnapshotNotFound)
+}
+
+func TestAnalyzer_SnapshotInvalidURL(t *testing.T) {
+	analyzer := newSnapshotAnalyzer(t, createTestConfig(), nil)
+
+	_, err := analyzer.Snapshot(context.Background(), "ftp://example.com/file")
+	assertAnalysisError(t, err, constants.ErrorCodeFTPURL)
+}
+
+func assertAnalysisError(t *testing.T, err error, code string) {
+	t.Helper()
+	var analysisErr *AnalysisError
+	require.ErrorAs(t, err, &analysisErr)
+	assert.Equal(t, code, analysisErr.Code)
+}
+
+func TestSaveSnapshot_NoContentType(t *testing.T) {
+	analyzer := newSnapshotAnalyzer(t, createTestConfig(), nil)
+	target, err := url.Parse("https://example.com/app")
+	require.NoError(t, err)
+	warnings := &analysisWarnings{}
+
+	analyzer.saveSnapshot(context.Background(), target, &fetchResult{body: snapshotPage}, warnings)
+
+	snapshot, err := analyzer.Snapshot(context.Background(), "https://example.com/app")
+	require.NoError(t, err)
+	assert.Equal(t, constants.SnapshotContentTypeHTML, snapshot.ContentType)
+	assert.Equal(t, snapshotPage, string(snapshot.Body))
+	assert.Empty(t, warnings.list())
+}
+
+func TestSaveSnapshot_RenderedPage(t *testing.T) {
+	analyzer := newSnapshotAnalyzer(t, createTestConfig(), nil)
+	target, err := url.Parse("https://example.com/app")
+	require.NoError(t, err)
+	warnings := &analysisWarnings{}
+
+	// The snapshot of a URL is always of its default view, which a rendered page is not
+	analyzer.saveSnapshot(context.Background(), target, &fetchResult{body: snapshotPage, renderedWithJS: true}, warnings)
+
+	_, err = analyzer.Snapshot(context.Background(), "https://example.com/app")
+	assertAnalysisError(t, err, constants.ErrorCodeSnapshotNotFound)
+	require.Len(t, warnings.list(), 1)
+	assert.Equal(t, constants.WarningCodeSnapshotNotStored, warnings.list()[0].Code)
+	assert.Contains(t, warnings.list()[0].Message, "render_js")
+}
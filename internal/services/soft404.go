@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// detectSoft404 reports why a page served with status 200 looks like a "not found" page, or
+// "" when it does not. A short page mentioning a configured phrase in its title, headings or
+// text is suspected, as is any page titled like an error page seen on the same host before.
+// Long pages are never suspected by phrase, so articles about missing pages are left alone.
+func (a *Analyzer) detectSoft404(ctx context.Context, doc *goquery.Document, target *url.URL) string {
+	options := a.optionsFor(ctx)
+	title := a.extractPageTitle(doc)
+	host := strings.ToLower(target.Host)
+	if title != "" && a.errorTitles.matches(host, title) {
+		return constants.Soft404ReasonErrorTitle
+	}
+
+	text := visibleText(doc)
+	if utf8.RuneCountInString(text) > options.Soft404.MaxTextLength {
+		return ""
+	}
+	if !containsSoft404Phrase(title+" "+text, options.Soft404.Phrases) {
+		return ""
+	}
+	if title != "" {
+		a.errorTitles.remember(host, title)
+	}
+	return constants.Soft404ReasonErrorPhrase
+}
+
+// rememberErrorTitle records the title of a genuine 404 or 410 page for later soft 404 checks
+func (a *Analyzer) rememberErrorTitle(page *fetchResult, doc *goquery.Document, target *url.URL) {
+	if page.statusCode != constants.StatusNotFound && page.statusCode != constants.StatusGone {
+		return
+	}
+	if title := a.extractPageTitle(doc); title != "" {
+		a.errorTitles.remember(strings.ToLower(target.Host), title)
+	}
+}
+
+// containsSoft404Phrase reports whether text contains one of the phrases, ignoring case and
+// the style of apostrophe
+func containsSoft404Phrase(text string, phrases []string) bool {
+	text = normalizeSoft404Text(text)
+	for _, phrase := range phrases {
+		if phrase = normalizeSoft404Text(phrase); phrase != "" && strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+var apostropheReplacer = strings.NewReplacer("’", "'", "‘", "'")
+
+func normalizeSoft404Text(text string) string {
+	return strings.ToLower(apostropheReplacer.Replace(text))
+}
+
+// visibleText returns the text of the document body without scripts, styles and templates,
+// cleaned like other extracted text
+func visibleText(doc *goquery.Document) string {
+	var b strings.Builder
+	for _, body := range doc.Find("body").Nodes {
+		appendText(&b, body, " ")
+	}
+	return cleanText(b.String())
+}
+
+// errorTitleCache remembers the title of each host's error page for a while, so that later
+// pages of the host with the same title are recognized even when they are long
+type errorTitleCache struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	titles map[string]errorTitle
+}
+
+type errorTitle struct {
+	title string
+	until time.Time
+}
+
+func newErrorTitleCache(ttl time.Duration) *errorTitleCache {
+	return &errorTitleCache{ttl: ttl, titles: make(map[string]errorTitle)}
+}
+
+func (e *errorTitleCache) matches(host, title string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	known, ok := e.titles[host]
+	if ok && time.Now().After(known.until) {
+		delete(e.titles, host)
+		return false
+	}
+	return ok && known.title == title
+}
+
+func (e *errorTitleCache) remember(host, title string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := e.titles[host]; !ok && len(e.titles) >= constants.Soft404MaxErrorTitles {
+		// Drop expired entries; when every entry is still live the new host is not remembered
+		for h, known := range e.titles {
+			if now.After(known.until) {
+				delete(e.titles, h)
+			}
+		}
+		if len(e.titles) >= constants.Soft404MaxErrorTitles {
+			return
+		}
+	}
+	e.titles[host] = errorTitle{title: title, until: now.Add(e.ttl)}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+const (
+	soft404Page = `<html><head><title>Shop</title></head><body><nav><a href="/">Home</a></nav>
+		<h1>Page not found</h1><p>Sorry, the page you're looking for isn't here.</p></body></html>`
+	soft404SpanishPage = `<html><head><title>Tienda</title></head><body><h1>Página no encontrada</h1></body></html>`
+	soft404TitlePage   = `<html><head><title>404 - Shop</title></head><body><p>Try the search box.</p></body></html>`
+	realErrorPage      = `<html><head><title>Oops | Shop</title></head><body><p>Nothing here.</p></body></html>`
+)
+
+// soft404Article is a long, legitimate page that mentions "not found" in its prose
+var soft404Article = `<html><head><title>Debugging DNS</title></head><body><h1>Debugging DNS</h1><p>` +
+	strings.Repeat("When a lookup fails, resolvers answer NXDOMAIN and the host is reported as not found. ", 20) +
+	`</p></body></html>`
+
+// newSoft404Server serves each path's page with status 200, except /missing, a real 404 page
+func newSoft404Server(t *testing.T, pages map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(realErrorPage))
+			return
+		}
+		w.Write([]byte(pages[r.URL.Path]))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_Soft404(t *testing.T) {
+	tests := []struct {
+		name           string
+		page           string
+		expectedReason string
+	}{
+		{name: "Not found heading", page: soft404Page, expectedReason: constants.Soft404ReasonErrorPhrase},
+		{name: "Spanish not found heading", page: soft404SpanishPage, expectedReason: constants.Soft404ReasonErrorPhrase},
+		{name: "404 in the title", page: soft404TitlePage, expectedReason: constants.Soft404ReasonErrorPhrase},
+		{name: "Article mentioning not found", page: soft404Article},
+		{name: "Short page without error phrases", page: desktopPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSoft404Server(t, map[string]string{"/": tt.page})
+			analyzer := newTestAnalyzer(t, nil)
+			cache := analyzer.cache.(*MockCache)
+
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{SkipLinkCheck: true})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedReason != "", result.Soft404Suspected)
+			assert.Equal(t, tt.expectedReason, result.Soft404Reason)
+			if tt.expectedReason != "" {
+				cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				cache.AssertCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Analyze_Soft404KnownErrorTitle(t *testing.T) {
+	// A long page, which the phrases alone would not flag, titled like the host's real 404 page
+	longErrorPage := `<html><head><title>Oops | Shop</title></head><body><p>` + strings.Repeat("Browse our catalog. ", 100) + `</p></body></html>`
+	server := newSoft404Server(t, map[string]string{"/gone": longErrorPage, "/article": soft404Article})
+	analyzer := newTestAnalyzer(t, nil)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/gone", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.False(t, result.Soft404Suspected, "the error title is not known yet")
+
+	_, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/missing", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.Error(t, err)
+
+	result, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/gone?again", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.True(t, result.Soft404Suspected)
+	assert.Equal(t, constants.Soft404ReasonErrorTitle, result.Soft404Reason)
+
+	result, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/article", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.False(t, result.Soft404Suspected, "other titles of the host are unaffected")
+}
+
+func TestAnalyzer_Analyze_Soft404ConfiguredPhrases(t *testing.T) {
+	server := newSoft404Server(t, map[string]string{"/": `<html><head><title>Shop</title></head><body><h1>Hoppsan, sidan saknas</h1></body></html>`})
+	analyzer := newTestAnalyzer(t, nil)
+	analyzer.options.Soft404.Phrases = []string{"sidan saknas"}
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.True(t, result.Soft404Suspected)
+}
+
+func TestVisibleText(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head><title>Title</title></head><body>
+		<h1>Hello</h1><script>var notFound = "404";</script><style>.x{}</style><p>  world  </p></body></html>`))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", visibleText(doc))
+}
+
+func TestContainsSoft404Phrase(t *testing.T) {
+	phrases := []string{"page doesn't exist"}
+	assert.True(t, containsSoft404Phrase("This Page Doesn’t Exist", phrases))
+	assert.False(t, containsSoft404Phrase("This page exists", phrases))
+	assert.False(t, containsSoft404Phrase("anything", []string{""}))
+}
+
+func TestErrorTitleCache_Expires(t *testing.T) {
+	cache := newErrorTitleCache(time.Millisecond)
+	cache.remember("example.com", "Oops")
+	assert.True(t, cache.matches("example.com", "Oops"))
+	assert.False(t, cache.matches("example.com", "Home"))
+	assert.False(t, cache.matches("other.example", "Oops"))
+
+	time.Sleep(5 * time.Millisecond)
+	assert.False(t, cache.matches("example.com", "Oops"))
+}
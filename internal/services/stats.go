@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// analysisStats counts the resources one analysis uses. A nil stats counts nothing.
+type analysisStats struct {
+	metrics *metrics.Metrics
+
+	outbound        atomic.Int64
+	pageBytes       atomic.Int64
+	linkBytes       atomic.Int64
+	activeLinks     atomic.Int64
+	peakLinks       atomic.Int64
+	cacheRoundTrips atomic.Int64
+
+	hostsMu sync.Mutex
+	hosts   map[string]bool // External hosts link checks were sent to
+}
+
+type statsContextKey struct{}
+
+// withStats attaches fresh resource counters for one analysis to the context
+func (a *Analyzer) withStats(ctx context.Context) (context.Context, *analysisStats) {
+	stats := &analysisStats{metrics: a.metrics}
+	return context.WithValue(ctx, statsContextKey{}, stats), stats
+}
+
+// statsFrom returns the resource counters of the analysis running under ctx, if any
+func statsFrom(ctx context.Context) *analysisStats {
+	stats, _ := ctx.Value(statsContextKey{}).(*analysisStats)
+	return stats
+}
+
+// request counts an outbound request sent to a target
+func (s *analysisStats) request() {
+	if s == nil {
+		return
+	}
+	s.outbound.Add(1)
+}
+
+// received counts response bytes, as transferred, from the given source
+func (s *analysisStats) received(source string, n int64) {
+	if s == nil || n == 0 {
+		return
+	}
+	if source == constants.FetchSourcePage {
+		s.pageBytes.Add(n)
+	} else {
+		s.linkBytes.Add(n)
+	}
+	s.metrics.FetchedBytes.WithLabelValues(source).Add(float64(n))
+}
+
+// linkCheckStarted counts a link check in flight; the returned func ends it
+func (s *analysisStats) linkCheckStarted() func() {
+	if s == nil {
+		return func() {}
+	}
+	active := s.activeLinks.Add(1)
+	for {
+		peak := s.peakLinks.Load()
+		if active <= peak || s.peakLinks.CompareAndSwap(peak, active) {
+			break
+		}
+	}
+	return func() { s.activeLinks.Add(-1) }
+}
+
+// cacheRoundTrip counts a round trip to the cache server
+func (s *analysisStats) cacheRoundTrip() {
+	if s == nil {
+		return
+	}
+	s.cacheRoundTrips.Add(1)
+}
+
+// externalHost records an external host a link check was sent to
+func (s *analysisStats) externalHost(host string) {
+	if s == nil {
+		return
+	}
+	s.hostsMu.Lock()
+	defer s.hostsMu.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]bool)
+	}
+	s.hosts[host] = true
+}
+
+// report returns the counts so far
+func (s *analysisStats) report() *models.AnalysisStats {
+	pageBytes, linkBytes := s.pageBytes.Load(), s.linkBytes.Load()
+	s.hostsMu.Lock()
+	externalHosts := len(s.hosts)
+	s.hostsMu.Unlock()
+	return &models.AnalysisStats{
+		OutboundRequests:    int(s.outbound.Load()),
+		BytesFetched:        pageBytes + linkBytes,
+		PageBytes:           pageBytes,
+		LinkCheckBytes:      linkBytes,
+		PeakLinkConcurrency: int(s.peakLinks.Load()),
+		CacheRoundTrips:     int(s.cacheRoundTrips.Load()),
+		ExternalHosts:       externalHosts,
+	}
+}
+
+// drainLinkResponse reads what remains of a link check response, up to a small limit so the
+// connection can be reused, and counts the bytes received
+func drainLinkResponse(ctx context.Context, body io.Reader) {
+	n, _ := io.Copy(io.Discard, io.LimitReader(body, constants.LinkCheckDrainBytes))
+	statsFrom(ctx).received(constants.FetchSourceLink, n)
+}
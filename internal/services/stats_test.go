@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+const statsPage = `<html><head><title>Stats</title></head><body>
+	<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a><a href="/d">D</a></body></html>`
+
+// newStatsServer serves statsPage; link checks are held until all four are in flight
+func newStatsServer(t *testing.T) *httptest.Server {
+	var inFlight atomic.Int32
+	allInFlight := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(statsPage))
+			return
+		}
+		if inFlight.Add(1) == 4 {
+			close(allInFlight)
+		}
+		select {
+		case <-allInFlight:
+		case <-time.After(2 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_IncludeStats(t *testing.T) {
+	server := newStatsServer(t)
+	cache, _, m := newTestCache(t)
+	defer cache.Close()
+	cfg := createTestConfig()
+	cfg.Cache.Enabled = true
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), m, cache)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{IncludeStats: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, &models.AnalysisStats{
+		OutboundRequests:    5, // The page and its four links
+		BytesFetched:        int64(len(statsPage)),
+		PageBytes:           int64(len(statsPage)),
+		PeakLinkConcurrency: 4,
+		CacheRoundTrips:     2, // The lookup and the write
+	}, result.Stats)
+	assert.Equal(t, float64(len(statsPage)), testutil.ToFloat64(m.FetchedBytes.WithLabelValues(constants.FetchSourcePage)))
+
+	// A cached result reports the lookup only, and the cache never holds stats
+	result, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{IncludeStats: true})
+	require.NoError(t, err)
+	assert.Equal(t, &models.AnalysisStats{CacheRoundTrips: 1}, result.Stats)
+
+	result, err = analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/", models.AnalyzeOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, result.Stats, "stats are opt-in")
+}
+
+func TestAnalyzer_AnalyzeHTML_IncludeStats(t *testing.T) {
+	server := newStatsServer(t)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	result, err := analyzer.AnalyzeHTML(context.Background(), statsPage, server.URL+"/", models.AnalyzeOptions{IncludeStats: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, &models.AnalysisStats{OutboundRequests: 4, PeakLinkConcurrency: 4}, result.Stats)
+}
+
+func TestAnalysisStats_CountsLinkResponseBytes(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	ctx, stats := analyzer.withStats(context.Background())
+
+	drainLinkResponse(ctx, strings.NewReader(strings.Repeat("x", 10)))
+	drainLinkResponse(ctx, strings.NewReader(strings.Repeat("x", 2*constants.LinkCheckDrainBytes)))
+
+	report := stats.report()
+	assert.Equal(t, int64(10+constants.LinkCheckDrainBytes), report.LinkCheckBytes)
+	assert.Equal(t, report.LinkCheckBytes, report.BytesFetched)
+
+	// Counting without stats in the context is a no-op
+	drainLinkResponse(context.Background(), strings.NewReader("x"))
+	var nilStats *analysisStats
+	nilStats.request()
+	nilStats.linkCheckStarted()()
+}
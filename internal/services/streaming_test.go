@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newStreamingServer writes prefix, then streams log lines until the client goes away
+func newStreamingServer(t *testing.T, prefix string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(constants.HeaderContentType, "text/html")
+		w.Write([]byte(prefix))
+		flusher := w.(http.Flusher)
+		flusher.Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+				w.Write([]byte("<p>2024-05-01T12:00:00Z INFO request served</p>\n"))
+				flusher.Flush()
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+const streamingHead = `<!DOCTYPE html><html><head><title>Live log</title><meta name="description" content="Server log"></head><body><h1>Log</h1>`
+
+func TestAnalyzer_Analyze_StreamingPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		configure   func(*config.Config)
+		truncatedBy string
+	}{
+		{
+			name: "Timeout",
+			configure: func(cfg *config.Config) {
+				cfg.Analyzer.Timeouts.Page.Overall = 300 * time.Millisecond
+			},
+			truncatedBy: constants.FetchTruncatedByTimeout,
+		},
+		{
+			name: "Size limit",
+			configure: func(cfg *config.Config) {
+				cfg.Analyzer.MaxBodyBytes = 4096
+			},
+			truncatedBy: constants.FetchTruncatedBySizeLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStreamingServer(t, streamingHead)
+			analyzer := newTestAnalyzer(t, tt.configure)
+
+			start := time.Now()
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL, models.AnalyzeOptions{SkipLinkCheck: true})
+			require.NoError(t, err)
+			assert.Less(t, time.Since(start), 5*time.Second)
+
+			assert.Equal(t, "Live log", result.Title)
+			assert.Equal(t, 1, result.Headings["h1"])
+			require.NotNil(t, result.Fetch)
+			assert.True(t, result.Fetch.Truncated)
+			assert.Equal(t, tt.truncatedBy, result.Fetch.TruncatedBy)
+			require.Len(t, result.Warnings, 1)
+			assert.Equal(t, constants.WarningCodeBodyTruncated, result.Warnings[0].Code)
+			if tt.truncatedBy == constants.FetchTruncatedBySizeLimit {
+				assert.Equal(t, int64(4096), result.Fetch.DecompressedBytes, "the prefix stops at the limit")
+			}
+		})
+	}
+}
+
+func TestAnalyzer_FetchWebpage_StreamingWithoutHead(t *testing.T) {
+	// The head never closes, so there is nothing worth analyzing
+	server := newStreamingServer(t, `<html><head><title>Live log</title>`)
+
+	t.Run("Timeout", func(t *testing.T) {
+		analyzer := newTestAnalyzer(t, func(cfg *config.Config) {
+			cfg.Analyzer.Timeouts.Page.Overall = 200 * time.Millisecond
+		})
+		_, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+		assert.ErrorContains(t, err, "failed to read response body")
+	})
+
+	t.Run("Size limit", func(t *testing.T) {
+		analyzer := newTestAnalyzer(t, func(cfg *config.Config) {
+			cfg.Analyzer.MaxBodyBytes = 1024
+		})
+		_, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+		assert.ErrorContains(t, err, "exceeds maximum size")
+	})
+}
+
+func TestHeadReceived(t *testing.T) {
+	assert.True(t, headReceived([]byte(`<html><head><title>A</title></HEAD>`)))
+	assert.True(t, headReceived([]byte(`<html><title>A</title><BODY class="x">`)), "the body start implies the head ended")
+	assert.False(t, headReceived([]byte(`<html><head><title>A</title><meta name="a">`)))
+}
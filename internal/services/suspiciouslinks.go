@@ -0,0 +1,156 @@
+package services
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// allowedScriptMixes are the script combinations a legitimate label may use, those of
+// Japanese, Chinese and Korean names written alongside Latin (the highly restrictive
+// profile of Unicode TS #39)
+var allowedScriptMixes = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// suspiciousLinkTally collects the phishing signals of the anchors classified
+type suspiciousLinkTally struct {
+	result models.SuspiciousLinks
+}
+
+// add checks one resolved anchor. Punycode and mixed-script hostnames are only counted
+// for external links, as the internal ones share the analyzed page's host.
+func (t *suspiciousLinkTally) add(s *goquery.Selection, link *url.URL, external bool) {
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return
+	}
+	host := strings.TrimSuffix(strings.ToLower(link.Hostname()), ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return
+	}
+
+	var reasons []string
+	if external && hasPunycodeLabel(host) {
+		t.result.PunycodeHosts++
+		reasons = append(reasons, constants.SuspiciousLinkPunycodeHost)
+	}
+	if external && mixesScripts(host) {
+		t.result.MixedScript++
+		reasons = append(reasons, constants.SuspiciousLinkMixedScript)
+	}
+	text := selectionText(s)
+	if shown := textDomain(text); shown != "" && registeredDomain(shown) != registeredDomain(host) {
+		t.result.TextMismatch++
+		reasons = append(reasons, constants.SuspiciousLinkTextMismatch)
+	}
+
+	if len(reasons) > 0 && len(t.result.Samples) < constants.SuspiciousLinkMaxSamples {
+		if runes := []rune(text); len(runes) > constants.SuspiciousLinkMaxTextLength {
+			text = string(runes[:constants.SuspiciousLinkMaxTextLength])
+		}
+		t.result.Samples = append(t.result.Samples, models.SuspiciousLink{URL: link.String(), Text: text, Reasons: reasons})
+	}
+}
+
+// hasPunycodeLabel reports whether any label of an ASCII hostname is punycode encoded
+func hasPunycodeLabel(host string) bool {
+	for label := range strings.SplitSeq(host, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			return true
+		}
+	}
+	return false
+}
+
+// mixesScripts reports whether a label of the hostname, once decoded from punycode, mixes
+// letters of scripts no legitimate name combines, such as a Cyrillic "а" among Latin letters
+func mixesScripts(host string) bool {
+	for label := range strings.SplitSeq(host, ".") {
+		if decoded, err := idna.ToUnicode(label); err == nil {
+			label = decoded
+		}
+		var scripts []string
+		for _, r := range label {
+			if script := scriptOf(r); script != "" && !slices.Contains(scripts, script) {
+				scripts = append(scripts, script)
+			}
+		}
+		if len(scripts) > 1 && !slices.ContainsFunc(allowedScriptMixes, func(mix []string) bool {
+			return !slices.ContainsFunc(scripts, func(script string) bool { return !slices.Contains(mix, script) })
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptOf returns the Unicode script of a letter, or "" for digits, hyphens and other
+// characters shared between scripts
+func scriptOf(r rune) string {
+	if r <= unicode.MaxASCII {
+		if unicode.IsLetter(r) {
+			return "Latin"
+		}
+		return ""
+	}
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return ""
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// textDomain returns the ASCII hostname anchor text shows when the text is a URL or a bare
+// domain, such as "https://bank.com/login" or "bank.com", and "" otherwise. Domains
+// must end in a public suffix, so file names like "index.html" are not taken for one.
+func textDomain(text string) string {
+	if text == "" || strings.ContainsAny(text, " @") {
+		return ""
+	}
+	lower := strings.ToLower(text)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		if strings.Contains(lower, "://") {
+			return ""
+		}
+		text = "http://" + text
+	}
+	shown, err := url.Parse(text)
+	if err != nil {
+		return ""
+	}
+	host, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.ToLower(shown.Hostname()), "."))
+	if err != nil || !strings.Contains(host, ".") || net.ParseIP(host) != nil {
+		return ""
+	}
+	if _, icann := publicsuffix.PublicSuffix(host); !icann {
+		return ""
+	}
+	return host
+}
+
+// registeredDomain returns the domain registered for a hostname, or the hostname itself when
+// it has none, as for IP addresses
+func registeredDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_SuspiciousLinks(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	doc, err := analyzer.parseHTML(loadFixture(t, "deceptive_links.html"))
+	require.NoError(t, err)
+	baseURL, _ := url.Parse("https://shop.example.com/notice")
+
+	analysis, _, _ := analyzer.classifyLinks(context.Background(), doc, baseURL, analyzer.domLimitsFor(context.Background(), doc))
+	assert.Equal(t, models.SuspiciousLinks{
+		PunycodeHosts: 4,
+		MixedScript:   2,
+		TextMismatch:  3,
+		Samples: []models.SuspiciousLink{
+			{URL: "https://xn--pple-43d.com/signin", Text: "apple.com", Reasons: []string{constants.SuspiciousLinkPunycodeHost, constants.SuspiciousLinkMixedScript, constants.SuspiciousLinkTextMismatch}},
+			{URL: "https://xn--ggle-55da.com/", Text: "Search", Reasons: []string{constants.SuspiciousLinkPunycodeHost, constants.SuspiciousLinkMixedScript}},
+			{URL: "https://xn--e1afmkfd.xn--p1ai/", Text: "пример.рф", Reasons: []string{constants.SuspiciousLinkPunycodeHost}},
+			{URL: "https://xn--5ck2eqb538s34z.jp/", Text: "Tokyo Tower", Reasons: []string{constants.SuspiciousLinkPunycodeHost}},
+			{URL: "https://paypal.com.account-verify.net/login", Text: "https://www.paypal.com/signin", Reasons: []string{constants.SuspiciousLinkTextMismatch}},
+			{URL: "https://shop.example.com/account", Text: "secure-bank.com", Reasons: []string{constants.SuspiciousLinkTextMismatch}},
+		},
+	}, analysis.Suspicious)
+}
+
+func TestAnalyzer_SuspiciousLinkSamplesNeedDetails(t *testing.T) {
+	html := loadFixture(t, "deceptive_links.html")
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	result, err := analyzer.AnalyzeHTML(t.Context(), html, "https://shop.example.com/notice", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Links.Suspicious.TextMismatch)
+	assert.Nil(t, result.Links.Suspicious.Samples)
+
+	result, err = analyzer.AnalyzeHTML(t.Context(), html, "https://shop.example.com/notice", models.AnalyzeOptions{SkipLinkCheck: true, IncludeLinkDetails: true})
+	require.NoError(t, err)
+	assert.Len(t, result.Links.Suspicious.Samples, 6)
+}
+
+func TestTextDomain(t *testing.T) {
+	tests := map[string]string{
+		"example.com":                  "example.com",
+		"WWW.Example.COM.":             "www.example.com",
+		"https://bank.co.uk/login?x=1": "bank.co.uk",
+		"http://пример.рф":             "xn--e1afmkfd.xn--p1ai",
+		"index.html":                   "",
+		"README":                       "",
+		"read more":                    "",
+		"help@example.com":             "",
+		"ftp://files.example.com":      "",
+		"192.168.1.1":                  "",
+		"":                             "",
+	}
+	for text, expected := range tests {
+		assert.Equal(t, expected, textDomain(text), text)
+	}
+}
+
+func TestMixesScripts(t *testing.T) {
+	assert.False(t, mixesScripts("example.com"))
+	assert.False(t, mixesScripts("my-site-2.com"))
+	assert.False(t, mixesScripts("xn--e1afmkfd.xn--p1ai"), "Cyrillic labels")
+	assert.False(t, mixesScripts("xn--5ck2eqb538s34z.jp"), "Han and Katakana")
+	assert.True(t, mixesScripts("xn--pple-43d.com"), "Cyrillic а among Latin letters")
+	assert.True(t, mixesScripts("paypαl.com"), "Greek α among Latin letters")
+}
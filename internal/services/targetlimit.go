@@ -0,0 +1,71 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// targetLimiter paces the page fetches of each target host across every client, so clients
+// staying under their own limits cannot together flood one site. Like the client rate limiter
+// it keeps a token bucket per key in memory and forgets them all periodically.
+type targetLimiter struct {
+	mu      sync.Mutex
+	hosts   map[string]*rate.Limiter
+	rate    rate.Limit
+	burst   int
+	resetAt time.Time // When the buckets are next forgotten
+}
+
+// newTargetLimiter returns the per-target limiter, or nil when rate limiting is disabled or
+// no per-target rate is configured
+func newTargetLimiter(cfg config.RateLimitConfig) *targetLimiter {
+	if !cfg.Enabled || cfg.PerTargetPerMinute <= 0 {
+		return nil
+	}
+	return &targetLimiter{
+		hosts:   make(map[string]*rate.Limiter),
+		rate:    rate.Limit(cfg.PerTargetPerMinute / 60.0),
+		burst:   max(1, int(cfg.PerTargetPerMinute*constants.DefaultRateLimitBurstFactor)),
+		resetAt: time.Now().Add(constants.DefaultRateLimitCleanupTimeout),
+	}
+}
+
+// allow takes a token for a page fetch of host, returning a TARGET_QUOTA_EXCEEDED error
+// with the wait for the next token when the host's bucket is empty
+func (l *targetLimiter) allow(host string) error {
+	if l == nil {
+		return nil
+	}
+	now := time.Now()
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	if now.After(l.resetAt) {
+		l.hosts = make(map[string]*rate.Limiter)
+		l.resetAt = now.Add(constants.DefaultRateLimitCleanupTimeout)
+	}
+	limiter, ok := l.hosts[host]
+	if !ok {
+		limiter = rate.NewLimiter(l.rate, l.burst)
+		l.hosts[host] = limiter
+	}
+	l.mu.Unlock()
+
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return &AnalysisError{
+			Code:       constants.ErrorCodeTargetQuotaExceeded,
+			Status:     constants.StatusTooManyRequests,
+			Message:    "Too many analyses of this target host; try again later",
+			RetryAfter: delay,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// targetRateLimit allows perMinute analyses of each target host
+func targetRateLimit(perMinute float64) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{Enabled: true, PerTargetPerMinute: perMinute}
+	}
+}
+
+func TestAnalyzer_TargetRateLimit(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`<html><head><title>Small site</title></head><body><h1>Hi</h1></body></html>`))
+	}))
+	defer server.Close()
+
+	// 60 per minute allows a burst of 6
+	analyzer := newTestAnalyzer(t, targetRateLimit(60))
+
+	// Many clients hammer the same host at once, each with a page of its own
+	const requests = 20
+	var wg sync.WaitGroup
+	var allowed, limited atomic.Int32
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := analyzer.AnalyzeWithOptions(context.Background(), fmt.Sprintf("%s/page-%d", server.URL, i), models.AnalyzeOptions{SkipLinkCheck: true})
+			var analysisErr *AnalysisError
+			switch {
+			case err == nil:
+				allowed.Add(1)
+			case errors.As(err, &analysisErr) && analysisErr.Code == constants.ErrorCodeTargetQuotaExceeded:
+				assert.Equal(t, constants.StatusTooManyRequests, analysisErr.Status)
+				assert.Positive(t, analysisErr.RetryAfter)
+				limited.Add(1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(6), allowed.Load())
+	assert.Equal(t, int32(requests-6), limited.Load())
+	assert.Equal(t, int32(6), fetches.Load(), "limited analyses never reach the target")
+
+	// Another host keeps its own budget; localhost reaches the same server
+	otherHost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	_, err := analyzer.AnalyzeWithOptions(context.Background(), otherHost+"/", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+}
+
+func TestAnalyzer_TargetRateLimit_ServesCachedResults(t *testing.T) {
+	cached := &models.AnalyzeResponse{URL: "http://example.com/", Title: "Cached"}
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, mock.Anything).Return(cached, time.Hour, nil)
+	analyzer := newTestAnalyzer(t, targetRateLimit(1))
+	analyzer.cache = cache
+
+	for range 5 {
+		result, err := analyzer.Analyze(context.Background(), "http://example.com/")
+		require.NoError(t, err, "cache reads are never limited")
+		assert.Equal(t, "Cached", result.Title)
+	}
+}
+
+func TestNewTargetLimiter(t *testing.T) {
+	assert.Nil(t, newTargetLimiter(config.RateLimitConfig{Enabled: true}), "no per-target rate")
+	assert.Nil(t, newTargetLimiter(config.RateLimitConfig{PerTargetPerMinute: 10}), "rate limiting disabled")
+
+	limiter := newTargetLimiter(config.RateLimitConfig{Enabled: true, PerTargetPerMinute: 5})
+	require.NotNil(t, limiter)
+	require.NoError(t, limiter.allow("Example.com"), "a burst of at least one")
+	err := limiter.allow("example.COM")
+	var analysisErr *AnalysisError
+	require.ErrorAs(t, err, &analysisErr, "hosts are compared case-insensitively")
+	assert.InDelta(t, 12*time.Second, analysisErr.RetryAfter, float64(time.Second))
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+// invisibleRunes are format characters that render as nothing but make otherwise equal
+// strings differ
+var invisibleRunes = strings.NewReplacer(
+	"\u200b", "", // Zero width space
+	"\u200c", "", // Zero width non-joiner
+	"\u200d", "", // Zero width joiner
+	"\u2060", "", // Word joiner
+	"\ufeff", "", // Byte order mark, or zero width no-break space
+	"\u00ad", "", // Soft hyphen
+)
+
+// cleanText normalizes extracted text so the same text always compares equal: the text is
+// sanitized, zero width characters are stripped, the text is NFC normalized and runs of
+// whitespace, non-breaking spaces and line breaks included, collapse to single spaces.
+// Entities are decoded by the HTML parser already and are not decoded again, as browsers
+// show a double-encoded entity as written.
+func cleanText(s string) string {
+	s = norm.NFC.String(invisibleRunes.Replace(sanitizeText(s)))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// sanitizeText replaces invalid UTF-8 with U+FFFD and strips control characters other than
+// whitespace, such as null bytes, which some JSON clients reject even escaped
+func sanitizeText(s string) string {
+	if !needsSanitizing(s) {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if isStrippedControl(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(s, "\uFFFD"))
+}
+
+// needsSanitizing reports whether sanitizeText would change s
+func needsSanitizing(s string) bool {
+	return !utf8.ValidString(s) || strings.ContainsFunc(s, isStrippedControl)
+}
+
+// isStrippedControl reports whether sanitizeText strips r. Tabs, line breaks and the
+// carriage returns of CRLF line endings are whitespace and are kept.
+func isStrippedControl(r rune) bool {
+	return unicode.IsControl(r) && !unicode.IsSpace(r)
+}
+
+// selectionText returns the cleaned text of the selection, without the contents of scripts,
+// styles, noscript fallbacks and templates, which goquery's Text would include
+func selectionText(s *goquery.Selection) string {
+	var b strings.Builder
+	for _, n := range s.Nodes {
+		appendText(&b, n, "")
+	}
+	return cleanText(b.String())
+}
+
+// appendText writes the text below n, skipping elements that are not shown, followed each
+// time by sep
+func appendText(b *strings.Builder, n *html.Node, sep string) {
+	switch {
+	case n.Type == html.TextNode:
+		b.WriteString(n.Data)
+		b.WriteString(sep)
+		return
+	case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style" || n.Data == "noscript" || n.Data == "template"):
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		appendText(b, child, sep)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Plain", "Home", "Home"},
+		{"Surrounding whitespace", "  \tHome \n", "Home"},
+		{"CRLF inside", "Shop\r\n  Now", "Shop Now"},
+		{"Non-breaking spaces", "Shop\u00a0\u00a0Now", "Shop Now"},
+		{"Zero width joiner", "Sho\u200dp", "Shop"},
+		{"Zero width space and word joiner", "Sh\u200bop\u2060 Now", "Shop Now"},
+		{"Byte order mark", "\ufeffHome", "Home"},
+		{"Soft hyphen", "Docu\u00admentation", "Documentation"},
+		{"Combining characters", "Cafe\u0301", "Caf\u00e9"},
+		{"Precomposed characters", "Caf\u00e9", "Caf\u00e9"},
+		{"Decoded entities kept", "Fish & Chips – <Menu>", "Fish & Chips – <Menu>"},
+		{"Only invisible characters", "\u200b \r\n", ""},
+		{"Null bytes", "Ho\x00me", "Home"},
+		{"Control characters", "\x1b[31mSale\x7f\u0085Now\x01", "[31mSale Now"},
+		{"Invalid UTF-8", "Caf\xe9 \xff\xfeMenu", "Caf\ufffd \ufffdMenu"},
+		{"Overlong encoding", "a\xc0\xafb", "a\ufffdb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cleanText(tt.input))
+		})
+	}
+}
+
+func TestExtractedTextIsCleaned(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		title    string
+		heading  string
+		metaDesc string
+	}{
+		{
+			name:     "Entities",
+			html:     `<title>Fish &amp; Chips &#8211; Menu</title><meta name="description" content="Fresh &amp; hot &quot;daily&quot;"><h1>Tom &amp; Jerry&nbsp;&nbsp;Show</h1>`,
+			title:    "Fish & Chips – Menu",
+			heading:  "Tom & Jerry Show",
+			metaDesc: `Fresh & hot "daily"`,
+		},
+		{
+			name:     "Double-encoded entities stay as browsers show them",
+			html:     `<title>A &amp;amp; B</title><h1>A</h1>`,
+			title:    "A &amp; B",
+			heading:  "A",
+			metaDesc: "",
+		},
+		{
+			name:     "CRLF and combining characters",
+			html:     "<title>Cafe\u0301\r\n  Menu</title><meta name=\"description\" content=\"Cafe&#x301;\r\nmenu\"><h1>Cafe\u0301\r\n Menu</h1>",
+			title:    "Caf\u00e9 Menu",
+			heading:  "Caf\u00e9 Menu",
+			metaDesc: "Caf\u00e9 menu",
+		},
+		{
+			name:     "Zero width characters",
+			html:     "<title>\ufeffHo\u200dme</title><h1>Wel&#x200b;come</h1>",
+			title:    "Home",
+			heading:  "Welcome",
+			metaDesc: "",
+		},
+		{
+			name:    "Scripts, styles and SVG titles",
+			html:    `<title>Home</title><h1>Welcome<script>track("h1")</script><style>h1{color:red}</style><noscript>Enable JS</noscript></h1><svg><title>Logo</title></svg>`,
+			title:   "Home",
+			heading: "Welcome",
+		},
+	}
+
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tt.html + "</head></html>"))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.title, analyzer.extractPageTitle(doc))
+			assert.Equal(t, []string{tt.heading}, analyzer.extractHeadingText(doc)["h1"])
+			page := analyzer.summarizePage(doc)
+			assert.Equal(t, tt.metaDesc, page.metaDescription)
+		})
+	}
+}
+
+func TestAccessibleName_IsCleaned(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<body>
+		<a id="a" href="/x">Read&nbsp;&#x200b;more</a>
+		<a id="b" href="/y" aria-label=" Caf&#x65;&#x301; ">x</a>
+		<a id="c" href="/z">Next<script>1</script></a>
+	</body>`))
+	require.NoError(t, err)
+	checker := &a11yChecker{doc: doc}
+
+	assert.Equal(t, "Read more", checker.accessibleName(doc.Find("#a")))
+	assert.Equal(t, "Caf\u00e9", checker.accessibleName(doc.Find("#b")))
+	assert.Equal(t, "Next", checker.accessibleName(doc.Find("#c")))
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// unacceptedListener returns the address of a socket that never accepts connections. With a
+// backlog of 0 its accept queue fills with a single connection, after which the kernel drops
+// further connection attempts, so they hang until the connect timeout.
+func unacceptedListener(t *testing.T) string {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { syscall.Close(fd) })
+	require.NoError(t, syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}))
+	require.NoError(t, syscall.Listen(fd, 0))
+	sa, err := syscall.Getsockname(fd)
+	require.NoError(t, err)
+	addr := fmt.Sprintf("127.0.0.1:%d", sa.(*syscall.SockaddrInet4).Port)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return addr
+}
+
+func TestAnalyzer_Timeouts_Connect(t *testing.T) {
+	addr := unacceptedListener(t)
+	analyzer := timeoutsAnalyzer(t)
+
+	start := time.Now()
+	_, err := analyzer.fetchWebpage(context.Background(), "http://"+addr+"/", nil)
+	require.Error(t, err)
+	var opErr *net.OpError
+	require.True(t, errors.As(err, &opErr), "got %v", err)
+	assert.Equal(t, "dial", opErr.Op)
+	assert.True(t, opErr.Timeout())
+	assert.Less(t, time.Since(start), 5*time.Second, "the stage timeout fires before the overall ceiling")
+
+	_, _, err = analyzer.probeLink(context.Background(), "http://"+addr+"/link", false)
+	require.Error(t, err)
+	require.True(t, errors.As(err, &opErr), "got %v", err)
+	assert.Equal(t, "dial", opErr.Op)
+	assert.Equal(t, constants.LinkFailureTimeout, linkFailureCategory(0, err))
+}
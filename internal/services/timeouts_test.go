@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// silentListener accepts connections and never writes to them, so requests hang waiting for
+// the TLS handshake or the response headers
+func silentListener(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				<-done
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// timeoutsAnalyzer returns an analyzer with short stage timeouts under a long overall ceiling
+func timeoutsAnalyzer(t *testing.T) *Analyzer {
+	cfg := createTestConfig()
+	stages := config.FetchTimeouts{
+		Connect:        200 * time.Millisecond,
+		TLSHandshake:   200 * time.Millisecond,
+		ResponseHeader: 200 * time.Millisecond,
+		Overall:        10 * time.Second,
+	}
+	cfg.Analyzer.Timeouts = config.TimeoutsConfig{Page: stages, Links: stages}
+	return NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+}
+
+func TestNewAnalyzer_Timeouts(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	page := analyzer.httpClient.Transport.(*resolvingTransport).base
+	assert.Equal(t, constants.DefaultPageTLSHandshakeTimeout, page.TLSHandshakeTimeout)
+	assert.Equal(t, constants.DefaultPageResponseHeaderTimeout, page.ResponseHeaderTimeout)
+	assert.Equal(t, constants.DefaultLinkTimeout, analyzer.httpClient.Timeout)
+
+	links := analyzer.linkClient.Transport.(*resolvingTransport).base
+	assert.NotSame(t, page, links)
+	assert.Equal(t, constants.DefaultLinkTLSHandshakeTimeout, links.TLSHandshakeTimeout)
+	assert.Equal(t, constants.DefaultLinkResponseHeaderTimeout, links.ResponseHeaderTimeout)
+	assert.Equal(t, constants.DefaultLinkTimeout, analyzer.linkClient.Timeout)
+}
+
+func TestResolveOptions_Timeouts(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Analyzer.LinkTimeout = 7 * time.Second
+	cfg.Analyzer.Timeouts.Page = config.FetchTimeouts{Connect: time.Second, Overall: 20 * time.Second}
+
+	options := resolveOptions(cfg)
+
+	assert.Equal(t, config.FetchTimeouts{
+		Connect:        time.Second,
+		TLSHandshake:   constants.DefaultPageTLSHandshakeTimeout,
+		ResponseHeader: constants.DefaultPageResponseHeaderTimeout,
+		Overall:        20 * time.Second,
+	}, options.Timeouts.Page)
+	assert.Equal(t, config.FetchTimeouts{
+		Connect:        constants.DefaultLinkConnectTimeout,
+		TLSHandshake:   constants.DefaultLinkTLSHandshakeTimeout,
+		ResponseHeader: constants.DefaultLinkResponseHeaderTimeout,
+		Overall:        7 * time.Second,
+	}, options.Timeouts.Links, "the link timeout stays the overall ceiling")
+	assert.Zero(t, cfg.Analyzer.Timeouts.Links, "the configuration is not modified")
+}
+
+func TestAnalyzer_Timeouts_ResponseHeader(t *testing.T) {
+	addr := silentListener(t)
+	analyzer := timeoutsAnalyzer(t)
+
+	start := time.Now()
+	_, err := analyzer.fetchWebpage(context.Background(), "http://"+addr+"/", nil)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "timeout awaiting response headers")
+	assert.Less(t, time.Since(start), 5*time.Second, "the stage timeout fires before the overall ceiling")
+
+	_, _, err = analyzer.probeLink(context.Background(), "http://"+addr+"/link", false)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "timeout awaiting response headers")
+	assert.Equal(t, constants.LinkFailureTimeout, linkFailureCategory(0, err))
+}
+
+func TestAnalyzer_Timeouts_TLSHandshake(t *testing.T) {
+	addr := silentListener(t)
+	analyzer := timeoutsAnalyzer(t)
+
+	start := time.Now()
+	_, err := analyzer.fetchWebpage(context.Background(), "https://"+addr+"/", nil)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "TLS handshake timeout")
+	assert.Less(t, time.Since(start), 5*time.Second, "the stage timeout fires before the overall ceiling")
+
+	_, _, err = analyzer.probeLink(context.Background(), "https://"+addr+"/link", false)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "TLS handshake timeout")
+	assert.Equal(t, constants.LinkFailureTimeout, linkFailureCategory(0, err))
+}
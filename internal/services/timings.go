@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// fetchTimings records the phases of a page fetch through httptrace. Dials can run on other
+// goroutines, so every hook takes the lock. Each request of a redirect chain starts over, so
+// the timings describe the request that returned the page.
+type fetchTimings struct {
+	mu sync.Mutex
+	fetchPhases
+}
+
+// fetchPhases are the timings of one request
+type fetchPhases struct {
+	start        time.Time
+	dnsStart     time.Time
+	dns          time.Duration
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	tls          time.Duration
+	ttfb         time.Duration
+	resolvedIPs  []string
+	remoteAddr   string
+	reused       bool
+}
+
+// withFetchTrace attaches an httptrace.ClientTrace recording into fresh fetch timings to the context
+func withFetchTrace(ctx context.Context) (context.Context, *fetchTimings) {
+	t := &fetchTimings{}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.fetchPhases = fetchPhases{start: time.Now()}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Since(t.dnsStart)
+			for _, addr := range info.Addrs {
+				t.resolvedIPs = append(t.resolvedIPs, addr.String())
+			}
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// Parallel dials of several addresses count from the first one
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.tls = time.Since(t.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.ttfb = time.Since(t.start)
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+// report returns the recorded timings
+func (t *fetchTimings) report() *models.FetchTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &models.FetchTimings{
+		DNSLookupMs:      t.dns.Milliseconds(),
+		ConnectMs:        t.connect.Milliseconds(),
+		TLSHandshakeMs:   t.tls.Milliseconds(),
+		TTFBMs:           t.ttfb.Milliseconds(),
+		ResolvedIPs:      t.resolvedIPs,
+		RemoteAddr:       t.remoteAddr,
+		ConnectionReused: t.reused,
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func newTimingsServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Timings</title></head><body></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_FetchWebpage_Timings(t *testing.T) {
+	server := newTimingsServer(t)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	page, err := analyzer.fetchWebpage(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	timings := page.fetch.Timings
+	require.NotNil(t, timings)
+
+	assert.Zero(t, timings.DNSLookupMs, "literal IP addresses are not looked up")
+	assert.Empty(t, timings.ResolvedIPs)
+	assert.GreaterOrEqual(t, timings.ConnectMs, int64(0))
+	assert.Zero(t, timings.TLSHandshakeMs, "plain http has no handshake")
+	assert.GreaterOrEqual(t, timings.TTFBMs, int64(0))
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), timings.RemoteAddr)
+	assert.False(t, timings.ConnectionReused)
+
+	page, err = analyzer.fetchWebpage(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+	assert.True(t, page.fetch.Timings.ConnectionReused)
+	assert.Zero(t, page.fetch.Timings.ConnectMs)
+}
+
+func TestAnalyzer_FetchWebpage_TimingsResolveHostnames(t *testing.T) {
+	server := newTimingsServer(t)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	page, err := analyzer.fetchWebpage(context.Background(), strings.Replace(server.URL, "127.0.0.1", "localhost", 1), nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, page.fetch.Timings.ResolvedIPs, "127.0.0.1")
+	assert.GreaterOrEqual(t, page.fetch.Timings.DNSLookupMs, int64(0))
+}
+
+func TestWithFetchTrace_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, timings := withFetchTrace(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	assert.Positive(t, timings.tls)
+	assert.Positive(t, timings.connect)
+	assert.Positive(t, timings.ttfb)
+	assert.GreaterOrEqual(t, timings.ttfb, timings.tls, "the first byte arrives after the handshake")
+}
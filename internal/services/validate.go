@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// validationStep is one check of ValidateURL, returning whether it passed and why
+type validationStep struct {
+	check string
+	run   func() (bool, string)
+}
+
+// ValidateURL checks whether targetURL could be analyzed, without analyzing it or touching
+// the cache: its length, syntax, scheme and host, the target policy, that its host resolves, and
+// then, concurrently, that robots.txt allows it and that it answers a HEAD request.
+// Once a check fails the checks depending on it are skipped. All checks share a deadline
+// of constants.ValidationTimeout.
+func (a *Analyzer) ValidateURL(ctx context.Context, targetURL string) *models.ValidationReport {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, constants.ValidationTimeout)
+	defer cancel()
+	ctx, warnings := withPolicyWarnings(ctx)
+	ctx, _ = a.withBudget(ctx)
+
+	report := &models.ValidationReport{URL: models.StripCredentials(targetURL), Valid: true}
+	var parsedURL *url.URL
+	steps := []validationStep{
+		{constants.ValidationCheckLength, func() (bool, string) {
+			if len(targetURL) > constants.MaxURLLength {
+				return false, fmt.Sprintf("URL is %d characters long; at most %d are allowed", len(targetURL), constants.MaxURLLength)
+			}
+			return true, ""
+		}},
+		{constants.ValidationCheckSyntax, func() (bool, string) {
+			var err error
+			if parsedURL, err = url.Parse(targetURL); err != nil {
+				return false, err.Error()
+			}
+			return true, ""
+		}},
+		{constants.ValidationCheckScheme, func() (bool, string) {
+			if parsedURL.Scheme == "" {
+				return false, "URL has no scheme; URL scheme must be http or https"
+			}
+			if urlErr := models.CheckScheme(parsedURL.Scheme); urlErr != nil {
+				return false, urlErr.Message
+			}
+			return true, ""
+		}},
+		{constants.ValidationCheckHost, func() (bool, string) {
+			var err error
+			if parsedURL, err = a.parseAndValidateURL(targetURL); err != nil {
+				return false, err.Error()
+			}
+			return true, ""
+		}},
+		{constants.ValidationCheckPolicy, func() (bool, string) {
+			if err := a.policy.check(ctx, parsedURL); err != nil {
+				return false, err.Error()
+			}
+			if blocked := warnings.list(); len(blocked) > 0 {
+				return true, fmt.Sprintf("would be blocked by %s; the target policy is in dry-run mode", blocked[0].Rule)
+			}
+			return true, ""
+		}},
+		{constants.ValidationCheckDNS, func() (bool, string) {
+			return a.validateDNS(ctx, parsedURL.Hostname())
+		}},
+	}
+
+	failed := false
+	for _, step := range steps {
+		if failed {
+			report.Checks = append(report.Checks, models.ValidationCheck{Check: step.check, Skipped: true})
+			continue
+		}
+		pass, detail := step.run()
+		report.Checks = append(report.Checks, models.ValidationCheck{Check: step.check, Pass: pass, Detail: detail})
+		failed = !pass
+	}
+
+	// robots.txt and the HEAD probe are independent requests to the target
+	probes := []validationStep{
+		{constants.ValidationCheckRobots, func() (bool, string) { return a.validateRobots(ctx, parsedURL) }},
+		{constants.ValidationCheckReachable, func() (bool, string) { return a.validateReachable(ctx, parsedURL) }},
+	}
+	results := make([]models.ValidationCheck, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		if failed {
+			results[i] = models.ValidationCheck{Check: probe.check, Skipped: true}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pass, detail := probe.run()
+			results[i] = models.ValidationCheck{Check: probe.check, Pass: pass, Detail: detail}
+		}()
+	}
+	wg.Wait()
+	report.Checks = append(report.Checks, results...)
+
+	for _, check := range report.Checks {
+		if !check.Pass && !check.Skipped {
+			report.Valid = false
+		}
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+	return report
+}
+
+// validateDNS checks that host resolves; IP addresses need no lookup
+func (a *Analyzer) validateDNS(ctx context.Context, host string) (bool, string) {
+	if net.ParseIP(host) != nil {
+		return true, "host is an IP address"
+	}
+	addrs, err := a.lookupHost(ctx, host)
+	if err != nil {
+		return false, fmt.Sprintf("host does not resolve: %v", err)
+	}
+	return true, fmt.Sprintf("resolves to %s", strings.Join(addrs, ", "))
+}
+
+// validateRobots checks that the target's robots.txt allows every crawler to fetch it.
+// As RFC 9309 has it, a missing robots.txt (4xx) allows everything and an unreachable one
+// (5xx or no answer) disallows everything.
+func (a *Analyzer) validateRobots(ctx context.Context, target *url.URL) (bool, string) {
+	robotsURL := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: constants.RobotsTxtPath}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	if err := budgetFrom(ctx).acquire(ctx); err != nil {
+		return false, err.Error()
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Sprintf("robots.txt could not be fetched: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= constants.StatusInternalServerError:
+		return false, fmt.Sprintf("robots.txt answered %d; crawlers treat the whole site as disallowed", resp.StatusCode)
+	case resp.StatusCode >= constants.StatusBadRequest:
+		return true, fmt.Sprintf("no robots.txt (%d); every path is allowed", resp.StatusCode)
+	case resp.StatusCode != constants.StatusOK:
+		return true, fmt.Sprintf("robots.txt answered %d; every path is allowed", resp.StatusCode)
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	allowed, rule := parseRobots(io.LimitReader(resp.Body, constants.MaxRobotsTxtBytes)).allows(path)
+	if !allowed {
+		return false, fmt.Sprintf("robots.txt disallows %s (Disallow: %s)", path, rule)
+	}
+	if rule != "" {
+		return true, fmt.Sprintf("robots.txt allows %s (Allow: %s)", path, rule)
+	}
+	return true, ""
+}
+
+// validateReachable sends a single HEAD request to the target, without following redirects.
+// Servers that refuse HEAD requests with 405 or 501 still answered, so they are reachable.
+func (a *Analyzer) validateReachable(ctx context.Context, target *url.URL) (bool, string) {
+	client := *a.httpClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	if err := budgetFrom(ctx).acquire(ctx); err != nil {
+		return false, err.Error()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Sprintf("HEAD request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == constants.StatusMethodNotAllowed || resp.StatusCode == constants.StatusNotImplemented:
+		return true, fmt.Sprintf("HEAD answered %d; the server does not support HEAD requests", resp.StatusCode)
+	case resp.StatusCode >= constants.StatusBadRequest:
+		return false, fmt.Sprintf("HEAD answered %d", resp.StatusCode)
+	case resp.StatusCode >= constants.StatusMultipleChoices:
+		return true, fmt.Sprintf("HEAD answered %d, redirecting to %s", resp.StatusCode, resp.Header.Get(constants.HeaderLocation))
+	}
+	return true, fmt.Sprintf("HEAD answered %d", resp.StatusCode)
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newValidationSite serves a robots.txt disallowing /private, except to down.example where
+// robots.txt answers 503, a 404 at /gone and 200 everywhere else
+func newValidationSite(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == constants.RobotsTxtPath && strings.HasPrefix(r.Host, "down.example"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == constants.RobotsTxtPath:
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case r.URL.Path == "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newValidationAnalyzer sends every request to server and resolves every host but
+// missing.example
+func newValidationAnalyzer(t *testing.T, cfg *config.Config, server *httptest.Server) *Analyzer {
+	analyzer := NewAnalyzer(cfg, zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+	dialServer(analyzer, server)
+	analyzer.lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if host == "missing.example" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"192.0.2.10"}, nil
+	}
+	return analyzer
+}
+
+func TestAnalyzer_ValidateURL_Valid(t *testing.T) {
+	analyzer := newValidationAnalyzer(t, createTestConfig(), newValidationSite(t))
+
+	report := analyzer.ValidateURL(context.Background(), "http://site.example/docs")
+	assert.True(t, report.Valid)
+	assert.Equal(t, "http://site.example/docs", report.URL)
+	checks := make([]string, 0, len(report.Checks))
+	for _, check := range report.Checks {
+		assert.True(t, check.Pass, check.Check)
+		checks = append(checks, check.Check)
+	}
+	assert.Equal(t, []string{"length", "syntax", "scheme", "host", "policy", "dns", "robots", "reachability"}, checks)
+	assert.Less(t, report.DurationMs, constants.ValidationTimeout.Milliseconds())
+}
+
+func TestAnalyzer_ValidateURL_FailsOneCheck(t *testing.T) {
+	server := newValidationSite(t)
+	blocking := createTestConfig()
+	blocking.Analyzer.TargetPolicy.BlockedHosts = []string{"blocked.example"}
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		url     string
+		failed  string
+		detail  string
+		skipped []string
+	}{
+		{
+			name:    "Too long",
+			url:     "http://site.example/" + strings.Repeat("a", constants.MaxURLLength),
+			failed:  constants.ValidationCheckLength,
+			detail:  "at most 2048",
+			skipped: []string{"syntax", "scheme", "host", "policy", "dns", "robots", "reachability"},
+		},
+		{
+			name:    "Malformed",
+			url:     "http://site.example/%zz",
+			failed:  constants.ValidationCheckSyntax,
+			detail:  "invalid URL escape",
+			skipped: []string{"scheme", "host", "policy", "dns", "robots", "reachability"},
+		},
+		{
+			name:    "Unsupported scheme",
+			url:     "ftp://site.example/file.txt",
+			failed:  constants.ValidationCheckScheme,
+			detail:  "FTP is not supported",
+			skipped: []string{"host", "policy", "dns", "robots", "reachability"},
+		},
+		{
+			name:    "Missing host",
+			url:     "http:///docs",
+			failed:  constants.ValidationCheckHost,
+			detail:  "missing scheme or host",
+			skipped: []string{"policy", "dns", "robots", "reachability"},
+		},
+		{
+			name:    "Blocked by the target policy",
+			cfg:     blocking,
+			url:     "http://www.blocked.example/",
+			failed:  constants.ValidationCheckPolicy,
+			detail:  "blocked_hosts:blocked.example",
+			skipped: []string{"dns", "robots", "reachability"},
+		},
+		{
+			name:    "Unresolvable host",
+			url:     "http://missing.example/",
+			failed:  constants.ValidationCheckDNS,
+			detail:  "no such host",
+			skipped: []string{"robots", "reachability"},
+		},
+		{
+			name:   "Disallowed by robots.txt",
+			url:    "http://site.example/private/report",
+			failed: constants.ValidationCheckRobots,
+			detail: "Disallow: /private",
+		},
+		{
+			name:   "Unreachable robots.txt",
+			url:    "http://down.example/",
+			failed: constants.ValidationCheckRobots,
+			detail: "answered 503",
+		},
+		{
+			name:   "Not found",
+			url:    "http://site.example/gone",
+			failed: constants.ValidationCheckReachable,
+			detail: "HEAD answered 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if cfg == nil {
+				cfg = createTestConfig()
+			}
+			analyzer := newValidationAnalyzer(t, cfg, server)
+
+			report := analyzer.ValidateURL(context.Background(), tt.url)
+			assert.False(t, report.Valid)
+			var failed, skipped []string
+			for _, check := range report.Checks {
+				switch {
+				case check.Skipped:
+					assert.False(t, check.Pass)
+					skipped = append(skipped, check.Check)
+				case !check.Pass:
+					failed = append(failed, check.Check)
+					assert.Contains(t, check.Detail, tt.detail)
+				}
+			}
+			assert.Equal(t, []string{tt.failed}, failed)
+			assert.Equal(t, tt.skipped, skipped)
+			assert.Len(t, report.Checks, 8)
+		})
+	}
+}
+
+func TestAnalyzer_ValidateURL_DryRunPolicy(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Analyzer.TargetPolicy = config.TargetPolicyConfig{Mode: constants.TargetPolicyModeDryRun, BlockedHosts: []string{"site.example"}}
+	analyzer := newValidationAnalyzer(t, cfg, newValidationSite(t))
+
+	report := analyzer.ValidateURL(context.Background(), "http://site.example/")
+	assert.True(t, report.Valid, "dry-run mode blocks nothing")
+	policy := report.Checks[4]
+	require.Equal(t, constants.ValidationCheckPolicy, policy.Check)
+	assert.True(t, policy.Pass)
+	assert.Contains(t, policy.Detail, "dry-run")
+}
+
+func TestAnalyzer_ValidateURL_HeadNotAllowed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+	analyzer := newValidationAnalyzer(t, createTestConfig(), server)
+
+	report := analyzer.ValidateURL(context.Background(), server.URL+"/")
+	assert.True(t, report.Valid, "the server answered, so it is reachable")
+	reachability := report.Checks[len(report.Checks)-1]
+	assert.Equal(t, models.ValidationCheck{Check: constants.ValidationCheckReachable, Pass: true, Detail: "HEAD answered 405; the server does not support HEAD requests"}, reachability)
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// analysisWarnings collects the non-fatal problems of one analysis, so the response can say
+// where it is incomplete. Warnings never change the outcome or HTTP status of the analysis.
+type analysisWarnings struct {
+	mu       sync.Mutex
+	warnings []models.Warning
+}
+
+type warningsContextKey struct{}
+
+// withWarnings attaches an empty warning list for one analysis to the context
+func withWarnings(ctx context.Context) (context.Context, *analysisWarnings) {
+	warnings := &analysisWarnings{}
+	return context.WithValue(ctx, warningsContextKey{}, warnings), warnings
+}
+
+// warningsFrom returns the warnings of the analysis running under ctx, if any
+func warningsFrom(ctx context.Context) *analysisWarnings {
+	warnings, _ := ctx.Value(warningsContextKey{}).(*analysisWarnings)
+	return warnings
+}
+
+// add records a warning, once per code
+func (w *analysisWarnings) add(code, message string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, warning := range w.warnings {
+		if warning.Code == code {
+			return
+		}
+	}
+	w.warnings = append(w.warnings, models.Warning{Code: code, Message: message})
+}
+
+// list returns the warnings in the order they were raised, or nil when there are none
+func (w *analysisWarnings) list() []models.Warning {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.warnings) == 0 {
+		return nil
+	}
+	return append([]models.Warning(nil), w.warnings...)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// newLinksServer serves a page with n internal links, each of which is accessible
+func newLinksServer(t *testing.T, n int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path != "/" {
+			w.Write([]byte("<html><body>Linked</body></html>"))
+			return
+		}
+		var page strings.Builder
+		page.WriteString("<html><head><title>Links</title></head><body>")
+		for i := range n {
+			fmt.Fprintf(&page, `<a href="/page/%d">Page %d</a>`, i, i)
+		}
+		page.WriteString("</body></html>")
+		w.Write([]byte(page.String()))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnalyzer_Analyze_WarnsOfLinksNotChecked(t *testing.T) {
+	server := newLinksServer(t, 5)
+	analyzer := newTestAnalyzer(t, func(cfg *config.Config) {
+		cfg.Analyzer.MaxLinks = 2
+	})
+
+	result, err := analyzer.Analyze(context.Background(), server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, result.Links.Internal)
+	assert.Equal(t, []models.Warning{{
+		Code:    constants.WarningCodeLinksNotChecked,
+		Message: "3 links were not checked; analyzer.max_links is 2",
+	}}, result.Warnings)
+}
+
+func TestAnalyzer_Analyze_WarnsOfCacheFailures(t *testing.T) {
+	server := newLinksServer(t, 1)
+
+	t.Run("Write", func(t *testing.T) {
+		cache := &MockCache{}
+		cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), nil)
+		cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("connection refused"))
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+		result, err := analyzer.Analyze(context.Background(), server.URL)
+		require.NoError(t, err, "a cache failure does not fail the analysis")
+
+		assert.Equal(t, "Links", result.Title)
+		assert.Equal(t, []models.Warning{{Code: constants.WarningCodeCacheWriteFailed, Message: "The result could not be cached"}}, result.Warnings)
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		cache := &MockCache{}
+		cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), errors.New("connection refused"))
+		cache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			assert.Empty(t, args.Get(2).(*models.AnalyzeResponse).Warnings, "request warnings are never cached")
+		})
+		analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+		result, err := analyzer.Analyze(context.Background(), server.URL)
+		require.NoError(t, err)
+
+		assert.Equal(t, []models.Warning{{
+			Code:    constants.WarningCodeCacheReadFailed,
+			Message: "The cache could not be read; the page was analyzed again",
+		}}, result.Warnings)
+	})
+}
+
+func TestAnalysisWarnings_OncePerCode(t *testing.T) {
+	_, warnings := withWarnings(context.Background())
+	warnings.add(constants.WarningCodeLinksNotChecked, "first")
+	warnings.add(constants.WarningCodeCacheWriteFailed, "write")
+	warnings.add(constants.WarningCodeLinksNotChecked, "second")
+
+	assert.Equal(t, []models.Warning{
+		{Code: constants.WarningCodeLinksNotChecked, Message: "first"},
+		{Code: constants.WarningCodeCacheWriteFailed, Message: "write"},
+	}, warnings.list())
+
+	var none *analysisWarnings
+	none.add(constants.WarningCodeLinksNotChecked, "ignored")
+	assert.Nil(t, none.list())
+}
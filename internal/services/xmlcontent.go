@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/html/charset"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// errXMLLimit stops reading a sitemap or feed with more entries than are counted
+var errXMLLimit = errors.New("entry limit reached")
+
+// w3cDatetimeLayouts are the forms of the W3C datetime sitemaps date their entries with
+var w3cDatetimeLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00", time.DateOnly, "2006-01", "2006"}
+
+// feedDateLayouts are the RFC 822 dates of RSS as found in the wild, one-digit days and
+// missing weekdays included, and the W3C datetimes of Atom and Dublin Core dates
+var feedDateLayouts = []string{
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	time.RFC3339,
+}
+
+// xmlContent summarizes a sitemap or a feed
+type xmlContent struct {
+	kind      string
+	sitemap   *models.Sitemap
+	feed      *models.Feed
+	malformed error // Where the document stopped being well-formed; the summary covers what came before
+}
+
+// analyzeXML summarizes a fetched sitemap or feed. It returns nil for pages of other kinds,
+// XHTML and other XML documents included, which are analyzed as HTML.
+func (a *Analyzer) analyzeXML(ctx context.Context, targetURL string, page *fetchResult) *models.AnalyzeResponse {
+	if !isXMLContent(page.header, page.body) {
+		return nil
+	}
+	start := time.Now()
+	content := parseXMLContent(page.body)
+	if content == nil {
+		return nil
+	}
+	if content.malformed != nil {
+		a.log(ctx).Warn("Malformed XML document", zap.String("url", targetURL), zap.String("kind", content.kind), zap.Error(content.malformed))
+		warningsFrom(ctx).add(constants.WarningCodeXMLMalformed, fmt.Sprintf("The %s is not well-formed XML; only the entries before the error were counted", content.kind))
+	}
+
+	result := &models.AnalyzeResponse{
+		URL:         targetURL,
+		ContentKind: content.kind,
+		Sitemap:     content.sitemap,
+		Feed:        content.feed,
+		AnalyzedAt:  time.Now(),
+	}
+	result.Phases.ParseMs = time.Since(start).Milliseconds()
+	return result
+}
+
+// isXMLContent reports whether a body is served as XML or starts with an XML declaration.
+// XHTML qualifies too; parseXMLContent tells it apart by its root element.
+func isXMLContent(header http.Header, body string) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get(constants.HeaderContentType))
+	if mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	return strings.HasPrefix(strings.TrimLeft(body, "\ufeff \t\r\n"), "<?xml")
+}
+
+// parseXMLContent summarizes body when its root element is a sitemap, with a urlset or
+// sitemapindex root, or an RSS or Atom feed. It returns nil for other documents, which are
+// analyzed as HTML. Elements are matched by local name, whatever their namespace, and only
+// the first entries up to a limit are read, so the work is bounded like the body is.
+func parseXMLContent(body string) *xmlContent {
+	dec := xml.NewDecoder(strings.NewReader(body))
+	dec.CharsetReader = charset.NewReaderLabel
+	// Feeds are often written by HTML templates, with entities like &nbsp; and bare ampersands
+	// that a strict decoder would stop at
+	dec.Entity = xml.HTMLEntity
+	dec.Strict = false
+
+	var root xml.StartElement
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+
+	switch root.Name.Local {
+	case "urlset", "sitemapindex":
+		return parseSitemap(dec, root)
+	case "rss", "RDF":
+		return parseRSS(dec)
+	case "feed":
+		return parseAtom(dec)
+	}
+	return nil
+}
+
+// parseSitemap counts the entries of a sitemap and the range of their lastmod dates
+func parseSitemap(dec *xml.Decoder, root xml.StartElement) *xmlContent {
+	sitemap := &models.Sitemap{Index: root.Name.Local == "sitemapindex"}
+	entry := "url"
+	if sitemap.Index {
+		entry = "sitemap"
+	}
+
+	var earliest, latest time.Time
+	err := eachChild(dec, func(start xml.StartElement) error {
+		if start.Name.Local != entry {
+			return dec.Skip()
+		}
+		if sitemap.URLCount == constants.SitemapMaxEntries {
+			sitemap.Truncated = true
+			return errXMLLimit
+		}
+		var e struct {
+			Lastmod string `xml:"lastmod"`
+		}
+		if err := dec.DecodeElement(&e, &start); err != nil {
+			return err
+		}
+		sitemap.URLCount++
+
+		lastmod := strings.TrimSpace(e.Lastmod)
+		if lastmod == "" {
+			return nil
+		}
+		t, ok := parseTime(lastmod, w3cDatetimeLayouts)
+		if !ok {
+			sitemap.InvalidLastmod++
+			return nil
+		}
+		if earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+		if t.After(latest) {
+			latest = t
+		}
+		return nil
+	})
+	if !earliest.IsZero() {
+		sitemap.LastmodEarliest, sitemap.LastmodLatest = &earliest, &latest
+	}
+	return &xmlContent{kind: constants.ContentKindSitemap, sitemap: sitemap, malformed: malformedXML(err)}
+}
+
+// parseRSS reads the title and items of an RSS 2.0 feed, whose items are in its channel,
+// or an RSS 1.0 feed, whose items follow its channel
+func parseRSS(dec *xml.Decoder) *xmlContent {
+	feed := &models.Feed{Format: constants.FeedFormatRSS}
+	var latest time.Time
+
+	var child func(start xml.StartElement) error
+	child = func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "channel":
+			return eachChild(dec, child)
+		case "title":
+			return decodeTitle(dec, start, &feed.Title)
+		case "item":
+			var item struct {
+				PubDate string `xml:"pubDate"`
+				Date    string `xml:"date"` // Dublin Core, as RSS 1.0 dates items
+			}
+			if err := decodeItem(dec, start, feed, &item); err != nil {
+				return err
+			}
+			latest = latestDate(latest, item.PubDate, item.Date)
+			return nil
+		}
+		return dec.Skip()
+	}
+	err := eachChild(dec, child)
+	if !latest.IsZero() {
+		feed.LatestItemAt = &latest
+	}
+	return &xmlContent{kind: constants.ContentKindFeed, feed: feed, malformed: malformedXML(err)}
+}
+
+// parseAtom reads the title and entries of an Atom feed
+func parseAtom(dec *xml.Decoder) *xmlContent {
+	feed := &models.Feed{Format: constants.FeedFormatAtom}
+	var latest time.Time
+
+	err := eachChild(dec, func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "title":
+			return decodeTitle(dec, start, &feed.Title)
+		case "entry":
+			var entry struct {
+				Updated   string `xml:"updated"`
+				Published string `xml:"published"`
+			}
+			if err := decodeItem(dec, start, feed, &entry); err != nil {
+				return err
+			}
+			latest = latestDate(latest, entry.Updated, entry.Published)
+			return nil
+		}
+		return dec.Skip()
+	})
+	if !latest.IsZero() {
+		feed.LatestItemAt = &latest
+	}
+	return &xmlContent{kind: constants.ContentKindFeed, feed: feed, malformed: malformedXML(err)}
+}
+
+// decodeItem decodes the feed item starting at start into item and counts it, unless the
+// feed has as many items as are counted
+func decodeItem(dec *xml.Decoder, start xml.StartElement, feed *models.Feed, item any) error {
+	if feed.ItemCount == constants.FeedMaxItems {
+		feed.Truncated = true
+		return errXMLLimit
+	}
+	if err := dec.DecodeElement(item, &start); err != nil {
+		return err
+	}
+	feed.ItemCount++
+	return nil
+}
+
+// latestDate returns the latest of latest and the feed dates; unparsable dates are ignored
+func latestDate(latest time.Time, dates ...string) time.Time {
+	for _, date := range dates {
+		if t, ok := parseTime(strings.TrimSpace(date), feedDateLayouts); ok && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// decodeTitle sets title to the text of the element starting at start, unless a title was
+// read already
+func decodeTitle(dec *xml.Decoder, start xml.StartElement, title *string) error {
+	if *title != "" {
+		return dec.Skip()
+	}
+	var text struct {
+		Value string `xml:",chardata"`
+	}
+	if err := dec.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	*title = cleanText(text.Value)
+	return nil
+}
+
+// eachChild calls fn for each child element of the element being read, up to its end. fn
+// must consume the child, by decoding or skipping it.
+func eachChild(dec *xml.Decoder, fn func(start xml.StartElement) error) error {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// malformedXML returns the error that cut the reading of a document short, if it was not
+// the entry limit
+func malformedXML(err error) error {
+	if err == nil || errors.Is(err, errXMLLimit) {
+		return nil
+	}
+	return fmt.Errorf("malformed XML: %w", err)
+}
+
+// parseTime parses value with the first of layouts that fits it
+func parseTime(value string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func TestAnalyzer_Sitemap(t *testing.T) {
+	sitemap := loadFixture(t, "sitemap.xml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	cache := &MockCache{}
+	cache.On("Get", mock.Anything, mock.Anything).Return(nil, time.Duration(0), nil)
+	cache.On("Set", mock.Anything, mock.Anything, mock.AnythingOfType("*models.AnalyzeResponse"), mock.Anything).Return(nil)
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), cache)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/sitemap.xml", models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.ContentKindSitemap, result.ContentKind)
+	assert.Nil(t, result.Feed)
+	require.NotNil(t, result.Sitemap)
+	assert.False(t, result.Sitemap.Index)
+	assert.Equal(t, 5, result.Sitemap.URLCount)
+	assert.Equal(t, 1, result.Sitemap.InvalidLastmod)
+	assert.True(t, time.Date(2023, 11, 15, 7, 30, 0, 0, time.UTC).Equal(*result.Sitemap.LastmodEarliest))
+	assert.True(t, time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC).Equal(*result.Sitemap.LastmodLatest))
+	assert.False(t, result.Sitemap.Truncated)
+	assert.Empty(t, result.Warnings)
+	require.NotNil(t, result.Fetch)
+	assert.Equal(t, http.StatusOK, result.Fetch.StatusCode)
+	cache.AssertCalled(t, "Set", mock.Anything, mock.Anything, result, mock.Anything)
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, `"sitemap"`, string(fields["content_kind"]))
+	assert.Contains(t, fields, "sitemap")
+	assert.Contains(t, fields, "fetch")
+	for _, html := range []string{"title", "headings", "links", "forms", "seo", "accessibility"} {
+		assert.NotContains(t, fields, html, "HTML sections are left out")
+	}
+}
+
+func TestAnalyzer_HTMLContentKind(t *testing.T) {
+	analyzer := NewAnalyzer(createTestConfig(), zaptest.NewLogger(t), NewMockMetrics(), &MockCache{})
+
+	result, err := analyzer.AnalyzeHTML(context.Background(), `<html><head><title>Page</title></head><body></body></html>`, "https://example.com/", models.AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.ContentKindHTML, result.ContentKind)
+	assert.Nil(t, result.Sitemap)
+	assert.Nil(t, result.Feed)
+}
+
+func TestParseXMLContent(t *testing.T) {
+	t.Run("RSS 2.0", func(t *testing.T) {
+		content := parseXMLContent(loadFixture(t, "feed.rss"))
+
+		require.NotNil(t, content)
+		assert.NoError(t, content.malformed)
+		assert.Equal(t, constants.ContentKindFeed, content.kind)
+		require.NotNil(t, content.feed)
+		assert.Equal(t, constants.FeedFormatRSS, content.feed.Format)
+		assert.Equal(t, "Example Blog", content.feed.Title, "the channel title, not the image's")
+		assert.Equal(t, 3, content.feed.ItemCount)
+		assert.True(t, time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC).Equal(*content.feed.LatestItemAt))
+	})
+
+	t.Run("RSS 1.0", func(t *testing.T) {
+		content := parseXMLContent(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>Old School</title><items><rdf:Seq><rdf:li resource="https://example.com/1"/></rdf:Seq></items></channel>
+  <item><title>One</title><dc:date>2024-02-01T10:00:00Z</dc:date></item>
+  <item><title>Two</title><dc:date>2024-02-03T10:00:00Z</dc:date></item>
+</rdf:RDF>`)
+
+		require.NotNil(t, content)
+		assert.Equal(t, "Old School", content.feed.Title)
+		assert.Equal(t, 2, content.feed.ItemCount)
+		assert.True(t, time.Date(2024, 2, 3, 10, 0, 0, 0, time.UTC).Equal(*content.feed.LatestItemAt))
+	})
+
+	t.Run("Atom", func(t *testing.T) {
+		content := parseXMLContent(`<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="text">Example Atom</title>
+  <updated>2024-03-20T00:00:00Z</updated>
+  <entry><title>One</title><published>2024-03-01T00:00:00Z</published><updated>2024-03-10T00:00:00Z</updated></entry>
+  <entry><title>Two</title><updated>2024-03-05T00:00:00Z</updated></entry>
+</feed>`)
+
+		require.NotNil(t, content)
+		assert.Equal(t, constants.FeedFormatAtom, content.feed.Format)
+		assert.Equal(t, "Example Atom", content.feed.Title)
+		assert.Equal(t, 2, content.feed.ItemCount)
+		assert.True(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC).Equal(*content.feed.LatestItemAt), "the feed's own updated date is not an item's")
+	})
+
+	t.Run("Sitemap index", func(t *testing.T) {
+		content := parseXMLContent(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap-1.xml</loc><lastmod>2024-01</lastmod></sitemap>
+  <sitemap><loc>https://example.com/sitemap-2.xml</loc></sitemap>
+</sitemapindex>`)
+
+		require.NotNil(t, content)
+		assert.Equal(t, constants.ContentKindSitemap, content.kind)
+		assert.True(t, content.sitemap.Index)
+		assert.Equal(t, 2, content.sitemap.URLCount)
+		assert.True(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Equal(*content.sitemap.LastmodLatest))
+	})
+
+	t.Run("Entries past the limit are not read", func(t *testing.T) {
+		body := "<urlset>" + strings.Repeat("<url><loc>https://example.com/</loc></url>", constants.SitemapMaxEntries+1) + "</urlset>"
+		content := parseXMLContent(body)
+
+		require.NotNil(t, content)
+		assert.NoError(t, content.malformed)
+		assert.Equal(t, constants.SitemapMaxEntries, content.sitemap.URLCount)
+		assert.True(t, content.sitemap.Truncated)
+	})
+
+	t.Run("Malformed documents are counted up to the error", func(t *testing.T) {
+		content := parseXMLContent(`<rss><channel><title>Cut</title><item><pubDate>Mon, 18 Mar 2024 12:00:00 +0000</pubDate></item><item>`)
+
+		require.NotNil(t, content)
+		assert.Error(t, content.malformed)
+		assert.Equal(t, 1, content.feed.ItemCount)
+		assert.Equal(t, "Cut", content.feed.Title)
+	})
+
+	t.Run("Other XML documents are analyzed as HTML", func(t *testing.T) {
+		assert.Nil(t, parseXMLContent(`<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"><head><title>XHTML</title></head></html>`))
+		assert.Nil(t, parseXMLContent(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`))
+		assert.Nil(t, parseXMLContent("not xml at all"))
+	})
+
+	t.Run("HTML entities and bare ampersands are read", func(t *testing.T) {
+		content := parseXMLContent(`<rss><channel><title>Tom&nbsp;&amp; Jerry & Friends</title><item><title>One</title></item></channel></rss>`)
+
+		require.NotNil(t, content)
+		assert.NoError(t, content.malformed)
+		assert.Equal(t, "Tom & Jerry & Friends", content.feed.Title)
+		assert.Equal(t, 1, content.feed.ItemCount)
+	})
+
+	t.Run("Declared encodings are decoded", func(t *testing.T) {
+		content := parseXMLContent("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Caf\xe9</title></channel></rss>")
+
+		require.NotNil(t, content)
+		assert.NoError(t, content.malformed)
+		assert.Equal(t, "Café", content.feed.Title)
+	})
+}
+
+func TestAnalyzer_MalformedFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss><channel><title>Cut</title><item><title>One</title></item><item><title>Two`))
+	}))
+	defer server.Close()
+
+	analyzer := newTestAnalyzer(t, nil)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), server.URL+"/feed", models.AnalyzeOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.ContentKindFeed, result.ContentKind)
+	assert.Equal(t, 1, result.Feed.ItemCount)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, constants.WarningCodeXMLMalformed, result.Warnings[0].Code)
+}
+
+func TestIsXMLContent(t *testing.T) {
+	header := func(contentType string) http.Header {
+		return http.Header{constants.HeaderContentType: []string{contentType}}
+	}
+
+	assert.True(t, isXMLContent(header("application/xml"), "<urlset/>"))
+	assert.True(t, isXMLContent(header("text/xml; charset=utf-8"), "<urlset/>"))
+	assert.True(t, isXMLContent(header("application/atom+xml"), "<feed/>"))
+	assert.True(t, isXMLContent(header("text/plain"), "\ufeff  <?xml version=\"1.0\"?><rss/>"), "declared XML served with the wrong type")
+	assert.False(t, isXMLContent(header("text/html"), "<!DOCTYPE html><html></html>"))
+	assert.False(t, isXMLContent(http.Header{}, "<html></html>"))
+}
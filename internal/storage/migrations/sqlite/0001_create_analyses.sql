@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS analyses (
+    id                 INTEGER   PRIMARY KEY AUTOINCREMENT,
+    url                TEXT      NOT NULL,
+    analyzed_at        TIMESTAMP NOT NULL,
+    created_at         TIMESTAMP NOT NULL,
+    html_version       TEXT      NOT NULL,
+    title              TEXT      NOT NULL,
+    internal_links     INTEGER   NOT NULL,
+    external_links     INTEGER   NOT NULL,
+    inaccessible_links INTEGER   NOT NULL,
+    has_login_form     BOOLEAN   NOT NULL,
+    seo_score          INTEGER   NOT NULL,
+    result             TEXT      NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS analyses_url_analyzed_at_idx ON analyses (url, analyzed_at);
+CREATE INDEX IF NOT EXISTS analyses_analyzed_at_idx ON analyses (analyzed_at);
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the pgx database/sql driver
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite" // Registers the sqlite database/sql driver
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// sqlDrivers maps the configured driver to the registered database/sql driver name
+var sqlDrivers = map[string]string{
+	constants.StorageDriverPostgres: "pgx",
+	constants.StorageDriverSQLite:   "sqlite",
+}
+
+const recordColumns = "id, url, analyzed_at, created_at, html_version, title, internal_links, external_links, inaccessible_links, has_login_form, seo_score, result"
+
+// SQLStore stores analyses in PostgreSQL or SQLite
+type SQLStore struct {
+	db        *sql.DB
+	driver    string
+	logger    *zap.Logger
+	batchSize int
+}
+
+// Open connects to the configured database and applies pending migrations
+func Open(ctx context.Context, cfg config.StorageConfig, logger *zap.Logger) (*SQLStore, error) {
+	driverName, ok := sqlDrivers[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported storage driver %q", cfg.Driver)
+	}
+
+	db, err := sql.Open(driverName, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+	if cfg.Driver == constants.StorageDriverSQLite {
+		// SQLite allows one writer at a time, and every connection to :memory: is a separate database
+		db.SetMaxOpenConns(1)
+	}
+
+	store := &SQLStore{db: db, driver: cfg.Driver, logger: logger, batchSize: constants.StorageReadBatchSize}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to storage: %w", err)
+	}
+	if err := store.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// migrate applies the driver's migration files that have not been applied yet, in name order
+func (s *SQLStore) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version TEXT PRIMARY KEY, applied_at TIMESTAMP NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	dir := path.Join("migrations", s.driver)
+	entries, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		version := strings.TrimSuffix(entry.Name(), ".sql")
+
+		var applied int
+		if err := s.db.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`), version).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", version, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		script, err := migrations.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+		if err := s.apply(ctx, version, string(script)); err != nil {
+			return err
+		}
+		s.logger.Info("Applied storage migration", zap.String("version", version))
+	}
+
+	return nil
+}
+
+// apply runs one migration and records it in a single transaction
+func (s *SQLStore) apply(ctx context.Context, version, script string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range strings.Split(script, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), version, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", version, err)
+	}
+
+	return tx.Commit()
+}
+
+// Save inserts a row for a completed analysis
+func (s *SQLStore) Save(ctx context.Context, result *models.AnalyzeResponse) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, s.rebind(`INSERT INTO analyses
+		(url, analyzed_at, created_at, html_version, title, internal_links, external_links, inaccessible_links, has_login_form, seo_score, result)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		result.URL,
+		result.AnalyzedAt.UTC(),
+		time.Now().UTC(),
+		result.HTMLVersion,
+		result.Title,
+		result.Links.Internal,
+		result.Links.External,
+		result.Links.Inaccessible,
+		result.HasLoginForm,
+		result.SEO.Score,
+		string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store analysis: %w", err)
+	}
+	return nil
+}
+
+// List returns the records matching the query, newest first
+func (s *SQLStore) List(ctx context.Context, query Query) (*Page, error) {
+	where, args := whereClause(query)
+
+	page := &Page{Records: []Record{}, Limit: query.Limit, Offset: query.Offset}
+	if err := s.db.QueryRowContext(ctx, s.rebind("SELECT COUNT(*) FROM analyses"+where), args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count analyses: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		s.rebind("SELECT "+recordColumns+" FROM analyses"+where+" ORDER BY analyzed_at DESC, id DESC LIMIT ? OFFSET ?"),
+		append(args, query.Limit, query.Offset)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list analyses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, *record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list analyses: %w", err)
+	}
+
+	return page, nil
+}
+
+// Each calls fn with every record matching the query, oldest first. A zero Limit returns all
+// matches; Offset is ignored. Records are read in batches of batchSize, and each batch is read
+// whole before fn sees it, so a slow consumer never holds a connection: with SQLite's single
+// connection, analyses are saved while an export runs. An error returned by fn stops the
+// iteration and is returned as is.
+func (s *SQLStore) Each(ctx context.Context, query Query, fn func(*Record) error) error {
+	remaining := query.Limit
+	var last *Record
+	for {
+		limit := s.batchSize
+		if query.Limit > 0 {
+			limit = min(limit, remaining)
+		}
+		batch, err := s.readBatch(ctx, query, last, limit)
+		if err != nil {
+			return err
+		}
+		for _, record := range batch {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if len(batch) < limit {
+			return nil
+		}
+		last = batch[len(batch)-1]
+		if query.Limit > 0 {
+			if remaining -= len(batch); remaining == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// readBatch returns up to limit records matching the query, oldest first, that come after
+// last, or from the first when last is nil
+func (s *SQLStore) readBatch(ctx context.Context, query Query, last *Record, limit int) ([]*Record, error) {
+	where, args := whereClause(query)
+	if last != nil {
+		after := "(analyzed_at > ? OR (analyzed_at = ? AND id > ?))"
+		if where == "" {
+			where = " WHERE " + after
+		} else {
+			where += " AND " + after
+		}
+		args = append(args, last.AnalyzedAt.UTC(), last.AnalyzedAt.UTC(), last.ID)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		s.rebind("SELECT "+recordColumns+" FROM analyses"+where+" ORDER BY analyzed_at, id LIMIT ?"),
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []*Record
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analyses: %w", err)
+	}
+	return batch, nil
+}
+
+// whereClause builds the filter of a query with ? placeholders
+func whereClause(query Query) (string, []any) {
+	var conditions []string
+	var args []any
+	if query.URL != "" {
+		conditions = append(conditions, "url = ?")
+		args = append(args, query.URL)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "analyzed_at >= ?")
+		args = append(args, query.From.UTC())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "analyzed_at < ?")
+		args = append(args, query.To.UTC())
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// scanRecord reads one row selected with recordColumns
+func scanRecord(rows *sql.Rows) (*Record, error) {
+	var record Record
+	var result []byte
+	if err := rows.Scan(&record.ID, &record.URL, &record.AnalyzedAt, &record.CreatedAt, &record.HTMLVersion, &record.Title,
+		&record.InternalLinks, &record.ExternalLinks, &record.InaccessibleLinks, &record.HasLoginForm, &record.SEOScore, &result); err != nil {
+		return nil, fmt.Errorf("failed to read analysis: %w", err)
+	}
+	record.Result = json.RawMessage(result)
+	return &record, nil
+}
+
+// Close closes the database connection pool
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// rebind rewrites ? placeholders into the numbered $n form PostgreSQL expects
+func (s *SQLStore) rebind(query string) string {
+	if s.driver != constants.StorageDriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
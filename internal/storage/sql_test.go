@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+func openTestStore(t *testing.T) *SQLStore {
+	store, err := Open(context.Background(), config.StorageConfig{
+		Enabled: true,
+		Driver:  constants.StorageDriverSQLite,
+		DSN:     "file::memory:",
+	}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func testResult(url string, analyzedAt time.Time) *models.AnalyzeResponse {
+	return &models.AnalyzeResponse{
+		URL:          url,
+		HTMLVersion:  "HTML5",
+		Title:        "Example",
+		HasLoginForm: true,
+		AnalyzedAt:   analyzedAt,
+		Links:        models.LinkAnalysis{Internal: 3, External: 2, Inaccessible: 1},
+		SEO:          models.SEOReport{Score: 85},
+	}
+}
+
+func TestOpen_UnsupportedDriver(t *testing.T) {
+	_, err := Open(context.Background(), config.StorageConfig{Driver: "mysql"}, zaptest.NewLogger(t))
+	assert.ErrorContains(t, err, "unsupported storage driver")
+}
+
+func TestSQLStore_MigrationsAreIdempotent(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.migrate(ctx))
+
+	var applied int
+	require.NoError(t, store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, 1, applied)
+}
+
+func TestSQLStore_SaveAndList(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.Save(ctx, testResult("https://example.com", base)))
+	require.NoError(t, store.Save(ctx, testResult("https://example.com", base.Add(time.Hour))))
+	require.NoError(t, store.Save(ctx, testResult("https://example.com", base.Add(2*time.Hour))))
+	require.NoError(t, store.Save(ctx, testResult("https://other.example", base.Add(30*time.Minute))))
+
+	t.Run("Stored columns and result", func(t *testing.T) {
+		page, err := store.List(ctx, Query{URL: "https://other.example", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+
+		record := page.Records[0]
+		assert.Equal(t, "https://other.example", record.URL)
+		assert.True(t, base.Add(30*time.Minute).Equal(record.AnalyzedAt))
+		assert.Equal(t, "HTML5", record.HTMLVersion)
+		assert.Equal(t, "Example", record.Title)
+		assert.Equal(t, 3, record.InternalLinks)
+		assert.Equal(t, 2, record.ExternalLinks)
+		assert.Equal(t, 1, record.InaccessibleLinks)
+		assert.True(t, record.HasLoginForm)
+		assert.Equal(t, 85, record.SEOScore)
+
+		var result models.AnalyzeResponse
+		require.NoError(t, json.Unmarshal(record.Result, &result))
+		assert.Equal(t, "https://other.example", result.URL)
+	})
+
+	t.Run("Newest first", func(t *testing.T) {
+		page, err := store.List(ctx, Query{URL: "https://example.com", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.Records, 3)
+		assert.True(t, base.Add(2*time.Hour).Equal(page.Records[0].AnalyzedAt))
+		assert.True(t, base.Equal(page.Records[2].AnalyzedAt))
+	})
+
+	t.Run("Time range is half-open", func(t *testing.T) {
+		page, err := store.List(ctx, Query{From: base.Add(30 * time.Minute), To: base.Add(2 * time.Hour), Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 2, page.Total)
+		require.Len(t, page.Records, 2)
+		assert.Equal(t, "https://example.com", page.Records[0].URL)
+		assert.Equal(t, "https://other.example", page.Records[1].URL)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		page, err := store.List(ctx, Query{Limit: 2, Offset: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 4, page.Total)
+		assert.Equal(t, 2, page.Limit)
+		assert.Equal(t, 2, page.Offset)
+		require.Len(t, page.Records, 2)
+		assert.True(t, base.Add(30*time.Minute).Equal(page.Records[0].AnalyzedAt))
+		assert.True(t, base.Equal(page.Records[1].AnalyzedAt))
+	})
+
+	t.Run("No matches", func(t *testing.T) {
+		page, err := store.List(ctx, Query{URL: "https://missing.example", Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 0, page.Total)
+		assert.Empty(t, page.Records)
+	})
+}
+
+func TestSQLStore_Rebind(t *testing.T) {
+	postgres := &SQLStore{driver: constants.StorageDriverPostgres}
+	sqlite := &SQLStore{driver: constants.StorageDriverSQLite}
+
+	query := "SELECT * FROM analyses WHERE url = ? AND analyzed_at >= ? LIMIT ?"
+	assert.Equal(t, "SELECT * FROM analyses WHERE url = $1 AND analyzed_at >= $2 LIMIT $3", postgres.rebind(query))
+	assert.Equal(t, query, sqlite.rebind(query))
+}
+
+func TestNoOpStore(t *testing.T) {
+	store := NewNoOpStore()
+
+	assert.NoError(t, store.Save(context.Background(), testResult("https://example.com", time.Now())))
+	_, err := store.List(context.Background(), Query{})
+	assert.ErrorIs(t, err, ErrDisabled)
+	assert.NoError(t, store.Close())
+}
+
+func TestSQLStore_Each(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.Save(ctx, testResult("https://example.com", base.Add(time.Duration(4-i)*time.Hour))))
+	}
+
+	collect := func(query Query) []time.Time {
+		var analyzedAt []time.Time
+		require.NoError(t, store.Each(ctx, query, func(record *Record) error {
+			analyzedAt = append(analyzedAt, record.AnalyzedAt)
+			return nil
+		}))
+		return analyzedAt
+	}
+
+	t.Run("Oldest first without a limit", func(t *testing.T) {
+		analyzedAt := collect(Query{})
+		require.Len(t, analyzedAt, 5)
+		for i := range analyzedAt {
+			assert.True(t, base.Add(time.Duration(i)*time.Hour).Equal(analyzedAt[i]))
+		}
+	})
+
+	t.Run("Range and limit", func(t *testing.T) {
+		analyzedAt := collect(Query{From: base.Add(time.Hour), To: base.Add(4 * time.Hour), Limit: 2})
+		require.Len(t, analyzedAt, 2)
+		assert.True(t, base.Add(time.Hour).Equal(analyzedAt[0]))
+		assert.True(t, base.Add(2*time.Hour).Equal(analyzedAt[1]))
+	})
+
+	t.Run("Read in batches", func(t *testing.T) {
+		store.batchSize = 2
+		defer func() { store.batchSize = constants.StorageReadBatchSize }()
+
+		assert.Len(t, collect(Query{}), 5)
+		assert.Len(t, collect(Query{Limit: 3}), 3)
+		assert.Len(t, collect(Query{Limit: 4}), 4)
+	})
+
+	t.Run("Saves are not blocked while records are read", func(t *testing.T) {
+		writeCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		saved := false
+		require.NoError(t, store.Each(ctx, Query{Limit: 1}, func(record *Record) error {
+			saved = true
+			return store.Save(writeCtx, testResult("https://example.com/later", base.Add(24*time.Hour)))
+		}))
+		assert.True(t, saved)
+	})
+
+	t.Run("Callback error stops the iteration", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := store.Each(ctx, Query{}, func(record *Record) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// ErrDisabled is returned when records are queried while storage is disabled
+var ErrDisabled = errors.New("analysis storage is disabled")
+
+// Store persists completed analyses for later querying
+type Store interface {
+	Save(ctx context.Context, result *models.AnalyzeResponse) error
+	List(ctx context.Context, query Query) (*Page, error)
+	Each(ctx context.Context, query Query, fn func(*Record) error) error
+	Close() error
+}
+
+// Record is a stored analysis; the type is shared with API clients through models
+type Record = models.AnalysisRecord
+
+// Query filters and paginates stored records. Zero values do not filter.
+type Query struct {
+	URL    string
+	From   time.Time // Inclusive lower bound on AnalyzedAt
+	To     time.Time // Exclusive upper bound on AnalyzedAt
+	Limit  int
+	Offset int
+}
+
+// Page is one page of records, newest first
+type Page = models.AnalysesPage
+
+// NoOpStore discards analyses when storage is disabled
+type NoOpStore struct{}
+
+// NewNoOpStore creates a store that keeps nothing
+func NewNoOpStore() *NoOpStore {
+	return &NoOpStore{}
+}
+
+func (s *NoOpStore) Save(ctx context.Context, result *models.AnalyzeResponse) error {
+	return nil
+}
+
+func (s *NoOpStore) List(ctx context.Context, query Query) (*Page, error) {
+	return nil, ErrDisabled
+}
+
+func (s *NoOpStore) Each(ctx context.Context, query Query, fn func(*Record) error) error {
+	return ErrDisabled
+}
+
+func (s *NoOpStore) Close() error {
+	return nil
+}
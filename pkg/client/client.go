@@ -0,0 +1,246 @@
+// Package client calls the webpage analyser API. Requests and responses use the
+// server's own model types, so the client stays in sync with the API.
+//
+// The server has no batch analysis or job endpoints, so the client has no AnalyzeBatch or
+// job methods.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/models"
+)
+
+// API types, shared with the server
+type (
+	AnalyzeOptions  = models.AnalyzeOptions
+	AnalyzeResponse = models.AnalyzeResponse
+	AnalysisRecord  = models.AnalysisRecord
+	AnalysesPage    = models.AnalysesPage
+	ErrorResponse   = models.ErrorResponse
+)
+
+// Client defaults
+const (
+	DefaultTimeout      = 60 * time.Second // Above the server's default analysis deadline
+	DefaultRetryBackoff = 500 * time.Millisecond
+	DefaultMaxRetryWait = 30 * time.Second
+	HeaderAPIKey        = constants.HeaderAPIKey
+)
+
+// Options configures a Client. Zero values use the defaults.
+type Options struct {
+	APIKey       string        // Sent as X-API-Key, for gateways in front of the service
+	Timeout      time.Duration // Per attempt
+	MaxRetries   int           // Retries of throttled and unavailable responses; 0 disables retries
+	RetryBackoff time.Duration // Wait before the first retry, doubled for every further one
+	MaxRetryWait time.Duration // Longer Retry-After delays are returned as errors instead of waited for
+	HTTPClient   *http.Client  // Replaces the default client; Timeout is then ignored
+}
+
+// Client calls the webpage analyser API
+type Client struct {
+	baseURL      string
+	apiKey       string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+	maxRetryWait time.Duration
+}
+
+// New creates a client for the API served at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts Options) *Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	if opts.MaxRetryWait == 0 {
+		opts.MaxRetryWait = DefaultMaxRetryWait
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: opts.Timeout}
+	}
+
+	return &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		apiKey:       opts.APIKey,
+		httpClient:   httpClient,
+		maxRetries:   opts.MaxRetries,
+		retryBackoff: opts.RetryBackoff,
+		maxRetryWait: opts.MaxRetryWait,
+	}
+}
+
+// Analyze fetches and analyzes the page at targetURL. A result cut short by the analysis
+// deadline, which the server returns only with opts.AllowPartial, has Partial set.
+func (c *Client) Analyze(ctx context.Context, targetURL string, opts AnalyzeOptions) (*AnalyzeResponse, error) {
+	var result AnalyzeResponse
+	body := models.AnalyzeRequest{URL: targetURL, Options: opts}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyze", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AnalyzeHTML analyzes submitted HTML, resolving links against baseURL
+func (c *Client) AnalyzeHTML(ctx context.Context, html, baseURL string, opts AnalyzeOptions) (*AnalyzeResponse, error) {
+	var result AnalyzeResponse
+	body := models.AnalyzeHTMLRequest{HTML: html, BaseURL: baseURL, Options: opts}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/analyze/html", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListQuery filters and paginates stored analyses. Zero values do not filter.
+type ListQuery struct {
+	URL    string
+	From   time.Time // Inclusive lower bound on analyzed_at
+	To     time.Time // Exclusive upper bound on analyzed_at
+	Limit  int
+	Offset int
+}
+
+// ListAnalyses returns a page of stored analyses, newest first. It fails with
+// ErrorCodeStorageDisabled when the server does not store analyses.
+func (c *Client) ListAnalyses(ctx context.Context, query ListQuery) (*AnalysesPage, error) {
+	params := url.Values{}
+	if query.URL != "" {
+		params.Set("url", query.URL)
+	}
+	if !query.From.IsZero() {
+		params.Set("from", query.From.Format(time.RFC3339Nano))
+	}
+	if !query.To.IsZero() {
+		params.Set("to", query.To.Format(time.RFC3339Nano))
+	}
+	if query.Limit > 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Offset > 0 {
+		params.Set("offset", strconv.Itoa(query.Offset))
+	}
+
+	path := "/api/v1/analyses"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var page AnalysesPage
+	if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// do sends a request, retrying throttled and unavailable responses, and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, path, payload, out)
+		if err == nil || attempt >= c.maxRetries {
+			return err
+		}
+
+		wait, retry := c.retryDelay(err, backoff)
+		if !retry {
+			return err
+		}
+		backoff *= 2
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends the request once
+func (c *Client) attempt(ctx context.Context, method, path string, payload []byte, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(constants.HeaderAccept, constants.ContentTypeJSON)
+	if payload != nil {
+		req.Header.Set(constants.HeaderContentType, constants.ContentTypeJSON)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(HeaderAPIKey, c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &transportError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= constants.StatusBadRequest {
+		return newAPIError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryDelay reports whether a failed attempt is retried and how long to wait first.
+// The server's Retry-After takes precedence over the backoff.
+func (c *Client) retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	switch e := err.(type) {
+	case *transportError:
+		return backoff, true
+	case *APIError:
+		switch e.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		default:
+			return 0, false
+		}
+		if e.RetryAfter == 0 {
+			return backoff, true
+		}
+		return e.RetryAfter, e.RetryAfter <= c.maxRetryWait
+	default:
+		return 0, false
+	}
+}
+
+// transportError is a request that received no response
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/webpage-analyser-server/internal/config"
+	"github.com/webpage-analyser-server/internal/constants"
+	"github.com/webpage-analyser-server/internal/handlers"
+	"github.com/webpage-analyser-server/internal/metrics"
+	"github.com/webpage-analyser-server/internal/middleware"
+	"github.com/webpage-analyser-server/internal/router"
+	"github.com/webpage-analyser-server/internal/services"
+	"github.com/webpage-analyser-server/internal/storage"
+)
+
+// newAPIServer serves the real router, storing analyses in SQLite when withStorage is set
+func newAPIServer(t *testing.T, withStorage bool) *httptest.Server {
+	// The router loads its templates relative to the repository root
+	t.Chdir("../..")
+
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "test"}}
+	logger := zaptest.NewLogger(t)
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	cache := services.NewNoOpCache(logger)
+
+	var store storage.Store = storage.NewNoOpStore()
+	if withStorage {
+		var err error
+		store, err = storage.Open(context.Background(), config.StorageConfig{
+			Enabled: true,
+			Driver:  constants.StorageDriverSQLite,
+			DSN:     filepath.Join(t.TempDir(), "analyses.db"),
+		}, logger)
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+	}
+
+	analyzer := services.NewAnalyzer(cfg, logger, m, cache)
+	analyzer.SetStore(store)
+	t.Cleanup(func() { analyzer.Close() })
+
+	rateLimiter := middleware.NewRateLimiter(nil)
+	r := router.New(cfg, logger, m,
+		handlers.NewAnalyzeHandler(cfg, logger, analyzer),
+		handlers.NewAnalysesHandler(logger, store),
+		handlers.NewValidateHandler(logger, analyzer),
+		handlers.NewDuplicatesHandler(logger, analyzer),
+		handlers.NewExportHandler(cfg, logger, store, cache),
+		handlers.NewCacheStatsHandler(logger, cache),
+		handlers.NewRateLimitHandler(logger, rateLimiter, cfg.RateLimit),
+		handlers.NewCapabilitiesHandler(services.Capabilities(cfg)),
+		handlers.NewReadinessHandler(nil),
+		handlers.NewSnapshotHandler(logger, analyzer),
+		rateLimiter,
+	)
+	server := httptest.NewServer(r.Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTargetServer serves the page under analysis
+func newTargetServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Fixture</title></head><body><h1>Hello</h1><a href="/about">About</a></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Analyze(t *testing.T) {
+	api := newAPIServer(t, false)
+	target := newTargetServer(t)
+	client := New(api.URL, Options{})
+
+	result, err := client.Analyze(context.Background(), target.URL, AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, target.URL, result.URL)
+	assert.Equal(t, "Fixture", result.Title)
+	assert.Equal(t, 1, result.Headings["h1"])
+	assert.Equal(t, 1, result.Links.Internal)
+	assert.False(t, result.Partial)
+}
+
+func TestClient_AnalyzeHTML(t *testing.T) {
+	api := newAPIServer(t, false)
+	client := New(api.URL, Options{})
+
+	result, err := client.AnalyzeHTML(context.Background(), `<html><head><title>Submitted</title></head><body><a href="https://other.com/">Other</a></body></html>`, "https://example.com/", AnalyzeOptions{SkipLinkCheck: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Submitted", result.Title)
+	assert.Equal(t, 1, result.Links.External)
+}
+
+func TestClient_APIError(t *testing.T) {
+	api := newAPIServer(t, false)
+	client := New(api.URL, Options{MaxRetries: 2})
+
+	_, err := client.Analyze(context.Background(), "ftp://example.com/file", AnalyzeOptions{})
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, ErrorCodeFTPURL, apiErr.Code)
+	assert.NotEmpty(t, apiErr.Message)
+	assert.NotEmpty(t, apiErr.RequestID)
+	assert.Contains(t, err.Error(), "FTP_URL_UNSUPPORTED")
+}
+
+func TestClient_ListAnalyses(t *testing.T) {
+	t.Run("Storage disabled", func(t *testing.T) {
+		client := New(newAPIServer(t, false).URL, Options{})
+
+		_, err := client.ListAnalyses(context.Background(), ListQuery{})
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, ErrorCodeStorageDisabled, apiErr.Code)
+	})
+
+	t.Run("Stored analyses", func(t *testing.T) {
+		client := New(newAPIServer(t, true).URL, Options{})
+		target := newTargetServer(t)
+
+		_, err := client.Analyze(context.Background(), target.URL, AnalyzeOptions{SkipLinkCheck: true})
+		require.NoError(t, err)
+		_, err = client.AnalyzeHTML(context.Background(), `<title>Other</title>`, "https://example.com/", AnalyzeOptions{SkipLinkCheck: true})
+		require.NoError(t, err)
+
+		page, err := client.ListAnalyses(context.Background(), ListQuery{URL: target.URL, From: time.Now().Add(-time.Hour), Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		assert.Equal(t, 1, page.Total)
+		assert.Equal(t, 10, page.Limit)
+		assert.Equal(t, "Fixture", page.Records[0].Title)
+	})
+}
+
+func TestClient_Retries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get(HeaderAPIKey))
+		switch attempts.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set(constants.HeaderRetryAfter, "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"code":429,"message":"Rate limit exceeded"}`))
+		default:
+			w.Write([]byte(`{"url":"https://example.com","title":"Retried"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, Options{APIKey: "secret", MaxRetries: 2, RetryBackoff: time.Millisecond})
+	result, err := client.Analyze(context.Background(), "https://example.com", AnalyzeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Retried", result.Title)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestClient_RetriesExhaustedOrNotRetried(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           int
+		retryAfter       string
+		expectedAttempts int32
+	}{
+		{name: "Retries exhausted", status: http.StatusBadGateway, expectedAttempts: 3},
+		{name: "Retry-After beyond the maximum wait", status: http.StatusTooManyRequests, retryAfter: "3600", expectedAttempts: 1},
+		{name: "Client errors are not retried", status: http.StatusBadRequest, expectedAttempts: 1},
+		{name: "Analysis timeouts are not retried", status: http.StatusGatewayTimeout, expectedAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				if tt.retryAfter != "" {
+					w.Header().Set(constants.HeaderRetryAfter, tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := New(server.URL, Options{MaxRetries: 2, RetryBackoff: time.Millisecond})
+			_, err := client.Analyze(context.Background(), "https://example.com", AnalyzeOptions{})
+
+			var apiErr *APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.expectedAttempts, attempts.Load())
+			if tt.retryAfter != "" {
+				assert.Equal(t, time.Hour, apiErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 19, 10, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, 2*time.Minute, parseRetryAfter("Tue, 19 Mar 2024 10:32:00 GMT", now))
+	assert.Zero(t, parseRetryAfter("Tue, 19 Mar 2024 10:00:00 GMT", now))
+	assert.Zero(t, parseRetryAfter("-5", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+	assert.Zero(t, parseRetryAfter("", now))
+}
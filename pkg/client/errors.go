@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/webpage-analyser-server/internal/constants"
+)
+
+// ErrorCode identifies why the API rejected a request
+type ErrorCode string
+
+// Error codes returned by the API
+const (
+	ErrorCodeInternal              ErrorCode = constants.ErrorCodeInternal
+	ErrorCodeInvalidRequest        ErrorCode = constants.ErrorCodeInvalidRequest
+	ErrorCodeValidation            ErrorCode = constants.ErrorCodeValidation
+	ErrorCodePayloadTooLarge       ErrorCode = constants.ErrorCodePayloadTooLarge
+	ErrorCodeAnalysisFailed        ErrorCode = constants.ErrorCodeAnalysisFailed
+	ErrorCodeBotProtection         ErrorCode = constants.ErrorCodeBotProtection
+	ErrorCodeAnalysisTimeout       ErrorCode = constants.ErrorCodeAnalysisTimeout
+	ErrorCodeTargetRateLimited     ErrorCode = constants.ErrorCodeTargetRateLimited
+	ErrorCodeStorageDisabled       ErrorCode = constants.ErrorCodeStorageDisabled
+	ErrorCodeExportUnavailable     ErrorCode = constants.ErrorCodeExportUnavailable
+	ErrorCodeUnauthorized          ErrorCode = constants.ErrorCodeUnauthorized
+	ErrorCodeUnsupportedScheme     ErrorCode = constants.ErrorCodeUnsupportedScheme
+	ErrorCodeDataURL               ErrorCode = constants.ErrorCodeDataURL
+	ErrorCodeFileURL               ErrorCode = constants.ErrorCodeFileURL
+	ErrorCodeFTPURL                ErrorCode = constants.ErrorCodeFTPURL
+	ErrorCodeWebSocketURL          ErrorCode = constants.ErrorCodeWebSocketURL
+	ErrorCodeInvalidHost           ErrorCode = constants.ErrorCodeInvalidHost
+	ErrorCodeCredentialsNotAllowed ErrorCode = constants.ErrorCodeCredentialsNotAllowed
+	ErrorCodeTargetBlocked         ErrorCode = constants.ErrorCodeTargetBlocked
+	ErrorCodeEmptyDocument         ErrorCode = constants.ErrorCodeEmptyDocument
+	ErrorCodeTargetQuotaExceeded   ErrorCode = constants.ErrorCodeTargetQuotaExceeded
+)
+
+// APIError is an error response of the API
+type APIError struct {
+	StatusCode int
+	Code       ErrorCode // Empty for responses without an error code, such as the rate limiter's
+	Message    string
+	Details    string
+	RequestID  string
+	RetryAfter time.Duration // From the Retry-After header; 0 when absent
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("webpage analyser: %d", e.StatusCode)
+	if e.Code != "" {
+		msg += " " + string(e.Code)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Details != "" {
+		msg += " (" + e.Details + ")"
+	}
+	return msg
+}
+
+// newAPIError reads an ErrorResponse body; bodies that are not one keep only the status
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(constants.HeaderRequestID),
+		RetryAfter: parseRetryAfter(resp.Header.Get(constants.HeaderRetryAfter), time.Now()),
+	}
+
+	var body ErrorResponse
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &body); err != nil {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+		return apiErr
+	}
+	apiErr.Code = ErrorCode(body.ErrorCode)
+	apiErr.Message = body.Message
+	apiErr.Details = body.Details
+	if body.RequestID != "" {
+		apiErr.RequestID = body.RequestID
+	}
+	return apiErr
+}
+
+// parseRetryAfter reads a Retry-After value in seconds or as an HTTP-date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
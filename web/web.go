@@ -0,0 +1,10 @@
+// Package web holds the frontend templates and static assets, embedded in the binary so it
+// serves them from any working directory
+package web
+
+import "embed"
+
+// Assets holds templates/ and static/
+//
+//go:embed templates static
+var Assets embed.FS